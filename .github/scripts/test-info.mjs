@@ -501,6 +501,9 @@ const components = {
         conformance: true,
         conformanceSetup: 'docker-compose.sh etcd',
     },
+    'state.filesystem': {
+        conformance: true,
+    },
     'state.in-memory': {
         conformance: true,
     },
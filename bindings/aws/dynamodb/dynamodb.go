@@ -36,12 +36,17 @@ type DynamoDB struct {
 }
 
 type dynamoDBMetadata struct {
-	Region       string `json:"region" mapstructure:"region"`
-	Endpoint     string `json:"endpoint" mapstructure:"endpoint"`
-	AccessKey    string `json:"accessKey" mapstructure:"accessKey"`
-	SecretKey    string `json:"secretKey" mapstructure:"secretKey"`
-	SessionToken string `json:"sessionToken" mapstructure:"sessionToken"`
-	Table        string `json:"table" mapstructure:"table"`
+	Region              string `json:"region" mapstructure:"region"`
+	Endpoint            string `json:"endpoint" mapstructure:"endpoint"`
+	AccessKey           string `json:"accessKey" mapstructure:"accessKey"`
+	SecretKey           string `json:"secretKey" mapstructure:"secretKey"`
+	SessionToken        string `json:"sessionToken" mapstructure:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn" mapstructure:"assumeRoleArn"`
+	SessionName         string `json:"sessionName" mapstructure:"sessionName"`
+	ExternalID          string `json:"externalID" mapstructure:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn" mapstructure:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions" mapstructure:"allowedRegions"`
+	Table               string `json:"table" mapstructure:"table"`
 }
 
 // NewDynamoDB returns a new DynamoDB instance.
@@ -105,7 +110,18 @@ func (d *DynamoDB) getDynamoDBMetadata(spec bindings.Metadata) (*dynamoDBMetadat
 }
 
 func (d *DynamoDB) getClient(metadata *dynamoDBMetadata) (*dynamodb.DynamoDB, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		Endpoint:            metadata.Endpoint,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, err
 	}
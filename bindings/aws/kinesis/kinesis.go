@@ -63,6 +63,11 @@ type kinesisMetadata struct {
 	AccessKey           string `json:"accessKey" mapstructure:"accessKey"`
 	SecretKey           string `json:"secretKey" mapstructure:"secretKey"`
 	SessionToken        string `json:"sessionToken" mapstructure:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn" mapstructure:"assumeRoleArn"`
+	SessionName         string `json:"sessionName" mapstructure:"sessionName"`
+	ExternalID          string `json:"externalID" mapstructure:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn" mapstructure:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions" mapstructure:"allowedRegions"`
 	KinesisConsumerMode string `json:"mode" mapstructure:"mode"`
 }
 
@@ -356,7 +361,18 @@ func (a *AWSKinesis) waitUntilConsumerExists(ctx aws.Context, input *kinesis.Des
 }
 
 func (a *AWSKinesis) getClient(metadata *kinesisMetadata) (*kinesis.Kinesis, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		Endpoint:            metadata.Endpoint,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, err
 	}
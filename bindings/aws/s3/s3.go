@@ -34,6 +34,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/internal/component/blobmetadata"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
@@ -62,19 +63,24 @@ type AWSS3 struct {
 }
 
 type s3Metadata struct {
-	Region         string `json:"region" mapstructure:"region"`
-	Endpoint       string `json:"endpoint" mapstructure:"endpoint"`
-	AccessKey      string `json:"accessKey" mapstructure:"accessKey"`
-	SecretKey      string `json:"secretKey" mapstructure:"secretKey"`
-	SessionToken   string `json:"sessionToken" mapstructure:"sessionToken"`
-	Bucket         string `json:"bucket" mapstructure:"bucket"`
-	DecodeBase64   bool   `json:"decodeBase64,string" mapstructure:"decodeBase64"`
-	EncodeBase64   bool   `json:"encodeBase64,string" mapstructure:"encodeBase64"`
-	ForcePathStyle bool   `json:"forcePathStyle,string" mapstructure:"forcePathStyle"`
-	DisableSSL     bool   `json:"disableSSL,string" mapstructure:"disableSSL"`
-	InsecureSSL    bool   `json:"insecureSSL,string" mapstructure:"insecureSSL"`
-	FilePath       string `mapstructure:"filePath"`
-	PresignTTL     string `mapstructure:"presignTTL"`
+	Region              string `json:"region" mapstructure:"region"`
+	Endpoint            string `json:"endpoint" mapstructure:"endpoint"`
+	AccessKey           string `json:"accessKey" mapstructure:"accessKey"`
+	SecretKey           string `json:"secretKey" mapstructure:"secretKey"`
+	SessionToken        string `json:"sessionToken" mapstructure:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn" mapstructure:"assumeRoleArn"`
+	SessionName         string `json:"sessionName" mapstructure:"sessionName"`
+	ExternalID          string `json:"externalID" mapstructure:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn" mapstructure:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions" mapstructure:"allowedRegions"`
+	Bucket              string `json:"bucket" mapstructure:"bucket"`
+	DecodeBase64        bool   `json:"decodeBase64,string" mapstructure:"decodeBase64"`
+	EncodeBase64        bool   `json:"encodeBase64,string" mapstructure:"encodeBase64"`
+	ForcePathStyle      bool   `json:"forcePathStyle,string" mapstructure:"forcePathStyle"`
+	DisableSSL          bool   `json:"disableSSL,string" mapstructure:"disableSSL"`
+	InsecureSSL         bool   `json:"insecureSSL,string" mapstructure:"insecureSSL"`
+	FilePath            string `mapstructure:"filePath"`
+	PresignTTL          string `mapstructure:"presignTTL"`
 }
 
 type createResponse struct {
@@ -92,6 +98,14 @@ type listPayload struct {
 	Prefix     string `json:"prefix"`
 	MaxResults int32  `json:"maxResults"`
 	Delimiter  string `json:"delimiter"`
+	// If true, the headers and user-defined metadata of each object are included, which requires a request per object.
+	IncludeMetadata bool `json:"includeMetadata"`
+}
+
+type listResponse struct {
+	*s3.ListObjectsOutput
+	// Headers and user-defined metadata of each object, by key, if requested.
+	ObjectMetadata map[string]map[string]string `json:"ObjectMetadata,omitempty"`
 }
 
 // NewAWSS3 returns a new AWSS3 instance.
@@ -180,10 +194,21 @@ func (s *AWSS3) create(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 		r = b64.NewDecoder(b64.StdEncoding, r)
 	}
 
+	headers, userMetadata, err := blobmetadata.ParseRequest(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: %w", err)
+	}
+
 	resultUpload, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
-		Bucket: ptr.Of(metadata.Bucket),
-		Key:    ptr.Of(key),
-		Body:   r,
+		Bucket:             ptr.Of(metadata.Bucket),
+		Key:                ptr.Of(key),
+		Body:               r,
+		ContentType:        nonEmpty(headers.ContentType),
+		ContentEncoding:    nonEmpty(headers.ContentEncoding),
+		ContentLanguage:    nonEmpty(headers.ContentLanguage),
+		ContentDisposition: nonEmpty(headers.ContentDisposition),
+		CacheControl:       nonEmpty(headers.CacheControl),
+		Metadata:           aws.StringMap(userMetadata),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("s3 binding error: uploading failed: %w", err)
@@ -274,33 +299,88 @@ func (s *AWSS3) get(ctx context.Context, req *bindings.InvokeRequest) (*bindings
 		return nil, fmt.Errorf("s3 binding error: required metadata '%s' missing", metadataKey)
 	}
 
-	buff := &aws.WriteAtBuffer{}
+	input := &s3.GetObjectInput{
+		Bucket: ptr.Of(s.metadata.Bucket),
+		Key:    ptr.Of(key),
+	}
 
-	_, err = s.downloader.DownloadWithContext(ctx,
-		buff,
-		&s3.GetObjectInput{
-			Bucket: ptr.Of(s.metadata.Bucket),
-			Key:    ptr.Of(key),
-		},
-	)
-	if err != nil {
-		return nil, fmt.Errorf("s3 binding error: error downloading S3 object: %w", err)
+	var respMetadata map[string]string
+	if utils.IsTruthy(req.Metadata[blobmetadata.IncludeMetadataKey]) {
+		head, headErr := s.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: input.Bucket,
+			Key:    input.Key,
+		})
+		if headErr != nil {
+			return nil, fmt.Errorf("s3 binding error: error reading S3 object metadata: %w", headErr)
+		}
+		respMetadata = headObjectMetadata(head)
+		// Ensure the object that is downloaded is the one whose metadata was read
+		input.IfMatch = head.ETag
+	}
+
+	var raw []byte
+	if val := req.Metadata[blobmetadata.RangeKey]; val != "" {
+		rng, rangeErr := blobmetadata.ParseRange(val)
+		if rangeErr != nil {
+			return nil, fmt.Errorf("s3 binding error: %w", rangeErr)
+		}
+		input.Range = ptr.Of(rng.String())
+
+		// The downloader doesn't return the Content-Range of the response, which has the size of the object
+		out, getErr := s.s3Client.GetObjectWithContext(ctx, input)
+		if getErr != nil {
+			return nil, fmt.Errorf("s3 binding error: error downloading S3 object: %w", getErr)
+		}
+		defer out.Body.Close()
+		raw, err = io.ReadAll(out.Body)
+		if err != nil {
+			return nil, fmt.Errorf("s3 binding error: error reading S3 object: %w", err)
+		}
+		if respMetadata == nil {
+			respMetadata = make(map[string]string, 2)
+		}
+		blobmetadata.AddRange(respMetadata, aws.StringValue(out.ContentRange))
+	} else {
+		buff := &aws.WriteAtBuffer{}
+		_, err = s.downloader.DownloadWithContext(ctx, buff, input)
+		if err != nil {
+			return nil, fmt.Errorf("s3 binding error: error downloading S3 object: %w", err)
+		}
+		raw = buff.Bytes()
 	}
 
 	var data []byte
 	if metadata.EncodeBase64 {
-		encoded := b64.StdEncoding.EncodeToString(buff.Bytes())
+		encoded := b64.StdEncoding.EncodeToString(raw)
 		data = []byte(encoded)
 	} else {
-		data = buff.Bytes()
+		data = raw
 	}
 
 	return &bindings.InvokeResponse{
 		Data:     data,
-		Metadata: nil,
+		Metadata: respMetadata,
 	}, nil
 }
 
+// headObjectMetadata returns the headers and user-defined metadata of an object as response metadata.
+func headObjectMetadata(head *s3.HeadObjectOutput) map[string]string {
+	return blobmetadata.ResponseMetadata(blobmetadata.Headers{
+		ContentType:        aws.StringValue(head.ContentType),
+		ContentEncoding:    aws.StringValue(head.ContentEncoding),
+		ContentLanguage:    aws.StringValue(head.ContentLanguage),
+		ContentDisposition: aws.StringValue(head.ContentDisposition),
+		CacheControl:       aws.StringValue(head.CacheControl),
+	}, aws.StringValueMap(head.Metadata))
+}
+
+func nonEmpty(val string) *string {
+	if val == "" {
+		return nil
+	}
+	return &val
+}
+
 func (s *AWSS3) delete(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	key := req.Metadata[metadataKey]
 	if key == "" {
@@ -343,7 +423,22 @@ func (s *AWSS3) list(ctx context.Context, req *bindings.InvokeRequest) (*binding
 		return nil, fmt.Errorf("s3 binding error: list operation failed: %w", err)
 	}
 
-	jsonResponse, err := json.Marshal(result)
+	res := listResponse{ListObjectsOutput: result}
+	if payload.IncludeMetadata {
+		res.ObjectMetadata = make(map[string]map[string]string, len(result.Contents))
+		for _, obj := range result.Contents {
+			head, headErr := s.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+				Bucket: ptr.Of(s.metadata.Bucket),
+				Key:    obj.Key,
+			})
+			if headErr != nil {
+				return nil, fmt.Errorf("s3 binding error: list operation: error reading metadata of object %s: %w", aws.StringValue(obj.Key), headErr)
+			}
+			res.ObjectMetadata[aws.StringValue(obj.Key)] = headObjectMetadata(head)
+		}
+	}
+
+	jsonResponse, err := json.Marshal(res)
 	if err != nil {
 		return nil, fmt.Errorf("s3 binding error: list operation: cannot marshal list to json: %w", err)
 	}
@@ -380,7 +475,18 @@ func (s *AWSS3) parseMetadata(md bindings.Metadata) (*s3Metadata, error) {
 }
 
 func (s *AWSS3) getSession(metadata *s3Metadata) (*session.Session, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		Endpoint:            metadata.Endpoint,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// fakeS3 stores objects in memory, with their headers.
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data   []byte
+	header http.Header
+}
+
+var storedHeaders = []string{"Content-Type", "Content-Encoding", "Content-Language", "Content-Disposition", "Cache-Control"}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if r.URL.Path == "/bucket" || r.URL.Path == "/bucket/" {
+		// ListObjects
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>`)
+		for key := range f.objects {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, key, len(f.objects[key].data))
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		h := http.Header{}
+		for k, v := range r.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+				h[k] = v
+			}
+		}
+		for _, k := range storedHeaders {
+			if v := r.Header.Get(k); v != "" {
+				h.Set(k, v)
+			}
+		}
+		f.objects[key] = fakeObject{data: data, header: h}
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodHead, http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Length", fmt.Sprint(len(obj.data)))
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(obj.data)-1, len(obj.data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(obj.data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestObjectMetadata(t *testing.T) {
+	fake := &fakeS3{objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s := NewAWSS3(logger.NewLogger("test")).(*AWSS3)
+	err := s.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"accessKey":      "key",
+		"secretKey":      "secret",
+		"region":         "us-east-1",
+		"bucket":         "bucket",
+		"endpoint":       srv.URL,
+		"forcePathStyle": "true",
+		"disableSSL":     "true",
+	}}})
+	require.NoError(t, err)
+
+	t.Run("create with headers and metadata", func(t *testing.T) {
+		_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("hello"),
+			Metadata: map[string]string{
+				"key":                "obj",
+				"contentType":        "text/plain",
+				"cacheControl":       "max-age=60",
+				"contentDisposition": `attachment; filename="hello.txt"`,
+				"metadata.owner":     "team-a",
+			},
+		})
+		require.NoError(t, err)
+
+		obj := fake.objects["obj"]
+		assert.Equal(t, "text/plain", obj.header.Get("Content-Type"))
+		assert.Equal(t, "max-age=60", obj.header.Get("Cache-Control"))
+		assert.Equal(t, `attachment; filename="hello.txt"`, obj.header.Get("Content-Disposition"))
+		assert.Equal(t, "team-a", obj.header.Get("X-Amz-Meta-Owner"))
+	})
+
+	t.Run("invalid metadata name", func(t *testing.T) {
+		_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("hello"),
+			Metadata: map[string]string{
+				"key":               "obj2",
+				"metadata.my owner": "team-a",
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("get with metadata", func(t *testing.T) {
+		res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata: map[string]string{
+				"key":             "obj",
+				"includeMetadata": "true",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res.Data))
+		assert.Equal(t, map[string]string{
+			"contentType":        "text/plain",
+			"cacheControl":       "max-age=60",
+			"contentDisposition": `attachment; filename="hello.txt"`,
+			"metadata.Owner":     "team-a",
+		}, res.Metadata)
+	})
+
+	t.Run("get without metadata", func(t *testing.T) {
+		res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata: map[string]string{
+				"key": "obj",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res.Data))
+		assert.Nil(t, res.Metadata)
+	})
+
+	t.Run("list with metadata", func(t *testing.T) {
+		res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Data:      []byte(`{"includeMetadata": true}`),
+		})
+		require.NoError(t, err)
+
+		var list map[string]any
+		require.NoError(t, json.Unmarshal(res.Data, &list))
+		require.Len(t, list["Contents"], 1)
+		assert.Equal(t, map[string]any{
+			"obj": map[string]any{
+				"contentType":        "text/plain",
+				"cacheControl":       "max-age=60",
+				"contentDisposition": `attachment; filename="hello.txt"`,
+				"metadata.Owner":     "team-a",
+			},
+		}, list["ObjectMetadata"])
+	})
+
+	t.Run("list without metadata", func(t *testing.T) {
+		res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Data:      []byte(`{}`),
+		})
+		require.NoError(t, err)
+
+		var list map[string]any
+		require.NoError(t, json.Unmarshal(res.Data, &list))
+		require.Len(t, list["Contents"], 1)
+		assert.NotContains(t, list, "ObjectMetadata")
+	})
+}
@@ -44,15 +44,20 @@ type AWSSES struct {
 }
 
 type sesMetadata struct {
-	Region       string `json:"region"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
-	EmailFrom    string `json:"emailFrom"`
-	EmailTo      string `json:"emailTo"`
-	Subject      string `json:"subject"`
-	EmailCc      string `json:"emailCc"`
-	EmailBcc     string `json:"emailBcc"`
+	Region              string `json:"region"`
+	AccessKey           string `json:"accessKey"`
+	SecretKey           string `json:"secretKey"`
+	SessionToken        string `json:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn"`
+	SessionName         string `json:"sessionName"`
+	ExternalID          string `json:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions"`
+	EmailFrom           string `json:"emailFrom"`
+	EmailTo             string `json:"emailTo"`
+	Subject             string `json:"subject"`
+	EmailCc             string `json:"emailCc"`
+	EmailBcc            string `json:"emailBcc"`
 }
 
 // NewAWSSES creates a new AWSSES binding instance.
@@ -159,7 +164,17 @@ func (metadata sesMetadata) mergeWithRequestMetadata(req *bindings.InvokeRequest
 }
 
 func (a *AWSSES) getClient(metadata *sesMetadata) (*ses.SES, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, "")
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("SES binding error: error creating AWS session %w", err)
 	}
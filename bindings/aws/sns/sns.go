@@ -36,12 +36,17 @@ type AWSSNS struct {
 }
 
 type snsMetadata struct {
-	TopicArn     string `json:"topicArn"`
-	Region       string `json:"region"`
-	Endpoint     string `json:"endpoint"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
+	TopicArn            string `json:"topicArn"`
+	Region              string `json:"region"`
+	Endpoint            string `json:"endpoint"`
+	AccessKey           string `json:"accessKey"`
+	SecretKey           string `json:"secretKey"`
+	SessionToken        string `json:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn"`
+	SessionName         string `json:"sessionName"`
+	ExternalID          string `json:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions"`
 }
 
 type dataPayload struct {
@@ -81,7 +86,18 @@ func (a *AWSSNS) parseMetadata(meta bindings.Metadata) (*snsMetadata, error) {
 }
 
 func (a *AWSSNS) getClient(metadata *snsMetadata) (*sns.SNS, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		Endpoint:            metadata.Endpoint,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, err
 	}
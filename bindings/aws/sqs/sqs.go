@@ -22,7 +22,9 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/cenkalti/backoff/v4"
 
 	"github.com/dapr/components-contrib/bindings"
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
@@ -30,11 +32,20 @@ import (
 	"github.com/dapr/kit/logger"
 )
 
+var _ bindings.ReadBufferMetricsSetter = (*AWSSQS)(nil)
+
 // AWSSQS allows receiving and sending data to/from AWS SQS.
 type AWSSQS struct {
 	Client   *sqs.SQS
 	QueueURL *string
 
+	readRetryPolicy bindings.ReadRetryPolicy
+
+	// Buffer between receiving messages and delivering them to the app
+	name          string
+	readBuffer    bindings.ReadBufferSettings
+	bufferMetrics bindings.ReadBufferMetricsRecorder
+
 	logger  logger.Logger
 	wg      sync.WaitGroup
 	closeCh chan struct{}
@@ -42,12 +53,17 @@ type AWSSQS struct {
 }
 
 type sqsMetadata struct {
-	QueueName    string `json:"queueName"`
-	Region       string `json:"region"`
-	Endpoint     string `json:"endpoint"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
+	QueueName           string `json:"queueName"`
+	Region              string `json:"region"`
+	Endpoint            string `json:"endpoint"`
+	AccessKey           string `json:"accessKey"`
+	SecretKey           string `json:"secretKey"`
+	SessionToken        string `json:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn"`
+	SessionName         string `json:"sessionName"`
+	ExternalID          string `json:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions"`
 }
 
 // NewAWSSQS returns a new AWS SQS instance.
@@ -65,6 +81,16 @@ func (a *AWSSQS) Init(ctx context.Context, metadata bindings.Metadata) error {
 		return err
 	}
 
+	a.readRetryPolicy, err = bindings.ParseReadRetryPolicy(metadata.Properties)
+	if err != nil {
+		return err
+	}
+	a.readBuffer, err = bindings.ParseReadBufferSettings(metadata.Properties)
+	if err != nil {
+		return err
+	}
+	a.name = metadata.Name
+
 	client, err := a.getClient(m)
 	if err != nil {
 		return err
@@ -98,66 +124,108 @@ func (a *AWSSQS) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bind
 	return nil, err
 }
 
+// SetReadBufferMetricsRecorder sets the recorder of the utilization of the read buffer.
+func (a *AWSSQS) SetReadBufferMetricsRecorder(recorder bindings.ReadBufferMetricsRecorder) {
+	a.bufferMetrics = recorder
+}
+
 func (a *AWSSQS) Read(ctx context.Context, handler bindings.Handler) error {
 	if a.closed.Load() {
 		return errors.New("binding is closed")
 	}
 
-	a.wg.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	a.wg.Add(2)
+	go func() {
+		defer a.wg.Done()
+		// Stop when the binding is closed
+		select {
+		case <-ctx.Done():
+		case <-a.closeCh:
+			cancel()
+		}
+	}()
 	go func() {
 		defer a.wg.Done()
+		defer cancel()
+
+		buffer := bindings.NewReadBuffer(ctx, a.readBuffer, handler, a.bufferMetrics, a.name)
+		defer buffer.Wait()
 
 		// Repeat until the context is canceled or component is closed
-		for {
-			if ctx.Err() != nil || a.closed.Load() {
-				return
-			}
+		err := bindings.RunReadLoop(ctx, a.readRetryPolicy, func(ctx context.Context) error {
+			return a.receive(ctx, buffer)
+		}, func(err error, delay time.Duration) {
+			a.logger.Errorf("Unable to receive message from queue %q, retrying in %s: %v", *a.QueueURL, delay, err)
+		})
+		if err != nil {
+			a.logger.Errorf("Stopped receiving messages from queue %q: %v", *a.QueueURL, err)
+		}
+	}()
 
-			result, err := a.Client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
-				QueueUrl: a.QueueURL,
-				AttributeNames: aws.StringSlice([]string{
-					"SentTimestamp",
-				}),
-				MaxNumberOfMessages: aws.Int64(1),
-				MessageAttributeNames: aws.StringSlice([]string{
-					"All",
-				}),
-				WaitTimeSeconds: aws.Int64(20),
-			})
-			if err != nil {
-				a.logger.Errorf("Unable to receive message from queue %q, %v.", *a.QueueURL, err)
-			}
+	return nil
+}
 
-			if len(result.Messages) > 0 {
-				for _, m := range result.Messages {
-					body := m.Body
-					res := bindings.ReadResponse{
-						Data: []byte(*body),
-					}
-					_, err := handler(ctx, &res)
-					if err == nil {
-						msgHandle := m.ReceiptHandle
-
-						// Use a background context here because ctx may be canceled already
-						a.Client.DeleteMessageWithContext(context.Background(), &sqs.DeleteMessageInput{
-							QueueUrl:      a.QueueURL,
-							ReceiptHandle: msgHandle,
-						})
-					}
-				}
-			}
+// receive receives a message from the queue and adds it to the buffer, which deletes it once it's processed successfully.
+// It blocks while the buffer is full, so no more messages are received until the app catches up.
+func (a *AWSSQS) receive(ctx context.Context, buffer *bindings.ReadBuffer) error {
+	result, err := a.Client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl: a.QueueURL,
+		AttributeNames: aws.StringSlice([]string{
+			"SentTimestamp",
+		}),
+		MaxNumberOfMessages: aws.Int64(1),
+		MessageAttributeNames: aws.StringSlice([]string{
+			"All",
+		}),
+		WaitTimeSeconds: aws.Int64(20),
+	})
+	if err != nil {
+		if isPermanentError(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
 
-			select {
-			case <-ctx.Done():
-			case <-a.closeCh:
-			case <-time.After(time.Millisecond * 50):
+	for _, m := range result.Messages {
+		body := m.Body
+		res := bindings.ReadResponse{
+			Data: []byte(*body),
+		}
+		msgHandle := m.ReceiptHandle
+		// Messages that aren't deleted become visible again after the visibility timeout of the queue
+		err = buffer.Enqueue(ctx, &res, func(_ []byte, err error) {
+			if err != nil {
+				return
 			}
+			// Use a background context here because ctx may be canceled already
+			a.Client.DeleteMessageWithContext(context.Background(), &sqs.DeleteMessageInput{
+				QueueUrl:      a.QueueURL,
+				ReceiptHandle: msgHandle,
+			})
+		})
+		if err != nil {
+			return err
 		}
-	}()
+	}
 
 	return nil
 }
 
+// isPermanentError returns true for errors caused by the configuration, which retrying doesn't solve.
+func isPermanentError(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	switch awsErr.Code() {
+	case sqs.ErrCodeQueueDoesNotExist, "AccessDenied", "AccessDeniedException", "InvalidClientTokenId", "UnrecognizedClientException", "SignatureDoesNotMatch":
+		return true
+	default:
+		return false
+	}
+}
+
 func (a *AWSSQS) Close() error {
 	if a.closed.CompareAndSwap(false, true) {
 		close(a.closeCh)
@@ -177,7 +245,18 @@ func (a *AWSSQS) parseSQSMetadata(meta bindings.Metadata) (*sqsMetadata, error)
 }
 
 func (a *AWSSQS) getClient(metadata *sqsMetadata) (*sqs.SQS, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		Endpoint:            metadata.Endpoint,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, err
 	}
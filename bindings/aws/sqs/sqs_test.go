@@ -14,8 +14,12 @@ limitations under the License.
 package sqs
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/dapr/components-contrib/bindings"
@@ -36,3 +40,10 @@ func TestParseMetadata(t *testing.T) {
 	assert.Equal(t, "a", sqsM.Endpoint)
 	assert.Equal(t, "t", sqsM.SessionToken)
 }
+
+func TestIsPermanentError(t *testing.T) {
+	assert.True(t, isPermanentError(awserr.New(sqs.ErrCodeQueueDoesNotExist, "queue does not exist", nil)))
+	assert.True(t, isPermanentError(fmt.Errorf("wrapped: %w", awserr.New("AccessDenied", "denied", nil))))
+	assert.False(t, isPermanentError(awserr.New("RequestError", "send request failed", nil)))
+	assert.False(t, isPermanentError(errors.New("generic")))
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
@@ -31,6 +32,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	storageinternal "github.com/dapr/components-contrib/internal/component/azure/blobstorage"
+	"github.com/dapr/components-contrib/internal/component/blobmetadata"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
@@ -125,6 +127,20 @@ func (a *AzureBlobStorage) create(ctx context.Context, req *bindings.InvokeReque
 		blobName = id.String()
 	}
 
+	// Other keys are stored as metadata as-is, while user-defined metadata can also be set with the prefix shared by all blob bindings
+	_, userMetadata, err := blobmetadata.ParseRequest(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	for k := range req.Metadata {
+		if strings.HasPrefix(strings.ToLower(k), blobmetadata.UserMetadataPrefix) {
+			delete(req.Metadata, k)
+		}
+	}
+	for k, v := range userMetadata {
+		req.Metadata[k] = v
+	}
+
 	blobHTTPHeaders, err := storageinternal.CreateBlobHTTPHeadersFromRequest(req.Metadata, nil, a.logger)
 	if err != nil {
 		return nil, err
@@ -185,6 +201,14 @@ func (a *AzureBlobStorage) get(ctx context.Context, req *bindings.InvokeRequest)
 	downloadOptions := azblob.DownloadStreamOptions{
 		AccessConditions: &blob.AccessConditions{},
 	}
+	rangeVal := req.Metadata[blobmetadata.RangeKey]
+	if rangeVal != "" {
+		rng, err := blobmetadata.ParseRange(rangeVal)
+		if err != nil {
+			return nil, err
+		}
+		downloadOptions.Range = blob.HTTPRange{Offset: rng.Offset, Count: rng.Count}
+	}
 
 	blobDownloadResponse, err := blockBlobClient.DownloadStream(ctx, &downloadOptions)
 	if err != nil {
@@ -213,15 +237,36 @@ func (a *AzureBlobStorage) get(ctx context.Context, req *bindings.InvokeRequest)
 			return nil, fmt.Errorf("error reading blob metadata: %w", err)
 		}
 
-		if len(props.Metadata) > 0 {
-			metadata = make(map[string]string, len(props.Metadata))
-			for k, v := range props.Metadata {
-				if v == nil {
-					continue
-				}
-				metadata[k] = *v
+		metadata = make(map[string]string, len(props.Metadata)+5)
+		for k, v := range props.Metadata {
+			if v == nil {
+				continue
 			}
+			metadata[k] = *v
 		}
+		blobmetadata.AddHeaders(metadata, blobmetadata.Headers{
+			ContentType:        derefString(props.ContentType),
+			ContentEncoding:    derefString(props.ContentEncoding),
+			ContentLanguage:    derefString(props.ContentLanguage),
+			ContentDisposition: derefString(props.ContentDisposition),
+			CacheControl:       derefString(props.CacheControl),
+		})
+	}
+
+	if rangeVal != "" {
+		if metadata == nil {
+			metadata = make(map[string]string, 2)
+		}
+		contentRange := derefString(blobDownloadResponse.ContentRange)
+		// The SDK doesn't send a range starting at 0 without an end, so the whole blob is downloaded
+		if contentRange == "" && blobDownloadResponse.ContentLength != nil {
+			if size := *blobDownloadResponse.ContentLength; size > 0 {
+				contentRange = fmt.Sprintf("bytes 0-%d/%d", size-1, size)
+			} else {
+				contentRange = "bytes */0"
+			}
+		}
+		blobmetadata.AddRange(metadata, contentRange)
 	}
 
 	return &bindings.InvokeResponse{
@@ -230,6 +275,13 @@ func (a *AzureBlobStorage) get(ctx context.Context, req *bindings.InvokeRequest)
 	}, nil
 }
 
+func derefString(val *string) string {
+	if val == nil {
+		return ""
+	}
+	return *val
+}
+
 func (a *AzureBlobStorage) delete(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	var blockBlobClient *blockblob.Client
 	val, ok := req.Metadata[metadataKeyBlobName]
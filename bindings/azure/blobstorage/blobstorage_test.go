@@ -56,3 +56,17 @@ func TestDeleteOption(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestCreateOption(t *testing.T) {
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test")).(*AzureBlobStorage)
+
+	t.Run("return error for invalid user-defined metadata", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":       "foo",
+			"metadata.owner": "a\r\nb",
+		}
+		_, err := blobStorage.create(context.Background(), &r)
+		assert.Error(t, err)
+	})
+}
@@ -18,14 +18,17 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue"
+	"github.com/cenkalti/backoff/v4"
 
 	"github.com/dapr/components-contrib/bindings"
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
@@ -209,8 +212,9 @@ func NewAzureQueueHelper(logger logger.Logger) QueueHelper {
 
 // AzureStorageQueues is an input/output binding reading from and sending events to Azure Storage queues.
 type AzureStorageQueues struct {
-	metadata *storageQueuesMetadata
-	helper   QueueHelper
+	metadata        *storageQueuesMetadata
+	helper          QueueHelper
+	readRetryPolicy bindings.ReadRetryPolicy
 
 	logger logger.Logger
 
@@ -257,6 +261,11 @@ func (a *AzureStorageQueues) Init(ctx context.Context, metadata bindings.Metadat
 		return err
 	}
 
+	a.readRetryPolicy, err = bindings.ParseReadRetryPolicy(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -349,18 +358,32 @@ func (a *AzureStorageQueues) Read(ctx context.Context, handler bindings.Handler)
 	go func() {
 		defer a.wg.Done()
 		// Read until context is canceled
-		var err error
-		for readCtx.Err() == nil {
-			err = a.helper.Read(readCtx, &c)
-			if err != nil {
-				a.logger.Errorf("error from c: %s", err)
+		err := bindings.RunReadLoop(readCtx, a.readRetryPolicy, func(ctx context.Context) error {
+			err := a.helper.Read(ctx, &c)
+			if isPermanentError(err) {
+				return backoff.Permanent(err)
 			}
+			return err
+		}, func(err error, delay time.Duration) {
+			a.logger.Errorf("Error reading from queue, retrying in %s: %v", delay, err)
+		})
+		if err != nil {
+			a.logger.Errorf("Stopped reading from queue: %v", err)
 		}
 	}()
 
 	return nil
 }
 
+// isPermanentError returns true for errors caused by the configuration, such as a missing queue or invalid credentials, which retrying doesn't solve.
+func isPermanentError(err error) bool {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	return respErr.StatusCode == http.StatusNotFound || respErr.StatusCode == http.StatusForbidden || respErr.StatusCode == http.StatusUnauthorized
+}
+
 func (a *AzureStorageQueues) Close() error {
 	if a.closed.CompareAndSwap(false, true) {
 		close(a.closeCh)
@@ -16,10 +16,14 @@ package storagequeues
 import (
 	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -444,3 +448,11 @@ func TestParseMetadataWithInvalidTTL(t *testing.T) {
 		})
 	}
 }
+
+func TestIsPermanentError(t *testing.T) {
+	assert.True(t, isPermanentError(&azcore.ResponseError{StatusCode: http.StatusNotFound}))
+	assert.True(t, isPermanentError(fmt.Errorf("wrapped: %w", &azcore.ResponseError{StatusCode: http.StatusForbidden})))
+	assert.False(t, isPermanentError(&azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}))
+	assert.False(t, isPermanentError(errors.New("generic")))
+	assert.False(t, isPermanentError(nil))
+}
@@ -17,12 +17,16 @@ import (
 	"bytes"
 	"context"
 	b64 "encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"net/url"
 	"reflect"
 	"strconv"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/google/uuid"
@@ -30,6 +34,7 @@ import (
 	"google.golang.org/api/option"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/blobmetadata"
 	"github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
@@ -46,6 +51,8 @@ const (
 	metadataKeyBC = "name"
 )
 
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
 // GCPStorage allows saving data to GCP bucket storage.
 type GCPStorage struct {
 	metadata *gcpMetadata
@@ -67,6 +74,12 @@ type gcpMetadata struct {
 	ClientCertURL       string `json:"client_x509_cert_url" mapstructure:"client_x509_cert_url"`
 	DecodeBase64        bool   `json:"decodeBase64,string" mapstructure:"decodeBase64"`
 	EncodeBase64        bool   `json:"encodeBase64,string" mapstructure:"encodeBase64"`
+
+	// Size, in bytes, of the chunks of resumable uploads; rounded up to a multiple of 256KiB.
+	// If a chunk fails to upload, only that chunk is retried. Set to 0 to upload objects in a single request, which is not resumable.
+	UploadChunkSize *int `json:"-" mapstructure:"uploadChunkSize"`
+	// Maximum time spent retrying the upload of each chunk.
+	UploadChunkRetryDeadline time.Duration `json:"-" mapstructure:"uploadChunkRetryDeadline"`
 }
 
 type listPayload struct {
@@ -77,6 +90,10 @@ type listPayload struct {
 
 type createResponse struct {
 	ObjectURL string `json:"objectURL"`
+	// Generation of the object that was created.
+	Generation int64 `json:"generation"`
+	// CRC32C checksum of the object, base64-encoded in big-endian order as in the Cloud Storage API.
+	CRC32C string `json:"crc32c"`
 }
 
 // NewGCPStorage returns a new GCP storage instance.
@@ -115,6 +132,10 @@ func (g *GCPStorage) parseMetadata(meta bindings.Metadata) (*gcpMetadata, error)
 		return nil, err
 	}
 
+	if m.UploadChunkSize != nil && *m.UploadChunkSize < 0 {
+		return nil, errors.New("gcp bucket binding error: 'uploadChunkSize' must not be negative")
+	}
+
 	return &m, nil
 }
 
@@ -164,14 +185,32 @@ func (g *GCPStorage) create(ctx context.Context, req *bindings.InvokeRequest) (*
 		req.Data = []byte(d)
 	}
 
-	var r io.Reader = bytes.NewReader(req.Data)
+	data := req.Data
 	if metadata.DecodeBase64 {
-		r = b64.NewDecoder(b64.StdEncoding, r)
+		data, err = io.ReadAll(b64.NewDecoder(b64.StdEncoding, bytes.NewReader(req.Data)))
+		if err != nil {
+			return nil, fmt.Errorf("gcp bucket binding error. decoding base64 data: %w", err)
+		}
 	}
 
-	h := g.client.Bucket(g.metadata.Bucket).Object(name).NewWriter(ctx)
-	defer h.Close()
-	if _, err = io.Copy(h, r); err != nil {
+	headers, userMetadata, err := blobmetadata.ParseRequest(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. %w", err)
+	}
+
+	h := g.newWriter(ctx, name, data)
+	h.ContentType = headers.ContentType
+	h.ContentEncoding = headers.ContentEncoding
+	h.ContentLanguage = headers.ContentLanguage
+	h.ContentDisposition = headers.ContentDisposition
+	h.CacheControl = headers.CacheControl
+	h.Metadata = userMetadata
+	if _, err = h.Write(data); err != nil {
+		h.Close()
+		return nil, fmt.Errorf("gcp bucket binding error. Uploading: %w", err)
+	}
+	// The upload is completed, and the object is created, when the writer is closed
+	if err = h.Close(); err != nil {
 		return nil, fmt.Errorf("gcp bucket binding error. Uploading: %w", err)
 	}
 
@@ -180,8 +219,11 @@ func (g *GCPStorage) create(ctx context.Context, req *bindings.InvokeRequest) (*
 		return nil, fmt.Errorf("gcp bucket binding error. error building url response: %w", err)
 	}
 
+	attrs := h.Attrs()
 	resp := createResponse{
-		ObjectURL: objectURL.String(),
+		ObjectURL:  objectURL.String(),
+		Generation: attrs.Generation,
+		CRC32C:     encodeCRC32C(attrs.CRC32C),
 	}
 
 	b, err := json.Marshal(resp)
@@ -194,6 +236,27 @@ func (g *GCPStorage) create(ctx context.Context, req *bindings.InvokeRequest) (*
 	}, nil
 }
 
+// newWriter returns a writer for the object, which uses a resumable upload unless chunking is disabled.
+// The CRC32C checksum of the data is sent with the upload, so the service rejects objects that were corrupted in transit.
+func (g *GCPStorage) newWriter(ctx context.Context, name string, data []byte) *storage.Writer {
+	h := g.client.Bucket(g.metadata.Bucket).Object(name).NewWriter(ctx)
+	if g.metadata.UploadChunkSize != nil {
+		h.ChunkSize = *g.metadata.UploadChunkSize
+	}
+	if g.metadata.UploadChunkRetryDeadline > 0 {
+		h.ChunkRetryDeadline = g.metadata.UploadChunkRetryDeadline
+	}
+	h.CRC32C = crc32.Checksum(data, crc32cTable)
+	h.SendCRC32C = true
+	return h
+}
+
+func encodeCRC32C(sum uint32) string {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, sum)
+	return b64.StdEncoding.EncodeToString(b)
+}
+
 func (g *GCPStorage) get(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	metadata, err := g.metadata.mergeWithRequestMetadata(req)
 	if err != nil {
@@ -207,8 +270,27 @@ func (g *GCPStorage) get(ctx context.Context, req *bindings.InvokeRequest) (*bin
 		return nil, fmt.Errorf("gcp bucket binding error: can't read key value")
 	}
 
+	obj := g.client.Bucket(g.metadata.Bucket).Object(key)
+
+	var respMetadata map[string]string
+	if utils.IsTruthy(req.Metadata[blobmetadata.IncludeMetadataKey]) {
+		attrs, attrsErr := obj.Attrs(ctx)
+		if attrsErr != nil {
+			return nil, fmt.Errorf("gcp bucket binding error: error reading bucket object metadata: %w", attrsErr)
+		}
+		respMetadata = blobmetadata.ResponseMetadata(blobmetadata.Headers{
+			ContentType:        attrs.ContentType,
+			ContentEncoding:    attrs.ContentEncoding,
+			ContentLanguage:    attrs.ContentLanguage,
+			ContentDisposition: attrs.ContentDisposition,
+			CacheControl:       attrs.CacheControl,
+		}, attrs.Metadata)
+		// Ensure the object that is downloaded is the one whose metadata was read
+		obj = obj.Generation(attrs.Generation)
+	}
+
 	var rc io.ReadCloser
-	rc, err = g.client.Bucket(g.metadata.Bucket).Object(key).NewReader(ctx)
+	rc, err = obj.NewReader(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("gcp bucketgcp bucket binding error: error downloading bucket object: %w", err)
 	}
@@ -226,7 +308,7 @@ func (g *GCPStorage) get(ctx context.Context, req *bindings.InvokeRequest) (*bin
 
 	return &bindings.InvokeResponse{
 		Data:     data,
-		Metadata: nil,
+		Metadata: respMetadata,
 	}, nil
 }
 
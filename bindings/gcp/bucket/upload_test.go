@@ -0,0 +1,239 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+// fakeResumableServer implements the parts of the resumable upload protocol of Cloud Storage used by the client.
+// The first attempt at uploading the chunk at failOffset fails with a transient error.
+type fakeResumableServer struct {
+	lock          sync.Mutex
+	failOffset    int64
+	failed        bool
+	data          []byte
+	sentCRC32C    string
+	attrs         map[string]any
+	chunkRequests []string
+}
+
+func (f *fakeResumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Query().Get("uploadType") == "resumable":
+		_ = json.NewDecoder(r.Body).Decode(&f.attrs)
+		f.sentCRC32C, _ = f.attrs["crc32c"].(string)
+		w.Header().Set("Location", "http://"+r.Host+"/session/1")
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/session/1":
+		contentRange := r.Header.Get("Content-Range")
+		f.chunkRequests = append(f.chunkRequests, contentRange)
+		body, _ := io.ReadAll(r.Body)
+
+		// Content-Range is "bytes start-end/total", where total is "*" until the last chunk
+		var start, end int64
+		var total string
+		_, err := fmt.Sscanf(strings.Replace(contentRange, "/", " ", 1), "bytes %d-%d %s", &start, &end, &total)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if start == f.failOffset && !f.failed {
+			f.failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if start != int64(len(f.data)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.data = append(f.data, body...)
+
+		if total == "*" {
+			// The client asks for a 200 response with this header, instead of a 308 response
+			w.Header().Set("X-Http-Status-Code-Override", "308")
+			w.Header().Set("Range", "bytes=0-"+strconv.Itoa(len(f.data)-1))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		f.writeAttrs(w)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/my_bucket/o/my_object":
+		f.writeAttrs(w)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/my_bucket/my_object":
+		w.Header().Set("X-Goog-Generation", "1680000000000001")
+		w.Write(f.data)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeResumableServer) writeAttrs(w http.ResponseWriter) {
+	res := map[string]any{
+		"bucket":     "my_bucket",
+		"name":       "my_object",
+		"size":       strconv.Itoa(len(f.data)),
+		"generation": "1680000000000001",
+		"crc32c":     encodeCRC32C(crc32.Checksum(f.data, crc32cTable)),
+	}
+	for _, k := range []string{"contentType", "cacheControl", "contentDisposition", "metadata"} {
+		if v, ok := f.attrs[k]; ok {
+			res[k] = v
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func TestResumableUpload(t *testing.T) {
+	fake := &fakeResumableServer{failOffset: 256 * 1024}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client, err := storage.NewClient(context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	gs := GCPStorage{
+		logger: logger.NewLogger("test"),
+		client: client,
+		metadata: &gcpMetadata{
+			Bucket:          "my_bucket",
+			UploadChunkSize: ptr.Of(256 * 1024),
+		},
+	}
+
+	data := bytes.Repeat([]byte("0123456789"), 60*1024)
+	res, err := gs.create(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      data,
+		Metadata: map[string]string{
+			"key":            "my_object",
+			"contentType":    "text/plain",
+			"cacheControl":   "no-cache",
+			"metadata.owner": "team-a",
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, data, fake.data)
+	assert.Equal(t, "text/plain", fake.attrs["contentType"])
+	assert.Equal(t, "no-cache", fake.attrs["cacheControl"])
+	assert.Equal(t, map[string]any{"owner": "team-a"}, fake.attrs["metadata"])
+	assert.Equal(t, encodeCRC32C(crc32.Checksum(data, crc32cTable)), fake.sentCRC32C)
+	// The failed chunk is retried in the same session, without uploading the first chunk again
+	assert.Equal(t, []string{
+		"bytes 0-262143/*",
+		"bytes 262144-524287/*",
+		"bytes 262144-524287/*",
+		"bytes 524288-614399/614400",
+	}, fake.chunkRequests)
+
+	var resp createResponse
+	require.NoError(t, json.Unmarshal(res.Data, &resp))
+	assert.Equal(t, "https://storage.googleapis.com/my_bucket/my_object", resp.ObjectURL)
+	assert.Equal(t, int64(1680000000000001), resp.Generation)
+	assert.Equal(t, encodeCRC32C(crc32.Checksum(data, crc32cTable)), resp.CRC32C)
+
+	t.Run("get with metadata", func(t *testing.T) {
+		res, err := gs.get(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata: map[string]string{
+				"key":             "my_object",
+				"includeMetadata": "true",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, data, res.Data)
+		assert.Equal(t, map[string]string{
+			"contentType":    "text/plain",
+			"cacheControl":   "no-cache",
+			"metadata.owner": "team-a",
+		}, res.Metadata)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		_, err := gs.create(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      data,
+			Metadata: map[string]string{
+				"key":          "my_object",
+				"metadata.a:b": "x",
+			},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestParseUploadMetadata(t *testing.T) {
+	gs := GCPStorage{logger: logger.NewLogger("test")}
+
+	t.Run("chunk size", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"bucket":                   "my_bucket",
+			"uploadChunkSize":          "1048576",
+			"uploadChunkRetryDeadline": "1m",
+		}
+		meta, err := gs.parseMetadata(m)
+		require.NoError(t, err)
+		require.NotNil(t, meta.UploadChunkSize)
+		assert.Equal(t, 1048576, *meta.UploadChunkSize)
+		assert.Equal(t, "1m0s", meta.UploadChunkRetryDeadline.String())
+
+		// Upload settings are not part of the credentials
+		b, err := json.Marshal(meta)
+		require.NoError(t, err)
+		assert.NotContains(t, string(b), "1048576")
+	})
+
+	t.Run("negative chunk size", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"bucket":          "my_bucket",
+			"uploadChunkSize": "-1",
+		}
+		_, err := gs.parseMetadata(m)
+		require.Error(t, err)
+	})
+}
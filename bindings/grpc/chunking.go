@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+// Maximum size added to an encoded message by each field on the path to the chunked field: its tag and length, which are varints of up to 5 bytes each.
+const chunkFieldOverhead = 10
+
+// chunkMessage splits the value of a bytes field of the message in chunks, so each message fits in maxSize bytes once encoded.
+// The path of the field is a list of field names separated by dots, such as "payload.body"; all other fields are repeated in each chunk.
+// Servers reassemble the value by concatenating the chunks in the order they are received.
+func chunkMessage(in proto.Message, path string, maxSize int) ([]proto.Message, error) {
+	names := strings.Split(path, ".")
+
+	base := proto.Clone(in)
+	parent, fd, err := chunkField(base.ProtoReflect(), names)
+	if err != nil {
+		return nil, err
+	}
+	data := parent.Get(fd).Bytes()
+	parent.Clear(fd)
+
+	chunkSize := maxSize - proto.Size(base) - len(names)*chunkFieldOverhead
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("%w: the fields of the message other than '%s' do not fit in %d bytes", bindings.ErrMessageTooLarge, path, maxSize)
+	}
+
+	chunks := make([]proto.Message, 0, len(data)/chunkSize+1)
+	for {
+		n := len(data)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		chunk := proto.Clone(base)
+		// The path was already validated on the base message
+		p, f, _ := chunkField(chunk.ProtoReflect(), names)
+		p.Set(f, protoreflect.ValueOfBytes(data[:n]))
+		chunks = append(chunks, chunk)
+
+		data = data[n:]
+		if len(data) == 0 {
+			return chunks, nil
+		}
+	}
+}
+
+// chunkField returns the bytes field at the path and the message that contains it, creating the messages on the path if needed.
+func chunkField(msg protoreflect.Message, names []string) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, nil, fmt.Errorf("field '%s' not found in message %s", name, msg.Descriptor().FullName())
+		}
+		if fd.IsList() || fd.IsMap() {
+			return nil, nil, fmt.Errorf("field '%s' of message %s is repeated", name, msg.Descriptor().FullName())
+		}
+		if i == len(names)-1 {
+			if fd.Kind() != protoreflect.BytesKind {
+				return nil, nil, fmt.Errorf("field '%s' of message %s is not a bytes field", name, msg.Descriptor().FullName())
+			}
+			return msg, fd, nil
+		}
+		if fd.Kind() != protoreflect.MessageKind {
+			return nil, nil, fmt.Errorf("field '%s' of message %s is not a message", name, msg.Descriptor().FullName())
+		}
+		msg = msg.Mutable(fd).Message()
+	}
+	return nil, nil, fmt.Errorf("invalid field path '%s'", strings.Join(names, "."))
+}
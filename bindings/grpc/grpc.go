@@ -0,0 +1,409 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// InvokeOperation invokes a unary or server-streaming gRPC method.
+	InvokeOperation bindings.OperationKind = "invoke"
+
+	// keys from request's metadata.
+	methodKey      = "method"
+	maxMessagesKey = "maxMessages"
+	chunkFieldKey  = "chunkField"
+	headerPrefix   = "header:"
+
+	// Header sent to the server with the number of chunks of a chunked request.
+	chunkCountHeader = "dapr-chunk-count"
+
+	// keys from response's metadata.
+	respMethodKey       = "method"
+	respStreamingKey    = "streaming"
+	respMessageCountKey = "messageCount"
+	respTruncatedKey    = "truncated"
+	respChunkCountKey   = "chunkCount"
+
+	defaultMaxStreamMessages = 100
+	defaultStreamEndWait     = 100 * time.Millisecond
+	// Default maximum size of messages received by gRPC servers, used to split chunked requests when 'maxMessageSize' is not set.
+	defaultChunkMessageSize = 4 * 1024 * 1024
+)
+
+type grpcMetadata struct {
+	// Address of the target gRPC server, in the "host:port" format.
+	Address string `mapstructure:"address"`
+	// If true, connects to the server using TLS.
+	UseTLS bool `mapstructure:"useTLS"`
+	// Maximum number of messages collected from a server-streaming method.
+	MaxStreamMessages int `mapstructure:"maxStreamMessages"`
+	// After the maximum number of messages is received from a stream, how long to wait for another message, which means the stream is truncated, or for the end of the stream.
+	// If zero, streams are reported as truncated as soon as the maximum is reached.
+	StreamEndWait time.Duration `mapstructure:"streamEndWait"`
+	// Timeout for each invocation. Defaults to no timeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Maximum size in bytes of messages sent and received. Defaults to the limits of gRPC.
+	MaxMessageSize int `mapstructure:"maxMessageSize"`
+}
+
+// GRPC is an output binding that invokes arbitrary gRPC methods, resolving them via server reflection.
+type GRPC struct {
+	metadata grpcMetadata
+	conn     *grpc.ClientConn
+	resolver *methodResolver
+	callOpts []grpc.CallOption
+	logger   logger.Logger
+}
+
+// NewGRPC returns a new gRPC output binding.
+func NewGRPC(logger logger.Logger) bindings.OutputBinding {
+	return &GRPC{logger: logger}
+}
+
+// Init performs metadata parsing and connects to the server.
+func (g *GRPC) Init(_ context.Context, meta bindings.Metadata) error {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	g.metadata = m
+
+	var creds credentials.TransportCredentials
+	if m.UseTLS {
+		creds = credentials.NewTLS(&tls.Config{
+			MinVersion: tls.VersionTLS12,
+		})
+	} else {
+		creds = insecure.NewCredentials()
+	}
+
+	// The maximum message size only applies to the invoked methods, and not to the server reflection calls
+	if m.MaxMessageSize > 0 {
+		g.callOpts = []grpc.CallOption{
+			grpc.MaxCallRecvMsgSize(m.MaxMessageSize),
+			grpc.MaxCallSendMsgSize(m.MaxMessageSize),
+		}
+	}
+
+	g.conn, err = grpc.Dial(m.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("failed to connect to '%s': %w", m.Address, err)
+	}
+	g.resolver = newMethodResolver(g.conn)
+
+	return nil
+}
+
+func parseMetadata(meta bindings.Metadata) (grpcMetadata, error) {
+	m := grpcMetadata{
+		MaxStreamMessages: defaultMaxStreamMessages,
+		StreamEndWait:     defaultStreamEndWait,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.Address == "" {
+		return m, errors.New("metadata property 'address' is required")
+	}
+	if m.MaxStreamMessages <= 0 {
+		return m, errors.New("metadata property 'maxStreamMessages' must be greater than 0")
+	}
+	if m.MaxMessageSize < 0 {
+		return m, errors.New("metadata property 'maxMessageSize' must not be negative")
+	}
+	if m.StreamEndWait < 0 {
+		return m, errors.New("metadata property 'streamEndWait' must not be negative")
+	}
+
+	return m, nil
+}
+
+// Operations returns the list of operations supported by the binding.
+func (g *GRPC) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{InvokeOperation}
+}
+
+// Invoke calls the gRPC method named in the request's metadata, using the request's data as JSON-encoded message.
+func (g *GRPC) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Operation != InvokeOperation {
+		return nil, fmt.Errorf("invalid operation type: %s. Expected %s", req.Operation, InvokeOperation)
+	}
+
+	method := req.Metadata[methodKey]
+	if method == "" {
+		return nil, fmt.Errorf("required metadata '%s' not set", methodKey)
+	}
+
+	maxMessages := g.metadata.MaxStreamMessages
+	if val := req.Metadata[maxMessagesKey]; val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid value for metadata '%s': %s", maxMessagesKey, val)
+		}
+		maxMessages = n
+	}
+
+	if g.metadata.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.metadata.Timeout)
+		defer cancel()
+	}
+	ctx = withOutgoingHeaders(ctx, req.Metadata)
+
+	md, err := g.resolver.Resolve(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	chunkPath := req.Metadata[chunkFieldKey]
+	if chunkPath != "" && (!md.IsStreamingClient() || md.IsStreamingServer()) {
+		return nil, fmt.Errorf("metadata '%s' requires a method that uses client streaming and returns a single message", chunkFieldKey)
+	} else if chunkPath == "" && md.IsStreamingClient() {
+		return nil, fmt.Errorf("method '%s' uses client streaming, which is only supported for chunked requests", method)
+	}
+
+	in := dynamicpb.NewMessage(md.Input())
+	if len(req.Data) > 0 {
+		err = protojson.Unmarshal(req.Data, in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse request message as %s: %w", md.Input().FullName(), err)
+		}
+	}
+
+	fullMethod := "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+	resp := &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			respMethodKey:    fullMethod,
+			respStreamingKey: strconv.FormatBool(md.IsStreamingServer()),
+		},
+	}
+
+	if chunkPath != "" {
+		out, chunks, err := g.invokeChunked(ctx, fullMethod, md, in, chunkPath)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data, err = protojson.Marshal(out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode response message: %w", err)
+		}
+		resp.Metadata[respChunkCountKey] = strconv.Itoa(chunks)
+		return resp, nil
+	}
+
+	if g.metadata.MaxMessageSize > 0 {
+		if size := proto.Size(in); size > g.metadata.MaxMessageSize {
+			return nil, fmt.Errorf("%w: request message is %d bytes, and the maximum is %d; set '%s' to send it in chunks", bindings.ErrMessageTooLarge, size, g.metadata.MaxMessageSize, chunkFieldKey)
+		}
+	}
+
+	if !md.IsStreamingServer() {
+		out := dynamicpb.NewMessage(md.Output())
+		err = g.conn.Invoke(ctx, fullMethod, in, out, g.callOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("error invoking '%s': %w", fullMethod, sizeError(err))
+		}
+		resp.Data, err = protojson.Marshal(out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode response message: %w", err)
+		}
+		return resp, nil
+	}
+
+	messages, truncated, err := g.invokeServerStream(ctx, fullMethod, md, in, maxMessages)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data, err = json.Marshal(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response messages: %w", err)
+	}
+	resp.Metadata[respMessageCountKey] = strconv.Itoa(len(messages))
+	resp.Metadata[respTruncatedKey] = strconv.FormatBool(truncated)
+
+	return resp, nil
+}
+
+// invokeServerStream calls a server-streaming method and collects up to maxMessages responses.
+// The returned boolean is true if the server sent more messages than the maximum, which were discarded when the stream was closed.
+// Servers that keep the stream open are waited for at most streamEndWait after the maximum is reached.
+func (g *GRPC) invokeServerStream(ctx context.Context, fullMethod string, md protoreflect.MethodDescriptor, in *dynamicpb.Message, maxMessages int) ([]json.RawMessage, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	desc := &grpc.StreamDesc{
+		StreamName:    string(md.Name()),
+		ServerStreams: true,
+	}
+	stream, err := g.conn.NewStream(ctx, desc, fullMethod, g.callOpts...)
+	if err != nil {
+		return nil, false, fmt.Errorf("error invoking '%s': %w", fullMethod, err)
+	}
+	err = stream.SendMsg(in)
+	if err != nil {
+		return nil, false, fmt.Errorf("error sending message to '%s': %w", fullMethod, err)
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return nil, false, fmt.Errorf("error closing send stream for '%s': %w", fullMethod, err)
+	}
+
+	messages := make([]json.RawMessage, 0)
+	for len(messages) < maxMessages {
+		out := dynamicpb.NewMessage(md.Output())
+		err = stream.RecvMsg(out)
+		if errors.Is(err, io.EOF) {
+			return messages, false, nil
+		} else if err != nil {
+			return nil, false, fmt.Errorf("error receiving message from '%s': %w", fullMethod, sizeError(err))
+		}
+		b, err := protojson.Marshal(out)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to encode response message: %w", err)
+		}
+		messages = append(messages, b)
+	}
+
+	if g.metadata.StreamEndWait > 0 {
+		// The stream is truncated only if the server sends another message, even one that's too large
+		next := make(chan error, 1)
+		go func() {
+			next <- stream.RecvMsg(dynamicpb.NewMessage(md.Output()))
+		}()
+		select {
+		case err = <-next:
+			if errors.Is(err, io.EOF) {
+				return messages, false, nil
+			}
+			err = sizeError(err)
+			if err != nil && !errors.Is(err, bindings.ErrMessageTooLarge) {
+				return nil, false, fmt.Errorf("error receiving message from '%s': %w", fullMethod, err)
+			}
+		case <-time.After(g.metadata.StreamEndWait):
+			// The server keeps the stream open without sending more messages
+			return messages, false, nil
+		}
+	}
+
+	g.logger.Debugf("Reached the maximum of %d messages for stream '%s'; closing it", maxMessages, fullMethod)
+	return messages, true, nil
+}
+
+// invokeChunked calls a client-streaming method, sending the message in chunks that fit in the maximum message size.
+// It returns the response and the number of chunks that were sent.
+func (g *GRPC) invokeChunked(ctx context.Context, fullMethod string, md protoreflect.MethodDescriptor, in *dynamicpb.Message, chunkPath string) (*dynamicpb.Message, int, error) {
+	maxSize := g.metadata.MaxMessageSize
+	if maxSize == 0 {
+		maxSize = defaultChunkMessageSize
+	}
+	chunks, err := chunkMessage(in, chunkPath, maxSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx = grpcmd.AppendToOutgoingContext(ctx, chunkCountHeader, strconv.Itoa(len(chunks)))
+
+	desc := &grpc.StreamDesc{
+		StreamName:    string(md.Name()),
+		ClientStreams: true,
+	}
+	stream, err := g.conn.NewStream(ctx, desc, fullMethod, g.callOpts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error invoking '%s': %w", fullMethod, err)
+	}
+	for i, chunk := range chunks {
+		err = stream.SendMsg(chunk)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error sending chunk %d to '%s': %w", i, fullMethod, sizeError(err))
+		}
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error closing send stream for '%s': %w", fullMethod, err)
+	}
+
+	out := dynamicpb.NewMessage(md.Output())
+	err = stream.RecvMsg(out)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error receiving message from '%s': %w", fullMethod, sizeError(err))
+	}
+	return out, len(chunks), nil
+}
+
+// sizeError wraps errors returned by gRPC for messages that exceed the maximum size with bindings.ErrMessageTooLarge.
+func sizeError(err error) error {
+	s, ok := status.FromError(err)
+	if ok && s.Code() == codes.ResourceExhausted && strings.Contains(s.Message(), "larger than max") {
+		return fmt.Errorf("%w: %s", bindings.ErrMessageTooLarge, s.Message())
+	}
+	return err
+}
+
+func withOutgoingHeaders(ctx context.Context, reqMetadata map[string]string) context.Context {
+	pairs := make([]string, 0)
+	for k, v := range reqMetadata {
+		if strings.HasPrefix(k, headerPrefix) {
+			pairs = append(pairs, strings.TrimPrefix(k, headerPrefix), v)
+		}
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return grpcmd.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// Close closes the connection to the server.
+func (g *GRPC) Close() error {
+	if g.conn == nil {
+		return nil
+	}
+	return g.conn.Close()
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (g *GRPC) GetComponentMetadata() map[string]string {
+	metadataStruct := grpcMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
@@ -0,0 +1,402 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func startTestServer(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("mysvc", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	testpb.RegisterTestServiceServer(srv, &testService{})
+	reflection.Register(srv)
+
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+// testService reassembles chunked requests and returns payloads of the requested size.
+type testService struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (s *testService) UnaryCall(_ context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	return &testpb.SimpleResponse{
+		Payload: &testpb.Payload{Body: make([]byte, req.GetResponseSize())},
+	}, nil
+}
+
+func (s *testService) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	md, _ := grpcmd.FromIncomingContext(stream.Context())
+	if len(md.Get(chunkCountHeader)) == 0 {
+		return errors.New("missing chunk count")
+	}
+
+	var body []byte
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		body = append(body, req.GetPayload().GetBody()...)
+	}
+	// The aggregated size is the length of the reassembled body, which must only contain "a"
+	if strings.Trim(string(body), "a") != "" {
+		return errors.New("invalid body")
+	}
+	return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: int32(len(body))})
+}
+
+func (s *testService) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	for _, p := range req.GetResponseParameters() {
+		err := stream.Send(&testpb.StreamingOutputCallResponse{
+			Payload: &testpb.Payload{Body: make([]byte, p.GetSize())},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func initBinding(t *testing.T, props map[string]string) *GRPC {
+	t.Helper()
+
+	b := NewGRPC(logger.NewLogger("test")).(*GRPC)
+	err := b.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+	t.Cleanup(func() { b.Close() })
+
+	return b
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"address": "localhost:50051",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "localhost:50051", m.Address)
+		assert.Equal(t, defaultMaxStreamMessages, m.MaxStreamMessages)
+		assert.Equal(t, defaultStreamEndWait, m.StreamEndWait)
+		assert.False(t, m.UseTLS)
+	})
+
+	t.Run("streamEndWait", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"address":       "localhost:50051",
+			"streamEndWait": "0",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), m.StreamEndWait)
+
+		_, err = parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"address":       "localhost:50051",
+			"streamEndWait": "-1s",
+		}}})
+		require.Error(t, err)
+	})
+
+	t.Run("missing address", func(t *testing.T) {
+		_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{}}})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid maxStreamMessages", func(t *testing.T) {
+		_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"address":           "localhost:50051",
+			"maxStreamMessages": "0",
+		}}})
+		require.Error(t, err)
+	})
+}
+
+func TestParseMethodName(t *testing.T) {
+	tests := []struct {
+		in      string
+		service string
+		method  string
+		wantErr bool
+	}{
+		{in: "grpc.health.v1.Health/Check", service: "grpc.health.v1.Health", method: "Check"},
+		{in: "/grpc.health.v1.Health/Check", service: "grpc.health.v1.Health", method: "Check"},
+		{in: "grpc.health.v1.Health.Check", service: "grpc.health.v1.Health", method: "Check"},
+		{in: "Check", wantErr: true},
+		{in: "grpc.health.v1.Health/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			service, method, err := parseMethodName(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.service, service)
+			assert.Equal(t, tt.method, method)
+		})
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	addr := startTestServer(t)
+	b := initBinding(t, map[string]string{
+		"address": addr,
+	})
+
+	t.Run("unary", func(t *testing.T) {
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+			Data:      []byte(`{"service":"mysvc"}`),
+			Metadata: map[string]string{
+				"method": "grpc.health.v1.Health/Check",
+			},
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"status":"SERVING"}`, string(res.Data))
+		assert.Equal(t, "/grpc.health.v1.Health/Check", res.Metadata["method"])
+		assert.Equal(t, "false", res.Metadata["streaming"])
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+			Data:      []byte(`{"service":"mysvc"}`),
+			Metadata: map[string]string{
+				"method":      "grpc.health.v1.Health/Watch",
+				"maxMessages": "1",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "true", res.Metadata["streaming"])
+		assert.Equal(t, "1", res.Metadata["messageCount"])
+		// The server keeps the stream open, but doesn't send another message
+		assert.Equal(t, "false", res.Metadata["truncated"])
+
+		var messages []map[string]any
+		require.NoError(t, json.Unmarshal(res.Data, &messages))
+		require.Len(t, messages, 1)
+		assert.Equal(t, "SERVING", messages[0]["status"])
+	})
+
+	t.Run("server streaming truncation", func(t *testing.T) {
+		invoke := func(t *testing.T, maxMessages string) *bindings.InvokeResponse {
+			t.Helper()
+			res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+				Operation: InvokeOperation,
+				Data:      []byte(`{"responseParameters":[{"size":1},{"size":2}]}`),
+				Metadata: map[string]string{
+					"method":      "grpc.testing.TestService/StreamingOutputCall",
+					"maxMessages": maxMessages,
+				},
+			})
+			require.NoError(t, err)
+			return res
+		}
+
+		// The stream ends after exactly the maximum number of messages
+		res := invoke(t, "2")
+		assert.Equal(t, "2", res.Metadata["messageCount"])
+		assert.Equal(t, "false", res.Metadata["truncated"])
+
+		res = invoke(t, "1")
+		assert.Equal(t, "1", res.Metadata["messageCount"])
+		assert.Equal(t, "true", res.Metadata["truncated"])
+	})
+
+	t.Run("server streaming without waiting for the end of the stream", func(t *testing.T) {
+		b := initBinding(t, map[string]string{
+			"address":       addr,
+			"streamEndWait": "0",
+		})
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+			Data:      []byte(`{"responseParameters":[{"size":1},{"size":2}]}`),
+			Metadata: map[string]string{
+				"method":      "grpc.testing.TestService/StreamingOutputCall",
+				"maxMessages": "2",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "2", res.Metadata["messageCount"])
+		assert.Equal(t, "true", res.Metadata["truncated"])
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+			Metadata: map[string]string{
+				"method": "grpc.health.v1.Health/Nope",
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+			Data:      []byte(`{"foo":"bar"}`),
+			Metadata: map[string]string{
+				"method": "grpc.health.v1.Health/Check",
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("missing method", func(t *testing.T) {
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	addr := startTestServer(t)
+	b := initBinding(t, map[string]string{
+		"address":        addr,
+		"maxMessageSize": "1024",
+	})
+
+	invoke := func(method string, data string, meta map[string]string) (*bindings.InvokeResponse, error) {
+		md := map[string]string{"method": method}
+		for k, v := range meta {
+			md[k] = v
+		}
+		return b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: InvokeOperation,
+			Data:      []byte(data),
+			Metadata:  md,
+		})
+	}
+	body := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 3000)))
+
+	t.Run("response within the maximum", func(t *testing.T) {
+		_, err := invoke("grpc.testing.TestService/UnaryCall", `{"responseSize":100}`, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("response larger than the maximum", func(t *testing.T) {
+		_, err := invoke("grpc.testing.TestService/UnaryCall", `{"responseSize":2000}`, nil)
+		require.ErrorIs(t, err, bindings.ErrMessageTooLarge)
+	})
+
+	t.Run("request larger than the maximum", func(t *testing.T) {
+		_, err := invoke("grpc.testing.TestService/UnaryCall", `{"payload":{"body":"`+body+`"}}`, nil)
+		require.ErrorIs(t, err, bindings.ErrMessageTooLarge)
+	})
+
+	t.Run("chunked request", func(t *testing.T) {
+		res, err := invoke("grpc.testing.TestService/StreamingInputCall", `{"payload":{"body":"`+body+`"}}`, map[string]string{
+			"chunkField": "payload.body",
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"aggregatedPayloadSize":3000}`, string(res.Data))
+		assert.Equal(t, "3", res.Metadata["chunkCount"])
+	})
+
+	t.Run("chunked empty request", func(t *testing.T) {
+		res, err := invoke("grpc.testing.TestService/StreamingInputCall", `{}`, map[string]string{
+			"chunkField": "payload.body",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "1", res.Metadata["chunkCount"])
+	})
+
+	t.Run("client streaming without chunking", func(t *testing.T) {
+		_, err := invoke("grpc.testing.TestService/StreamingInputCall", `{}`, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("chunking a unary method", func(t *testing.T) {
+		_, err := invoke("grpc.testing.TestService/UnaryCall", `{}`, map[string]string{
+			"chunkField": "payload.body",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid chunk field", func(t *testing.T) {
+		_, err := invoke("grpc.testing.TestService/StreamingInputCall", `{}`, map[string]string{
+			"chunkField": "payload.type",
+		})
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, bindings.ErrMessageTooLarge)
+	})
+}
+
+func TestChunkMessage(t *testing.T) {
+	in := &testpb.StreamingInputCallRequest{
+		Payload: &testpb.Payload{
+			Type: testpb.PayloadType_COMPRESSABLE,
+			Body: []byte(strings.Repeat("a", 100)),
+		},
+	}
+
+	chunks, err := chunkMessage(in, "payload.body", 50)
+	require.NoError(t, err)
+	require.Len(t, chunks, 4)
+
+	var body []byte
+	for _, c := range chunks {
+		assert.LessOrEqual(t, proto.Size(c), 50)
+		p := c.(*testpb.StreamingInputCallRequest).GetPayload()
+		assert.Equal(t, testpb.PayloadType_COMPRESSABLE, p.GetType())
+		body = append(body, p.GetBody()...)
+	}
+	assert.Equal(t, in.GetPayload().GetBody(), body)
+
+	t.Run("other fields too large", func(t *testing.T) {
+		_, err := chunkMessage(in, "payload.body", 10)
+		require.ErrorIs(t, err, bindings.ErrMessageTooLarge)
+	})
+
+	t.Run("field not found", func(t *testing.T) {
+		_, err := chunkMessage(in, "payload.nope", 50)
+		require.Error(t, err)
+	})
+}
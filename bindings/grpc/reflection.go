@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// methodResolver resolves method descriptors using the gRPC server reflection service.
+// Resolved descriptors are cached, so the server is queried only once per method.
+type methodResolver struct {
+	conn  grpc.ClientConnInterface
+	cache map[string]protoreflect.MethodDescriptor
+	lock  sync.RWMutex
+}
+
+func newMethodResolver(conn grpc.ClientConnInterface) *methodResolver {
+	return &methodResolver{
+		conn:  conn,
+		cache: make(map[string]protoreflect.MethodDescriptor),
+	}
+}
+
+// parseMethodName splits a fully-qualified method name into the service and method names.
+// Both "package.Service/Method" and "package.Service.Method" are accepted, optionally with a leading slash.
+func parseMethodName(name string) (service string, method string, err error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "/")
+	idx := strings.LastIndex(name, "/")
+	if idx < 0 {
+		idx = strings.LastIndex(name, ".")
+	}
+	if idx <= 0 || idx == len(name)-1 {
+		return "", "", fmt.Errorf("invalid method name '%s': must be in the format 'package.Service/Method'", name)
+	}
+	return name[:idx], name[idx+1:], nil
+}
+
+// Resolve returns the descriptor for the given fully-qualified method name.
+func (r *methodResolver) Resolve(ctx context.Context, name string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, err := parseMethodName(name)
+	if err != nil {
+		return nil, err
+	}
+	key := serviceName + "/" + methodName
+
+	r.lock.RLock()
+	md, ok := r.cache[key]
+	r.lock.RUnlock()
+	if ok {
+		return md, nil
+	}
+
+	files, err := r.fetchFiles(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service '%s' not found: %w", serviceName, err)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a service", serviceName)
+	}
+	md = sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, fmt.Errorf("method '%s' not found in service '%s'", methodName, serviceName)
+	}
+	r.lock.Lock()
+	r.cache[key] = md
+	r.lock.Unlock()
+
+	return md, nil
+}
+
+// fetchFiles retrieves the file descriptor that defines the symbol, together with all its transitive dependencies.
+func (r *methodResolver) fetchFiles(ctx context.Context, symbol string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := rpb.NewServerReflectionClient(r.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reflection stream: %w", err)
+	}
+	defer stream.CloseSend()
+
+	fds := make(map[string]*descriptorpb.FileDescriptorProto)
+	ordered := make([]*descriptorpb.FileDescriptorProto, 0)
+	requested := make(map[string]bool)
+
+	var queue []*rpb.ServerReflectionRequest
+	queue = append(queue, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	})
+	for len(queue) > 0 {
+		req := queue[0]
+		queue = queue[1:]
+
+		received, err := reflectionRequest(stream, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range received {
+			if _, ok := fds[fd.GetName()]; ok {
+				continue
+			}
+			fds[fd.GetName()] = fd
+			ordered = append(ordered, fd)
+		}
+		for _, fd := range received {
+			for _, dep := range fd.GetDependency() {
+				if _, ok := fds[dep]; ok || requested[dep] {
+					continue
+				}
+				requested[dep] = true
+				queue = append(queue, &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+				})
+			}
+		}
+	}
+
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: ordered})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors for '%s': %w", symbol, err)
+	}
+	return files, nil
+}
+
+func reflectionRequest(stream rpb.ServerReflection_ServerReflectionInfoClient, req *rpb.ServerReflectionRequest) ([]*descriptorpb.FileDescriptorProto, error) {
+	err := stream.Send(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send reflection request: %w", err)
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive reflection response: %w", err)
+	}
+
+	switch mr := res.GetMessageResponse().(type) {
+	case *rpb.ServerReflectionResponse_ErrorResponse:
+		return nil, fmt.Errorf("reflection error %d: %s", mr.ErrorResponse.GetErrorCode(), mr.ErrorResponse.GetErrorMessage())
+	case *rpb.ServerReflectionResponse_FileDescriptorResponse:
+		raw := mr.FileDescriptorResponse.GetFileDescriptorProto()
+		out := make([]*descriptorpb.FileDescriptorProto, len(raw))
+		for i, b := range raw {
+			out[i] = &descriptorpb.FileDescriptorProto{}
+			err = proto.Unmarshal(b, out[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse file descriptor: %w", err)
+			}
+		}
+		return out, nil
+	default:
+		return nil, errors.New("unexpected reflection response")
+	}
+}
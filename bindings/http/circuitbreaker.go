@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+const (
+	defaultCircuitBreakerOpenDuration   = 30 * time.Second
+	defaultCircuitBreakerHalfOpenProbes = 1
+)
+
+// ErrCircuitOpen is returned when a request is rejected without being sent because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// newCircuitBreaker returns the circuit breaker for requests to the endpoint, or nil if it's disabled.
+func (h *HTTPSource) newCircuitBreaker() *gobreaker.TwoStepCircuitBreaker {
+	threshold := h.metadata.CircuitBreakerFailureThreshold
+	if threshold == 0 {
+		return nil
+	}
+
+	openDuration := h.metadata.CircuitBreakerOpenDuration
+	if openDuration <= 0 {
+		openDuration = defaultCircuitBreakerOpenDuration
+	}
+	probes := h.metadata.CircuitBreakerHalfOpenProbes
+	if probes == 0 {
+		probes = defaultCircuitBreakerHalfOpenProbes
+	}
+
+	return gobreaker.NewTwoStepCircuitBreaker(gobreaker.Settings{
+		Name: h.metadata.URL,
+		// Requests allowed through while half-open; the circuit closes once they all succeed
+		MaxRequests: probes,
+		Timeout:     openDuration,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= threshold
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			switch to {
+			case gobreaker.StateOpen:
+				h.logger.Warnf("Circuit breaker for HTTP endpoint %s is open after %d consecutive failures: requests will fail fast for %v", name, threshold, openDuration)
+			case gobreaker.StateHalfOpen:
+				h.logger.Infof("Circuit breaker for HTTP endpoint %s is half-open: allowing %d probe requests", name, probes)
+			case gobreaker.StateClosed:
+				h.logger.Infof("Circuit breaker for HTTP endpoint %s is closed (was %s)", name, from)
+			}
+		},
+	})
+}
+
+// allowRequest checks whether the circuit breaker lets a request through.
+// If it does, the returned function must be called with the outcome of the request.
+func (h *HTTPSource) allowRequest() (func(ctx context.Context, resp *http.Response, err error), error) {
+	if h.breaker == nil {
+		return func(context.Context, *http.Response, error) {}, nil
+	}
+
+	done, err := h.breaker.Allow()
+	if err != nil {
+		// gobreaker returns ErrTooManyRequests when all the probes of a half-open circuit are in progress
+		return nil, fmt.Errorf("%w: requests to %s are rejected until the endpoint recovers", ErrCircuitOpen, h.metadata.URL)
+	}
+
+	return func(ctx context.Context, resp *http.Response, err error) {
+		switch {
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			// The request was canceled by the caller, which says nothing about the health of the endpoint
+			done(true)
+		case err != nil:
+			done(false)
+		default:
+			done(resp.StatusCode < http.StatusInternalServerError)
+		}
+	}, nil
+}
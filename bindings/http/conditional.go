@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// Invocation metadata keys used to send conditional requests.
+	IfNoneMatchMetadataKey     = "ifNoneMatch"
+	IfModifiedSinceMetadataKey = "ifModifiedSince"
+
+	// Response metadata keys.
+	NotModifiedMetadataKey  = "notModified"
+	ETagMetadataKey         = "etag"
+	LastModifiedMetadataKey = "lastModified"
+)
+
+// setConditionalHeaders sets the If-None-Match and If-Modified-Since request headers from the invocation metadata.
+// The value of "ifModifiedSince" can be in the HTTP date format or in RFC 3339 format.
+func setConditionalHeaders(request *http.Request, md map[string]string) error {
+	if val := md[IfNoneMatchMetadataKey]; val != "" {
+		request.Header.Set("If-None-Match", val)
+	}
+
+	if val := md[IfModifiedSinceMetadataKey]; val != "" {
+		t, err := http.ParseTime(val)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, val)
+			if err != nil {
+				return fmt.Errorf("invalid value for '%s': must be a HTTP date or a RFC 3339 timestamp", IfModifiedSinceMetadataKey)
+			}
+		}
+		request.Header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	}
+
+	return nil
+}
+
+// addConditionalResponseMetadata adds the validators returned by the server to the response metadata, so they can be used in the next conditional request.
+func addConditionalResponseMetadata(md map[string]string, resp *http.Response) {
+	if val := resp.Header.Get("ETag"); val != "" {
+		md[ETagMetadataKey] = val
+	}
+	if val := resp.Header.Get("Last-Modified"); val != "" {
+		md[LastModifiedMetadataKey] = val
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		md[NotModifiedMetadataKey] = "true"
+	}
+}
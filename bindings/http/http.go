@@ -31,9 +31,12 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/sony/gobreaker"
+
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/tracing"
 	"github.com/dapr/kit/logger"
 )
 
@@ -56,6 +59,8 @@ type HTTPSource struct {
 	metadata      httpMetadata
 	client        *http.Client
 	errorIfNot2XX bool
+	breaker       *gobreaker.TwoStepCircuitBreaker
+	tracer        tracing.Tracer
 	logger        logger.Logger
 }
 
@@ -67,6 +72,16 @@ type httpMetadata struct {
 	SecurityToken       string         `mapstructure:"securityToken"`
 	SecurityTokenHeader string         `mapstructure:"securityTokenHeader"`
 	ResponseTimeout     *time.Duration `mapstructure:"responseTimeout"`
+	// Maximum size in bytes of response bodies that are read. Responses are not limited when this is 0.
+	MaxResponseSize int64 `mapstructure:"maxResponseSize"`
+
+	// Number of consecutive failures (connection errors or 5xx responses) after which the circuit breaker opens.
+	// The circuit breaker is disabled when this is 0.
+	CircuitBreakerFailureThreshold uint32 `mapstructure:"circuitBreakerFailureThreshold"`
+	// Time the circuit breaker stays open before allowing probe requests.
+	CircuitBreakerOpenDuration time.Duration `mapstructure:"circuitBreakerOpenDuration"`
+	// Number of probe requests allowed while half-open; the circuit closes if all succeed.
+	CircuitBreakerHalfOpenProbes uint32 `mapstructure:"circuitBreakerHalfOpenProbes"`
 }
 
 // NewHTTP returns a new HTTPSource.
@@ -74,6 +89,13 @@ func NewHTTP(logger logger.Logger) bindings.OutputBinding {
 	return &HTTPSource{logger: logger}
 }
 
+var _ tracing.TracerSetter = (*HTTPSource)(nil)
+
+// SetTracer sets the tracer used to create a client span for each request.
+func (h *HTTPSource) SetTracer(tracer tracing.Tracer) {
+	h.tracer = tracer
+}
+
 // Init performs metadata parsing.
 func (h *HTTPSource) Init(_ context.Context, meta bindings.Metadata) error {
 	var err error
@@ -81,6 +103,10 @@ func (h *HTTPSource) Init(_ context.Context, meta bindings.Metadata) error {
 		return err
 	}
 
+	if h.metadata.MaxResponseSize < 0 {
+		return errors.New("metadata property 'maxResponseSize' must not be negative")
+	}
+
 	var tlsConfig *tls.Config
 	if h.metadata.MTLSClientCert != "" && h.metadata.MTLSClientKey != "" {
 		tlsConfig, err = h.readMTLSCertificates()
@@ -113,6 +139,8 @@ func (h *HTTPSource) Init(_ context.Context, meta bindings.Metadata) error {
 		h.errorIfNot2XX = true
 	}
 
+	h.breaker = h.newCircuitBreaker()
+
 	return nil
 }
 
@@ -188,7 +216,7 @@ func (h *HTTPSource) Operations() []bindings.OperationKind {
 }
 
 // Invoke performs an HTTP request to the configured HTTP endpoint.
-func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeRequest) (_ *bindings.InvokeResponse, err error) {
 	u := h.metadata.URL
 
 	errorIfNot2XX := h.errorIfNot2XX // Default to the component config (default is true)
@@ -262,6 +290,11 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 		}
 	}
 
+	err = setConditionalHeaders(request, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	// HTTP binding needs to inject traceparent header for proper tracing stack.
 	if tp, ok := req.Metadata[TraceparentHeaderKey]; ok && tp != "" {
 		if _, ok := request.Header[http.CanonicalHeaderKey(TraceparentHeaderKey)]; ok {
@@ -278,8 +311,31 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 		request.Header.Set(TracestateHeaderKey, ts)
 	}
 
+	// If a tracer is set, the request is sent with the context of a client span, which is a child of the trace context in the metadata
+	if h.tracer != nil {
+		parent, _ := tracing.Extract(tracing.MapCarrier{
+			TraceparentHeaderKey: req.Metadata[TraceparentHeaderKey],
+			TracestateHeaderKey:  req.Metadata[TracestateHeaderKey],
+		})
+		var span tracing.Span
+		_, span = tracing.StartSpan(ctx, h.tracer, "HTTP "+method, tracing.SpanKindClient, parent, map[string]string{
+			"http.method": method,
+			"http.url":    u,
+		})
+		defer func() {
+			span.End(err)
+		}()
+		tracing.Inject(tracing.HeaderCarrier(request.Header), span.SpanContext())
+	}
+
+	done, err := h.allowRequest()
+	if err != nil {
+		return nil, err
+	}
+
 	// Send the question
 	resp, err := h.client.Do(request)
+	done(parentCtx, resp, err)
 	if err != nil {
 		return nil, err
 	}
@@ -287,7 +343,7 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 
 	// Read the response body. For empty responses (e.g. 204 No Content)
 	// `b` will be an empty slice.
-	b, err := io.ReadAll(resp.Body)
+	b, err := h.readBody(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -302,9 +358,13 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 	for key, values := range resp.Header {
 		metadata[key] = strings.Join(values, ", ")
 	}
+	addConditionalResponseMetadata(metadata, resp)
 
 	// Create an error for non-200 status codes unless suppressed.
-	if errorIfNot2XX && resp.StatusCode/100 != 2 {
+	// A 304 Not Modified is the expected response to a conditional request and is not an error.
+	if resp.StatusCode == http.StatusNotModified {
+		b = nil
+	} else if errorIfNot2XX && resp.StatusCode/100 != 2 {
 		err = fmt.Errorf("received status code %d", resp.StatusCode)
 	}
 
@@ -314,6 +374,26 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 	}, err
 }
 
+// readBody reads the body of a response, failing with bindings.ErrMessageTooLarge if it's larger than the maximum size.
+func (h *HTTPSource) readBody(resp *http.Response) ([]byte, error) {
+	maxSize := h.metadata.MaxResponseSize
+	if maxSize == 0 {
+		return io.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: response body is %d bytes, and the maximum is %d", bindings.ErrMessageTooLarge, resp.ContentLength, maxSize)
+	}
+	// Read one more byte than the maximum to detect larger bodies without a content length
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxSize {
+		return nil, fmt.Errorf("%w: response body is larger than the maximum of %d bytes", bindings.ErrMessageTooLarge, maxSize)
+	}
+	return b, nil
+}
+
 // GetComponentMetadata returns the metadata of the component.
 func (h *HTTPSource) GetComponentMetadata() map[string]string {
 	metadataStruct := httpMetadata{}
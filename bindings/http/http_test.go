@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/tracing"
 	"github.com/dapr/kit/logger"
 )
 
@@ -264,6 +266,40 @@ func TestTraceHeadersForwarded(t *testing.T) {
 	})
 }
 
+func TestTracing(t *testing.T) {
+	handler := NewHTTPHandler()
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	hs, err := InitBinding(s, nil)
+	require.NoError(t, err)
+	tracer := &tracing.RecordingTracer{}
+	hs.(*HTTPSource).SetTracer(tracer)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := TestCase{
+		input:      "GET",
+		operation:  "get",
+		metadata:   map[string]string{"path": "/", "traceparent": traceparent, "tracestate": "a=b"},
+		path:       "/",
+		statusCode: 200,
+	}.ToInvokeRequest()
+	_, err = hs.Invoke(context.Background(), &req)
+	require.NoError(t, err)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "HTTP GET", spans[0].Name)
+	assert.Equal(t, tracing.SpanKindClient, spans[0].Kind)
+	assert.Equal(t, traceparent, spans[0].Parent.Traceparent())
+	assert.True(t, spans[0].Ended)
+	assert.NoError(t, spans[0].Err)
+
+	// The request is sent with the context of the client span
+	assert.Equal(t, spans[0].Context.Traceparent(), handler.Headers["Traceparent"])
+	assert.Equal(t, "a=b", handler.Headers["Tracestate"])
+}
+
 func InitBindingForHTTPS(s *httptest.Server, extraProps map[string]string) (bindings.OutputBinding, error) {
 	m := bindings.Metadata{Base: metadata.Base{
 		Properties: map[string]string{
@@ -669,3 +705,196 @@ func verifyTimeoutBehavior(t *testing.T, hs bindings.OutputBinding, handler *HTT
 		})
 	}
 }
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		failing  atomic.Bool
+		requests atomic.Int32
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, map[string]string{
+		"circuitBreakerFailureThreshold": "2",
+		"circuitBreakerOpenDuration":     "100ms",
+	})
+	require.NoError(t, err)
+
+	invoke := func() error {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		return err
+	}
+
+	require.NoError(t, invoke())
+
+	failing.Store(true)
+	for i := 0; i < 2; i++ {
+		err = invoke()
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	assert.Equal(t, int32(3), requests.Load())
+
+	// The circuit is open: requests fail fast without reaching the server
+	err = invoke()
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// After the open duration, a probe is allowed; its failure opens the circuit again
+	time.Sleep(150 * time.Millisecond)
+	require.Error(t, invoke())
+	assert.Equal(t, int32(4), requests.Load())
+	require.ErrorIs(t, invoke(), ErrCircuitOpen)
+
+	// Once the endpoint recovers, a successful probe closes the circuit
+	failing.Store(false)
+	time.Sleep(150 * time.Millisecond)
+	require.NoError(t, invoke())
+	require.NoError(t, invoke())
+	assert.Equal(t, int32(6), requests.Load())
+}
+
+func TestCircuitBreakerDisabledByDefault(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, nil)
+	require.NoError(t, err)
+	assert.Nil(t, hs.(*HTTPSource).breaker)
+
+	for i := 0; i < 10; i++ {
+		_, err = hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Tue, 10 Oct 2023 07:28:00 GMT"
+	)
+	var received http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, nil)
+	require.NoError(t, err)
+
+	t.Run("unconditional request returns validators", func(t *testing.T) {
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res.Data))
+		assert.Equal(t, etag, res.Metadata[ETagMetadataKey])
+		assert.Equal(t, lastModified, res.Metadata[LastModifiedMetadataKey])
+		assert.NotContains(t, res.Metadata, NotModifiedMetadataKey)
+	})
+
+	t.Run("not modified with ifNoneMatch", func(t *testing.T) {
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{IfNoneMatchMetadataKey: etag},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, etag, received.Get("If-None-Match"))
+		assert.Empty(t, res.Data)
+		assert.Equal(t, "304", res.Metadata["statusCode"])
+		assert.Equal(t, "true", res.Metadata[NotModifiedMetadataKey])
+		assert.Equal(t, etag, res.Metadata[ETagMetadataKey])
+	})
+
+	t.Run("not modified with ifModifiedSince in RFC 3339 format", func(t *testing.T) {
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{IfModifiedSinceMetadataKey: "2023-10-10T09:28:00+02:00"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, lastModified, received.Get("If-Modified-Since"))
+		assert.Equal(t, "true", res.Metadata[NotModifiedMetadataKey])
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{IfNoneMatchMetadataKey: `"v0"`},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res.Data))
+		assert.NotContains(t, res.Metadata, NotModifiedMetadataKey)
+	})
+
+	t.Run("invalid ifModifiedSince", func(t *testing.T) {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{IfModifiedSinceMetadataKey: "yesterday"},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") == "true" {
+			// Flushing before writing the body sends it without a content length
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, map[string]string{
+		"maxResponseSize": "5",
+	})
+	require.NoError(t, err)
+
+	t.Run("content length larger than the maximum", func(t *testing.T) {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		require.ErrorIs(t, err, bindings.ErrMessageTooLarge)
+	})
+
+	t.Run("body without content length larger than the maximum", func(t *testing.T) {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{"path": "/?chunked=true"},
+		})
+		require.ErrorIs(t, err, bindings.ErrMessageTooLarge)
+	})
+
+	t.Run("body within the maximum", func(t *testing.T) {
+		hs, err := InitBinding(s, map[string]string{
+			"maxResponseSize": "11",
+		})
+		require.NoError(t, err)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{"path": "/?chunked=true"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(res.Data))
+	})
+
+	t.Run("negative maximum", func(t *testing.T) {
+		_, err := InitBinding(s, map[string]string{
+			"maxResponseSize": "-1",
+		})
+		require.Error(t, err)
+	})
+}
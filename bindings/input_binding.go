@@ -19,6 +19,7 @@ import (
 	"io"
 
 	"github.com/dapr/components-contrib/health"
+	"github.com/dapr/components-contrib/state"
 )
 
 // InputBinding is the interface to define a binding that triggers on incoming events.
@@ -33,6 +34,12 @@ type InputBinding interface {
 	GetComponentMetadata() map[string]string
 }
 
+// CheckpointStoreSetter is implemented by input bindings that persist their position in a stream, to resume from it after a restart.
+// When the "checkpointStore" metadata property is set, the runtime passes the state store with that name to the binding after Init and before Read.
+type CheckpointStoreSetter interface {
+	SetCheckpointStore(store state.Store) error
+}
+
 // Handler is the handler used to invoke the app handler.
 type Handler func(context.Context, *ReadResponse) ([]byte, error)
 
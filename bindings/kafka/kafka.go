@@ -15,7 +15,9 @@ package kafka
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
@@ -25,22 +27,39 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/internal/component/kafka"
+	"github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/tracing"
 )
 
 const (
 	publishTopic = "publishTopic"
 	topics       = "topics"
+
+	// Invocation metadata with a comma-separated list of topics the message is published to, instead of publishTopic.
+	publishTopics = "publishTopics"
+	// Component or invocation metadata: when publishing to multiple topics, fail if the message isn't published to all of them.
+	requireAllAcks = "requireAllAcks"
 )
 
 type Binding struct {
-	kafka        *kafka.Kafka
-	publishTopic string
-	topics       []string
-	logger       logger.Logger
-	closeCh      chan struct{}
-	closed       atomic.Bool
-	wg           sync.WaitGroup
+	kafka          *kafka.Kafka
+	publishTopic   string
+	topics         []string
+	requireAllAcks bool
+	logger         logger.Logger
+	closeCh        chan struct{}
+	closed         atomic.Bool
+	wg             sync.WaitGroup
+}
+
+var _ tracing.TracerSetter = (*Binding)(nil)
+
+// topicPublishResult is the outcome of publishing a message to one of multiple topics.
+type topicPublishResult struct {
+	Topic   string `json:"topic"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
 }
 
 // NewKafka returns a new kafka binding instance.
@@ -55,6 +74,11 @@ func NewKafka(logger logger.Logger) bindings.InputOutputBinding {
 	}
 }
 
+// SetTracer sets the tracer used to create spans for published and consumed messages.
+func (b *Binding) SetTracer(tracer tracing.Tracer) {
+	b.kafka.SetTracer(tracer)
+}
+
 func (b *Binding) Init(ctx context.Context, metadata bindings.Metadata) error {
 	err := b.kafka.Init(ctx, metadata.Properties)
 	if err != nil {
@@ -71,6 +95,20 @@ func (b *Binding) Init(ctx context.Context, metadata bindings.Metadata) error {
 		b.topics = strings.Split(val, ",")
 	}
 
+	b.requireAllAcks = utils.IsTruthy(metadata.Properties[requireAllAcks])
+
+	// The shared read retry policy replaces "consumeRetryInterval" only when configured, to keep the existing behavior otherwise
+	for k := range metadata.Properties {
+		if strings.HasPrefix(strings.ToLower(k), "readretry") {
+			policy, err := bindings.ParseReadRetryPolicy(metadata.Properties)
+			if err != nil {
+				return err
+			}
+			b.kafka.SetConsumeBackOff(policy.NewBackOff)
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -87,10 +125,95 @@ func (b *Binding) Close() (err error) {
 }
 
 func (b *Binding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if val, ok := req.Metadata[publishTopics]; ok {
+		return b.publishToTopics(ctx, parseTopics(val), req)
+	}
+
 	err := b.kafka.Publish(ctx, b.publishTopic, req.Data, req.Metadata)
 	return nil, err
 }
 
+// publishToTopics publishes the message to multiple topics concurrently, returning the result for each topic in the response data.
+// Unless all acks are required, an error is returned only if the message could not be published to any topic.
+func (b *Binding) publishToTopics(ctx context.Context, topicList []string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if len(topicList) == 0 {
+		return nil, fmt.Errorf("metadata property '%s' must contain at least one topic", publishTopics)
+	}
+
+	requireAll := b.requireAllAcks
+	if val, ok := req.Metadata[requireAllAcks]; ok {
+		requireAll = utils.IsTruthy(val)
+	}
+
+	// The properties that control the binding are not sent as message headers
+	md := make(map[string]string, len(req.Metadata))
+	for k, v := range req.Metadata {
+		if k != publishTopics && k != requireAllAcks {
+			md[k] = v
+		}
+	}
+
+	results := make([]topicPublishResult, len(topicList))
+	errs := make([]error, len(topicList))
+	var wg sync.WaitGroup
+	wg.Add(len(topicList))
+	for i, topic := range topicList {
+		go func(i int, topic string) {
+			defer wg.Done()
+			results[i].Topic = topic
+			err := b.kafka.Publish(ctx, topic, req.Data, md)
+			if err != nil {
+				results[i].Error = err.Error()
+				errs[i] = fmt.Errorf("failed to publish to topic '%s': %w", topic, err)
+				return
+			}
+			results[i].Success = true
+		}(i, topic)
+	}
+	wg.Wait()
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	res := &bindings.InvokeResponse{
+		Data: data,
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	if failed == len(results) || (failed > 0 && requireAll) {
+		return res, fmt.Errorf("message was not published to %d of %d topics: %w", failed, len(results), errors.Join(errs...))
+	}
+	if failed > 0 {
+		b.logger.Warnf("Message was not published to %d of %d topics: %v", failed, len(results), errors.Join(errs...))
+	}
+	return res, nil
+}
+
+// parseTopics parses a comma-separated list of topics, removing empty values and duplicates.
+func parseTopics(val string) []string {
+	parts := strings.Split(val, ",")
+	res := make([]string, 0, len(parts))
+	seen := make(map[string]struct{}, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		res = append(res, p)
+	}
+	return res
+}
+
 func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
 	if b.closed.Load() {
 		return errors.New("error: binding is closed")
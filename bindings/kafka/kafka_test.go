@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseTopics(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, parseTopics("a, b,,c,a"))
+	assert.Empty(t, parseTopics(" , "))
+}
+
+func TestPublishToTopics(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("a", 0, broker.BrokerID()).
+			SetLeader("b", 0, broker.BrokerID()).
+			SetLeader("bad", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).
+			SetVersion(3).
+			SetError("bad", 0, sarama.ErrMessageSizeTooLarge),
+	})
+
+	initBinding := func(t *testing.T, props map[string]string) *Binding {
+		b := NewKafka(logger.NewLogger("test")).(*Binding)
+		properties := map[string]string{
+			"brokers":       broker.Addr(),
+			"authType":      "none",
+			"consumerGroup": "group",
+			"publishTopic":  "a",
+			"version":       "2.0.0",
+		}
+		for k, v := range props {
+			properties[k] = v
+		}
+		err := b.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: properties}})
+		require.NoError(t, err)
+		t.Cleanup(func() { b.Close() })
+		return b
+	}
+
+	invoke := func(b *Binding, md map[string]string) ([]topicPublishResult, error) {
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data:      []byte("hello"),
+			Metadata:  md,
+			Operation: bindings.CreateOperation,
+		})
+		if res == nil {
+			return nil, err
+		}
+		var results []topicPublishResult
+		require.NoError(t, json.Unmarshal(res.Data, &results))
+		return results, err
+	}
+
+	t.Run("all topics succeed", func(t *testing.T) {
+		b := initBinding(t, nil)
+		results, err := invoke(b, map[string]string{publishTopics: "a,b"})
+		require.NoError(t, err)
+		assert.Equal(t, []topicPublishResult{
+			{Topic: "a", Success: true},
+			{Topic: "b", Success: true},
+		}, results)
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		b := initBinding(t, nil)
+		results, err := invoke(b, map[string]string{publishTopics: "a,bad"})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.True(t, results[0].Success)
+		assert.False(t, results[1].Success)
+		assert.Equal(t, "bad", results[1].Topic)
+		assert.NotEmpty(t, results[1].Error)
+	})
+
+	t.Run("partial failure with requireAllAcks in the request", func(t *testing.T) {
+		b := initBinding(t, nil)
+		results, err := invoke(b, map[string]string{publishTopics: "a,bad", requireAllAcks: "true"})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "topic 'bad'")
+		require.Len(t, results, 2)
+		assert.True(t, results[0].Success)
+		assert.False(t, results[1].Success)
+	})
+
+	t.Run("partial failure with requireAllAcks in the component", func(t *testing.T) {
+		b := initBinding(t, map[string]string{requireAllAcks: "true"})
+		_, err := invoke(b, map[string]string{publishTopics: "a,bad"})
+		require.Error(t, err)
+	})
+
+	t.Run("all topics fail", func(t *testing.T) {
+		b := initBinding(t, nil)
+		results, err := invoke(b, map[string]string{publishTopics: "bad"})
+		require.Error(t, err)
+		require.Len(t, results, 1)
+	})
+
+	t.Run("no topics", func(t *testing.T) {
+		b := initBinding(t, nil)
+		_, err := invoke(b, map[string]string{publishTopics: ""})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,801 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package binlog contains an input binding that streams the changes to rows of MySQL tables from the binlog, connecting to the server as a replica.
+package binlog
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-log/log"
+
+	"github.com/dapr/components-contrib/bindings"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	connectionURLKey = "url"
+
+	operationInsert = "insert"
+	operationUpdate = "update"
+	operationDelete = "delete"
+
+	metadataOperationKey = "operation"
+	metadataDatabaseKey  = "database"
+	metadataTableKey     = "table"
+	metadataPositionKey  = "position"
+
+	defaultHeartbeatInterval = 30 * time.Second
+	// Interval of the checkpoints saved when the transactions don't contain changes sent to the app
+	idleCheckpointInterval = 10 * time.Second
+	checkpointKeyPrefix    = "binlog||"
+)
+
+// Schemas of the server, whose changes are not sent to the app unless their tables are listed.
+var systemSchemas = map[string]bool{
+	"mysql":              true,
+	"information_schema": true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+type binlogMetadata struct {
+	// URL is the connection string to connect to MySQL, with a user that has the REPLICATION SLAVE and REPLICATION CLIENT privileges.
+	URL string `mapstructure:"url"`
+	// ID of the binding as a replica of the server, which must be different from the IDs of the server and all other replicas.
+	// If 0, a random ID is used.
+	ServerID uint32 `mapstructure:"serverID"`
+	// Comma-separated list of tables whose changes are sent to the app, as "database.table", or "table" for tables in the database of the connection string.
+	// If empty, changes to all tables are sent.
+	Tables string `mapstructure:"tables"`
+	// Name of the state store where the position in the binlog is saved.
+	// If empty, streaming starts from the current position of the server at every start.
+	CheckpointStore string `mapstructure:"checkpointStore"`
+	// Key of the position in the state store; defaults to the name of the component.
+	CheckpointKey string `mapstructure:"checkpointKey"`
+	// Interval of the heartbeats sent by the server when there are no events, which are used to detect broken connections.
+	HeartbeatInterval time.Duration `mapstructure:"heartbeatInterval"`
+
+	tables []string
+}
+
+// Position is a position in the binlog of the server.
+type Position struct {
+	File string `json:"file"`
+	Pos  uint32 `json:"pos"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Pos)
+}
+
+// Change is a change to a row, which is sent to the app as the data of an event.
+type Change struct {
+	// One of "insert", "update" and "delete".
+	Operation string `json:"operation"`
+	Database  string `json:"database"`
+	Table     string `json:"table"`
+	// Position of the event with the change in the binlog.
+	Position Position `json:"position"`
+	// Time of the change, in seconds since the Unix epoch.
+	Timestamp uint32 `json:"timestamp"`
+	// Values of the columns before an update or delete.
+	Before map[string]any `json:"before,omitempty"`
+	// Values of the columns after an insert or update.
+	After map[string]any `json:"after,omitempty"`
+}
+
+// Binding is an input binding that sends the changes to rows in the binlog of a MySQL server to the app.
+// The binlog must be in the ROW format. The position after the last transaction processed by the app is saved in a state store, so after a restart streaming resumes from it.
+type Binding struct {
+	logger          logger.Logger
+	metadata        binlogMetadata
+	readRetryPolicy bindings.ReadRetryPolicy
+	config          *mysql.Config
+	db              *sql.DB
+	closed          atomic.Bool
+	closeCh         chan struct{}
+	wg              sync.WaitGroup
+
+	lock            sync.Mutex
+	checkpointStore state.Store
+	// Position after the last transaction processed by the app
+	position Position
+	columns  map[string][]columnInfo
+}
+
+// NewBinlog returns a new MySQL binlog input binding.
+func NewBinlog(logger logger.Logger) bindings.InputBinding {
+	return &Binding{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+		columns: map[string][]columnInfo{},
+	}
+}
+
+var _ bindings.CheckpointStoreSetter = (*Binding)(nil)
+
+// Init initializes the binding, and checks that the binlog is in the ROW format.
+func (b *Binding) Init(ctx context.Context, meta bindings.Metadata) error {
+	m, cfg, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	b.metadata = m
+	b.config = cfg
+
+	b.readRetryPolicy, err = bindings.ParseReadRetryPolicy(meta.Properties)
+	if err != nil {
+		return err
+	}
+
+	b.db, err = sql.Open("mysql", m.URL)
+	if err != nil {
+		return fmt.Errorf("error opening DB connection: %w", internalutils.RedactError(err, m.URL))
+	}
+
+	var format string
+	err = b.db.QueryRowContext(ctx, "SELECT @@GLOBAL.binlog_format").Scan(&format)
+	if err != nil {
+		b.db.Close()
+		return fmt.Errorf("error reading the binlog format: %w", err)
+	}
+	if !strings.EqualFold(format, "ROW") {
+		b.db.Close()
+		return fmt.Errorf("the binlog format is '%s', but it must be 'ROW'", format)
+	}
+
+	return nil
+}
+
+func parseMetadata(meta bindings.Metadata) (binlogMetadata, *mysql.Config, error) {
+	m := binlogMetadata{
+		HeartbeatInterval: defaultHeartbeatInterval,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, nil, err
+	}
+
+	if m.URL == "" {
+		return m, nil, fmt.Errorf("required metadata not set: %s", connectionURLKey)
+	}
+	cfg, err := mysql.ParseDSN(m.URL)
+	if err != nil {
+		return m, nil, fmt.Errorf("illegal Data Source Name (DSN) specified by %s", connectionURLKey)
+	}
+	if m.ServerID == 0 {
+		// Random ID in the upper half of the range, which is rarely used by servers
+		var b [4]byte
+		_, err = rand.Read(b[:])
+		if err != nil {
+			return m, nil, err
+		}
+		m.ServerID = binary.LittleEndian.Uint32(b[:]) | 1<<31
+	}
+	if m.HeartbeatInterval <= 0 {
+		return m, nil, errors.New("metadata property 'heartbeatInterval' must be greater than 0")
+	}
+	if m.CheckpointKey == "" {
+		m.CheckpointKey = meta.Name
+	}
+
+	for _, t := range strings.Split(m.Tables, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !strings.Contains(t, ".") {
+			if cfg.DBName == "" {
+				return m, nil, fmt.Errorf("table '%s' must be in the format 'database.table', as the connection string doesn't have a database", t)
+			}
+			t = cfg.DBName + "." + t
+		}
+		m.tables = append(m.tables, t)
+	}
+
+	return m, cfg, nil
+}
+
+// SetCheckpointStore sets the state store where the position in the binlog is saved.
+func (b *Binding) SetCheckpointStore(store state.Store) error {
+	if store == nil {
+		return errors.New("checkpoint store is nil")
+	}
+	if b.metadata.CheckpointStore == "" {
+		return errors.New("'checkpointStore' is not set in the component metadata")
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.checkpointStore = store
+	return nil
+}
+
+func (b *Binding) getCheckpointStore() (state.Store, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.metadata.CheckpointStore != "" && b.checkpointStore == nil {
+		return nil, fmt.Errorf("checkpoint store '%s' is configured, but it was not set", b.metadata.CheckpointStore)
+	}
+	return b.checkpointStore, nil
+}
+
+// Read starts streaming the changes from the binlog to the handler, from the saved position or else the current position of the server.
+func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
+	if b.closed.Load() {
+		return errors.New("binding is closed")
+	}
+
+	pos, err := b.startPosition(ctx)
+	if err != nil {
+		return err
+	}
+	b.position = pos
+	b.logger.Infof("Streaming changes from the binlog starting at %s", pos)
+
+	readCtx, cancel := context.WithCancel(ctx)
+	b.wg.Add(2)
+
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		select {
+		case <-b.closeCh:
+		case <-readCtx.Done():
+		}
+	}()
+
+	go func() {
+		defer b.wg.Done()
+		// Changes after the last processed transaction are streamed again after reconnecting
+		err := bindings.RunReadLoop(readCtx, b.readRetryPolicy, func(ctx context.Context) error {
+			return b.stream(ctx, handler)
+		}, func(err error, delay time.Duration) {
+			b.logger.Errorf("Error streaming changes from the binlog, reconnecting in %s: %v", delay, err)
+		})
+		if err != nil {
+			b.logger.Errorf("Stopped streaming changes from the binlog: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// startPosition returns the position saved in the checkpoint store or, if there's none, the current position of the server.
+func (b *Binding) startPosition(ctx context.Context) (Position, error) {
+	store, err := b.getCheckpointStore()
+	if err != nil {
+		return Position{}, err
+	}
+	if store != nil {
+		res, err := store.Get(ctx, &state.GetRequest{Key: checkpointKeyPrefix + b.metadata.CheckpointKey})
+		if err != nil {
+			return Position{}, fmt.Errorf("error reading the checkpoint: %w", err)
+		}
+		if res != nil && len(res.Data) > 0 {
+			var pos Position
+			err = json.Unmarshal(res.Data, &pos)
+			if err != nil || pos.File == "" {
+				return Position{}, fmt.Errorf("invalid checkpoint '%s'", string(res.Data))
+			}
+			return pos, nil
+		}
+	}
+
+	// The statement was renamed in MySQL 8.4
+	var pos Position
+	for _, stmt := range []string{"SHOW BINARY LOG STATUS", "SHOW MASTER STATUS"} {
+		err = b.queryPosition(ctx, stmt, &pos)
+		if err == nil {
+			return pos, nil
+		}
+	}
+	return Position{}, fmt.Errorf("error reading the current binlog position: %w", err)
+}
+
+func (b *Binding) queryPosition(ctx context.Context, stmt string, pos *Position) error {
+	rows, err := b.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return errors.New("binary logging is not enabled")
+	}
+	// The statement returns more columns, depending on the version of the server
+	values := make([]any, len(cols))
+	values[0] = &pos.File
+	values[1] = &pos.Pos
+	for i := 2; i < len(values); i++ {
+		values[i] = new(sql.RawBytes)
+	}
+	return rows.Scan(values...)
+}
+
+// stream streams the changes from the binlog until the context is canceled or an error occurs.
+func (b *Binding) stream(ctx context.Context, handler bindings.Handler) error {
+	// Reconnections are handled by the read loop, which resumes from the last processed transaction
+	syncer := replication.NewBinlogSyncer(b.syncerConfig())
+	defer syncer.Close()
+
+	b.lock.Lock()
+	pos := b.position
+	b.lock.Unlock()
+	streamer, err := syncer.StartSync(gomysql.Position{Name: pos.File, Pos: pos.Pos})
+	if err != nil {
+		return fmt.Errorf("error requesting the binlog: %w", err)
+	}
+
+	s := &streamState{
+		binding:  b,
+		handler:  handler,
+		file:     pos.File,
+		columns:  map[uint64][]columnInfo{},
+		lastSave: time.Now(),
+	}
+	for {
+		ev, err := streamer.GetEvent(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("error reading binlog event: %w", err)
+		}
+		err = s.process(ctx, ev)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// syncerConfig returns the configuration of the binlog syncer, with the connection properties of the connection string.
+func (b *Binding) syncerConfig() replication.BinlogSyncerConfig {
+	dialer := &net.Dialer{Timeout: b.config.Timeout}
+	return replication.BinlogSyncerConfig{
+		ServerID: b.metadata.ServerID,
+		Flavor:   "mysql",
+		// Without a port, the address is used as is, which supports Unix sockets too
+		Host:      b.config.Addr,
+		User:      b.config.User,
+		Password:  b.config.Passwd,
+		TLSConfig: b.config.TLS,
+		Dialer:    dialer.DialContext,
+		// Heartbeats are sent when there are no events, so a connection without any event is broken
+		HeartbeatPeriod:         b.metadata.HeartbeatInterval,
+		ReadTimeout:             2 * b.metadata.HeartbeatInterval,
+		DisableRetrySync:        true,
+		UseDecimal:              true,
+		TimestampStringLocation: time.UTC,
+		Logger:                  log.NewDefault(logHandler{logger: b.logger}),
+	}
+}
+
+// logHandler writes the logs of the binlog syncer to the logger of the component, at the debug level.
+type logHandler struct {
+	logger logger.Logger
+}
+
+func (h logHandler) Write(p []byte) (int, error) {
+	h.logger.Debug(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+func (h logHandler) Close() error {
+	return nil
+}
+
+// streamState is the state of a binlog stream.
+type streamState struct {
+	binding *Binding
+	handler bindings.Handler
+	// Current binlog file
+	file string
+	// Columns of the tables whose changes are sent to the app, by ID of the table in the table map events
+	columns map[uint64][]columnInfo
+	// Whether changes were sent to the app since the last checkpoint
+	pending  bool
+	lastSave time.Time
+}
+
+// process processes a binlog event.
+func (s *streamState) process(ctx context.Context, ev *replication.BinlogEvent) error {
+	h := ev.Header
+	switch e := ev.Event.(type) {
+	case *replication.RotateEvent:
+		s.file = string(e.NextLogName)
+		return s.commit(ctx, Position{File: s.file, Pos: uint32(e.Position)}, true)
+
+	case *replication.QueryEvent:
+		query := strings.TrimSpace(string(e.Query))
+		if strings.EqualFold(query, "COMMIT") {
+			// Transactions on transactional tables end with a XID event instead
+			return s.commit(ctx, Position{File: s.file, Pos: h.LogPos}, false)
+		}
+		if isDDL(query) {
+			// Columns of tables are read again after the structure of tables changes
+			s.binding.invalidateColumns()
+		}
+
+	case *replication.XIDEvent:
+		return s.commit(ctx, Position{File: s.file, Pos: h.LogPos}, false)
+
+	case *replication.TableMapEvent:
+		schema, table := string(e.Schema), string(e.Table)
+		if !s.binding.includesTable(schema, table) {
+			delete(s.columns, e.TableID)
+			return nil
+		}
+		cols, err := s.binding.tableColumns(ctx, schema, table)
+		if err != nil {
+			return err
+		}
+		s.columns[e.TableID] = cols
+
+	case *replication.RowsEvent:
+		cols, ok := s.columns[e.TableID]
+		if !ok {
+			return nil
+		}
+		return s.processRows(ctx, h, e, cols)
+	}
+	return nil
+}
+
+// processRows sends the changes to the rows in a rows event to the app.
+func (s *streamState) processRows(ctx context.Context, h *replication.EventHeader, e *replication.RowsEvent, cols []columnInfo) error {
+	var operation string
+	switch h.EventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		operation = operationInsert
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		operation = operationUpdate
+	default:
+		operation = operationDelete
+	}
+
+	// Updates have the before and after images of each row
+	step := 1
+	if operation == operationUpdate {
+		step = 2
+	}
+	for i := 0; i+step <= len(e.Rows); i += step {
+		c := Change{
+			Operation: operation,
+			Database:  string(e.Table.Schema),
+			Table:     string(e.Table.Table),
+			Position:  Position{File: s.file, Pos: h.LogPos},
+			Timestamp: h.Timestamp,
+		}
+		switch operation {
+		case operationInsert:
+			c.After = rowValues(e.Table, cols, e.Rows[i])
+		case operationDelete:
+			c.Before = rowValues(e.Table, cols, e.Rows[i])
+		case operationUpdate:
+			c.Before = rowValues(e.Table, cols, e.Rows[i])
+			c.After = rowValues(e.Table, cols, e.Rows[i+1])
+		}
+		err := s.binding.handleChange(ctx, s.handler, c)
+		if err != nil {
+			return err
+		}
+		s.pending = true
+	}
+	return nil
+}
+
+// isDDL returns true if the query may change the structure of tables.
+func isDDL(query string) bool {
+	stmt := strings.ToUpper(query)
+	for _, prefix := range []string{"ALTER", "CREATE", "DROP", "RENAME", "TRUNCATE"} {
+		if strings.HasPrefix(stmt, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// commit records the position after a transaction or a rotation, and saves it to the checkpoint store.
+// Checkpoints are saved after every transaction with changes sent to the app, and periodically otherwise.
+func (s *streamState) commit(ctx context.Context, pos Position, force bool) error {
+	if pos.Pos == 0 || pos.File == "" {
+		return nil
+	}
+	b := s.binding
+	b.lock.Lock()
+	b.position = pos
+	store := b.checkpointStore
+	b.lock.Unlock()
+
+	if store == nil || !(force || s.pending || time.Since(s.lastSave) >= idleCheckpointInterval) {
+		return nil
+	}
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	err = store.Set(ctx, &state.SetRequest{
+		Key:         checkpointKeyPrefix + b.metadata.CheckpointKey,
+		Value:       data,
+		ContentType: ptr.Of("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving the checkpoint at %s: %w", pos, err)
+	}
+	s.pending = false
+	s.lastSave = time.Now()
+	return nil
+}
+
+// columnInfo describes a column of a table, with the properties that are not in table map events.
+type columnInfo struct {
+	Name     string
+	Unsigned bool
+	// Binary is true for columns with binary strings, whose values are sent to the app as base64
+	Binary bool
+	// Labels of the values of ENUM and SET columns
+	Labels []string
+	// Set is true for SET columns, whose values are sent to the app as comma-separated labels
+	Set bool
+}
+
+// rowValues returns the values of the columns in an image of a row, by name.
+func rowValues(tm *replication.TableMapEvent, cols []columnInfo, values []any) map[string]any {
+	if values == nil {
+		return nil
+	}
+	res := make(map[string]any, len(values))
+	for i, v := range values {
+		if i < len(cols) {
+			res[cols[i].Name] = convertValue(tm.ColumnType[i], tm.ColumnMeta[i], &cols[i], v)
+		} else {
+			// The table has more columns than when they were read
+			res["@"+strconv.Itoa(i+1)] = convertValue(tm.ColumnType[i], tm.ColumnMeta[i], &columnInfo{}, v)
+		}
+	}
+	return res
+}
+
+// convertValue converts a value decoded from a rows event to the value sent to the app, using the properties of the column.
+// Integers are sent as int64 or uint64, decimals as strings to preserve their precision, and temporal values as strings in the format used by MySQL.
+func convertValue(typ byte, meta uint16, col *columnInfo, v any) any {
+	switch x := v.(type) {
+	case int8:
+		if col.Unsigned {
+			return uint64(uint8(x))
+		}
+		return int64(x)
+	case int16:
+		if col.Unsigned {
+			return uint64(uint16(x))
+		}
+		return int64(x)
+	case int32:
+		if col.Unsigned {
+			if typ == gomysql.MYSQL_TYPE_INT24 {
+				return uint64(uint32(x) & 0xffffff)
+			}
+			return uint64(uint32(x))
+		}
+		return int64(x)
+	case int:
+		// YEAR columns
+		return int64(x)
+	case int64:
+		switch {
+		case col.Set:
+			if len(col.Labels) == 0 {
+				return uint64(x)
+			}
+			var members []string
+			for i, l := range col.Labels {
+				if x&(1<<i) != 0 {
+					members = append(members, l)
+				}
+			}
+			return strings.Join(members, ",")
+		case col.Labels != nil:
+			// ENUM columns
+			if x > 0 && int(x) <= len(col.Labels) {
+				return col.Labels[x-1]
+			}
+			return x
+		case col.Unsigned || typ == gomysql.MYSQL_TYPE_BIT:
+			return uint64(x)
+		}
+		return x
+	case decimal.Decimal:
+		return x.StringFixed(int32(meta & 0xff))
+	case string:
+		if typ == gomysql.MYSQL_TYPE_JSON && json.Valid([]byte(x)) {
+			return json.RawMessage(x)
+		}
+		if col.Binary {
+			return []byte(x)
+		}
+		return x
+	case []byte:
+		// TEXT columns have the type of BLOB columns
+		if !col.Binary {
+			return string(x)
+		}
+		return x
+	}
+	return v
+}
+
+// handleChange sends a change to the app.
+func (b *Binding) handleChange(ctx context.Context, handler bindings.Handler, c Change) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding change as JSON: %w", err)
+	}
+	_, err = handler(ctx, &bindings.ReadResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataOperationKey: c.Operation,
+			metadataDatabaseKey:  c.Database,
+			metadataTableKey:     c.Table,
+			metadataPositionKey:  c.Position.String(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error handling change at %s to table %s.%s: %w", c.Position, c.Database, c.Table, err)
+	}
+	return nil
+}
+
+func (b *Binding) includesTable(database, table string) bool {
+	if len(b.metadata.tables) == 0 {
+		return !systemSchemas[strings.ToLower(database)]
+	}
+	name := database + "." + table
+	for _, t := range b.metadata.tables {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tableColumns returns the columns of a table, which are read from the information schema the first time.
+func (b *Binding) tableColumns(ctx context.Context, database, table string) ([]columnInfo, error) {
+	key := database + "." + table
+	b.lock.Lock()
+	cols, ok := b.columns[key]
+	b.lock.Unlock()
+	if ok {
+		return cols, nil
+	}
+
+	rows, err := b.db.QueryContext(ctx, "SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", database, table)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the columns of table %s: %w", key, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, dataType, columnType string
+		err = rows.Scan(&name, &dataType, &columnType)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the columns of table %s: %w", key, err)
+		}
+		col := columnInfo{
+			Name:     name,
+			Unsigned: strings.Contains(strings.ToLower(columnType), "unsigned"),
+		}
+		switch strings.ToLower(dataType) {
+		case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob",
+			"geometry", "point", "linestring", "polygon", "multipoint", "multilinestring", "multipolygon", "geometrycollection", "geomcollection":
+			col.Binary = true
+		case "enum", "set":
+			col.Labels = parseLabels(columnType)
+			col.Set = strings.EqualFold(dataType, "set")
+		}
+		cols = append(cols, col)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the columns of table %s: %w", key, err)
+	}
+
+	b.lock.Lock()
+	b.columns[key] = cols
+	b.lock.Unlock()
+	return cols, nil
+}
+
+func (b *Binding) invalidateColumns() {
+	b.lock.Lock()
+	b.columns = map[string][]columnInfo{}
+	b.lock.Unlock()
+}
+
+// Close stops streaming changes.
+func (b *Binding) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		close(b.closeCh)
+	}
+	b.wg.Wait()
+	if b.db != nil {
+		return b.db.Close()
+	}
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (b *Binding) GetComponentMetadata() map[string]string {
+	metadataStruct := binlogMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
+
+// parseLabels parses the labels of the values of an ENUM or SET column from its type, such as "enum('a','b')".
+func parseLabels(columnType string) []string {
+	start := strings.IndexByte(columnType, '(')
+	end := strings.LastIndexByte(columnType, ')')
+	if start < 0 || end <= start {
+		return nil
+	}
+	var (
+		labels []string
+		cur    strings.Builder
+		inStr  bool
+	)
+	s := columnType[start+1 : end]
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case !inStr && c == '\'':
+			inStr = true
+			cur.Reset()
+		case inStr && c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			cur.WriteByte('\'')
+			i++
+		case inStr && c == '\'':
+			inStr = false
+			labels = append(labels, cur.String())
+		case inStr:
+			cur.WriteByte(c)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, cfg, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Name: "orders", Properties: map[string]string{
+			"url": "replicator:secret@tcp(localhost:3306)/",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "localhost:3306", cfg.Addr)
+		assert.NotZero(t, m.ServerID&(1<<31))
+		assert.Equal(t, "orders", m.CheckpointKey)
+		assert.Equal(t, defaultHeartbeatInterval, m.HeartbeatInterval)
+		assert.Empty(t, m.tables)
+	})
+
+	t.Run("all properties", func(t *testing.T) {
+		m, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Name: "orders", Properties: map[string]string{
+			"url":               "replicator:secret@tcp(localhost:3306)/shop",
+			"serverID":          "1001",
+			"tables":            "orders, sales.items,",
+			"checkpointStore":   "statestore",
+			"checkpointKey":     "orders-cdc",
+			"heartbeatInterval": "5s",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1001), m.ServerID)
+		assert.Equal(t, []string{"shop.orders", "sales.items"}, m.tables)
+		assert.Equal(t, "statestore", m.CheckpointStore)
+		assert.Equal(t, "orders-cdc", m.CheckpointKey)
+		assert.Equal(t, 5*time.Second, m.HeartbeatInterval)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{},
+			{"url": "not a dsn"},
+			{"url": "root@tcp(localhost:3306)/", "tables": "orders"},
+			{"url": "root@tcp(localhost:3306)/", "heartbeatInterval": "0"},
+		} {
+			_, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			assert.Error(t, err, props)
+		}
+	})
+}
+
+func newTestBinding(t *testing.T, props map[string]string) (*Binding, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	b := NewBinlog(logger.NewLogger("test")).(*Binding)
+	props["url"] = "root@tcp(localhost:3306)/shop"
+	b.metadata, b.config, err = parseMetadata(bindings.Metadata{Base: metadata.Base{Name: "orders", Properties: props}})
+	require.NoError(t, err)
+	b.db = db
+	return b, mock
+}
+
+func TestCheckpointStore(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		b, _ := newTestBinding(t, map[string]string{})
+		require.Error(t, b.SetCheckpointStore(inmemory.NewInMemoryStateStore(logger.NewLogger("test"))))
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		b, _ := newTestBinding(t, map[string]string{"checkpointStore": "statestore"})
+		_, err := b.startPosition(context.Background())
+		require.Error(t, err)
+	})
+
+	t.Run("resume from checkpoint", func(t *testing.T) {
+		b, _ := newTestBinding(t, map[string]string{"checkpointStore": "statestore"})
+		store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+		require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+		require.NoError(t, store.Set(context.Background(), &state.SetRequest{
+			Key:   checkpointKeyPrefix + "orders",
+			Value: []byte(`{"file":"binlog.000007","pos":1234}`),
+		}))
+		require.NoError(t, b.SetCheckpointStore(store))
+
+		pos, err := b.startPosition(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, Position{File: "binlog.000007", Pos: 1234}, pos)
+	})
+
+	t.Run("current position of the server", func(t *testing.T) {
+		b, mock := newTestBinding(t, map[string]string{})
+		mock.ExpectQuery("SHOW BINARY LOG STATUS").WillReturnError(errors.New("syntax error"))
+		mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+			sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+				AddRow("binlog.000003", 157, "", "", ""))
+
+		pos, err := b.startPosition(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, Position{File: "binlog.000003", Pos: 157}, pos)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// event returns a binlog event as decoded by the binlog syncer.
+func event(eventType replication.EventType, logPos uint32, ev replication.Event) *replication.BinlogEvent {
+	return &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: eventType, LogPos: logPos, Timestamp: 1684318830},
+		Event:  ev,
+	}
+}
+
+// tableMap returns a table map event of a table in the "shop" database, with the columns "id", "name" and "price".
+func tableMap(id uint64, table string) *replication.TableMapEvent {
+	return &replication.TableMapEvent{
+		TableID:     id,
+		Schema:      []byte("shop"),
+		Table:       []byte(table),
+		ColumnCount: 3,
+		ColumnType:  []byte{gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_VARCHAR, gomysql.MYSQL_TYPE_NEWDECIMAL},
+		ColumnMeta:  []uint16{0, 80, 10<<8 | 2},
+	}
+}
+
+func rowsEvent(eventType replication.EventType, logPos uint32, tm *replication.TableMapEvent, rows ...[]any) *replication.BinlogEvent {
+	return event(eventType, logPos, &replication.RowsEvent{
+		Table:   tm,
+		TableID: tm.TableID,
+		Rows:    rows,
+	})
+}
+
+func TestStreamState(t *testing.T) {
+	const columnsQuery = "SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE FROM information_schema.COLUMNS"
+	columns := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE"}).
+			AddRow("id", "int", "int").
+			AddRow("name", "varchar", "varchar(20)").
+			AddRow("price", "decimal", "decimal(10,2)")
+	}
+	orders := tableMap(7, "orders")
+	price1999 := decimal.RequireFromString("19.99")
+	price500 := decimal.RequireFromString("5.00")
+
+	setup := func(t *testing.T, handler bindings.Handler) (*streamState, sqlmock.Sqlmock, state.Store) {
+		b, mock := newTestBinding(t, map[string]string{
+			"checkpointStore": "statestore",
+			"tables":          "orders",
+		})
+		store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+		require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+		require.NoError(t, b.SetCheckpointStore(store))
+		s := &streamState{
+			binding:  b,
+			handler:  handler,
+			columns:  map[uint64][]columnInfo{},
+			lastSave: time.Now(),
+		}
+		return s, mock, store
+	}
+
+	checkpoint := func(t *testing.T, store state.Store) Position {
+		res, err := store.Get(context.Background(), &state.GetRequest{Key: checkpointKeyPrefix + "orders"})
+		require.NoError(t, err)
+		var pos Position
+		if len(res.Data) > 0 {
+			require.NoError(t, json.Unmarshal(res.Data, &pos))
+		}
+		return pos
+	}
+
+	t.Run("changes are sent and checkpointed at commit", func(t *testing.T) {
+		var received []*bindings.ReadResponse
+		s, mock, store := setup(t, func(ctx context.Context, msg *bindings.ReadResponse) ([]byte, error) {
+			received = append(received, msg)
+			return nil, nil
+		})
+		mock.ExpectQuery(columnsQuery).WithArgs("shop", "orders").WillReturnRows(columns())
+		ctx := context.Background()
+
+		for _, ev := range []*replication.BinlogEvent{
+			event(replication.ROTATE_EVENT, 0, &replication.RotateEvent{Position: 4, NextLogName: []byte("binlog.000002")}),
+			event(replication.FORMAT_DESCRIPTION_EVENT, 0, &replication.FormatDescriptionEvent{}),
+			event(replication.QUERY_EVENT, 200, &replication.QueryEvent{Schema: []byte("shop"), Query: []byte("BEGIN")}),
+			event(replication.TABLE_MAP_EVENT, 300, orders),
+			rowsEvent(replication.UPDATE_ROWS_EVENTv2, 400, orders, []any{int32(1), "book", price1999}, []any{int32(1), "book", price500}),
+		} {
+			require.NoError(t, s.process(ctx, ev))
+		}
+		require.Len(t, received, 1)
+		assert.Equal(t, map[string]string{
+			metadataOperationKey: operationUpdate,
+			metadataDatabaseKey:  "shop",
+			metadataTableKey:     "orders",
+			metadataPositionKey:  "binlog.000002:400",
+		}, received[0].Metadata)
+		var c Change
+		require.NoError(t, json.Unmarshal(received[0].Data, &c))
+		assert.Equal(t, uint32(1684318830), c.Timestamp)
+		assert.Equal(t, map[string]any{"id": float64(1), "name": "book", "price": "19.99"}, c.Before)
+		assert.Equal(t, map[string]any{"id": float64(1), "name": "book", "price": "5.00"}, c.After)
+		// The rotation is checkpointed, but not the changes before the commit
+		assert.Equal(t, Position{File: "binlog.000002", Pos: 4}, checkpoint(t, store))
+
+		require.NoError(t, s.process(ctx, event(replication.XID_EVENT, 500, &replication.XIDEvent{})))
+		assert.Equal(t, Position{File: "binlog.000002", Pos: 500}, checkpoint(t, store))
+		assert.Equal(t, Position{File: "binlog.000002", Pos: 500}, s.binding.position)
+
+		// Inserts and deletes have one image of each row
+		received = nil
+		require.NoError(t, s.process(ctx, rowsEvent(replication.WRITE_ROWS_EVENTv2, 600, orders, []any{int32(2), "pen", price500}, []any{int32(3), nil, price500})))
+		require.NoError(t, s.process(ctx, rowsEvent(replication.DELETE_ROWS_EVENTv2, 700, orders, []any{int32(2), "pen", price500})))
+		require.Len(t, received, 3)
+		var inserted, deleted Change
+		require.NoError(t, json.Unmarshal(received[1].Data, &inserted))
+		assert.Equal(t, operationInsert, inserted.Operation)
+		assert.Nil(t, inserted.Before)
+		assert.Equal(t, map[string]any{"id": float64(3), "name": nil, "price": "5.00"}, inserted.After)
+		require.NoError(t, json.Unmarshal(received[2].Data, &deleted))
+		assert.Equal(t, operationDelete, deleted.Operation)
+		assert.Equal(t, map[string]any{"id": float64(2), "name": "pen", "price": "5.00"}, deleted.Before)
+		assert.Nil(t, deleted.After)
+
+		// Columns are cached until a DDL statement
+		require.NoError(t, s.process(ctx, event(replication.TABLE_MAP_EVENT, 800, orders)))
+		require.NoError(t, s.process(ctx, event(replication.QUERY_EVENT, 900, &replication.QueryEvent{Schema: []byte("shop"), Query: []byte("ALTER TABLE orders ADD COLUMN note TEXT")})))
+		mock.ExpectQuery(columnsQuery).WithArgs("shop", "orders").WillReturnRows(columns())
+		require.NoError(t, s.process(ctx, event(replication.TABLE_MAP_EVENT, 1000, orders)))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("changes to other tables are skipped", func(t *testing.T) {
+		s, mock, store := setup(t, func(ctx context.Context, msg *bindings.ReadResponse) ([]byte, error) {
+			t.Fatal("unexpected change")
+			return nil, nil
+		})
+		ctx := context.Background()
+		s.file = "binlog.000002"
+
+		refund := tableMap(8, "refund")
+		require.NoError(t, s.process(ctx, event(replication.TABLE_MAP_EVENT, 300, refund)))
+		require.NoError(t, s.process(ctx, rowsEvent(replication.WRITE_ROWS_EVENTv2, 400, refund, []any{int32(1), "a", price500})))
+		require.NoError(t, s.process(ctx, event(replication.XID_EVENT, 500, &replication.XIDEvent{})))
+		// Idle transactions are checkpointed periodically
+		assert.Equal(t, Position{}, checkpoint(t, store))
+		assert.Equal(t, Position{File: "binlog.000002", Pos: 500}, s.binding.position)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("handler error prevents the commit", func(t *testing.T) {
+		s, mock, store := setup(t, func(ctx context.Context, msg *bindings.ReadResponse) ([]byte, error) {
+			return nil, errors.New("failed")
+		})
+		mock.ExpectQuery(columnsQuery).WithArgs("shop", "orders").WillReturnRows(columns())
+		ctx := context.Background()
+		s.file = "binlog.000002"
+		s.binding.position = Position{File: "binlog.000002", Pos: 200}
+
+		require.NoError(t, s.process(ctx, event(replication.TABLE_MAP_EVENT, 300, orders)))
+		err := s.process(ctx, rowsEvent(replication.WRITE_ROWS_EVENTv2, 400, orders, []any{int32(1), "book", price1999}))
+		require.Error(t, err)
+		assert.Equal(t, Position{File: "binlog.000002", Pos: 200}, s.binding.position)
+		assert.Equal(t, Position{}, checkpoint(t, store))
+	})
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		typ   byte
+		meta  uint16
+		col   columnInfo
+		in    any
+		value any
+	}{
+		{name: "tinyint", typ: gomysql.MYSQL_TYPE_TINY, in: int8(-1), value: int64(-1)},
+		{name: "unsigned tinyint", typ: gomysql.MYSQL_TYPE_TINY, col: columnInfo{Unsigned: true}, in: int8(-1), value: uint64(255)},
+		{name: "unsigned mediumint", typ: gomysql.MYSQL_TYPE_INT24, col: columnInfo{Unsigned: true}, in: int32(-1), value: uint64(0xffffff)},
+		{name: "unsigned int", typ: gomysql.MYSQL_TYPE_LONG, col: columnInfo{Unsigned: true}, in: int32(-1), value: uint64(0xffffffff)},
+		{name: "unsigned bigint", typ: gomysql.MYSQL_TYPE_LONGLONG, col: columnInfo{Unsigned: true}, in: int64(-1), value: uint64(0xffffffffffffffff)},
+		{name: "year", typ: gomysql.MYSQL_TYPE_YEAR, in: 2023, value: int64(2023)},
+		{name: "decimal", typ: gomysql.MYSQL_TYPE_NEWDECIMAL, meta: 10<<8 | 2, in: decimal.RequireFromString("5.00"), value: "5.00"},
+		{name: "datetime", typ: gomysql.MYSQL_TYPE_DATETIME2, in: "2023-05-17 10:20:30", value: "2023-05-17 10:20:30"},
+		{name: "bit", typ: gomysql.MYSQL_TYPE_BIT, in: int64(0x102), value: uint64(0x102)},
+		{name: "varbinary", typ: gomysql.MYSQL_TYPE_VARCHAR, col: columnInfo{Binary: true}, in: "\xff\x00", value: []byte{0xff, 0x00}},
+		{name: "enum", typ: gomysql.MYSQL_TYPE_STRING, col: columnInfo{Labels: []string{"a", "b"}}, in: int64(2), value: "b"},
+		{name: "enum without labels", typ: gomysql.MYSQL_TYPE_STRING, in: int64(2), value: int64(2)},
+		{name: "set", typ: gomysql.MYSQL_TYPE_STRING, col: columnInfo{Labels: []string{"a", "b", "c"}, Set: true}, in: int64(0b101), value: "a,c"},
+		{name: "text", typ: gomysql.MYSQL_TYPE_BLOB, in: []byte("hi"), value: "hi"},
+		{name: "blob", typ: gomysql.MYSQL_TYPE_BLOB, col: columnInfo{Binary: true}, in: []byte("hi"), value: []byte("hi")},
+		{name: "json", typ: gomysql.MYSQL_TYPE_JSON, in: `{"a":[1,true]}`, value: json.RawMessage(`{"a":[1,true]}`)},
+		{name: "null", typ: gomysql.MYSQL_TYPE_LONG, in: nil, value: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.value, convertValue(tt.typ, tt.meta, &tt.col, tt.in))
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	assert.Equal(t, []string{"a", "b c", "it's"}, parseLabels("enum('a','b c','it''s')"))
+	assert.Equal(t, []string{"x,y", "z"}, parseLabels("set('x,y','z')"))
+	assert.Nil(t, parseLabels("int"))
+}
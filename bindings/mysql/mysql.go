@@ -30,6 +30,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 
 	"github.com/dapr/components-contrib/bindings"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
@@ -262,7 +263,7 @@ func initDB(url, pemPath string) (*sql.DB, error) {
 
 	db, err := sql.Open("mysql", url)
 	if err != nil {
-		return nil, fmt.Errorf("error opening DB connection: %w", err)
+		return nil, fmt.Errorf("error opening DB connection: %w", internalutils.RedactError(err, url))
 	}
 
 	return db, nil
@@ -15,11 +15,15 @@ package bindings
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dapr/components-contrib/health"
 )
 
+// ErrMessageTooLarge is returned by output bindings when a request or response exceeds the maximum size configured for the binding.
+var ErrMessageTooLarge = errors.New("message exceeds the maximum size")
+
 // OutputBinding is the interface for an output binding, allowing users to invoke remote systems with optional payloads.
 type OutputBinding interface {
 	Init(ctx context.Context, metadata Metadata) error
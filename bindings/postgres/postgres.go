@@ -25,6 +25,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/dapr/components-contrib/bindings"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
@@ -68,7 +69,7 @@ func (p *Postgres) Init(ctx context.Context, meta bindings.Metadata) error {
 
 	poolConfig, err := pgxpool.ParseConfig(m.ConnectionURL)
 	if err != nil {
-		return fmt.Errorf("error opening DB connection: %w", err)
+		return fmt.Errorf("error opening DB connection: %w", internalutils.RedactError(err, m.ConnectionURL))
 	}
 
 	// This context doesn't control the lifetime of the connection pool, and is
@@ -0,0 +1,268 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+)
+
+const (
+	pluginPgoutput = "pgoutput"
+	pluginWal2JSON = "wal2json"
+
+	operationInsert = "insert"
+	operationUpdate = "update"
+	operationDelete = "delete"
+)
+
+// Change is a change to a row, which is sent to the app as the data of an event.
+type Change struct {
+	// One of "insert", "update" and "delete".
+	Operation string `json:"operation"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	// Position of the change in the write-ahead log.
+	LSN string `json:"lsn"`
+	// Values of the columns after an insert or update.
+	New map[string]any `json:"new,omitempty"`
+	// Values of the columns of the replica identity, usually the primary key, before an update or delete.
+	Old map[string]any `json:"old,omitempty"`
+}
+
+// decoder decodes the messages of an output plugin.
+type decoder interface {
+	// pluginArgs returns the options of the output plugin, for the START_REPLICATION command.
+	pluginArgs() []string
+	// decode returns the changes in a message, and the position to confirm if the message is the commit of a transaction.
+	decode(msg pglogrepl.XLogData) (changes []Change, commit pglogrepl.LSN, err error)
+}
+
+// pgoutputDecoder decodes the messages of pgoutput, the built-in output plugin used for logical replication.
+type pgoutputDecoder struct {
+	publication string
+	// Tables described by the server before the first change to them is sent
+	relations map[uint32]*pglogrepl.RelationMessage
+}
+
+func newPgoutputDecoder(publication string) *pgoutputDecoder {
+	return &pgoutputDecoder{
+		publication: publication,
+		relations:   map[uint32]*pglogrepl.RelationMessage{},
+	}
+}
+
+func (d *pgoutputDecoder) pluginArgs() []string {
+	return []string{
+		"proto_version '1'",
+		"publication_names " + quoteLiteral(quoteIdentifier(d.publication)),
+	}
+}
+
+func (d *pgoutputDecoder) decode(msg pglogrepl.XLogData) ([]Change, pglogrepl.LSN, error) {
+	logicalMsg, err := pglogrepl.Parse(msg.WALData)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid pgoutput message: %w", err)
+	}
+
+	var (
+		c                  Change
+		relation           uint32
+		oldTuple, newTuple *pglogrepl.TupleData
+	)
+	switch m := logicalMsg.(type) {
+	case *pglogrepl.RelationMessage:
+		d.relations[m.RelationID] = m
+		return nil, 0, nil
+	case *pglogrepl.CommitMessage:
+		return nil, m.TransactionEndLSN, nil
+	case *pglogrepl.InsertMessage:
+		c.Operation = operationInsert
+		relation, newTuple = m.RelationID, m.Tuple
+	case *pglogrepl.UpdateMessage:
+		// The old values are only sent if the replica identity changed, or the table has a full replica identity
+		c.Operation = operationUpdate
+		relation, oldTuple, newTuple = m.RelationID, m.OldTuple, m.NewTuple
+	case *pglogrepl.DeleteMessage:
+		c.Operation = operationDelete
+		relation, oldTuple = m.RelationID, m.OldTuple
+	default:
+		// Begin, origin, type, truncate and other messages don't carry row changes
+		return nil, 0, nil
+	}
+
+	rel, ok := d.relations[relation]
+	if !ok {
+		return nil, 0, errors.New("change to a table that was not described by the server")
+	}
+	c.Schema = rel.Namespace
+	c.Table = rel.RelationName
+	c.LSN = msg.WALStart.String()
+	c.Old, err = tupleValues(rel, oldTuple)
+	if err == nil {
+		c.New, err = tupleValues(rel, newTuple)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s message for table %s.%s: %w", c.Operation, c.Schema, c.Table, err)
+	}
+	return []Change{c}, 0, nil
+}
+
+// tupleValues returns the values of the columns of a row, in the text format.
+// Unchanged values of TOASTed columns are not sent by the server, and are omitted.
+func tupleValues(rel *pglogrepl.RelationMessage, tuple *pglogrepl.TupleData) (map[string]any, error) {
+	if tuple == nil {
+		return nil, nil
+	}
+	if len(tuple.Columns) > len(rel.Columns) {
+		return nil, fmt.Errorf("row has %d columns, but the table has %d", len(tuple.Columns), len(rel.Columns))
+	}
+	res := make(map[string]any, len(tuple.Columns))
+	for i, val := range tuple.Columns {
+		col := rel.Columns[i]
+		switch val.DataType {
+		case pglogrepl.TupleDataTypeNull:
+			res[col.Name] = nil
+		case pglogrepl.TupleDataTypeToast:
+			// Unchanged TOASTed value
+		case pglogrepl.TupleDataTypeText:
+			res[col.Name] = textValue(col.DataType, val.Data)
+		default:
+			return nil, fmt.Errorf("invalid value of column %s", col.Name)
+		}
+	}
+	return res, nil
+}
+
+// OIDs of the built-in types that are converted to JSON values other than strings.
+const (
+	boolOID   = 16
+	int8OID   = 20
+	int2OID   = 21
+	int4OID   = 23
+	oidOID    = 26
+	jsonOID   = 114
+	float4OID = 700
+	float8OID = 701
+	jsonbOID  = 3802
+)
+
+// textValue converts a value in the text format to a value with the same JSON representation as in wal2json.
+// Values of other types, including numeric to preserve their precision, are returned as strings.
+func textValue(typeOID uint32, val []byte) any {
+	s := string(val)
+	switch typeOID {
+	case boolOID:
+		return s == "t"
+	case int2OID, int4OID, int8OID, oidOID:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case float4OID, float8OID:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case jsonOID, jsonbOID:
+		if json.Valid(val) {
+			return json.RawMessage(s)
+		}
+	}
+	return s
+}
+
+// wal2jsonDecoder decodes the messages of the wal2json output plugin, in the format version 2, with one message per change.
+// See: https://github.com/eulerto/wal2json
+type wal2jsonDecoder struct {
+	tables []string
+}
+
+type wal2jsonMessage struct {
+	Action   string           `json:"action"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+type wal2jsonColumn struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (d *wal2jsonDecoder) pluginArgs() []string {
+	args := []string{`"format-version" '2'`}
+	if len(d.tables) > 0 {
+		// Commas and periods in the names of tables must be escaped with backslashes
+		escaped := make([]string, len(d.tables))
+		for i, t := range d.tables {
+			schema, table, _ := strings.Cut(t, ".")
+			escaped[i] = wal2jsonEscape(schema) + "." + wal2jsonEscape(table)
+		}
+		args = append(args, `"add-tables" `+quoteLiteral(strings.Join(escaped, ",")))
+	}
+	return args
+}
+
+func wal2jsonEscape(name string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ".", `\.`).Replace(name)
+}
+
+func (d *wal2jsonDecoder) decode(msg pglogrepl.XLogData) ([]Change, pglogrepl.LSN, error) {
+	var m wal2jsonMessage
+	err := json.Unmarshal(msg.WALData, &m)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid wal2json message: %w", err)
+	}
+
+	c := Change{
+		Schema: m.Schema,
+		Table:  m.Table,
+		LSN:    msg.WALStart.String(),
+	}
+	switch m.Action {
+	case "C":
+		// The position after the commit message, so the transaction isn't streamed again
+		return nil, msg.WALStart + pglogrepl.LSN(len(msg.WALData)), nil
+	case "I":
+		c.Operation = operationInsert
+		c.New = wal2jsonValues(m.Columns)
+	case "U":
+		c.Operation = operationUpdate
+		c.New = wal2jsonValues(m.Columns)
+		c.Old = wal2jsonValues(m.Identity)
+	case "D":
+		c.Operation = operationDelete
+		c.Old = wal2jsonValues(m.Identity)
+	default:
+		// Begin, truncate and logical decoding messages don't carry row changes
+		return nil, 0, nil
+	}
+	return []Change{c}, 0, nil
+}
+
+func wal2jsonValues(cols []wal2jsonColumn) map[string]any {
+	if len(cols) == 0 {
+		return nil
+	}
+	res := make(map[string]any, len(cols))
+	for _, c := range cols {
+		res[c.Name] = c.Value
+	}
+	return res
+}
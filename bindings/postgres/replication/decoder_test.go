@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pgoutputMessage builds pgoutput messages for tests.
+type pgoutputMessage []byte
+
+func (m pgoutputMessage) byte(b byte) pgoutputMessage {
+	return append(m, b)
+}
+
+func (m pgoutputMessage) uint16(v uint16) pgoutputMessage {
+	return binary.BigEndian.AppendUint16(m, v)
+}
+
+func (m pgoutputMessage) uint32(v uint32) pgoutputMessage {
+	return binary.BigEndian.AppendUint32(m, v)
+}
+
+func (m pgoutputMessage) uint64(v uint64) pgoutputMessage {
+	return binary.BigEndian.AppendUint64(m, v)
+}
+
+func (m pgoutputMessage) string(s string) pgoutputMessage {
+	return append(append(m, s...), 0)
+}
+
+func (m pgoutputMessage) text(s string) pgoutputMessage {
+	return append(m.byte('t').uint32(uint32(len(s))), s...)
+}
+
+func relationMessage() pgoutputMessage {
+	m := pgoutputMessage{'R'}.uint32(1).string("public").string("orders").byte('d').uint16(4)
+	m = m.byte(1).string("id").uint32(int8OID).uint32(0xffffffff)
+	m = m.byte(0).string("status").uint32(25).uint32(0xffffffff)
+	m = m.byte(0).string("paid").uint32(boolOID).uint32(0xffffffff)
+	m = m.byte(0).string("details").uint32(jsonbOID).uint32(0xffffffff)
+	return m
+}
+
+func TestPgoutputDecoder(t *testing.T) {
+	d := newPgoutputDecoder("pub")
+	assert.Equal(t, []string{"proto_version '1'", `publication_names '"pub"'`}, d.pluginArgs())
+
+	decode := func(m pgoutputMessage) ([]Change, pglogrepl.LSN, error) {
+		return d.decode(pglogrepl.XLogData{WALStart: 0x1_00000010, WALData: m})
+	}
+
+	t.Run("change before relation", func(t *testing.T) {
+		_, _, err := decode(pgoutputMessage{'I'}.uint32(1).byte('N').uint16(0))
+		require.Error(t, err)
+	})
+
+	changes, commit, err := decode(relationMessage())
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Zero(t, commit)
+
+	t.Run("begin", func(t *testing.T) {
+		changes, commit, err := decode(pgoutputMessage{'B'}.uint64(1).uint64(2).uint32(3))
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Zero(t, commit)
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		changes, _, err := decode(pgoutputMessage{'I'}.uint32(1).byte('N').uint16(4).
+			text("42").text("new").text("f").text(`{"a":1}`))
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, Change{
+			Operation: operationInsert,
+			Schema:    "public",
+			Table:     "orders",
+			LSN:       "1/10",
+			New: map[string]any{
+				"id":      int64(42),
+				"status":  "new",
+				"paid":    false,
+				"details": json.RawMessage(`{"a":1}`),
+			},
+		}, changes[0])
+	})
+
+	t.Run("update with old key and unchanged TOASTed value", func(t *testing.T) {
+		changes, _, err := decode(pgoutputMessage{'U'}.uint32(1).
+			byte('K').uint16(4).text("42").byte('n').byte('n').byte('n').
+			byte('N').uint16(4).text("43").text("paid").text("t").byte('u'))
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, operationUpdate, changes[0].Operation)
+		assert.Equal(t, map[string]any{"id": int64(42), "status": nil, "paid": nil, "details": nil}, changes[0].Old)
+		assert.Equal(t, map[string]any{"id": int64(43), "status": "paid", "paid": true}, changes[0].New)
+	})
+
+	t.Run("update without old values", func(t *testing.T) {
+		changes, _, err := decode(pgoutputMessage{'U'}.uint32(1).
+			byte('N').uint16(2).text("42").text("shipped"))
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Nil(t, changes[0].Old)
+		assert.Equal(t, map[string]any{"id": int64(42), "status": "shipped"}, changes[0].New)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		changes, _, err := decode(pgoutputMessage{'D'}.uint32(1).byte('K').uint16(1).text("42"))
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, operationDelete, changes[0].Operation)
+		assert.Equal(t, map[string]any{"id": int64(42)}, changes[0].Old)
+		assert.Nil(t, changes[0].New)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		changes, commit, err := decode(pgoutputMessage{'C'}.byte(0).uint64(0x100).uint64(0x180).uint64(0))
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Equal(t, pglogrepl.LSN(0x180), commit)
+	})
+}
+
+func TestWal2JSONDecoder(t *testing.T) {
+	d := &wal2jsonDecoder{tables: []string{"public.orders", "sales.line.items"}}
+	assert.Equal(t, []string{`"format-version" '2'`, `"add-tables" 'public.orders,sales.line\.items'`}, d.pluginArgs())
+
+	decode := func(s string) ([]Change, pglogrepl.LSN, error) {
+		return d.decode(pglogrepl.XLogData{WALStart: 0x100, WALData: []byte(s)})
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		changes, commit, err := decode(`{"action":"I","schema":"public","table":"orders","columns":[{"name":"id","type":"integer","value":1},{"name":"status","type":"text","value":"new"}]}`)
+		require.NoError(t, err)
+		assert.Zero(t, commit)
+		require.Len(t, changes, 1)
+		assert.Equal(t, operationInsert, changes[0].Operation)
+		assert.Equal(t, "0/100", changes[0].LSN)
+		b, err := json.Marshal(changes[0].New)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":1,"status":"new"}`, string(b))
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		changes, _, err := decode(`{"action":"U","schema":"public","table":"orders","columns":[{"name":"id","value":1}],"identity":[{"name":"id","value":2}]}`)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, operationUpdate, changes[0].Operation)
+		assert.Equal(t, json.RawMessage("2"), changes[0].Old["id"])
+
+		changes, _, err = decode(`{"action":"D","schema":"public","table":"orders","identity":[{"name":"id","value":1}]}`)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, operationDelete, changes[0].Operation)
+		assert.Nil(t, changes[0].New)
+	})
+
+	t.Run("begin and commit", func(t *testing.T) {
+		changes, commit, err := decode(`{"action":"B"}`)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Zero(t, commit)
+
+		changes, commit, err = decode(`{"action":"C"}`)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Equal(t, pglogrepl.LSN(0x100+len(`{"action":"C"}`)), commit)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, _, err := decode(`{`)
+		require.Error(t, err)
+	})
+}
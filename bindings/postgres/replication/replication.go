@@ -0,0 +1,424 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replication contains an input binding that streams the changes to rows of PostgreSQL tables from a logical replication slot.
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/dapr/components-contrib/bindings"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	connectionURLKey = "url"
+
+	defaultSlotName              = "dapr"
+	defaultPublicationName       = "dapr"
+	defaultStatusInterval        = 10 * time.Second
+	defaultSchema                = "public"
+	metadataOperationKey         = "operation"
+	metadataSchemaKey            = "schema"
+	metadataTableKey             = "table"
+	metadataLSNKey               = "lsn"
+	replicationRuntimeParamValue = "database"
+)
+
+type replicationMetadata struct {
+	// ConnectionURL is the connection string to connect to the database, with a user that has the REPLICATION attribute.
+	ConnectionURL string `mapstructure:"url"`
+	// Name of the logical replication slot, which is created if it doesn't exist.
+	SlotName string `mapstructure:"slotName"`
+	// Output plugin of the slot: "pgoutput" (default) or "wal2json".
+	Plugin string `mapstructure:"plugin"`
+	// Name of the publication with the tables whose changes are streamed by pgoutput, which is created if it doesn't exist.
+	PublicationName string `mapstructure:"publicationName"`
+	// Comma-separated list of tables whose changes are sent to the app, as "schema.table" or "table" for tables in the public schema.
+	// If empty, changes to all tables are sent.
+	Tables string `mapstructure:"tables"`
+	// Interval of the status updates sent to the server with the position of the last processed change.
+	StatusInterval time.Duration `mapstructure:"statusInterval"`
+
+	tables []string
+}
+
+// Binding is an input binding that sends the changes to rows streamed from a logical replication slot to the app.
+// The position of the last change processed by the app is confirmed to the server, so after a restart streaming resumes from it.
+type Binding struct {
+	logger          logger.Logger
+	metadata        replicationMetadata
+	readRetryPolicy bindings.ReadRetryPolicy
+	config          *pgconn.Config
+	closed          atomic.Bool
+	closeCh         chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewReplication returns a new PostgreSQL logical replication input binding.
+func NewReplication(logger logger.Logger) bindings.InputBinding {
+	return &Binding{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Init initializes the binding, creating the slot and the publication if they don't exist.
+func (b *Binding) Init(ctx context.Context, meta bindings.Metadata) error {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	b.metadata = m
+
+	b.readRetryPolicy, err = bindings.ParseReadRetryPolicy(meta.Properties)
+	if err != nil {
+		return err
+	}
+
+	b.config, err = pgconn.ParseConfig(m.ConnectionURL)
+	if err != nil {
+		return fmt.Errorf("error parsing the connection string: %w", internalutils.RedactError(err, m.ConnectionURL))
+	}
+	b.config.RuntimeParams["replication"] = replicationRuntimeParamValue
+
+	conn, err := pgconn.ConnectConfig(ctx, b.config)
+	if err != nil {
+		return fmt.Errorf("error connecting to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if m.Plugin == pluginPgoutput {
+		tables := "ALL TABLES"
+		if len(m.tables) > 0 {
+			quoted := make([]string, len(m.tables))
+			for i, t := range m.tables {
+				schema, table, _ := strings.Cut(t, ".")
+				quoted[i] = quoteIdentifier(schema) + "." + quoteIdentifier(table)
+			}
+			tables = "TABLE " + strings.Join(quoted, ", ")
+		}
+		_, err = conn.Exec(ctx, "CREATE PUBLICATION "+quoteIdentifier(m.PublicationName)+" FOR "+tables).ReadAll()
+		if err != nil && !isDuplicateObject(err) {
+			return fmt.Errorf("error creating publication '%s': %w", m.PublicationName, err)
+		}
+	}
+
+	_, err = pglogrepl.CreateReplicationSlot(ctx, conn, quoteIdentifier(m.SlotName), m.Plugin, pglogrepl.CreateReplicationSlotOptions{})
+	if err != nil && !isDuplicateObject(err) {
+		return fmt.Errorf("error creating replication slot '%s': %w", m.SlotName, err)
+	}
+
+	return nil
+}
+
+func parseMetadata(meta bindings.Metadata) (replicationMetadata, error) {
+	m := replicationMetadata{
+		SlotName:        defaultSlotName,
+		Plugin:          pluginPgoutput,
+		PublicationName: defaultPublicationName,
+		StatusInterval:  defaultStatusInterval,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.ConnectionURL == "" {
+		return m, fmt.Errorf("required metadata not set: %s", connectionURLKey)
+	}
+	if m.SlotName == "" {
+		return m, errors.New("metadata property 'slotName' must not be empty")
+	}
+	m.Plugin = strings.ToLower(m.Plugin)
+	switch m.Plugin {
+	case pluginPgoutput:
+		if m.PublicationName == "" {
+			return m, errors.New("metadata property 'publicationName' must not be empty")
+		}
+	case pluginWal2JSON:
+	default:
+		return m, fmt.Errorf("invalid value '%s' for metadata property 'plugin': must be '%s' or '%s'", m.Plugin, pluginPgoutput, pluginWal2JSON)
+	}
+	if m.StatusInterval <= 0 {
+		return m, errors.New("metadata property 'statusInterval' must be greater than 0")
+	}
+
+	for _, t := range strings.Split(m.Tables, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !strings.Contains(t, ".") {
+			t = defaultSchema + "." + t
+		}
+		m.tables = append(m.tables, t)
+	}
+
+	return m, nil
+}
+
+// isDuplicateObject returns true if the error is because the object to create, such as the slot or the publication, already exists.
+func isDuplicateObject(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42710"
+}
+
+// quoteIdentifier quotes an identifier, such as the name of a slot or publication.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes a string literal, such as an option of the output plugin.
+func quoteLiteral(val string) string {
+	return `'` + strings.ReplaceAll(val, `'`, `''`) + `'`
+}
+
+func (b *Binding) newDecoder() decoder {
+	if b.metadata.Plugin == pluginWal2JSON {
+		return &wal2jsonDecoder{tables: b.metadata.tables}
+	}
+	return newPgoutputDecoder(b.metadata.PublicationName)
+}
+
+// Read starts streaming the changes from the slot to the handler.
+func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
+	if b.closed.Load() {
+		return errors.New("binding is closed")
+	}
+
+	readCtx, cancel := context.WithCancel(ctx)
+	b.wg.Add(2)
+
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		select {
+		case <-b.closeCh:
+		case <-readCtx.Done():
+		}
+	}()
+
+	go func() {
+		defer b.wg.Done()
+		// Changes that were not confirmed are streamed again after reconnecting
+		err := bindings.RunReadLoop(readCtx, b.readRetryPolicy, func(ctx context.Context) error {
+			return b.stream(ctx, handler)
+		}, func(err error, delay time.Duration) {
+			b.logger.Errorf("Error streaming changes from replication slot '%s', reconnecting in %s: %v", b.metadata.SlotName, delay, err)
+		})
+		if err != nil {
+			b.logger.Errorf("Stopped streaming changes from replication slot '%s': %v", b.metadata.SlotName, err)
+		}
+	}()
+
+	return nil
+}
+
+// stream streams the changes from the slot until the context is canceled or an error occurs.
+func (b *Binding) stream(ctx context.Context, handler bindings.Handler) error {
+	conn, err := pgconn.ConnectConfig(ctx, b.config)
+	if err != nil {
+		return fmt.Errorf("error connecting to the database: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	dec := b.newDecoder()
+	// Streaming starts from the position confirmed last
+	err = pglogrepl.StartReplication(ctx, conn, quoteIdentifier(b.metadata.SlotName), 0, pglogrepl.StartReplicationOptions{
+		PluginArgs: dec.pluginArgs(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start replication: %w", err)
+	}
+	b.logger.Infof("Streaming changes from replication slot '%s'", b.metadata.SlotName)
+
+	s := &streamState{
+		binding: b,
+		decoder: dec,
+		handler: handler,
+	}
+	nextStatus := time.Now().Add(b.metadata.StatusInterval)
+	for {
+		if time.Now().After(nextStatus) {
+			err = s.sendStatusUpdate(ctx, conn)
+			if err != nil {
+				return fmt.Errorf("error sending status update: %w", err)
+			}
+			nextStatus = time.Now().Add(b.metadata.StatusInterval)
+		}
+
+		recvCtx, recvCancel := context.WithDeadline(ctx, nextStatus)
+		msg, err := conn.ReceiveMessage(recvCtx)
+		recvCancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				// Confirm the changes that were processed before stopping
+				_ = s.sendStatusUpdate(context.Background(), conn)
+				return nil
+			}
+			if pgconn.Timeout(err) {
+				continue
+			}
+			return fmt.Errorf("error receiving message: %w", err)
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.CopyData:
+			reply, err := s.process(ctx, m.Data)
+			if err != nil {
+				return err
+			}
+			if reply {
+				nextStatus = time.Time{}
+			}
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(m)
+		case *pgproto3.CopyDone:
+			return errors.New("replication stream was closed by the server")
+		}
+	}
+}
+
+// streamState is the state of a replication stream.
+type streamState struct {
+	binding *Binding
+	decoder decoder
+	handler bindings.Handler
+	// Position up to which all changes were processed by the app
+	confirmed pglogrepl.LSN
+	// Whether the changes of a transaction are being received
+	inTransaction bool
+}
+
+// process processes a message of the replication stream, and returns whether the server requested a status update.
+func (s *streamState) process(ctx context.Context, data []byte) (bool, error) {
+	if len(data) == 0 {
+		return false, nil
+	}
+	switch data[0] {
+	case pglogrepl.PrimaryKeepaliveMessageByteID:
+		ka, err := pglogrepl.ParsePrimaryKeepaliveMessage(data[1:])
+		if err != nil {
+			return false, err
+		}
+		// All changes before the end of the WAL sent by the server were received, and processed unless a transaction is in progress
+		if !s.inTransaction && ka.ServerWALEnd > s.confirmed {
+			s.confirmed = ka.ServerWALEnd
+		}
+		return ka.ReplyRequested, nil
+
+	case pglogrepl.XLogDataByteID:
+		xld, err := pglogrepl.ParseXLogData(data[1:])
+		if err != nil {
+			return false, err
+		}
+		changes, commit, err := s.decoder.decode(xld)
+		if err != nil {
+			return false, err
+		}
+		if commit != 0 {
+			s.inTransaction = false
+			if commit > s.confirmed {
+				s.confirmed = commit
+			}
+			return false, nil
+		}
+		s.inTransaction = true
+		for _, c := range changes {
+			err = s.binding.handleChange(ctx, s.handler, c)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// sendStatusUpdate reports the position up to which the changes were processed.
+// The server keeps the changes after it in the slot, and streams them again after a restart.
+func (s *streamState) sendStatusUpdate(ctx context.Context, conn *pgconn.PgConn) error {
+	// The flushed and applied positions default to the written one
+	return pglogrepl.SendStandbyStatusUpdate(ctx, conn, pglogrepl.StandbyStatusUpdate{
+		WALWritePosition: s.confirmed,
+	})
+}
+
+// handleChange sends a change to the app, if it's to one of the tables of the binding.
+func (b *Binding) handleChange(ctx context.Context, handler bindings.Handler, c Change) error {
+	if !b.includesTable(c.Schema, c.Table) {
+		return nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding change as JSON: %w", err)
+	}
+	_, err = handler(ctx, &bindings.ReadResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataOperationKey: c.Operation,
+			metadataSchemaKey:    c.Schema,
+			metadataTableKey:     c.Table,
+			metadataLSNKey:       c.LSN,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error handling change at %s to table %s.%s: %w", c.LSN, c.Schema, c.Table, err)
+	}
+	return nil
+}
+
+func (b *Binding) includesTable(schema, table string) bool {
+	if len(b.metadata.tables) == 0 {
+		return true
+	}
+	name := schema + "." + table
+	for _, t := range b.metadata.tables {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops streaming changes.
+func (b *Binding) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		close(b.closeCh)
+	}
+	b.wg.Wait()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (b *Binding) GetComponentMetadata() map[string]string {
+	metadataStruct := replicationMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
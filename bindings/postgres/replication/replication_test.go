@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replication
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"url": "postgres://localhost/db",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, defaultSlotName, m.SlotName)
+		assert.Equal(t, pluginPgoutput, m.Plugin)
+		assert.Equal(t, defaultPublicationName, m.PublicationName)
+		assert.Equal(t, defaultStatusInterval, m.StatusInterval)
+		assert.Empty(t, m.tables)
+	})
+
+	t.Run("all properties", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"url":             "postgres://localhost/db",
+			"slotName":        "orders_slot",
+			"plugin":          "WAL2JSON",
+			"publicationName": "orders_pub",
+			"tables":          "orders, sales.items,",
+			"statusInterval":  "1s",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "orders_slot", m.SlotName)
+		assert.Equal(t, pluginWal2JSON, m.Plugin)
+		assert.Equal(t, "orders_pub", m.PublicationName)
+		assert.Equal(t, []string{"public.orders", "sales.items"}, m.tables)
+		assert.Equal(t, time.Second, m.StatusInterval)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{},
+			{"url": "postgres://localhost/db", "slotName": ""},
+			{"url": "postgres://localhost/db", "plugin": "test_decoding"},
+			{"url": "postgres://localhost/db", "publicationName": ""},
+			{"url": "postgres://localhost/db", "statusInterval": "0"},
+		} {
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			assert.Error(t, err, props)
+		}
+	})
+}
+
+func xLogDataMessage(start pglogrepl.LSN, data []byte) []byte {
+	msg := []byte{pglogrepl.XLogDataByteID}
+	msg = binary.BigEndian.AppendUint64(msg, uint64(start))
+	msg = binary.BigEndian.AppendUint64(msg, uint64(start))
+	msg = binary.BigEndian.AppendUint64(msg, 0)
+	return append(msg, data...)
+}
+
+func keepaliveMessage(end pglogrepl.LSN, reply bool) []byte {
+	msg := []byte{pglogrepl.PrimaryKeepaliveMessageByteID}
+	msg = binary.BigEndian.AppendUint64(msg, uint64(end))
+	msg = binary.BigEndian.AppendUint64(msg, 0)
+	if reply {
+		return append(msg, 1)
+	}
+	return append(msg, 0)
+}
+
+func TestStreamState(t *testing.T) {
+	b := NewReplication(logger.NewLogger("test")).(*Binding)
+	b.metadata.tables = []string{"public.orders"}
+
+	var (
+		received   []Change
+		handlerErr error
+	)
+	s := &streamState{
+		binding: b,
+		decoder: &wal2jsonDecoder{},
+		handler: func(_ context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			if handlerErr != nil {
+				return nil, handlerErr
+			}
+			var c Change
+			require.NoError(t, json.Unmarshal(res.Data, &c))
+			assert.Equal(t, c.Operation, res.Metadata[metadataOperationKey])
+			assert.Equal(t, c.Table, res.Metadata[metadataTableKey])
+			received = append(received, c)
+			return nil, nil
+		},
+	}
+	process := func(msg []byte) bool {
+		reply, err := s.process(context.Background(), msg)
+		require.NoError(t, err)
+		return reply
+	}
+
+	commit := []byte(`{"action":"C"}`)
+	process(xLogDataMessage(0x100, []byte(`{"action":"B"}`)))
+	process(xLogDataMessage(0x110, []byte(`{"action":"I","schema":"public","table":"orders","columns":[{"name":"id","value":1}]}`)))
+	process(xLogDataMessage(0x120, []byte(`{"action":"I","schema":"public","table":"other","columns":[{"name":"id","value":1}]}`)))
+
+	// Changes of a transaction in progress aren't confirmed
+	assert.True(t, process(keepaliveMessage(0x200, true)))
+	assert.Zero(t, s.confirmed)
+
+	process(xLogDataMessage(0x130, commit))
+	assert.Equal(t, pglogrepl.LSN(0x130+len(commit)), s.confirmed)
+	require.Len(t, received, 1)
+	assert.Equal(t, "orders", received[0].Table)
+
+	assert.False(t, process(keepaliveMessage(0x200, false)))
+	assert.Equal(t, pglogrepl.LSN(0x200), s.confirmed)
+
+	// Changes that the app fails to process are not confirmed
+	handlerErr = errors.New("app error")
+	process(xLogDataMessage(0x300, []byte(`{"action":"B"}`)))
+	_, err := s.process(context.Background(), xLogDataMessage(0x310, []byte(`{"action":"D","schema":"public","table":"orders","identity":[{"name":"id","value":1}]}`)))
+	require.ErrorIs(t, err, handlerErr)
+	assert.Equal(t, pglogrepl.LSN(0x200), s.confirmed)
+}
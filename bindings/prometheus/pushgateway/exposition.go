@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pushgateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	metricTypeCounter = "counter"
+	metricTypeGauge   = "gauge"
+	metricTypeUntyped = "untyped"
+)
+
+var (
+	metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// metric is a sample sent in the request data.
+type metric struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Help   string            `json:"help"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// parseMetrics parses the request data, which is either a single metric or an array of metrics.
+func parseMetrics(data []byte) ([]metric, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("request data must contain at least one metric")
+	}
+
+	var metrics []metric
+	var err error
+	if data[0] == '[' {
+		err = json.Unmarshal(data, &metrics)
+	} else {
+		metrics = make([]metric, 1)
+		err = json.Unmarshal(data, &metrics[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	if len(metrics) == 0 {
+		return nil, errors.New("request data must contain at least one metric")
+	}
+
+	for i := range metrics {
+		err = metrics[i].validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+func (m *metric) validate() error {
+	if !metricNameRegex.MatchString(m.Name) {
+		return fmt.Errorf("invalid metric name '%s'", m.Name)
+	}
+	m.Type = strings.ToLower(m.Type)
+	switch m.Type {
+	case "":
+		m.Type = metricTypeUntyped
+	case metricTypeCounter, metricTypeGauge, metricTypeUntyped:
+		// Valid
+	default:
+		return fmt.Errorf("unsupported type '%s' for metric '%s': must be one of counter, gauge, untyped", m.Type, m.Name)
+	}
+	if m.Type == metricTypeCounter && m.Value < 0 {
+		return fmt.Errorf("invalid value for counter '%s': must not be negative", m.Name)
+	}
+	for name := range m.Labels {
+		if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name '%s' for metric '%s'", name, m.Name)
+		}
+	}
+	return nil
+}
+
+// formatMetrics returns the metrics in the Prometheus text exposition format.
+// Samples of the same metric are grouped together, under a single HELP and TYPE line, as required by the format.
+func formatMetrics(metrics []metric) ([]byte, error) {
+	order := make([]string, 0, len(metrics))
+	byName := make(map[string][]metric, len(metrics))
+	for _, m := range metrics {
+		if _, ok := byName[m.Name]; !ok {
+			order = append(order, m.Name)
+		}
+		byName[m.Name] = append(byName[m.Name], m)
+	}
+
+	var buf bytes.Buffer
+	for _, name := range order {
+		samples := byName[name]
+		help, typ := samples[0].Help, samples[0].Type
+		for _, s := range samples[1:] {
+			if s.Type != typ {
+				return nil, fmt.Errorf("metric '%s' has conflicting types '%s' and '%s'", name, typ, s.Type)
+			}
+			if help == "" {
+				help = s.Help
+			}
+		}
+
+		if help != "" {
+			buf.WriteString("# HELP " + name + " " + helpEscaper.Replace(help) + "\n")
+		}
+		buf.WriteString("# TYPE " + name + " " + typ + "\n")
+		for _, s := range samples {
+			buf.WriteString(name)
+			writeLabels(&buf, s.Labels)
+			buf.WriteByte(' ')
+			buf.WriteString(formatValue(s.Value))
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeLabels(buf *bytes.Buffer, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(name + `="` + labelValueEscaper.Replace(labels[name]) + `"`)
+	}
+	buf.WriteByte('}')
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pushgateway
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type pushgatewayMetadata struct {
+	// Base URL of the Pushgateway, such as "http://pushgateway:9091".
+	URL string `mapstructure:"url"`
+	// Name of the job the metrics are grouped under. Can be overridden per request.
+	Job string `mapstructure:"job"`
+	// Optional instance the metrics are grouped under. Can be overridden per request.
+	Instance string `mapstructure:"instance"`
+	// Credentials for basic authentication.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Timeout for requests to the Pushgateway.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func parseMetadata(meta bindings.Metadata) (pushgatewayMetadata, error) {
+	m := pushgatewayMetadata{
+		Timeout: defaultTimeout,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.URL == "" {
+		return m, errors.New("metadata property 'url' is required")
+	}
+	u, err := url.Parse(m.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return m, errors.New("metadata property 'url' must be an absolute http or https URL")
+	}
+	if m.Job == "" {
+		return m, errors.New("metadata property 'job' is required")
+	}
+	if m.Timeout <= 0 {
+		m.Timeout = defaultTimeout
+	}
+
+	return m, nil
+}
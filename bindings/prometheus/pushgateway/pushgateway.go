@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// PushOperation adds the metrics to the group, replacing the existing metrics with the same names.
+	PushOperation bindings.OperationKind = "push"
+	// ReplaceOperation replaces all the metrics of the group.
+	ReplaceOperation bindings.OperationKind = "replace"
+
+	// keys from request's metadata.
+	jobKey      = "job"
+	instanceKey = "instance"
+
+	// keys from response's metadata.
+	respStatusCodeKey = "statusCode"
+
+	contentTypeTextFormat = "text/plain; version=0.0.4; charset=utf-8"
+	maxErrorBodyLength    = 1024
+)
+
+// Pushgateway is an output binding that pushes metrics to a Prometheus Pushgateway.
+type Pushgateway struct {
+	metadata pushgatewayMetadata
+	client   *http.Client
+	logger   logger.Logger
+}
+
+// NewPushgateway returns a new Prometheus Pushgateway output binding.
+func NewPushgateway(logger logger.Logger) bindings.OutputBinding {
+	return &Pushgateway{logger: logger}
+}
+
+// Init performs metadata parsing.
+func (p *Pushgateway) Init(_ context.Context, meta bindings.Metadata) error {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	p.metadata = m
+	p.client = &http.Client{
+		Timeout: m.Timeout,
+	}
+	return nil
+}
+
+// Operations returns the list of operations supported by the binding.
+func (p *Pushgateway) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		PushOperation,
+		ReplaceOperation,
+		bindings.DeleteOperation,
+	}
+}
+
+// Invoke pushes the metrics in the request's data to the Pushgateway, or deletes the metrics of the group.
+func (p *Pushgateway) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	var (
+		method string
+		body   []byte
+	)
+	switch req.Operation {
+	case PushOperation, ReplaceOperation:
+		metrics, err := parseMetrics(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		body, err = formatMetrics(metrics)
+		if err != nil {
+			return nil, err
+		}
+		method = http.MethodPost
+		if req.Operation == ReplaceOperation {
+			method = http.MethodPut
+		}
+	case bindings.DeleteOperation:
+		method = http.MethodDelete
+	default:
+		return nil, fmt.Errorf("invalid operation type: %s. Expected %s, %s or %s", req.Operation, PushOperation, ReplaceOperation, bindings.DeleteOperation)
+	}
+
+	job, instance := p.metadata.Job, p.metadata.Instance
+	if val := req.Metadata[jobKey]; val != "" {
+		job = val
+	}
+	if val, ok := req.Metadata[instanceKey]; ok {
+		instance = val
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, p.groupURL(job, instance), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", contentTypeTextFormat)
+	}
+	if p.metadata.Username != "" {
+		request.SetBasicAuth(p.metadata.Username, p.metadata.Password)
+	}
+
+	resp, err := p.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to the Pushgateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The Pushgateway responds with 200 or 202 on success
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return nil, fmt.Errorf("the Pushgateway responded with status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			respStatusCodeKey: strconv.Itoa(resp.StatusCode),
+		},
+	}, nil
+}
+
+// groupURL returns the URL of the group identified by the job and, optionally, the instance.
+func (p *Pushgateway) groupURL(job string, instance string) string {
+	u := strings.TrimSuffix(p.metadata.URL, "/") + "/metrics/" + groupingKeyPath(jobKey, job)
+	if instance != "" {
+		u += "/" + groupingKeyPath(instanceKey, instance)
+	}
+	return u
+}
+
+// groupingKeyPath returns the path segment for a label of the grouping key.
+// Values containing a "/" are base64-encoded, as supported by the Pushgateway; empty values are encoded as "=".
+func groupingKeyPath(name string, value string) string {
+	switch {
+	case value == "":
+		return name + "@base64/="
+	case strings.Contains(value, "/"):
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	default:
+		return name + "/" + url.PathEscape(value)
+	}
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (p *Pushgateway) GetComponentMetadata() map[string]string {
+	metadataStruct := pushgatewayMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
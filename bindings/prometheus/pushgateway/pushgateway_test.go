@@ -0,0 +1,257 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pushgateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+type recordedRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        string
+	user        string
+	password    string
+}
+
+func startTestServer(t *testing.T, status int) (string, *[]recordedRequest) {
+	t.Helper()
+
+	requests := []recordedRequest{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, password, _ := r.BasicAuth()
+		requests = append(requests, recordedRequest{
+			method:      r.Method,
+			path:        r.URL.EscapedPath(),
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+			user:        user,
+			password:    password,
+		})
+		w.WriteHeader(status)
+		if status >= 400 {
+			w.Write([]byte("bad metrics"))
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s.URL, &requests
+}
+
+func initBinding(t *testing.T, props map[string]string) *Pushgateway {
+	t.Helper()
+
+	b := NewPushgateway(logger.NewLogger("test")).(*Pushgateway)
+	err := b.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"url": "http://localhost:9091",
+			"job": "myjob",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:9091", m.URL)
+		assert.Equal(t, "myjob", m.Job)
+		assert.Equal(t, defaultTimeout, m.Timeout)
+	})
+
+	invalid := map[string]map[string]string{
+		"missing url": {"job": "myjob"},
+		"invalid url": {"url": "localhost:9091", "job": "myjob"},
+		"missing job": {"url": "http://localhost:9091"},
+	}
+	for name, props := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestFormatMetrics(t *testing.T) {
+	t.Run("single metric", func(t *testing.T) {
+		metrics, err := parseMetrics([]byte(`{"name":"job_duration_seconds","type":"gauge","help":"Duration of the job.","value":12.5}`))
+		require.NoError(t, err)
+		out, err := formatMetrics(metrics)
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP job_duration_seconds Duration of the job.\n"+
+			"# TYPE job_duration_seconds gauge\n"+
+			"job_duration_seconds 12.5\n", string(out))
+	})
+
+	t.Run("samples are grouped by name", func(t *testing.T) {
+		metrics, err := parseMetrics([]byte(`[
+			{"name":"processed_total","type":"counter","labels":{"stage":"b","kind":"x"},"value":3},
+			{"name":"last_run","value":1.7e9},
+			{"name":"processed_total","type":"COUNTER","labels":{"stage":"a\"\\\n"},"value":1}
+		]`))
+		require.NoError(t, err)
+		out, err := formatMetrics(metrics)
+		require.NoError(t, err)
+		assert.Equal(t, "# TYPE processed_total counter\n"+
+			`processed_total{kind="x",stage="b"} 3`+"\n"+
+			`processed_total{stage="a\"\\\n"} 1`+"\n"+
+			"# TYPE last_run untyped\n"+
+			"last_run 1.7e+09\n", string(out))
+	})
+
+	t.Run("conflicting types", func(t *testing.T) {
+		metrics, err := parseMetrics([]byte(`[{"name":"m","type":"gauge","value":1},{"name":"m","type":"counter","value":1}]`))
+		require.NoError(t, err)
+		_, err = formatMetrics(metrics)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid metrics", func(t *testing.T) {
+		invalid := []string{
+			``,
+			`[]`,
+			`not json`,
+			`{"name":"1abc","value":1}`,
+			`{"name":"m","type":"histogram","value":1}`,
+			`{"name":"m","type":"counter","value":-1}`,
+			`{"name":"m","labels":{"__name__":"x"},"value":1}`,
+			`{"name":"m","labels":{"a-b":"x"},"value":1}`,
+		}
+		for _, data := range invalid {
+			_, err := parseMetrics([]byte(data))
+			assert.Error(t, err, data)
+		}
+	})
+}
+
+func TestGroupingKeyPath(t *testing.T) {
+	assert.Equal(t, "job/myjob", groupingKeyPath("job", "myjob"))
+	assert.Equal(t, "instance/my%20host", groupingKeyPath("instance", "my host"))
+	assert.Equal(t, "job@base64/YS9i", groupingKeyPath("job", "a/b"))
+	assert.Equal(t, "instance@base64/=", groupingKeyPath("instance", ""))
+}
+
+func TestInvoke(t *testing.T) {
+	data := []byte(`{"name":"records","type":"gauge","value":42}`)
+
+	t.Run("push", func(t *testing.T) {
+		url, requests := startTestServer(t, http.StatusOK)
+		b := initBinding(t, map[string]string{
+			"url":      url + "/",
+			"job":      "myjob",
+			"instance": "host1",
+			"username": "user",
+			"password": "pass",
+		})
+
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: PushOperation,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "200", res.Metadata[respStatusCodeKey])
+
+		require.Len(t, *requests, 1)
+		r := (*requests)[0]
+		assert.Equal(t, http.MethodPost, r.method)
+		assert.Equal(t, "/metrics/job/myjob/instance/host1", r.path)
+		assert.Equal(t, contentTypeTextFormat, r.contentType)
+		assert.Equal(t, "# TYPE records gauge\nrecords 42\n", r.body)
+		assert.Equal(t, "user", r.user)
+		assert.Equal(t, "pass", r.password)
+	})
+
+	t.Run("replace with grouping key from the request", func(t *testing.T) {
+		url, requests := startTestServer(t, http.StatusAccepted)
+		b := initBinding(t, map[string]string{
+			"url":      url,
+			"job":      "myjob",
+			"instance": "host1",
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: ReplaceOperation,
+			Data:      data,
+			Metadata: map[string]string{
+				"job":      "otherjob",
+				"instance": "",
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, *requests, 1)
+		assert.Equal(t, http.MethodPut, (*requests)[0].method)
+		assert.Equal(t, "/metrics/job/otherjob", (*requests)[0].path)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		url, requests := startTestServer(t, http.StatusAccepted)
+		b := initBinding(t, map[string]string{
+			"url": url,
+			"job": "myjob",
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, *requests, 1)
+		assert.Equal(t, http.MethodDelete, (*requests)[0].method)
+		assert.Equal(t, "/metrics/job/myjob", (*requests)[0].path)
+		assert.Empty(t, (*requests)[0].body)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		url, _ := startTestServer(t, http.StatusBadRequest)
+		b := initBinding(t, map[string]string{
+			"url": url,
+			"job": "myjob",
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: PushOperation,
+			Data:      data,
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "400")
+		assert.ErrorContains(t, err, "bad metrics")
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		b := initBinding(t, map[string]string{
+			"url": "http://localhost:9091",
+			"job": "myjob",
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+		})
+		require.Error(t, err)
+	})
+}
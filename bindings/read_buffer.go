@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+// ReadBufferSettings configures the buffer between the read loop of an input binding and the app handler.
+// It's configured with the "readBufferCapacity" metadata property.
+type ReadBufferSettings struct {
+	// Maximum number of messages received from the broker and waiting to be delivered to the app. The buffer is disabled if 0.
+	Capacity int `mapstructure:"readBufferCapacity"`
+}
+
+// ParseReadBufferSettings returns the buffer settings configured in the metadata properties.
+func ParseReadBufferSettings(props map[string]string) (ReadBufferSettings, error) {
+	s := ReadBufferSettings{}
+	err := metadata.DecodeMetadata(props, &s)
+	if err != nil {
+		return s, err
+	}
+	if s.Capacity < 0 {
+		return s, errors.New("metadata property 'readBufferCapacity' must not be negative")
+	}
+	return s, nil
+}
+
+// ReadBufferMetricsRecorder records the utilization of the read buffers of input bindings.
+// Its methods are invoked concurrently.
+type ReadBufferMetricsRecorder interface {
+	// RecordReadBufferUtilization is invoked when a message is added to or removed from the buffer of the binding, with the number of buffered messages and the capacity.
+	RecordReadBufferUtilization(binding string, length int, capacity int)
+}
+
+// ReadBufferMetricsSetter is implemented by input bindings that report the utilization of their read buffer.
+// The recorder must be set before Read is invoked.
+type ReadBufferMetricsSetter interface {
+	SetReadBufferMetricsRecorder(recorder ReadBufferMetricsRecorder)
+}
+
+// ReadBuffer holds messages received by the read loop of an input binding until the app handler processes them, so receiving isn't blocked by a slow app.
+// When the buffer is full, Enqueue blocks, which stops the read loop from receiving more messages from the broker until the app catches up.
+// Messages are delivered to the handler one at a time, in the order they were received.
+type ReadBuffer struct {
+	handler  Handler
+	recorder ReadBufferMetricsRecorder
+	binding  string
+	ch       chan bufferedRead
+	wg       sync.WaitGroup
+}
+
+type bufferedRead struct {
+	res  *ReadResponse
+	done func(res []byte, err error)
+}
+
+// NewReadBuffer returns a new ReadBuffer that delivers messages to handler until ctx is canceled.
+// If the capacity in settings is 0, messages are delivered to the handler directly by Enqueue.
+// recorder may be nil; binding is the name reported to it.
+func NewReadBuffer(ctx context.Context, settings ReadBufferSettings, handler Handler, recorder ReadBufferMetricsRecorder, binding string) *ReadBuffer {
+	b := &ReadBuffer{
+		handler:  handler,
+		recorder: recorder,
+		binding:  binding,
+	}
+	if settings.Capacity == 0 {
+		return b
+	}
+
+	b.ch = make(chan bufferedRead, settings.Capacity)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.deliver(ctx)
+	}()
+	return b
+}
+
+// Enqueue adds a message to the buffer, blocking while it's full; done is invoked with the result of the handler once the message is delivered.
+// Messages that are still buffered when ctx is canceled are not delivered, and done is invoked with the error of the context, so they can be released to the broker.
+// It returns the error of ctx if it's canceled while waiting, in which case done is not invoked.
+func (b *ReadBuffer) Enqueue(ctx context.Context, res *ReadResponse, done func(res []byte, err error)) error {
+	if b.ch == nil {
+		done(b.handler(ctx, res))
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	select {
+	case b.ch <- bufferedRead{res: res, done: done}:
+		b.record()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until the buffer stopped delivering messages, after the context passed to NewReadBuffer is canceled.
+func (b *ReadBuffer) Wait() {
+	b.wg.Wait()
+}
+
+func (b *ReadBuffer) deliver(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			// Release the messages that weren't delivered
+			for {
+				select {
+				case item := <-b.ch:
+					b.record()
+					item.done(nil, ctx.Err())
+				default:
+					return
+				}
+			}
+		case item := <-b.ch:
+			b.record()
+			// The select picks at random when both cases are ready, so don't deliver the message if ctx is canceled already
+			if ctx.Err() != nil {
+				item.done(nil, ctx.Err())
+				continue
+			}
+			item.done(b.handler(ctx, item.res))
+		}
+	}
+}
+
+func (b *ReadBuffer) record() {
+	if b.recorder != nil {
+		b.recorder.RecordReadBufferUtilization(b.binding, len(b.ch), cap(b.ch))
+	}
+}
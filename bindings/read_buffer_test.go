@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBufferRecorder struct {
+	lock    sync.Mutex
+	lengths []int
+}
+
+func (r *fakeBufferRecorder) RecordReadBufferUtilization(binding string, length int, capacity int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lengths = append(r.lengths, length)
+}
+
+func (r *fakeBufferRecorder) maxLength() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	res := 0
+	for _, l := range r.lengths {
+		if l > res {
+			res = l
+		}
+	}
+	return res
+}
+
+func TestParseReadBufferSettings(t *testing.T) {
+	s, err := ParseReadBufferSettings(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, s.Capacity)
+
+	s, err = ParseReadBufferSettings(map[string]string{"readBufferCapacity": "10"})
+	require.NoError(t, err)
+	assert.Equal(t, 10, s.Capacity)
+
+	_, err = ParseReadBufferSettings(map[string]string{"readBufferCapacity": "-1"})
+	require.Error(t, err)
+}
+
+func TestReadBuffer(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		var received []string
+		b := NewReadBuffer(context.Background(), ReadBufferSettings{}, func(ctx context.Context, res *ReadResponse) ([]byte, error) {
+			received = append(received, string(res.Data))
+			return []byte("ok"), nil
+		}, nil, "binding")
+
+		var result []byte
+		require.NoError(t, b.Enqueue(context.Background(), &ReadResponse{Data: []byte("a")}, func(res []byte, err error) {
+			result = res
+		}))
+		// The handler is invoked synchronously
+		assert.Equal(t, []string{"a"}, received)
+		assert.Equal(t, []byte("ok"), result)
+	})
+
+	t.Run("backpressure when full", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		release := make(chan struct{})
+		recorder := &fakeBufferRecorder{}
+		b := NewReadBuffer(ctx, ReadBufferSettings{Capacity: 2}, func(ctx context.Context, res *ReadResponse) ([]byte, error) {
+			<-release
+			return nil, nil
+		}, recorder, "binding")
+
+		var delivered sync.WaitGroup
+		delivered.Add(4)
+		enqueued := make(chan struct{})
+		go func() {
+			defer close(enqueued)
+			for i := 0; i < 4; i++ {
+				require.NoError(t, b.Enqueue(ctx, &ReadResponse{}, func(_ []byte, err error) {
+					assert.NoError(t, err)
+					delivered.Done()
+				}))
+			}
+		}()
+
+		// One message is being delivered, and two are buffered, so the fourth one blocks
+		select {
+		case <-enqueued:
+			t.Fatal("enqueue should block while the buffer is full")
+		case <-time.After(100 * time.Millisecond):
+		}
+		assert.Equal(t, 2, recorder.maxLength())
+
+		close(release)
+		<-enqueued
+		delivered.Wait()
+	})
+
+	t.Run("buffered messages are released on cancelation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		b := NewReadBuffer(ctx, ReadBufferSettings{Capacity: 5}, func(ctx context.Context, res *ReadResponse) ([]byte, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, nil, "binding")
+
+		var lock sync.Mutex
+		var errs []error
+		done := func(_ []byte, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			errs = append(errs, err)
+		}
+		for i := 0; i < 3; i++ {
+			require.NoError(t, b.Enqueue(ctx, &ReadResponse{}, done))
+		}
+		<-started
+		cancel()
+		b.Wait()
+
+		assert.Len(t, errs, 3)
+		for _, err := range errs {
+			require.ErrorIs(t, err, context.Canceled)
+		}
+		require.ErrorIs(t, b.Enqueue(ctx, &ReadResponse{}, done), context.Canceled)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+// Defaults for the retry policy of the read loop of input bindings.
+const (
+	DefaultReadRetryInitialInterval = 500 * time.Millisecond
+	DefaultReadRetryMaxInterval     = time.Minute
+	DefaultReadRetryMultiplier      = 1.5
+)
+
+// ReadRetryPolicy controls how the read loop of an input binding backs off after an error.
+// It's configured with the "readRetryInitialInterval", "readRetryMaxInterval", "readRetryMultiplier", and "readRetryMaxAttempts" metadata properties.
+type ReadRetryPolicy struct {
+	// Delay after the first error; it grows by Multiplier after each consecutive error, up to MaxInterval.
+	InitialInterval time.Duration `mapstructure:"readRetryInitialInterval"`
+	MaxInterval     time.Duration `mapstructure:"readRetryMaxInterval"`
+	Multiplier      float64       `mapstructure:"readRetryMultiplier"`
+	// Number of consecutive failed attempts after which the loop stops. Unlimited if 0.
+	MaxAttempts int `mapstructure:"readRetryMaxAttempts"`
+}
+
+// ParseReadRetryPolicy returns the retry policy configured in the metadata properties, applying the defaults.
+func ParseReadRetryPolicy(props map[string]string) (ReadRetryPolicy, error) {
+	p := ReadRetryPolicy{
+		InitialInterval: DefaultReadRetryInitialInterval,
+		MaxInterval:     DefaultReadRetryMaxInterval,
+		Multiplier:      DefaultReadRetryMultiplier,
+	}
+	err := metadata.DecodeMetadata(props, &p)
+	if err != nil {
+		return p, err
+	}
+
+	if p.InitialInterval <= 0 {
+		return p, errors.New("metadata property 'readRetryInitialInterval' must be greater than 0")
+	}
+	if p.MaxInterval < p.InitialInterval {
+		return p, errors.New("metadata property 'readRetryMaxInterval' must not be less than 'readRetryInitialInterval'")
+	}
+	if p.Multiplier < 1 {
+		return p, errors.New("metadata property 'readRetryMultiplier' must be at least 1")
+	}
+	if p.MaxAttempts < 0 {
+		return p, errors.New("metadata property 'readRetryMaxAttempts' must not be negative")
+	}
+	return p, nil
+}
+
+// NewBackOff returns a backoff that implements the policy.
+func (p ReadRetryPolicy) NewBackOff() backoff.BackOff {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = p.InitialInterval
+	bo.MaxInterval = p.MaxInterval
+	bo.Multiplier = p.Multiplier
+	bo.MaxElapsedTime = 0
+	bo.Reset()
+
+	if p.MaxAttempts > 0 {
+		// WithMaxRetries counts the retries, which are one less than the attempts
+		return backoff.WithMaxRetries(bo, uint64(p.MaxAttempts-1))
+	}
+	return bo
+}
+
+// RunReadLoop invokes poll until ctx is canceled.
+// After poll returns an error, the next invocation is delayed according to the policy; the delay is reset after a successful invocation.
+// The loop stops and returns the error when poll returns a permanent error, created with backoff.Permanent, or after MaxAttempts consecutive errors.
+// If onError is not nil, it's invoked for each error that is retried, with the delay before the next attempt.
+func RunReadLoop(ctx context.Context, policy ReadRetryPolicy, poll func(ctx context.Context) error, onError func(err error, delay time.Duration)) error {
+	bo := policy.NewBackOff()
+
+	for ctx.Err() == nil {
+		err := poll(ctx)
+		if err == nil {
+			bo.Reset()
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		var permanent *backoff.PermanentError
+		if errors.As(err, &permanent) {
+			return permanent.Err
+		}
+
+		delay := bo.NextBackOff()
+		if delay == backoff.Stop {
+			return fmt.Errorf("giving up after %d consecutive errors: %w", policy.MaxAttempts, err)
+		}
+		if onError != nil {
+			onError(err, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReadRetryPolicy(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		p, err := ParseReadRetryPolicy(map[string]string{})
+		require.NoError(t, err)
+		assert.Equal(t, ReadRetryPolicy{
+			InitialInterval: DefaultReadRetryInitialInterval,
+			MaxInterval:     DefaultReadRetryMaxInterval,
+			Multiplier:      DefaultReadRetryMultiplier,
+		}, p)
+	})
+
+	t.Run("custom values", func(t *testing.T) {
+		p, err := ParseReadRetryPolicy(map[string]string{
+			"readRetryInitialInterval": "2s",
+			"readRetryMaxInterval":     "30s",
+			"readRetryMultiplier":      "3",
+			"readRetryMaxAttempts":     "5",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ReadRetryPolicy{
+			InitialInterval: 2 * time.Second,
+			MaxInterval:     30 * time.Second,
+			Multiplier:      3,
+			MaxAttempts:     5,
+		}, p)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for name, props := range map[string]map[string]string{
+			"zero initial interval":    {"readRetryInitialInterval": "0"},
+			"max less than initial":    {"readRetryInitialInterval": "10s", "readRetryMaxInterval": "1s"},
+			"multiplier less than one": {"readRetryMultiplier": "0.5"},
+			"negative max attempts":    {"readRetryMaxAttempts": "-1"},
+		} {
+			_, err := ParseReadRetryPolicy(props)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestRunReadLoop(t *testing.T) {
+	policy := ReadRetryPolicy{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+		Multiplier:      2,
+	}
+
+	t.Run("retries errors with backoff and stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			calls  int
+			delays []time.Duration
+		)
+		err := RunReadLoop(ctx, policy, func(ctx context.Context) error {
+			calls++
+			switch {
+			case calls == 6:
+				cancel()
+				return nil
+			case calls == 3:
+				return nil
+			default:
+				return errors.New("transient")
+			}
+		}, func(err error, delay time.Duration) {
+			delays = append(delays, delay)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 6, calls)
+		require.Len(t, delays, 4)
+		// The delay grows with consecutive errors, and is reset after a success
+		// Each delay is randomized by up to ±50% (the default randomization factor of the backoff)
+		for i, interval := range []time.Duration{time.Millisecond, 2 * time.Millisecond, time.Millisecond, 2 * time.Millisecond} {
+			assert.GreaterOrEqual(t, delays[i], interval/2, i)
+			assert.LessOrEqual(t, delays[i], interval*3/2, i)
+		}
+	})
+
+	t.Run("permanent errors stop the loop", func(t *testing.T) {
+		var calls int
+		err := RunReadLoop(context.Background(), policy, func(ctx context.Context) error {
+			calls++
+			return backoff.Permanent(errors.New("queue not found"))
+		}, nil)
+		require.EqualError(t, err, "queue not found")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		p := policy
+		p.MaxAttempts = 3
+		var calls int
+		err := RunReadLoop(context.Background(), p, func(ctx context.Context) error {
+			calls++
+			return errors.New("transient")
+		}, nil)
+		require.ErrorContains(t, err, "giving up after 3 consecutive errors")
+		assert.Equal(t, 3, calls)
+	})
+}
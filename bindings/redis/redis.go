@@ -21,6 +21,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
@@ -51,7 +52,7 @@ func (r *Redis) Init(ctx context.Context, meta bindings.Metadata) (err error) {
 
 	_, err = r.client.PingResult(ctx)
 	if err != nil {
-		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
 	}
 
 	return err
@@ -59,7 +60,7 @@ func (r *Redis) Init(ctx context.Context, meta bindings.Metadata) (err error) {
 
 func (r *Redis) Ping(ctx context.Context) error {
 	if _, err := r.client.PingResult(ctx); err != nil {
-		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
 	}
 
 	return nil
@@ -0,0 +1,406 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sftp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	sftpClient "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	fileNameMetadataKey = "fileName"
+
+	defaultPort              = "22"
+	defaultConnectionTimeout = 30 * time.Second
+)
+
+type sftpMetadata struct {
+	// Address of the server, in the "host:port" format; the port defaults to 22.
+	Address string `mapstructure:"address"`
+	// Name of the user.
+	Username string `mapstructure:"username"`
+	// Password of the user, for password authentication.
+	Password string `mapstructure:"password"`
+	// Private key in the PEM format, for public key authentication.
+	PrivateKey string `mapstructure:"privateKey"`
+	// Passphrase of the private key, if it's encrypted.
+	PrivateKeyPassphrase string `mapstructure:"privateKeyPassphrase"`
+	// Entries in the known_hosts format with the keys of the server; connections to servers whose key doesn't match are refused.
+	KnownHosts string `mapstructure:"knownHosts"`
+	// Directory on the server that the names of files are relative to.
+	RootPath string `mapstructure:"rootPath"`
+	// Timeout of the connection to the server, including the handshake.
+	ConnectionTimeout time.Duration `mapstructure:"connectionTimeout"`
+}
+
+// SFTP is an output binding that transfers files to and from an SFTP server.
+type SFTP struct {
+	metadata  sftpMetadata
+	sshConfig *ssh.ClientConfig
+	logger    logger.Logger
+	client    *client
+	lock      sync.Mutex
+	dial      func() (*client, error)
+}
+
+// client is an SFTP session, with the SSH connection that it runs on.
+type client struct {
+	*sftpClient.Client
+	sshConn *ssh.Client
+}
+
+// Close closes the session and the SSH connection.
+func (c *client) Close() error {
+	err := c.Client.Close()
+	if c.sshConn != nil {
+		err = errors.Join(err, c.sshConn.Close())
+	}
+	return err
+}
+
+// FileInfo contains the attributes of a file returned by the list operation.
+type FileInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Mode    uint32    `json:"mode"`
+	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"isDir"`
+}
+
+type createResponse struct {
+	FileName string `json:"fileName"`
+}
+
+// NewSFTP returns a new SFTP output binding.
+func NewSFTP(logger logger.Logger) bindings.OutputBinding {
+	s := &SFTP{logger: logger}
+	s.dial = s.dialSSH
+	return s
+}
+
+// Init performs metadata parsing and connects to the server.
+func (s *SFTP) Init(_ context.Context, meta bindings.Metadata) error {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	s.metadata = m
+
+	hostKeyCallback, err := parseKnownHosts(m.KnownHosts)
+	if err != nil {
+		return err
+	}
+	auth, err := authMethods(m)
+	if err != nil {
+		return err
+	}
+	s.sshConfig = &ssh.ClientConfig{
+		User:            m.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         m.ConnectionTimeout,
+	}
+
+	_, err = s.getClient()
+	return err
+}
+
+func parseMetadata(meta bindings.Metadata) (sftpMetadata, error) {
+	m := sftpMetadata{
+		ConnectionTimeout: defaultConnectionTimeout,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.Address == "" {
+		return m, errors.New("metadata property 'address' is required")
+	}
+	if _, _, err = net.SplitHostPort(m.Address); err != nil {
+		m.Address = net.JoinHostPort(m.Address, defaultPort)
+	}
+	if m.Username == "" {
+		return m, errors.New("metadata property 'username' is required")
+	}
+	if m.Password == "" && m.PrivateKey == "" {
+		return m, errors.New("one of the metadata properties 'password' and 'privateKey' is required")
+	}
+	// Host keys are always verified
+	if strings.TrimSpace(m.KnownHosts) == "" {
+		return m, errors.New("metadata property 'knownHosts' is required, with the key of the server")
+	}
+	if m.RootPath == "" {
+		m.RootPath = "."
+	}
+
+	return m, nil
+}
+
+// authMethods returns the methods used to authenticate with the server: public key first, if a private key is set, then password.
+func authMethods(m sftpMetadata) ([]ssh.AuthMethod, error) {
+	var res []ssh.AuthMethod
+	if m.PrivateKey != "" {
+		var (
+			signer ssh.Signer
+			err    error
+		)
+		if m.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(m.PrivateKey), []byte(m.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(m.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the private key: %w", err)
+		}
+		res = append(res, ssh.PublicKeys(signer))
+	}
+	if m.Password != "" {
+		res = append(res, ssh.Password(m.Password))
+	}
+	return res, nil
+}
+
+// parseKnownHosts returns a callback that accepts only the host keys in the entries, which are in the known_hosts format.
+func parseKnownHosts(entries string) (ssh.HostKeyCallback, error) {
+	// The knownhosts package only reads files
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the known_hosts file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(entries + "\n")
+	closeErr := f.Close()
+	if err != nil || closeErr != nil {
+		return nil, fmt.Errorf("failed to write the known_hosts file: %w", errors.Join(err, closeErr))
+	}
+
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata property 'knownHosts': %w", err)
+	}
+	return callback, nil
+}
+
+// dialSSH connects to the server, and starts an SFTP session.
+func (s *SFTP) dialSSH() (*client, error) {
+	conn, err := ssh.Dial("tcp", s.metadata.Address, s.sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to '%s': %w", s.metadata.Address, err)
+	}
+	c, err := sftpClient.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start the SFTP session: %w", err)
+	}
+	return &client{Client: c, sshConn: conn}, nil
+}
+
+// getClient returns the client, connecting to the server if there's no connection.
+func (s *SFTP) getClient() (*client, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.client != nil {
+		return s.client, nil
+	}
+	c, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	s.client = c
+	return c, nil
+}
+
+// resetClient closes the connection after an error that isn't returned by the server, so the next operation reconnects.
+func (s *SFTP) resetClient(c *client, err error) {
+	if err == nil || isStatusError(err) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.client == c {
+		s.logger.Warnf("Closing the SFTP connection after an error: %v", err)
+		c.Close()
+		s.client = nil
+	}
+}
+
+// Operations enumerates supported binding operations.
+func (s *SFTP) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		bindings.GetOperation,
+		bindings.ListOperation,
+		bindings.DeleteOperation,
+	}
+}
+
+// Invoke is called for output bindings.
+func (s *SFTP) Invoke(_ context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	fileName := req.Metadata[fileNameMetadataKey]
+	if fileName == "" {
+		switch req.Operation {
+		case bindings.CreateOperation:
+			u, err := uuid.NewRandom()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate UUID: %w", err)
+			}
+			fileName = u.String()
+		case bindings.GetOperation, bindings.DeleteOperation:
+			return nil, fmt.Errorf("metadata property '%s' is required", fileNameMetadataKey)
+		}
+	}
+	// Names are relative to the root path, and can't refer to files outside of it
+	fileName = path.Clean("/" + fileName)[1:]
+	remotePath := path.Join(s.metadata.RootPath, fileName)
+
+	c, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var res *bindings.InvokeResponse
+	switch req.Operation {
+	case bindings.CreateOperation:
+		err = writeFile(c, remotePath, req.Data)
+		if err == nil {
+			res, err = jsonResponse(createResponse{FileName: fileName})
+		}
+	case bindings.GetOperation:
+		var data []byte
+		data, err = readFile(c, remotePath)
+		if err == nil {
+			res = &bindings.InvokeResponse{Data: data}
+		}
+	case bindings.ListOperation:
+		var files []FileInfo
+		files, err = readDir(c, remotePath)
+		if err == nil {
+			res, err = jsonResponse(files)
+		}
+	case bindings.DeleteOperation:
+		err = c.Remove(remotePath)
+	default:
+		return nil, fmt.Errorf("unsupported operation %s", req.Operation)
+	}
+	if err != nil {
+		s.resetClient(c, err)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("file not found: %s", fileName)
+		}
+		return nil, fmt.Errorf("error in operation %s on '%s': %w", req.Operation, fileName, err)
+	}
+
+	return res, nil
+}
+
+// isStatusError returns true if the error was returned by the server.
+func isStatusError(err error) bool {
+	var se *sftpClient.StatusError
+	// The client converts some statuses to the errors of the os package
+	return errors.As(err, &se) || errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission)
+}
+
+// writeFile creates or truncates a file, and writes data to it.
+func writeFile(c *client, path string, data []byte) error {
+	f, err := c.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return errors.Join(err, f.Close())
+}
+
+// readFile returns the content of a file.
+func readFile(c *client, path string) ([]byte, error) {
+	f, err := c.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// readDir returns the files in a directory.
+func readDir(c *client, path string) ([]FileInfo, error) {
+	infos, err := c.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]FileInfo, len(infos))
+	for i, fi := range infos {
+		files[i] = FileInfo{
+			Name:    fi.Name(),
+			Size:    fi.Size(),
+			Mode:    uint32(fi.Mode()),
+			ModTime: fi.ModTime(),
+			IsDir:   fi.IsDir(),
+		}
+		// Report the permissions as returned by the server
+		if stat, ok := fi.Sys().(*sftpClient.FileStat); ok {
+			files[i].Mode = stat.Mode
+		}
+	}
+	return files, nil
+}
+
+func jsonResponse(v any) (*bindings.InvokeResponse, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response as JSON: %w", err)
+	}
+	return &bindings.InvokeResponse{Data: b}, nil
+}
+
+// Close closes the connection to the server.
+func (s *SFTP) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (s *SFTP) GetComponentMetadata() map[string]string {
+	metadataStruct := sftpMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"testing"
+
+	sftpClient "github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// newTestServer returns the handlers of an in-memory SFTP server, with the "/upload" directory.
+func newTestServer(t *testing.T) sftpClient.Handlers {
+	t.Helper()
+
+	handlers := sftpClient.InMemHandler()
+	c := connectTestServer(t, handlers)
+	require.NoError(t, c.Mkdir("/upload"))
+	return handlers
+}
+
+// connectTestServer returns a client connected to a server with the handlers through in-memory pipes.
+func connectTestServer(t *testing.T, handlers sftpClient.Handlers) *sftpClient.Client {
+	t.Helper()
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	server := sftpClient.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverR, serverW}, handlers)
+	go func() {
+		server.Serve()
+		server.Close()
+	}()
+	c, err := sftpClient.NewClientPipe(clientR, clientW)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// readServerFile returns the content of a file on the server.
+func readServerFile(t *testing.T, handlers sftpClient.Handlers, path string) []byte {
+	t.Helper()
+
+	f, err := connectTestServer(t, handlers).Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return data
+}
+
+// newTestBinding returns a binding connected to the server with in-memory pipes.
+func newTestBinding(t *testing.T, handlers sftpClient.Handlers) *SFTP {
+	t.Helper()
+
+	s := NewSFTP(logger.NewLogger("test")).(*SFTP)
+	s.metadata = sftpMetadata{RootPath: "/upload"}
+	s.dial = func() (*client, error) {
+		return &client{Client: connectTestServer(t, handlers)}, nil
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestOperations(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestBinding(t, server)
+	ctx := context.Background()
+
+	// Large enough to be written and read in multiple chunks
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	res, err := s.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      data,
+		Metadata:  map[string]string{fileNameMetadataKey: "a.txt"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fileName":"a.txt"}`, string(res.Data))
+	assert.Equal(t, data, readServerFile(t, server, "/upload/a.txt"))
+
+	res, err = s.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{fileNameMetadataKey: "a.txt"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, data, res.Data)
+
+	// Names can't refer to files outside of the root path
+	_, err = s.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte("b"),
+		Metadata:  map[string]string{fileNameMetadataKey: "../../b.txt"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), readServerFile(t, server, "/upload/b.txt"))
+
+	res, err = s.Invoke(ctx, &bindings.InvokeRequest{Operation: bindings.ListOperation})
+	require.NoError(t, err)
+	var files []FileInfo
+	require.NoError(t, json.Unmarshal(res.Data, &files))
+	require.Len(t, files, 2)
+	assert.Equal(t, "a.txt", files[0].Name)
+	assert.Equal(t, int64(len(data)), files[0].Size)
+	assert.False(t, files[0].IsDir)
+	assert.Equal(t, "b.txt", files[1].Name)
+
+	_, err = s.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: bindings.DeleteOperation,
+		Metadata:  map[string]string{fileNameMetadataKey: "a.txt"},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{fileNameMetadataKey: "a.txt"},
+	})
+	require.EqualError(t, err, "file not found: a.txt")
+
+	_, err = s.Invoke(ctx, &bindings.InvokeRequest{Operation: bindings.DeleteOperation})
+	require.Error(t, err)
+
+	// Errors returned by the server don't close the connection
+	assert.NotNil(t, s.client)
+}
+
+func TestCreateWithoutFileName(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestBinding(t, server)
+
+	res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte("x"),
+	})
+	require.NoError(t, err)
+	var created createResponse
+	require.NoError(t, json.Unmarshal(res.Data, &created))
+	assert.Len(t, created.FileName, 36)
+	assert.Equal(t, []byte("x"), readServerFile(t, server, "/upload/"+created.FileName))
+}
+
+func TestParseMetadata(t *testing.T) {
+	props := map[string]string{
+		"address":    "sftp.example.com",
+		"username":   "user",
+		"password":   "pass",
+		"knownHosts": "sftp.example.com ssh-ed25519 AAAA",
+	}
+	m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+	assert.Equal(t, "sftp.example.com:22", m.Address)
+	assert.Equal(t, ".", m.RootPath)
+	assert.Equal(t, defaultConnectionTimeout, m.ConnectionTimeout)
+
+	for _, key := range []string{"address", "username", "password", "knownHosts"} {
+		invalid := map[string]string{}
+		for k, v := range props {
+			if k != key {
+				invalid[k] = v
+			}
+		}
+		_, err = parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: invalid}})
+		assert.Error(t, err, key)
+	}
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+// startSSHServer starts an SSH server with the host key, which serves the sftp subsystem with the handlers, and returns its address.
+func startSSHServer(t *testing.T, hostKey ssh.Signer, handlers sftpClient.Handlers) string {
+	t.Helper()
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "user" && string(password) == "pass" {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(nc, config)
+				if err != nil {
+					nc.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChan := range chans {
+					ch, chReqs, err := newChan.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						for req := range chReqs {
+							ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+							req.Reply(ok, nil)
+							if ok {
+								go func() {
+									sftpClient.NewRequestServer(ch, handlers).Serve()
+									ch.Close()
+								}()
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestSSH(t *testing.T) {
+	hostKey := newSigner(t)
+	server := newTestServer(t)
+	addr := startSSHServer(t, hostKey, server)
+
+	props := map[string]string{
+		"address":    addr,
+		"username":   "user",
+		"password":   "pass",
+		"knownHosts": knownhosts.Line([]string{addr}, hostKey.PublicKey()),
+		"rootPath":   "/upload",
+	}
+
+	t.Run("host key matches", func(t *testing.T) {
+		s := NewSFTP(logger.NewLogger("test")).(*SFTP)
+		require.NoError(t, s.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: props}}))
+		defer s.Close()
+
+		_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("hello"),
+			Metadata:  map[string]string{fileNameMetadataKey: "hello.txt"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("hello"), readServerFile(t, server, "/upload/hello.txt"))
+	})
+
+	t.Run("host key doesn't match", func(t *testing.T) {
+		other := map[string]string{}
+		for k, v := range props {
+			other[k] = v
+		}
+		other["knownHosts"] = knownhosts.Line([]string{addr}, newSigner(t).PublicKey())
+		s := NewSFTP(logger.NewLogger("test")).(*SFTP)
+		err := s.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: other}})
+		require.ErrorContains(t, err, "key mismatch")
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		other := map[string]string{}
+		for k, v := range props {
+			other[k] = v
+		}
+		other["knownHosts"] = knownhosts.Line([]string{"other.example.com"}, hostKey.PublicKey())
+		s := NewSFTP(logger.NewLogger("test")).(*SFTP)
+		err := s.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: other}})
+		require.ErrorContains(t, err, "key is unknown")
+	})
+
+	t.Run("private key", func(t *testing.T) {
+		der, err := x509.MarshalPKCS8PrivateKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+		require.NoError(t, err)
+		clientKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		auth, err := authMethods(sftpMetadata{PrivateKey: string(clientKey), Password: "pass"})
+		require.NoError(t, err)
+		assert.Len(t, auth, 2)
+
+		_, err = authMethods(sftpMetadata{PrivateKey: "invalid"})
+		require.Error(t, err)
+	})
+}
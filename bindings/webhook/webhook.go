@@ -0,0 +1,365 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// keys in the metadata of each request delivered to the app.
+	contentTypeKey = "contentType"
+	methodKey      = "method"
+	pathKey        = "path"
+	queryKey       = "query"
+
+	formContentType = "application/x-www-form-urlencoded"
+
+	defaultPath              = "/"
+	defaultMaxBodySize int64 = 4 << 20
+	defaultReadTimeout       = 30 * time.Second
+
+	signatureAlgorithmSHA256 = "sha256"
+	signatureAlgorithmSHA1   = "sha1"
+
+	// Timeout for requests in flight to complete when the binding is closed.
+	shutdownTimeout = 10 * time.Second
+)
+
+var (
+	errBodyTooLarge        = errors.New("request body is too large")
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+	errInvalidSignature    = errors.New("invalid signature")
+)
+
+type webhookMetadata struct {
+	// Address the HTTP server listens on, such as ":8080".
+	ListenAddress string `mapstructure:"listenAddress"`
+	// Path that receives the requests.
+	Path string `mapstructure:"path"`
+	// Maximum size of the request body in bytes. It applies both to the body as received and after decompression.
+	MaxBodySize int64 `mapstructure:"maxBodySize"`
+	// Timeout for reading each request, including the body.
+	ReadTimeout time.Duration `mapstructure:"readTimeout"`
+	// Header that contains the HMAC signature of the body. Signatures are not verified if empty.
+	SignatureHeader string `mapstructure:"signatureHeader"`
+	// Hash function of the HMAC: "sha256" or "sha1".
+	SignatureAlgorithm string `mapstructure:"signatureAlgorithm"`
+	// Secret used to compute the HMAC.
+	SigningSecret string `mapstructure:"signingSecret"`
+
+	newHash func() hash.Hash
+}
+
+// Webhook is an input binding that runs an HTTP server and delivers each request to the app.
+type Webhook struct {
+	metadata webhookMetadata
+	logger   logger.Logger
+
+	listener net.Listener
+	closed   atomic.Bool
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWebhook returns a new webhook input binding.
+func NewWebhook(logger logger.Logger) bindings.InputBinding {
+	return &Webhook{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Init performs metadata parsing.
+func (w *Webhook) Init(_ context.Context, meta bindings.Metadata) error {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	w.metadata = m
+
+	return nil
+}
+
+func parseMetadata(meta bindings.Metadata) (webhookMetadata, error) {
+	m := webhookMetadata{
+		Path:               defaultPath,
+		MaxBodySize:        defaultMaxBodySize,
+		ReadTimeout:        defaultReadTimeout,
+		SignatureAlgorithm: signatureAlgorithmSHA256,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.ListenAddress == "" {
+		return m, errors.New("metadata property 'listenAddress' is required")
+	}
+	if !strings.HasPrefix(m.Path, "/") {
+		return m, errors.New("metadata property 'path' must start with '/'")
+	}
+	if m.MaxBodySize <= 0 {
+		return m, errors.New("metadata property 'maxBodySize' must be greater than 0")
+	}
+	if m.ReadTimeout < 0 {
+		return m, errors.New("metadata property 'readTimeout' must not be negative")
+	}
+
+	if m.SignatureHeader != "" {
+		if m.SigningSecret == "" {
+			return m, errors.New("metadata property 'signingSecret' is required when 'signatureHeader' is set")
+		}
+		switch strings.ToLower(m.SignatureAlgorithm) {
+		case signatureAlgorithmSHA256:
+			m.newHash = sha256.New
+		case signatureAlgorithmSHA1:
+			m.newHash = sha1.New
+		default:
+			return m, fmt.Errorf("metadata property 'signatureAlgorithm' must be '%s' or '%s'", signatureAlgorithmSHA256, signatureAlgorithmSHA1)
+		}
+		m.SignatureAlgorithm = strings.ToLower(m.SignatureAlgorithm)
+	}
+
+	return m, nil
+}
+
+// Read starts the HTTP server, and invokes the handler for each request received.
+func (w *Webhook) Read(ctx context.Context, handler bindings.Handler) error {
+	if w.closed.Load() {
+		return errors.New("binding is closed")
+	}
+
+	listener, err := net.Listen("tcp", w.metadata.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", w.metadata.ListenAddress, err)
+	}
+	w.listener = listener
+
+	mux := http.NewServeMux()
+	mux.Handle(w.metadata.Path, w.requestHandler(handler))
+	srv := &http.Server{
+		Handler:     mux,
+		ReadTimeout: w.metadata.ReadTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	w.wg.Add(2)
+	go func() {
+		defer w.wg.Done()
+		// Stop when the context is canceled or the binding closed
+		select {
+		case <-ctx.Done():
+		case <-w.closeCh:
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		defer w.wg.Done()
+		w.logger.Infof("Receiving webhook requests on %s%s", listener.Addr(), w.metadata.Path)
+		err := srv.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			w.logger.Errorf("Webhook server stopped with error: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// requestHandler returns the HTTP handler that delivers requests to the app.
+func (w *Webhook) requestHandler(handler bindings.Handler) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost && req.Method != http.MethodPut {
+			res.Header().Set("Allow", "POST, PUT")
+			http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := w.readBody(res, req)
+		if err != nil {
+			w.logger.Debugf("Rejected webhook request: %v", err)
+			switch {
+			case errors.Is(err, errBodyTooLarge):
+				http.Error(res, err.Error(), http.StatusRequestEntityTooLarge)
+			case errors.Is(err, errUnsupportedEncoding):
+				http.Error(res, err.Error(), http.StatusUnsupportedMediaType)
+			case errors.Is(err, errInvalidSignature):
+				http.Error(res, err.Error(), http.StatusUnauthorized)
+			default:
+				http.Error(res, "invalid request body", http.StatusBadRequest)
+			}
+			return
+		}
+
+		contentType := req.Header.Get("Content-Type")
+		data, err := decodePayload(contentType, body)
+		if err != nil {
+			w.logger.Debugf("Rejected webhook request: %v", err)
+			http.Error(res, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		out, err := handler(req.Context(), &bindings.ReadResponse{
+			Data: data,
+			Metadata: map[string]string{
+				contentTypeKey: contentType,
+				methodKey:      req.Method,
+				pathKey:        req.URL.Path,
+				queryKey:       req.URL.RawQuery,
+			},
+		})
+		if err != nil {
+			w.logger.Errorf("Error from app handling webhook request: %v", err)
+			http.Error(res, "error processing the request", http.StatusInternalServerError)
+			return
+		}
+
+		res.WriteHeader(http.StatusOK)
+		if len(out) > 0 {
+			res.Write(out)
+		}
+	}
+}
+
+// readBody reads the body of the request, verifies its signature, and decompresses it according to the "Content-Encoding" header.
+// The size of the body is limited to maxBodySize both before and after decompression, to protect against decompression bombs.
+func (w *Webhook) readBody(res http.ResponseWriter, req *http.Request) ([]byte, error) {
+	raw, err := readLimited(http.MaxBytesReader(res, req.Body, w.metadata.MaxBodySize+1), w.metadata.MaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature is computed on the body exactly as it was sent
+	err = w.verifySignature(req.Header.Get(w.metadata.SignatureHeader), raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return raw, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		return readLimited(gz, w.metadata.MaxBodySize)
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
+
+// verifySignature checks that signature is the HMAC of body, if signatures are enabled.
+// The signature is hex-encoded, optionally prefixed with the name of the algorithm as in "sha256=<hex>".
+func (w *Webhook) verifySignature(signature string, body []byte) error {
+	if w.metadata.SignatureHeader == "" {
+		return nil
+	}
+
+	signature = strings.TrimSpace(signature)
+	if prefix, rest, ok := strings.Cut(signature, "="); ok && strings.EqualFold(prefix, w.metadata.SignatureAlgorithm) {
+		signature = rest
+	}
+	received, err := hex.DecodeString(signature)
+	if err != nil || len(received) == 0 {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(w.metadata.newHash, []byte(w.metadata.SigningSecret))
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// readLimited reads r fully, returning errBodyTooLarge if it contains more than limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errBodyTooLarge
+		}
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+// decodePayload converts URL-encoded forms to a JSON object whose properties are the fields of the form, each with the list of its values.
+// Other payloads are returned as-is.
+func decodePayload(contentType string, body []byte) ([]byte, error) {
+	if contentType == "" {
+		return body, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != formContentType {
+		return body, nil
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid form body: %w", err)
+	}
+	return json.Marshal(values)
+}
+
+// Close stops the HTTP server.
+func (w *Webhook) Close() error {
+	if w.closed.CompareAndSwap(false, true) {
+		close(w.closeCh)
+	}
+	w.wg.Wait()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (w *Webhook) GetComponentMetadata() map[string]string {
+	metadataStruct := webhookMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"listenAddress": ":8080",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, ":8080", m.ListenAddress)
+		assert.Equal(t, defaultPath, m.Path)
+		assert.Equal(t, defaultMaxBodySize, m.MaxBodySize)
+		assert.Equal(t, defaultReadTimeout, m.ReadTimeout)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, props := range map[string]map[string]string{
+			"missing listenAddress": {},
+			"relative path":         {"listenAddress": ":8080", "path": "hooks"},
+			"zero maxBodySize":      {"listenAddress": ":8080", "maxBodySize": "0"},
+			"negative readTimeout":  {"listenAddress": ":8080", "readTimeout": "-1s"},
+		} {
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestRequestHandler(t *testing.T) {
+	w := &Webhook{
+		logger:   logger.NewLogger("test"),
+		metadata: webhookMetadata{Path: "/", MaxBodySize: 1024},
+	}
+
+	var received *bindings.ReadResponse
+	handler := w.requestHandler(func(_ context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received = res
+		if string(res.Data) == "fail" {
+			return nil, errors.New("simulated")
+		}
+		return []byte("ok"), nil
+	})
+
+	do := func(body []byte, headers map[string]string) *httptest.ResponseRecorder {
+		received = nil
+		req := httptest.NewRequest(http.MethodPost, "/?source=test", bytes.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	t.Run("plain body", func(t *testing.T) {
+		rec := do([]byte(`{"a":1}`), map[string]string{"Content-Type": "application/json"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ok", rec.Body.String())
+		require.NotNil(t, received)
+		assert.Equal(t, `{"a":1}`, string(received.Data))
+		assert.Equal(t, "application/json", received.Metadata[contentTypeKey])
+		assert.Equal(t, http.MethodPost, received.Metadata[methodKey])
+		assert.Equal(t, "source=test", received.Metadata[queryKey])
+	})
+
+	t.Run("gzip body", func(t *testing.T) {
+		rec := do(gzipBytes(t, []byte(`{"a":1}`)), map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, received)
+		assert.Equal(t, `{"a":1}`, string(received.Data))
+	})
+
+	t.Run("form body", func(t *testing.T) {
+		rec := do([]byte("name=dapr&tag=a&tag=b"), map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, received)
+		assert.JSONEq(t, `{"name":["dapr"],"tag":["a","b"]}`, string(received.Data))
+		assert.Equal(t, "application/x-www-form-urlencoded; charset=utf-8", received.Metadata[contentTypeKey])
+	})
+
+	t.Run("gzip form body", func(t *testing.T) {
+		rec := do(gzipBytes(t, []byte("name=dapr")), map[string]string{"Content-Type": "application/x-www-form-urlencoded", "Content-Encoding": "gzip"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, received)
+		assert.JSONEq(t, `{"name":["dapr"]}`, string(received.Data))
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		rec := do(bytes.Repeat([]byte("a"), 1025), nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Nil(t, received)
+	})
+
+	t.Run("decompressed body too large", func(t *testing.T) {
+		// Compresses to far less than the limit
+		body := gzipBytes(t, bytes.Repeat([]byte("a"), 64<<10))
+		require.Less(t, len(body), 1024)
+		rec := do(body, map[string]string{"Content-Encoding": "gzip"})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Nil(t, received)
+	})
+
+	t.Run("invalid gzip body", func(t *testing.T) {
+		rec := do([]byte("not gzip"), map[string]string{"Content-Encoding": "gzip"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		rec := do([]byte("data"), map[string]string{"Content-Encoding": "br"})
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("app error", func(t *testing.T) {
+		rec := do([]byte("fail"), nil)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestReadAndClose(t *testing.T) {
+	w := NewWebhook(logger.NewLogger("test")).(*Webhook)
+	err := w.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"listenAddress": "127.0.0.1:0",
+		"path":          "/hooks",
+	}}})
+	require.NoError(t, err)
+
+	receivedCh := make(chan string, 1)
+	err = w.Read(context.Background(), func(_ context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		receivedCh <- string(res.Data)
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	res, err := http.Post("http://"+w.listener.Addr().String()+"/hooks", "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	select {
+	case data := <-receivedCh:
+		assert.Equal(t, "hello", data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not delivered")
+	}
+
+	require.NoError(t, w.Close())
+	_, err = http.Post("http://"+w.listener.Addr().String()+"/hooks", "text/plain", strings.NewReader("hello"))
+	assert.Error(t, err)
+	assert.Error(t, w.Read(context.Background(), nil))
+}
+
+func TestSignatureVerification(t *testing.T) {
+	sign := func(newHash func() hash.Hash, secret string, body []byte) string {
+		mac := hmac.New(newHash, []byte(secret))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	newWebhook := func(t *testing.T, props map[string]string) *Webhook {
+		t.Helper()
+
+		props["listenAddress"] = ":8080"
+		w := NewWebhook(logger.NewLogger("test")).(*Webhook)
+		require.NoError(t, w.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: props}}))
+		return w
+	}
+
+	do := func(w *Webhook, body []byte, headers map[string]string) (int, bool) {
+		var delivered bool
+		handler := w.requestHandler(func(context.Context, *bindings.ReadResponse) ([]byte, error) {
+			delivered = true
+			return nil, nil
+		})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code, delivered
+	}
+
+	t.Run("sha256 with prefix", func(t *testing.T) {
+		w := newWebhook(t, map[string]string{
+			"signatureHeader": "X-Hub-Signature-256",
+			"signingSecret":   "mysecret",
+		})
+		body := []byte(`{"action":"opened"}`)
+
+		code, delivered := do(w, body, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "mysecret", body)})
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, delivered)
+
+		code, delivered = do(w, body, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "othersecret", body)})
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.False(t, delivered)
+
+		code, _ = do(w, body, nil)
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _ = do(w, body, map[string]string{"X-Hub-Signature-256": "not-hex"})
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("sha1 without prefix", func(t *testing.T) {
+		w := newWebhook(t, map[string]string{
+			"signatureHeader":    "X-Signature",
+			"signatureAlgorithm": "SHA1",
+			"signingSecret":      "mysecret",
+		})
+		body := []byte("hello")
+
+		code, delivered := do(w, body, map[string]string{"X-Signature": sign(sha1.New, "mysecret", body)})
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, delivered)
+	})
+
+	t.Run("computed on the raw body", func(t *testing.T) {
+		w := newWebhook(t, map[string]string{
+			"signatureHeader": "X-Signature",
+			"signingSecret":   "mysecret",
+		})
+
+		// Compressed body: the signature covers the bytes as sent
+		body := gzipBytes(t, []byte("hello"))
+		code, _ := do(w, body, map[string]string{"Content-Encoding": "gzip", "X-Signature": sign(sha256.New, "mysecret", []byte("hello"))})
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = do(w, body, map[string]string{"Content-Encoding": "gzip", "X-Signature": sign(sha256.New, "mysecret", body)})
+		assert.Equal(t, http.StatusOK, code)
+
+		// Form body: the signature covers the form as sent, not the JSON delivered to the app
+		form := []byte("b=2&a=1")
+		code, _ = do(w, form, map[string]string{"Content-Type": "application/x-www-form-urlencoded", "X-Signature": sign(sha256.New, "mysecret", form)})
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		for name, props := range map[string]map[string]string{
+			"missing secret":      {"listenAddress": ":8080", "signatureHeader": "X-Signature"},
+			"unsupported hash fn": {"listenAddress": ":8080", "signatureHeader": "X-Signature", "signingSecret": "s", "signatureAlgorithm": "md5"},
+		} {
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gorilla/websocket"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Prefix of the metadata properties that are sent as headers in the handshake request.
+	headerPrefix = "header:"
+
+	// keys in the metadata of each message delivered to the app.
+	messageTypeKey = "messageType"
+	urlKey         = "url"
+
+	messageTypeText   = "text"
+	messageTypeBinary = "binary"
+
+	defaultHandshakeTimeout         = 10 * time.Second
+	defaultReconnectInitialInterval = time.Second
+	defaultReconnectMaxInterval     = time.Minute
+
+	// Timeout for writing control frames.
+	writeControlTimeout = 5 * time.Second
+)
+
+type websocketMetadata struct {
+	// URL of the WebSocket endpoint, with the "ws" or "wss" scheme.
+	URL string `mapstructure:"url"`
+	// Token sent as bearer token in the "Authorization" header of the handshake request.
+	BearerToken string `mapstructure:"bearerToken"`
+	// Comma-separated list of subprotocols to request.
+	Subprotocols string `mapstructure:"subprotocols"`
+	// Timeout for the handshake.
+	HandshakeTimeout time.Duration `mapstructure:"handshakeTimeout"`
+	// Interval for sending ping frames. Pings are disabled if 0.
+	PingInterval time.Duration `mapstructure:"pingInterval"`
+	// If no frame (including pongs) is received within this duration, the connection is considered dead and is re-established. Disabled if 0.
+	ReadTimeout time.Duration `mapstructure:"readTimeout"`
+	// Initial and maximum interval between reconnection attempts.
+	ReconnectInitialInterval time.Duration `mapstructure:"reconnectInitialInterval"`
+	ReconnectMaxInterval     time.Duration `mapstructure:"reconnectMaxInterval"`
+
+	// Headers sent in the handshake request, from the "header:" properties.
+	headers http.Header
+}
+
+// WebSocket is an input binding that connects to a WebSocket endpoint and delivers each frame to the app.
+type WebSocket struct {
+	metadata websocketMetadata
+	dialer   *websocket.Dialer
+	logger   logger.Logger
+
+	closed  atomic.Bool
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWebSocket returns a new WebSocket input binding.
+func NewWebSocket(logger logger.Logger) bindings.InputBinding {
+	return &WebSocket{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Init performs metadata parsing.
+func (w *WebSocket) Init(_ context.Context, meta bindings.Metadata) error {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+	w.metadata = m
+
+	w.dialer = &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: m.HandshakeTimeout,
+	}
+	if m.Subprotocols != "" {
+		for _, p := range strings.Split(m.Subprotocols, ",") {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				w.dialer.Subprotocols = append(w.dialer.Subprotocols, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseMetadata(meta bindings.Metadata) (websocketMetadata, error) {
+	m := websocketMetadata{
+		HandshakeTimeout:         defaultHandshakeTimeout,
+		ReconnectInitialInterval: defaultReconnectInitialInterval,
+		ReconnectMaxInterval:     defaultReconnectMaxInterval,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.URL == "" {
+		return m, errors.New("metadata property 'url' is required")
+	}
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return m, fmt.Errorf("metadata property 'url' is invalid: %w", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return m, errors.New("metadata property 'url' must have the 'ws' or 'wss' scheme")
+	}
+	if m.HandshakeTimeout < 0 || m.PingInterval < 0 || m.ReadTimeout < 0 {
+		return m, errors.New("metadata properties 'handshakeTimeout', 'pingInterval', and 'readTimeout' must not be negative")
+	}
+	if m.PingInterval > 0 && m.ReadTimeout > 0 && m.ReadTimeout <= m.PingInterval {
+		return m, errors.New("metadata property 'readTimeout' must be greater than 'pingInterval'")
+	}
+	if m.ReconnectInitialInterval <= 0 || m.ReconnectMaxInterval < m.ReconnectInitialInterval {
+		return m, errors.New("metadata property 'reconnectInitialInterval' must be greater than 0 and not greater than 'reconnectMaxInterval'")
+	}
+
+	m.headers = http.Header{}
+	for k, v := range meta.Properties {
+		if len(k) > len(headerPrefix) && strings.EqualFold(k[:len(headerPrefix)], headerPrefix) {
+			m.headers.Add(k[len(headerPrefix):], v)
+		}
+	}
+	if m.BearerToken != "" {
+		m.headers.Set("Authorization", "Bearer "+m.BearerToken)
+	}
+
+	return m, nil
+}
+
+// Read connects to the endpoint and invokes the handler for each frame received, reconnecting when the connection is lost.
+func (w *WebSocket) Read(ctx context.Context, handler bindings.Handler) error {
+	if w.closed.Load() {
+		return errors.New("binding is closed")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w.wg.Add(2)
+	go func() {
+		defer w.wg.Done()
+		// Stop when the binding is closed
+		select {
+		case <-ctx.Done():
+		case <-w.closeCh:
+			cancel()
+		}
+	}()
+	go func() {
+		defer w.wg.Done()
+		defer cancel()
+		w.readLoop(ctx, handler)
+	}()
+
+	return nil
+}
+
+func (w *WebSocket) readLoop(ctx context.Context, handler bindings.Handler) {
+	// Reconnection backoff
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = w.metadata.ReconnectInitialInterval
+	bo.MaxInterval = w.metadata.ReconnectMaxInterval
+	bo.MaxElapsedTime = 0
+
+	// Repeat until context is canceled or binding closed.
+	for ctx.Err() == nil {
+		conn, _, err := w.dialer.DialContext(ctx, w.metadata.URL, w.metadata.headers)
+		if err == nil {
+			// Reset the backoff on connection success
+			bo.Reset()
+			w.logger.Infof("Connected to WebSocket endpoint %s", w.metadata.URL)
+			err = w.consume(ctx, conn, handler)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := bo.NextBackOff()
+		w.logger.Errorf("Error reading from WebSocket endpoint %s: %v. Attempting to reconnect in %s...", w.metadata.URL, err, wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// consume reads frames from the connection until it fails or ctx is canceled.
+func (w *WebSocket) consume(ctx context.Context, conn *websocket.Conn, handler bindings.Handler) error {
+	connCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		conn.Close()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.keepalive(connCtx, conn)
+	}()
+
+	if w.metadata.ReadTimeout > 0 {
+		extendDeadline := func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(w.metadata.ReadTimeout))
+		}
+		extendDeadline("")
+		conn.SetPongHandler(extendDeadline)
+		defaultPingHandler := conn.PingHandler()
+		conn.SetPingHandler(func(data string) error {
+			extendDeadline(data)
+			return defaultPingHandler(data)
+		})
+	}
+
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if w.metadata.ReadTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(w.metadata.ReadTimeout))
+		}
+
+		md := map[string]string{
+			urlKey: w.metadata.URL,
+		}
+		switch msgType {
+		case websocket.TextMessage:
+			md[messageTypeKey] = messageTypeText
+		case websocket.BinaryMessage:
+			md[messageTypeKey] = messageTypeBinary
+		}
+		_, err = handler(ctx, &bindings.ReadResponse{
+			Data:     data,
+			Metadata: md,
+		})
+		if err != nil {
+			w.logger.Errorf("Error from app handling WebSocket frame: %v", err)
+		}
+	}
+}
+
+// keepalive sends ping frames at the configured interval, and closes the connection when ctx is canceled to unblock the reader.
+func (w *WebSocket) keepalive(ctx context.Context, conn *websocket.Conn) {
+	var tickCh <-chan time.Time
+	if w.metadata.PingInterval > 0 {
+		ticker := time.NewTicker(w.metadata.PingInterval)
+		defer ticker.Stop()
+		tickCh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Attempt a clean close, then close the underlying connection
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(writeControlTimeout),
+			)
+			conn.Close()
+			return
+		case <-tickCh:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeControlTimeout))
+			if err != nil {
+				w.logger.Warnf("Failed to send ping to WebSocket endpoint %s: %v", w.metadata.URL, err)
+			}
+		}
+	}
+}
+
+// Close stops reading and closes the connection.
+func (w *WebSocket) Close() error {
+	if w.closed.CompareAndSwap(false, true) {
+		close(w.closeCh)
+	}
+	w.wg.Wait()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (w *WebSocket) GetComponentMetadata() map[string]string {
+	metadataStruct := websocketMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.BindingType)
+	return metadataInfo
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"url": "wss://example.com/feed",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "wss://example.com/feed", m.URL)
+		assert.Equal(t, defaultHandshakeTimeout, m.HandshakeTimeout)
+		assert.Equal(t, defaultReconnectInitialInterval, m.ReconnectInitialInterval)
+		assert.Equal(t, defaultReconnectMaxInterval, m.ReconnectMaxInterval)
+		assert.Zero(t, m.PingInterval)
+		assert.Zero(t, m.ReadTimeout)
+		assert.Empty(t, m.headers)
+	})
+
+	t.Run("headers and auth", func(t *testing.T) {
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"url":              "ws://example.com/feed",
+			"header:X-Api-Key": "mykey",
+			"bearerToken":      "mytoken",
+			"pingInterval":     "10s",
+			"readTimeout":      "30s",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "mykey", m.headers.Get("X-Api-Key"))
+		assert.Equal(t, "Bearer mytoken", m.headers.Get("Authorization"))
+		assert.Equal(t, 10*time.Second, m.PingInterval)
+		assert.Equal(t, 30*time.Second, m.ReadTimeout)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := []map[string]string{
+			{},
+			{"url": "http://example.com"},
+			{"url": "ws://example.com", "pingInterval": "-1s"},
+			{"url": "ws://example.com", "pingInterval": "10s", "readTimeout": "5s"},
+			{"url": "ws://example.com", "reconnectInitialInterval": "0"},
+			{"url": "ws://example.com", "reconnectInitialInterval": "1m", "reconnectMaxInterval": "1s"},
+		}
+		for _, props := range invalid {
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			assert.Error(t, err, props)
+		}
+	})
+}
+
+func TestRead(t *testing.T) {
+	var connections atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "mykey" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Drop the first connection after one frame to test reconnections
+		n := connections.Add(1)
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		if n == 1 {
+			return
+		}
+		conn.WriteMessage(websocket.BinaryMessage, []byte{0x1, 0x2})
+		// Wait for the client to close the connection
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	b := NewWebSocket(logger.NewLogger("test"))
+	err := b.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"url":                      "ws" + strings.TrimPrefix(srv.URL, "http"),
+		"header:X-Api-Key":         "mykey",
+		"reconnectInitialInterval": "10ms",
+	}}})
+	require.NoError(t, err)
+
+	received := make(chan *bindings.ReadResponse, 10)
+	err = b.Read(context.Background(), func(_ context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received <- res
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	expect := []struct {
+		data    string
+		msgType string
+	}{
+		{"hello", messageTypeText},
+		{"hello", messageTypeText},
+		{"\x01\x02", messageTypeBinary},
+	}
+	for _, e := range expect {
+		select {
+		case res := <-received:
+			assert.Equal(t, e.data, string(res.Data))
+			assert.Equal(t, e.msgType, res.Metadata[messageTypeKey])
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for frame")
+		}
+	}
+	assert.Equal(t, int32(2), connections.Load())
+
+	require.NoError(t, b.Close())
+}
+
+func TestReadTimeout(t *testing.T) {
+	var connections atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		connections.Add(1)
+
+		// Never reply to pings, so the client's read deadline expires
+		conn.SetPingHandler(func(string) error { return nil })
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	b := NewWebSocket(logger.NewLogger("test"))
+	err := b.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"url":                      "ws" + strings.TrimPrefix(srv.URL, "http"),
+		"pingInterval":             "10ms",
+		"readTimeout":              "50ms",
+		"reconnectInitialInterval": "10ms",
+	}}})
+	require.NoError(t, err)
+
+	err = b.Read(context.Background(), func(context.Context, *bindings.ReadResponse) ([]byte, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return connections.Load() >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, b.Close())
+}
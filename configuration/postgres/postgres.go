@@ -57,6 +57,9 @@ type pgResponse struct {
 
 const (
 	payloadDataKey      = "data"
+	prefixMetadataKey   = "prefix"
+	depthMetadataKey    = "depth"
+	keySeparator        = "/"
 	QueryTableExists    = "SELECT EXISTS (SELECT FROM pg_tables where tablename = $1)"
 	maxIdentifierLength = 64 // https://www.postgresql.org/docs/current/limits.html
 )
@@ -140,10 +143,23 @@ func (p *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequ
 		p.logger.Error(err)
 		return nil, err
 	}
-	query, params, err := buildQuery(req, p.metadata.ConfigTable)
+	tree, err := parseTreeOptions(req)
 	if err != nil {
 		p.logger.Error(err)
-		return nil, fmt.Errorf("error in configuration store query: '%w' ", err)
+		return nil, err
+	}
+	var (
+		query  string
+		params []interface{}
+	)
+	if tree.prefix != "" {
+		query, params = buildPrefixQuery(tree.prefix, p.metadata.ConfigTable)
+	} else {
+		query, params, err = buildQuery(req, p.metadata.ConfigTable)
+		if err != nil {
+			p.logger.Error(err)
+			return nil, fmt.Errorf("error in configuration store query: '%w' ", err)
+		}
 	}
 	rows, err := p.client.Query(ctx, query, params...)
 	if err != nil {
@@ -166,11 +182,74 @@ func (p *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequ
 		return nil, fmt.Errorf("unable to parse response from configuration store - %w", err)
 	}
 	result := getUniqueItemPerKey(items)
+	if tree.depth > 0 {
+		filterByDepth(result, tree.prefix, tree.depth)
+	}
 	return &configuration.GetResponse{
 		Items: result,
 	}, nil
 }
 
+// treeOptions contains the options for fetching all keys under a prefix, such as "service/env/".
+type treeOptions struct {
+	prefix string
+	// Maximum number of "/"-separated segments of the keys after the prefix; 0 means unlimited.
+	depth int
+}
+
+// parseTreeOptions reads the "prefix" and "depth" properties from the metadata of a Get request.
+func parseTreeOptions(req *configuration.GetRequest) (treeOptions, error) {
+	var (
+		opts     treeOptions
+		depthStr string
+	)
+	for k, v := range req.Metadata {
+		switch strings.ToLower(k) {
+		case prefixMetadataKey:
+			opts.prefix = v
+		case depthMetadataKey:
+			depthStr = v
+		}
+	}
+
+	if depthStr != "" {
+		if opts.prefix == "" {
+			return opts, fmt.Errorf("metadata property '%s' requires '%s'", depthMetadataKey, prefixMetadataKey)
+		}
+		depth, err := strconv.Atoi(depthStr)
+		if err != nil || depth < 0 {
+			return opts, fmt.Errorf("invalid value for metadata property '%s': '%s'", depthMetadataKey, depthStr)
+		}
+		opts.depth = depth
+	}
+	if opts.prefix != "" {
+		if len(req.Keys) > 0 {
+			return opts, fmt.Errorf("metadata property '%s' cannot be used together with a list of keys", prefixMetadataKey)
+		}
+		if !allowedChars.MatchString(opts.prefix) {
+			return opts, fmt.Errorf("invalid prefix : '%v'", opts.prefix)
+		}
+	}
+	return opts, nil
+}
+
+// buildPrefixQuery returns the query selecting all keys that begin with the prefix.
+func buildPrefixQuery(prefix string, configTable string) (string, []interface{}) {
+	// Escape the characters that have a special meaning in LIKE patterns
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	return "SELECT * FROM " + configTable + ` WHERE KEY LIKE $1 ESCAPE '\'`, []interface{}{pattern}
+}
+
+// filterByDepth removes the items whose key has more than depth segments after the prefix.
+func filterByDepth(items map[string]*configuration.Item, prefix string, depth int) {
+	for key := range items {
+		rest := strings.Trim(strings.TrimPrefix(key, prefix), keySeparator)
+		if rest != "" && strings.Count(rest, keySeparator)+1 > depth {
+			delete(items, key)
+		}
+	}
+}
+
 func (p *ConfigurationStore) Subscribe(ctx context.Context, req *configuration.SubscribeRequest, handler configuration.UpdateHandler) (string, error) {
 	pgNotifyChannel := ""
 	for k, v := range req.Metadata {
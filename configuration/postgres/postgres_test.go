@@ -20,6 +20,8 @@ import (
 
 	"github.com/pashagolub/pgxmock/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
 
 	"github.com/dapr/components-contrib/configuration"
 )
@@ -112,3 +114,61 @@ func TestValidateInput(t *testing.T) {
 	keys3 := []string{"Name 1=1"}
 	assert.Error(t, validateInput(keys3), "invalid key : 'Name 1=1'")
 }
+
+func TestParseTreeOptions(t *testing.T) {
+	t.Run("no prefix", func(t *testing.T) {
+		opts, err := parseTreeOptions(&configuration.GetRequest{Keys: []string{"key"}})
+		require.NoError(t, err)
+		assert.Equal(t, treeOptions{}, opts)
+	})
+
+	t.Run("prefix and depth", func(t *testing.T) {
+		opts, err := parseTreeOptions(&configuration.GetRequest{
+			Metadata: map[string]string{
+				"Prefix": "service/env/",
+				"depth":  "2",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, treeOptions{prefix: "service/env/", depth: 2}, opts)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := []*configuration.GetRequest{
+			{Metadata: map[string]string{"depth": "1"}},
+			{Metadata: map[string]string{"prefix": "service/", "depth": "foo"}},
+			{Metadata: map[string]string{"prefix": "service/", "depth": "-1"}},
+			{Metadata: map[string]string{"prefix": "service' OR 1=1"}},
+			{Keys: []string{"key"}, Metadata: map[string]string{"prefix": "service/"}},
+		}
+		for _, req := range invalid {
+			_, err := parseTreeOptions(req)
+			assert.Error(t, err, req.Metadata)
+		}
+	})
+}
+
+func TestBuildPrefixQuery(t *testing.T) {
+	query, params := buildPrefixQuery("service/my_env/", "cfgtbl")
+	assert.Equal(t, `SELECT * FROM cfgtbl WHERE KEY LIKE $1 ESCAPE '\'`, query)
+	assert.Equal(t, []interface{}{`service/my\_env/%`}, params)
+}
+
+func TestFilterByDepth(t *testing.T) {
+	newItems := func() map[string]*configuration.Item {
+		return map[string]*configuration.Item{
+			"service/env":           {Value: "0"},
+			"service/env/key":       {Value: "1"},
+			"service/env/sub/key":   {Value: "2"},
+			"service/env/sub/x/key": {Value: "3"},
+		}
+	}
+
+	items := newItems()
+	filterByDepth(items, "service/env", 1)
+	assert.ElementsMatch(t, []string{"service/env", "service/env/key"}, maps.Keys(items))
+
+	items = newItems()
+	filterByDepth(items, "service/env/", 2)
+	assert.ElementsMatch(t, []string{"service/env", "service/env/key", "service/env/sub/key"}, maps.Keys(items))
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/dapr/components-contrib/configuration"
 	"github.com/dapr/components-contrib/configuration/redis/internal"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
@@ -119,7 +120,7 @@ func (r *ConfigurationStore) Init(ctx context.Context, metadata configuration.Me
 	}
 
 	if _, err = r.client.Ping(ctx).Result(); err != nil {
-		return fmt.Errorf("redis store: error connecting to redis at %s: %s", m.Host, err)
+		return fmt.Errorf("redis store: error connecting to redis at %s: %s", internalutils.RedactConnectionString(m.Host), internalutils.RedactError(err, m.Host))
 	}
 
 	r.replicas, err = r.getConnectedSlaves(ctx)
@@ -204,14 +205,23 @@ func (r *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequ
 	}
 
 	items := make(map[string]*configuration.Item, len(keys))
+	if len(keys) == 0 {
+		return &configuration.GetResponse{
+			Items: items,
+		}, nil
+	}
 
-	// query by keys
-	for _, redisKey := range keys {
-		item := &configuration.Item{
-			Metadata: map[string]string{},
-		}
+	// Query all keys in a single round trip
+	// Errors are checked on each command, as Exec returns the error of the first failed command, which could be a key that doesn't exist
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, redisKey := range keys {
+		cmds[i] = pipe.Get(ctx, redisKey)
+	}
+	_, _ = pipe.Exec(ctx)
 
-		redisValue, err := r.client.Get(ctx, redisKey).Result()
+	for i, redisKey := range keys {
+		redisValue, err := cmds[i].Result()
 		if err != nil {
 			if err == redis.Nil {
 				r.logger.Warnf("redis key %s does not exist, ignore it\n", redisKey)
@@ -223,6 +233,10 @@ func (r *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequ
 			}
 			return &configuration.GetResponse{}, fmt.Errorf("fail to get configuration for redis key=%s, error is %s", redisKey, err)
 		}
+
+		item := &configuration.Item{
+			Metadata: map[string]string{},
+		}
 		val, version := internal.GetRedisValueAndVersion(redisValue)
 		item.Version = version
 		item.Value = val
@@ -117,6 +117,32 @@ func TestConfigurationStore_Get(t *testing.T) {
 				Items: map[string]*configuration.Item{},
 			},
 		},
+		{
+			name: "get multiple keys with some not existing",
+			fields: fields{
+				client: c,
+				json:   jsoniter.ConfigFastest,
+				logger: logger.NewLogger("test"),
+			},
+			args: args{
+				req: &configuration.GetRequest{
+					Keys: []string{"testKey", "notExistKey", "testKey2"},
+				},
+				ctx: context.Background(),
+			},
+			want: &configuration.GetResponse{
+				Items: map[string]*configuration.Item{
+					"testKey": {
+						Value:    "testValue",
+						Metadata: make(map[string]string),
+					},
+					"testKey2": {
+						Value:    "testValue2",
+						Metadata: make(map[string]string),
+					},
+				},
+			},
+		},
 		{
 			name: "test does not throw error for wrong type during get all",
 			prepare: func(client *redis.Client) {
@@ -190,6 +216,21 @@ func TestConfigurationStore_Get(t *testing.T) {
 	}
 }
 
+func TestConfigurationStore_GetConnectionError(t *testing.T) {
+	s, c := setupMiniredis()
+	s.Close()
+
+	r := &ConfigurationStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+	_, err := r.Get(context.Background(), &configuration.GetRequest{
+		Keys: []string{"testKey", "testKey2"},
+	})
+	assert.Error(t, err)
+}
+
 func TestParseConnectedSlaves(t *testing.T) {
 	store := &ConfigurationStore{logger: logger.NewLogger("test")}
 
@@ -14,27 +14,65 @@ limitations under the License.
 package aws
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 
 	"github.com/dapr/kit/logger"
 )
 
-func GetClient(accessKey string, secretKey string, sessionToken string, region string, endpoint string) (*session.Session, error) {
-	awsConfig := aws.NewConfig()
+// Environment variables set on pods that use IAM Roles for Service Accounts (IRSA), or any other web identity federation.
+const (
+	webIdentityTokenFileEnvVar = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	roleARNEnvVar              = "AWS_ROLE_ARN"
+	roleSessionNameEnvVar      = "AWS_ROLE_SESSION_NAME"
+)
 
-	if region != "" {
-		awsConfig = awsConfig.WithRegion(region)
-	}
+// Temporary credentials are refreshed this long before they expire, so requests in flight don't fail.
+const credentialsExpiryWindow = 5 * time.Minute
+
+// Options contains the settings used to connect to AWS.
+type Options struct {
+	Region   string
+	Endpoint string
+
+	// Static credentials. When they're not set, credentials are obtained from the web identity token file, if any, or else from the default chain of the SDK.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
 
-	if accessKey != "" && secretKey != "" {
-		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(accessKey, secretKey, sessionToken))
+	// Role assumed using the credentials above, for example for cross-account access.
+	AssumeRoleArn string
+	// Name of the session for the assumed role. If empty, a name is generated.
+	SessionName string
+	// External ID required by the trust policy of the assumed role, if any.
+	ExternalID string
+	// Optional role assumed first, whose credentials are then used to assume AssumeRoleArn.
+	IntermediateRoleArn string
+
+	// Comma-separated list of regions the component is allowed to connect to. If empty, any region is allowed.
+	AllowedRegions string
+}
+
+// GetClient returns a session for the AWS SDK, using the credentials resolved from opts.
+func GetClient(opts Options) (*session.Session, error) {
+	awsConfig := aws.NewConfig()
+
+	if opts.Region != "" {
+		awsConfig = awsConfig.WithRegion(opts.Region)
 	}
 
-	if endpoint != "" {
-		awsConfig = awsConfig.WithEndpoint(endpoint)
+	if opts.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(opts.Endpoint)
 	}
 
 	awsSession, err := session.NewSessionWithOptions(session.Options{
@@ -45,6 +83,27 @@ func GetClient(accessKey string, secretKey string, sessionToken string, region s
 		return nil, err
 	}
 
+	err = validateRegion(aws.StringValue(awsSession.Config.Region), opts.Endpoint, opts.AllowedRegions)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.IntermediateRoleArn != "" && opts.AssumeRoleArn == "" {
+		return nil, fmt.Errorf("an intermediate role can only be used together with a role to assume")
+	}
+
+	if provider := credentialsProvider(awsSession, opts, os.Getenv); provider != nil {
+		awsSession.Config.Credentials = credentials.NewCredentials(provider)
+	}
+
+	// Assume the role right away, so errors such as a trust policy that rejects the assumption are reported during init
+	if opts.AssumeRoleArn != "" {
+		_, err = awsSession.Config.Credentials.Get()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	userAgentHandler := request.NamedHandler{
 		Name: "UserAgentHandler",
 		Fn:   request.MakeAddToUserAgentHandler("dapr", logger.DaprVersion),
@@ -53,3 +112,99 @@ func GetClient(accessKey string, secretKey string, sessionToken string, region s
 
 	return awsSession, nil
 }
+
+// credentialsProvider returns the provider for the credentials configured in opts or in the environment.
+// It returns nil when the default chain of the SDK should be used.
+func credentialsProvider(sess *session.Session, opts Options, getenv func(string) string) credentials.Provider {
+	var provider credentials.Provider
+	switch {
+	case opts.AccessKey != "" && opts.SecretKey != "":
+		provider = &credentials.StaticProvider{Value: credentials.Value{
+			AccessKeyID:     opts.AccessKey,
+			SecretAccessKey: opts.SecretKey,
+			SessionToken:    opts.SessionToken,
+		}}
+	case getenv(webIdentityTokenFileEnvVar) != "" && getenv(roleARNEnvVar) != "":
+		// The token is read from the file every time the credentials are refreshed, as it's rotated by the platform
+		provider = stscreds.NewWebIdentityRoleProviderWithOptions(
+			stsClient(sess, nil),
+			getenv(roleARNEnvVar),
+			getenv(roleSessionNameEnvVar),
+			stscreds.FetchTokenPath(getenv(webIdentityTokenFileEnvVar)),
+			func(p *stscreds.WebIdentityRoleProvider) {
+				p.ExpiryWindow = credentialsExpiryWindow
+			},
+		)
+	}
+
+	if opts.AssumeRoleArn == "" {
+		return provider
+	}
+
+	// Roles are assumed with the credentials resolved above, or with the ones from the default chain.
+	// The external ID is only sent to the target role, as that's the one owned by the other party.
+	if opts.IntermediateRoleArn != "" {
+		provider = newAssumeRoleProvider(stsClient(sess, credentialsFrom(provider)), opts.IntermediateRoleArn, opts.SessionName, "")
+	}
+	return newAssumeRoleProvider(stsClient(sess, credentialsFrom(provider)), opts.AssumeRoleArn, opts.SessionName, opts.ExternalID)
+}
+
+// credentialsFrom returns the credentials for provider, or nil if provider is nil.
+func credentialsFrom(provider credentials.Provider) *credentials.Credentials {
+	if provider == nil {
+		return nil
+	}
+	return credentials.NewCredentials(provider)
+}
+
+// assumeRoleProvider assumes a role using STS, refreshing the credentials before they expire.
+// Errors returned when the assumption is rejected explain what to check.
+type assumeRoleProvider struct {
+	*stscreds.AssumeRoleProvider
+}
+
+func newAssumeRoleProvider(client stscreds.AssumeRoler, roleARN string, sessionName string, externalID string) *assumeRoleProvider {
+	p := &stscreds.AssumeRoleProvider{
+		Client:          client,
+		RoleARN:         roleARN,
+		RoleSessionName: sessionName,
+		Duration:        stscreds.DefaultDuration,
+		ExpiryWindow:    credentialsExpiryWindow,
+	}
+	if externalID != "" {
+		p.ExternalID = aws.String(externalID)
+	}
+	return &assumeRoleProvider{AssumeRoleProvider: p}
+}
+
+// Retrieve assumes the role.
+func (p *assumeRoleProvider) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(aws.BackgroundContext())
+}
+
+// RetrieveWithContext assumes the role.
+func (p *assumeRoleProvider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	val, err := p.AssumeRoleProvider.RetrieveWithContext(ctx)
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == "AccessDenied" {
+			hint := "the trust policy of the role must allow the current principal to assume it"
+			if p.ExternalID != nil {
+				hint += ", with the configured external ID"
+			}
+			return val, fmt.Errorf("not authorized to assume role '%s': %s: %w", p.RoleARN, hint, err)
+		}
+		return val, fmt.Errorf("failed to assume role '%s': %w", p.RoleARN, err)
+	}
+	return val, nil
+}
+
+// stsClient returns a client for STS that uses creds, or the credentials of the session if nil.
+// STS is always reached at its default endpoint, even when the component is configured with a custom one.
+func stsClient(sess *session.Session, creds *credentials.Credentials) *sts.STS {
+	cfg := &aws.Config{Endpoint: aws.String("")}
+	if creds != nil {
+		cfg.Credentials = creds
+	}
+	return sts.New(sess, cfg)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/ptr"
+)
+
+func TestCredentialsProvider(t *testing.T) {
+	sess, err := session.NewSession()
+	require.NoError(t, err)
+
+	noEnv := func(string) string { return "" }
+	webIdentityEnv := func(key string) string {
+		return map[string]string{
+			webIdentityTokenFileEnvVar: "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+			roleARNEnvVar:              "arn:aws:iam::123456789012:role/irsa",
+			roleSessionNameEnvVar:      "pod",
+		}[key]
+	}
+
+	t.Run("default chain", func(t *testing.T) {
+		assert.Nil(t, credentialsProvider(sess, Options{}, noEnv))
+	})
+
+	t.Run("static keys", func(t *testing.T) {
+		provider := credentialsProvider(sess, Options{AccessKey: "ak", SecretKey: "sk", SessionToken: "st"}, webIdentityEnv)
+		require.IsType(t, &credentials.StaticProvider{}, provider)
+		val, err := provider.Retrieve()
+		require.NoError(t, err)
+		assert.Equal(t, "ak", val.AccessKeyID)
+		assert.Equal(t, "sk", val.SecretAccessKey)
+		assert.Equal(t, "st", val.SessionToken)
+	})
+
+	t.Run("web identity", func(t *testing.T) {
+		provider := credentialsProvider(sess, Options{}, webIdentityEnv)
+		assert.IsType(t, &stscreds.WebIdentityRoleProvider{}, provider)
+
+		// The token file alone isn't enough
+		provider = credentialsProvider(sess, Options{}, func(key string) string {
+			if key == webIdentityTokenFileEnvVar {
+				return "/token"
+			}
+			return ""
+		})
+		assert.Nil(t, provider)
+	})
+
+	t.Run("assume role", func(t *testing.T) {
+		provider := credentialsProvider(sess, Options{AssumeRoleArn: "arn:aws:iam::210987654321:role/target", SessionName: "dapr", ExternalID: "partner"}, webIdentityEnv)
+		require.IsType(t, &assumeRoleProvider{}, provider)
+		arp := provider.(*assumeRoleProvider)
+		assert.Equal(t, "arn:aws:iam::210987654321:role/target", arp.RoleARN)
+		assert.Equal(t, "dapr", arp.RoleSessionName)
+		assert.Equal(t, ptr.Of("partner"), arp.ExternalID)
+		assert.Equal(t, credentialsExpiryWindow, arp.ExpiryWindow)
+
+		provider = credentialsProvider(sess, Options{AssumeRoleArn: "arn:aws:iam::210987654321:role/target"}, noEnv)
+		require.IsType(t, &assumeRoleProvider{}, provider)
+		assert.Nil(t, provider.(*assumeRoleProvider).ExternalID)
+	})
+
+	t.Run("role chaining", func(t *testing.T) {
+		provider := credentialsProvider(sess, Options{
+			AssumeRoleArn:       "arn:aws:iam::210987654321:role/target",
+			IntermediateRoleArn: "arn:aws:iam::123456789012:role/hop",
+			ExternalID:          "partner",
+		}, noEnv)
+		require.IsType(t, &assumeRoleProvider{}, provider)
+		arp := provider.(*assumeRoleProvider)
+		assert.Equal(t, "arn:aws:iam::210987654321:role/target", arp.RoleARN)
+
+		// The target role is assumed with the credentials of the intermediate role
+		client := arp.Client.(*sts.STS)
+		require.NotNil(t, client.Config.Credentials)
+		assert.NotSame(t, sess.Config.Credentials, client.Config.Credentials)
+	})
+}
+
+type fakeAssumeRoler struct {
+	input *sts.AssumeRoleInput
+	err   error
+}
+
+func (f *fakeAssumeRoler) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	return f.AssumeRoleWithContext(context.Background(), input)
+}
+
+func (f *fakeAssumeRoler) AssumeRoleWithContext(_ context.Context, input *sts.AssumeRoleInput, _ ...request.Option) (*sts.AssumeRoleOutput, error) {
+	f.input = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     ptr.Of("ak"),
+		SecretAccessKey: ptr.Of("sk"),
+		SessionToken:    ptr.Of("st"),
+		Expiration:      ptr.Of(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestAssumeRoleProvider(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		client := &fakeAssumeRoler{}
+		p := newAssumeRoleProvider(client, "arn:aws:iam::210987654321:role/target", "dapr", "partner")
+		val, err := p.Retrieve()
+		require.NoError(t, err)
+		assert.Equal(t, "ak", val.AccessKeyID)
+		assert.Equal(t, "partner", *client.input.ExternalId)
+		assert.Equal(t, "dapr", *client.input.RoleSessionName)
+		assert.False(t, p.IsExpired())
+
+		// Credentials are considered expired ahead of time
+		assert.WithinDuration(t, time.Now().Add(time.Hour-credentialsExpiryWindow), p.ExpiresAt(), time.Minute)
+	})
+
+	t.Run("rejected by the trust policy", func(t *testing.T) {
+		client := &fakeAssumeRoler{err: awserr.New("AccessDenied", "not authorized to perform sts:AssumeRole", nil)}
+		p := newAssumeRoleProvider(client, "arn:aws:iam::210987654321:role/target", "", "partner")
+		_, err := p.Retrieve()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not authorized to assume role 'arn:aws:iam::210987654321:role/target'")
+		assert.Contains(t, err.Error(), "external ID")
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		client := &fakeAssumeRoler{err: errors.New("simulated")}
+		p := newAssumeRoleProvider(client, "arn:aws:iam::210987654321:role/target", "", "")
+		_, err := p.Retrieve()
+		require.ErrorContains(t, err, "failed to assume role")
+	})
+}
+
+func TestGetClientValidation(t *testing.T) {
+	_, err := GetClient(Options{IntermediateRoleArn: "arn:aws:iam::123456789012:role/hop"})
+	require.Error(t, err)
+}
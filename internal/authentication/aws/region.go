@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// regionLabelRegex matches a label of a hostname that is the name of a region, such as "eu-west-1" or "us-gov-west-1".
+var regionLabelRegex = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// validateRegion returns an error if allowedRegions is not empty and either the region or the region of the endpoint isn't in it.
+// When the endpoint is overridden, its hostname must contain the region, as with the standard AWS endpoints, so it can be verified.
+func validateRegion(region string, endpoint string, allowedRegions string) error {
+	allowed := parseAllowedRegions(allowedRegions)
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	if region == "" {
+		return fmt.Errorf("a region must be configured when the allowed regions are restricted")
+	}
+	if _, ok := allowed[strings.ToLower(region)]; !ok {
+		return fmt.Errorf("region '%s' is not in the allowed regions", region)
+	}
+
+	if endpoint == "" {
+		return nil
+	}
+	endpointRegion := regionFromEndpoint(endpoint)
+	if endpointRegion == "" {
+		return fmt.Errorf("cannot determine the region of endpoint '%s' to check it against the allowed regions", endpoint)
+	}
+	if _, ok := allowed[endpointRegion]; !ok {
+		return fmt.Errorf("region '%s' of endpoint '%s' is not in the allowed regions", endpointRegion, endpoint)
+	}
+	return nil
+}
+
+// parseAllowedRegions returns the set of regions in the comma-separated list, in lowercase.
+func parseAllowedRegions(allowedRegions string) map[string]struct{} {
+	res := map[string]struct{}{}
+	for _, r := range strings.Split(allowedRegions, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r != "" {
+			res[r] = struct{}{}
+		}
+	}
+	return res
+}
+
+// regionFromEndpoint returns the region in the hostname of the endpoint, such as "eu-west-1" for "https://sqs.eu-west-1.amazonaws.com".
+// It returns an empty string if the hostname doesn't contain a region.
+func regionFromEndpoint(endpoint string) string {
+	host := endpoint
+	if !strings.Contains(endpoint, "://") {
+		host = "https://" + endpoint
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return ""
+	}
+	for _, label := range strings.Split(strings.ToLower(u.Hostname()), ".") {
+		if regionLabelRegex.MatchString(label) {
+			return label
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRegion(t *testing.T) {
+	t.Run("no allowlist", func(t *testing.T) {
+		require.NoError(t, validateRegion("", "http://localhost:4566", ""))
+		require.NoError(t, validateRegion("ap-south-1", "", " , "))
+	})
+
+	t.Run("allowed region", func(t *testing.T) {
+		require.NoError(t, validateRegion("eu-west-1", "", "eu-west-1, eu-central-1"))
+		require.NoError(t, validateRegion("EU-Central-1", "", "eu-west-1,eu-central-1"))
+	})
+
+	t.Run("region not allowed", func(t *testing.T) {
+		err := validateRegion("us-east-1", "", "eu-west-1,eu-central-1")
+		require.ErrorContains(t, err, "region 'us-east-1' is not in the allowed regions")
+	})
+
+	t.Run("missing region", func(t *testing.T) {
+		err := validateRegion("", "", "eu-west-1")
+		require.ErrorContains(t, err, "a region must be configured")
+	})
+
+	t.Run("allowed endpoint", func(t *testing.T) {
+		require.NoError(t, validateRegion("eu-west-1", "https://sqs.eu-west-1.amazonaws.com", "eu-west-1"))
+		require.NoError(t, validateRegion("eu-west-1", "s3.dualstack.eu-west-1.amazonaws.com", "eu-west-1"))
+	})
+
+	t.Run("endpoint in another region", func(t *testing.T) {
+		err := validateRegion("eu-west-1", "https://sqs.us-east-1.amazonaws.com", "eu-west-1")
+		require.ErrorContains(t, err, "region 'us-east-1' of endpoint 'https://sqs.us-east-1.amazonaws.com' is not in the allowed regions")
+	})
+
+	t.Run("endpoint without region", func(t *testing.T) {
+		err := validateRegion("eu-west-1", "http://localhost:4566", "eu-west-1")
+		require.ErrorContains(t, err, "cannot determine the region of endpoint")
+	})
+}
+
+func TestRegionFromEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"https://sqs.eu-west-1.amazonaws.com":             "eu-west-1",
+		"dynamodb.us-gov-west-1.amazonaws.com":            "us-gov-west-1",
+		"https://sns.cn-north-1.amazonaws.com.cn:443/":    "cn-north-1",
+		"https://bucket.s3.ap-southeast-2.amazonaws.com/": "ap-southeast-2",
+		"http://localhost:4566":                           "",
+		"https://vpce-0123.sqs.amazonaws.com":             "",
+	}
+	for endpoint, expect := range tests {
+		assert.Equal(t, expect, regionFromEndpoint(endpoint), endpoint)
+	}
+}
@@ -79,9 +79,43 @@ func NewEnvironmentSettings(md map[string]string) (EnvironmentSettings, error) {
 		return es, err
 	}
 	es.Cloud = azureCloud
+	err = es.validateRegion()
+	if err != nil {
+		return es, err
+	}
 	return es, nil
 }
 
+// validateRegion returns an error if the allowed regions are restricted and the region of the resource isn't one of them.
+// Endpoints of most Azure services don't contain the region, so the region the resource is deployed in must be configured.
+func (s EnvironmentSettings) validateRegion() error {
+	allowedRegions, _ := s.GetEnvironment("AllowedRegions")
+	allowed := map[string]struct{}{}
+	for _, r := range strings.Split(allowedRegions, ",") {
+		r = normalizeRegion(r)
+		if r != "" {
+			allowed[r] = struct{}{}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	region, _ := s.GetEnvironment("Region")
+	if normalizeRegion(region) == "" {
+		return errors.New("the Azure region of the resource must be configured when the allowed regions are restricted")
+	}
+	if _, ok := allowed[normalizeRegion(region)]; !ok {
+		return fmt.Errorf("region '%s' of the Azure resource is not in the allowed regions", region)
+	}
+	return nil
+}
+
+// normalizeRegion returns the name of the region in lowercase and without spaces, so "West Europe" matches "westeurope".
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.ReplaceAll(region, " ", ""))
+}
+
 // GetAzureEnvironment returns the Azure environment for a given name.
 func (s EnvironmentSettings) GetAzureEnvironment() (*cloud.Configuration, error) {
 	envName, _ := s.GetEnvironment("AzureEnvironment")
@@ -86,6 +86,36 @@ func TestAzureCloud(t *testing.T) {
 	assert.Equal(t, "core.chinacloudapi.cn", settings.EndpointSuffix(ServiceAzureStorage))
 }
 
+func TestAllowedRegions(t *testing.T) {
+	t.Run("not restricted", func(t *testing.T) {
+		_, err := NewEnvironmentSettings(map[string]string{"azureRegion": "eastus"})
+		require.NoError(t, err)
+	})
+
+	t.Run("allowed region", func(t *testing.T) {
+		_, err := NewEnvironmentSettings(map[string]string{
+			"azureAllowedRegions": "westeurope, northeurope",
+			"azureRegion":         "West Europe",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("region not allowed", func(t *testing.T) {
+		_, err := NewEnvironmentSettings(map[string]string{
+			"allowedRegions": "westeurope,northeurope",
+			"azureRegion":    "eastus",
+		})
+		require.ErrorContains(t, err, "region 'eastus' of the Azure resource is not in the allowed regions")
+	})
+
+	t.Run("missing region", func(t *testing.T) {
+		_, err := NewEnvironmentSettings(map[string]string{
+			"azureAllowedRegions": "westeurope",
+		})
+		require.ErrorContains(t, err, "the Azure region of the resource must be configured")
+	})
+}
+
 func TestEndpointSuffix(t *testing.T) {
 	es := EnvironmentSettings{}
 
@@ -35,6 +35,10 @@ var MetadataKeys = map[string][]string{ //nolint:gochecknoglobals
 	// Identifier for the Azure environment
 	// Allowed values (case-insensitive): AzurePublicCloud/AzurePublic, AzureChinaCloud/AzureChina, AzureUSGovernmentCloud/AzureUSGovernment
 	"AzureEnvironment": {"azureEnvironment", "azureCloud"},
+	// Comma-separated list of regions the resource is allowed to be in
+	"AllowedRegions": {"azureAllowedRegions", "allowedRegions"},
+	// Region the resource is deployed in, checked against the allowed regions
+	"Region": {"azureRegion"},
 
 	// Metadata keys for storage components
 
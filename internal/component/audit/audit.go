@@ -0,0 +1,337 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the keys modified by state stores in an audit log, with the operation, the actor and the time of each change.
+//
+// The log is written ahead of the changes: a record is written before (or, in a table, in the same transaction as) the operation it describes, and the operation fails if the record can't be written.
+// So the log may contain records of operations that failed afterwards, but never misses an operation that succeeded.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/component/holder"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	// SinkTable stores the records in a table of the database of the state store, in the same transaction as the changes.
+	SinkTable = "table"
+	// SinkFile appends the records to a file, as JSON lines.
+	SinkFile = "file"
+	// SinkStateStore saves the records in another state store.
+	SinkStateStore = "statestore"
+	// SinkPubSub publishes the records to a topic of a pub/sub component.
+	SinkPubSub = "pubsub"
+
+	// DefaultTable is the name of the table of the records when the sink is SinkTable.
+	DefaultTable = "dapr_state_audit"
+
+	defaultActorKey = "actor"
+	keyPrefix       = "audit||"
+)
+
+// Metadata contains the properties used to configure the audit log of a state store.
+// It's meant to be included (with "squash") in the metadata struct of the component.
+type Metadata struct {
+	// Where the records are written: "table", "file", "statestore" or "pubsub". If empty, the feature is disabled.
+	AuditLogSink string `mapstructure:"auditLogSink"`
+	// Name of the table of the records, with the "table" sink
+	AuditLogTable string `mapstructure:"auditLogTable"`
+	// Path of the file of the records, with the "file" sink
+	AuditLogFile string `mapstructure:"auditLogFile"`
+	// Name of the state store or pub/sub component the records are written to, with the "statestore" and "pubsub" sinks
+	AuditLogComponent string `mapstructure:"auditLogComponent"`
+	// Topic the records are published to, with the "pubsub" sink
+	AuditLogTopic string `mapstructure:"auditLogTopic"`
+	// Key of the request metadata with the actor that performed the operation
+	AuditLogActorKey string `mapstructure:"auditLogActorKey"`
+}
+
+// Enabled returns true if an audit-log sink is configured.
+func (m Metadata) Enabled() bool {
+	return m.AuditLogSink != ""
+}
+
+// Dependency returns the name of the component records are written to, for errors.
+func (m Metadata) Dependency() string {
+	return "audit-log component '" + m.AuditLogComponent + "'"
+}
+
+// InTable returns true if the records are stored in a table of the state store.
+func (m Metadata) InTable() bool {
+	return m.AuditLogSink == SinkTable
+}
+
+// Validate checks the properties of the configured sink, and sets the defaults of the optional ones.
+func (m *Metadata) Validate() error {
+	if m.AuditLogActorKey == "" {
+		m.AuditLogActorKey = defaultActorKey
+	}
+
+	switch m.AuditLogSink {
+	case "":
+		return nil
+	case SinkTable:
+		if m.AuditLogTable == "" {
+			m.AuditLogTable = DefaultTable
+		}
+	case SinkFile:
+		if m.AuditLogFile == "" {
+			return errors.New("metadata property 'auditLogFile' is required when 'auditLogSink' is 'file'")
+		}
+	case SinkStateStore:
+		if m.AuditLogComponent == "" {
+			return errors.New("metadata property 'auditLogComponent' is required when 'auditLogSink' is 'statestore'")
+		}
+	case SinkPubSub:
+		if m.AuditLogComponent == "" || m.AuditLogTopic == "" {
+			return errors.New("metadata properties 'auditLogComponent' and 'auditLogTopic' are required when 'auditLogSink' is 'pubsub'")
+		}
+	default:
+		return fmt.Errorf("invalid value for 'auditLogSink': '%s' is not one of 'table', 'file', 'statestore', 'pubsub'", m.AuditLogSink)
+	}
+	return nil
+}
+
+// Record is an entry of the audit log.
+// The operation is "upsert" or "delete", as in transactions.
+type Record struct {
+	Key       string              `json:"key"`
+	Operation state.OperationType `json:"operation"`
+	Actor     string              `json:"actor,omitempty"`
+	Time      time.Time           `json:"time"`
+}
+
+// Sink writes records outside of the state store.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+}
+
+// Log creates the records of the operations of a state store, and writes them to its sink.
+type Log struct {
+	metadata Metadata
+	sink     Sink
+	now      func() time.Time
+}
+
+// NewLog returns a new Log with the given sink, which is nil if the records are stored in a table.
+func NewLog(metadata Metadata, sink Sink) *Log {
+	return &Log{
+		metadata: metadata,
+		sink:     sink,
+		now:      time.Now,
+	}
+}
+
+// InTable returns true if the records are stored in a table by the state store, which must insert them in the same transaction as the operations.
+func (l *Log) InTable() bool {
+	return l.metadata.InTable()
+}
+
+// Table returns the name of the table of the records.
+func (l *Log) Table() string {
+	return l.metadata.AuditLogTable
+}
+
+// Records returns the records of the operations, which all have the same time.
+func (l *Log) Records(ops ...state.TransactionalStateOperation) []Record {
+	now := l.now().UTC()
+	records := make([]Record, 0, len(ops))
+	for _, o := range ops {
+		records = append(records, Record{
+			Key:       o.GetKey(),
+			Operation: o.Operation(),
+			Actor:     o.GetMetadata()[l.metadata.AuditLogActorKey],
+			Time:      now,
+		})
+	}
+	return records
+}
+
+// Write writes the records to the sink.
+// It must not be called when the records are stored in a table.
+func (l *Log) Write(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if l.sink == nil {
+		return errors.New("the audit log has no sink")
+	}
+	err := l.sink.Write(ctx, records)
+	if err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	return nil
+}
+
+// FileSink appends records to a file, one JSON object per line.
+type FileSink struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileSink returns a new FileSink.
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Write appends the records to the file, and flushes it to disk.
+func (s *FileSink) Write(_ context.Context, records []Record) error {
+	var buf []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf)
+	if err == nil {
+		err = f.Sync()
+	}
+	return errors.Join(err, f.Close())
+}
+
+// StoreSink saves records in a state store.
+// Each record is saved under a key made of the prefix of the component, the time of the record and a sequence number, so keys sort by time.
+type StoreSink struct {
+	store  state.Store
+	prefix string
+	seq    atomic.Uint64
+}
+
+// NewStoreSink returns a new StoreSink.
+// The prefix is added to the keys of all records, and should identify the component.
+func NewStoreSink(store state.Store, prefix string) *StoreSink {
+	return &StoreSink{
+		store:  store,
+		prefix: keyPrefix + prefix + "||",
+	}
+}
+
+// Write saves the records in the state store.
+func (s *StoreSink) Write(ctx context.Context, records []Record) error {
+	reqs := make([]state.SetRequest, len(records))
+	for i, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		reqs[i] = state.SetRequest{
+			Key:         s.prefix + strconv.FormatInt(r.Time.UnixNano(), 10) + "||" + strconv.FormatUint(s.seq.Add(1), 10),
+			Value:       data,
+			ContentType: ptr.Of("application/json"),
+		}
+	}
+	return s.store.BulkSet(ctx, reqs)
+}
+
+// PublisherSink publishes each record as a JSON message to a topic.
+type PublisherSink struct {
+	publish state.AuditLogPublishFn
+	topic   string
+}
+
+// NewPublisherSink returns a new PublisherSink.
+func NewPublisherSink(publish state.AuditLogPublishFn, topic string) *PublisherSink {
+	return &PublisherSink{
+		publish: publish,
+		topic:   topic,
+	}
+}
+
+// Write publishes the records, in order.
+func (s *PublisherSink) Write(ctx context.Context, records []Record) error {
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		err = s.publish(ctx, s.topic, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Holder holds the Log of a component.
+// With the "table" and "file" sinks the Log is created by Init; with the "statestore" and "pubsub" sinks, it's created when the runtime sets the other component.
+type Holder struct {
+	holder.Holder[Metadata, Log]
+}
+
+// Init sets the metadata of the component and the prefix of the keys of records saved in a state store.
+// The metadata must have been validated.
+func (h *Holder) Init(metadata Metadata, prefix string) {
+	h.Holder.Init(metadata, prefix)
+	switch metadata.AuditLogSink {
+	case SinkTable:
+		_ = h.Set(func(metadata Metadata, _ string) (*Log, error) {
+			return NewLog(metadata, nil), nil
+		})
+	case SinkFile:
+		_ = h.Set(func(metadata Metadata, _ string) (*Log, error) {
+			return NewLog(metadata, NewFileSink(metadata.AuditLogFile)), nil
+		})
+	}
+}
+
+// InTable returns true if the records are stored in a table of the state store.
+func (h *Holder) InTable() bool {
+	return h.Metadata().InTable()
+}
+
+// SetAuditLogStore sets the state store that records are saved to.
+func (h *Holder) SetAuditLogStore(store state.Store) error {
+	if store == nil {
+		return errors.New("audit-log store is nil")
+	}
+	return h.Set(func(metadata Metadata, prefix string) (*Log, error) {
+		if metadata.AuditLogSink != SinkStateStore {
+			return nil, errors.New("'auditLogSink' is not 'statestore' in the component metadata")
+		}
+		return NewLog(metadata, NewStoreSink(store, prefix)), nil
+	})
+}
+
+// SetAuditLogPublisher sets the function that publishes records.
+func (h *Holder) SetAuditLogPublisher(publish state.AuditLogPublishFn) error {
+	if publish == nil {
+		return errors.New("audit-log publisher is nil")
+	}
+	return h.Set(func(metadata Metadata, _ string) (*Log, error) {
+		if metadata.AuditLogSink != SinkPubSub {
+			return nil, errors.New("'auditLogSink' is not 'pubsub' in the component metadata")
+		}
+		return NewLog(metadata, NewPublisherSink(publish, metadata.AuditLogTopic)), nil
+	})
+}
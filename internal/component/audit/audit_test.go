@@ -0,0 +1,213 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func newStateStore(t *testing.T) state.Store {
+	t.Helper()
+
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+	t.Cleanup(func() { store.(interface{ Close() error }).Close() })
+	return store
+}
+
+func TestMetadataValidate(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		m := Metadata{}
+		require.NoError(t, m.Validate())
+		assert.False(t, m.Enabled())
+		assert.Equal(t, "actor", m.AuditLogActorKey)
+	})
+
+	t.Run("table has a default name", func(t *testing.T) {
+		m := Metadata{AuditLogSink: SinkTable}
+		require.NoError(t, m.Validate())
+		assert.True(t, m.InTable())
+		assert.Equal(t, DefaultTable, m.AuditLogTable)
+	})
+
+	t.Run("required properties", func(t *testing.T) {
+		for _, m := range []Metadata{
+			{AuditLogSink: SinkFile},
+			{AuditLogSink: SinkStateStore},
+			{AuditLogSink: SinkPubSub, AuditLogComponent: "pubsub"},
+			{AuditLogSink: SinkPubSub, AuditLogTopic: "audit"},
+			{AuditLogSink: "kafka"},
+		} {
+			require.Error(t, m.Validate(), m.AuditLogSink)
+		}
+	})
+
+	t.Run("custom actor key", func(t *testing.T) {
+		m := Metadata{AuditLogSink: SinkFile, AuditLogFile: "audit.log", AuditLogActorKey: "user"}
+		require.NoError(t, m.Validate())
+		assert.Equal(t, "user", m.AuditLogActorKey)
+	})
+}
+
+func TestRecords(t *testing.T) {
+	m := Metadata{AuditLogSink: SinkTable}
+	require.NoError(t, m.Validate())
+	l := NewLog(m, nil)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	records := l.Records(
+		state.SetRequest{Key: "a", Metadata: map[string]string{"actor": "alice"}},
+		state.DeleteRequest{Key: "b"},
+	)
+	assert.Equal(t, []Record{
+		{Key: "a", Operation: state.OperationUpsert, Actor: "alice", Time: now},
+		{Key: "b", Operation: state.OperationDelete, Time: now},
+	}, records)
+	assert.True(t, l.InTable())
+	assert.Equal(t, DefaultTable, l.Table())
+	require.Error(t, l.Write(context.Background(), records))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s := NewFileSink(path)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.Write(context.Background(), []Record{{Key: "a", Operation: state.OperationUpsert, Time: now}}))
+	require.NoError(t, s.Write(context.Background(), []Record{{Key: "b", Operation: state.OperationDelete, Actor: "bob", Time: now}}))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{
+		`{"key":"a","operation":"upsert","time":"2023-05-01T10:00:00Z"}`,
+		`{"key":"b","operation":"delete","actor":"bob","time":"2023-05-01T10:00:00Z"}`,
+	}, lines)
+}
+
+func TestStoreSink(t *testing.T) {
+	stateStore := newStateStore(t)
+	s := NewStoreSink(stateStore, "postgresql||state")
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	records := []Record{
+		{Key: "a", Operation: state.OperationUpsert, Time: now},
+		{Key: "a", Operation: state.OperationDelete, Time: now},
+	}
+	require.NoError(t, s.Write(context.Background(), records))
+
+	for i, r := range records {
+		key := "audit||postgresql||state||" + "1682935200000000000||" + strconv.Itoa(i+1)
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: key})
+		require.NoError(t, err)
+		require.NotNil(t, res.Data, key)
+		var saved Record
+		require.NoError(t, json.Unmarshal(res.Data, &saved))
+		assert.Equal(t, r, saved)
+	}
+}
+
+func TestPublisherSink(t *testing.T) {
+	var published []string
+	s := NewPublisherSink(func(_ context.Context, topic string, data []byte) error {
+		published = append(published, topic+": "+string(data))
+		if strings.Contains(string(data), `"key":"fail"`) {
+			return errors.New("publish failed")
+		}
+		return nil
+	}, "audit")
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.Write(context.Background(), []Record{{Key: "a", Operation: state.OperationUpsert, Time: now}}))
+	assert.Equal(t, []string{`audit: {"key":"a","operation":"upsert","time":"2023-05-01T10:00:00Z"}`}, published)
+
+	published = nil
+	err := s.Write(context.Background(), []Record{{Key: "fail", Time: now}, {Key: "b", Time: now}})
+	require.Error(t, err)
+	assert.Len(t, published, 1)
+}
+
+func TestHolder(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		h := &Holder{}
+		h.Init(Metadata{}, "postgresql||state")
+		require.Error(t, h.SetAuditLogStore(newStateStore(t)))
+	})
+
+	t.Run("table and file sinks don't need to be set", func(t *testing.T) {
+		h := &Holder{}
+		h.Init(Metadata{AuditLogSink: SinkTable, AuditLogTable: DefaultTable}, "postgresql||state")
+		assert.True(t, h.InTable())
+		l, err := h.Get()
+		require.NoError(t, err)
+		assert.True(t, l.InTable())
+
+		h.Init(Metadata{AuditLogSink: SinkFile, AuditLogFile: filepath.Join(t.TempDir(), "audit.log")}, "postgresql||state")
+		assert.False(t, h.InTable())
+		l, err = h.Get()
+		require.NoError(t, err)
+		require.NoError(t, l.Write(context.Background(), []Record{{Key: "a"}}))
+	})
+
+	t.Run("state store", func(t *testing.T) {
+		h := &Holder{}
+		h.Init(Metadata{AuditLogSink: SinkStateStore, AuditLogComponent: "auditstore"}, "postgresql||state")
+		require.Error(t, h.SetAuditLogStore(nil))
+		require.Error(t, h.SetAuditLogPublisher(func(context.Context, string, []byte) error { return nil }))
+
+		require.NoError(t, h.SetAuditLogStore(newStateStore(t)))
+		l, err := h.Get()
+		require.NoError(t, err)
+		require.NotNil(t, l)
+	})
+
+	t.Run("pubsub", func(t *testing.T) {
+		h := &Holder{}
+		h.Init(Metadata{AuditLogSink: SinkPubSub, AuditLogComponent: "pubsub", AuditLogTopic: "audit"}, "postgresql||state")
+		require.Error(t, h.SetAuditLogStore(newStateStore(t)))
+
+		var topic string
+		require.NoError(t, h.SetAuditLogPublisher(func(_ context.Context, t string, _ []byte) error {
+			topic = t
+			return nil
+		}))
+		l, err := h.Get()
+		require.NoError(t, err)
+		require.NoError(t, l.Write(context.Background(), []Record{{Key: "a"}}))
+		assert.Equal(t, "audit", topic)
+	})
+}
@@ -50,6 +50,10 @@ type AzureEventHubs struct {
 
 	managementCreds azcore.TokenCredential
 
+	// Active subscriptions, by topic
+	subscriptions     map[string]*subscription
+	subscriptionsLock sync.Mutex
+
 	// TODO(@ItalyPaleAle): Remove in Dapr 1.13
 	isFailed atomic.Bool
 }
@@ -62,6 +66,7 @@ func NewAzureEventHubs(logger logger.Logger, isBinding bool) *AzureEventHubs {
 		producersLock:       &sync.RWMutex{},
 		producers:           make(map[string]*azeventhubs.ProducerClient, 1),
 		checkpointStoreLock: &sync.RWMutex{},
+		subscriptions:       map[string]*subscription{},
 	}
 }
 
@@ -145,7 +150,7 @@ func (aeh *AzureEventHubs) Subscribe(subscribeCtx context.Context, topic string,
 	}
 
 	// Get the processor client
-	processor, err := aeh.getProcessorForTopic(subscribeCtx, topic)
+	processor, consumerClient, err := aeh.getProcessorForTopic(subscribeCtx, topic)
 	if err != nil {
 		return fmt.Errorf("error trying to establish a connection: %w", err)
 	}
@@ -214,20 +219,71 @@ func (aeh *AzureEventHubs) Subscribe(subscribeCtx context.Context, topic string,
 	// Get the subscribe handler
 	eventHandler := subscribeHandler(subscribeCtx, getAllProperties, retryHandler)
 
+	sub := &subscription{
+		seekCh: make(chan seekOperation),
+	}
+	aeh.subscriptionsLock.Lock()
+	aeh.subscriptions[topic] = sub
+	aeh.subscriptionsLock.Unlock()
+
+	go func() {
+		defer func() {
+			aeh.subscriptionsLock.Lock()
+			if aeh.subscriptions[topic] == sub {
+				delete(aeh.subscriptions, topic)
+			}
+			aeh.subscriptionsLock.Unlock()
+		}()
+
+		for {
+			runCtx, runCancel := context.WithCancel(subscribeCtx)
+			running := aeh.runProcessor(runCtx, topic, processor, eventHandler)
+
+			select {
+			case <-subscribeCtx.Done():
+				runCancel()
+				return
+			case op := <-sub.seekCh:
+				// The partitions are released before their checkpoints are moved, then the processor is restarted to read them
+				runCancel()
+				running.Wait()
+				op.result <- aeh.moveCheckpoints(op.ctx, topic, consumerClient, op.req)
+
+				processor, err = aeh.newProcessor(subscribeCtx, consumerClient)
+				if err != nil {
+					aeh.logger.Errorf("Error restarting the subscription to topic %s after seeking: %v", topic, err)
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// runProcessor runs the processor until runCtx is canceled, processing the events of the partitions that it claims.
+// The returned WaitGroup is done when the processor and all partition clients are stopped.
+func (aeh *AzureEventHubs) runProcessor(runCtx context.Context, topic string, processor *azeventhubs.Processor, eventHandler func(e *azeventhubs.ReceivedEventData) error) *sync.WaitGroup {
+	running := &sync.WaitGroup{}
+	running.Add(2)
+
 	// Process all partition clients as they come in
 	go func() {
+		defer running.Done()
 		for {
 			// This will block until a new partition client is available
 			// It returns nil if processor.Run terminates or if the context is canceled
-			partitionClient := processor.NextPartitionClient(subscribeCtx)
+			partitionClient := processor.NextPartitionClient(runCtx)
 			if partitionClient == nil {
 				return
 			}
 			aeh.logger.Debugf("Received client for partition %s", partitionClient.PartitionID())
 
 			// Once we get a partition client, process the events in a separate goroutine
+			running.Add(1)
 			go func() {
-				processErr := aeh.processEvents(subscribeCtx, topic, partitionClient, eventHandler)
+				defer running.Done()
+				processErr := aeh.processEvents(runCtx, topic, partitionClient, eventHandler)
 				// Do not log context.Canceled which happens at shutdown
 				if processErr != nil && !errors.Is(processErr, context.Canceled) {
 					aeh.logger.Errorf("Error processing events from partition client: %v", processErr)
@@ -238,15 +294,16 @@ func (aeh *AzureEventHubs) Subscribe(subscribeCtx context.Context, topic string,
 
 	// Start the processor
 	go func() {
+		defer running.Done()
 		// This is a blocking call that runs until the context is canceled
-		err = processor.Run(subscribeCtx)
+		err := processor.Run(runCtx)
 		// Do not log context.Canceled which happens at shutdown
 		if err != nil && !errors.Is(err, context.Canceled) {
 			aeh.logger.Errorf("Error from event processor: %v", err)
 		}
 	}()
 
-	return nil
+	return running
 }
 
 func (aeh *AzureEventHubs) processEvents(subscribeCtx context.Context, topic string, partitionClient *azeventhubs.ProcessorPartitionClient, eventHandler func(e *azeventhubs.ReceivedEventData) error) error {
@@ -399,11 +456,11 @@ func (aeh *AzureEventHubs) getProducerClientForTopic(ctx context.Context, topic
 }
 
 // Creates a processor for a given topic.
-func (aeh *AzureEventHubs) getProcessorForTopic(ctx context.Context, topic string) (*azeventhubs.Processor, error) {
+func (aeh *AzureEventHubs) getProcessorForTopic(ctx context.Context, topic string) (*azeventhubs.Processor, *azeventhubs.ConsumerClient, error) {
 	// Get the checkpoint store
 	checkpointStore, err := aeh.getCheckpointStore(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to connect to the checkpoint store: %w", err)
+		return nil, nil, fmt.Errorf("unable to connect to the checkpoint store: %w", err)
 	}
 
 	// Create a new entity if needed
@@ -414,7 +471,7 @@ func (aeh *AzureEventHubs) getProcessorForTopic(ctx context.Context, topic strin
 		err = aeh.ensureEventHubEntity(ctx, topic)
 		aeh.producersLock.Unlock()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Event Hub entity %s: %w", topic, err)
+			return nil, nil, fmt.Errorf("failed to create Event Hub entity %s: %w", topic, err)
 		}
 
 		// Abuse on the lock on checkpoints which are used by all tasks creating processors
@@ -422,7 +479,7 @@ func (aeh *AzureEventHubs) getProcessorForTopic(ctx context.Context, topic strin
 		err = aeh.ensureSubscription(ctx, topic)
 		aeh.checkpointStoreLock.Unlock()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Event Hub subscription to entity %s: %w", topic, err)
+			return nil, nil, fmt.Errorf("failed to create Event Hub subscription to entity %s: %w", topic, err)
 		}
 	}
 
@@ -437,30 +494,44 @@ func (aeh *AzureEventHubs) getProcessorForTopic(ctx context.Context, topic strin
 		var connString string
 		connString, err = aeh.constructConnectionStringFromTopic(topic)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		consumerClient, err = azeventhubs.NewConsumerClientFromConnectionString(connString, "", aeh.metadata.ConsumerGroup, clientOpts)
 		if err != nil {
-			return nil, fmt.Errorf("unable to connect to Azure Event Hub using a connection string: %w", err)
+			return nil, nil, fmt.Errorf("unable to connect to Azure Event Hub using a connection string: %w", err)
 		}
 	} else {
 		// Use Azure AD
 		cred, tokenErr := aeh.metadata.azEnvSettings.GetTokenCredential()
 		if tokenErr != nil {
-			return nil, fmt.Errorf("failed to get credentials from Azure AD: %w", tokenErr)
+			return nil, nil, fmt.Errorf("failed to get credentials from Azure AD: %w", tokenErr)
 		}
 		consumerClient, err = azeventhubs.NewConsumerClient(aeh.metadata.EventHubNamespace, topic, aeh.metadata.ConsumerGroup, cred, clientOpts)
 		if err != nil {
-			return nil, fmt.Errorf("unable to connect to Azure Event Hub using Azure AD: %w", err)
+			return nil, nil, fmt.Errorf("unable to connect to Azure Event Hub using Azure AD: %w", err)
 		}
 	}
 
 	// Create the processor from the consumer client and checkpoint store
-	processor, err := azeventhubs.NewProcessor(consumerClient, checkpointStore, nil)
+	// The processor claims the ownership of partitions in the checkpoint store, so each partition is processed by a single instance at a time
+	processor, err := azeventhubs.NewProcessor(consumerClient, checkpointStore, aeh.metadata.processorOptions())
 	if err != nil {
-		return nil, fmt.Errorf("unable to create the processor: %w", err)
+		return nil, nil, fmt.Errorf("unable to create the processor: %w", err)
 	}
 
+	return processor, consumerClient, nil
+}
+
+// Creates a new processor from the consumer client of a subscription, which claims the same partitions as the previous one.
+func (aeh *AzureEventHubs) newProcessor(ctx context.Context, consumerClient *azeventhubs.ConsumerClient) (*azeventhubs.Processor, error) {
+	checkpointStore, err := aeh.getCheckpointStore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the checkpoint store: %w", err)
+	}
+	processor, err := azeventhubs.NewProcessor(consumerClient, checkpointStore, aeh.metadata.processorOptions())
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the processor: %w", err)
+	}
 	return processor, nil
 }
 
@@ -15,7 +15,9 @@ package eventhubs
 
 import (
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -124,3 +126,54 @@ func TestConstructConnectionStringFromTopic(t *testing.T) {
 		assert.Equal(t, "", c)
 	})
 }
+
+func TestProcessorOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseEventHubsMetadata(map[string]string{"connectionString": "fake"}, false, testLogger)
+		require.NoError(t, err)
+
+		opts := m.processorOptions()
+		assert.Equal(t, azeventhubs.ProcessorStrategy(""), opts.LoadBalancingStrategy)
+		assert.Equal(t, time.Duration(0), opts.PartitionExpirationDuration)
+		assert.Equal(t, time.Duration(0), opts.UpdateInterval)
+		assert.Equal(t, int32(0), opts.Prefetch)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		m, err := parseEventHubsMetadata(map[string]string{
+			"connectionString":             "fake",
+			"partitionOwnershipExpiration": "30s",
+			"ownershipUpdateInterval":      "5s",
+			"loadBalancingStrategy":        "Greedy",
+			"prefetchCount":                "-1",
+		}, false, testLogger)
+		require.NoError(t, err)
+
+		opts := m.processorOptions()
+		assert.Equal(t, azeventhubs.ProcessorStrategyGreedy, opts.LoadBalancingStrategy)
+		assert.Equal(t, 30*time.Second, opts.PartitionExpirationDuration)
+		assert.Equal(t, 5*time.Second, opts.UpdateInterval)
+		assert.Equal(t, int32(-1), opts.Prefetch)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		tests := map[string]struct {
+			props map[string]string
+			err   string
+		}{
+			"strategy":                 {props: map[string]string{"loadBalancingStrategy": "random"}, err: "loadBalancingStrategy"},
+			"negative expiration":      {props: map[string]string{"partitionOwnershipExpiration": "-1s"}, err: "partitionOwnershipExpiration"},
+			"prefetch":                 {props: map[string]string{"prefetchCount": "-2"}, err: "prefetchCount"},
+			"expiration too short":     {props: map[string]string{"partitionOwnershipExpiration": "5s"}, err: "must be shorter than partitionOwnershipExpiration"},
+			"interval too long":        {props: map[string]string{"ownershipUpdateInterval": "2m"}, err: "must be shorter than partitionOwnershipExpiration"},
+			"negative update interval": {props: map[string]string{"ownershipUpdateInterval": "-1s"}, err: "ownershipUpdateInterval"},
+		}
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				tc.props["connectionString"] = "fake"
+				_, err := parseEventHubsMetadata(tc.props, false, testLogger)
+				require.ErrorContains(t, err, tc.err)
+			})
+		}
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
 
@@ -39,6 +40,12 @@ type AzureEventHubsMetadata struct {
 	SubscriptionID          string `json:"subscriptionID" mapstructure:"subscriptionID"`
 	ResourceGroupName       string `json:"resourceGroupName" mapstructure:"resourceGroupName"`
 
+	// Options of the processor, which claims partitions in the checkpoint store so each partition is owned by one subscriber in the consumer group
+	PartitionOwnershipExpiration time.Duration `json:"partitionOwnershipExpiration" mapstructure:"partitionOwnershipExpiration"`
+	OwnershipUpdateInterval      time.Duration `json:"ownershipUpdateInterval" mapstructure:"ownershipUpdateInterval"`
+	LoadBalancingStrategy        string        `json:"loadBalancingStrategy" mapstructure:"loadBalancingStrategy"`
+	PrefetchCount                int32         `json:"prefetchCount,string" mapstructure:"prefetchCount"`
+
 	// Binding only
 	EventHub      string `json:"eventHub" mapstructure:"eventHub" only:"bindings"`
 	ConsumerGroup string `json:"consumerGroup" mapstructure:"consumerGroup" only:"bindings"` // Alias for ConsumerID
@@ -120,6 +127,11 @@ func parseEventHubsMetadata(meta map[string]string, isBinding bool, log logger.L
 		log.Warn("Property storageAccountKey is ignored when storageConnectionString is present")
 	}
 
+	err = m.validateProcessorOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	// Entity management is only possible when using Azure AD
 	if m.EnableEntityManagement && m.ConnectionString != "" {
 		m.EnableEntityManagement = false
@@ -141,6 +153,54 @@ func parseEventHubsMetadata(meta map[string]string, isBinding bool, log logger.L
 	return &m, nil
 }
 
+// Default values of the processor options, which match the defaults of the SDK.
+const (
+	defaultPartitionOwnershipExpiration = time.Minute
+	defaultOwnershipUpdateInterval      = 10 * time.Second
+)
+
+func (m *AzureEventHubsMetadata) validateProcessorOptions() error {
+	switch strings.ToLower(m.LoadBalancingStrategy) {
+	case "", string(azeventhubs.ProcessorStrategyBalanced), string(azeventhubs.ProcessorStrategyGreedy):
+	default:
+		return fmt.Errorf("invalid value for property loadBalancingStrategy: '%s'; supported values are 'balanced' and 'greedy'", m.LoadBalancingStrategy)
+	}
+	if m.PartitionOwnershipExpiration < 0 {
+		return errors.New("property partitionOwnershipExpiration must not be negative")
+	}
+	if m.OwnershipUpdateInterval < 0 {
+		return errors.New("property ownershipUpdateInterval must not be negative")
+	}
+	if m.PrefetchCount < -1 {
+		return errors.New("property prefetchCount must be -1 (to disable prefetching), 0 (to use the default), or positive")
+	}
+
+	// Ownership must be renewed before it expires, or other subscribers could claim partitions that are still being processed
+	expiration := m.PartitionOwnershipExpiration
+	if expiration == 0 {
+		expiration = defaultPartitionOwnershipExpiration
+	}
+	updateInterval := m.OwnershipUpdateInterval
+	if updateInterval == 0 {
+		updateInterval = defaultOwnershipUpdateInterval
+	}
+	if updateInterval >= expiration {
+		return fmt.Errorf("property ownershipUpdateInterval (%v) must be shorter than partitionOwnershipExpiration (%v)", updateInterval, expiration)
+	}
+
+	return nil
+}
+
+// processorOptions returns the options for the processor.
+func (m *AzureEventHubsMetadata) processorOptions() *azeventhubs.ProcessorOptions {
+	return &azeventhubs.ProcessorOptions{
+		LoadBalancingStrategy:       azeventhubs.ProcessorStrategy(strings.ToLower(m.LoadBalancingStrategy)),
+		UpdateInterval:              m.OwnershipUpdateInterval,
+		PartitionExpirationDuration: m.PartitionOwnershipExpiration,
+		Prefetch:                    m.PrefetchCount,
+	}
+}
+
 // Returns the hub name (topic) from the connection string.
 func hubNameFromConnString(connString string) string {
 	props, err := azeventhubs.ParseConnectionString(connString)
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// subscription is an active subscription to a topic.
+type subscription struct {
+	seekCh chan seekOperation
+}
+
+// seekOperation is a request to move the position of a subscription, which is performed by the goroutine that runs it.
+type seekOperation struct {
+	ctx    context.Context
+	req    *pubsub.SeekRequest
+	result chan error
+}
+
+// Seek moves the position of the subscription to a topic, in the partitions owned by this instance.
+// Messages are delivered again from the event with the sequence number in the request, or from the first event enqueued at or after the timestamp.
+func (aeh *AzureEventHubs) Seek(ctx context.Context, req *pubsub.SeekRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+
+	aeh.subscriptionsLock.Lock()
+	sub, ok := aeh.subscriptions[req.Topic]
+	aeh.subscriptionsLock.Unlock()
+	if !ok {
+		return fmt.Errorf("not subscribed to topic %s", req.Topic)
+	}
+
+	aeh.logger.Warnf("Seeking the subscription to topic %s: messages will be delivered again, including those that were already processed", req.Topic)
+	op := seekOperation{
+		ctx:    ctx,
+		req:    req,
+		result: make(chan error, 1),
+	}
+	select {
+	case sub.seekCh <- op:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err = <-op.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// moveCheckpoints updates the checkpoints of the partitions owned by the consumer client, so the next processor starts from the position in the request.
+// It must be called while no processor is running for the topic.
+func (aeh *AzureEventHubs) moveCheckpoints(ctx context.Context, topic string, consumerClient *azeventhubs.ConsumerClient, req *pubsub.SeekRequest) error {
+	checkpointStore, err := aeh.getCheckpointStore(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to connect to the checkpoint store: %w", err)
+	}
+
+	fqdn := aeh.metadata.EventHubNamespace
+	if aeh.metadata.ConnectionString != "" {
+		connString, err := aeh.constructConnectionStringFromTopic(topic)
+		if err != nil {
+			return err
+		}
+		props, err := azeventhubs.ParseConnectionString(connString)
+		if err != nil {
+			return fmt.Errorf("invalid connection string: %w", err)
+		}
+		fqdn = props.FullyQualifiedNamespace
+	}
+
+	getCtx, getCancel := context.WithTimeout(ctx, resourceGetTimeout)
+	hubProps, err := consumerClient.GetEventHubProperties(getCtx, nil)
+	getCancel()
+	if err != nil {
+		return fmt.Errorf("failed to get the properties of Event Hub %s: %w", topic, err)
+	}
+
+	ownerships, err := checkpointStore.ListOwnership(ctx, fqdn, hubProps.Name, aeh.metadata.ConsumerGroup, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list the owned partitions: %w", err)
+	}
+
+	moved := 0
+	for _, o := range ownerships {
+		if o.OwnerID != consumerClient.ID() || (req.Partition != "" && o.PartitionID != req.Partition) {
+			continue
+		}
+
+		offset, seq, err := aeh.resolveCheckpoint(ctx, consumerClient, o.PartitionID, req)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the position of partition %s: %w", o.PartitionID, err)
+		}
+		err = checkpointStore.UpdateCheckpoint(ctx, azeventhubs.Checkpoint{
+			ConsumerGroup:           aeh.metadata.ConsumerGroup,
+			EventHubName:            hubProps.Name,
+			FullyQualifiedNamespace: fqdn,
+			PartitionID:             o.PartitionID,
+			Offset:                  &offset,
+			SequenceNumber:          &seq,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update the checkpoint of partition %s: %w", o.PartitionID, err)
+		}
+		aeh.logger.Infof("Moved partition %s of topic %s after sequence number %d", o.PartitionID, topic, seq)
+		moved++
+	}
+
+	if moved == 0 {
+		return fmt.Errorf("no partitions of topic %s matching the request are owned by this instance", topic)
+	}
+	return nil
+}
+
+// resolveCheckpoint returns the offset and sequence number of the checkpoint for a partition, which are those of the event before the first one to deliver.
+func (aeh *AzureEventHubs) resolveCheckpoint(ctx context.Context, consumerClient *azeventhubs.ConsumerClient, partitionID string, req *pubsub.SeekRequest) (int64, int64, error) {
+	getCtx, getCancel := context.WithTimeout(ctx, resourceGetTimeout)
+	props, err := consumerClient.GetPartitionProperties(getCtx, partitionID, nil)
+	getCancel()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, ok := seekStartPosition(req, props)
+	if !ok {
+		offset, seq := seekCheckpoint(nil, props)
+		return offset, seq, nil
+	}
+
+	partitionClient, err := consumerClient.NewPartitionClient(partitionID, &azeventhubs.PartitionClientOptions{
+		StartPosition: start,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), resourceGetTimeout)
+		defer closeCancel()
+		closeErr := partitionClient.Close(closeCtx)
+		if closeErr != nil {
+			aeh.logger.Errorf("Error while closing partition client: %v", closeErr)
+		}
+	}()
+
+	// Receive the first event at the position; the deadline is reached if there are no events after it
+	receiveCtx, receiveCancel := context.WithTimeout(ctx, resourceCreationTimeout)
+	events, err := partitionClient.ReceiveEvents(receiveCtx, 1, nil)
+	receiveCancel()
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return 0, 0, err
+	}
+
+	var first *azeventhubs.ReceivedEventData
+	if len(events) > 0 {
+		first = events[0]
+	}
+	offset, seq := seekCheckpoint(first, props)
+	return offset, seq, nil
+}
+
+// seekStartPosition returns the position of the first event to deliver in a partition.
+// It returns false if there are no events at or after the position, so no new events are delivered.
+func seekStartPosition(req *pubsub.SeekRequest, props azeventhubs.PartitionProperties) (azeventhubs.StartPosition, bool) {
+	if props.IsEmpty {
+		return azeventhubs.StartPosition{}, false
+	}
+	if req.Timestamp != nil {
+		if req.Timestamp.After(props.LastEnqueuedOn) {
+			return azeventhubs.StartPosition{}, false
+		}
+		ts := req.Timestamp.UTC().Truncate(time.Millisecond)
+		return azeventhubs.StartPosition{EnqueuedTime: &ts, Inclusive: true}, true
+	}
+
+	// Sequence numbers before the first event in the partition are moved to it
+	seq := *req.Offset
+	if seq > props.LastEnqueuedSequenceNumber {
+		return azeventhubs.StartPosition{}, false
+	}
+	if seq < props.BeginningSequenceNumber {
+		seq = props.BeginningSequenceNumber
+	}
+	return azeventhubs.StartPosition{SequenceNumber: &seq, Inclusive: true}, true
+}
+
+// seekCheckpoint returns the offset and sequence number of the checkpoint before the first event to deliver.
+// If first is nil, the checkpoint is at the last event in the partition.
+func seekCheckpoint(first *azeventhubs.ReceivedEventData, props azeventhubs.PartitionProperties) (int64, int64) {
+	switch {
+	case first != nil && first.Offset != nil:
+		// Offsets are positions in the partition, so the offset before the one of the event is after all previous events
+		return *first.Offset - 1, first.SequenceNumber - 1
+	case props.IsEmpty:
+		return -1, props.BeginningSequenceNumber - 1
+	default:
+		return props.LastEnqueuedOffset, props.LastEnqueuedSequenceNumber
+	}
+}
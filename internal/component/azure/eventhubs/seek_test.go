@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestSeekStartPosition(t *testing.T) {
+	now := time.Now()
+	props := azeventhubs.PartitionProperties{
+		BeginningSequenceNumber:    10,
+		LastEnqueuedSequenceNumber: 20,
+		LastEnqueuedOffset:         4096,
+		LastEnqueuedOn:             now,
+	}
+
+	t.Run("sequence number in the partition", func(t *testing.T) {
+		start, ok := seekStartPosition(&pubsub.SeekRequest{Offset: ptr.Of(int64(15))}, props)
+		assert.True(t, ok)
+		assert.Equal(t, int64(15), *start.SequenceNumber)
+		assert.True(t, start.Inclusive)
+	})
+
+	t.Run("sequence number before the first event", func(t *testing.T) {
+		start, ok := seekStartPosition(&pubsub.SeekRequest{Offset: ptr.Of(int64(0))}, props)
+		assert.True(t, ok)
+		assert.Equal(t, int64(10), *start.SequenceNumber)
+	})
+
+	t.Run("sequence number after the last event", func(t *testing.T) {
+		_, ok := seekStartPosition(&pubsub.SeekRequest{Offset: ptr.Of(int64(21))}, props)
+		assert.False(t, ok)
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		ts := now.Add(-time.Hour)
+		start, ok := seekStartPosition(&pubsub.SeekRequest{Timestamp: &ts}, props)
+		assert.True(t, ok)
+		assert.True(t, start.EnqueuedTime.Equal(ts.Truncate(time.Millisecond)))
+		assert.True(t, start.Inclusive)
+	})
+
+	t.Run("timestamp after the last event", func(t *testing.T) {
+		ts := now.Add(time.Hour)
+		_, ok := seekStartPosition(&pubsub.SeekRequest{Timestamp: &ts}, props)
+		assert.False(t, ok)
+	})
+
+	t.Run("empty partition", func(t *testing.T) {
+		_, ok := seekStartPosition(&pubsub.SeekRequest{Offset: ptr.Of(int64(15))}, azeventhubs.PartitionProperties{IsEmpty: true})
+		assert.False(t, ok)
+	})
+}
+
+func TestSeekCheckpoint(t *testing.T) {
+	props := azeventhubs.PartitionProperties{
+		BeginningSequenceNumber:    10,
+		LastEnqueuedSequenceNumber: 20,
+		LastEnqueuedOffset:         4096,
+	}
+
+	t.Run("before the first event", func(t *testing.T) {
+		offset, seq := seekCheckpoint(&azeventhubs.ReceivedEventData{
+			Offset:         ptr.Of(int64(1024)),
+			SequenceNumber: 15,
+		}, props)
+		assert.Equal(t, int64(1023), offset)
+		assert.Equal(t, int64(14), seq)
+	})
+
+	t.Run("no events", func(t *testing.T) {
+		offset, seq := seekCheckpoint(nil, props)
+		assert.Equal(t, int64(4096), offset)
+		assert.Equal(t, int64(20), seq)
+	})
+
+	t.Run("empty partition", func(t *testing.T) {
+		offset, seq := seekCheckpoint(nil, azeventhubs.PartitionProperties{IsEmpty: true, BeginningSequenceNumber: 5})
+		assert.Equal(t, int64(-1), offset)
+		assert.Equal(t, int64(4), seq)
+	})
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"context"
+	"fmt"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+// SetClaimCheckStore sets the state store used to offload payloads larger than the "claimCheckThreshold".
+func (c *Client) SetClaimCheckStore(store state.Store) error {
+	return c.claimCheck.SetClaimCheckStore(store)
+}
+
+// ClaimCheckStore returns the claim-check store, or nil if the feature is disabled.
+// It returns an error if a claim-check store is configured but it was not set.
+func (c *Client) ClaimCheckStore() (*claimcheck.Store, error) {
+	return c.claimCheck.Get()
+}
+
+// offloadPublishRequest returns a copy of req whose payload is offloaded to the claim-check store, if needed.
+func offloadPublishRequest(ctx context.Context, claimCheck *claimcheck.Store, req *pubsub.PublishRequest) (*pubsub.PublishRequest, error) {
+	if claimCheck == nil {
+		return req, nil
+	}
+	data, md, err := claimCheck.Offload(ctx, req.Data, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	offloaded := *req
+	offloaded.Data = data
+	offloaded.Metadata = md
+	return &offloaded, nil
+}
+
+// offloadBulkPublishRequest returns a copy of req whose entries' payloads are offloaded to the claim-check store, if needed.
+// If any payload can't be offloaded, the payloads offloaded before it are discarded.
+func offloadBulkPublishRequest(ctx context.Context, claimCheck *claimcheck.Store, req *pubsub.BulkPublishRequest, log logger.Logger) (*pubsub.BulkPublishRequest, error) {
+	if claimCheck == nil {
+		return req, nil
+	}
+	offloaded := *req
+	offloaded.Entries = make([]pubsub.BulkMessageEntry, len(req.Entries))
+	for i, entry := range req.Entries {
+		data, md, err := claimCheck.Offload(ctx, entry.Event, entry.Metadata)
+		if err != nil {
+			discardBulkPublishRequest(claimCheck, &pubsub.BulkPublishRequest{Entries: offloaded.Entries[:i]}, log)
+			return nil, err
+		}
+		entry.Event = data
+		entry.Metadata = md
+		offloaded.Entries[i] = entry
+	}
+	return &offloaded, nil
+}
+
+// discardBulkPublishRequest deletes the offloaded payloads of the entries of a request that could not be published.
+func discardBulkPublishRequest(claimCheck *claimcheck.Store, req *pubsub.BulkPublishRequest, log logger.Logger) {
+	for _, entry := range req.Entries {
+		discardClaimCheck(claimCheck, entry.Metadata, log)
+	}
+}
+
+// discardClaimCheck deletes the offloaded payload of a message that could not be published.
+// If this fails, the payload is deleted when its TTL expires.
+func discardClaimCheck(claimCheck *claimcheck.Store, metadata map[string]string, log logger.Logger) {
+	if claimCheck == nil {
+		return
+	}
+	err := claimCheck.Discard(context.Background(), metadata)
+	if err != nil {
+		log.Warnf("Failed to delete offloaded payload of unpublished message: %v", err)
+	}
+}
+
+// claimCheckMetadata returns the claim-check reference of a received message as metadata for the claim-check store.
+func claimCheckMetadata(m *azservicebus.ReceivedMessage) map[string]string {
+	ref, _ := m.ApplicationProperties[claimcheck.MetadataKey].(string)
+	if ref == "" {
+		return nil
+	}
+	return map[string]string{claimcheck.MetadataKey: ref}
+}
+
+// rehydrateMessages replaces the body of the messages that carry a claim-check reference with the offloaded payload.
+func (s *Subscription) rehydrateMessages(ctx context.Context, msgs []*azservicebus.ReceivedMessage) error {
+	if s.claimCheck == nil {
+		return nil
+	}
+	for _, m := range msgs {
+		md := claimCheckMetadata(m)
+		if md == nil {
+			continue
+		}
+		body, err := s.claimCheck.Rehydrate(ctx, m.Body, md)
+		if err != nil {
+			return fmt.Errorf("message %s: %w", m.MessageID, err)
+		}
+		m.Body = body
+	}
+	return nil
+}
+
+// releaseClaimCheck deletes the offloaded payload of a message that was completed.
+// Failures are logged, as the payload is deleted when its TTL expires.
+func (s *Subscription) releaseClaimCheck(ctx context.Context, m *azservicebus.ReceivedMessage) {
+	if s.claimCheck == nil {
+		return
+	}
+	err := s.claimCheck.Release(ctx, claimCheckMetadata(m))
+	if err != nil {
+		s.logger.Warnf("Failed to delete offloaded payload of message %s on %s: %v", m.MessageID, s.entity, err)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+type fakeReceiver struct {
+	Receiver
+	lock      sync.Mutex
+	completed []string
+	abandoned []string
+}
+
+func (r *fakeReceiver) CompleteMessage(_ context.Context, m *azservicebus.ReceivedMessage, _ *azservicebus.CompleteMessageOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.completed = append(r.completed, m.MessageID)
+	return nil
+}
+
+func (r *fakeReceiver) AbandonMessage(_ context.Context, m *azservicebus.ReceivedMessage, _ *azservicebus.AbandonMessageOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.abandoned = append(r.abandoned, m.MessageID)
+	return nil
+}
+
+func newClaimCheckStore(t *testing.T) (*claimcheck.Store, state.Store) {
+	t.Helper()
+
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	return claimcheck.NewStore(stateStore, "servicebus", claimcheck.Metadata{ClaimCheckThreshold: 10}), stateStore
+}
+
+func TestClaimCheck(t *testing.T) {
+	payload := []byte(strings.Repeat("x", 100))
+	log := logger.NewLogger("test")
+
+	t.Run("publish request is offloaded", func(t *testing.T) {
+		store, _ := newClaimCheckStore(t)
+		req := &pubsub.PublishRequest{Data: payload, Topic: "topic", Metadata: map[string]string{"a": "b"}}
+		offloaded, err := offloadPublishRequest(context.Background(), store, req)
+		require.NoError(t, err)
+		assert.Empty(t, offloaded.Data)
+		assert.NotEmpty(t, offloaded.Metadata[claimcheck.MetadataKey])
+		assert.Equal(t, payload, req.Data, "request must not be modified")
+
+		msg, err := NewASBMessageFromPubsubRequest(offloaded)
+		require.NoError(t, err)
+		assert.Equal(t, offloaded.Metadata[claimcheck.MetadataKey], msg.ApplicationProperties[claimcheck.MetadataKey])
+	})
+
+	t.Run("bulk publish request is offloaded", func(t *testing.T) {
+		store, stateStore := newClaimCheckStore(t)
+		req := &pubsub.BulkPublishRequest{
+			Topic: "topic",
+			Entries: []pubsub.BulkMessageEntry{
+				{EntryId: "1", Event: []byte("small")},
+				{EntryId: "2", Event: payload},
+			},
+		}
+		offloaded, err := offloadBulkPublishRequest(context.Background(), store, req, log)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("small"), offloaded.Entries[0].Event)
+		assert.Empty(t, offloaded.Entries[1].Event)
+		ref := offloaded.Entries[1].Metadata[claimcheck.MetadataKey]
+		require.NotEmpty(t, ref)
+
+		discardBulkPublishRequest(store, offloaded, log)
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: ref})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+
+	t.Run("subscription rehydrates and releases payloads", func(t *testing.T) {
+		store, stateStore := newClaimCheckStore(t)
+		_, md, err := store.Offload(context.Background(), payload, nil)
+		require.NoError(t, err)
+		ref := md[claimcheck.MetadataKey]
+
+		s := NewSubscription(SubscriptionOptions{
+			MaxActiveMessages: 1,
+			TimeoutInSec:      5,
+			Entity:            "topic",
+			ClaimCheck:        store,
+		}, log)
+		receiver := &fakeReceiver{}
+		var received []byte
+		handler := GetPubSubHandlerFunc("topic", func(ctx context.Context, msg *pubsub.NewMessage) error {
+			received = msg.Data
+			return nil
+		}, log, 0)
+
+		msg := &azservicebus.ReceivedMessage{
+			MessageID:             "msg1",
+			SequenceNumber:        ptr.Of(int64(1)),
+			ApplicationProperties: map[string]any{claimcheck.MetadataKey: ref},
+		}
+		s.activeOperationsChan <- struct{}{}
+		s.handleAsync(context.Background(), []*azservicebus.ReceivedMessage{msg}, handler, receiver)
+
+		assert.Equal(t, payload, received)
+		assert.Equal(t, []string{"msg1"}, receiver.completed)
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: ref})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+
+		// The payload is gone, so a redelivery is abandoned without invoking the handler
+		received = nil
+		s.activeOperationsChan <- struct{}{}
+		s.handleAsync(context.Background(), []*azservicebus.ReceivedMessage{msg}, handler, receiver)
+		assert.Nil(t, received)
+		assert.Equal(t, []string{"msg1"}, receiver.abandoned)
+	})
+}
@@ -16,6 +16,7 @@ package servicebus
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,9 +26,15 @@ import (
 	"golang.org/x/exp/maps"
 
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/internal/component/batching"
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/internal/component/encryption"
 	"github.com/dapr/kit/logger"
 )
 
+// Name of the rule that Service Bus adds to new subscriptions.
+const defaultRuleName = "$Default"
+
 // Type that matches Client.EnsureTopic and Client.EnsureSubscription
 type ensureFn func(context.Context, string) error
 
@@ -38,6 +45,9 @@ type Client struct {
 	metadata    *Metadata
 	lock        *sync.RWMutex
 	senders     map[string]*servicebus.Sender
+	batcher     *batching.Batcher[*batchedMessage]
+	claimCheck  claimcheck.Holder
+	encryption  encryption.Holder
 }
 
 // NewClient creates a new Client object.
@@ -94,6 +104,12 @@ func NewClient(metadata *Metadata, rawMetadata map[string]string) (*Client, erro
 		}
 	}
 
+	if metadata.Settings.Enabled() {
+		client.batcher = batching.New(metadata.Settings, client.sendBatch)
+	}
+	client.claimCheck.Init(metadata.ClaimCheck, "servicebus")
+	client.encryption.Init(metadata.Encryption)
+
 	return client, nil
 }
 
@@ -197,6 +213,11 @@ func (c *Client) CloseAllSenders(log logger.Logger) {
 
 // Close the client and every sender or consumer created by the connnection.
 func (c *Client) Close(log logger.Logger) {
+	// Flush pending batches first, as sending them requires the lock
+	if c.batcher != nil {
+		c.batcher.Close()
+	}
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -334,6 +355,22 @@ func (c *Client) shouldCreateSubscription(parentCtx context.Context, topic, subs
 		return false, fmt.Errorf("subscription %s already exists but session requirement doesn't match", subscription)
 	}
 
+	if c.metadata.SubscriptionRule != "" {
+		var rules []sbadmin.RuleProperties
+		pager := c.adminClient.NewListRulesPager(topic, subscription, nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return false, fmt.Errorf("could not list rules of subscription %s: %w", subscription, err)
+			}
+			rules = append(rules, page.Rules...)
+		}
+		err = checkSubscriptionRules(rules, c.metadata.SubscriptionRule)
+		if err != nil {
+			return false, fmt.Errorf("subscription %s already exists but its rules don't match 'subscriptionRule': %w", subscription, err)
+		}
+	}
+
 	return false, nil
 }
 
@@ -347,6 +384,20 @@ func (c *Client) createSubscription(parentCtx context.Context, topic, subscripti
 	if err != nil {
 		return fmt.Errorf("could not create subscription %s: %w", subscription, err)
 	}
+
+	if c.metadata.SubscriptionRule != "" {
+		// New subscriptions come with a "$Default" rule that matches all messages: replace its filter with the configured one
+		_, err = c.adminClient.UpdateRule(ctx, topic, subscription, sbadmin.RuleProperties{
+			Name: defaultRuleName,
+			Filter: &sbadmin.SQLFilter{
+				Expression: c.metadata.SubscriptionRule,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not set rule on subscription %s: %w", subscription, err)
+		}
+	}
+
 	return nil
 }
 
@@ -387,6 +438,21 @@ func (c *Client) ReconnectionBackoff() backoff.BackOff {
 	return bo
 }
 
+// checkSubscriptionRules returns an error if the rules of an existing subscription don't consist of a single SQL filter with the given expression.
+func checkSubscriptionRules(rules []sbadmin.RuleProperties, expression string) error {
+	if len(rules) != 1 {
+		return fmt.Errorf("expected 1 rule but found %d", len(rules))
+	}
+	filter, ok := rules[0].Filter.(*sbadmin.SQLFilter)
+	if !ok {
+		return fmt.Errorf("rule '%s' is not a SQL filter", rules[0].Name)
+	}
+	if strings.TrimSpace(filter.Expression) != strings.TrimSpace(expression) {
+		return fmt.Errorf("rule '%s' has filter '%s'", rules[0].Name, filter.Expression)
+	}
+	return nil
+}
+
 func notEqual(a, b *bool) bool {
 	if a == nil && b == nil {
 		return false
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"testing"
+
+	sbadmin "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSubscriptionRules(t *testing.T) {
+	sqlRule := func(expression string) sbadmin.RuleProperties {
+		return sbadmin.RuleProperties{
+			Name:   defaultRuleName,
+			Filter: &sbadmin.SQLFilter{Expression: expression},
+		}
+	}
+
+	t.Run("matching rule", func(t *testing.T) {
+		err := checkSubscriptionRules([]sbadmin.RuleProperties{sqlRule("priority = 'high'")}, "priority = 'high'")
+		assert.NoError(t, err)
+	})
+
+	t.Run("surrounding whitespace is ignored", func(t *testing.T) {
+		err := checkSubscriptionRules([]sbadmin.RuleProperties{sqlRule(" priority = 'high'\n")}, "priority = 'high'")
+		assert.NoError(t, err)
+	})
+
+	t.Run("different expression", func(t *testing.T) {
+		err := checkSubscriptionRules([]sbadmin.RuleProperties{sqlRule("priority = 'low'")}, "priority = 'high'")
+		assert.ErrorContains(t, err, "priority = 'low'")
+	})
+
+	t.Run("default rule matching all messages", func(t *testing.T) {
+		err := checkSubscriptionRules([]sbadmin.RuleProperties{
+			{Name: defaultRuleName, Filter: &sbadmin.TrueFilter{}},
+		}, "priority = 'high'")
+		assert.ErrorContains(t, err, "is not a SQL filter")
+	})
+
+	t.Run("no rules", func(t *testing.T) {
+		err := checkSubscriptionRules(nil, "priority = 'high'")
+		assert.ErrorContains(t, err, "found 0")
+	})
+
+	t.Run("additional rules", func(t *testing.T) {
+		err := checkSubscriptionRules([]sbadmin.RuleProperties{
+			sqlRule("priority = 'high'"),
+			sqlRule("priority = 'low'"),
+		}, "priority = 'high'")
+		assert.ErrorContains(t, err, "found 2")
+	})
+}
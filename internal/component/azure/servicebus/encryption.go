@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"context"
+	"fmt"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/internal/component/encryption"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// SetCryptoProvider sets the crypto component used to encrypt payloads when publishing and decrypt them when receiving.
+func (c *Client) SetCryptoProvider(provider crypto.SubtleCrypto) error {
+	return c.encryption.SetCryptoProvider(provider)
+}
+
+// Encrypter returns the encrypter of payloads, or nil if the feature is disabled.
+// It returns an error if a crypto component is configured but it was not set.
+func (c *Client) Encrypter() (*encryption.Encrypter, error) {
+	return c.encryption.Get()
+}
+
+// encryptPublishRequest returns a copy of req whose payload is encrypted, if a crypto component is configured.
+func encryptPublishRequest(ctx context.Context, encrypter *encryption.Encrypter, req *pubsub.PublishRequest) (*pubsub.PublishRequest, error) {
+	if encrypter == nil {
+		return req, nil
+	}
+	data, md, err := encrypter.Encrypt(ctx, req.Data, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := *req
+	encrypted.Data = data
+	encrypted.Metadata = md
+	return &encrypted, nil
+}
+
+// encryptBulkPublishRequest returns a copy of req whose entries' payloads are encrypted, if a crypto component is configured.
+func encryptBulkPublishRequest(ctx context.Context, encrypter *encryption.Encrypter, req *pubsub.BulkPublishRequest) (*pubsub.BulkPublishRequest, error) {
+	if encrypter == nil {
+		return req, nil
+	}
+	encrypted := *req
+	encrypted.Entries = make([]pubsub.BulkMessageEntry, len(req.Entries))
+	for i, entry := range req.Entries {
+		data, md, err := encrypter.Encrypt(ctx, entry.Event, entry.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		entry.Event = data
+		entry.Metadata = md
+		encrypted.Entries[i] = entry
+	}
+	return &encrypted, nil
+}
+
+// encryptionMetadata returns the properties of a received message used to decrypt it.
+func encryptionMetadata(m *azservicebus.ReceivedMessage) map[string]string {
+	md := map[string]string{}
+	for _, k := range []string{encryption.KeyNameMetadataKey, encryption.AlgorithmMetadataKey, encryption.WrappedKeyMetadataKey, encryption.TagMetadataKey} {
+		if v, _ := m.ApplicationProperties[k].(string); v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// decryptMessages replaces the body of the encrypted messages with the plaintext.
+// It returns an error if any message can't be decrypted, including when no crypto component is configured, so ciphertext is never delivered.
+func (s *Subscription) decryptMessages(ctx context.Context, msgs []*azservicebus.ReceivedMessage) error {
+	for _, m := range msgs {
+		md := encryptionMetadata(m)
+		if !encryption.IsEncrypted(md) {
+			continue
+		}
+		body, err := s.encrypter.Decrypt(ctx, m.Body, md)
+		if err != nil {
+			return fmt.Errorf("message %s: %w", m.MessageID, err)
+		}
+		m.Body = body
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/crypto/localstorage"
+	"github.com/dapr/components-contrib/internal/component/encryption"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func newEncrypter(t *testing.T) *encryption.Encrypter {
+	t.Helper()
+
+	dir := t.TempDir()
+	jwk := `{"kty":"oct","k":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.json"), []byte(jwk), 0o600))
+	provider := localstorage.NewLocalStorageCrypto(logger.NewLogger("test"))
+	require.NoError(t, provider.Init(context.Background(), crypto.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"path": dir},
+	}}))
+	return encryption.NewEncrypter(provider, encryption.Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json"})
+}
+
+func TestEncryption(t *testing.T) {
+	log := logger.NewLogger("test")
+	encrypter := newEncrypter(t)
+
+	req := &pubsub.PublishRequest{Data: []byte("secret"), Topic: "topic", Metadata: map[string]string{"a": "b"}}
+	encrypted, err := encryptPublishRequest(context.Background(), encrypter, req)
+	require.NoError(t, err)
+	assert.NotEqual(t, req.Data, encrypted.Data)
+	assert.Equal(t, []byte("secret"), req.Data, "request must not be modified")
+	asbMsg, err := NewASBMessageFromPubsubRequest(encrypted)
+	require.NoError(t, err)
+	newMessage := func() *azservicebus.ReceivedMessage {
+		return &azservicebus.ReceivedMessage{
+			MessageID:             "msg1",
+			SequenceNumber:        ptr.Of(int64(1)),
+			Body:                  asbMsg.Body,
+			ApplicationProperties: asbMsg.ApplicationProperties,
+		}
+	}
+
+	t.Run("subscription decrypts payloads", func(t *testing.T) {
+		s := NewSubscription(SubscriptionOptions{
+			MaxActiveMessages: 1,
+			TimeoutInSec:      5,
+			Entity:            "topic",
+			Encrypter:         encrypter,
+		}, log)
+		receiver := &fakeReceiver{}
+		var received []byte
+		handler := GetPubSubHandlerFunc("topic", func(ctx context.Context, msg *pubsub.NewMessage) error {
+			received = msg.Data
+			return nil
+		}, log, 0)
+
+		s.activeOperationsChan <- struct{}{}
+		s.handleAsync(context.Background(), []*azservicebus.ReceivedMessage{newMessage()}, handler, receiver)
+		assert.Equal(t, []byte("secret"), received)
+		assert.Equal(t, []string{"msg1"}, receiver.completed)
+	})
+
+	t.Run("encrypted messages are abandoned without a crypto component", func(t *testing.T) {
+		s := NewSubscription(SubscriptionOptions{
+			MaxActiveMessages: 1,
+			TimeoutInSec:      5,
+			Entity:            "topic",
+		}, log)
+		receiver := &fakeReceiver{}
+		invoked := false
+		handler := GetPubSubHandlerFunc("topic", func(ctx context.Context, msg *pubsub.NewMessage) error {
+			invoked = true
+			return nil
+		}, log, 0)
+
+		s.activeOperationsChan <- struct{}{}
+		s.handleAsync(context.Background(), []*azservicebus.ReceivedMessage{newMessage()}, handler, receiver)
+		assert.False(t, invoked)
+		assert.Equal(t, []string{"msg1"}, receiver.abandoned)
+	})
+
+	t.Run("bulk publish request is encrypted", func(t *testing.T) {
+		req := &pubsub.BulkPublishRequest{
+			Topic: "topic",
+			Entries: []pubsub.BulkMessageEntry{
+				{EntryId: "1", Event: []byte("one")},
+				{EntryId: "2", Event: []byte("two")},
+			},
+		}
+		encrypted, err := encryptBulkPublishRequest(context.Background(), encrypter, req)
+		require.NoError(t, err)
+		for i, entry := range encrypted.Entries {
+			assert.True(t, encryption.IsEncrypted(entry.Metadata))
+			plaintext, err := encrypter.Decrypt(context.Background(), entry.Event, entry.Metadata)
+			require.NoError(t, err)
+			assert.Equal(t, req.Entries[i].Event, plaintext)
+		}
+	})
+}
@@ -21,6 +21,7 @@ import (
 	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/batching"
 	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/ptr"
@@ -161,6 +162,9 @@ func addMetadataToMessage(asbMsg *azservicebus.Message, metadata map[string]stri
 				asbMsg.ScheduledEnqueueTime = &timeVal
 			}
 
+		// Controls how the message is published, and is not sent
+		case batching.SkipBatchingKey:
+
 		// Fallback: set as application property
 		default:
 			asbMsg.ApplicationProperties[k] = v
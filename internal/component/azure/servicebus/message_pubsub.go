@@ -24,12 +24,22 @@ import (
 	"github.com/dapr/components-contrib/pubsub"
 )
 
+// Application properties containing the trace context of a message.
+const (
+	traceParentProperty  = "traceparent"
+	traceStateProperty   = "tracestate"
+	diagnosticIDProperty = "Diagnostic-Id"
+)
+
 // NewPubsubMessageFromASBMessage returns a pubsub.NewMessage from a message received from ASB.
 func NewPubsubMessageFromASBMessage(asbMsg *azservicebus.ReceivedMessage, topic string) (*pubsub.NewMessage, error) {
 	pubsubMsg := &pubsub.NewMessage{
 		Topic: topic,
 		Data:  asbMsg.Body,
 	}
+	if asbMsg.ContentType != nil && *asbMsg.ContentType != "" {
+		pubsubMsg.ContentType = asbMsg.ContentType
+	}
 
 	pubsubMsg.Metadata = addMessageAttributesToMetadata(pubsubMsg.Metadata, asbMsg)
 
@@ -47,6 +57,9 @@ func NewBulkMessageEntryFromASBMessage(asbMsg *azservicebus.ReceivedMessage) (pu
 		EntryId: entryId.String(),
 		Event:   asbMsg.Body,
 	}
+	if asbMsg.ContentType != nil {
+		bulkMsgEntry.ContentType = *asbMsg.ContentType
+	}
 
 	bulkMsgEntry.Metadata = addMessageAttributesToMetadata(bulkMsgEntry.Metadata, asbMsg)
 
@@ -83,6 +96,17 @@ func addMessageAttributesToMetadata(metadata map[string]string, asbMsg *azservic
 		metadata["metadata."+MessageKeyLockToken] = base64.StdEncoding.EncodeToString(asbMsg.LockToken[:])
 	}
 
+	// Include the trace context, if set by the publisher as application properties.
+	// Messages sent by the Azure SDKs carry it in the "Diagnostic-Id" property, in the W3C traceparent format.
+	if v, ok := asbMsg.ApplicationProperties[traceParentProperty].(string); ok && v != "" {
+		metadata[traceParentProperty] = v
+	} else if v, ok := asbMsg.ApplicationProperties[diagnosticIDProperty].(string); ok && v != "" {
+		metadata[traceParentProperty] = v
+	}
+	if v, ok := asbMsg.ApplicationProperties[traceStateProperty].(string); ok && v != "" {
+		metadata[traceStateProperty] = v
+	}
+
 	// Always set delivery count.
 	metadata["metadata."+MessageKeyDeliveryCount] = strconv.FormatInt(int64(asbMsg.DeliveryCount), 10)
 
@@ -19,6 +19,7 @@ import (
 
 	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAddMessageAttributesToMetadata(t *testing.T) {
@@ -78,3 +79,33 @@ func TestAddMessageAttributesToMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestNewPubsubMessageFromASBMessage(t *testing.T) {
+	t.Run("content type and trace context", func(t *testing.T) {
+		contentType := "application/json"
+		msg, err := NewPubsubMessageFromASBMessage(&azservicebus.ReceivedMessage{
+			Body:        []byte(`{"id":42}`),
+			ContentType: &contentType,
+			ApplicationProperties: map[string]interface{}{
+				"traceparent": "00-1-2-01",
+				"tracestate":  "k=v",
+			},
+		}, "mytopic")
+		require.NoError(t, err)
+		require.NotNil(t, msg.ContentType)
+		assert.Equal(t, "application/json", *msg.ContentType)
+		assert.Equal(t, "00-1-2-01", msg.Metadata["traceparent"])
+		assert.Equal(t, "k=v", msg.Metadata["tracestate"])
+	})
+
+	t.Run("Diagnostic-Id", func(t *testing.T) {
+		msg, err := NewPubsubMessageFromASBMessage(&azservicebus.ReceivedMessage{
+			ApplicationProperties: map[string]interface{}{
+				"Diagnostic-Id": "00-1-2-01",
+			},
+		}, "mytopic")
+		require.NoError(t, err)
+		assert.Nil(t, msg.ContentType)
+		assert.Equal(t, "00-1-2-01", msg.Metadata["traceparent"])
+	})
+}
@@ -20,6 +20,9 @@ import (
 
 	sbadmin "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
 
+	"github.com/dapr/components-contrib/internal/component/batching"
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/internal/component/encryption"
 	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
@@ -48,6 +51,13 @@ type Metadata struct {
 	PublishInitialRetryIntervalInMs int    `mapstructure:"publishInitialRetryIntervalInMs"`
 	NamespaceName                   string `mapstructure:"namespaceName"` // Only for Azure AD
 
+	/** For pubsubs only **/
+	batching.Settings `mapstructure:",squash" only:"pubsub"`
+	SubscriptionRule  string              `mapstructure:"subscriptionRule" only:"pubsub"` // Only topics - SQL filter expression applied to new subscriptions
+	PrefetchCount     *int                `mapstructure:"prefetchCount" only:"pubsub"`    // Maximum number of received messages waiting for a handler - only used with maxConcurrentHandlers
+	ClaimCheck        claimcheck.Metadata `mapstructure:",squash" only:"pubsub"`
+	Encryption        encryption.Metadata `mapstructure:",squash" only:"pubsub"`
+
 	/** For bindings only **/
 	QueueName string `mapstructure:"queueName" only:"bindings"` // Only queues
 }
@@ -73,6 +83,8 @@ const (
 	keyPublishInitialRetryIntervalInMs = "publishInitialRetryIntervalInMs" // Alias: "publishInitialRetryInternalInMs" (backwards compatibility due to typo)
 	keyNamespaceName                   = "namespaceName"
 	keyQueueName                       = "queueName"
+	keySubscriptionRule                = "subscriptionRule"
+	keyPrefetchCount                   = "prefetchCount"
 )
 
 // Defaults.
@@ -155,6 +167,15 @@ func ParseMetadata(md map[string]string, logger logger.Logger, mode byte) (m *Me
 		return m, mdErr
 	}
 
+	mdErr = m.Settings.Validate()
+	if mdErr != nil {
+		return m, mdErr
+	}
+	mdErr = m.Encryption.Validate()
+	if mdErr != nil {
+		return m, mdErr
+	}
+
 	/* Required configuration settings - no defaults. */
 	if m.ConnectionString != "" {
 		// The connection string and the namespace cannot both be present.
@@ -187,6 +208,10 @@ func ParseMetadata(md map[string]string, logger logger.Logger, mode byte) (m *Me
 		return m, err
 	}
 
+	if m.PrefetchCount != nil && *m.PrefetchCount < 0 {
+		return m, errors.New(keyPrefetchCount + " must not be negative")
+	}
+
 	/* Nullable configuration settings - defaults will be set by the server. */
 
 	if m.DefaultMessageTimeToLiveInSec == nil {
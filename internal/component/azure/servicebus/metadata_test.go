@@ -15,8 +15,12 @@ package servicebus
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/batching"
 )
 
 const invalidNumber = "invalid_number"
@@ -39,7 +43,8 @@ func getFakeProperties() map[string]string {
 		keyMinConnectionRecoveryInSec:    "5",
 		keyMaxConnectionRecoveryInSec:    "600",
 		keyMaxRetriableErrorsPerSec:      "50",
-		keyQueueName:                     "myqueue", // For queue bindings only
+		keyQueueName:                     "myqueue",           // For queue bindings only
+		keySubscriptionRule:              "priority = 'high'", // For topics only
 	}
 }
 
@@ -72,6 +77,7 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assert.Equal(t, 240, *m.AutoDeleteOnIdleInSec)
 		assert.NotNil(t, m.MaxDeliveryCount)
 		assert.Equal(t, int32(10), *m.MaxDeliveryCount)
+		assert.Equal(t, "priority = 'high'", m.SubscriptionRule)
 		assert.NotNil(t, m.DefaultMessageTimeToLiveInSec)
 		assert.Equal(t, 2400, *m.DefaultMessageTimeToLiveInSec)
 		assert.NotNil(t, m.LockDurationInSec)
@@ -458,6 +464,40 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("missing nullable prefetchCount", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, 0)
+
+		// assert.
+		assert.Nil(t, m.PrefetchCount)
+		assert.Nil(t, err)
+	})
+
+	t.Run("valid optional prefetchCount", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyPrefetchCount] = "5"
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, 0)
+
+		// assert.
+		assert.Equal(t, 5, *m.PrefetchCount)
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid optional prefetchCount", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyPrefetchCount] = "-1"
+
+		// act.
+		_, err := ParseMetadata(fakeProperties, nil, 0)
+
+		// assert.
+		assert.Error(t, err)
+	})
+
 	t.Run("missing nullable maxDeliveryCount", func(t *testing.T) {
 		fakeProperties := getFakeProperties()
 		delete(fakeProperties, keyMaxDeliveryCount)
@@ -506,3 +546,32 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assert.Nil(t, err)
 	})
 }
+
+func TestParsePublishBatchMetadata(t *testing.T) {
+	t.Run("batching disabled by default", func(t *testing.T) {
+		m, err := ParseMetadata(getFakeProperties(), nil, MetadataModeTopics)
+		require.NoError(t, err)
+		assert.False(t, m.Settings.Enabled())
+	})
+
+	t.Run("batching settings", func(t *testing.T) {
+		props := getFakeProperties()
+		props["publishBatchMaxDelay"] = "10ms"
+		props["publishBatchMaxMessages"] = "20"
+
+		m, err := ParseMetadata(props, nil, MetadataModeTopics)
+		require.NoError(t, err)
+		assert.True(t, m.Settings.Enabled())
+		assert.Equal(t, 10*time.Millisecond, m.PublishBatchMaxDelay)
+		assert.Equal(t, 20, m.PublishBatchMaxMessages)
+		assert.Equal(t, batching.DefaultMaxBytes, m.PublishBatchMaxBytes)
+	})
+
+	t.Run("invalid batching settings", func(t *testing.T) {
+		props := getFakeProperties()
+		props["publishBatchMaxMessages"] = "-1"
+
+		_, err := ParseMetadata(props, nil, MetadataModeTopics)
+		require.Error(t, err)
+	})
+}
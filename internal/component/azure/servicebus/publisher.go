@@ -15,6 +15,7 @@ package servicebus
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/batching"
 	"github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
@@ -35,34 +37,81 @@ const (
 )
 
 // PublishPubSub is used by PubSub components to publish messages. It includes a retry logic that can also cause reconnections.
+// If batching is enabled, the message is sent together with other messages published to the same topic, unless the "skipBatching" metadata property is true.
+// If a claim-check store is configured, payloads larger than the threshold are offloaded to it, and the message carries a reference to the payload.
 func (c *Client) PublishPubSub(ctx context.Context, req *pubsub.PublishRequest, ensureFn ensureFn, log logger.Logger) error {
+	encrypter, err := c.encryption.Get()
+	if err != nil {
+		return err
+	}
+	// Payloads are encrypted before they're offloaded, so the claim-check store only holds ciphertext
+	req, err = encryptPublishRequest(ctx, encrypter, req)
+	if err != nil {
+		return err
+	}
+	claimCheck, err := c.claimCheck.Get()
+	if err != nil {
+		return err
+	}
+	req, err = offloadPublishRequest(ctx, claimCheck, req)
+	if err != nil {
+		return err
+	}
+
+	err = c.publishPubSub(ctx, req, ensureFn, log)
+	if err != nil {
+		discardClaimCheck(claimCheck, req.Metadata, log)
+	}
+	return err
+}
+
+func (c *Client) publishPubSub(ctx context.Context, req *pubsub.PublishRequest, ensureFn ensureFn, log logger.Logger) error {
 	msg, err := NewASBMessageFromPubsubRequest(req)
 	if err != nil {
 		return err
 	}
 
-	bo := c.publishBackOff(ctx)
+	if c.batcher != nil && !batching.SkipBatching(req.Metadata) {
+		size := len(req.Data)
+		for k, v := range req.Metadata {
+			size += len(k) + len(v)
+		}
+		return c.batcher.Publish(ctx, req.Topic, &batchedMessage{
+			msg:      msg,
+			ensureFn: ensureFn,
+			log:      log,
+		}, size)
+	}
 
 	msgID := "nil"
 	if msg.MessageID != nil {
 		msgID = *msg.MessageID
 	}
-	err = retry.NotifyRecover(
+	return c.sendWithRetry(ctx, req.Topic, ensureFn, log, "Service Bus message ("+msgID+")", func(ctx context.Context, sender *servicebus.Sender) error {
+		return sender.SendMessage(ctx, msg, nil)
+	})
+}
+
+// sendWithRetry invokes sendFn with the sender for the topic, retrying on network errors (after reconnecting) and on retriable AMQP errors.
+func (c *Client) sendWithRetry(ctx context.Context, topic string, ensureFn ensureFn, log logger.Logger, desc string, sendFn func(ctx context.Context, sender *servicebus.Sender) error) error {
+	bo := c.publishBackOff(ctx)
+
+	err := retry.NotifyRecover(
 		func() error {
 			// Get the sender
-			sender, rErr := c.GetSender(ctx, req.Topic, ensureFn)
+			sender, rErr := c.GetSender(ctx, topic, ensureFn)
 			if rErr != nil {
 				return fmt.Errorf("failed to create a sender: %w", rErr)
 			}
 
 			// Try sending the message
 			publishCtx, publisCancel := context.WithTimeout(ctx, time.Second*time.Duration(c.metadata.TimeoutInSec))
-			rErr = sender.SendMessage(publishCtx, msg, nil)
+			rErr = sendFn(publishCtx, sender)
 			publisCancel()
 			if rErr != nil {
 				if IsNetworkError(rErr) {
 					// Retry after reconnecting
-					c.CloseSender(req.Topic, log)
+					c.CloseSender(topic, log)
 					return rErr
 				}
 
@@ -78,18 +127,88 @@ func (c *Client) PublishPubSub(ctx context.Context, req *pubsub.PublishRequest,
 		},
 		bo,
 		func(err error, _ time.Duration) {
-			log.Warnf("Could not publish Service Bus message (%s). Retrying...: %v", msgID, err)
+			log.Warnf("Could not publish %s. Retrying...: %v", desc, err)
 		},
 		func() {
-			log.Infof("Successfully published Service Bus message (%s) after it previously failed", msgID)
+			log.Infof("Successfully published %s after it previously failed", desc)
 		},
 	)
 	if err != nil {
-		log.Errorf("Too many failed attempts while publishing Service Bus message (%s): %v", msgID, err)
+		log.Errorf("Too many failed attempts while publishing %s: %v", desc, err)
 	}
 	return err
 }
 
+// batchedMessage is a message published with PublishPubSub that is waiting to be sent in a batch.
+type batchedMessage struct {
+	msg      *servicebus.Message
+	ensureFn ensureFn
+	log      logger.Logger
+}
+
+// sendBatch sends the messages coalesced by the batcher.
+// Messages are sent in as few Service Bus batches as possible, each limited to the maximum size allowed by the entity.
+func (c *Client) sendBatch(topic string, msgs []*batchedMessage) []error {
+	var (
+		errs   []error
+		failed bool
+	)
+	setErr := func(i int, err error) {
+		if errs == nil {
+			errs = make([]error, len(msgs))
+		}
+		errs[i] = err
+	}
+
+	ensureFn := msgs[0].ensureFn
+	log := msgs[0].log
+	for start := 0; start < len(msgs) && !failed; {
+		var (
+			n        int
+			tooLarge bool
+		)
+		err := c.sendWithRetry(context.Background(), topic, ensureFn, log, "batch of Service Bus messages", func(ctx context.Context, sender *servicebus.Sender) error {
+			batch, err := sender.NewMessageBatch(ctx, nil)
+			if err != nil {
+				return err
+			}
+			n = 0
+			for _, m := range msgs[start:] {
+				err = batch.AddMessage(m.msg, nil)
+				if errors.Is(err, servicebus.ErrMessageTooLarge) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				n++
+			}
+			if n == 0 {
+				// The first message doesn't fit in a batch on its own
+				tooLarge = true
+				return nil
+			}
+			return sender.SendMessageBatch(ctx, batch, nil)
+		})
+
+		switch {
+		case err != nil:
+			// We can't tell which messages were sent, so all remaining messages are reported as failed
+			for i := start; i < len(msgs); i++ {
+				setErr(i, err)
+			}
+			failed = true
+		case tooLarge:
+			setErr(start, servicebus.ErrMessageTooLarge)
+			start++
+		default:
+			start += n
+		}
+	}
+
+	return errs
+}
+
 // PublishPubSubBulk is used by PubSub components to publush bulk messages.
 func (c *Client) PublishPubSubBulk(ctx context.Context, req *pubsub.BulkPublishRequest, ensureFn ensureFn, log logger.Logger) (pubsub.BulkPublishResponse, error) {
 	// If the request is empty, sender.SendMessageBatch will panic later.
@@ -99,6 +218,33 @@ func (c *Client) PublishPubSubBulk(ctx context.Context, req *pubsub.BulkPublishR
 		return pubsub.BulkPublishResponse{}, nil
 	}
 
+	encrypter, err := c.encryption.Get()
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(req.Entries, err), err
+	}
+	encrypted, err := encryptBulkPublishRequest(ctx, encrypter, req)
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(req.Entries, err), err
+	}
+	claimCheck, err := c.claimCheck.Get()
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(req.Entries, err), err
+	}
+	offloaded, err := offloadBulkPublishRequest(ctx, claimCheck, encrypted, log)
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(req.Entries, err), err
+	}
+
+	res, err := c.publishPubSubBulk(ctx, offloaded, ensureFn)
+	if err != nil {
+		// Azure Service Bus does not return individual status for each message, so none of them was published
+		discardBulkPublishRequest(claimCheck, offloaded, log)
+		return pubsub.NewBulkPublishResponse(req.Entries, err), err
+	}
+	return res, nil
+}
+
+func (c *Client) publishPubSubBulk(ctx context.Context, req *pubsub.BulkPublishRequest, ensureFn ensureFn) (pubsub.BulkPublishResponse, error) {
 	// Get the sender
 	sender, err := c.GetSender(ctx, req.Topic, ensureFn)
 	if err != nil {
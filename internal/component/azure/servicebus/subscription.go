@@ -25,6 +25,8 @@ import (
 	"go.uber.org/multierr"
 	"go.uber.org/ratelimit"
 
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/internal/component/encryption"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
 	"github.com/dapr/kit/retry"
@@ -64,6 +66,8 @@ type Subscription struct {
 	maxBulkSubCount      int
 	retriableErrLimiter  ratelimit.Limiter
 	handleChan           chan struct{}
+	claimCheck           *claimcheck.Store
+	encrypter            *encryption.Encrypter
 	logger               logger.Logger
 }
 
@@ -77,6 +81,12 @@ type SubscriptionOptions struct {
 	LockRenewalInSec      int
 	RequireSessions       bool
 	SessionIdleTimeout    time.Duration
+	// If set together with MaxConcurrentHandlers, limits the number of messages that are received while all handlers are busy
+	PrefetchCount *int
+	// If set, payloads offloaded to the claim-check store are loaded before invoking the handler, and deleted after the message is completed
+	ClaimCheck *claimcheck.Store
+	// If set, encrypted payloads are decrypted before invoking the handler; encrypted messages are never delivered if it's not set
+	Encrypter *encryption.Encrypter
 }
 
 // NewBulkSubscription returns a new Subscription object.
@@ -97,6 +107,15 @@ func NewSubscription(opts SubscriptionOptions, logger logger.Logger) *Subscripti
 		opts.MaxBulkSubCount = &opts.MaxActiveMessages
 	}
 
+	maxActiveOperations := opts.MaxActiveMessages / (*opts.MaxBulkSubCount)
+	if opts.PrefetchCount != nil && opts.MaxConcurrentHandlers > 0 {
+		// Messages are received only while there are fewer than PrefetchCount waiting for a handler, so their locks don't expire before they are handled
+		limit := opts.MaxConcurrentHandlers + (*opts.PrefetchCount+*opts.MaxBulkSubCount-1)/(*opts.MaxBulkSubCount)
+		if limit < maxActiveOperations {
+			maxActiveOperations = limit
+		}
+	}
+
 	s := &Subscription{
 		entity:              opts.Entity,
 		activeMessages:      make(map[int64]*azservicebus.ReceivedMessage),
@@ -105,10 +124,12 @@ func NewSubscription(opts SubscriptionOptions, logger logger.Logger) *Subscripti
 		sessionIdleTimeout:  opts.SessionIdleTimeout,
 		maxBulkSubCount:     *opts.MaxBulkSubCount,
 		requireSessions:     opts.RequireSessions,
+		claimCheck:          opts.ClaimCheck,
+		encrypter:           opts.Encrypter,
 		logger:              logger,
 		// This is a pessimistic estimate of the number of total operations that can be active at any given time.
 		// In case of a non-bulk subscription, one operation is one message.
-		activeOperationsChan: make(chan struct{}, opts.MaxActiveMessages/(*opts.MaxBulkSubCount)),
+		activeOperationsChan: make(chan struct{}, maxActiveOperations),
 	}
 
 	if opts.MaxRetriableEPS > 0 {
@@ -444,6 +465,32 @@ func (s *Subscription) handleAsync(ctx context.Context, msgs []*azservicebus.Rec
 		}
 	}
 
+	// Load the payloads offloaded to the claim-check store; if that fails, the messages are abandoned so they can be retried
+	err := s.rehydrateMessages(ctx, msgs)
+	if err != nil {
+		consumeToken = true
+		s.logger.Errorf("Failed to load offloaded payload for messages on %s: %s", s.entity, err)
+		finalizeCtx, finalizeCancel := context.WithTimeout(context.Background(), s.timeout)
+		for _, msg := range msgs {
+			s.AbandonMessage(finalizeCtx, receiver, msg)
+		}
+		finalizeCancel()
+		return
+	}
+
+	// Decrypt the payloads; messages that can't be decrypted are abandoned, and moved to the dead-letter queue once they reach the maximum delivery count
+	err = s.decryptMessages(ctx, msgs)
+	if err != nil {
+		consumeToken = true
+		s.logger.Errorf("Failed to decrypt messages on %s: %s", s.entity, err)
+		finalizeCtx, finalizeCancel := context.WithTimeout(context.Background(), s.timeout)
+		for _, msg := range msgs {
+			s.AbandonMessage(finalizeCtx, receiver, msg)
+		}
+		finalizeCancel()
+		return
+	}
+
 	// Invoke the handler to process the message.
 	resps, err := handler(ctx, msgs)
 	if err != nil {
@@ -543,7 +590,11 @@ func (s *Subscription) CompleteMessage(ctx context.Context, receiver Receiver, m
 	if err != nil {
 		// Log only
 		s.logger.Warnf("Error completing message %s on %s: %s", m.MessageID, s.entity, err.Error())
+		return
 	}
+
+	// The payload is deleted only once the message can't be redelivered
+	s.releaseClaimCheck(ctx, m)
 }
 
 func (s *Subscription) addActiveMessage(m *azservicebus.ReceivedMessage) error {
@@ -91,3 +91,39 @@ func TestNewSubscription(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSubscriptionPrefetchCount(t *testing.T) {
+	testcases := []struct {
+		name                            string
+		maxBulkSubCount                 *int
+		maxConcurrentHandlers           int
+		prefetchCount                   *int
+		activeOperationsChanCapExpected int
+	}{
+		{"prefetchCount not set", nil, 2, nil, 1000},
+		{"no prefetching", nil, 2, ptr.Of(0), 2},
+		{"prefetchCount is positive", nil, 2, ptr.Of(10), 12},
+		{"prefetchCount with bulk subscriptions", ptr.Of(10), 2, ptr.Of(15), 4},
+		{"prefetchCount greater than maxActiveMessages", nil, 2, ptr.Of(5000), 1000},
+		{"unlimited handlers", nil, 0, ptr.Of(10), 1000},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub := NewSubscription(
+				SubscriptionOptions{
+					MaxActiveMessages:     1000,
+					TimeoutInSec:          1,
+					MaxBulkSubCount:       tc.maxBulkSubCount,
+					MaxConcurrentHandlers: tc.maxConcurrentHandlers,
+					PrefetchCount:         tc.prefetchCount,
+					Entity:                "test",
+					LockRenewalInSec:      30,
+				},
+				logger.NewLogger("test"),
+			)
+			if cap(sub.activeOperationsChan) != tc.activeOperationsChanCapExpected {
+				t.Errorf("Expected capacity of sub.activeOperationsChan to be %d but got %d", tc.activeOperationsChanCapExpected, cap(sub.activeOperationsChan))
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package batching coalesces messages published individually into batches, which pub/sub components send with a single request.
+//
+// A message is added to the pending batch of its topic, which is sent when it reaches the maximum number of messages or bytes, or when the maximum delay since its first message elapses.
+// Publishers wait until the batch containing their message is sent, so they receive the result of the send as if the message was published individually.
+package batching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/utils"
+)
+
+const (
+	// SkipBatchingKey is the name of the metadata property of a publish request that, when true, sends the message immediately.
+	SkipBatchingKey = "skipBatching"
+
+	// DefaultMaxMessages is the default maximum number of messages in a batch.
+	DefaultMaxMessages = 100
+	// DefaultMaxBytes is the default maximum size of the messages in a batch.
+	DefaultMaxBytes = 1024 * 1024
+)
+
+// ErrClosed is returned when adding messages to a batcher that was closed.
+var ErrClosed = errors.New("batcher is closed")
+
+// Settings contains the metadata properties used to configure batching of published messages.
+// It's meant to be embedded (with "squash") in the metadata struct of the component.
+type Settings struct {
+	// Maximum time a message waits for other messages to be batched with. If 0, batching is disabled.
+	PublishBatchMaxDelay time.Duration `mapstructure:"publishBatchMaxDelay"`
+	// Maximum number of messages in a batch.
+	PublishBatchMaxMessages int `mapstructure:"publishBatchMaxMessages"`
+	// Maximum total size of the messages in a batch, in bytes. A message larger than this is sent in a batch of its own.
+	PublishBatchMaxBytes int `mapstructure:"publishBatchMaxBytes"`
+}
+
+// Enabled returns true if batching is enabled.
+func (m Settings) Enabled() bool {
+	return m.PublishBatchMaxDelay > 0
+}
+
+// Validate returns an error if the metadata is invalid, and sets the default values.
+func (m *Settings) Validate() error {
+	if m.PublishBatchMaxDelay < 0 {
+		return errors.New("invalid value for 'publishBatchMaxDelay': must not be negative")
+	}
+	if m.PublishBatchMaxMessages < 0 {
+		return errors.New("invalid value for 'publishBatchMaxMessages': must not be negative")
+	}
+	if m.PublishBatchMaxBytes < 0 {
+		return errors.New("invalid value for 'publishBatchMaxBytes': must not be negative")
+	}
+	if m.PublishBatchMaxMessages == 0 {
+		m.PublishBatchMaxMessages = DefaultMaxMessages
+	}
+	if m.PublishBatchMaxBytes == 0 {
+		m.PublishBatchMaxBytes = DefaultMaxBytes
+	}
+	return nil
+}
+
+// SkipBatching returns true if the publish request metadata asks for the message to be sent immediately.
+// Components should not send the property with the message.
+func SkipBatching(md map[string]string) bool {
+	return utils.IsTruthy(md[SkipBatchingKey])
+}
+
+// SendFn sends a batch of messages for a topic.
+// It returns nil if all messages were sent, or a slice with the error for each message (nil for messages that were sent).
+type SendFn[T any] func(topic string, msgs []T) []error
+
+// Batcher coalesces messages into batches.
+type Batcher[T any] struct {
+	settings Settings
+	send     SendFn[T]
+
+	lock    sync.Mutex
+	batches map[string]*batch[T]
+	// Completion channel of the last batch sent for each topic, so batches of a topic are sent in order
+	inflight map[string]chan struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+type batch[T any] struct {
+	msgs    []T
+	results []chan error
+	size    int
+	timer   *time.Timer
+}
+
+// New returns a new Batcher. The settings must have been validated.
+func New[T any](settings Settings, send SendFn[T]) *Batcher[T] {
+	return &Batcher[T]{
+		settings: settings,
+		send:     send,
+		batches:  map[string]*batch[T]{},
+		inflight: map[string]chan struct{}{},
+	}
+}
+
+// Publish adds a message of the given size to the pending batch of the topic, and waits until the batch is sent.
+// If ctx is canceled before the batch is sent, Publish returns the context's error, but the message may still be sent.
+func (b *Batcher[T]) Publish(ctx context.Context, topic string, msg T, size int) error {
+	result := make(chan error, 1)
+
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return ErrClosed
+	}
+
+	cur := b.batches[topic]
+	// If the message doesn't fit in the pending batch, send that first
+	if cur != nil && cur.size+size > b.settings.PublishBatchMaxBytes {
+		b.sendLocked(topic, cur)
+		cur = nil
+	}
+	if cur == nil {
+		cur = &batch[T]{}
+		b.batches[topic] = cur
+		cur.timer = time.AfterFunc(b.settings.PublishBatchMaxDelay, func() {
+			b.lock.Lock()
+			defer b.lock.Unlock()
+			if b.batches[topic] == cur {
+				b.sendLocked(topic, cur)
+			}
+		})
+	}
+
+	cur.msgs = append(cur.msgs, msg)
+	cur.results = append(cur.results, result)
+	cur.size += size
+	if len(cur.msgs) >= b.settings.PublishBatchMaxMessages || cur.size >= b.settings.PublishBatchMaxBytes {
+		b.sendLocked(topic, cur)
+	}
+	b.lock.Unlock()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendLocked removes the batch from the pending ones and sends it in background, after the previous batch of the topic.
+// It must be called while holding the lock.
+func (b *Batcher[T]) sendLocked(topic string, cur *batch[T]) {
+	cur.timer.Stop()
+	delete(b.batches, topic)
+
+	prev := b.inflight[topic]
+	done := make(chan struct{})
+	b.inflight[topic] = done
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() {
+			close(done)
+			b.lock.Lock()
+			if b.inflight[topic] == done {
+				delete(b.inflight, topic)
+			}
+			b.lock.Unlock()
+		}()
+
+		if prev != nil {
+			<-prev
+		}
+		errs := b.send(topic, cur.msgs)
+		for i, ch := range cur.results {
+			if errs == nil {
+				ch <- nil
+			} else if i < len(errs) {
+				ch <- errs[i]
+			} else {
+				ch <- fmt.Errorf("no result for message %d in the batch", i)
+			}
+		}
+	}()
+}
+
+// Close sends all pending batches and waits until they're sent.
+// After Close returns, messages can't be added anymore.
+func (b *Batcher[T]) Close() {
+	b.lock.Lock()
+	b.closed = true
+	for topic, cur := range b.batches {
+		b.sendLocked(topic, cur)
+	}
+	b.lock.Unlock()
+
+	b.wg.Wait()
+}
+
+// ErrorForAll returns a slice where every message in a batch of n messages failed with err, or nil if err is nil.
+func ErrorForAll(err error, n int) []error {
+	if err == nil {
+		return nil
+	}
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batching
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	lock    sync.Mutex
+	batches map[string][][]string
+	fail    map[string]error
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		batches: map[string][][]string{},
+		fail:    map[string]error{},
+	}
+}
+
+func (r *recorder) send(topic string, msgs []string) []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.batches[topic] = append(r.batches[topic], msgs)
+
+	var errs []error
+	for i, m := range msgs {
+		if err, ok := r.fail[m]; ok {
+			if errs == nil {
+				errs = make([]error, len(msgs))
+			}
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+func (r *recorder) get(topic string) [][]string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.batches[topic]
+}
+
+// publishAll publishes the messages concurrently and returns the error for each.
+func publishAll(b *Batcher[string], topic string, msgs ...string) []error {
+	errs := make([]error, len(msgs))
+	var wg sync.WaitGroup
+	wg.Add(len(msgs))
+	for i, m := range msgs {
+		go func(i int, m string) {
+			defer wg.Done()
+			errs[i] = b.Publish(context.Background(), topic, m, len(m))
+		}(i, m)
+		// Ensure messages are added in order
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s := Settings{}
+		require.NoError(t, s.Validate())
+		assert.False(t, s.Enabled())
+		assert.Equal(t, DefaultMaxMessages, s.PublishBatchMaxMessages)
+		assert.Equal(t, DefaultMaxBytes, s.PublishBatchMaxBytes)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		s := Settings{PublishBatchMaxDelay: time.Millisecond, PublishBatchMaxMessages: 5}
+		require.NoError(t, s.Validate())
+		assert.True(t, s.Enabled())
+		assert.Equal(t, 5, s.PublishBatchMaxMessages)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []Settings{
+			{PublishBatchMaxDelay: -1},
+			{PublishBatchMaxMessages: -1},
+			{PublishBatchMaxBytes: -1},
+		} {
+			require.Error(t, s.Validate())
+		}
+	})
+}
+
+func TestSkipBatching(t *testing.T) {
+	assert.False(t, SkipBatching(nil))
+	assert.False(t, SkipBatching(map[string]string{SkipBatchingKey: "false"}))
+	assert.True(t, SkipBatching(map[string]string{SkipBatchingKey: "true"}))
+}
+
+func TestBatcher(t *testing.T) {
+	t.Run("batch is sent when full", func(t *testing.T) {
+		r := newRecorder()
+		b := New(Settings{PublishBatchMaxDelay: time.Hour, PublishBatchMaxMessages: 3, PublishBatchMaxBytes: DefaultMaxBytes}, r.send)
+		defer b.Close()
+
+		errs := publishAll(b, "t1", "a", "b", "c")
+		assert.Equal(t, []error{nil, nil, nil}, errs)
+		assert.Equal(t, [][]string{{"a", "b", "c"}}, r.get("t1"))
+	})
+
+	t.Run("batch is sent after the max delay", func(t *testing.T) {
+		r := newRecorder()
+		b := New(Settings{PublishBatchMaxDelay: 50 * time.Millisecond, PublishBatchMaxMessages: 100, PublishBatchMaxBytes: DefaultMaxBytes}, r.send)
+		defer b.Close()
+
+		start := time.Now()
+		errs := publishAll(b, "t1", "a", "b")
+		assert.Equal(t, []error{nil, nil}, errs)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, [][]string{{"a", "b"}}, r.get("t1"))
+	})
+
+	t.Run("batches are per topic", func(t *testing.T) {
+		r := newRecorder()
+		b := New(Settings{PublishBatchMaxDelay: 20 * time.Millisecond, PublishBatchMaxMessages: 100, PublishBatchMaxBytes: DefaultMaxBytes}, r.send)
+		defer b.Close()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			publishAll(b, "t1", "a")
+		}()
+		go func() {
+			defer wg.Done()
+			publishAll(b, "t2", "b")
+		}()
+		wg.Wait()
+		assert.Equal(t, [][]string{{"a"}}, r.get("t1"))
+		assert.Equal(t, [][]string{{"b"}}, r.get("t2"))
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		r := newRecorder()
+		b := New(Settings{PublishBatchMaxDelay: 50 * time.Millisecond, PublishBatchMaxMessages: 100, PublishBatchMaxBytes: 5}, r.send)
+		defer b.Close()
+
+		errs := publishAll(b, "t1", "aa", "bb", "cc", "dddddd")
+		assert.Equal(t, []error{nil, nil, nil, nil}, errs)
+		assert.Equal(t, [][]string{{"aa", "bb"}, {"cc"}, {"dddddd"}}, r.get("t1"))
+	})
+
+	t.Run("errors are returned to each publisher", func(t *testing.T) {
+		r := newRecorder()
+		failErr := errors.New("simulated")
+		r.fail["b"] = failErr
+		b := New(Settings{PublishBatchMaxDelay: time.Hour, PublishBatchMaxMessages: 2, PublishBatchMaxBytes: DefaultMaxBytes}, r.send)
+		defer b.Close()
+
+		errs := publishAll(b, "t1", "a", "b")
+		assert.NoError(t, errs[0])
+		assert.ErrorIs(t, errs[1], failErr)
+	})
+
+	t.Run("close flushes pending batches", func(t *testing.T) {
+		r := newRecorder()
+		b := New(Settings{PublishBatchMaxDelay: time.Hour, PublishBatchMaxMessages: 100, PublishBatchMaxBytes: DefaultMaxBytes}, r.send)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- b.Publish(context.Background(), "t1", "a", 1)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		assert.Empty(t, r.get("t1"))
+
+		b.Close()
+		assert.Equal(t, [][]string{{"a"}}, r.get("t1"))
+		require.NoError(t, <-errCh)
+
+		err := b.Publish(context.Background(), "t1", "b", 1)
+		require.ErrorIs(t, err, ErrClosed)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		r := newRecorder()
+		b := New(Settings{PublishBatchMaxDelay: time.Hour, PublishBatchMaxMessages: 100, PublishBatchMaxBytes: DefaultMaxBytes}, r.send)
+		defer b.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := b.Publish(ctx, "t1", "a", 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestErrorForAll(t *testing.T) {
+	assert.Nil(t, ErrorForAll(nil, 3))
+	err := errors.New("simulated")
+	assert.Equal(t, []error{err, err}, ErrorForAll(err, 2))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package blobmetadata contains helpers for the bindings of blob stores to pass the standard HTTP headers and the user-defined metadata of objects.
+package blobmetadata
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ContentTypeKey        = "contentType"
+	ContentEncodingKey    = "contentEncoding"
+	ContentLanguageKey    = "contentLanguage"
+	ContentDispositionKey = "contentDisposition"
+	CacheControlKey       = "cacheControl"
+
+	// UserMetadataPrefix is the prefix of the keys of request and response metadata containing user-defined object metadata.
+	// For example, "metadata.owner" sets the "owner" metadata, which is stored as "x-amz-meta-owner" in S3 and "x-goog-meta-owner" in Cloud Storage.
+	UserMetadataPrefix = "metadata."
+
+	// IncludeMetadataKey is the key of request metadata that, when true, makes get operations return the headers and user-defined metadata of objects.
+	IncludeMetadataKey = "includeMetadata"
+)
+
+// Headers are the standard HTTP headers stored with an object and returned when it's downloaded.
+type Headers struct {
+	ContentType        string
+	ContentEncoding    string
+	ContentLanguage    string
+	ContentDisposition string
+	CacheControl       string
+}
+
+// ParseRequest returns the headers and the user-defined metadata from the metadata of a request.
+// Keys of headers are matched case-insensitively, while user-defined metadata is read from keys with the UserMetadataPrefix.
+// An error is returned if the name of metadata or a value is not valid in an HTTP header.
+func ParseRequest(meta map[string]string) (Headers, map[string]string, error) {
+	var (
+		h    Headers
+		user map[string]string
+	)
+	for k, v := range meta {
+		var dst *string
+		switch strings.ToLower(k) {
+		case strings.ToLower(ContentTypeKey):
+			dst = &h.ContentType
+		case strings.ToLower(ContentEncodingKey):
+			dst = &h.ContentEncoding
+		case strings.ToLower(ContentLanguageKey):
+			dst = &h.ContentLanguage
+		case strings.ToLower(ContentDispositionKey):
+			dst = &h.ContentDisposition
+		case strings.ToLower(CacheControlKey):
+			dst = &h.CacheControl
+		}
+		if dst != nil {
+			err := ValidateValue(k, v)
+			if err != nil {
+				return h, nil, err
+			}
+			*dst = v
+			continue
+		}
+
+		if len(k) > len(UserMetadataPrefix) && strings.EqualFold(k[:len(UserMetadataPrefix)], UserMetadataPrefix) {
+			name := k[len(UserMetadataPrefix):]
+			err := ValidateName(name)
+			if err != nil {
+				return h, nil, err
+			}
+			err = ValidateValue(k, v)
+			if err != nil {
+				return h, nil, err
+			}
+			if user == nil {
+				user = make(map[string]string)
+			}
+			user[name] = v
+		}
+	}
+	return h, user, nil
+}
+
+// ResponseMetadata returns the metadata of a response containing the non-empty headers and the user-defined metadata, whose keys have the UserMetadataPrefix.
+func ResponseMetadata(h Headers, user map[string]string) map[string]string {
+	res := make(map[string]string, len(user)+5)
+	AddHeaders(res, h)
+	for k, v := range user {
+		res[UserMetadataPrefix+k] = v
+	}
+	return res
+}
+
+// AddHeaders adds the non-empty headers to the metadata of a response.
+func AddHeaders(res map[string]string, h Headers) {
+	for k, v := range map[string]string{
+		ContentTypeKey:        h.ContentType,
+		ContentEncodingKey:    h.ContentEncoding,
+		ContentLanguageKey:    h.ContentLanguage,
+		ContentDispositionKey: h.ContentDisposition,
+		CacheControlKey:       h.CacheControl,
+	} {
+		if v != "" {
+			res[k] = v
+		}
+	}
+}
+
+// ValidateName returns an error if the name of user-defined metadata is not a valid HTTP header name.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid object metadata name: must not be empty")
+	}
+	for i := 0; i < len(name); i++ {
+		if !isTokenChar(name[i]) {
+			return fmt.Errorf("invalid object metadata name '%s': contains a character not allowed in HTTP header names", name)
+		}
+	}
+	return nil
+}
+
+// ValidateValue returns an error if the value contains characters that are not allowed in HTTP headers, such as newlines.
+func ValidateValue(key string, value string) error {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return fmt.Errorf("invalid value for object metadata '%s': contains a control character", key)
+		}
+	}
+	return nil
+}
+
+// isTokenChar returns true if the character is allowed in tokens, such as header names, as per RFC 7230.
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+	}
+}
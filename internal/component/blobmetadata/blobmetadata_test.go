@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blobmetadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequest(t *testing.T) {
+	t.Run("headers and user metadata", func(t *testing.T) {
+		h, user, err := ParseRequest(map[string]string{
+			"key":                "my/object",
+			"ContentType":        "text/plain",
+			"contentencoding":    "gzip",
+			"contentLanguage":    "en",
+			"contentDisposition": `attachment; filename="a.txt"`,
+			"cacheControl":       "max-age=3600",
+			"metadata.owner":     "team-a",
+			"Metadata.X-Trace":   "abc",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Headers{
+			ContentType:        "text/plain",
+			ContentEncoding:    "gzip",
+			ContentLanguage:    "en",
+			ContentDisposition: `attachment; filename="a.txt"`,
+			CacheControl:       "max-age=3600",
+		}, h)
+		assert.Equal(t, map[string]string{
+			"owner":   "team-a",
+			"X-Trace": "abc",
+		}, user)
+	})
+
+	t.Run("no metadata", func(t *testing.T) {
+		h, user, err := ParseRequest(map[string]string{"key": "my/object"})
+		require.NoError(t, err)
+		assert.Equal(t, Headers{}, h)
+		assert.Nil(t, user)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := []map[string]string{
+			{"metadata.my owner": "a"},
+			{"metadata.owner:": "a"},
+			{"metadata.owner": "a\r\nX-Injected: b"},
+			{"cacheControl": "no-cache\n"},
+		}
+		for _, meta := range invalid {
+			_, _, err := ParseRequest(meta)
+			assert.Error(t, err, meta)
+		}
+	})
+}
+
+func TestResponseMetadata(t *testing.T) {
+	res := ResponseMetadata(Headers{
+		ContentType:  "text/plain",
+		CacheControl: "no-cache",
+	}, map[string]string{
+		"owner": "team-a",
+	})
+	assert.Equal(t, map[string]string{
+		"contentType":    "text/plain",
+		"cacheControl":   "no-cache",
+		"metadata.owner": "team-a",
+	}, res)
+
+	// Round-trip
+	h, user, err := ParseRequest(res)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", h.ContentType)
+	assert.Equal(t, "no-cache", h.CacheControl)
+	assert.Equal(t, map[string]string{"owner": "team-a"}, user)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blobmetadata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RangeKey is the key of request metadata with the range of bytes that get operations download, in the format of the HTTP Range header.
+	// For example, "bytes=0-1023" downloads the first 1024 bytes, and "bytes=1024-" downloads everything after them.
+	RangeKey = "range"
+
+	// ContentRangeKey is the key of response metadata with the range of bytes that was downloaded, in the format of the HTTP Content-Range header, such as "bytes 0-1023/4096".
+	ContentRangeKey = "contentRange"
+	// TotalSizeKey is the key of response metadata with the size in bytes of the whole object.
+	TotalSizeKey = "totalSize"
+)
+
+// Range is a range of bytes of an object.
+type Range struct {
+	// Offset of the first byte.
+	Offset int64
+	// Number of bytes; 0 means until the end of the object.
+	Count int64
+}
+
+// ParseRange parses a range in the format of the HTTP Range header: "bytes=start-end", where end is inclusive and optional.
+// Suffix ranges ("bytes=-N") and multiple ranges are not supported, as not all blob stores do.
+func ParseRange(val string) (Range, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(val), "bytes=")
+	if !ok {
+		return Range{}, fmt.Errorf("invalid range '%s': must be in the format 'bytes=start-end'", val)
+	}
+	startStr, endStr, ok := strings.Cut(spec, "-")
+	if !ok || startStr == "" {
+		return Range{}, fmt.Errorf("invalid range '%s': must be in the format 'bytes=start-end'", val)
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return Range{}, fmt.Errorf("invalid start of range '%s'", val)
+	}
+	if endStr == "" {
+		return Range{Offset: start}, nil
+	}
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < start {
+		return Range{}, fmt.Errorf("invalid end of range '%s'", val)
+	}
+	return Range{Offset: start, Count: end - start + 1}, nil
+}
+
+// String returns the range in the format of the HTTP Range header.
+func (r Range) String() string {
+	if r.Count == 0 {
+		return "bytes=" + strconv.FormatInt(r.Offset, 10) + "-"
+	}
+	return "bytes=" + strconv.FormatInt(r.Offset, 10) + "-" + strconv.FormatInt(r.Offset+r.Count-1, 10)
+}
+
+// TotalSize returns the size of the whole object from a Content-Range header, such as "bytes 0-1023/4096".
+// False is returned if the header doesn't contain the size.
+func TotalSize(contentRange string) (int64, bool) {
+	_, size, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// AddRange adds the range that was downloaded and the size of the whole object to the metadata of a response.
+func AddRange(res map[string]string, contentRange string) {
+	if contentRange == "" {
+		return
+	}
+	res[ContentRangeKey] = contentRange
+	if size, ok := TotalSize(contentRange); ok {
+		res[TotalSizeKey] = strconv.FormatInt(size, 10)
+	}
+}
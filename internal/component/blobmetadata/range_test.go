@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blobmetadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		for val, expect := range map[string]Range{
+			"bytes=0-1023":  {Offset: 0, Count: 1024},
+			"bytes=100-100": {Offset: 100, Count: 1},
+			"bytes=1024-":   {Offset: 1024},
+			" bytes=5-9 ":   {Offset: 5, Count: 5},
+		} {
+			r, err := ParseRange(val)
+			require.NoError(t, err, val)
+			assert.Equal(t, expect, r, val)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, val := range []string{
+			"",
+			"0-10",
+			"bytes=",
+			"bytes=-500",
+			"bytes=10-5",
+			"bytes=a-b",
+			"bytes=0-10,20-30",
+		} {
+			_, err := ParseRange(val)
+			assert.Error(t, err, val)
+		}
+	})
+
+	t.Run("string", func(t *testing.T) {
+		assert.Equal(t, "bytes=0-1023", Range{Count: 1024}.String())
+		assert.Equal(t, "bytes=1024-", Range{Offset: 1024}.String())
+	})
+}
+
+func TestAddRange(t *testing.T) {
+	res := map[string]string{}
+	AddRange(res, "bytes 0-1023/4096")
+	assert.Equal(t, map[string]string{
+		ContentRangeKey: "bytes 0-1023/4096",
+		TotalSizeKey:    "4096",
+	}, res)
+
+	res = map[string]string{}
+	AddRange(res, "bytes 0-1023/*")
+	assert.Equal(t, map[string]string{ContentRangeKey: "bytes 0-1023/*"}, res)
+
+	res = map[string]string{}
+	AddRange(res, "")
+	assert.Empty(t, res)
+}
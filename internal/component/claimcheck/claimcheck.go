@@ -0,0 +1,209 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package claimcheck implements the claim-check pattern for pub/sub components: payloads larger than a threshold are stored in a state store, and messages carry a reference to them instead.
+//
+// Components use the store in this order:
+//  1. When publishing, call Offload and publish the returned data and metadata. If publishing fails, call Discard so the payload isn't orphaned.
+//  2. When receiving, call Rehydrate to load the payload before invoking the handler.
+//  3. After the handler succeeded, call Release to delete the payload.
+//
+// Payloads that are never released (for example because the message expired) are deleted by the state store when their TTL expires.
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/internal/component/holder"
+	"github.com/dapr/components-contrib/state"
+)
+
+const (
+	// MetadataKey is the name of the metadata property (sent as header or application property) that contains the reference to an offloaded payload.
+	MetadataKey = "dapr-claim-check"
+
+	// DefaultThreshold is the default size, in bytes, above which payloads are offloaded.
+	DefaultThreshold = 192 << 10 // 192 KiB
+
+	// DefaultTTL is the default time offloaded payloads are retained for, if they're not released earlier.
+	DefaultTTL = 7 * 24 * time.Hour
+
+	keyPrefix = "claimcheck||"
+)
+
+// Metadata contains the properties used to configure the claim-check feature of a component.
+// It's meant to be embedded (with "squash") in the metadata struct of the component.
+type Metadata struct {
+	// Name of the state store used to hold offloaded payloads. If empty, the feature is disabled.
+	ClaimCheckStore string `mapstructure:"claimCheckStore"`
+	// Size, in bytes, above which payloads are offloaded.
+	ClaimCheckThreshold int `mapstructure:"claimCheckThreshold"`
+	// Time offloaded payloads are retained for if they're not released.
+	ClaimCheckTTL time.Duration `mapstructure:"claimCheckTTL"`
+	// If true, payloads are not deleted after the message is delivered, and are only removed when their TTL expires.
+	// This is required when the same messages are consumed by multiple subscribers.
+	ClaimCheckRetainAfterDelivery bool `mapstructure:"claimCheckRetainAfterDelivery"`
+}
+
+// Enabled returns true if a claim-check store is configured.
+func (m Metadata) Enabled() bool {
+	return m.ClaimCheckStore != ""
+}
+
+// Dependency returns the name of the claim-check store, for errors.
+func (m Metadata) Dependency() string {
+	return "claim-check store '" + m.ClaimCheckStore + "'"
+}
+
+// Store offloads large payloads to a state store.
+type Store struct {
+	store     state.Store
+	prefix    string
+	threshold int
+	ttl       string
+	retain    bool
+}
+
+// NewStore returns a new Store.
+// The prefix is added to the keys of all payloads, and should identify the component.
+func NewStore(store state.Store, prefix string, md Metadata) *Store {
+	threshold := md.ClaimCheckThreshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	ttl := md.ClaimCheckTTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		store:     store,
+		prefix:    keyPrefix + prefix + "||",
+		threshold: threshold,
+		ttl:       strconv.FormatInt(int64(ttl.Seconds()), 10),
+		retain:    md.ClaimCheckRetainAfterDelivery,
+	}
+}
+
+// Offload stores data in the state store if it's larger than the threshold, and returns the data and metadata to publish.
+// If data is offloaded, the returned data is empty and the returned metadata is a copy of metadata that contains the reference to the payload; otherwise, data and metadata are returned unchanged.
+// If the payload can't be stored, an error is returned and the message must not be published.
+func (s *Store) Offload(ctx context.Context, data []byte, metadata map[string]string) ([]byte, map[string]string, error) {
+	if len(data) <= s.threshold {
+		return data, metadata, nil
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate claim-check reference: %w", err)
+	}
+	ref := s.prefix + id.String()
+	err = s.store.Set(ctx, &state.SetRequest{
+		Key:   ref,
+		Value: data,
+		Metadata: map[string]string{
+			"ttlInSeconds": s.ttl,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to offload payload of %d bytes to the claim-check store: %w", len(data), err)
+	}
+
+	md := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md[MetadataKey] = ref
+	return []byte{}, md, nil
+}
+
+// Rehydrate returns the payload of a received message.
+// If metadata contains a claim-check reference, the payload is loaded from the state store; otherwise, data is returned unchanged.
+func (s *Store) Rehydrate(ctx context.Context, data []byte, metadata map[string]string) ([]byte, error) {
+	ref, err := reference(metadata)
+	if ref == "" || err != nil {
+		return data, err
+	}
+
+	res, err := s.store.Get(ctx, &state.GetRequest{
+		Key: ref,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payload '%s' from the claim-check store: %w", ref, err)
+	}
+	if res == nil || res.Data == nil {
+		return nil, fmt.Errorf("payload '%s' not found in the claim-check store; it may have expired", ref)
+	}
+	return res.Data, nil
+}
+
+// Release deletes the payload referenced by metadata after the message was delivered, unless payloads are retained after delivery.
+func (s *Store) Release(ctx context.Context, metadata map[string]string) error {
+	if s.retain {
+		return nil
+	}
+	return s.Discard(ctx, metadata)
+}
+
+// Discard deletes the payload referenced by metadata, for example because the message could not be published.
+func (s *Store) Discard(ctx context.Context, metadata map[string]string) error {
+	ref, err := reference(metadata)
+	if ref == "" || err != nil {
+		return err
+	}
+
+	err = s.store.Delete(ctx, &state.DeleteRequest{
+		Key: ref,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete payload '%s' from the claim-check store: %w", ref, err)
+	}
+	return nil
+}
+
+// reference returns the claim-check reference in metadata, or an empty string if there's none.
+// References that don't point to a payload stored by a Store are rejected, so messages can't be used to read arbitrary keys from the state store.
+func reference(metadata map[string]string) (string, error) {
+	ref := metadata[MetadataKey]
+	if ref == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(ref, keyPrefix) {
+		return "", fmt.Errorf("invalid claim-check reference '%s'", ref)
+	}
+	return ref, nil
+}
+
+// Holder holds the Store of a component, which is created when the runtime sets the claim-check store.
+type Holder struct {
+	holder.Holder[Metadata, Store]
+}
+
+// SetClaimCheckStore sets the state store used to hold offloaded payloads.
+func (h *Holder) SetClaimCheckStore(store state.Store) error {
+	if store == nil {
+		return errors.New("claim-check store is nil")
+	}
+	return h.Set(func(metadata Metadata, prefix string) (*Store, error) {
+		if !metadata.Enabled() {
+			return nil, errors.New("'claimCheckStore' is not set in the component metadata")
+		}
+		return NewStore(store, prefix, metadata), nil
+	})
+}
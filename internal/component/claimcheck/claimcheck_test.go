@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func newStateStore(t *testing.T) state.Store {
+	t.Helper()
+
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+	t.Cleanup(func() { store.(interface{ Close() error }).Close() })
+	return store
+}
+
+type failingStore struct {
+	state.Store
+}
+
+func (failingStore) Set(context.Context, *state.SetRequest) error {
+	return errors.New("simulated")
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	stateStore := newStateStore(t)
+	s := NewStore(stateStore, "kafka", Metadata{ClaimCheckThreshold: 10, ClaimCheckTTL: time.Hour})
+	assert.Equal(t, "3600", s.ttl)
+
+	t.Run("small payloads are not offloaded", func(t *testing.T) {
+		md := map[string]string{"a": "b"}
+		data, resMd, err := s.Offload(ctx, []byte("0123456789"), md)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("0123456789"), data)
+		assert.Equal(t, md, resMd)
+
+		data, err = s.Rehydrate(ctx, data, resMd)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("0123456789"), data)
+	})
+
+	t.Run("large payloads are offloaded and rehydrated", func(t *testing.T) {
+		payload := []byte(strings.Repeat("x", 100))
+		md := map[string]string{"a": "b"}
+		data, resMd, err := s.Offload(ctx, payload, md)
+		require.NoError(t, err)
+		assert.Empty(t, data)
+		assert.Equal(t, "b", resMd["a"])
+		assert.True(t, strings.HasPrefix(resMd[MetadataKey], "claimcheck||kafka||"))
+		assert.NotContains(t, md, MetadataKey, "metadata of the request must not be modified")
+
+		data, err = s.Rehydrate(ctx, data, resMd)
+		require.NoError(t, err)
+		assert.Equal(t, payload, data)
+
+		require.NoError(t, s.Release(ctx, resMd))
+		_, err = s.Rehydrate(ctx, nil, resMd)
+		require.ErrorContains(t, err, "not found in the claim-check store")
+	})
+
+	t.Run("payloads are retained after delivery if configured", func(t *testing.T) {
+		retaining := NewStore(stateStore, "kafka", Metadata{ClaimCheckThreshold: 10, ClaimCheckRetainAfterDelivery: true})
+		assert.Equal(t, "604800", retaining.ttl)
+
+		_, md, err := retaining.Offload(ctx, []byte(strings.Repeat("x", 100)), nil)
+		require.NoError(t, err)
+		require.NoError(t, retaining.Release(ctx, md))
+		_, err = retaining.Rehydrate(ctx, nil, md)
+		require.NoError(t, err)
+
+		require.NoError(t, retaining.Discard(ctx, md))
+		_, err = retaining.Rehydrate(ctx, nil, md)
+		require.Error(t, err)
+	})
+
+	t.Run("offload failures are returned", func(t *testing.T) {
+		failing := NewStore(failingStore{stateStore}, "kafka", Metadata{ClaimCheckThreshold: 10})
+		_, _, err := failing.Offload(ctx, []byte(strings.Repeat("x", 100)), nil)
+		require.ErrorContains(t, err, "simulated")
+	})
+
+	t.Run("references to other keys are rejected", func(t *testing.T) {
+		require.NoError(t, stateStore.Set(ctx, &state.SetRequest{Key: "secret", Value: []byte("x")}))
+		_, err := s.Rehydrate(ctx, nil, map[string]string{MetadataKey: "secret"})
+		require.ErrorContains(t, err, "invalid claim-check reference")
+		require.Error(t, s.Discard(ctx, map[string]string{MetadataKey: "secret"}))
+	})
+}
+
+func TestHolder(t *testing.T) {
+	h := &Holder{}
+	h.Init(Metadata{}, "prefix")
+	require.Error(t, h.SetClaimCheckStore(newStateStore(t)))
+
+	h.Init(Metadata{ClaimCheckStore: "statestore"}, "prefix")
+	require.Error(t, h.SetClaimCheckStore(nil))
+	require.NoError(t, h.SetClaimCheckStore(newStateStore(t)))
+	s, err := h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultThreshold, s.threshold)
+	assert.Equal(t, "claimcheck||prefix||", s.prefix)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deadletter persists poison messages, which could not be processed after all retries, in a state store, so they can be inspected.
+//
+// Components call Save when they give up on a message, and acknowledge the message (or commit its offset) only if Save succeeded.
+// Each message is stored as a JSON record under a key made of the prefix of the component, the topic and the ID of the message (such as its offset), so saving a message again after a crash overwrites the same record.
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/component/holder"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+const keyPrefix = "deadletter||"
+
+// Metadata contains the properties used to configure the dead-letter store of a component.
+// It's meant to be included (with "squash") in the metadata struct of the component.
+type Metadata struct {
+	// Name of the state store that poison messages are saved to. If empty, the feature is disabled.
+	DeadLetterStore string `mapstructure:"deadLetterStore"`
+	// Time poison messages are retained for. If 0, they're retained until they're deleted.
+	DeadLetterTTL time.Duration `mapstructure:"deadLetterTTL"`
+}
+
+// Enabled returns true if a dead-letter store is configured.
+func (m Metadata) Enabled() bool {
+	return m.DeadLetterStore != ""
+}
+
+// Dependency returns the name of the dead-letter store, for errors.
+func (m Metadata) Dependency() string {
+	return "dead-letter store '" + m.DeadLetterStore + "'"
+}
+
+// Message is a message that could not be processed.
+type Message struct {
+	// Topic (or stream, or queue) the message was received from
+	Topic string
+	// ID of the message in the topic, such as its offset
+	ID string
+	// Payload of the message, as received
+	Data []byte
+	// Metadata (headers or properties) of the message
+	Metadata map[string]string
+	// Error returned by the last attempt at processing the message
+	Error string
+}
+
+// Record is the value saved in the state store for a poison message.
+type Record struct {
+	Topic          string            `json:"topic"`
+	ID             string            `json:"id"`
+	Data           []byte            `json:"data"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	Error          string            `json:"error"`
+	DeadLetteredAt time.Time         `json:"deadLetteredAt"`
+}
+
+// Store saves poison messages in a state store.
+type Store struct {
+	store  state.Store
+	prefix string
+	ttl    string
+	now    func() time.Time
+}
+
+// NewStore returns a new Store.
+// The prefix is added to the keys of all records, and should identify the component and the consumer.
+func NewStore(store state.Store, prefix string, ttl time.Duration) *Store {
+	s := &Store{
+		store:  store,
+		prefix: keyPrefix + prefix + "||",
+		now:    time.Now,
+	}
+	if ttl > 0 {
+		s.ttl = strconv.FormatInt(int64(ttl.Seconds()), 10)
+	}
+	return s
+}
+
+// Key returns the key of the record of the message with the given topic and ID.
+func (s *Store) Key(topic string, id string) string {
+	return s.prefix + topic + "||" + id
+}
+
+// Save stores the message in the state store, and returns the key of its record.
+// If the message can't be stored, an error is returned and the message must not be acknowledged.
+func (s *Store) Save(ctx context.Context, msg Message) (string, error) {
+	data, err := json.Marshal(Record{
+		Topic:          msg.Topic,
+		ID:             msg.ID,
+		Data:           msg.Data,
+		Metadata:       msg.Metadata,
+		Error:          msg.Error,
+		DeadLetteredAt: s.now().UTC(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode poison message '%s' of topic '%s': %w", msg.ID, msg.Topic, err)
+	}
+
+	req := &state.SetRequest{
+		Key:         s.Key(msg.Topic, msg.ID),
+		Value:       data,
+		ContentType: ptr.Of("application/json"),
+	}
+	if s.ttl != "" {
+		req.Metadata = map[string]string{
+			"ttlInSeconds": s.ttl,
+		}
+	}
+	err = s.store.Set(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to save poison message '%s' of topic '%s' to the dead-letter store: %w", msg.ID, msg.Topic, err)
+	}
+	return req.Key, nil
+}
+
+// Holder holds the Store of a component, which is created when the runtime sets the dead-letter store.
+type Holder struct {
+	holder.Holder[Metadata, Store]
+}
+
+// SetDeadLetterStore sets the state store that poison messages are saved to.
+func (h *Holder) SetDeadLetterStore(store state.Store) error {
+	if store == nil {
+		return errors.New("dead-letter store is nil")
+	}
+	return h.Set(func(metadata Metadata, prefix string) (*Store, error) {
+		if !metadata.Enabled() {
+			return nil, errors.New("'deadLetterStore' is not set in the component metadata")
+		}
+		return NewStore(store, prefix, metadata.DeadLetterTTL), nil
+	})
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func newStateStore(t *testing.T) state.Store {
+	t.Helper()
+
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+	t.Cleanup(func() { store.(interface{ Close() error }).Close() })
+	return store
+}
+
+func TestStore(t *testing.T) {
+	stateStore := newStateStore(t)
+	s := NewStore(stateStore, "kafka||group1", time.Hour)
+	assert.Equal(t, "3600", s.ttl)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	key, err := s.Save(context.Background(), Message{
+		Topic:    "orders",
+		ID:       "0/42",
+		Data:     []byte("not json"),
+		Metadata: map[string]string{"h": "v"},
+		Error:    "handler failed",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "deadletter||kafka||group1||orders||0/42", key)
+	assert.Equal(t, key, s.Key("orders", "0/42"))
+
+	res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: key})
+	require.NoError(t, err)
+	var record Record
+	require.NoError(t, json.Unmarshal(res.Data, &record))
+	assert.Equal(t, Record{
+		Topic:          "orders",
+		ID:             "0/42",
+		Data:           []byte("not json"),
+		Metadata:       map[string]string{"h": "v"},
+		Error:          "handler failed",
+		DeadLetteredAt: now,
+	}, record)
+
+	// Without a TTL, records are retained
+	assert.Empty(t, NewStore(stateStore, "p", 0).ttl)
+}
+
+func TestHolder(t *testing.T) {
+	h := &Holder{}
+	h.Init(Metadata{}, "prefix")
+	require.Error(t, h.SetDeadLetterStore(newStateStore(t)))
+
+	h.Init(Metadata{DeadLetterStore: "statestore", DeadLetterTTL: time.Minute}, "prefix")
+	require.Error(t, h.SetDeadLetterStore(nil))
+	require.NoError(t, h.SetDeadLetterStore(newStateStore(t)))
+	s, err := h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "60", s.ttl)
+	assert.Equal(t, "deadletter||prefix||", s.prefix)
+}
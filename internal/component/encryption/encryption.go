@@ -0,0 +1,248 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package encryption encrypts the payloads of pub/sub messages end-to-end with a crypto component, so they can only be read by subscribers that have access to the key.
+//
+// Payloads are encrypted with envelope encryption: each message is encrypted with a new AES-256-GCM data key, which is wrapped with the key in the crypto component.
+// The wrapped data key, the name of the key that wrapped it and the algorithm are sent in the metadata (headers or application properties) of the message.
+//
+// Components call Encrypt before publishing a message, and Decrypt after receiving it and before invoking the handler.
+// Messages that can't be decrypted must not be delivered, including unencrypted messages, unless "cryptoAllowUnencrypted" is set while publishers are migrated.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/internal/component/holder"
+)
+
+const (
+	// KeyNameMetadataKey is the name of the metadata property that contains the name of the key that wrapped the data key of the message.
+	KeyNameMetadataKey = "dapr-encryption-key"
+	// AlgorithmMetadataKey is the name of the metadata property that contains the algorithm used to wrap the data key.
+	AlgorithmMetadataKey = "dapr-encryption-alg"
+	// WrappedKeyMetadataKey is the name of the metadata property that contains the wrapped data key, encoded as base64.
+	WrappedKeyMetadataKey = "dapr-encryption-wrapped-key"
+	// TagMetadataKey is the name of the metadata property that contains the authentication tag of the wrapped data key, encoded as base64, for algorithms that return one.
+	TagMetadataKey = "dapr-encryption-wrapped-key-tag"
+
+	// DefaultKeyWrapAlgorithm is the default algorithm used to wrap data keys.
+	DefaultKeyWrapAlgorithm = "A256KW"
+
+	dataKeySize = 32
+)
+
+// ErrDecrypt is returned by Decrypt for messages that can't be decrypted.
+var ErrDecrypt = errors.New("failed to decrypt message")
+
+// Metadata contains the properties used to configure the encryption of payloads of a component.
+// It's meant to be included (with "squash") in the metadata struct of the component.
+type Metadata struct {
+	// Name of the crypto component used to wrap data keys. If empty, the feature is disabled.
+	CryptoComponent string `mapstructure:"cryptoComponent"`
+	// Name of the key, in the crypto component, used to wrap data keys when publishing.
+	CryptoKeyName string `mapstructure:"cryptoKeyName"`
+	// Algorithm used to wrap data keys.
+	CryptoKeyWrapAlgorithm string `mapstructure:"cryptoKeyWrapAlgorithm"`
+	// If true, unencrypted messages are delivered, so encryption can be enabled before all publishers encrypt their messages.
+	CryptoAllowUnencrypted bool `mapstructure:"cryptoAllowUnencrypted"`
+}
+
+// Enabled returns true if a crypto component is configured.
+func (m Metadata) Enabled() bool {
+	return m.CryptoComponent != ""
+}
+
+// Dependency returns the name of the crypto component, for errors.
+func (m Metadata) Dependency() string {
+	return "crypto component '" + m.CryptoComponent + "'"
+}
+
+// Validate returns an error if the metadata is not valid.
+func (m Metadata) Validate() error {
+	if m.Enabled() && m.CryptoKeyName == "" {
+		return errors.New("metadata property 'cryptoKeyName' is required when 'cryptoComponent' is set")
+	}
+	return nil
+}
+
+// IsEncrypted returns true if the metadata of a received message shows that its payload is encrypted.
+func IsEncrypted(metadata map[string]string) bool {
+	return metadata[WrappedKeyMetadataKey] != ""
+}
+
+// Encrypter encrypts and decrypts payloads with a crypto component.
+// A nil Encrypter publishes payloads unencrypted, and fails to decrypt encrypted messages.
+type Encrypter struct {
+	provider         crypto.SubtleCrypto
+	keyName          string
+	algorithm        string
+	allowUnencrypted bool
+}
+
+// NewEncrypter returns a new Encrypter.
+func NewEncrypter(provider crypto.SubtleCrypto, md Metadata) *Encrypter {
+	algorithm := md.CryptoKeyWrapAlgorithm
+	if algorithm == "" {
+		algorithm = DefaultKeyWrapAlgorithm
+	}
+	return &Encrypter{
+		provider:         provider,
+		keyName:          md.CryptoKeyName,
+		algorithm:        algorithm,
+		allowUnencrypted: md.CryptoAllowUnencrypted,
+	}
+}
+
+// Encrypt encrypts data, and returns the ciphertext and a copy of metadata that contains the properties required to decrypt it.
+// If the Encrypter is nil, data and metadata are returned unchanged.
+func (e *Encrypter) Encrypt(ctx context.Context, data []byte, metadata map[string]string) ([]byte, map[string]string, error) {
+	if e == nil {
+		return data, metadata, nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	_, err := io.ReadFull(rand.Reader, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	jwkKey, err := jwk.FromRaw(dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, tag, err := e.provider.WrapKey(ctx, jwkKey, e.algorithm, e.keyName, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key with key '%s': %w", e.keyName, err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The nonce is prepended to the ciphertext; it can be random as each data key encrypts a single message
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(data)+gcm.Overhead())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := gcm.Seal(nonce, nonce, data, nil)
+
+	md := make(map[string]string, len(metadata)+4)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md[KeyNameMetadataKey] = e.keyName
+	md[AlgorithmMetadataKey] = e.algorithm
+	md[WrappedKeyMetadataKey] = base64.StdEncoding.EncodeToString(wrapped)
+	if len(tag) > 0 {
+		md[TagMetadataKey] = base64.StdEncoding.EncodeToString(tag)
+	}
+	return ciphertext, md, nil
+}
+
+// Decrypt returns the plaintext payload of a received message.
+// It returns an error wrapping ErrDecrypt if the message can't be decrypted: if it's encrypted and the Encrypter is nil or doesn't have access to the key, or if it's not encrypted and the Encrypter isn't nil.
+// Unencrypted messages are returned unchanged if the Encrypter is nil, or if it allows unencrypted messages.
+func (e *Encrypter) Decrypt(ctx context.Context, data []byte, metadata map[string]string) ([]byte, error) {
+	if !IsEncrypted(metadata) {
+		if e != nil && !e.allowUnencrypted {
+			return nil, fmt.Errorf("%w: the message is not encrypted", ErrDecrypt)
+		}
+		return data, nil
+	}
+	if e == nil {
+		return nil, fmt.Errorf("%w: the message is encrypted, but no crypto component is configured", ErrDecrypt)
+	}
+
+	keyName := metadata[KeyNameMetadataKey]
+	algorithm := metadata[AlgorithmMetadataKey]
+	if keyName == "" || algorithm == "" {
+		return nil, fmt.Errorf("%w: missing '%s' or '%s' metadata", ErrDecrypt, KeyNameMetadataKey, AlgorithmMetadataKey)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[WrappedKeyMetadataKey])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid wrapped key: %v", ErrDecrypt, err)
+	}
+	var tag []byte
+	if val := metadata[TagMetadataKey]; val != "" {
+		tag, err = base64.StdEncoding.DecodeString(val)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid wrapped key tag: %v", ErrDecrypt, err)
+		}
+	}
+
+	jwkKey, err := e.provider.UnwrapKey(ctx, wrapped, algorithm, keyName, nil, tag, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to unwrap data key with key '%s': %v", ErrDecrypt, keyName, err)
+	}
+	var dataKey []byte
+	err = jwkKey.Raw(&dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid data key: %v", ErrDecrypt, err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: payload is too short", ErrDecrypt)
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Holder holds the Encrypter of a component, which is created when the runtime sets the crypto component.
+// Encryption doesn't prefix keys, so the prefix of the embedded holder is unused.
+type Holder struct {
+	holder.Holder[Metadata, Encrypter]
+}
+
+// Init sets the metadata of the component.
+func (h *Holder) Init(metadata Metadata) {
+	h.Holder.Init(metadata, "")
+}
+
+// SetCryptoProvider sets the crypto component used to wrap data keys.
+func (h *Holder) SetCryptoProvider(provider crypto.SubtleCrypto) error {
+	if provider == nil {
+		return errors.New("crypto component is nil")
+	}
+	return h.Set(func(metadata Metadata, _ string) (*Encrypter, error) {
+		if !metadata.Enabled() {
+			return nil, errors.New("'cryptoComponent' is not set in the component metadata")
+		}
+		return NewEncrypter(provider, metadata), nil
+	})
+}
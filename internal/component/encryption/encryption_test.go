@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/crypto/localstorage"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func newProvider(t *testing.T, keys ...string) crypto.SubtleCrypto {
+	t.Helper()
+	dir := t.TempDir()
+	for i, name := range keys {
+		k := make([]byte, 32)
+		k[0] = byte(i + 1)
+		jwk := `{"kty":"oct","k":"` + base64.RawURLEncoding.EncodeToString(k) + `"}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(jwk), 0o600))
+	}
+	provider := localstorage.NewLocalStorageCrypto(logger.NewLogger("test"))
+	err := provider.Init(context.Background(), crypto.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"path": dir},
+	}})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestMetadata(t *testing.T) {
+	assert.False(t, Metadata{}.Enabled())
+	require.NoError(t, Metadata{}.Validate())
+	require.NoError(t, Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json"}.Validate())
+	require.Error(t, Metadata{CryptoComponent: "crypto"}.Validate())
+}
+
+func TestEncrypter(t *testing.T) {
+	ctx := context.Background()
+	provider := newProvider(t, "key.json", "other.json")
+	e := NewEncrypter(provider, Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json"})
+
+	t.Run("round trip", func(t *testing.T) {
+		in := map[string]string{"h": "v"}
+		ciphertext, md, err := e.Encrypt(ctx, []byte("hello world"), in)
+		require.NoError(t, err)
+		assert.NotContains(t, string(ciphertext), "hello world")
+		assert.True(t, IsEncrypted(md))
+		assert.Equal(t, "key.json", md[KeyNameMetadataKey])
+		assert.Equal(t, DefaultKeyWrapAlgorithm, md[AlgorithmMetadataKey])
+		assert.Equal(t, "v", md["h"])
+		// The metadata of the request is not modified
+		assert.Equal(t, map[string]string{"h": "v"}, in)
+
+		plaintext, err := e.Decrypt(ctx, ciphertext, md)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(plaintext))
+	})
+
+	t.Run("unencrypted message", func(t *testing.T) {
+		_, err := e.Decrypt(ctx, []byte("plain"), map[string]string{"h": "v"})
+		require.ErrorIs(t, err, ErrDecrypt)
+
+		var nilEncrypter *Encrypter
+		plaintext, err := nilEncrypter.Decrypt(ctx, []byte("plain"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "plain", string(plaintext))
+
+		allowing := NewEncrypter(provider, Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json", CryptoAllowUnencrypted: true})
+		plaintext, err = allowing.Decrypt(ctx, []byte("plain"), map[string]string{"h": "v"})
+		require.NoError(t, err)
+		assert.Equal(t, "plain", string(plaintext))
+	})
+
+	t.Run("nil encrypter publishes unencrypted", func(t *testing.T) {
+		var nilEncrypter *Encrypter
+		data, md, err := nilEncrypter.Encrypt(ctx, []byte("plain"), map[string]string{"h": "v"})
+		require.NoError(t, err)
+		assert.Equal(t, "plain", string(data))
+		assert.Equal(t, map[string]string{"h": "v"}, md)
+	})
+
+	t.Run("no crypto component", func(t *testing.T) {
+		ciphertext, md, err := e.Encrypt(ctx, []byte("secret"), nil)
+		require.NoError(t, err)
+		var nilEncrypter *Encrypter
+		_, err = nilEncrypter.Decrypt(ctx, ciphertext, md)
+		require.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		ciphertext, md, err := e.Encrypt(ctx, []byte("secret"), nil)
+		require.NoError(t, err)
+		md[KeyNameMetadataKey] = "other.json"
+		_, err = e.Decrypt(ctx, ciphertext, md)
+		require.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		ciphertext, md, err := e.Encrypt(ctx, []byte("secret"), nil)
+		require.NoError(t, err)
+		ciphertext[len(ciphertext)-1] ^= 0xff
+		_, err = e.Decrypt(ctx, ciphertext, md)
+		require.ErrorIs(t, err, ErrDecrypt)
+	})
+}
+
+func TestHolder(t *testing.T) {
+	provider := newProvider(t, "key.json")
+
+	h := &Holder{}
+	h.Init(Metadata{})
+	require.Error(t, h.SetCryptoProvider(provider))
+
+	h.Init(Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json"})
+	require.Error(t, h.SetCryptoProvider(nil))
+	require.NoError(t, h.SetCryptoProvider(provider))
+	e, err := h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "key.json", e.keyName)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package holder contains Holder, which holds an object that a component creates from another component, such as a state store, that the runtime sets after the component is initialized.
+package holder
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Metadata is the metadata of the feature of a component that uses the held object.
+type Metadata interface {
+	// Enabled returns true if the feature is configured.
+	Enabled() bool
+	// Dependency describes the component the object is created from, such as "claim-check store 'mystore'".
+	Dependency() string
+}
+
+// Holder holds an object of type T, which is created from the metadata M, the prefix of the component, and another component.
+// The zero value is ready to use, with the feature disabled.
+type Holder[M Metadata, T any] struct {
+	metadata M
+	prefix   string
+	value    *T
+	lock     sync.RWMutex
+}
+
+// Init sets the metadata and the prefix, discarding the object.
+func (h *Holder[M, T]) Init(metadata M, prefix string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.metadata = metadata
+	h.prefix = prefix
+	h.value = nil
+}
+
+// Metadata returns the metadata set with Init.
+func (h *Holder[M, T]) Metadata() M {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.metadata
+}
+
+// Set replaces the object with the one returned by create, which is called with the metadata and the prefix.
+// If create returns an error, the object is not changed.
+func (h *Holder[M, T]) Set(create func(metadata M, prefix string) (*T, error)) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	value, err := create(h.metadata, h.prefix)
+	if err != nil {
+		return err
+	}
+	h.value = value
+	return nil
+}
+
+// Get returns the object, or nil if the feature is disabled.
+// It returns an error if the feature is enabled but the object was not set.
+func (h *Holder[M, T]) Get() (*T, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if !h.metadata.Enabled() {
+		return nil, nil
+	}
+	if h.value == nil {
+		return nil, fmt.Errorf("%s is configured, but it was not set", h.metadata.Dependency())
+	}
+	return h.value, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package holder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMetadata struct {
+	Store string
+}
+
+func (m testMetadata) Enabled() bool {
+	return m.Store != ""
+}
+
+func (m testMetadata) Dependency() string {
+	return "test store '" + m.Store + "'"
+}
+
+func TestHolder(t *testing.T) {
+	create := func(m testMetadata, prefix string) (*string, error) {
+		v := prefix + "||" + m.Store
+		return &v, nil
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var h Holder[testMetadata, string]
+		v, err := h.Get()
+		require.NoError(t, err)
+		assert.Nil(t, v)
+	})
+
+	t.Run("enabled but not set", func(t *testing.T) {
+		var h Holder[testMetadata, string]
+		h.Init(testMetadata{Store: "statestore"}, "prefix")
+		_, err := h.Get()
+		require.EqualError(t, err, "test store 'statestore' is configured, but it was not set")
+	})
+
+	t.Run("set", func(t *testing.T) {
+		var h Holder[testMetadata, string]
+		h.Init(testMetadata{Store: "statestore"}, "prefix")
+		require.NoError(t, h.Set(create))
+		v, err := h.Get()
+		require.NoError(t, err)
+		assert.Equal(t, "prefix||statestore", *v)
+		assert.Equal(t, "statestore", h.Metadata().Store)
+
+		// A failed Set keeps the object, and Init discards it
+		require.Error(t, h.Set(func(testMetadata, string) (*string, error) {
+			return nil, errors.New("invalid store")
+		}))
+		v, err = h.Get()
+		require.NoError(t, err)
+		assert.Equal(t, "prefix||statestore", *v)
+
+		h.Init(testMetadata{Store: "other"}, "prefix")
+		_, err = h.Get()
+		require.Error(t, err)
+	})
+}
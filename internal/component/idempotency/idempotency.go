@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package idempotency records the IDs of messages that were processed, in a state store, so pub/sub components can skip duplicate deliveries.
+//
+// To avoid losing messages, components must use the store in this order:
+//  1. Call IsProcessed before invoking the handler, and skip the handler if the message was already processed.
+//  2. Invoke the handler.
+//  3. If the handler succeeded, call MarkProcessed.
+//  4. Only then acknowledge the message (or commit its offset).
+//
+// If the process crashes after step 3 but before step 4, the message is redelivered and skipped; if it crashes before step 3, the message is processed again.
+// Messages are never acknowledged without being processed.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/component/holder"
+	"github.com/dapr/components-contrib/state"
+)
+
+const (
+	// DefaultTTL is the default time the ID of a processed message is retained for.
+	DefaultTTL = 24 * time.Hour
+
+	keyPrefix = "idempotency||"
+)
+
+// Metadata contains the properties used to configure the idempotency feature of a component.
+// It's meant to be embedded (with "squash") in the metadata struct of the component.
+type Metadata struct {
+	// Name of the state store used to record the IDs of processed messages. If empty, the feature is disabled.
+	IdempotencyStore string `mapstructure:"idempotencyStore"`
+	// Time the IDs of processed messages are retained for. Duplicates delivered after this time are not detected.
+	IdempotencyTTL time.Duration `mapstructure:"idempotencyTTL"`
+}
+
+// Enabled returns true if an idempotency store is configured.
+func (m Metadata) Enabled() bool {
+	return m.IdempotencyStore != ""
+}
+
+// Dependency returns the name of the idempotency store, for errors.
+func (m Metadata) Dependency() string {
+	return "idempotency store '" + m.IdempotencyStore + "'"
+}
+
+// Store records the IDs of processed messages in a state store.
+type Store struct {
+	store  state.Store
+	prefix string
+	ttl    string
+}
+
+// NewStore returns a new Store.
+// The prefix is added to the keys of all records, and should identify the component and the consumer, so different consumers of the same messages don't skip each other's messages.
+func NewStore(store state.Store, prefix string, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		store:  store,
+		prefix: keyPrefix + prefix + "||",
+		ttl:    strconv.FormatInt(int64(ttl.Seconds()), 10),
+	}
+}
+
+// IsProcessed returns true if the message with the given ID was already processed.
+func (s *Store) IsProcessed(ctx context.Context, id string) (bool, error) {
+	res, err := s.store.Get(ctx, &state.GetRequest{
+		Key: s.prefix + id,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check if message '%s' was already processed: %w", id, err)
+	}
+	return res != nil && len(res.Data) > 0, nil
+}
+
+// MarkProcessed records that the message with the given ID was processed.
+func (s *Store) MarkProcessed(ctx context.Context, id string) error {
+	err := s.store.Set(ctx, &state.SetRequest{
+		Key:   s.prefix + id,
+		Value: []byte(`"1"`),
+		Metadata: map[string]string{
+			"ttlInSeconds": s.ttl,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record that message '%s' was processed: %w", id, err)
+	}
+	return nil
+}
+
+// Holder holds the Store of a component, which is created when the runtime sets the idempotency store.
+type Holder struct {
+	holder.Holder[Metadata, Store]
+}
+
+// SetIdempotencyStore sets the state store used to record the IDs of processed messages.
+func (h *Holder) SetIdempotencyStore(store state.Store) error {
+	if store == nil {
+		return errors.New("idempotency store is nil")
+	}
+	return h.Set(func(metadata Metadata, prefix string) (*Store, error) {
+		if !metadata.Enabled() {
+			return nil, errors.New("'idempotencyStore' is not set in the component metadata")
+		}
+		return NewStore(store, prefix, metadata.IdempotencyTTL), nil
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func newStateStore(t *testing.T) state.Store {
+	t.Helper()
+
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+	t.Cleanup(func() { store.(interface{ Close() error }).Close() })
+	return store
+}
+
+func TestStore(t *testing.T) {
+	stateStore := newStateStore(t)
+	s := NewStore(stateStore, "consumer1", time.Hour)
+	assert.Equal(t, "3600", s.ttl)
+
+	processed, err := s.IsProcessed(context.Background(), "msg1")
+	require.NoError(t, err)
+	assert.False(t, processed)
+
+	require.NoError(t, s.MarkProcessed(context.Background(), "msg1"))
+
+	processed, err = s.IsProcessed(context.Background(), "msg1")
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	// Other consumers have their own records
+	other := NewStore(stateStore, "consumer2", 0)
+	assert.Equal(t, "86400", other.ttl)
+	processed, err = other.IsProcessed(context.Background(), "msg1")
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestHolder(t *testing.T) {
+	h := &Holder{}
+	h.Init(Metadata{}, "prefix")
+	require.Error(t, h.SetIdempotencyStore(newStateStore(t)))
+
+	h.Init(Metadata{IdempotencyStore: "statestore", IdempotencyTTL: time.Minute}, "prefix")
+	require.Error(t, h.SetIdempotencyStore(nil))
+	require.NoError(t, h.SetIdempotencyStore(newStateStore(t)))
+	s, err := h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "60", s.ttl)
+	assert.Equal(t, "idempotency||prefix||", s.prefix)
+}
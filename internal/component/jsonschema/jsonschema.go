@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonschema validates the data of messages received by pub/sub components against a JSON Schema, so malformed events are rejected before they reach handlers.
+//
+// The schema is compiled once when the component is initialized. Components validate each message before delivering it, and send the messages that don't conform to the dead-letter path without retrying them.
+package jsonschema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// ErrInvalid is returned by Validate for messages that don't conform to the schema.
+var ErrInvalid = errors.New("message does not conform to the JSON schema")
+
+// Metadata contains the properties used to configure the schema of a component.
+// It's meant to be included (with "squash") in the metadata struct of the component.
+type Metadata struct {
+	// JSON Schema that the data of messages must conform to, either inline or as a "file://", "http://" or "https://" URL.
+	// If empty, messages are not validated.
+	JSONSchema string `mapstructure:"jsonSchema"`
+}
+
+// Validator validates the data of messages against a schema.
+// A nil Validator accepts all messages.
+type Validator struct {
+	schema *gojsonschema.Schema
+}
+
+// Compile loads and compiles the schema in the metadata.
+// It returns nil if no schema is configured.
+func Compile(m Metadata) (*Validator, error) {
+	val := strings.TrimSpace(m.JSONSchema)
+	if val == "" {
+		return nil, nil
+	}
+
+	var loader gojsonschema.JSONLoader
+	if isReference(val) {
+		loader = gojsonschema.NewReferenceLoader(val)
+	} else {
+		loader = gojsonschema.NewStringLoader(val)
+	}
+	schema, err := gojsonschema.NewSchema(loader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata property 'jsonSchema': %w", err)
+	}
+	return &Validator{schema: schema}, nil
+}
+
+func isReference(val string) bool {
+	for _, scheme := range []string{"file://", "http://", "https://"} {
+		if strings.HasPrefix(strings.ToLower(val), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error wrapping ErrInvalid, with the validation errors, if the data of the message doesn't conform to the schema.
+// If the message is a CloudEvent, its "data" (or "data_base64") is validated; otherwise, the whole message is.
+func (v *Validator) Validate(msg []byte) error {
+	if v == nil {
+		return nil
+	}
+
+	data, err := cloudEventData(msg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	res, err := v.schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		// The data is not valid JSON
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if res.Valid() {
+		return nil
+	}
+
+	errs := make([]string, len(res.Errors()))
+	for i, e := range res.Errors() {
+		errs[i] = e.String()
+	}
+	return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(errs, "; "))
+}
+
+// cloudEventData returns the data of a CloudEvent in structured mode, or msg itself if it's not a CloudEvent.
+func cloudEventData(msg []byte) ([]byte, error) {
+	var ce map[string]json.RawMessage
+	if json.Unmarshal(msg, &ce) != nil || ce[pubsub.SpecVersionField] == nil {
+		return msg, nil
+	}
+
+	if raw, ok := ce[pubsub.DataBase64Field]; ok {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", pubsub.DataBase64Field, err)
+		}
+		return base64.StdEncoding.DecodeString(s)
+	}
+	if raw, ok := ce[pubsub.DataField]; ok {
+		return raw, nil
+	}
+	return []byte("null"), nil
+}
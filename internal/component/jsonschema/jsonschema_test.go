@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"orderId": {"type": "integer"}
+	},
+	"required": ["orderId"]
+}`
+
+func TestCompile(t *testing.T) {
+	t.Run("no schema", func(t *testing.T) {
+		v, err := Compile(Metadata{})
+		require.NoError(t, err)
+		assert.Nil(t, v)
+		// A nil validator accepts all messages
+		require.NoError(t, v.Validate([]byte("not json")))
+	})
+
+	t.Run("inline schema", func(t *testing.T) {
+		v, err := Compile(Metadata{JSONSchema: testSchema})
+		require.NoError(t, err)
+		assert.NotNil(t, v)
+	})
+
+	t.Run("referenced schema", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "schema.json")
+		require.NoError(t, os.WriteFile(path, []byte(testSchema), 0o600))
+		v, err := Compile(Metadata{JSONSchema: "file://" + filepath.ToSlash(path)})
+		require.NoError(t, err)
+		require.NoError(t, v.Validate([]byte(`{"orderId":1}`)))
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		_, err := Compile(Metadata{JSONSchema: `{"type": 42}`})
+		require.ErrorContains(t, err, "jsonSchema")
+
+		_, err = Compile(Metadata{JSONSchema: `{"type":`})
+		require.Error(t, err)
+	})
+
+	t.Run("missing referenced schema", func(t *testing.T) {
+		_, err := Compile(Metadata{JSONSchema: "file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "nope.json"))})
+		require.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	v, err := Compile(Metadata{JSONSchema: testSchema})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		msg   string
+		valid bool
+	}{
+		"raw message":                     {msg: `{"orderId":1}`, valid: true},
+		"invalid raw message":             {msg: `{"orderId":"1"}`},
+		"not JSON":                        {msg: `hello`},
+		"CloudEvent":                      {msg: `{"specversion":"1.0","id":"1","data":{"orderId":1}}`, valid: true},
+		"CloudEvent with invalid data":    {msg: `{"specversion":"1.0","id":"1","data":{"customer":"a"}}`},
+		"CloudEvent without data":         {msg: `{"specversion":"1.0","id":"1"}`},
+		"CloudEvent with base64 data":     {msg: `{"specversion":"1.0","id":"1","data_base64":"eyJvcmRlcklkIjoxfQ=="}`, valid: true},
+		"CloudEvent with invalid base64":  {msg: `{"specversion":"1.0","id":"1","data_base64":"!"}`},
+		"CloudEvent attributes not data":  {msg: `{"specversion":"1.0","id":"1","orderId":1}`},
+		"object without specversion":      {msg: `{"data":{"customer":"a"},"orderId":1}`, valid: true},
+		"CloudEvent with null data value": {msg: `{"specversion":"1.0","id":"1","data":null}`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := v.Validate([]byte(tt.msg))
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalid)
+			}
+		})
+	}
+
+	t.Run("error describes the violations", func(t *testing.T) {
+		err := v.Validate([]byte(`{"orderId":"1"}`))
+		require.ErrorContains(t, err, "orderId")
+	})
+}
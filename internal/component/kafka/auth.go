@@ -18,21 +18,66 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/Shopify/sarama"
 )
 
+// parseSASLMechanism returns the SASL mechanism for the value of the "saslMechanism" property, checking that it can be used with authType.
+// "SHA-256" and "SHA-512" are accepted as aliases of the SCRAM mechanisms.
+func parseSASLMechanism(authType string, mechanism string) (sarama.SASLMechanism, error) {
+	var res sarama.SASLMechanism
+	switch strings.ToUpper(mechanism) {
+	case "":
+		// Default for the auth type
+		switch strings.ToLower(authType) {
+		case passwordAuthType:
+			return sarama.SASLTypePlaintext, nil
+		case oidcAuthType:
+			return sarama.SASLTypeOAuth, nil
+		default:
+			return "", nil
+		}
+	case "PLAIN", "PLAINTEXT":
+		res = sarama.SASLTypePlaintext
+	case "SCRAM-SHA-256", "SHA-256":
+		res = sarama.SASLTypeSCRAMSHA256
+	case "SCRAM-SHA-512", "SHA-512":
+		res = sarama.SASLTypeSCRAMSHA512
+	case "OAUTHBEARER":
+		res = sarama.SASLTypeOAuth
+	default:
+		return "", fmt.Errorf("kafka error: invalid value for 'saslMechanism': must be one of 'PLAIN', 'SCRAM-SHA-256', 'SCRAM-SHA-512', or 'OAUTHBEARER'")
+	}
+
+	// The credentials required by the mechanism are the ones of the auth type
+	switch strings.ToLower(authType) {
+	case passwordAuthType:
+		if res == sarama.SASLTypeOAuth {
+			return "", fmt.Errorf("kafka error: saslMechanism '%s' requires authType '%s'", mechanism, oidcAuthType)
+		}
+	case oidcAuthType:
+		if res != sarama.SASLTypeOAuth {
+			return "", fmt.Errorf("kafka error: saslMechanism '%s' requires authType '%s'", mechanism, passwordAuthType)
+		}
+	default:
+		return "", fmt.Errorf("kafka error: saslMechanism can only be used with authType '%s' or '%s'", passwordAuthType, oidcAuthType)
+	}
+	return res, nil
+}
+
 func updatePasswordAuthInfo(config *sarama.Config, metadata *KafkaMetadata, saslUsername, saslPassword string) {
 	config.Net.SASL.Enable = true
 	config.Net.SASL.User = saslUsername
 	config.Net.SASL.Password = saslPassword
-	if metadata.SaslMechanism == "SHA-256" {
+	switch metadata.internalSaslMechanism {
+	case sarama.SASLTypeSCRAMSHA256:
 		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &XDGSCRAMClient{HashGeneratorFcn: SHA256} }
 		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
-	} else if metadata.SaslMechanism == "SHA-512" {
+	case sarama.SASLTypeSCRAMSHA512:
 		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &XDGSCRAMClient{HashGeneratorFcn: SHA512} }
 		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
-	} else {
+	default:
 		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
 	}
 }
@@ -73,6 +118,14 @@ func updateTLSConfig(config *sarama.Config, metadata *KafkaMetadata) error {
 }
 
 func updateOidcAuthInfo(config *sarama.Config, metadata *KafkaMetadata) error {
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+
+	if metadata.OAuthTokenProvider == fileTokenProvider {
+		config.Net.SASL.TokenProvider = &fileTokenSource{path: metadata.OAuthTokenFile}
+		return nil
+	}
+
 	tokenProvider := newOAuthTokenSource(metadata.OidcTokenEndpoint, metadata.OidcClientID, metadata.OidcClientSecret, metadata.internalOidcScopes)
 
 	if metadata.TLSCaCert != "" {
@@ -84,8 +137,6 @@ func updateOidcAuthInfo(config *sarama.Config, metadata *KafkaMetadata) error {
 
 	tokenProvider.skipCaVerify = metadata.TLSSkipVerify
 
-	config.Net.SASL.Enable = true
-	config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
 	config.Net.SASL.TokenProvider = &tokenProvider
 
 	return nil
@@ -21,6 +21,8 @@ import (
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -123,3 +125,104 @@ func TestAuth(t *testing.T) {
 		require.Nil(t, mockConfig.Net.TLS.Config)
 	})
 }
+
+func TestSASLMechanism(t *testing.T) {
+	k := getKafka()
+
+	passwordMetadata := func(mechanism string) map[string]string {
+		m := getAuthBaseMetadata()
+		m[authType] = passwordAuthType
+		m["saslUsername"] = "user"
+		m["saslPassword"] = "pass"
+		m["saslMechanism"] = mechanism
+		return m
+	}
+
+	t.Run("password mechanisms", func(t *testing.T) {
+		for mechanism, expected := range map[string]sarama.SASLMechanism{
+			"":              sarama.SASLTypePlaintext,
+			"PLAIN":         sarama.SASLTypePlaintext,
+			"SCRAM-SHA-256": sarama.SASLTypeSCRAMSHA256,
+			"scram-sha-512": sarama.SASLTypeSCRAMSHA512,
+			"SHA-512":       sarama.SASLTypeSCRAMSHA512,
+		} {
+			meta, err := k.getKafkaMetadata(passwordMetadata(mechanism))
+			require.NoError(t, err, mechanism)
+
+			config := sarama.NewConfig()
+			updatePasswordAuthInfo(config, meta, meta.SaslUsername, meta.SaslPassword)
+			require.Equal(t, expected, config.Net.SASL.Mechanism, mechanism)
+			require.Equal(t, expected == sarama.SASLTypeSCRAMSHA256 || expected == sarama.SASLTypeSCRAMSHA512, config.Net.SASL.SCRAMClientGeneratorFunc != nil, mechanism)
+		}
+	})
+
+	t.Run("credentials must match the mechanism", func(t *testing.T) {
+		_, err := k.getKafkaMetadata(passwordMetadata("OAUTHBEARER"))
+		require.ErrorContains(t, err, "requires authType 'oidc'")
+
+		m := getAuthBaseMetadata()
+		m[authType] = oidcAuthType
+		m["oidcTokenEndpoint"] = "https://idp.example.com/token"
+		m["oidcClientID"] = "id"
+		m["oidcClientSecret"] = "secret"
+		m["saslMechanism"] = "SCRAM-SHA-512"
+		_, err = k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "requires authType 'password'")
+
+		m = getAuthBaseMetadata()
+		m[authType] = noAuthType
+		m["saslMechanism"] = "PLAIN"
+		_, err = k.getKafkaMetadata(m)
+		require.Error(t, err)
+
+		_, err = k.getKafkaMetadata(passwordMetadata("GSSAPI"))
+		require.ErrorContains(t, err, "invalid value for 'saslMechanism'")
+	})
+
+	t.Run("oauthbearer with client credentials", func(t *testing.T) {
+		m := getAuthBaseMetadata()
+		m[authType] = oidcAuthType
+		m["saslMechanism"] = "OAUTHBEARER"
+		m["oidcTokenEndpoint"] = "https://idp.example.com/token"
+		m["oidcClientID"] = "id"
+		m["oidcClientSecret"] = "secret"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+
+		config := sarama.NewConfig()
+		require.NoError(t, updateOidcAuthInfo(config, meta))
+		require.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), config.Net.SASL.Mechanism)
+		require.IsType(t, &OAuthTokenSource{}, config.Net.SASL.TokenProvider)
+	})
+
+	t.Run("oauthbearer with token file", func(t *testing.T) {
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte("tok1\n"), 0o600))
+
+		m := getAuthBaseMetadata()
+		m[authType] = oidcAuthType
+		m["oauthTokenProvider"] = "file"
+		_, err := k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "oauthTokenFile")
+
+		m["oauthTokenFile"] = tokenFile
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+
+		config := sarama.NewConfig()
+		require.NoError(t, updateOidcAuthInfo(config, meta))
+		token, err := config.Net.SASL.TokenProvider.Token()
+		require.NoError(t, err)
+		require.Equal(t, "tok1", token.Token)
+
+		// Rotated tokens are picked up
+		require.NoError(t, os.WriteFile(tokenFile, []byte("tok2"), 0o600))
+		token, err = config.Net.SASL.TokenProvider.Token()
+		require.NoError(t, err)
+		require.Equal(t, "tok2", token.Token)
+
+		m["oauthTokenProvider"] = "other"
+		_, err = k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "oauthTokenProvider")
+	})
+}
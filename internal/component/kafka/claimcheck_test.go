@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func newClaimCheckKafka(t *testing.T) (*Kafka, *mocks.SyncProducer, state.Store) {
+	t.Helper()
+
+	producer := mocks.NewSyncProducer(t, sarama.NewConfig())
+	k := NewKafka(logger.NewLogger("test"))
+	k.producer = producer
+	k.claimCheck.Init(claimcheck.Metadata{ClaimCheckStore: "statestore", ClaimCheckThreshold: 10}, "kafka")
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	require.NoError(t, k.SetClaimCheckStore(stateStore))
+	return k, producer, stateStore
+}
+
+// headerValue returns the value of the header with the given key, or an empty string.
+func headerValue(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestClaimCheck(t *testing.T) {
+	payload := []byte(strings.Repeat("x", 100))
+
+	t.Run("large payloads are offloaded and rehydrated", func(t *testing.T) {
+		k, producer, stateStore := newClaimCheckKafka(t)
+		var sent *sarama.ProducerMessage
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			sent = msg
+			return nil
+		})
+
+		require.NoError(t, k.Publish(context.Background(), "topic", payload, map[string]string{"myheader": "value"}))
+		require.NotNil(t, sent)
+		value, err := sent.Value.Encode()
+		require.NoError(t, err)
+		assert.Empty(t, value)
+		ref := headerValue(sent, claimcheck.MetadataKey)
+		require.NotEmpty(t, ref)
+		assert.Equal(t, "value", headerValue(sent, "myheader"))
+
+		var received []byte
+		k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+			Handler: func(ctx context.Context, msg *NewEvent) error {
+				received = msg.Data
+				return nil
+			},
+		})
+		c := &consumer{k: k}
+		msg := &sarama.ConsumerMessage{
+			Topic:   "topic",
+			Value:   value,
+			Headers: []*sarama.RecordHeader{{Key: []byte(claimcheck.MetadataKey), Value: []byte(ref)}},
+		}
+		require.NoError(t, c.doCallback(&fakeSession{}, msg))
+		assert.Equal(t, payload, received)
+
+		// The payload is deleted after delivery
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: ref})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+
+	t.Run("small payloads are sent inline", func(t *testing.T) {
+		k, producer, _ := newClaimCheckKafka(t)
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			assert.Empty(t, headerValue(msg, claimcheck.MetadataKey))
+			return nil
+		})
+		require.NoError(t, k.Publish(context.Background(), "topic", []byte("hello"), nil))
+	})
+
+	t.Run("payload is discarded if publishing fails", func(t *testing.T) {
+		k, producer, stateStore := newClaimCheckKafka(t)
+		var ref string
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndFail(func(msg *sarama.ProducerMessage) error {
+			ref = headerValue(msg, claimcheck.MetadataKey)
+			return nil
+		}, sarama.ErrOutOfBrokers)
+
+		err := k.Publish(context.Background(), "topic", payload, nil)
+		require.True(t, errors.Is(err, sarama.ErrOutOfBrokers))
+		require.NotEmpty(t, ref)
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: ref})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+
+	t.Run("missing payload fails the delivery", func(t *testing.T) {
+		k, _, _ := newClaimCheckKafka(t)
+		k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+			Handler: func(ctx context.Context, msg *NewEvent) error {
+				t.Fatal("handler must not be invoked")
+				return nil
+			},
+		})
+		c := &consumer{k: k}
+		session := &fakeSession{}
+		msg := &sarama.ConsumerMessage{
+			Topic:   "topic",
+			Offset:  3,
+			Headers: []*sarama.RecordHeader{{Key: []byte(claimcheck.MetadataKey), Value: []byte("claimcheck||kafka||missing")}},
+		}
+		require.ErrorContains(t, c.doCallback(session, msg), "not found in the claim-check store")
+		assert.Empty(t, session.marked)
+	})
+}
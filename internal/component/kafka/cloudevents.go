@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// Prefix of the headers that contain the attributes of CloudEvents in binary mode, from the Kafka protocol binding of CloudEvents.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/kafka-protocol-binding.md.
+const cloudEventHeaderPrefix = "ce_"
+
+// toBinaryCloudEvent returns the payload and the metadata of a message to publish.
+// If the component is in binary mode and data is a CloudEvent, the attributes of the CloudEvent are moved to the metadata, which is copied.
+// Otherwise, data and metadata are returned unchanged.
+func (k *Kafka) toBinaryCloudEvent(data []byte, metadata map[string]string) ([]byte, map[string]string) {
+	if k.cloudEventMode != pubsub.CloudEventModeBinary {
+		return data, metadata
+	}
+	payload, headers, ok := pubsub.ToBinaryCloudEvent(data, cloudEventHeaderPrefix, contentTypeHeader)
+	if !ok {
+		return data, metadata
+	}
+	for name, value := range metadata {
+		if _, exists := headers[name]; !exists {
+			headers[name] = value
+		}
+	}
+	return payload, headers
+}
+
+// fromBinaryCloudEvent returns the payload of a received message and its content type.
+// If the component is in binary mode and the message carries a CloudEvent in binary mode, the payload is the CloudEvent in structured mode.
+// Otherwise, data is returned unchanged and contentType is nil.
+func (k *Kafka) fromBinaryCloudEvent(data []byte, metadata map[string]string) ([]byte, *string) {
+	if k.cloudEventMode != pubsub.CloudEventModeBinary {
+		return data, nil
+	}
+	ce, ok := pubsub.FromBinaryCloudEvent(data, metadata, cloudEventHeaderPrefix, contentTypeHeader)
+	if !ok {
+		return data, nil
+	}
+	ct := contenttype.CloudEventContentType
+	return ce, &ct
+}
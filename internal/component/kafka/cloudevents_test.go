@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+const testCloudEvent = `{"specversion":"1.0","id":"a","source":"s","type":"t","datacontenttype":"application/json","data":{"message":"hello"}}`
+
+func newCloudEventKafka(t *testing.T, mode pubsub.CloudEventMode) (*Kafka, *mocks.SyncProducer) {
+	t.Helper()
+
+	producer := mocks.NewSyncProducer(t, sarama.NewConfig())
+	k := NewKafka(logger.NewLogger("test"))
+	k.producer = producer
+	k.cloudEventMode = mode
+	return k, producer
+}
+
+// consumerHeaders converts the headers of a produced message to the headers of a consumed one.
+func consumerHeaders(msg *sarama.ProducerMessage) []*sarama.RecordHeader {
+	res := make([]*sarama.RecordHeader, len(msg.Headers))
+	for i := range msg.Headers {
+		res[i] = &msg.Headers[i]
+	}
+	return res
+}
+
+func TestCloudEventMode(t *testing.T) {
+	t.Run("metadata", func(t *testing.T) {
+		k := getKafka()
+		m := getCompleteMetadata()
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		assert.Equal(t, pubsub.CloudEventModeStructured, meta.internalCloudEventMode)
+
+		m["cloudEventMode"] = "binary"
+		meta, err = k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		assert.Equal(t, pubsub.CloudEventModeBinary, meta.internalCloudEventMode)
+
+		m["cloudEventMode"] = "invalid"
+		_, err = k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "cloudEventMode")
+	})
+
+	t.Run("structured mode sends CloudEvents unchanged", func(t *testing.T) {
+		k, producer := newCloudEventKafka(t, pubsub.CloudEventModeStructured)
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			value, err := msg.Value.Encode()
+			require.NoError(t, err)
+			assert.Equal(t, testCloudEvent, string(value))
+			assert.Empty(t, headerValue(msg, "ce_id"))
+			return nil
+		})
+		require.NoError(t, k.Publish(context.Background(), "topic", []byte(testCloudEvent), nil))
+	})
+
+	t.Run("binary mode round trip", func(t *testing.T) {
+		k, producer := newCloudEventKafka(t, pubsub.CloudEventModeBinary)
+		var sent *sarama.ProducerMessage
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			sent = msg
+			return nil
+		})
+		require.NoError(t, k.Publish(context.Background(), "topic", []byte(testCloudEvent), map[string]string{"partitionKey": "key", "myheader": "value"}))
+		require.NotNil(t, sent)
+
+		value, err := sent.Value.Encode()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"message":"hello"}`, string(value))
+		assert.Equal(t, "a", headerValue(sent, "ce_id"))
+		assert.Equal(t, "1.0", headerValue(sent, "ce_specversion"))
+		assert.Equal(t, "application/json", headerValue(sent, contentTypeHeader))
+		assert.Equal(t, "value", headerValue(sent, "myheader"))
+		key, err := sent.Key.Encode()
+		require.NoError(t, err)
+		assert.Equal(t, "key", string(key))
+
+		var received *NewEvent
+		k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+			Handler: func(ctx context.Context, msg *NewEvent) error {
+				received = msg
+				return nil
+			},
+		})
+		c := &consumer{k: k}
+		require.NoError(t, c.doCallback(&fakeSession{}, &sarama.ConsumerMessage{
+			Topic:   "topic",
+			Value:   value,
+			Headers: consumerHeaders(sent),
+		}))
+		require.NotNil(t, received)
+		assert.JSONEq(t, testCloudEvent, string(received.Data))
+		require.NotNil(t, received.ContentType)
+		assert.Equal(t, contenttype.CloudEventContentType, *received.ContentType)
+		assert.Equal(t, "value", received.Metadata["myheader"])
+	})
+
+	t.Run("binary mode sends other payloads unchanged", func(t *testing.T) {
+		k, producer := newCloudEventKafka(t, pubsub.CloudEventModeBinary)
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			value, err := msg.Value.Encode()
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(value))
+			assert.Empty(t, msg.Headers)
+			return nil
+		})
+		require.NoError(t, k.Publish(context.Background(), "topic", []byte("hello"), nil))
+
+		var received *NewEvent
+		k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+			Handler: func(ctx context.Context, msg *NewEvent) error {
+				received = msg
+				return nil
+			},
+		})
+		c := &consumer{k: k}
+		require.NoError(t, c.doCallback(&fakeSession{}, &sarama.ConsumerMessage{Topic: "topic", Value: []byte("hello")}))
+		assert.Equal(t, "hello", string(received.Data))
+		assert.Nil(t, received.ContentType)
+	})
+
+	t.Run("binary mode bulk publish", func(t *testing.T) {
+		k, producer := newCloudEventKafka(t, pubsub.CloudEventModeBinary)
+		var sent []*sarama.ProducerMessage
+		for i := 0; i < 2; i++ {
+			producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+				sent = append(sent, msg)
+				return nil
+			})
+		}
+		_, err := k.BulkPublish(context.Background(), "topic", []pubsub.BulkMessageEntry{
+			{EntryId: "1", Event: []byte(testCloudEvent)},
+			{EntryId: "2", Event: []byte("hello")},
+		}, map[string]string{"myheader": "value"})
+		require.NoError(t, err)
+		require.Len(t, sent, 2)
+
+		assert.Equal(t, "a", headerValue(sent[0], "ce_id"))
+		assert.Equal(t, "value", headerValue(sent[0], "myheader"))
+		value, err := sent[0].Value.Encode()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"message":"hello"}`, string(value))
+
+		assert.Empty(t, headerValue(sent[1], "ce_id"))
+		assert.Equal(t, "value", headerValue(sent[1], "myheader"))
+		value, err = sent[1].Value.Encode()
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(value))
+	})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Default interval at which offsets are committed when "commitBatchSize" is set, which matches the auto-commit interval of sarama.
+const defaultCommitInterval = time.Second
+
+// batchCommitSession is a consumer group session that commits the marked offsets after "commitBatchSize" messages, or every "commitInterval", instead of relying on the auto-commit of sarama.
+// Offsets are only marked after messages are processed, and in order within each partition, so a commit never moves past a message that wasn't processed; after a crash, up to the messages marked since the last commit are delivered again.
+type batchCommitSession struct {
+	sarama.ConsumerGroupSession
+
+	batchSize int
+	pending   int
+	lock      sync.Mutex
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// newBatchCommitSession returns a session that commits offsets in batches, and starts committing them every interval.
+func newBatchCommitSession(session sarama.ConsumerGroupSession, batchSize int, interval time.Duration) *batchCommitSession {
+	if interval <= 0 {
+		interval = defaultCommitInterval
+	}
+	s := &batchCommitSession{
+		ConsumerGroupSession: session,
+		batchSize:            batchSize,
+		stopCh:               make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Commit()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// MarkMessage marks the offset of a message that was processed, and commits the marked offsets once the batch is full.
+func (s *batchCommitSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.ConsumerGroupSession.MarkMessage(msg, metadata)
+
+	s.lock.Lock()
+	s.pending++
+	full := s.pending >= s.batchSize
+	s.lock.Unlock()
+	if full {
+		s.Commit()
+	}
+}
+
+// Commit commits the marked offsets synchronously.
+func (s *batchCommitSession) Commit() {
+	s.lock.Lock()
+	s.pending = 0
+	s.lock.Unlock()
+	s.ConsumerGroupSession.Commit()
+}
+
+// Close stops committing offsets periodically, and commits the offsets marked since the last commit.
+// It must be called when the session ends, as sarama doesn't commit offsets when auto-commit is disabled.
+func (s *batchCommitSession) Close() {
+	close(s.stopCh)
+	s.wg.Wait()
+	s.Commit()
+}
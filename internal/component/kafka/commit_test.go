@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+type commitSession struct {
+	fakeSession
+	lock    sync.Mutex
+	commits int
+}
+
+func (s *commitSession) Commit() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commits++
+}
+
+func (s *commitSession) commitCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.commits
+}
+
+func TestBatchCommitSession(t *testing.T) {
+	t.Run("commits when the batch is full", func(t *testing.T) {
+		session := &commitSession{}
+		s := newBatchCommitSession(session, 3, time.Hour)
+		for i := int64(0); i < 7; i++ {
+			s.MarkMessage(&sarama.ConsumerMessage{Topic: "topic", Offset: i}, "")
+		}
+		assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6}, session.marked)
+		assert.Equal(t, 2, session.commitCount())
+
+		// The rest of the batch is committed when the session ends
+		s.Close()
+		assert.Equal(t, 3, session.commitCount())
+	})
+
+	t.Run("commits every interval", func(t *testing.T) {
+		session := &commitSession{}
+		s := newBatchCommitSession(session, 100, 10*time.Millisecond)
+		defer s.Close()
+		s.MarkMessage(&sarama.ConsumerMessage{Topic: "topic", Offset: 0}, "")
+		assert.Eventually(t, func() bool {
+			return session.commitCount() > 0
+		}, time.Second, 5*time.Millisecond)
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,6 +26,11 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/cenkalti/backoff/v4"
 
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/internal/component/deadletter"
+	"github.com/dapr/components-contrib/internal/component/idempotency"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/tracing"
 	"github.com/dapr/kit/retry"
 )
 
@@ -35,9 +41,15 @@ type consumer struct {
 	stopped atomic.Bool
 	once    sync.Once
 	mutex   sync.Mutex
+
+	// Session that commits offsets in batches, set for the duration of each session if "commitBatchSize" is set
+	commitSession *batchCommitSession
 }
 
 func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if consumer.commitSession != nil {
+		session = consumer.commitSession
+	}
 	b := consumer.k.backOffConfig.NewBackOffWithContext(session.Context())
 	isBulkSubscribe := consumer.k.checkBulkSubscribe(claim.Topic())
 
@@ -87,11 +99,13 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 						consumer.k.logger.Infof("Successfully processed Kafka message after it previously failed: %s/%d/%d [key=%s]", message.Topic, message.Partition, message.Offset, asBase64String(message.Key))
 					}); err != nil {
 						consumer.k.logger.Errorf("Too many failed attempts at processing Kafka message: %s/%d/%d [key=%s]. Error: %v.", message.Topic, message.Partition, message.Offset, asBase64String(message.Key), err)
+						consumer.deadLetter(session, message, err)
 					}
 				} else {
 					err := consumer.doCallback(session, message)
 					if err != nil {
 						consumer.k.logger.Errorf("Error processing Kafka message: %s/%d/%d [key=%s]. Error: %v.", message.Topic, message.Partition, message.Offset, asBase64String(message.Key), err)
+						consumer.deadLetter(session, message, err)
 					}
 				}
 			// Should return when `session.Context()` is done.
@@ -134,10 +148,38 @@ func (consumer *consumer) doBulkCallback(session sarama.ConsumerGroupSession,
 	messages []*sarama.ConsumerMessage, handler BulkEventHandler, topic string,
 ) error {
 	consumer.k.logger.Debugf("Processing Kafka bulk message: %s", topic)
-	messageValues := make([]KafkaBulkMessageEntry, (len(messages)))
+	store, err := consumer.k.idempotency.Get()
+	if err != nil {
+		return err
+	}
+	claimCheck, err := consumer.k.claimCheck.Get()
+	if err != nil {
+		return err
+	}
+	encrypter, err := consumer.k.encryption.Get()
+	if err != nil {
+		return err
+	}
+
+	// Messages that were already processed are not sent to the handler, but their offsets are still marked in order
+	processed := make([]bool, len(messages))
+	// Messages that can't be decrypted or don't conform to the schema are not sent to the handler, and are dead-lettered instead
+	rejected := make([]bool, len(messages))
+	messageValues := make([]KafkaBulkMessageEntry, 0, len(messages))
 
 	for i, message := range messages {
 		if message != nil {
+			if store != nil {
+				processed[i], err = store.IsProcessed(session.Context(), messageID(message))
+				if err != nil {
+					return err
+				}
+				if processed[i] {
+					consumer.k.logger.Debugf("Skipping Kafka message that was already processed: %s/%d/%d", message.Topic, message.Partition, message.Offset)
+					continue
+				}
+			}
+
 			metadata := make(map[string]string, len(message.Headers))
 			if message.Headers != nil {
 				for _, t := range message.Headers {
@@ -149,34 +191,181 @@ func (consumer *consumer) doBulkCallback(session sarama.ConsumerGroupSession,
 				Event:    message.Value,
 				Metadata: metadata,
 			}
-			messageValues[i] = childMessage
+			if claimCheck != nil {
+				childMessage.Event, err = claimCheck.Rehydrate(session.Context(), message.Value, metadata)
+				if err != nil {
+					return err
+				}
+			}
+			if ct := contentTypeFromMetadata(metadata); ct != nil {
+				childMessage.ContentType = *ct
+			}
+			plaintext, decryptErr := encrypter.Decrypt(session.Context(), childMessage.Event, metadata)
+			if decryptErr != nil {
+				consumer.k.logger.Errorf("Rejecting Kafka message %s/%d/%d: %v", message.Topic, message.Partition, message.Offset, decryptErr)
+				consumer.deadLetter(session, message, decryptErr)
+				rejected[i] = true
+				continue
+			}
+			childMessage.Event = plaintext
+			if data, ct := consumer.k.fromBinaryCloudEvent(childMessage.Event, metadata); ct != nil {
+				childMessage.Event = data
+				childMessage.ContentType = *ct
+			}
+			if validateErr := consumer.k.schema.Validate(childMessage.Event); validateErr != nil {
+				consumer.k.logger.Errorf("Rejecting Kafka message %s/%d/%d: %v", message.Topic, message.Partition, message.Offset, validateErr)
+				consumer.deadLetter(session, message, validateErr)
+				rejected[i] = true
+				continue
+			}
+			messageValues = append(messageValues, childMessage)
 		}
 	}
-	event := KafkaBulkMessage{
-		Topic:   topic,
-		Entries: messageValues,
+
+	var responses []pubsub.BulkSubscribeResponseEntry
+	if len(messageValues) > 0 {
+		event := KafkaBulkMessage{
+			Topic:   topic,
+			Entries: messageValues,
+		}
+		done := make([]func(pubsub.DeliveryOutcome), len(messageValues))
+		spans := make([]tracing.Span, len(messageValues))
+		for i := range messageValues {
+			_, spans[i] = tracing.StartConsumerSpan(session.Context(), consumer.k.tracer, tracingSystem, topic, messageValues[i].Metadata)
+			done[i] = pubsub.StartDelivery(consumer.k.metrics, topic)
+		}
+		responses, err = handler(session.Context(), &event)
+		for i := range messageValues {
+			if err == nil || (i < len(responses) && responses[i].Error == nil && responses[i].EntryId == messageValues[i].EntryId) {
+				spans[i].End(nil)
+				done[i](pubsub.DeliveryAcked)
+			} else {
+				entryErr := err
+				if i < len(responses) && responses[i].Error != nil {
+					entryErr = responses[i].Error
+				}
+				spans[i].End(entryErr)
+				done[i](consumer.failedDeliveryOutcome(entryErr))
+			}
+		}
 	}
-	responses, err := handler(session.Context(), &event)
 
 	if err != nil {
-		for i, resp := range responses {
+		n := 0
+		retryable := false
+		for i, message := range messages {
+			if processed[i] {
+				session.MarkMessage(message, "")
+				continue
+			}
+			if rejected[i] {
+				continue
+			}
+			if message == nil || n >= len(responses) {
+				retryable = true
+				break
+			}
 			// An extra check to confirm that runtime returned responses are in order
-			if resp.EntryId != messageValues[i].EntryId {
+			resp := responses[n]
+			if resp.EntryId != messageValues[n].EntryId {
 				return errors.New("entry id mismatch while processing bulk messages")
 			}
+			n++
 			if resp.Error != nil {
-				break
+				if !consumer.k.errorClassifier.IsPermanent(resp.Error) {
+					retryable = true
+					break
+				}
+				// Retrying doesn't help, so the message is sent to the dead-letter path right away
+				consumer.deadLetter(session, message, resp.Error)
+				continue
 			}
-			session.MarkMessage(messages[i], "")
+			consumer.markProcessed(session.Context(), store, message)
+			session.MarkMessage(message, "")
+			consumer.releaseClaimCheck(session.Context(), claimCheck, message, messageValues[n-1].Metadata)
+		}
+		if !retryable {
+			return backoff.Permanent(err)
 		}
 	} else {
-		for _, message := range messages {
+		n := 0
+		for i, message := range messages {
+			if rejected[i] {
+				continue
+			}
+			if message != nil && !processed[i] {
+				consumer.markProcessed(session.Context(), store, message)
+			}
 			session.MarkMessage(message, "")
+			if message != nil && !processed[i] {
+				consumer.releaseClaimCheck(session.Context(), claimCheck, message, messageValues[n].Metadata)
+				n++
+			}
 		}
 	}
 	return err
 }
 
+// releaseClaimCheck deletes the offloaded payload of a message that was delivered, if a claim-check store is configured.
+// Failures are logged, as the payload is deleted when its TTL expires.
+func (consumer *consumer) releaseClaimCheck(ctx context.Context, claimCheck *claimcheck.Store, message *sarama.ConsumerMessage, metadata map[string]string) {
+	if claimCheck == nil {
+		return
+	}
+	err := claimCheck.Release(ctx, metadata)
+	if err != nil {
+		consumer.k.logger.Warnf("Failed to delete offloaded payload of Kafka message %s/%d/%d: %v", message.Topic, message.Partition, message.Offset, err)
+	}
+}
+
+// deadLetter saves a message that could not be processed to the dead-letter store, if one is configured, and marks its offset once it's saved.
+// Messages are not dead-lettered when the session is ending, as processing stopped because of the rebalance or shutdown, and they're delivered again.
+func (consumer *consumer) deadLetter(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage, processErr error) {
+	if session.Context().Err() != nil {
+		return
+	}
+	store, err := consumer.k.deadLetter.Get()
+	if store == nil || err != nil {
+		return
+	}
+
+	metadata := make(map[string]string, len(message.Headers))
+	for _, header := range message.Headers {
+		metadata[string(header.Key)] = string(header.Value)
+	}
+	key, err := store.Save(session.Context(), deadletter.Message{
+		Topic:    message.Topic,
+		ID:       fmt.Sprintf("%d/%d", message.Partition, message.Offset),
+		Data:     message.Value,
+		Metadata: metadata,
+		Error:    processErr.Error(),
+	})
+	if err != nil {
+		consumer.k.logger.Errorf("Failed to dead-letter Kafka message %s/%d/%d: %v", message.Topic, message.Partition, message.Offset, err)
+		return
+	}
+	consumer.k.logger.Warnf("Saved Kafka message %s/%d/%d to the dead-letter store with key %s", message.Topic, message.Partition, message.Offset, key)
+	session.MarkMessage(message, "")
+}
+
+// messageID returns the ID used to detect duplicate deliveries of a message.
+func messageID(message *sarama.ConsumerMessage) string {
+	return fmt.Sprintf("%s/%d/%d", message.Topic, message.Partition, message.Offset)
+}
+
+// markProcessed records that the message was processed, if an idempotency store is configured.
+// This must be done after the handler succeeded and before the offset of the message is marked, so a crash in between causes the message to be skipped when redelivered, rather than dropped.
+// Failures are logged but don't prevent the offset from being marked, as the message was processed successfully.
+func (consumer *consumer) markProcessed(ctx context.Context, store *idempotency.Store, message *sarama.ConsumerMessage) {
+	if store == nil {
+		return
+	}
+	err := store.MarkProcessed(ctx, messageID(message))
+	if err != nil {
+		consumer.k.logger.Warnf("Kafka message %s/%d/%d may be processed again if redelivered: %v", message.Topic, message.Partition, message.Offset, err)
+	}
+}
+
 func (consumer *consumer) doCallback(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) error {
 	consumer.k.logger.Debugf("Processing Kafka message: %s/%d/%d [key=%s]", message.Topic, message.Partition, message.Offset, asBase64String(message.Key))
 	handlerConfig, err := consumer.k.GetTopicHandlerConfig(message.Topic)
@@ -186,6 +375,22 @@ func (consumer *consumer) doCallback(session sarama.ConsumerGroupSession, messag
 	if !handlerConfig.IsBulkSubscribe && handlerConfig.Handler == nil {
 		return errors.New("invalid handler config for subscribe call")
 	}
+	store, err := consumer.k.idempotency.Get()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		processed, err := store.IsProcessed(session.Context(), messageID(message))
+		if err != nil {
+			return err
+		}
+		if processed {
+			consumer.k.logger.Debugf("Skipping Kafka message that was already processed: %s/%d/%d", message.Topic, message.Partition, message.Offset)
+			session.MarkMessage(message, "")
+			return nil
+		}
+	}
+
 	event := NewEvent{
 		Topic: message.Topic,
 		Data:  message.Value,
@@ -196,19 +401,113 @@ func (consumer *consumer) doCallback(session sarama.ConsumerGroupSession, messag
 		for _, header := range message.Headers {
 			event.Metadata[string(header.Key)] = string(header.Value)
 		}
+		event.ContentType = contentTypeFromMetadata(event.Metadata)
 	}
-	err = handlerConfig.Handler(session.Context(), &event)
+	claimCheck, err := consumer.k.claimCheck.Get()
+	if err != nil {
+		return err
+	}
+	if claimCheck != nil {
+		event.Data, err = claimCheck.Rehydrate(session.Context(), event.Data, event.Metadata)
+		if err != nil {
+			return err
+		}
+	}
+	encrypter, err := consumer.k.encryption.Get()
+	if err != nil {
+		return err
+	}
+	event.Data, err = encrypter.Decrypt(session.Context(), event.Data, event.Metadata)
+	if err != nil {
+		// Retrying doesn't make the key available, and the ciphertext must not be delivered
+		return backoff.Permanent(err)
+	}
+	if data, ct := consumer.k.fromBinaryCloudEvent(event.Data, event.Metadata); ct != nil {
+		event.Data = data
+		event.ContentType = ct
+	}
+	err = consumer.k.schema.Validate(event.Data)
+	if err != nil {
+		// Messages that don't conform to the schema are sent to the dead-letter path without retrying them
+		return backoff.Permanent(err)
+	}
+	handlerCtx, span := tracing.StartConsumerSpan(session.Context(), consumer.k.tracer, tracingSystem, message.Topic, event.Metadata)
+	done := pubsub.StartDelivery(consumer.k.metrics, message.Topic)
+	if consumer.k.IsTransactional() {
+		// The offset of the message is committed together with the messages published by the handler in the transaction
+		err = consumer.k.RunInTransaction(handlerCtx, func(ctx context.Context, txn *Transaction) error {
+			handlerErr := handlerConfig.Handler(ctx, &event)
+			if handlerErr != nil {
+				return handlerErr
+			}
+			return txn.addMessage(message)
+		})
+	} else {
+		err = handlerConfig.Handler(handlerCtx, &event)
+	}
+	span.End(err)
 	if err == nil {
+		consumer.markProcessed(session.Context(), store, message)
 		session.MarkMessage(message, "")
+		consumer.releaseClaimCheck(session.Context(), claimCheck, message, event.Metadata)
+		done(pubsub.DeliveryAcked)
+	} else {
+		done(consumer.failedDeliveryOutcome(err))
+		if consumer.k.errorClassifier.IsPermanent(err) {
+			// Retrying doesn't help, so the message is sent to the dead-letter path right away
+			return backoff.Permanent(err)
+		}
 	}
 	return err
 }
 
+// failedDeliveryOutcome returns what happens to a message that the handler failed to process with err.
+func (consumer *consumer) failedDeliveryOutcome(err error) pubsub.DeliveryOutcome {
+	if consumer.k.errorClassifier.IsPermanent(err) {
+		if store, _ := consumer.k.deadLetter.Get(); store != nil {
+			return pubsub.DeliveryDeadLettered
+		}
+		return pubsub.DeliveryNacked
+	}
+	if consumer.k.consumeRetryEnabled {
+		return pubsub.DeliveryRetried
+	}
+	return pubsub.DeliveryNacked
+}
+
+// contentTypeFromMetadata returns the value of the content type header of a message, if present.
+// This allows messages published by non-Dapr systems, such as raw JSON payloads, to be surfaced with the correct content type.
+func contentTypeFromMetadata(metadata map[string]string) *string {
+	for k, v := range metadata {
+		if v != "" && strings.EqualFold(k, contentTypeHeader) {
+			return &v
+		}
+	}
+	return nil
+}
+
 func (consumer *consumer) Cleanup(sarama.ConsumerGroupSession) error {
+	if consumer.commitSession != nil {
+		// Commit the offsets marked since the last batch, before the partitions are released
+		consumer.commitSession.Close()
+		consumer.commitSession = nil
+	}
 	return nil
 }
 
-func (consumer *consumer) Setup(sarama.ConsumerGroupSession) error {
+func (consumer *consumer) Setup(session sarama.ConsumerGroupSession) error {
+	err := consumer.k.seekStartOffsets(session)
+	if err != nil {
+		return err
+	}
+	err = consumer.k.applySeeks(session)
+	if err != nil {
+		return err
+	}
+	if consumer.k.commitBatchSize > 0 {
+		consumer.commitSession = newBatchCommitSession(session, consumer.k.commitBatchSize, consumer.k.commitInterval)
+	}
+
 	consumer.once.Do(func() {
 		close(consumer.ready)
 	})
@@ -253,6 +552,7 @@ func (k *Kafka) GetTopicHandlerConfig(topic string) (SubscriptionHandlerConfig,
 }
 
 // Subscribe to topic in the Kafka cluster, in a background goroutine
+// The consumer group is restarted with the topics of all the handlers; messages being processed are drained, and their offsets committed, before the restart.
 func (k *Kafka) Subscribe(ctx context.Context) error {
 	if k.consumerGroup == "" {
 		return errors.New("kafka: consumerGroup must be set to subscribe")
@@ -264,12 +564,44 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 	// Close resources and reset synchronization primitives
 	k.closeSubscriptionResources()
 
+	return k.startConsumer(ctx)
+}
+
+// Unsubscribe stops consuming topic, and restarts the consumer group for the remaining topics, in a background goroutine.
+// Messages of the topic that are being processed are drained, and their offsets committed, before the handler of the topic is removed.
+func (k *Kafka) Unsubscribe(ctx context.Context, topic string) error {
+	if k.consumerGroup == "" {
+		return errors.New("kafka: consumerGroup must be set to subscribe")
+	}
+
+	k.subscribeLock.Lock()
+	defer k.subscribeLock.Unlock()
+
+	if _, ok := k.subscribeTopics[topic]; !ok {
+		return nil
+	}
+
+	// Closing the consumer group waits for the handlers to return, so the handler is removed afterwards
+	k.closeSubscriptionResources()
+	delete(k.subscribeTopics, topic)
+
+	return k.startConsumer(ctx)
+}
+
+// startConsumer starts consuming the topics of all the handlers in a new consumer group, until ctx is canceled or the consumer group is closed.
+// It must be called with subscribeLock held, after closeSubscriptionResources.
+func (k *Kafka) startConsumer(ctx context.Context) error {
 	topics := k.subscribeTopics.TopicList()
 	if len(topics) == 0 {
 		// Nothing to subscribe to
 		return nil
 	}
 
+	err := k.EnsureTopics(topics...)
+	if err != nil {
+		return err
+	}
+
 	cg, err := sarama.NewConsumerGroup(k.brokers, k.consumerGroup, k.config)
 	if err != nil {
 		return err
@@ -283,6 +615,7 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 		ready:   ready,
 		running: make(chan struct{}),
 	}
+	c := &k.consumer
 
 	go func() {
 		k.logger.Debugf("Subscribed and listening to topics: %s", topics)
@@ -297,40 +630,63 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 			k.logger.Debugf("Starting loop to consume.")
 
 			// Consume the requested topics
-			bo := backoff.WithContext(backoff.NewConstantBackOff(k.consumeRetryInterval), ctx)
+			var bo backoff.BackOff
+			if k.consumeBackOff != nil {
+				bo = k.consumeBackOff()
+			} else {
+				bo = backoff.NewConstantBackOff(k.consumeRetryInterval)
+			}
 			innerErr := retry.NotifyRecover(func() error {
 				if ctxErr := ctx.Err(); ctxErr != nil {
 					return backoff.Permanent(ctxErr)
 				}
-				return k.cg.Consume(ctx, topics, &(k.consumer))
-			}, bo, func(err error, t time.Duration) {
+				err := cg.Consume(ctx, topics, c)
+				if errors.Is(err, sarama.ErrClosedConsumerGroup) ||
+					(k.consumeBackOff != nil && isPermanentConsumeError(err)) {
+					return backoff.Permanent(err)
+				}
+				return err
+			}, backoff.WithContext(bo, ctx), func(err error, t time.Duration) {
 				k.logger.Errorf("Error consuming %v. Retrying...: %v", topics, err)
 			}, func() {
 				k.logger.Infof("Recovered consuming %v", topics)
 			})
+			if errors.Is(innerErr, sarama.ErrClosedConsumerGroup) {
+				// The consumer group was closed to restart it or to close the component
+				break
+			}
 			if innerErr != nil && !errors.Is(innerErr, context.Canceled) {
 				k.logger.Errorf("Permanent error consuming %v: %v", topics, innerErr)
+				// With a custom backoff, the consumer stops when the retries are exhausted or the error is permanent
+				if k.consumeBackOff != nil {
+					break
+				}
 			}
 		}
 
 		k.logger.Debugf("Closing ConsumerGroup for topics: %v", topics)
-		err := k.cg.Close()
+		err := cg.Close()
 		if err != nil {
 			k.logger.Errorf("Error closing consumer group: %v", err)
 		}
 
 		// Ensure running channel is only closed once.
-		if k.consumer.stopped.CompareAndSwap(false, true) {
-			close(k.consumer.running)
+		if c.stopped.CompareAndSwap(false, true) {
+			close(c.running)
 		}
 	}()
 
-	<-ready
+	// Wait until the consumer group session is set up, or the consume loop stopped
+	select {
+	case <-ready:
+	case <-c.running:
+	}
 
 	return nil
 }
 
 // Close down consumer group resources, refresh once.
+// Closing the consumer group waits for the messages being processed, and commits their offsets.
 func (k *Kafka) closeSubscriptionResources() {
 	if k.cg != nil {
 		err := k.cg.Close()
@@ -338,11 +694,19 @@ func (k *Kafka) closeSubscriptionResources() {
 			k.logger.Errorf("Error closing consumer group: %v", err)
 		}
 
+		// Wait for the consume loop to stop
+		<-k.consumer.running
 		k.consumer.once.Do(func() {
-			// Wait for shutdown to be complete
-			<-k.consumer.running
 			close(k.consumer.ready)
-			k.consumer.once = sync.Once{}
 		})
+		k.cg = nil
 	}
 }
+
+// isPermanentConsumeError returns true for errors caused by the configuration, such as missing permissions, which retrying doesn't solve.
+func isPermanentConsumeError(err error) bool {
+	return errors.Is(err, sarama.ErrTopicAuthorizationFailed) ||
+		errors.Is(err, sarama.ErrGroupAuthorizationFailed) ||
+		errors.Is(err, sarama.ErrClusterAuthorizationFailed) ||
+		errors.Is(err, sarama.ErrSASLAuthenticationFailed)
+}
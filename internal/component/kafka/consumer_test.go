@@ -0,0 +1,202 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/idempotency"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func TestContentTypeFromMetadata(t *testing.T) {
+	t.Run("header present", func(t *testing.T) {
+		ct := contentTypeFromMetadata(map[string]string{"Content-Type": "application/json", "other": "x"})
+		require.NotNil(t, ct)
+		assert.Equal(t, "application/json", *ct)
+	})
+
+	t.Run("header missing or empty", func(t *testing.T) {
+		assert.Nil(t, contentTypeFromMetadata(nil))
+		assert.Nil(t, contentTypeFromMetadata(map[string]string{"content-type": ""}))
+	})
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func newIdempotentKafka(t *testing.T) *Kafka {
+	t.Helper()
+
+	k := NewKafka(logger.NewLogger("test"))
+	k.idempotency.Init(idempotency.Metadata{IdempotencyStore: "statestore"}, "kafka||group")
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	require.NoError(t, k.SetIdempotencyStore(stateStore))
+	return k
+}
+
+func TestIdempotentCallback(t *testing.T) {
+	k := newIdempotentKafka(t)
+	calls := 0
+	failNext := false
+	k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+		Handler: func(ctx context.Context, msg *NewEvent) error {
+			calls++
+			if failNext {
+				failNext = false
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+	})
+	c := &consumer{k: k}
+	session := &fakeSession{}
+
+	msg := &sarama.ConsumerMessage{Topic: "topic", Partition: 1, Offset: 10, Value: []byte("a")}
+	require.NoError(t, c.doCallback(session, msg))
+	// Redelivery of the same message is skipped, but its offset is marked
+	require.NoError(t, c.doCallback(session, msg))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []int64{10, 10}, session.marked)
+
+	// A message whose handler failed is not recorded as processed
+	failed := &sarama.ConsumerMessage{Topic: "topic", Partition: 1, Offset: 11, Value: []byte("b")}
+	failNext = true
+	require.Error(t, c.doCallback(session, failed))
+	require.NoError(t, c.doCallback(session, failed))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []int64{10, 10, 11}, session.marked)
+}
+
+func TestIdempotentBulkCallback(t *testing.T) {
+	k := newIdempotentKafka(t)
+	c := &consumer{k: k}
+	session := &fakeSession{}
+
+	var received [][]string
+	handler := func(ctx context.Context, msg *KafkaBulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+		ids := []string{}
+		responses := []pubsub.BulkSubscribeResponseEntry{}
+		var err error
+		for _, e := range msg.Entries {
+			ids = append(ids, string(e.Event))
+			resp := pubsub.BulkSubscribeResponseEntry{EntryId: e.EntryId}
+			if string(e.Event) == "fail" {
+				resp.Error = errors.New("failed")
+				err = resp.Error
+			}
+			responses = append(responses, resp)
+		}
+		received = append(received, ids)
+		return responses, err
+	}
+
+	messages := []*sarama.ConsumerMessage{
+		{Topic: "topic", Offset: 1, Value: []byte("a")},
+		{Topic: "topic", Offset: 2, Value: []byte("b")},
+	}
+	require.NoError(t, c.doBulkCallback(session, messages, handler, "topic"))
+	assert.Equal(t, []int64{1, 2}, session.marked)
+
+	// Already processed messages are not sent to the handler, and offsets are marked until the first failure
+	session.marked = nil
+	messages = append(messages,
+		&sarama.ConsumerMessage{Topic: "topic", Offset: 3, Value: []byte("c")},
+		&sarama.ConsumerMessage{Topic: "topic", Offset: 4, Value: []byte("fail")},
+		&sarama.ConsumerMessage{Topic: "topic", Offset: 5, Value: []byte("d")},
+	)
+	require.Error(t, c.doBulkCallback(session, messages, handler, "topic"))
+	assert.Equal(t, []int64{1, 2, 3}, session.marked)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "fail", "d"}}, received)
+
+	// Only the messages that weren't processed are redelivered to the handler
+	session.marked = nil
+	messages[3].Value = []byte("e")
+	require.NoError(t, c.doBulkCallback(session, messages, handler, "topic"))
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, session.marked)
+	assert.Equal(t, []string{"e", "d"}, received[2])
+}
+
+func TestDeliveryMetrics(t *testing.T) {
+	k := NewKafka(logger.NewLogger("test"))
+	k.consumeRetryEnabled = true
+	metrics := pubsub.NewDeliveryMetrics()
+	k.SetDeliveryMetricsRecorder(metrics)
+
+	k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+		Handler: func(ctx context.Context, msg *NewEvent) error {
+			if string(msg.Data) == "fail" {
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+	})
+	c := consumer{k: k}
+	session := &fakeSession{}
+
+	require.NoError(t, c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 1, Value: []byte("ok")}))
+	require.Error(t, c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 2, Value: []byte("fail")}))
+
+	handler := func(ctx context.Context, msg *KafkaBulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+		return []pubsub.BulkSubscribeResponseEntry{
+			{EntryId: msg.Entries[0].EntryId},
+			{EntryId: msg.Entries[1].EntryId, Error: errors.New("failed")},
+		}, errors.New("failed")
+	}
+	err := c.doBulkCallback(session, []*sarama.ConsumerMessage{
+		{Topic: "topic", Offset: 3},
+		{Topic: "topic", Offset: 4},
+	}, handler, "topic")
+	require.Error(t, err)
+
+	s := metrics.Snapshot()["topic"]
+	assert.Equal(t, int64(4), s.Delivered)
+	assert.Equal(t, int64(2), s.Acked)
+	assert.Equal(t, int64(2), s.Retried)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	t.Run("requires a consumer group", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		require.Error(t, k.Unsubscribe(context.Background(), "topic"))
+	})
+
+	t.Run("topics without a handler are ignored", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		k.consumerGroup = "group"
+		k.AddTopicHandler("other", SubscriptionHandlerConfig{Handler: func(ctx context.Context, msg *NewEvent) error { return nil }})
+		require.NoError(t, k.Unsubscribe(context.Background(), "topic"))
+		assert.Equal(t, []string{"other"}, k.subscribeTopics.TopicList())
+	})
+}
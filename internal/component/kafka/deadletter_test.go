@@ -0,0 +1,222 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/deadletter"
+	"github.com/dapr/components-contrib/internal/component/jsonschema"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func TestDeadLetter(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic:     "topic",
+		Partition: 2,
+		Offset:    7,
+		Value:     []byte("poison"),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("h"), Value: []byte("v")}},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		c := &consumer{k: k}
+		session := &fakeSession{}
+		c.deadLetter(session, msg, errors.New("handler failed"))
+		assert.Empty(t, session.marked)
+	})
+
+	t.Run("saved to the store", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		k.deadLetter.Init(deadletter.Metadata{DeadLetterStore: "statestore"}, "kafka||group")
+		stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+		require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+		require.NoError(t, k.SetDeadLetterStore(stateStore))
+		require.NoError(t, k.CheckDeadLetterStore())
+
+		c := &consumer{k: k}
+		session := &fakeSession{}
+		c.deadLetter(session, msg, errors.New("handler failed"))
+		// The offset is marked once the message is saved
+		assert.Equal(t, []int64{7}, session.marked)
+
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: "deadletter||kafka||group||topic||2/7"})
+		require.NoError(t, err)
+		var record deadletter.Record
+		require.NoError(t, json.Unmarshal(res.Data, &record))
+		assert.Equal(t, "topic", record.Topic)
+		assert.Equal(t, "2/7", record.ID)
+		assert.Equal(t, []byte("poison"), record.Data)
+		assert.Equal(t, map[string]string{"h": "v"}, record.Metadata)
+		assert.Equal(t, "handler failed", record.Error)
+	})
+
+	t.Run("configured but not set", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		k.deadLetter.Init(deadletter.Metadata{DeadLetterStore: "statestore"}, "kafka||group")
+		require.Error(t, k.CheckDeadLetterStore())
+	})
+}
+
+func TestRejectInvalidMessages(t *testing.T) {
+	k := NewKafka(logger.NewLogger("test"))
+	var err error
+	k.schema, err = jsonschema.Compile(jsonschema.Metadata{JSONSchema: `{"type":"object","required":["orderId"]}`})
+	require.NoError(t, err)
+	k.deadLetter.Init(deadletter.Metadata{DeadLetterStore: "statestore"}, "kafka||group")
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	require.NoError(t, k.SetDeadLetterStore(stateStore))
+
+	var received []string
+	k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+		Handler: func(ctx context.Context, msg *NewEvent) error {
+			received = append(received, string(msg.Data))
+			return nil
+		},
+	})
+	c := &consumer{k: k}
+
+	t.Run("single message", func(t *testing.T) {
+		session := &fakeSession{}
+		err := c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 1, Value: []byte(`{"customer":"a"}`)})
+		require.ErrorIs(t, err, jsonschema.ErrInvalid)
+		// Retrying doesn't make the message valid
+		var permanent *backoff.PermanentError
+		require.ErrorAs(t, err, &permanent)
+		assert.Empty(t, received)
+
+		require.NoError(t, c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 2, Value: []byte(`{"orderId":1}`)}))
+		assert.Equal(t, []string{`{"orderId":1}`}, received)
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		session := &fakeSession{}
+		var entries []string
+		handler := func(ctx context.Context, msg *KafkaBulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+			responses := []pubsub.BulkSubscribeResponseEntry{}
+			for _, e := range msg.Entries {
+				entries = append(entries, string(e.Event))
+				responses = append(responses, pubsub.BulkSubscribeResponseEntry{EntryId: e.EntryId})
+			}
+			return responses, nil
+		}
+		messages := []*sarama.ConsumerMessage{
+			{Topic: "topic", Offset: 3, Value: []byte(`{"orderId":1}`)},
+			{Topic: "topic", Offset: 4, Value: []byte(`not json`)},
+			{Topic: "topic", Offset: 5, Value: []byte(`{"orderId":2}`)},
+		}
+		require.NoError(t, c.doBulkCallback(session, messages, handler, "topic"))
+		assert.Equal(t, []string{`{"orderId":1}`, `{"orderId":2}`}, entries)
+		// The invalid message is marked once it's saved to the dead-letter store
+		assert.ElementsMatch(t, []int64{3, 4, 5}, session.marked)
+
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: "deadletter||kafka||group||topic||0/4"})
+		require.NoError(t, err)
+		var record deadletter.Record
+		require.NoError(t, json.Unmarshal(res.Data, &record))
+		assert.Contains(t, record.Error, "JSON schema")
+	})
+}
+
+func TestPermanentHandlerErrors(t *testing.T) {
+	k := NewKafka(logger.NewLogger("test"))
+	k.errorClassifier = pubsub.ErrorClassifier{PermanentErrorSubstrings: []string{"unknown customer"}}
+	k.deadLetter.Init(deadletter.Metadata{DeadLetterStore: "statestore"}, "kafka||group")
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	require.NoError(t, k.SetDeadLetterStore(stateStore))
+
+	k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+		Handler: func(ctx context.Context, msg *NewEvent) error {
+			switch string(msg.Data) {
+			case "invalid":
+				return pubsub.NewPermanentError(errors.New("invalid order"))
+			case "unknown":
+				return errors.New("unknown customer 42")
+			}
+			return errors.New("downstream unavailable")
+		},
+	})
+	c := &consumer{k: k}
+
+	t.Run("single message", func(t *testing.T) {
+		var permanent *backoff.PermanentError
+		session := &fakeSession{}
+
+		err := c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 1, Value: []byte("invalid")})
+		require.ErrorAs(t, err, &permanent)
+		err = c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 2, Value: []byte("unknown")})
+		require.ErrorAs(t, err, &permanent)
+
+		// Other errors are retried
+		err = c.doCallback(session, &sarama.ConsumerMessage{Topic: "topic", Offset: 3, Value: []byte("transient")})
+		require.Error(t, err)
+		assert.False(t, errors.As(err, &permanent))
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		handler := func(ctx context.Context, msg *KafkaBulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+			responses := []pubsub.BulkSubscribeResponseEntry{}
+			for _, e := range msg.Entries {
+				res := pubsub.BulkSubscribeResponseEntry{EntryId: e.EntryId}
+				switch string(e.Event) {
+				case "invalid":
+					res.Error = pubsub.NewPermanentError(errors.New("invalid order"))
+				case "transient":
+					res.Error = errors.New("downstream unavailable")
+				}
+				responses = append(responses, res)
+			}
+			return responses, errors.New("some entries failed")
+		}
+
+		session := &fakeSession{}
+		err := c.doBulkCallback(session, []*sarama.ConsumerMessage{
+			{Topic: "topic", Offset: 4, Value: []byte("ok")},
+			{Topic: "topic", Offset: 5, Value: []byte("invalid")},
+			{Topic: "topic", Offset: 6, Value: []byte("ok")},
+		}, handler, "topic")
+		var permanent *backoff.PermanentError
+		require.ErrorAs(t, err, &permanent)
+		// The permanently failed message is dead-lettered, and the following ones are processed
+		assert.ElementsMatch(t, []int64{4, 5, 6}, session.marked)
+		res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: "deadletter||kafka||group||topic||0/5"})
+		require.NoError(t, err)
+		assert.NotNil(t, res.Data)
+
+		session = &fakeSession{}
+		err = c.doBulkCallback(session, []*sarama.ConsumerMessage{
+			{Topic: "topic", Offset: 7, Value: []byte("invalid")},
+			{Topic: "topic", Offset: 8, Value: []byte("transient")},
+			{Topic: "topic", Offset: 9, Value: []byte("ok")},
+		}, handler, "topic")
+		require.Error(t, err)
+		assert.False(t, errors.As(err, &permanent))
+		// Processing stops at the message that can be retried
+		assert.Equal(t, []int64{7}, session.marked)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/crypto/localstorage"
+	"github.com/dapr/components-contrib/internal/component/encryption"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func newCryptoProvider(t *testing.T) crypto.SubtleCrypto {
+	t.Helper()
+
+	dir := t.TempDir()
+	jwk := `{"kty":"oct","k":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.json"), []byte(jwk), 0o600))
+	provider := localstorage.NewLocalStorageCrypto(logger.NewLogger("test"))
+	require.NoError(t, provider.Init(context.Background(), crypto.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"path": dir},
+	}}))
+	return provider
+}
+
+func TestEncryption(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, sarama.NewConfig())
+	k := NewKafka(logger.NewLogger("test"))
+	k.producer = producer
+	k.encryption.Init(encryption.Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json"})
+	require.Error(t, k.CheckCryptoProvider())
+	require.NoError(t, k.SetCryptoProvider(newCryptoProvider(t)))
+	require.NoError(t, k.CheckCryptoProvider())
+
+	var sent *sarama.ProducerMessage
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+	require.NoError(t, k.Publish(context.Background(), "topic", []byte("secret"), map[string]string{"myheader": "value"}))
+	require.NotNil(t, sent)
+	value, err := sent.Value.Encode()
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte("secret"), value)
+	assert.NotEmpty(t, headerValue(sent, encryption.WrappedKeyMetadataKey))
+	assert.Equal(t, "value", headerValue(sent, "myheader"))
+
+	headers := make([]*sarama.RecordHeader, len(sent.Headers))
+	for i := range sent.Headers {
+		headers[i] = &sent.Headers[i]
+	}
+	msg := &sarama.ConsumerMessage{Topic: "topic", Value: value, Headers: headers}
+
+	t.Run("payload is decrypted", func(t *testing.T) {
+		var received []byte
+		k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+			Handler: func(ctx context.Context, msg *NewEvent) error {
+				received = msg.Data
+				return nil
+			},
+		})
+		c := &consumer{k: k}
+		require.NoError(t, c.doCallback(&fakeSession{}, msg))
+		assert.Equal(t, []byte("secret"), received)
+	})
+
+	t.Run("payload that can't be decrypted is not delivered", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		k.AddTopicHandler("topic", SubscriptionHandlerConfig{
+			Handler: func(ctx context.Context, msg *NewEvent) error {
+				t.Fatal("handler must not be invoked")
+				return nil
+			},
+		})
+		c := &consumer{k: k}
+		err := c.doCallback(&fakeSession{}, msg)
+		require.ErrorIs(t, err, encryption.ErrDecrypt)
+		var permanent *backoff.PermanentError
+		require.ErrorAs(t, err, &permanent)
+	})
+}
+
+func TestTransactionalEncryption(t *testing.T) {
+	k, producers := newTransactionalKafka(t)
+	k.encryption.Init(encryption.Metadata{CryptoComponent: "crypto", CryptoKeyName: "key.json"})
+	require.NoError(t, k.SetCryptoProvider(newCryptoProvider(t)))
+
+	var sent *sarama.ProducerMessage
+	(*producers)[0].ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+	err := k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+		return txn.Publish(context.Background(), "out", []byte("secret"), nil)
+	})
+	require.NoError(t, err)
+	require.NotNil(t, sent)
+	value, err := sent.Value.Encode()
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte("secret"), value)
+	assert.NotEmpty(t, headerValue(sent, encryption.WrappedKeyMetadataKey))
+}
@@ -15,12 +15,23 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/cenkalti/backoff/v4"
 
+	"github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/internal/component/batching"
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/internal/component/deadletter"
+	"github.com/dapr/components-contrib/internal/component/encryption"
+	"github.com/dapr/components-contrib/internal/component/idempotency"
+	"github.com/dapr/components-contrib/internal/component/jsonschema"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/tracing"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/retry"
 )
@@ -28,6 +39,8 @@ import (
 // Kafka allows reading/writing to a Kafka consumer group.
 type Kafka struct {
 	producer        sarama.SyncProducer
+	maxMessageBytes int
+	batcher         *batching.Batcher[*sarama.ProducerMessage]
 	consumerGroup   string
 	brokers         []string
 	logger          logger.Logger
@@ -48,6 +61,50 @@ type Kafka struct {
 	DefaultConsumeRetryEnabled bool
 	consumeRetryEnabled        bool
 	consumeRetryInterval       time.Duration
+	// If set, used instead of the constant consumeRetryInterval to retry consuming
+	consumeBackOff func() backoff.BackOff
+	// Classifies the errors of handlers, so messages that fail permanently aren't retried
+	errorClassifier pubsub.ErrorClassifier
+
+	idempotency idempotency.Holder
+	claimCheck  claimcheck.Holder
+	deadLetter  deadletter.Holder
+	metrics     pubsub.DeliveryMetricsRecorder
+	tracer      tracing.Tracer
+
+	// Schema that the data of received messages must conform to, if set
+	schema *jsonschema.Validator
+	// Encrypts payloads when publishing and decrypts them when receiving, if a crypto component is configured
+	encryption encryption.Holder
+
+	// How CloudEvents are represented in messages
+	cloudEventMode pubsub.CloudEventMode
+
+	startOffset          startOffsetConfig
+	seekedPartitions     map[string]bool
+	seekedPartitionsLock sync.Mutex
+	// Positions of topics moved with Seek, which are applied in the next consumer group session
+	pendingSeeks map[string]seekTarget
+
+	topicPolicy       topicPolicy
+	ensuredTopics     map[string]bool
+	ensuredTopicsLock sync.Mutex
+	// Allows replacing the admin client in tests
+	newClusterAdmin func() (sarama.ClusterAdmin, error)
+
+	// If greater than 0, offsets are committed after this number of messages, every commitInterval, and when the session ends, rather than by the auto-commit of sarama
+	commitBatchSize int
+	commitInterval  time.Duration
+
+	// If set, consumed messages are handled in transactions of txnProducer
+	transactionalID string
+	// Transactional producer; messages published outside of a transaction are sent by producer, which is not transactional.
+	// It's replaced after fatal errors, so it must only be accessed with txnLock held.
+	txnProducer sarama.SyncProducer
+	// Serializes transactions, as a transactional producer runs one at a time
+	txnLock sync.Mutex
+	// Allows replacing the producers in tests
+	newSyncProducer func(transactionalID string) (sarama.SyncProducer, error)
 }
 
 func NewKafka(logger logger.Logger) *Kafka {
@@ -73,11 +130,32 @@ func (k *Kafka) Init(_ context.Context, metadata map[string]string) error {
 	k.brokers = meta.internalBrokers
 	k.consumerGroup = meta.ConsumerGroup
 	k.initialOffset = meta.internalInitialOffset
+	k.startOffset = meta.internalStartOffset
+	k.seekedPartitions = map[string]bool{}
+	k.topicPolicy = meta.internalTopicPolicy
+	k.ensuredTopics = map[string]bool{}
 	k.authType = meta.AuthType
+	k.maxMessageBytes = meta.MaxMessageBytes
+	k.transactionalID = meta.TransactionalID
+	k.cloudEventMode = meta.internalCloudEventMode
+	k.commitBatchSize = meta.CommitBatchSize
+	k.commitInterval = meta.CommitInterval
 
 	config := sarama.NewConfig()
 	config.Version = meta.internalVersion
 	config.Consumer.Offsets.Initial = k.initialOffset
+	if k.transactionalID != "" {
+		// Don't consume messages of aborted transactions
+		config.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
+	// Consumers decompress messages with any codec, regardless of this setting
+	config.Producer.Compression = meta.internalCompression
+	if k.commitBatchSize > 0 {
+		// Offsets are committed by the consumer in batches
+		config.Consumer.Offsets.AutoCommit.Enable = false
+	} else if k.commitInterval > 0 {
+		config.Consumer.Offsets.AutoCommit.Interval = k.commitInterval
+	}
 
 	if meta.ClientID != "" {
 		config.ClientID = meta.ClientID
@@ -112,11 +190,32 @@ func (k *Kafka) Init(_ context.Context, metadata map[string]string) error {
 
 	k.config = config
 	sarama.Logger = SaramaLogBridge{daprLogger: k.logger}
+	if k.newClusterAdmin == nil {
+		k.newClusterAdmin = func() (sarama.ClusterAdmin, error) {
+			return sarama.NewClusterAdmin(k.brokers, k.config)
+		}
+	}
+
+	if k.newSyncProducer == nil {
+		k.newSyncProducer = func(transactionalID string) (sarama.SyncProducer, error) {
+			return getSyncProducer(*k.config, k.brokers, k.maxMessageBytes, transactionalID)
+		}
+	}
 
-	k.producer, err = getSyncProducer(*k.config, k.brokers, meta.MaxMessageBytes)
+	k.producer, err = k.newSyncProducer("")
 	if err != nil {
 		return err
 	}
+	if k.transactionalID != "" {
+		k.txnProducer, err = k.newSyncProducer(k.transactionalID)
+		if err != nil {
+			k.producer.Close()
+			return err
+		}
+	}
+	if meta.Settings.Enabled() {
+		k.batcher = batching.New(meta.Settings, k.sendBatch)
+	}
 
 	// Default retry configuration is used if no
 	// backOff properties are set.
@@ -128,19 +227,109 @@ func (k *Kafka) Init(_ context.Context, metadata map[string]string) error {
 	}
 	k.consumeRetryEnabled = meta.ConsumeRetryEnabled
 	k.consumeRetryInterval = meta.ConsumeRetryInterval
+	k.errorClassifier = meta.ErrorClassifier
+	k.idempotency.Init(meta.Metadata, "kafka||"+k.consumerGroup)
+	k.claimCheck.Init(meta.ClaimCheck, "kafka")
+	k.deadLetter.Init(meta.DeadLetter, "kafka||"+k.consumerGroup)
+	k.schema, err = jsonschema.Compile(meta.JSONSchema)
+	if err != nil {
+		return err
+	}
+	err = meta.Encryption.Validate()
+	if err != nil {
+		return err
+	}
+	k.encryption.Init(meta.Encryption)
 
 	k.logger.Debug("Kafka message bus initialization complete")
 
 	return nil
 }
 
+// SetConsumeBackOff sets the backoff used to retry consuming after an error, instead of retrying at the constant "consumeRetryInterval".
+// When it's set, consuming stops if the backoff gives up or the error is permanent, such as missing permissions.
+// It must be called before Subscribe.
+func (k *Kafka) SetConsumeBackOff(newBackOff func() backoff.BackOff) {
+	k.consumeBackOff = newBackOff
+}
+
+// SetIdempotencyStore sets the state store used to record the IDs of processed messages, so duplicate deliveries are skipped.
+func (k *Kafka) SetIdempotencyStore(store state.Store) error {
+	return k.idempotency.SetIdempotencyStore(store)
+}
+
+// SetClaimCheckStore sets the state store used to offload payloads larger than the "claimCheckThreshold".
+func (k *Kafka) SetClaimCheckStore(store state.Store) error {
+	return k.claimCheck.SetClaimCheckStore(store)
+}
+
+// SetDeadLetterStore sets the state store that messages are saved to when they can't be processed after all retries.
+func (k *Kafka) SetDeadLetterStore(store state.Store) error {
+	return k.deadLetter.SetDeadLetterStore(store)
+}
+
+// SetCryptoProvider sets the crypto component used to encrypt and decrypt payloads.
+func (k *Kafka) SetCryptoProvider(provider crypto.SubtleCrypto) error {
+	return k.encryption.SetCryptoProvider(provider)
+}
+
+// SetDeliveryMetricsRecorder sets the recorder of metrics about the delivery of messages.
+// It must be called before Subscribe.
+func (k *Kafka) SetDeliveryMetricsRecorder(recorder pubsub.DeliveryMetricsRecorder) {
+	k.metrics = recorder
+}
+
+// SetTracer sets the tracer used to create spans for published and consumed messages.
+// It must be called before the component is used.
+func (k *Kafka) SetTracer(tracer tracing.Tracer) {
+	k.tracer = tracer
+}
+
+// CheckIdempotencyStore returns an error if an idempotency store is configured but it was not set.
+func (k *Kafka) CheckIdempotencyStore() error {
+	_, err := k.idempotency.Get()
+	return err
+}
+
+// CheckClaimCheckStore returns an error if a claim-check store is configured but it was not set.
+func (k *Kafka) CheckClaimCheckStore() error {
+	_, err := k.claimCheck.Get()
+	return err
+}
+
+// CheckDeadLetterStore returns an error if a dead-letter store is configured but it was not set.
+func (k *Kafka) CheckDeadLetterStore() error {
+	_, err := k.deadLetter.Get()
+	return err
+}
+
+// CheckCryptoProvider returns an error if a crypto component is configured but it was not set.
+func (k *Kafka) CheckCryptoProvider() error {
+	_, err := k.encryption.Get()
+	return err
+}
+
 func (k *Kafka) Close() (err error) {
 	k.closeSubscriptionResources()
 
+	// Flush pending batches before closing the producer
+	if k.batcher != nil {
+		k.batcher.Close()
+		k.batcher = nil
+	}
+
+	// Wait for the transaction in progress, if any
+	k.txnLock.Lock()
+	defer k.txnLock.Unlock()
+
 	if k.producer != nil {
 		err = k.producer.Close()
 		k.producer = nil
 	}
+	if k.txnProducer != nil {
+		err = errors.Join(err, k.txnProducer.Close())
+		k.txnProducer = nil
+	}
 
 	return err
 }
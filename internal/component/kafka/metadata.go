@@ -22,7 +22,14 @@ import (
 
 	"github.com/Shopify/sarama"
 
+	"github.com/dapr/components-contrib/internal/component/batching"
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
+	"github.com/dapr/components-contrib/internal/component/deadletter"
+	"github.com/dapr/components-contrib/internal/component/encryption"
+	"github.com/dapr/components-contrib/internal/component/idempotency"
+	"github.com/dapr/components-contrib/internal/component/jsonschema"
 	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
 )
 
 const (
@@ -39,34 +46,70 @@ const (
 	oidcAuthType         = "oidc"
 	mtlsAuthType         = "mtls"
 	noAuthType           = "none"
+
+	// Values for the oauthTokenProvider metadata property.
+	clientCredentialsTokenProvider = "clientCredentials"
+	fileTokenProvider              = "file"
+
+	// Header containing the content type of a message, if set by the publisher.
+	contentTypeHeader = "content-type"
 )
 
 type KafkaMetadata struct {
-	Brokers               string              `mapstructure:"brokers"`
-	internalBrokers       []string            `mapstructure:"-"`
-	ConsumerGroup         string              `mapstructure:"consumerGroup"`
-	ClientID              string              `mapstructure:"clientId"`
-	AuthType              string              `mapstructure:"authType"`
-	SaslUsername          string              `mapstructure:"saslUsername"`
-	SaslPassword          string              `mapstructure:"saslPassword"`
-	SaslMechanism         string              `mapstructure:"saslMechanism"`
-	InitialOffset         string              `mapstructure:"initialOffset"`
-	internalInitialOffset int64               `mapstructure:"-"`
-	MaxMessageBytes       int                 `mapstructure:"maxMessageBytes"`
-	OidcTokenEndpoint     string              `mapstructure:"oidcTokenEndpoint"`
-	OidcClientID          string              `mapstructure:"oidcClientID"`
-	OidcClientSecret      string              `mapstructure:"oidcClientSecret"`
-	OidcScopes            string              `mapstructure:"oidcScopes"`
-	internalOidcScopes    []string            `mapstructure:"-"`
-	TLSDisable            bool                `mapstructure:"disableTls"`
-	TLSSkipVerify         bool                `mapstructure:"skipVerify"`
-	TLSCaCert             string              `mapstructure:"caCert"`
-	TLSClientCert         string              `mapstructure:"clientCert"`
-	TLSClientKey          string              `mapstructure:"clientKey"`
-	ConsumeRetryEnabled   bool                `mapstructure:"consumeRetryEnabled"`
-	ConsumeRetryInterval  time.Duration       `mapstructure:"consumeRetryInterval"`
-	Version               string              `mapstructure:"version"`
-	internalVersion       sarama.KafkaVersion `mapstructure:"-"`
+	Brokers                string                  `mapstructure:"brokers"`
+	internalBrokers        []string                `mapstructure:"-"`
+	ConsumerGroup          string                  `mapstructure:"consumerGroup"`
+	ClientID               string                  `mapstructure:"clientId"`
+	AuthType               string                  `mapstructure:"authType"`
+	SaslUsername           string                  `mapstructure:"saslUsername"`
+	SaslPassword           string                  `mapstructure:"saslPassword"`
+	SaslMechanism          string                  `mapstructure:"saslMechanism"`
+	internalSaslMechanism  sarama.SASLMechanism    `mapstructure:"-"`
+	InitialOffset          string                  `mapstructure:"initialOffset"`
+	internalInitialOffset  int64                   `mapstructure:"-"`
+	StartTimestamp         string                  `mapstructure:"startTimestamp"`
+	StartOffset            *int64                  `mapstructure:"startOffset"`
+	ForceStartOffset       bool                    `mapstructure:"forceStartOffset"`
+	internalStartOffset    startOffsetConfig       `mapstructure:"-"`
+	AutoCreateTopics       bool                    `mapstructure:"autoCreateTopics"`
+	FailIfTopicMissing     bool                    `mapstructure:"failIfTopicMissing"`
+	TopicPartitions        int32                   `mapstructure:"topicPartitions"`
+	TopicReplicationFactor int16                   `mapstructure:"topicReplicationFactor"`
+	internalTopicPolicy    topicPolicy             `mapstructure:"-"`
+	MaxMessageBytes        int                     `mapstructure:"maxMessageBytes"`
+	OidcTokenEndpoint      string                  `mapstructure:"oidcTokenEndpoint"`
+	OidcClientID           string                  `mapstructure:"oidcClientID"`
+	OidcClientSecret       string                  `mapstructure:"oidcClientSecret"`
+	OidcScopes             string                  `mapstructure:"oidcScopes"`
+	internalOidcScopes     []string                `mapstructure:"-"`
+	OAuthTokenProvider     string                  `mapstructure:"oauthTokenProvider"`
+	OAuthTokenFile         string                  `mapstructure:"oauthTokenFile"`
+	TLSDisable             bool                    `mapstructure:"disableTls"`
+	TLSSkipVerify          bool                    `mapstructure:"skipVerify"`
+	TLSCaCert              string                  `mapstructure:"caCert"`
+	TLSClientCert          string                  `mapstructure:"clientCert"`
+	TLSClientKey           string                  `mapstructure:"clientKey"`
+	ConsumeRetryEnabled    bool                    `mapstructure:"consumeRetryEnabled"`
+	ConsumeRetryInterval   time.Duration           `mapstructure:"consumeRetryInterval"`
+	Version                string                  `mapstructure:"version"`
+	internalVersion        sarama.KafkaVersion     `mapstructure:"-"`
+	TransactionalID        string                  `mapstructure:"transactionalId"`
+	Compression            string                  `mapstructure:"compression"`
+	internalCompression    sarama.CompressionCodec `mapstructure:"-"`
+	CloudEventMode         string                  `mapstructure:"cloudEventMode"`
+	internalCloudEventMode pubsub.CloudEventMode   `mapstructure:"-"`
+	CommitInterval         time.Duration           `mapstructure:"commitInterval"`
+	CommitBatchSize        int                     `mapstructure:"commitBatchSize"`
+
+	idempotency.Metadata `mapstructure:",squash"`
+	batching.Settings    `mapstructure:",squash"`
+	// Not embedded, as it would conflict with idempotency.Metadata
+	ClaimCheck claimcheck.Metadata `mapstructure:",squash"`
+	DeadLetter deadletter.Metadata `mapstructure:",squash"`
+	JSONSchema jsonschema.Metadata `mapstructure:",squash"`
+	Encryption encryption.Metadata `mapstructure:",squash"`
+
+	ErrorClassifier pubsub.ErrorClassifier `mapstructure:",squash"`
 }
 
 // upgradeMetadata updates metadata properties based on deprecated usage.
@@ -108,8 +151,10 @@ func (k *Kafka) upgradeMetadata(metadata map[string]string) (map[string]string,
 // getKafkaMetadata returns new Kafka metadata.
 func (k *Kafka) getKafkaMetadata(meta map[string]string) (*KafkaMetadata, error) {
 	m := KafkaMetadata{
-		ConsumeRetryInterval: 100 * time.Millisecond,
-		internalVersion:      sarama.V2_0_0_0, //nolint:nosnakecase
+		ConsumeRetryInterval:   100 * time.Millisecond,
+		TopicPartitions:        defaultTopicPartitions,
+		TopicReplicationFactor: defaultTopicReplicationFactor,
+		internalVersion:        sarama.V2_0_0_0, //nolint:nosnakecase
 	}
 
 	err := metadata.DecodeMetadata(meta, &m)
@@ -129,11 +174,57 @@ func (k *Kafka) getKafkaMetadata(meta map[string]string) (*KafkaMetadata, error)
 
 	k.logger.Debugf("ConsumerGroup='%s', ClientID='%s', saslMechanism='%s'", m.ConsumerGroup, m.ClientID, m.SaslMechanism)
 
-	initialOffset, err := parseInitialOffset(meta["initialOffset"])
+	switch {
+	case strings.EqualFold(m.InitialOffset, initialOffsetTimestamp):
+		if m.StartTimestamp == "" {
+			return nil, errors.New("kafka error: 'startTimestamp' is required when 'initialOffset' is 'timestamp'")
+		}
+		ts, err := time.Parse(time.RFC3339, m.StartTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: invalid startTimestamp, must be in RFC3339 format: %w", err)
+		}
+		m.internalStartOffset = startOffsetConfig{mode: initialOffsetTimestamp, timestamp: ts, force: m.ForceStartOffset}
+		// Used for partitions created after the consumer group started
+		m.internalInitialOffset = sarama.OffsetOldest
+	case strings.EqualFold(m.InitialOffset, initialOffsetOffset):
+		if m.StartOffset == nil || *m.StartOffset < 0 {
+			return nil, errors.New("kafka error: a non-negative 'startOffset' is required when 'initialOffset' is 'offset'")
+		}
+		m.internalStartOffset = startOffsetConfig{mode: initialOffsetOffset, offset: *m.StartOffset, force: m.ForceStartOffset}
+		m.internalInitialOffset = sarama.OffsetOldest
+	default:
+		initialOffset, err := parseInitialOffset(meta["initialOffset"])
+		if err != nil {
+			return nil, err
+		}
+		m.internalInitialOffset = initialOffset
+	}
+
+	err = m.Settings.Validate()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("kafka error: %w", err)
+	}
+
+	if m.AutoCreateTopics && m.FailIfTopicMissing {
+		return nil, errors.New("kafka error: 'autoCreateTopics' and 'failIfTopicMissing' cannot be both enabled")
+	}
+	if m.TopicPartitions < 1 {
+		return nil, errors.New("kafka error: 'topicPartitions' must be at least 1")
+	}
+	if m.TopicReplicationFactor < 1 {
+		return nil, errors.New("kafka error: 'topicReplicationFactor' must be at least 1")
+	}
+	m.internalTopicPolicy = topicPolicy{
+		create:            m.AutoCreateTopics,
+		partitions:        m.TopicPartitions,
+		replicationFactor: m.TopicReplicationFactor,
+		failIfMissing:     m.FailIfTopicMissing,
+	}
+
+	m.internalCloudEventMode, err = pubsub.ParseCloudEventMode(m.CloudEventMode)
+	if err != nil {
+		return nil, fmt.Errorf("kafka error: %w", err)
 	}
-	m.internalInitialOffset = initialOffset
 
 	if m.Brokers != "" {
 		m.internalBrokers = strings.Split(m.Brokers, ",")
@@ -154,6 +245,11 @@ func (k *Kafka) getKafkaMetadata(meta map[string]string) (*KafkaMetadata, error)
 		return nil, errors.New("kafka error: 'authType' attribute was missing or empty")
 	}
 
+	m.internalSaslMechanism, err = parseSASLMechanism(m.AuthType, m.SaslMechanism)
+	if err != nil {
+		return nil, err
+	}
+
 	switch strings.ToLower(m.AuthType) {
 	case passwordAuthType:
 		if m.SaslUsername == "" {
@@ -165,22 +261,33 @@ func (k *Kafka) getKafkaMetadata(meta map[string]string) (*KafkaMetadata, error)
 		}
 		k.logger.Debug("Configuring SASL password authentication.")
 	case oidcAuthType:
-		if m.OidcTokenEndpoint == "" {
-			return nil, errors.New("kafka error: missing OIDC Token Endpoint for authType 'oidc'")
-		}
-		if m.OidcClientID == "" {
-			return nil, errors.New("kafka error: missing OIDC Client ID for authType 'oidc'")
-		}
-		if m.OidcClientSecret == "" {
-			return nil, errors.New("kafka error: missing OIDC Client Secret for authType 'oidc'")
-		}
-		if m.OidcScopes != "" {
-			m.internalOidcScopes = strings.Split(m.OidcScopes, ",")
-		} else {
-			k.logger.Warn("Warning: no OIDC scopes specified, using default 'openid' scope only. This is a security risk for token reuse.")
-			m.internalOidcScopes = []string{"openid"}
+		switch m.OAuthTokenProvider {
+		case "", clientCredentialsTokenProvider:
+			m.OAuthTokenProvider = clientCredentialsTokenProvider
+			if m.OidcTokenEndpoint == "" {
+				return nil, errors.New("kafka error: missing OIDC Token Endpoint for authType 'oidc'")
+			}
+			if m.OidcClientID == "" {
+				return nil, errors.New("kafka error: missing OIDC Client ID for authType 'oidc'")
+			}
+			if m.OidcClientSecret == "" {
+				return nil, errors.New("kafka error: missing OIDC Client Secret for authType 'oidc'")
+			}
+			if m.OidcScopes != "" {
+				m.internalOidcScopes = strings.Split(m.OidcScopes, ",")
+			} else {
+				k.logger.Warn("Warning: no OIDC scopes specified, using default 'openid' scope only. This is a security risk for token reuse.")
+				m.internalOidcScopes = []string{"openid"}
+			}
+			k.logger.Debug("Configuring SASL token authentication via OIDC.")
+		case fileTokenProvider:
+			if m.OAuthTokenFile == "" {
+				return nil, errors.New("kafka error: missing 'oauthTokenFile' for oauthTokenProvider 'file'")
+			}
+			k.logger.Debug("Configuring SASL token authentication with a token file.")
+		default:
+			return nil, fmt.Errorf("kafka error: invalid value for 'oauthTokenProvider': must be '%s' or '%s'", clientCredentialsTokenProvider, fileTokenProvider)
 		}
-		k.logger.Debug("Configuring SASL token authentication via OIDC.")
 	case mtlsAuthType:
 		if m.TLSClientCert != "" {
 			if !isValidPEM(m.TLSClientCert) {
@@ -237,5 +344,49 @@ func (k *Kafka) getKafkaMetadata(meta map[string]string) (*KafkaMetadata, error)
 		m.internalVersion = version
 	}
 
+	if m.TransactionalID != "" && !m.internalVersion.IsAtLeast(sarama.V0_11_0_0) { //nolint:nosnakecase
+		return nil, errors.New("kafka error: 'transactionalId' requires Kafka version 0.11.0.0 or later")
+	}
+
+	m.internalCompression, err = parseCompression(m.Compression, m.internalVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.CommitInterval < 0 {
+		return nil, errors.New("kafka error: 'commitInterval' must not be negative")
+	}
+	if m.CommitBatchSize < 0 {
+		return nil, errors.New("kafka error: 'commitBatchSize' must not be negative")
+	}
+	if m.CommitBatchSize > 0 && m.TransactionalID != "" {
+		return nil, errors.New("kafka error: 'commitBatchSize' can't be used with 'transactionalId', as offsets are committed in the transactions")
+	}
+
 	return &m, nil
 }
+
+// Minimum Kafka version of each compression codec of produced messages.
+var compressionMinVersions = map[sarama.CompressionCodec]sarama.KafkaVersion{
+	sarama.CompressionGZIP:   sarama.V0_8_2_0,  //nolint:nosnakecase
+	sarama.CompressionSnappy: sarama.V0_8_2_0,  //nolint:nosnakecase
+	sarama.CompressionLZ4:    sarama.V0_10_0_0, //nolint:nosnakecase
+	sarama.CompressionZSTD:   sarama.V2_1_0_0,  //nolint:nosnakecase
+}
+
+// parseCompression parses the value of the "compression" metadata property, and checks that the codec is supported by the Kafka version.
+func parseCompression(val string, version sarama.KafkaVersion) (sarama.CompressionCodec, error) {
+	val = strings.ToLower(strings.TrimSpace(val))
+	if val == "" || val == "none" {
+		return sarama.CompressionNone, nil
+	}
+	var codec sarama.CompressionCodec
+	err := codec.UnmarshalText([]byte(val))
+	if err != nil {
+		return sarama.CompressionNone, fmt.Errorf("kafka error: invalid value for 'compression' attribute: '%s'; supported values are 'none', 'gzip', 'snappy', 'lz4' and 'zstd'", val)
+	}
+	if minVersion := compressionMinVersions[codec]; !version.IsAtLeast(minVersion) {
+		return sarama.CompressionNone, fmt.Errorf("kafka error: compression '%s' requires Kafka version %s or later, but 'version' is %s", val, minVersion, version)
+	}
+	return codec, nil
+}
@@ -286,6 +286,50 @@ func TestInitialOffset(t *testing.T) {
 	require.Equal(t, sarama.OffsetNewest, meta.internalInitialOffset)
 }
 
+func TestStartOffset(t *testing.T) {
+	k := getKafka()
+
+	t.Run("timestamp", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["initialOffset"] = "timestamp"
+		m["startTimestamp"] = "2023-04-01T10:00:00Z"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, initialOffsetTimestamp, meta.internalStartOffset.mode)
+		require.Equal(t, time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC), meta.internalStartOffset.timestamp.UTC())
+		require.False(t, meta.internalStartOffset.force)
+		require.Equal(t, sarama.OffsetOldest, meta.internalInitialOffset)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["initialOffset"] = "offset"
+		m["startOffset"] = "42"
+		m["forceStartOffset"] = "true"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, initialOffsetOffset, meta.internalStartOffset.mode)
+		require.Equal(t, int64(42), meta.internalStartOffset.offset)
+		require.True(t, meta.internalStartOffset.force)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{"initialOffset": "timestamp"},
+			{"initialOffset": "timestamp", "startTimestamp": "yesterday"},
+			{"initialOffset": "offset"},
+			{"initialOffset": "offset", "startOffset": "-1"},
+		} {
+			m := getBaseMetadata()
+			for k, v := range props {
+				m[k] = v
+			}
+			_, err := k.getKafkaMetadata(m)
+			require.Error(t, err, props)
+		}
+	})
+}
+
 func TestTls(t *testing.T) {
 	k := getKafka()
 
@@ -360,3 +404,116 @@ func TestTls(t *testing.T) {
 		require.Equal(t, "missing CA certificate property 'caCert' for authType 'certificate'", err.Error())
 	})
 }
+
+func TestTopicPolicy(t *testing.T) {
+	k := getKafka()
+
+	t.Run("default", func(t *testing.T) {
+		meta, err := k.getKafkaMetadata(getBaseMetadata())
+		require.NoError(t, err)
+		require.False(t, meta.internalTopicPolicy.enabled())
+		require.Equal(t, int32(defaultTopicPartitions), meta.internalTopicPolicy.partitions)
+		require.Equal(t, int16(defaultTopicReplicationFactor), meta.internalTopicPolicy.replicationFactor)
+	})
+
+	t.Run("auto-create", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["autoCreateTopics"] = "true"
+		m["topicPartitions"] = "6"
+		m["topicReplicationFactor"] = "3"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, topicPolicy{create: true, partitions: 6, replicationFactor: 3}, meta.internalTopicPolicy)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{"autoCreateTopics": "true", "failIfTopicMissing": "true"},
+			{"topicPartitions": "0"},
+			{"topicReplicationFactor": "0"},
+		} {
+			m := getBaseMetadata()
+			for k, v := range props {
+				m[k] = v
+			}
+			_, err := k.getKafkaMetadata(m)
+			require.Error(t, err, props)
+		}
+	})
+}
+
+func TestCompression(t *testing.T) {
+	k := getKafka()
+
+	t.Run("default", func(t *testing.T) {
+		meta, err := k.getKafkaMetadata(getBaseMetadata())
+		require.NoError(t, err)
+		require.Equal(t, sarama.CompressionNone, meta.internalCompression)
+	})
+
+	t.Run("codecs", func(t *testing.T) {
+		for val, codec := range map[string]sarama.CompressionCodec{
+			"none":   sarama.CompressionNone,
+			"gzip":   sarama.CompressionGZIP,
+			"Snappy": sarama.CompressionSnappy,
+			"lz4":    sarama.CompressionLZ4,
+			"zstd":   sarama.CompressionZSTD,
+		} {
+			m := getBaseMetadata()
+			m["compression"] = val
+			m["version"] = "2.1.0"
+			meta, err := k.getKafkaMetadata(m)
+			require.NoError(t, err, val)
+			require.Equal(t, codec, meta.internalCompression, val)
+		}
+	})
+
+	t.Run("invalid codec", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["compression"] = "brotli"
+		_, err := k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "invalid value for 'compression' attribute")
+	})
+
+	t.Run("codec not supported by the version", func(t *testing.T) {
+		// The default version is 2.0.0
+		m := getBaseMetadata()
+		m["compression"] = "zstd"
+		_, err := k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "requires Kafka version 2.1.0 or later")
+
+		m["compression"] = "lz4"
+		m["version"] = "0.9.0.0"
+		_, err = k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "requires Kafka version 0.10.0.0 or later")
+	})
+}
+
+func TestCommitBatching(t *testing.T) {
+	k := getKafka()
+
+	t.Run("valid", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["commitBatchSize"] = "100"
+		m["commitInterval"] = "5s"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, 100, meta.CommitBatchSize)
+		require.Equal(t, 5*time.Second, meta.CommitInterval)
+	})
+
+	t.Run("negative batch size", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["commitBatchSize"] = "-1"
+		_, err := k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "'commitBatchSize' must not be negative")
+	})
+
+	t.Run("not allowed with transactions", func(t *testing.T) {
+		m := getBaseMetadata()
+		m["commitBatchSize"] = "10"
+		m["transactionalId"] = "txn"
+		_, err := k.getKafkaMetadata(m)
+		require.ErrorContains(t, err, "can't be used with 'transactionalId'")
+	})
+}
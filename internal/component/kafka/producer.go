@@ -19,10 +19,16 @@ import (
 
 	"github.com/Shopify/sarama"
 
+	"github.com/dapr/components-contrib/internal/component/batching"
+	"github.com/dapr/components-contrib/internal/component/claimcheck"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/tracing"
 )
 
-func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int) (sarama.SyncProducer, error) {
+// Value of the messaging system attribute of spans.
+const tracingSystem = "kafka"
+
+func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int, transactionalID string) (sarama.SyncProducer, error) {
 	// Add SyncProducer specific properties to copy of base config
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 5
@@ -32,6 +38,13 @@ func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int
 		config.Producer.MaxMessageBytes = maxMessageBytes
 	}
 
+	if transactionalID != "" {
+		// Transactions require the idempotent producer, which in turn requires a single request in flight per broker
+		config.Producer.Idempotent = true
+		config.Producer.Transaction.ID = transactionalID
+		config.Net.MaxOpenRequests = 1
+	}
+
 	producer, err := sarama.NewSyncProducer(brokers, &config)
 	if err != nil {
 		return nil, err
@@ -41,19 +54,86 @@ func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int
 }
 
 // Publish message to Kafka cluster.
-func (k *Kafka) Publish(_ context.Context, topic string, data []byte, metadata map[string]string) error {
+// If batching is enabled, the message is sent together with other messages published to the same topic, unless the "skipBatching" metadata property is true.
+// If ctx contains a transaction, the message is published within it; otherwise, it's published outside of any transaction, also by transactional components.
+func (k *Kafka) Publish(ctx context.Context, topic string, data []byte, metadata map[string]string) (err error) {
 	if k.producer == nil {
 		return errors.New("component is closed")
 	}
 	// k.logger.Debugf("Publishing topic %v with data: %v", topic, string(data))
 	k.logger.Debugf("Publishing on topic %v", topic)
 
+	err = k.EnsureTopics(topic)
+	if err != nil {
+		return err
+	}
+
+	data, metadata = k.toBinaryCloudEvent(data, metadata)
+	// Payloads are encrypted before they're offloaded, so the claim-check store only holds ciphertext
+	encrypter, err := k.encryption.Get()
+	if err != nil {
+		return err
+	}
+	data, metadata, err = encrypter.Encrypt(ctx, data, metadata)
+	if err != nil {
+		return err
+	}
+	claimCheck, err := k.claimCheck.Get()
+	if err != nil {
+		return err
+	}
+	if claimCheck != nil {
+		data, metadata, err = claimCheck.Offload(ctx, data, metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	span, metadata := tracing.StartProducerSpan(ctx, k.tracer, tracingSystem, topic, metadata)
+	defer func() {
+		span.End(err)
+	}()
+
+	skipBatching := batching.SkipBatching(metadata) || TransactionFromContext(ctx) != nil
+	msg, size := newProducerMessage(topic, data, metadata)
+
+	if k.batcher != nil && !skipBatching {
+		err = k.batcher.Publish(ctx, topic, msg, size)
+	} else {
+		err = k.sendMessages(ctx, []*sarama.ProducerMessage{msg})
+	}
+	if err != nil {
+		k.discardClaimCheck(claimCheck, metadata)
+	}
+	return err
+}
+
+// discardClaimCheck deletes the offloaded payload of a message that could not be published.
+// If this fails, the payload is deleted when its TTL expires.
+func (k *Kafka) discardClaimCheck(claimCheck *claimcheck.Store, metadata map[string]string) {
+	if claimCheck == nil {
+		return
+	}
+	err := claimCheck.Discard(context.Background(), metadata)
+	if err != nil {
+		k.logger.Warnf("Failed to delete offloaded payload of unpublished message: %v", err)
+	}
+}
+
+// newProducerMessage returns the message to publish on topic, together with its size used for batching.
+// The "partitionKey" metadata property is used as key of the message, and other properties are sent as headers.
+func newProducerMessage(topic string, data []byte, metadata map[string]string) (*sarama.ProducerMessage, int) {
 	msg := &sarama.ProducerMessage{
 		Topic: topic,
 		Value: sarama.ByteEncoder(data),
 	}
 
+	size := len(data)
 	for name, value := range metadata {
+		size += len(name) + len(value)
+		if name == batching.SkipBatchingKey {
+			continue
+		}
 		if name == key {
 			msg.Key = sarama.StringEncoder(value)
 		} else {
@@ -67,29 +147,112 @@ func (k *Kafka) Publish(_ context.Context, topic string, data []byte, metadata m
 		}
 	}
 
-	partition, offset, err := k.producer.SendMessage(msg)
+	return msg, size
+}
 
-	k.logger.Debugf("Partition: %v, offset: %v", partition, offset)
+// sendMessages sends the messages within the transaction in ctx if any, or with the non-transactional producer otherwise.
+func (k *Kafka) sendMessages(ctx context.Context, msgs []*sarama.ProducerMessage) error {
+	send := func(producer sarama.SyncProducer) error {
+		if len(msgs) == 1 {
+			partition, offset, err := producer.SendMessage(msgs[0])
+			k.logger.Debugf("Partition: %v, offset: %v", partition, offset)
+			return err
+		}
+		return producer.SendMessages(msgs)
+	}
 
-	if err != nil {
-		return err
+	// The transactional producer can't be used outside of the transaction, which the caller may be waiting for
+	if txn := TransactionFromContext(ctx); txn != nil {
+		return send(txn.producer)
 	}
+	return send(k.producer)
+}
 
-	return nil
+// sendBatch sends a batch of messages coalesced by the batcher.
+func (k *Kafka) sendBatch(topic string, msgs []*sarama.ProducerMessage) []error {
+	k.logger.Debugf("Publishing batch of %d messages on topic %v", len(msgs), topic)
+
+	// The index of each message is used to map errors to messages
+	for i := range msgs {
+		msgs[i].Metadata = i
+	}
+
+	err := k.sendMessages(context.Background(), msgs)
+	if err == nil {
+		return nil
+	}
+
+	var pErrs sarama.ProducerErrors
+	if !errors.As(err, &pErrs) {
+		return batching.ErrorForAll(err, len(msgs))
+	}
+	errs := make([]error, len(msgs))
+	for _, pErr := range pErrs {
+		i, ok := pErr.Msg.Metadata.(int)
+		if !ok || i < 0 || i >= len(msgs) {
+			return batching.ErrorForAll(err, len(msgs))
+		}
+		errs[i] = pErr.Err
+	}
+	return errs
 }
 
-func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.BulkMessageEntry, metadata map[string]string) (pubsub.BulkPublishResponse, error) {
+func (k *Kafka) BulkPublish(ctx context.Context, topic string, entries []pubsub.BulkMessageEntry, metadata map[string]string) (_ pubsub.BulkPublishResponse, err error) {
 	if k.producer == nil {
 		err := errors.New("component is closed")
 		return pubsub.NewBulkPublishResponse(entries, err), err
 	}
 	k.logger.Debugf("Bulk Publishing on topic %v", topic)
 
+	if err := k.EnsureTopics(topic); err != nil {
+		return pubsub.NewBulkPublishResponse(entries, err), err
+	}
+
+	encrypter, err := k.encryption.Get()
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(entries, err), err
+	}
+	claimCheck, err := k.claimCheck.Get()
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(entries, err), err
+	}
+
+	// A single span is created for all messages, which share the metadata
+	span, metadata := tracing.StartProducerSpan(ctx, k.tracer, tracingSystem, topic, metadata)
+	defer func() {
+		span.End(err)
+	}()
+
 	msgs := []*sarama.ProducerMessage{}
+	// Metadata of the entries whose payload was offloaded, by entry ID
+	offloaded := map[string]map[string]string{}
 	for _, entry := range entries {
+		// Headers that carry the attributes of the entry, if it's a CloudEvent sent in binary mode
+		event, entryHeaders := k.toBinaryCloudEvent(entry.Event, nil)
+		event, entryHeaders, err = encrypter.Encrypt(ctx, event, entryHeaders)
+		if err != nil {
+			for _, md := range offloaded {
+				k.discardClaimCheck(claimCheck, md)
+			}
+			return pubsub.NewBulkPublishResponse(entries, err), err
+		}
+		if claimCheck != nil {
+			var entryMetadata map[string]string
+			event, entryMetadata, err = claimCheck.Offload(ctx, event, nil)
+			if err != nil {
+				for _, md := range offloaded {
+					k.discardClaimCheck(claimCheck, md)
+				}
+				return pubsub.NewBulkPublishResponse(entries, err), err
+			}
+			if entryMetadata != nil {
+				offloaded[entry.EntryId] = entryMetadata
+			}
+		}
+
 		msg := &sarama.ProducerMessage{
 			Topic: topic,
-			Value: sarama.ByteEncoder(entry.Event),
+			Value: sarama.ByteEncoder(event),
 		}
 		// From Sarama documentation
 		// This field is used to hold arbitrary data you wish to include so it
@@ -102,6 +265,9 @@ func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.Bu
 		msg.Metadata = entry.EntryId
 
 		for name, value := range metadata {
+			if _, ok := entryHeaders[name]; ok {
+				continue
+			}
 			if name == key {
 				msg.Key = sarama.StringEncoder(value)
 			} else {
@@ -114,12 +280,34 @@ func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.Bu
 				})
 			}
 		}
+		for name, value := range entryHeaders {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{
+				Key:   []byte(name),
+				Value: []byte(value),
+			})
+		}
+		if ref, ok := offloaded[entry.EntryId][claimcheck.MetadataKey]; ok {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{
+				Key:   []byte(claimcheck.MetadataKey),
+				Value: []byte(ref),
+			})
+		}
 		msgs = append(msgs, msg)
 	}
 
-	if err := k.producer.SendMessages(msgs); err != nil {
-		// map the returned error to different entries
-		return k.mapKafkaProducerErrors(err, entries), err
+	if err = k.sendMessages(ctx, msgs); err != nil {
+		var res pubsub.BulkPublishResponse
+		if TransactionFromContext(ctx) != nil {
+			// A failed transaction is aborted, so none of the messages was published
+			res = pubsub.NewBulkPublishResponse(entries, err)
+		} else {
+			// map the returned error to different entries
+			res = k.mapKafkaProducerErrors(err, entries)
+		}
+		for _, failed := range res.FailedEntries {
+			k.discardClaimCheck(claimCheck, offloaded[failed.EntryId])
+		}
+		return res, err
 	}
 
 	return pubsub.BulkPublishResponse{}, nil
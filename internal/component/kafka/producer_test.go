@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/batching"
+	"github.com/dapr/kit/logger"
+)
+
+// batchSyncProducer wraps the mock producer to report per-message failures the way sarama does when sending a batch.
+type batchSyncProducer struct {
+	*mocks.SyncProducer
+	failures map[int]error
+}
+
+func (p *batchSyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	err := p.SyncProducer.SendMessages(msgs)
+	if err != nil {
+		return err
+	}
+	var errs sarama.ProducerErrors
+	for i, msg := range msgs {
+		if p.failures[i] != nil {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: p.failures[i]})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func TestPublishWithBatching(t *testing.T) {
+	newKafka := func(t *testing.T, maxMessages int) (*Kafka, *batchSyncProducer) {
+		producer := &batchSyncProducer{
+			SyncProducer: mocks.NewSyncProducer(t, sarama.NewConfig()),
+			failures:     map[int]error{},
+		}
+		k := NewKafka(logger.NewLogger("test"))
+		k.producer = producer
+		k.batcher = batching.New(batching.Settings{
+			PublishBatchMaxDelay:    time.Hour,
+			PublishBatchMaxMessages: maxMessages,
+			PublishBatchMaxBytes:    batching.DefaultMaxBytes,
+		}, k.sendBatch)
+		return k, producer
+	}
+
+	t.Run("messages are sent in a batch", func(t *testing.T) {
+		k, producer := newKafka(t, 2)
+		var sent []*sarama.ProducerMessage
+		var lock sync.Mutex
+		checker := func(msg *sarama.ProducerMessage) error {
+			lock.Lock()
+			sent = append(sent, msg)
+			lock.Unlock()
+			return nil
+		}
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checker)
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checker)
+		producer.failures[1] = sarama.ErrMessageSizeTooLarge
+
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		for i := 0; i < 2; i++ {
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = k.Publish(context.Background(), "mytopic", []byte("hello"), map[string]string{
+					"partitionKey":           "key",
+					"myheader":               "value",
+					batching.SkipBatchingKey: "false",
+				})
+			}(i)
+			time.Sleep(5 * time.Millisecond)
+		}
+		wg.Wait()
+
+		require.Len(t, sent, 2)
+		assert.Equal(t, sarama.StringEncoder("key"), sent[0].Key)
+		require.Len(t, sent[0].Headers, 1)
+		assert.Equal(t, "myheader", string(sent[0].Headers[0].Key))
+
+		// Each publisher receives the result of its own message
+		assert.NoError(t, errs[0])
+		assert.True(t, errors.Is(errs[1], sarama.ErrMessageSizeTooLarge))
+	})
+
+	t.Run("skip batching", func(t *testing.T) {
+		k, producer := newKafka(t, 100)
+		producer.ExpectSendMessageAndSucceed()
+
+		// This would block for an hour if the message was batched
+		err := k.Publish(context.Background(), "mytopic", []byte("hello"), map[string]string{
+			batching.SkipBatchingKey: "true",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("close flushes pending messages", func(t *testing.T) {
+		k, producer := newKafka(t, 100)
+		producer.ExpectSendMessageAndSucceed()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- k.Publish(context.Background(), "mytopic", []byte("hello"), nil)
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		require.NoError(t, k.Close())
+		require.NoError(t, <-errCh)
+	})
+}
@@ -20,6 +20,8 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -126,3 +128,21 @@ func (ts *OAuthTokenSource) Token() (*sarama.AccessToken, error) {
 func (ts *OAuthTokenSource) asSaramaToken() *sarama.AccessToken {
 	return &(sarama.AccessToken{Token: ts.CachedToken.AccessToken, Extensions: ts.Extensions})
 }
+
+// fileTokenSource provides the access token read from a file, such as a token projected by the platform.
+// The file is read every time a token is needed, so rotated tokens are picked up.
+type fileTokenSource struct {
+	path string
+}
+
+func (ts *fileTokenSource) Token() (*sarama.AccessToken, error) {
+	data, err := os.ReadFile(ts.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading oauth token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("oauth token file '%s' is empty", ts.path)
+	}
+	return &sarama.AccessToken{Token: token}, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/ptr"
+)
+
+// seekTarget is a position that the subscription to a topic was moved to at runtime.
+type seekTarget struct {
+	startOffsetConfig
+	// If set, only this partition is moved.
+	partition *int32
+}
+
+func (t seekTarget) String() string {
+	var pos string
+	if t.mode == initialOffsetTimestamp {
+		pos = "timestamp " + t.timestamp.Format(time.RFC3339)
+	} else {
+		pos = "offset " + strconv.FormatInt(t.offset, 10)
+	}
+	if t.partition != nil {
+		return fmt.Sprintf("%s of partition %d", pos, *t.partition)
+	}
+	return pos
+}
+
+// Seek moves the subscription to a topic to a position in the stream, so the messages after it are delivered again.
+// The consumer group is restarted, like in Subscribe, and the offsets of the partitions claimed by this instance are reset in the new session.
+// Partitions claimed by other instances of the consumer group are not moved.
+func (k *Kafka) Seek(ctx context.Context, req *pubsub.SeekRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return fmt.Errorf("kafka: %w", err)
+	}
+	if k.consumerGroup == "" {
+		return errors.New("kafka: consumerGroup must be set to seek")
+	}
+
+	var target seekTarget
+	if req.Partition != "" {
+		partition, err := strconv.ParseInt(req.Partition, 10, 32)
+		if err != nil || partition < 0 {
+			return fmt.Errorf("kafka: invalid partition '%s'", req.Partition)
+		}
+		target.partition = ptr.Of(int32(partition))
+	}
+	if req.Offset != nil {
+		target.mode = initialOffsetOffset
+		target.offset = *req.Offset
+	} else {
+		target.mode = initialOffsetTimestamp
+		target.timestamp = *req.Timestamp
+	}
+
+	k.subscribeLock.Lock()
+	defer k.subscribeLock.Unlock()
+
+	if _, ok := k.subscribeTopics[req.Topic]; !ok {
+		return fmt.Errorf("kafka: not subscribed to topic %s", req.Topic)
+	}
+
+	k.seekedPartitionsLock.Lock()
+	if k.pendingSeeks == nil {
+		k.pendingSeeks = map[string]seekTarget{}
+	}
+	k.pendingSeeks[req.Topic] = target
+	k.seekedPartitionsLock.Unlock()
+
+	k.logger.Warnf("Moving the subscription to topic %s to %s; messages after it will be delivered again", req.Topic, target)
+
+	// Messages being processed are drained, and their offsets committed, before the offsets are reset
+	k.closeSubscriptionResources()
+	return k.startConsumer(ctx)
+}
+
+// applySeeks resets the offsets of the partitions claimed by the session, for the topics moved with Seek.
+// Each seek is applied once, to the first session after it.
+func (k *Kafka) applySeeks(session sarama.ConsumerGroupSession) error {
+	k.seekedPartitionsLock.Lock()
+	defer k.seekedPartitionsLock.Unlock()
+
+	if len(k.pendingSeeks) == 0 {
+		return nil
+	}
+
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return fmt.Errorf("kafka: failed to create client to seek: %w", err)
+	}
+	defer client.Close()
+
+	claims := session.Claims()
+	for topic, target := range k.pendingSeeks {
+		moved := 0
+		for _, partition := range claims[topic] {
+			if target.partition != nil && *target.partition != partition {
+				continue
+			}
+			offset, err := resolveOffset(client, topic, partition, target.startOffsetConfig)
+			if err != nil {
+				return err
+			}
+			k.logger.Infof("Seeking partition %s/%d to offset %d", topic, partition, offset)
+			session.ResetOffset(topic, partition, offset, "")
+			moved++
+		}
+		if moved == 0 {
+			k.logger.Warnf("No partition of topic %s was moved to %s, as they are not claimed by this instance", topic, target)
+		}
+		delete(k.pendingSeeks, topic)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestSeek(t *testing.T) {
+	ts := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("topic", 0, broker.BrokerID()).
+			SetLeader("topic", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("topic", 0, sarama.OffsetNewest, 100).
+			SetOffset("topic", 0, ts.UnixMilli(), 40).
+			SetOffset("topic", 1, sarama.OffsetOldest, 50).
+			SetOffset("topic", 1, sarama.OffsetNewest, 200).
+			SetOffset("topic", 1, ts.UnixMilli(), 120),
+	})
+
+	newKafka := func() *Kafka {
+		config := sarama.NewConfig()
+		config.Version = sarama.V2_0_0_0 //nolint:nosnakecase
+		return &Kafka{
+			logger:           logger.NewLogger("test"),
+			brokers:          []string{broker.Addr()},
+			consumerGroup:    "group",
+			config:           config,
+			seekedPartitions: map[string]bool{},
+			subscribeTopics:  TopicHandlerConfig{},
+		}
+	}
+	newSession := func() *seekSession {
+		return &seekSession{
+			claims: map[string][]int32{"topic": {0, 1}, "other": {0}},
+			resets: map[int32]int64{},
+		}
+	}
+
+	t.Run("no pending seeks", func(t *testing.T) {
+		session := newSession()
+		require.NoError(t, newKafka().applySeeks(session))
+		assert.Empty(t, session.resets)
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		k := newKafka()
+		k.pendingSeeks = map[string]seekTarget{
+			"topic": {startOffsetConfig: startOffsetConfig{mode: initialOffsetTimestamp, timestamp: ts}},
+		}
+		session := newSession()
+		require.NoError(t, k.applySeeks(session))
+		assert.Equal(t, map[int32]int64{0: 40, 1: 120}, session.resets)
+
+		// Seeks are applied once
+		session = newSession()
+		require.NoError(t, k.applySeeks(session))
+		assert.Empty(t, session.resets)
+	})
+
+	t.Run("offset of a partition", func(t *testing.T) {
+		k := newKafka()
+		k.pendingSeeks = map[string]seekTarget{
+			"topic": {startOffsetConfig: startOffsetConfig{mode: initialOffsetOffset, offset: 10}, partition: ptr.Of(int32(1))},
+		}
+		session := newSession()
+		require.NoError(t, k.applySeeks(session))
+		// The offset is clamped to the available range
+		assert.Equal(t, map[int32]int64{1: 50}, session.resets)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		k := newKafka()
+		k.subscribeTopics["topic"] = SubscriptionHandlerConfig{}
+		for _, req := range []*pubsub.SeekRequest{
+			{Topic: "topic", Offset: ptr.Of(int64(10))},
+			{Topic: "topic", Offset: ptr.Of(int64(10)), Partition: "a", Confirm: true},
+			{Topic: "unknown", Offset: ptr.Of(int64(10)), Confirm: true},
+		} {
+			require.Error(t, k.Seek(context.Background(), req), req)
+		}
+		assert.Empty(t, k.pendingSeeks)
+	})
+}
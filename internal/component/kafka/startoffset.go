@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+const (
+	// Values for "initialOffset" that start consuming from a point in time or from a specific offset.
+	initialOffsetTimestamp = "timestamp"
+	initialOffsetOffset    = "offset"
+)
+
+// startOffsetConfig contains the configuration for starting to consume from a point in time or from a specific offset.
+type startOffsetConfig struct {
+	// Either initialOffsetTimestamp or initialOffsetOffset; empty if not enabled.
+	mode      string
+	timestamp time.Time
+	offset    int64
+	// If true, the offsets are reset even if the consumer group has committed offsets.
+	force bool
+}
+
+// seekStartOffsets resets the offsets of the partitions claimed by the session to the configured start offset.
+// Unless forced, only partitions without a committed offset are reset.
+// When forced, each partition is reset only once during the lifetime of the component, so consumer group rebalances don't cause messages to be consumed again.
+func (k *Kafka) seekStartOffsets(session sarama.ConsumerGroupSession) error {
+	if k.startOffset.mode == "" {
+		return nil
+	}
+
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return fmt.Errorf("kafka: failed to create client to seek to the start offset: %w", err)
+	}
+	defer client.Close()
+
+	claims := session.Claims()
+	var committed *sarama.OffsetFetchResponse
+	if !k.startOffset.force {
+		// The admin client shares the connection with client, so it doesn't need to be closed separately
+		admin, err := sarama.NewClusterAdminFromClient(client)
+		if err != nil {
+			return fmt.Errorf("kafka: failed to create admin client to seek to the start offset: %w", err)
+		}
+		committed, err = admin.ListConsumerGroupOffsets(k.consumerGroup, claims)
+		if err != nil {
+			return fmt.Errorf("kafka: failed to list committed offsets of consumer group %s: %w", k.consumerGroup, err)
+		}
+	}
+
+	k.seekedPartitionsLock.Lock()
+	defer k.seekedPartitionsLock.Unlock()
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			partitionKey := topic + "/" + strconv.FormatInt(int64(partition), 10)
+			if k.startOffset.force {
+				if k.seekedPartitions[partitionKey] {
+					continue
+				}
+			} else if block := committed.GetBlock(topic, partition); block != nil && block.Err == sarama.ErrNoError && block.Offset >= 0 {
+				continue
+			}
+
+			offset, err := resolveOffset(client, topic, partition, k.startOffset)
+			if err != nil {
+				return err
+			}
+			k.logger.Infof("Starting to consume partition %s from offset %d", partitionKey, offset)
+			session.ResetOffset(topic, partition, offset, "")
+			k.seekedPartitions[partitionKey] = true
+		}
+	}
+
+	return nil
+}
+
+// resolveOffset returns the offset of a partition to start consuming from.
+func resolveOffset(client sarama.Client, topic string, partition int32, cfg startOffsetConfig) (int64, error) {
+	newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, fmt.Errorf("kafka: failed to get the newest offset of %s/%d: %w", topic, partition, err)
+	}
+
+	switch cfg.mode {
+	case initialOffsetTimestamp:
+		// This returns the earliest offset whose timestamp is greater than or equal to the given one, or -1 if there's none
+		offset, err := client.GetOffset(topic, partition, cfg.timestamp.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("kafka: failed to get the offset of %s/%d at %s: %w", topic, partition, cfg.timestamp.Format(time.RFC3339), err)
+		}
+		if offset < 0 {
+			return newest, nil
+		}
+		return offset, nil
+	default:
+		oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return 0, fmt.Errorf("kafka: failed to get the oldest offset of %s/%d: %w", topic, partition, err)
+		}
+		// Clamp the offset to the range that's available in the partition
+		switch {
+		case cfg.offset < oldest:
+			return oldest, nil
+		case cfg.offset > newest:
+			return newest, nil
+		default:
+			return cfg.offset, nil
+		}
+	}
+}
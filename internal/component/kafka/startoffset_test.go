@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+type seekSession struct {
+	fakeSession
+	claims map[string][]int32
+	resets map[int32]int64
+}
+
+func (s *seekSession) Claims() map[string][]int32 {
+	return s.claims
+}
+
+func (s *seekSession) ResetOffset(topic string, partition int32, offset int64, _ string) {
+	s.resets[partition] = offset
+}
+
+func TestSeekStartOffsets(t *testing.T) {
+	ts := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("topic", 0, broker.BrokerID()).
+			SetLeader("topic", 1, broker.BrokerID()).
+			SetLeader("topic", 2, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("topic", 0, sarama.OffsetNewest, 100).
+			SetOffset("topic", 0, ts.UnixMilli(), 40).
+			SetOffset("topic", 1, sarama.OffsetOldest, 50).
+			SetOffset("topic", 1, sarama.OffsetNewest, 200).
+			SetOffset("topic", 1, ts.UnixMilli(), -1).
+			SetOffset("topic", 2, sarama.OffsetOldest, 0).
+			SetOffset("topic", 2, sarama.OffsetNewest, 10).
+			SetOffset("topic", 2, ts.UnixMilli(), 5),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "group", broker),
+		// Partition 2 has a committed offset
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("group", "topic", 0, -1, "", sarama.ErrNoError).
+			SetOffset("group", "topic", 1, -1, "", sarama.ErrNoError).
+			SetOffset("group", "topic", 2, 7, "", sarama.ErrNoError),
+	})
+
+	newKafka := func(cfg startOffsetConfig) *Kafka {
+		config := sarama.NewConfig()
+		config.Version = sarama.V2_0_0_0 //nolint:nosnakecase
+		return &Kafka{
+			logger:           logger.NewLogger("test"),
+			brokers:          []string{broker.Addr()},
+			consumerGroup:    "group",
+			config:           config,
+			startOffset:      cfg,
+			seekedPartitions: map[string]bool{},
+		}
+	}
+	newSession := func() *seekSession {
+		return &seekSession{
+			claims: map[string][]int32{"topic": {0, 1, 2}},
+			resets: map[int32]int64{},
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		session := newSession()
+		require.NoError(t, newKafka(startOffsetConfig{}).seekStartOffsets(session))
+		assert.Empty(t, session.resets)
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		session := newSession()
+		require.NoError(t, newKafka(startOffsetConfig{mode: initialOffsetTimestamp, timestamp: ts}).seekStartOffsets(session))
+		// Partition 1 has no messages after the timestamp, so it starts from the newest offset
+		assert.Equal(t, map[int32]int64{0: 40, 1: 200}, session.resets)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		session := newSession()
+		require.NoError(t, newKafka(startOffsetConfig{mode: initialOffsetOffset, offset: 20}).seekStartOffsets(session))
+		// The offset is clamped to the available range
+		assert.Equal(t, map[int32]int64{0: 20, 1: 50}, session.resets)
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		k := newKafka(startOffsetConfig{mode: initialOffsetTimestamp, timestamp: ts, force: true})
+		session := newSession()
+		require.NoError(t, k.seekStartOffsets(session))
+		assert.Equal(t, map[int32]int64{0: 40, 1: 200, 2: 5}, session.resets)
+
+		// Partitions are reset only once
+		session = newSession()
+		require.NoError(t, k.seekStartOffsets(session))
+		assert.Empty(t, session.resets)
+	})
+}
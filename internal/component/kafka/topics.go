@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+const (
+	defaultTopicPartitions        = 1
+	defaultTopicReplicationFactor = 1
+)
+
+// topicPolicy contains the configuration for creating topics that are missing.
+type topicPolicy struct {
+	// If true, missing topics are created with the given number of partitions and replication factor.
+	create            bool
+	partitions        int32
+	replicationFactor int16
+	// If true, publishing and subscribing fail when a topic is missing.
+	failIfMissing bool
+}
+
+// enabled returns true if topics need to be checked before they are used.
+// When it's false, topics are auto-created by the broker, if it's configured to do so.
+func (p topicPolicy) enabled() bool {
+	return p.create || p.failIfMissing
+}
+
+// EnsureTopics makes sure the topics exist, according to the topic creation policy.
+// Topics that don't exist are created, or an error is returned if "failIfTopicMissing" is set.
+// Existing topics are never changed, as changing their number of partitions would change the partition of each key; a warning is logged if their number of partitions or replication factor differs from the configured one.
+// Each topic is checked only once during the lifetime of the component.
+func (k *Kafka) EnsureTopics(topics ...string) error {
+	if !k.topicPolicy.enabled() || len(topics) == 0 {
+		return nil
+	}
+
+	k.ensuredTopicsLock.Lock()
+	defer k.ensuredTopicsLock.Unlock()
+
+	check := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if !k.ensuredTopics[topic] {
+			check = append(check, topic)
+		}
+	}
+	if len(check) == 0 {
+		return nil
+	}
+
+	admin, err := k.newClusterAdmin()
+	if err != nil {
+		return fmt.Errorf("kafka: failed to create admin client to check topics: %w", err)
+	}
+	defer admin.Close()
+
+	details, err := admin.DescribeTopics(check)
+	if err != nil {
+		return fmt.Errorf("kafka: failed to describe topics %v: %w", check, err)
+	}
+
+	for _, detail := range details {
+		switch {
+		case errors.Is(detail.Err, sarama.ErrNoError):
+			k.checkTopicConfig(detail)
+		case errors.Is(detail.Err, sarama.ErrUnknownTopicOrPartition):
+			err = k.createTopic(admin, detail.Name)
+		default:
+			err = fmt.Errorf("kafka: failed to describe topic %s: %w", detail.Name, detail.Err)
+		}
+		if err != nil {
+			return err
+		}
+		k.ensuredTopics[detail.Name] = true
+	}
+
+	return nil
+}
+
+func (k *Kafka) createTopic(admin sarama.ClusterAdmin, topic string) error {
+	if !k.topicPolicy.create {
+		return fmt.Errorf("kafka: topic %s does not exist", topic)
+	}
+
+	k.logger.Infof("Creating topic %s with %d partitions and replication factor %d", topic, k.topicPolicy.partitions, k.topicPolicy.replicationFactor)
+	err := admin.CreateTopic(topic, &sarama.TopicDetail{
+		NumPartitions:     k.topicPolicy.partitions,
+		ReplicationFactor: k.topicPolicy.replicationFactor,
+	}, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("kafka: failed to create topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// checkTopicConfig logs a warning if the number of partitions or the replication factor of an existing topic differs from the configured one.
+func (k *Kafka) checkTopicConfig(detail *sarama.TopicMetadata) {
+	if !k.topicPolicy.create {
+		return
+	}
+
+	partitions := len(detail.Partitions)
+	replicationFactor := 0
+	if partitions > 0 {
+		replicationFactor = len(detail.Partitions[0].Replicas)
+	}
+	if partitions != int(k.topicPolicy.partitions) || replicationFactor != int(k.topicPolicy.replicationFactor) {
+		k.logger.Warnf("Topic %s has %d partitions and replication factor %d, instead of the configured %d partitions and replication factor %d; existing topics are not changed", detail.Name, partitions, replicationFactor, k.topicPolicy.partitions, k.topicPolicy.replicationFactor)
+	}
+}
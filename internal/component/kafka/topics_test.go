@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+// fakeAdmin is a cluster admin that keeps the number of partitions of each topic in memory.
+// Methods that change existing topics are not implemented, and panic if called.
+type fakeAdmin struct {
+	sarama.ClusterAdmin
+	partitions       map[string]int32
+	created          map[string]*sarama.TopicDetail
+	describeRequests int
+}
+
+func newFakeAdmin(partitions map[string]int32) *fakeAdmin {
+	return &fakeAdmin{
+		partitions: partitions,
+		created:    map[string]*sarama.TopicDetail{},
+	}
+}
+
+func (a *fakeAdmin) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	a.describeRequests++
+	res := make([]*sarama.TopicMetadata, len(topics))
+	for i, topic := range topics {
+		res[i] = &sarama.TopicMetadata{Name: topic, Err: sarama.ErrUnknownTopicOrPartition}
+		if n, ok := a.partitions[topic]; ok {
+			res[i].Err = sarama.ErrNoError
+			res[i].Partitions = make([]*sarama.PartitionMetadata, n)
+			for j := range res[i].Partitions {
+				res[i].Partitions[j] = &sarama.PartitionMetadata{ID: int32(j), Replicas: []int32{1}}
+			}
+		}
+	}
+	return res, nil
+}
+
+func (a *fakeAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, _ bool) error {
+	a.created[topic] = detail
+	a.partitions[topic] = detail.NumPartitions
+	return nil
+}
+
+func (a *fakeAdmin) Close() error {
+	return nil
+}
+
+func newTopicsTestKafka(policy topicPolicy, admin *fakeAdmin) *Kafka {
+	return &Kafka{
+		logger:        logger.NewLogger("kafka_test"),
+		topicPolicy:   policy,
+		ensuredTopics: map[string]bool{},
+		newClusterAdmin: func() (sarama.ClusterAdmin, error) {
+			return admin, nil
+		},
+	}
+}
+
+func TestEnsureTopics(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		admin := newFakeAdmin(map[string]int32{})
+		k := newTopicsTestKafka(topicPolicy{}, admin)
+		require.NoError(t, k.EnsureTopics("missing"))
+		assert.Equal(t, 0, admin.describeRequests)
+	})
+
+	t.Run("create missing topics", func(t *testing.T) {
+		admin := newFakeAdmin(map[string]int32{"existing": 3})
+		k := newTopicsTestKafka(topicPolicy{create: true, partitions: 3, replicationFactor: 2}, admin)
+
+		require.NoError(t, k.EnsureTopics("existing", "missing"))
+		assert.Equal(t, map[string]*sarama.TopicDetail{
+			"missing": {NumPartitions: 3, ReplicationFactor: 2},
+		}, admin.created)
+
+		// Topics are checked only once
+		require.NoError(t, k.EnsureTopics("missing"))
+		assert.Equal(t, 1, admin.describeRequests)
+	})
+
+	t.Run("existing topics are not changed", func(t *testing.T) {
+		admin := newFakeAdmin(map[string]int32{"small": 1, "large": 8})
+		k := newTopicsTestKafka(topicPolicy{create: true, partitions: 4, replicationFactor: 1}, admin)
+
+		require.NoError(t, k.EnsureTopics("small", "large"))
+		assert.Equal(t, map[string]int32{"small": 1, "large": 8}, admin.partitions)
+		assert.Empty(t, admin.created)
+	})
+
+	t.Run("fail if missing", func(t *testing.T) {
+		admin := newFakeAdmin(map[string]int32{"existing": 1})
+		k := newTopicsTestKafka(topicPolicy{failIfMissing: true, partitions: 4, replicationFactor: 1}, admin)
+
+		require.NoError(t, k.EnsureTopics("existing"))
+		err := k.EnsureTopics("missing")
+		require.ErrorContains(t, err, "topic missing does not exist")
+		assert.Empty(t, admin.created)
+
+		// Missing topics are checked again
+		require.Error(t, k.EnsureTopics("missing"))
+		assert.Equal(t, 3, admin.describeRequests)
+	})
+}
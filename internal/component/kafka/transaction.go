@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// Transaction is a transaction of the transactional producer, enabled with the "transactionalId" metadata property.
+// Messages published and consumed messages added within a transaction are committed atomically: either all output messages become visible and the offsets of the consumed messages are committed, or none is.
+// Only messages published with the transaction, or with a context that contains it, are part of it: other messages are sent by a separate, non-transactional producer.
+type Transaction struct {
+	k        *Kafka
+	producer sarama.SyncProducer
+}
+
+type transactionContextKey struct{}
+
+// TransactionFromContext returns the transaction in the context, or nil.
+// When a message is consumed by a transactional component, the context passed to the handler contains the transaction the offset of the message is committed in; messages published with that context, including with Publish and BulkPublish, are part of the same transaction.
+// Handlers run within the transaction, so a transactional component handles one message at a time.
+func TransactionFromContext(ctx context.Context) *Transaction {
+	txn, _ := ctx.Value(transactionContextKey{}).(*Transaction)
+	return txn
+}
+
+// Publish publishes a message within the transaction.
+// The message is published like with the Publish method of the component, including encryption, claim check and tracing.
+func (t *Transaction) Publish(ctx context.Context, topic string, data []byte, metadata map[string]string) error {
+	return t.k.Publish(context.WithValue(ctx, transactionContextKey{}, t), topic, data, metadata)
+}
+
+// addMessage adds the offset of a consumed message to the transaction, so it's committed for the consumer group together with the transaction.
+func (t *Transaction) addMessage(message *sarama.ConsumerMessage) error {
+	return t.producer.AddMessageToTxn(message, t.k.consumerGroup, nil)
+}
+
+// IsTransactional returns true if the component was configured with a "transactionalId".
+func (k *Kafka) IsTransactional() bool {
+	return k.transactionalID != ""
+}
+
+// RunInTransaction runs fn within a new transaction, which is committed if fn returns nil and aborted otherwise.
+// If a transaction is already in ctx, fn runs within it instead.
+// A transactional producer runs a single transaction at a time, so concurrent invocations wait for each other.
+func (k *Kafka) RunInTransaction(ctx context.Context, fn func(ctx context.Context, txn *Transaction) error) error {
+	if !k.IsTransactional() {
+		return errors.New("kafka error: transactions require the 'transactionalId' metadata property")
+	}
+	if txn := TransactionFromContext(ctx); txn != nil {
+		return fn(ctx, txn)
+	}
+
+	k.txnLock.Lock()
+	defer k.txnLock.Unlock()
+
+	if k.txnProducer == nil {
+		return errors.New("component is closed")
+	}
+
+	err := k.txnProducer.BeginTxn()
+	if err != nil {
+		k.recoverTransaction(err)
+		return fmt.Errorf("kafka error: failed to begin transaction: %w", err)
+	}
+
+	txn := &Transaction{k: k, producer: k.txnProducer}
+	err = fn(context.WithValue(ctx, transactionContextKey{}, txn), txn)
+	if err != nil {
+		k.recoverTransaction(err)
+		return err
+	}
+
+	err = k.txnProducer.CommitTxn()
+	if err != nil {
+		k.recoverTransaction(err)
+		return fmt.Errorf("kafka error: failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// recoverTransaction brings the producer back to a state where it can begin a new transaction after err.
+// Open transactions are aborted. If the producer was fenced by another producer with the same transactional ID, or it's in a fatal state, it's replaced with a new producer, which fences any zombie instance in turn.
+// It must be called with txnLock held.
+func (k *Kafka) recoverTransaction(err error) {
+	status := k.txnProducer.TxnStatus()
+	if !errors.Is(err, sarama.ErrProducerFenced) && status&sarama.ProducerTxnFlagFatalError == 0 {
+		if status&(sarama.ProducerTxnFlagInTransaction|sarama.ProducerTxnFlagAbortableError) != 0 {
+			abortErr := k.txnProducer.AbortTxn()
+			if abortErr == nil {
+				return
+			}
+			k.logger.Warnf("Failed to abort Kafka transaction: %v", abortErr)
+		} else {
+			return
+		}
+	}
+
+	k.logger.Warnf("Reinitializing the transactional Kafka producer after error: %v", err)
+	closeErr := k.txnProducer.Close()
+	if closeErr != nil {
+		k.logger.Debugf("Error closing the transactional Kafka producer: %v", closeErr)
+	}
+	producer, newErr := k.newSyncProducer(k.transactionalID)
+	if newErr != nil {
+		// Keep the closed producer, so the next transaction fails and tries again
+		k.logger.Errorf("Failed to reinitialize the transactional Kafka producer: %v", newErr)
+		return
+	}
+	k.txnProducer = producer
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+// txnSyncProducer wraps the mock producer to record the outcome of transactions.
+type txnSyncProducer struct {
+	*mocks.SyncProducer
+	commitErr error
+	commits   int
+	aborts    int
+	added     []*sarama.ConsumerMessage
+	closed    bool
+}
+
+func (p *txnSyncProducer) CommitTxn() error {
+	if p.commitErr != nil {
+		return p.commitErr
+	}
+	p.commits++
+	return p.SyncProducer.CommitTxn()
+}
+
+func (p *txnSyncProducer) AbortTxn() error {
+	p.aborts++
+	return p.SyncProducer.AbortTxn()
+}
+
+func (p *txnSyncProducer) AddMessageToTxn(msg *sarama.ConsumerMessage, groupID string, metadata *string) error {
+	p.added = append(p.added, msg)
+	return p.SyncProducer.AddMessageToTxn(msg, groupID, metadata)
+}
+
+func (p *txnSyncProducer) Close() error {
+	p.closed = true
+	return p.SyncProducer.Close()
+}
+
+// newTransactionalKafka returns a transactional component, and the transactional producers it created.
+// k.producer is the non-transactional producer, a *mocks.SyncProducer.
+func newTransactionalKafka(t *testing.T) (*Kafka, *[]*txnSyncProducer) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0 //nolint:nosnakecase
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	producers := []*txnSyncProducer{}
+	k := NewKafka(logger.NewLogger("test"))
+	k.transactionalID = "txn"
+	k.consumerGroup = "group"
+	k.ensuredTopics = map[string]bool{"out": true}
+	k.newSyncProducer = func(transactionalID string) (sarama.SyncProducer, error) {
+		if transactionalID == "" {
+			return mocks.NewSyncProducer(t, config), nil
+		}
+		txnConfig := *config
+		txnConfig.Producer.Idempotent = true
+		txnConfig.Producer.Transaction.ID = transactionalID
+		txnConfig.Net.MaxOpenRequests = 1
+		p := &txnSyncProducer{SyncProducer: mocks.NewSyncProducer(t, &txnConfig)}
+		producers = append(producers, p)
+		return p, nil
+	}
+	var err error
+	k.producer, err = k.newSyncProducer("")
+	require.NoError(t, err)
+	k.txnProducer, err = k.newSyncProducer(k.transactionalID)
+	require.NoError(t, err)
+	return k, &producers
+}
+
+func TestRunInTransaction(t *testing.T) {
+	t.Run("commits when the function succeeds", func(t *testing.T) {
+		k, producers := newTransactionalKafka(t)
+		p := (*producers)[0]
+		p.ExpectSendMessageAndSucceed()
+
+		err := k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+			assert.Same(t, txn, TransactionFromContext(ctx))
+			return txn.Publish(ctx, "out", []byte("hello"), nil)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, p.commits)
+		assert.Equal(t, 0, p.aborts)
+	})
+
+	t.Run("aborts when the function fails", func(t *testing.T) {
+		k, producers := newTransactionalKafka(t)
+		p := (*producers)[0]
+
+		err := k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+			return errors.New("handler failed")
+		})
+		require.EqualError(t, err, "handler failed")
+		assert.Equal(t, 0, p.commits)
+		assert.Equal(t, 1, p.aborts)
+		assert.Len(t, *producers, 1)
+	})
+
+	t.Run("reinitializes the producer when fenced", func(t *testing.T) {
+		k, producers := newTransactionalKafka(t)
+		p := (*producers)[0]
+		p.commitErr = sarama.ErrProducerFenced
+
+		err := k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+			return nil
+		})
+		require.ErrorIs(t, err, sarama.ErrProducerFenced)
+		assert.True(t, p.closed)
+		require.Len(t, *producers, 2)
+		assert.Same(t, (*producers)[1], k.txnProducer)
+
+		// The new producer is used for the next transaction
+		err = k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, (*producers)[1].commits)
+	})
+
+	t.Run("fails if the component is not transactional", func(t *testing.T) {
+		k := NewKafka(logger.NewLogger("test"))
+		err := k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+			return nil
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestTransactionalPublish(t *testing.T) {
+	t.Run("publishes outside of transactions with the non-transactional producer", func(t *testing.T) {
+		k, producers := newTransactionalKafka(t)
+		k.producer.(*mocks.SyncProducer).ExpectSendMessageAndSucceed()
+
+		err := k.Publish(context.Background(), "out", []byte("hello"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, (*producers)[0].commits)
+	})
+
+	t.Run("publishes within the transaction in the context", func(t *testing.T) {
+		k, producers := newTransactionalKafka(t)
+		p := (*producers)[0]
+		p.ExpectSendMessageAndSucceed()
+		p.ExpectSendMessageAndSucceed()
+
+		err := k.RunInTransaction(context.Background(), func(ctx context.Context, txn *Transaction) error {
+			err := k.Publish(ctx, "out", []byte("a"), nil)
+			if err != nil {
+				return err
+			}
+			return k.Publish(ctx, "out", []byte("b"), nil)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, p.commits)
+	})
+}
+
+func TestTransactionalConsume(t *testing.T) {
+	k, producers := newTransactionalKafka(t)
+	p := (*producers)[0]
+	p.ExpectSendMessageAndSucceed()
+
+	k.AddTopicHandler("in", SubscriptionHandlerConfig{
+		Handler: func(ctx context.Context, msg *NewEvent) error {
+			return k.Publish(ctx, "out", msg.Data, nil)
+		},
+	})
+	c := consumer{k: k}
+	session := &fakeSession{}
+	message := &sarama.ConsumerMessage{Topic: "in", Partition: 1, Offset: 5, Value: []byte("hello")}
+
+	err := c.doCallback(session, message)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.commits)
+	assert.Equal(t, []*sarama.ConsumerMessage{message}, p.added)
+	assert.Equal(t, []int64{5}, session.marked)
+}
+
+func TestTransactionalConsumePublishOutsideTransaction(t *testing.T) {
+	k, producers := newTransactionalKafka(t)
+	p := (*producers)[0]
+	k.producer.(*mocks.SyncProducer).ExpectSendMessageAndSucceed()
+
+	// Messages published without the context of the handler aren't part of its transaction, and don't wait for it
+	k.AddTopicHandler("in", SubscriptionHandlerConfig{
+		Handler: func(ctx context.Context, msg *NewEvent) error {
+			return k.Publish(context.Background(), "out", msg.Data, nil)
+		},
+	})
+	c := consumer{k: k}
+	session := &fakeSession{}
+	message := &sarama.ConsumerMessage{Topic: "in", Partition: 1, Offset: 5, Value: []byte("hello")}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.doCallback(session, message)
+	}()
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler blocked publishing outside of the transaction")
+	}
+	assert.Equal(t, 1, p.commits)
+	assert.Equal(t, []*sarama.ConsumerMessage{message}, p.added)
+	assert.Equal(t, []int64{5}, session.marked)
+}
+
+func TestTransactionalIDMetadata(t *testing.T) {
+	k := getKafka()
+	m := getCompleteMetadata()
+	m["transactionalId"] = "ledger"
+	meta, err := k.getKafkaMetadata(m)
+	require.NoError(t, err)
+	assert.Equal(t, "ledger", meta.TransactionalID)
+
+	m["version"] = "0.10.2.0"
+	_, err = k.getKafkaMetadata(m)
+	require.ErrorContains(t, err, "transactionalId")
+}
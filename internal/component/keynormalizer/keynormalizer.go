@@ -0,0 +1,297 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package keynormalizer contains the normalization of the keys of requests that state stores can apply, so keys produced by different apps with inconsistent casing or separators refer to the same values.
+package keynormalizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+const (
+	// StepLowercase converts keys to lowercase.
+	StepLowercase = "lowercase"
+	// StepTrim removes leading and trailing whitespace from keys.
+	StepTrim = "trim"
+	// StepReplaceSeparators replaces the separator characters in keys with the replacement.
+	StepReplaceSeparators = "replaceSeparators"
+
+	// DefaultSeparators are the characters replaced by the "replaceSeparators" step if keyNormalizationSeparators is not set.
+	DefaultSeparators = "_.:/"
+	// DefaultReplacement is the string that replaces separators if keyNormalizationReplacement is not set.
+	DefaultReplacement = "-"
+
+	// Separator of the prefix that Dapr adds to keys, such as the app ID, which is not normalized
+	prefixSeparator = "||"
+)
+
+// Metadata contains the metadata properties of the normalization of keys.
+// Components embed it with `mapstructure:",squash"`.
+type Metadata struct {
+	// Comma-separated steps applied to keys: "lowercase", "trim" and "replaceSeparators". Empty (the default) to leave keys unchanged.
+	KeyNormalization string `mapstructure:"keyNormalization"`
+	// Characters replaced by the "replaceSeparators" step.
+	KeyNormalizationSeparators string `mapstructure:"keyNormalizationSeparators"`
+	// String that replaces each separator character in the "replaceSeparators" step.
+	KeyNormalizationReplacement string `mapstructure:"keyNormalizationReplacement"`
+}
+
+// Normalizer normalizes the keys of requests.
+// The zero value leaves keys unchanged.
+type Normalizer struct {
+	fn state.KeyNormalizeFn
+}
+
+// New returns the Normalizer configured with the metadata.
+// The steps are applied in a fixed order, so normalized keys are normalized to themselves: trim, replaceSeparators, then lowercase.
+func New(m Metadata) (Normalizer, error) {
+	var trim, replace, lowercase bool
+	for _, step := range strings.Split(m.KeyNormalization, ",") {
+		switch strings.TrimSpace(step) {
+		case "":
+		case StepTrim:
+			trim = true
+		case StepReplaceSeparators:
+			replace = true
+		case StepLowercase:
+			lowercase = true
+		default:
+			return Normalizer{}, fmt.Errorf("invalid value for 'keyNormalization': '%s' is not one of '%s', '%s', '%s'", step, StepLowercase, StepTrim, StepReplaceSeparators)
+		}
+	}
+
+	var replacer *strings.Replacer
+	if replace {
+		separators := m.KeyNormalizationSeparators
+		if separators == "" {
+			separators = DefaultSeparators
+		}
+		replacement := m.KeyNormalizationReplacement
+		if replacement == "" {
+			replacement = DefaultReplacement
+		}
+		if strings.IndexFunc(separators, func(r rune) bool {
+			return r == '|' || unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) >= 0 {
+			return Normalizer{}, errors.New("invalid value for 'keyNormalizationSeparators': separators can't be letters, digits or '|'")
+		}
+		if strings.ContainsAny(replacement, separators+"|") || strings.IndexFunc(replacement, unicode.IsSpace) >= 0 {
+			return Normalizer{}, errors.New("invalid value for 'keyNormalizationReplacement': the replacement can't contain separators, whitespace or '|'")
+		}
+		oldnew := make([]string, 0, 2*len(separators))
+		for _, r := range separators {
+			oldnew = append(oldnew, string(r), replacement)
+		}
+		replacer = strings.NewReplacer(oldnew...)
+	}
+
+	if !trim && replacer == nil && !lowercase {
+		return Normalizer{}, nil
+	}
+	return FromFunc(func(key string) string {
+		if trim {
+			key = strings.TrimSpace(key)
+		}
+		if replacer != nil {
+			key = replacer.Replace(key)
+		}
+		if lowercase {
+			key = strings.ToLower(key)
+		}
+		return key
+	}), nil
+}
+
+// FromFunc returns a Normalizer that normalizes keys with a custom function.
+// A nil function leaves keys unchanged.
+func FromFunc(fn state.KeyNormalizeFn) Normalizer {
+	return Normalizer{fn: fn}
+}
+
+// Enabled returns true if the Normalizer changes keys.
+func (n Normalizer) Enabled() bool {
+	return n.fn != nil
+}
+
+// Key returns the normalized key.
+// The prefix added by Dapr, up to the last "||", is left unchanged.
+func (n Normalizer) Key(key string) string {
+	if n.fn == nil {
+		return key
+	}
+	i := strings.LastIndex(key, prefixSeparator)
+	if i < 0 {
+		return n.fn(key)
+	}
+	i += len(prefixSeparator)
+	return key[:i] + n.fn(key[i:])
+}
+
+// GetRequest returns a copy of the request with the normalized key.
+func (n Normalizer) GetRequest(req *state.GetRequest) *state.GetRequest {
+	if n.fn == nil {
+		return req
+	}
+	r := *req
+	r.Key = n.Key(r.Key)
+	return &r
+}
+
+// SetRequest returns a copy of the request with the normalized key.
+func (n Normalizer) SetRequest(req *state.SetRequest) *state.SetRequest {
+	if n.fn == nil {
+		return req
+	}
+	r := *req
+	r.Key = n.Key(r.Key)
+	return &r
+}
+
+// DeleteRequest returns a copy of the request with the normalized key.
+func (n Normalizer) DeleteRequest(req *state.DeleteRequest) *state.DeleteRequest {
+	if n.fn == nil {
+		return req
+	}
+	r := *req
+	r.Key = n.Key(r.Key)
+	return &r
+}
+
+// TouchRequest returns a copy of the request with the normalized key.
+func (n Normalizer) TouchRequest(req *state.TouchRequest) *state.TouchRequest {
+	if n.fn == nil {
+		return req
+	}
+	r := *req
+	r.Key = n.Key(r.Key)
+	return &r
+}
+
+// ListKeysRequest returns a copy of the request with the normalized prefix, so the keys that start with the prefix before normalization are listed.
+// The keys in the response are normalized.
+func (n Normalizer) ListKeysRequest(req *state.ListKeysRequest) *state.ListKeysRequest {
+	if n.fn == nil || req.Prefix == "" {
+		return req
+	}
+	r := *req
+	r.Prefix = n.Key(r.Prefix)
+	return &r
+}
+
+// GetRequests returns a copy of the requests with the normalized keys.
+// The keys of the responses can be restored with RestoreKeys.
+func (n Normalizer) GetRequests(req []state.GetRequest) []state.GetRequest {
+	if n.fn == nil {
+		return req
+	}
+	res := make([]state.GetRequest, len(req))
+	for i := range req {
+		res[i] = req[i]
+		res[i].Key = n.Key(req[i].Key)
+	}
+	return res
+}
+
+// SetRequests returns a copy of the requests with the normalized keys.
+func (n Normalizer) SetRequests(req []state.SetRequest) []state.SetRequest {
+	if n.fn == nil {
+		return req
+	}
+	res := make([]state.SetRequest, len(req))
+	for i := range req {
+		res[i] = req[i]
+		res[i].Key = n.Key(req[i].Key)
+	}
+	return res
+}
+
+// DeleteRequests returns a copy of the requests with the normalized keys.
+func (n Normalizer) DeleteRequests(req []state.DeleteRequest) []state.DeleteRequest {
+	if n.fn == nil {
+		return req
+	}
+	res := make([]state.DeleteRequest, len(req))
+	for i := range req {
+		res[i] = req[i]
+		res[i].Key = n.Key(req[i].Key)
+	}
+	return res
+}
+
+// Operations returns a copy of the operations of a transaction with the normalized keys.
+func (n Normalizer) Operations(ops []state.TransactionalStateOperation) []state.TransactionalStateOperation {
+	if n.fn == nil {
+		return ops
+	}
+	res := make([]state.TransactionalStateOperation, len(ops))
+	for i, o := range ops {
+		switch req := o.(type) {
+		case state.SetRequest:
+			req.Key = n.Key(req.Key)
+			res[i] = req
+		case state.DeleteRequest:
+			req.Key = n.Key(req.Key)
+			res[i] = req
+		default:
+			res[i] = o
+		}
+	}
+	return res
+}
+
+// TransactionalStateRequest returns a copy of the request with the normalized keys.
+func (n Normalizer) TransactionalStateRequest(req *state.TransactionalStateRequest) *state.TransactionalStateRequest {
+	if n.fn == nil {
+		return req
+	}
+	r := *req
+	r.Operations = n.Operations(r.Operations)
+	return &r
+}
+
+// ReserveRequest returns a copy of the request with the normalized keys.
+func (n Normalizer) ReserveRequest(req *state.ReserveRequest) *state.ReserveRequest {
+	if n.fn == nil {
+		return req
+	}
+	r := *req
+	r.Operations = n.Operations(r.Operations)
+	return &r
+}
+
+// RestoreKeys replaces the normalized keys in the responses of a bulk get with the keys of the original requests.
+// Responses can be in any order; requests whose keys have the same normalized key are matched to the responses for that key in order.
+func (n Normalizer) RestoreKeys(req []state.GetRequest, res []state.BulkGetResponse) {
+	if n.fn == nil {
+		return
+	}
+	keys := make(map[string][]string, len(req))
+	for _, r := range req {
+		normalized := n.Key(r.Key)
+		keys[normalized] = append(keys[normalized], r.Key)
+	}
+	for i := range res {
+		normalized := res[i].Key
+		originals := keys[normalized]
+		if len(originals) == 0 {
+			continue
+		}
+		res[i].Key = originals[0]
+		keys[normalized] = originals[1:]
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keynormalizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestNew(t *testing.T) {
+	n, err := New(Metadata{})
+	require.NoError(t, err)
+	assert.False(t, n.Enabled())
+	assert.Equal(t, "myapp|| Order_1 ", n.Key("myapp|| Order_1 "))
+
+	for _, tc := range []struct {
+		name     string
+		metadata Metadata
+		key      string
+		expected string
+	}{
+		{"lowercase", Metadata{KeyNormalization: "lowercase"}, "MyApp||Order_1", "MyApp||order_1"},
+		{"trim", Metadata{KeyNormalization: "trim"}, "myapp|| Order 1 ", "myapp||Order 1"},
+		{"default separators", Metadata{KeyNormalization: "replaceSeparators"}, "myapp||order_1.a:b/c", "myapp||order-1-a-b-c"},
+		{"custom separators", Metadata{KeyNormalization: "replaceSeparators", KeyNormalizationSeparators: " -", KeyNormalizationReplacement: "_"}, "order 1-a", "order_1_a"},
+		{"all steps", Metadata{KeyNormalization: "lowercase, trim, replaceSeparators"}, " Order_1.A ", "order-1-a"},
+		{"actor keys", Metadata{KeyNormalization: "lowercase"}, "myapp||Type||ID||State", "myapp||Type||ID||state"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(tc.metadata)
+			require.NoError(t, err)
+			require.True(t, n.Enabled())
+			normalized := n.Key(tc.key)
+			assert.Equal(t, tc.expected, normalized)
+			// Normalized keys are normalized to themselves
+			assert.Equal(t, normalized, n.Key(normalized))
+		})
+	}
+
+	for _, m := range []Metadata{
+		{KeyNormalization: "uppercase"},
+		{KeyNormalization: "replaceSeparators", KeyNormalizationSeparators: "|"},
+		{KeyNormalization: "replaceSeparators", KeyNormalizationSeparators: "a"},
+		{KeyNormalization: "replaceSeparators", KeyNormalizationReplacement: "_"},
+		{KeyNormalization: "replaceSeparators", KeyNormalizationReplacement: " "},
+	} {
+		_, err = New(m)
+		require.Error(t, err, m)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	n := FromFunc(func(key string) string {
+		return key + "!"
+	})
+
+	get := &state.GetRequest{Key: "a"}
+	assert.Equal(t, "a!", n.GetRequest(get).Key)
+	assert.Equal(t, "a", get.Key)
+	assert.Equal(t, "a!", n.SetRequest(&state.SetRequest{Key: "a"}).Key)
+	assert.Equal(t, "a!", n.DeleteRequest(&state.DeleteRequest{Key: "a"}).Key)
+	assert.Equal(t, "a!", n.TouchRequest(&state.TouchRequest{Key: "a"}).Key)
+	assert.Equal(t, "p!", n.ListKeysRequest(&state.ListKeysRequest{Prefix: "p"}).Prefix)
+	assert.Equal(t, "", n.ListKeysRequest(&state.ListKeysRequest{}).Prefix)
+
+	sets := []state.SetRequest{{Key: "a"}}
+	assert.Equal(t, "a!", n.SetRequests(sets)[0].Key)
+	assert.Equal(t, "a", sets[0].Key)
+	assert.Equal(t, "a!", n.DeleteRequests([]state.DeleteRequest{{Key: "a"}})[0].Key)
+
+	ops := n.Operations([]state.TransactionalStateOperation{
+		state.SetRequest{Key: "a"},
+		state.DeleteRequest{Key: "b"},
+	})
+	assert.Equal(t, "a!", ops[0].GetKey())
+	assert.Equal(t, "b!", ops[1].GetKey())
+	multi := n.TransactionalStateRequest(&state.TransactionalStateRequest{Operations: ops})
+	assert.Equal(t, "a!!", multi.Operations[0].GetKey())
+	reserve := n.ReserveRequest(&state.ReserveRequest{ID: "r", Operations: ops})
+	assert.Equal(t, "b!!", reserve.Operations[1].GetKey())
+
+	// Requests are returned as-is when normalization is disabled
+	n = Normalizer{}
+	assert.Same(t, get, n.GetRequest(get))
+}
+
+func TestRestoreKeys(t *testing.T) {
+	n, err := New(Metadata{KeyNormalization: "lowercase"})
+	require.NoError(t, err)
+
+	req := []state.GetRequest{{Key: "A"}, {Key: "b"}, {Key: "a"}}
+	normalized := n.GetRequests(req)
+	assert.Equal(t, []state.GetRequest{{Key: "a"}, {Key: "b"}, {Key: "a"}}, normalized)
+
+	res := []state.BulkGetResponse{{Key: "b"}, {Key: "a"}, {Key: "a"}}
+	n.RestoreKeys(req, res)
+	assert.Equal(t, []state.BulkGetResponse{{Key: "b"}, {Key: "A"}, {Key: "a"}}, res)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/state"
+)
+
+// SetAuditLogStore sets the state store that the audit log is saved to. Implements state.AuditLogSinkSetter.
+func (p *PostgresDBAccess) SetAuditLogStore(store state.Store) error {
+	return p.auditLog.SetAuditLogStore(store)
+}
+
+// SetAuditLogPublisher sets the function that publishes the audit log. Implements state.AuditLogSinkSetter.
+func (p *PostgresDBAccess) SetAuditLogPublisher(publish state.AuditLogPublishFn) error {
+	return p.auditLog.SetAuditLogPublisher(publish)
+}
+
+// ensureAuditTable creates the table of the audit log, if it's stored in a table and the table doesn't exist.
+func (p *PostgresDBAccess) ensureAuditTable(ctx context.Context) error {
+	if !p.metadata.InTable() {
+		return nil
+	}
+
+	_, err := p.db.Exec(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			id bigserial NOT NULL PRIMARY KEY,
+			key text NOT NULL,
+			operation text NOT NULL,
+			actor text,
+			recordedat timestamp with time zone NOT NULL
+		)`,
+		p.metadata.AuditLogTable,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to create audit log table: %w", err)
+	}
+	return nil
+}
+
+// planAuditTable records in the validation report the creation of the table of the audit log, if it's stored in a table that doesn't exist.
+func (p *PostgresDBAccess) planAuditTable(ctx context.Context) error {
+	if !p.metadata.InTable() {
+		return nil
+	}
+
+	var exists bool
+	err := p.db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", p.metadata.AuditLogTable).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check if audit log table exists: %w", err)
+	}
+	if !exists {
+		p.validationReport.AddPlannedChange("create audit log table '%s'", p.metadata.AuditLogTable)
+	}
+	return nil
+}
+
+// writeAuditLog records the operations in the audit log, if it's enabled.
+// If the audit log is stored in a table, the records are inserted with db, so they're part of the transaction of the operations; otherwise, they're written to the sink, and must be written before the operations are executed.
+func (p *PostgresDBAccess) writeAuditLog(parentCtx context.Context, db dbquerier, ops ...state.TransactionalStateOperation) error {
+	log, err := p.auditLog.Get()
+	if err != nil || log == nil {
+		return err
+	}
+
+	records := log.Records(ops...)
+	if !log.InTable() {
+		return log.Write(parentCtx, records)
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, p.metadata.QueryTimeout)
+	defer cancel()
+	for _, r := range records {
+		var actor *string
+		if r.Actor != "" {
+			actor = &r.Actor
+		}
+		_, err = db.Exec(ctx,
+			"INSERT INTO "+log.Table()+" (key, operation, actor, recordedat) VALUES ($1, $2, $3, $4)",
+			r.Key, string(r.Operation), actor, r.Time,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write the audit log: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+		}
+	}
+	return nil
+}
+
+// execAudited executes a single operation together with its record in the audit log.
+// If the audit log is stored in a table, or the key must be checked for reservations, the operation is executed in a transaction.
+func (p *PostgresDBAccess) execAudited(parentCtx context.Context, methodName string, op state.TransactionalStateOperation, fn func(db dbquerier) error) error {
+	if !p.auditLog.InTable() && !p.supportsReservations {
+		err := p.writeAuditLog(parentCtx, p.db, op)
+		if err != nil {
+			return err
+		}
+		return fn(p.db)
+	}
+
+	tx, err := p.beginTx(parentCtx)
+	if err != nil {
+		return err
+	}
+	defer p.rollbackTx(parentCtx, tx, methodName)
+
+	err = p.checkNotReserved(parentCtx, tx, []state.TransactionalStateOperation{op})
+	if err != nil {
+		return err
+	}
+	err = p.writeAuditLog(parentCtx, tx, op)
+	if err != nil {
+		return err
+	}
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	err = tx.Commit(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// bulkOperations returns the requests of BulkSet or BulkDelete as operations, for the audit log and the check of the reservations.
+func bulkOperations[T state.TransactionalStateOperation](req []T) []state.TransactionalStateOperation {
+	ops := make([]state.TransactionalStateOperation, len(req))
+	for i := range req {
+		ops[i] = req[i]
+	}
+	return ops
+}
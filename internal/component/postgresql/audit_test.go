@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/audit"
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestAuditLogInTable(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+	m.pgDba.metadata.Metadata = audit.Metadata{AuditLogSink: audit.SinkTable}
+	require.NoError(t, m.pgDba.metadata.Metadata.Validate())
+	m.pgDba.auditLog.Init(m.pgDba.metadata.Metadata, "postgresql||state")
+
+	t.Run("set is executed in a transaction with its record", func(t *testing.T) {
+		setReq := createSetRequest()
+		setReq.Metadata = map[string]string{"actor": "alice"}
+		val, _ := json.Marshal(setReq.Value)
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO dapr_state_audit").
+			WithArgs(setReq.Key, "upsert", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO state").
+			WithArgs(setReq.Key, string(val), false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		require.NoError(t, m.pgDba.Set(context.Background(), &setReq))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("record is rolled back with a failed delete", func(t *testing.T) {
+		deleteReq := createDeleteRequest()
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO dapr_state_audit").
+			WithArgs(deleteReq.Key, "delete", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs(deleteReq.Key).
+			WillReturnError(errors.New("delete failed"))
+		m.db.ExpectRollback()
+
+		require.Error(t, m.pgDba.Delete(context.Background(), &deleteReq))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		setReq := createSetRequest()
+		deleteReq := createDeleteRequest()
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO dapr_state_audit").
+			WithArgs(setReq.Key, "upsert", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO dapr_state_audit").
+			WithArgs(deleteReq.Key, "delete", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO state").
+			WithArgs(setReq.Key, pgxmock.AnyArg(), false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs(deleteReq.Key).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.ExecuteMulti(context.Background(), &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{setReq, deleteReq},
+		})
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+}
+
+func TestAuditLogWriteAhead(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+
+	t.Run("records are written to the file before the operation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		m.pgDba.auditLog.Init(audit.Metadata{AuditLogSink: audit.SinkFile, AuditLogFile: path, AuditLogActorKey: "actor"}, "postgresql||state")
+
+		setReq := createSetRequest()
+		m.db.ExpectExec("INSERT INTO state").
+			WithArgs(setReq.Key, pgxmock.AnyArg(), false).
+			WillReturnError(errors.New("insert failed"))
+
+		require.Error(t, m.pgDba.Set(context.Background(), &setReq))
+		require.NoError(t, m.db.ExpectationsWereMet())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var record audit.Record
+		require.NoError(t, json.Unmarshal(data, &record))
+		assert.Equal(t, setReq.Key, record.Key)
+		assert.Equal(t, state.OperationUpsert, record.Operation)
+	})
+
+	t.Run("operation fails if the sink was not set", func(t *testing.T) {
+		m.pgDba.auditLog.Init(audit.Metadata{AuditLogSink: audit.SinkStateStore, AuditLogComponent: "auditstore"}, "postgresql||state")
+
+		deleteReq := createDeleteRequest()
+		err := m.pgDba.Delete(context.Background(), &deleteReq)
+		require.ErrorContains(t, err, "'auditstore' is configured, but it was not set")
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dapr/components-contrib/state"
+	stateutils "github.com/dapr/components-contrib/state/utils"
+)
+
+// useBulkLoad returns true if the items in a BulkSet request should be loaded with COPY.
+// This requires bulk loading to be enabled, and is used only when no item requires concurrency control.
+func (p *PostgresDBAccess) useBulkLoad(req []state.SetRequest) bool {
+	if p.metadata.BulkLoadThreshold <= 0 || len(req) < p.metadata.BulkLoadThreshold {
+		return false
+	}
+	for i := range req {
+		if (req[i].ETag != nil && *req[i].ETag != "") || req[i].Options.Concurrency == state.FirstWrite {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkLoad stores the items in a single transaction, inserting new keys with "COPY ... FROM STDIN".
+// Keys that already exist are updated with upserts only if the "bulkLoadOverwrite" option is enabled; otherwise, the bulk load fails.
+// Items with a TTL are always stored with upserts, so their expiration time is computed by the database.
+func (p *PostgresDBAccess) bulkLoad(parentCtx context.Context, req []state.SetRequest) error {
+	// If the same key appears more than once, the last item wins, as if the items were stored in order
+	keys := make([]string, 0, len(req))
+	last := make(map[string]int, len(req))
+	for i := range req {
+		if req[i].Key == "" {
+			return errors.New("missing key in set operation")
+		}
+		if _, ok := last[req[i].Key]; !ok {
+			keys = append(keys, req[i].Key)
+		}
+		last[req[i].Key] = i
+	}
+
+	tx, err := p.beginTx(parentCtx)
+	if err != nil {
+		return err
+	}
+	defer p.rollbackTx(parentCtx, tx, "BulkSet")
+
+	ops := bulkOperations(req)
+	err = p.checkNotReserved(parentCtx, tx, ops)
+	if err != nil {
+		return err
+	}
+	err = p.writeAuditLog(parentCtx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
+	existing, err := p.getExistingKeys(parentCtx, tx, keys)
+	if err != nil {
+		return err
+	}
+	if !p.metadata.BulkLoadOverwrite {
+		var conflicts int
+		for _, live := range existing {
+			if live {
+				conflicts++
+			}
+		}
+		if conflicts > 0 {
+			return fmt.Errorf("bulk load failed: %d keys already exist; set '%s' to overwrite them", conflicts, bulkLoadOverwriteKey)
+		}
+	}
+
+	rows := make([][]any, 0, len(keys))
+	upserts := make([]*state.SetRequest, 0)
+	for _, key := range keys {
+		item := &req[last[key]]
+		_, exists := existing[key]
+		_, hasTTL := item.Metadata[stateutils.MetadataTTLKey]
+		if exists || hasTTL {
+			upserts = append(upserts, item)
+			continue
+		}
+		value, isBinary, compressed, err := p.encodeValue(item.Value)
+		if err != nil {
+			return err
+		}
+		row := []any{key, value, isBinary}
+		if p.supportsCompression {
+			row = append(row, compressed)
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) > 0 {
+		// The query timeout is not applied to COPY, which is expected to take longer than other statements
+		var n int64
+		n, err = tx.CopyFrom(parentCtx, tableIdentifier(p.metadata.TableName), p.bulkLoadColumns(), pgx.CopyFromRows(rows))
+		if err != nil {
+			return fmt.Errorf("bulk load failed: %w", err)
+		}
+		if n != int64(len(rows)) {
+			return fmt.Errorf("bulk load failed: expected to copy %d rows, but copied %d", len(rows), n)
+		}
+	}
+
+	for _, item := range upserts {
+		err = p.doSet(parentCtx, tx, item)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	err = tx.Commit(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.Debugf("Bulk loaded %d keys, and upserted %d keys", len(rows), len(upserts))
+	return nil
+}
+
+func (p *PostgresDBAccess) bulkLoadColumns() []string {
+	if p.supportsCompression {
+		return []string{"key", "value", "isbinary", compressedValueColumn}
+	}
+	return []string{"key", "value", "isbinary"}
+}
+
+// getExistingKeys returns the keys that are already stored, and whether each is live (i.e. not expired).
+func (p *PostgresDBAccess) getExistingKeys(parentCtx context.Context, db dbquerier, keys []string) (map[string]bool, error) {
+	query := `SELECT key, (expiredate IS NULL OR expiredate > CURRENT_TIMESTAMP) FROM ` + p.metadata.TableName + ` WHERE key = ANY($1)`
+	rows, err := db.Query(parentCtx, query, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing keys: %w", err)
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var (
+			key  string
+			live bool
+		)
+		err = rows.Scan(&key, &live)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up existing keys: %w", err)
+		}
+		existing[key] = live
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing keys: %w", err)
+	}
+	return existing, nil
+}
+
+// tableIdentifier returns the identifier of a table whose name could be in the format "schema.table" or just "table".
+func tableIdentifier(name string) pgx.Identifier {
+	return pgx.Identifier(strings.Split(name, "."))
+}
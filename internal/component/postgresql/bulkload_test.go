@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestBulkLoad(t *testing.T) {
+	const existingKeysQuery = `SELECT key, \(expiredate IS NULL OR expiredate > CURRENT_TIMESTAMP\) FROM state WHERE key = ANY\(\$1\)`
+
+	setup := func(t *testing.T, overwrite bool) *mocks {
+		m, _ := mockDatabase(t)
+		t.Cleanup(m.db.Close)
+		m.pgDba.metadata.BulkLoadThreshold = 2
+		m.pgDba.metadata.BulkLoadOverwrite = overwrite
+		return m
+	}
+
+	reqs := func() []state.SetRequest {
+		return []state.SetRequest{
+			{Key: "k1", Value: "v1"},
+			{Key: "k2", Value: "v2"},
+		}
+	}
+
+	t.Run("below threshold uses upserts", func(t *testing.T) {
+		m := setup(t, false)
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("k1", `"v1"`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), reqs()[:1])
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("items with ETags use upserts", func(t *testing.T) {
+		m := setup(t, false)
+		req := reqs()
+		req[1].ETag = ptr.Of("1")
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("k1", `"v1"`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("k2", `"v2"`, false, uint32(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("new keys are copied", func(t *testing.T) {
+		m := setup(t, false)
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery(existingKeysQuery).
+			WithArgs([]string{"k1", "k2"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key", "live"}))
+		m.db.ExpectCopyFrom(`"state"`, m.pgDba.bulkLoadColumns()).
+			WillReturnResult(2)
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), reqs())
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate keys are copied once", func(t *testing.T) {
+		m := setup(t, false)
+		req := append(reqs(), state.SetRequest{Key: "k1", Value: "v1b"})
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery(existingKeysQuery).
+			WithArgs([]string{"k1", "k2"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key", "live"}))
+		m.db.ExpectCopyFrom(`"state"`, m.pgDba.bulkLoadColumns()).
+			WillReturnResult(2)
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("existing keys fail without overwrite", func(t *testing.T) {
+		m := setup(t, false)
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery(existingKeysQuery).
+			WithArgs([]string{"k1", "k2"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key", "live"}).AddRow("k1", true))
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), reqs())
+		require.ErrorContains(t, err, "1 keys already exist")
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("existing keys are upserted with overwrite", func(t *testing.T) {
+		m := setup(t, true)
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery(existingKeysQuery).
+			WithArgs([]string{"k1", "k2"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key", "live"}).AddRow("k1", true))
+		m.db.ExpectCopyFrom(`"state"`, m.pgDba.bulkLoadColumns()).
+			WillReturnResult(1)
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("k1", `"v1"`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), reqs())
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("expired keys and items with TTL are upserted", func(t *testing.T) {
+		m := setup(t, false)
+		req := reqs()
+		req[1].Metadata = map[string]string{"ttlInSeconds": "60"}
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery(existingKeysQuery).
+			WithArgs([]string{"k1", "k2"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key", "live"}).AddRow("k1", false))
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("k1", `"v1"`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("k2", `"v2"`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		err := m.pgDba.BulkSet(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+}
+
+func TestBulkLoadNotSupported(t *testing.T) {
+	dba := newPostgresDBAccess(logger.NewLogger("test"), Options{})
+	err := dba.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString":  "host=localhost user=postgres",
+		"bulkLoadThreshold": "1000",
+	}}})
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestTableIdentifier(t *testing.T) {
+	assert.Equal(t, `"state"`, tableIdentifier("state").Sanitize())
+	assert.Equal(t, `"myschema"."state"`, tableIdentifier("myschema.state").Sanitize())
+}
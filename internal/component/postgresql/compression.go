@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+)
+
+const (
+	compressionKey = "compression"
+
+	compressionNone = "none"
+	compressionZstd = "zstd"
+
+	defaultCompressionMinSize = 1024 // In bytes
+
+	// Name of the column that contains compressed values.
+	// The column is NULL for values that are stored uncompressed in the "value" column.
+	compressedValueColumn = "compressedvalue"
+)
+
+// Format markers, stored as the first byte of values in the "compressedvalue" column.
+// The low 4 bits identify the compression algorithm, and the high 4 bits the codec the value was serialized with.
+const (
+	compressedFormatNone byte = 0x00
+	compressedFormatZstd byte = 0x01
+	compressionMask      byte = 0x0f
+
+	codecFormatJSON        byte = 0x00
+	codecFormatMsgpack     byte = 0x10
+	codecFormatPassthrough byte = 0x20
+	codecMask              byte = 0xf0
+)
+
+// Decoder shared by all instances; DecodeAll is safe for concurrent use.
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// valueCompressor compresses values before storing them.
+type valueCompressor struct {
+	encoder *zstd.Encoder
+	minSize int
+	// Paths of the fields that are stored uncompressed, to allow querying them
+	queryableFields [][]string
+}
+
+func newValueCompressor(algorithm string, minSize int, queryableFields string) (*valueCompressor, error) {
+	switch strings.ToLower(algorithm) {
+	case "", compressionNone:
+		return nil, nil
+	case compressionZstd:
+		// Continue
+	default:
+		return nil, fmt.Errorf("invalid value for '%s': unsupported compression algorithm '%s'", compressionKey, algorithm)
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+
+	c := &valueCompressor{
+		encoder: encoder,
+		minSize: minSize,
+	}
+	for _, f := range strings.Split(queryableFields, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		c.queryableFields = append(c.queryableFields, strings.Split(f, "."))
+	}
+	return c, nil
+}
+
+// compress returns the values to store in the "value" and "compressedvalue" columns.
+// Values smaller than the minimum size (or all values, if c is nil) are stored uncompressed, and the compressed value is nil.
+// Otherwise, the "value" column contains only the queryable fields.
+func (c *valueCompressor) compress(value string) (string, []byte) {
+	if c == nil || len(value) < c.minSize {
+		return value, nil
+	}
+
+	compressed := make([]byte, 1, len(value)/2)
+	compressed[0] = compressedFormatZstd
+	compressed = c.encoder.EncodeAll([]byte(value), compressed)
+
+	return c.queryableProjection(value), compressed
+}
+
+// pack returns the content of the "compressedvalue" column for a value serialized with a codec other than JSON.
+// The value is compressed unless c is nil or the value is smaller than the minimum size.
+func (c *valueCompressor) pack(codecFormat byte, encoded []byte) []byte {
+	if c == nil || len(encoded) < c.minSize {
+		res := make([]byte, 1, len(encoded)+1)
+		res[0] = codecFormat | compressedFormatNone
+		return append(res, encoded...)
+	}
+
+	res := make([]byte, 1, len(encoded)/2)
+	res[0] = codecFormat | compressedFormatZstd
+	return c.encoder.EncodeAll(encoded, res)
+}
+
+// queryableProjection returns a JSON document that contains only the queryable fields of the value.
+func (c *valueCompressor) queryableProjection(value string) string {
+	if c == nil || len(c.queryableFields) == 0 {
+		return "null"
+	}
+
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	var doc map[string]any
+	if dec.Decode(&doc) != nil {
+		// Values that are not JSON objects have no queryable fields
+		return "null"
+	}
+
+	projection := map[string]any{}
+	for _, path := range c.queryableFields {
+		val, ok := lookupField(doc, path)
+		if !ok {
+			continue
+		}
+		parent := projection
+		for _, part := range path[:len(path)-1] {
+			child, ok := parent[part].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				parent[part] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = val
+	}
+
+	res, _ := json.Marshal(projection)
+	return string(res)
+}
+
+func lookupField(doc map[string]any, path []string) (any, bool) {
+	var cur any = doc
+	for _, part := range path {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = obj[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// decompressValue returns the original value from the content of the "compressedvalue" column.
+func decompressValue(compressed []byte) ([]byte, error) {
+	if len(compressed) == 0 {
+		return nil, errors.New("compressed value is empty")
+	}
+
+	format := compressed[0]
+	var data []byte
+	switch format & compressionMask {
+	case compressedFormatNone:
+		data = compressed[1:]
+	case compressedFormatZstd:
+		var err error
+		data, err = zstdDecoder.DecodeAll(compressed[1:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress value: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("compressed value has unsupported format %d", format)
+	}
+
+	switch format & codecMask {
+	case codecFormatJSON:
+		return data, nil
+	case codecFormatMsgpack:
+		return statecodec.Decode(statecodec.Msgpack, data)
+	case codecFormatPassthrough:
+		return statecodec.Decode(statecodec.ProtobufPassthrough, data)
+	default:
+		return nil, fmt.Errorf("compressed value has unsupported format %d", format)
+	}
+}
+
+// codecFormat returns the format marker for values serialized with the codec.
+func codecFormat(c statecodec.Codec) byte {
+	switch c.Name() {
+	case statecodec.Msgpack:
+		return codecFormatMsgpack
+	case statecodec.ProtobufPassthrough:
+		return codecFormatPassthrough
+	default:
+		return codecFormatJSON
+	}
+}
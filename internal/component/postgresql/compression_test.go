@@ -0,0 +1,260 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const testCompressibleValue = `{"person":{"org":"Dev Ops","id":1036},"city":"Seattle","state":"WA","notes":"` + "lorem ipsum dolor sit amet " + `"}`
+
+func TestNewValueCompressor(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		for _, algorithm := range []string{"", "none", "NONE"} {
+			c, err := newValueCompressor(algorithm, 0, "")
+			require.NoError(t, err)
+			assert.Nil(t, c)
+		}
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		c, err := newValueCompressor("zstd", 10, "person.org, city,")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.Equal(t, 10, c.minSize)
+		assert.Equal(t, [][]string{{"person", "org"}, {"city"}}, c.queryableFields)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := newValueCompressor("lz4", 0, "")
+		require.Error(t, err)
+	})
+}
+
+func TestCompressValue(t *testing.T) {
+	c, err := newValueCompressor("zstd", 32, "person.org,city,missing.field")
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		value, compressed := c.compress(testCompressibleValue)
+		require.NotNil(t, compressed)
+		assert.Equal(t, compressedFormatZstd, compressed[0])
+		assert.JSONEq(t, `{"person":{"org":"Dev Ops"},"city":"Seattle"}`, value)
+
+		res, err := decompressValue(compressed)
+		require.NoError(t, err)
+		assert.Equal(t, testCompressibleValue, string(res))
+	})
+
+	t.Run("small values are not compressed", func(t *testing.T) {
+		value, compressed := c.compress(`"hello"`)
+		assert.Nil(t, compressed)
+		assert.Equal(t, `"hello"`, value)
+	})
+
+	t.Run("values that are not objects have no queryable fields", func(t *testing.T) {
+		in := `"` + strings.Repeat("a", 64) + `"`
+		value, compressed := c.compress(in)
+		require.NotNil(t, compressed)
+		assert.Equal(t, "null", value)
+	})
+
+	t.Run("nil compressor", func(t *testing.T) {
+		var nc *valueCompressor
+		value, compressed := nc.compress(testCompressibleValue)
+		assert.Nil(t, compressed)
+		assert.Equal(t, testCompressibleValue, value)
+	})
+
+	t.Run("invalid compressed values", func(t *testing.T) {
+		_, err := decompressValue([]byte{})
+		require.Error(t, err)
+		_, err = decompressValue([]byte{99, 1, 2})
+		require.ErrorContains(t, err, "unsupported format")
+		_, err = decompressValue([]byte{compressedFormatZstd, 1, 2})
+		require.Error(t, err)
+	})
+}
+
+func TestCompressionInStore(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+	m.pgDba.supportsCompression = true
+	m.pgDba.compressor, _ = newValueCompressor("zstd", 32, "city")
+
+	t.Run("set stores the compressed value", func(t *testing.T) {
+		var opts SetQueryOptions
+		m.pgDba.setQueryFn = func(_ *state.SetRequest, o SetQueryOptions) string {
+			opts = o
+			return "INSERT INTO state"
+		}
+
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("key", `{"city":"Seattle"}`, false, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		err := m.pgDba.Set(context.Background(), &state.SetRequest{Key: "key", Value: map[string]any{
+			"city":  "Seattle",
+			"notes": strings.Repeat("lorem ipsum ", 10),
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "$4", opts.CompressedValueParam)
+	})
+
+	t.Run("get decompresses values", func(t *testing.T) {
+		_, compressed := m.pgDba.compressor.compress(testCompressibleValue)
+
+		m.db.ExpectQuery("SELECT").
+			WithArgs("key").
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "isbinary", "etag", "compressedvalue"}).
+				AddRow("key", []byte(`{"city":"Seattle"}`), false, int64(1), compressed))
+
+		res, err := m.pgDba.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, testCompressibleValue, string(res.Data))
+	})
+
+	t.Run("get reads uncompressed values", func(t *testing.T) {
+		m.db.ExpectQuery("SELECT").
+			WithArgs("key").
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "isbinary", "etag", "compressedvalue"}).
+				AddRow("key", []byte(`"hello"`), false, int64(1), nil))
+
+		res, err := m.pgDba.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `"hello"`, string(res.Data))
+	})
+
+	require.NoError(t, m.db.ExpectationsWereMet())
+}
+
+func TestCompressionNotSupported(t *testing.T) {
+	dba := newPostgresDBAccess(logger.NewLogger("test"), Options{})
+	err := dba.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString": "host=localhost user=postgres",
+		"compression":      "zstd",
+	}}})
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestPackValue(t *testing.T) {
+	c, err := newValueCompressor("zstd", 32, "")
+	require.NoError(t, err)
+
+	msgpackCodec, _ := statecodec.Parse(statecodec.Msgpack)
+	encoded, err := msgpackCodec.Encode([]byte(testCompressibleValue))
+	require.NoError(t, err)
+
+	t.Run("compressed", func(t *testing.T) {
+		packed := c.pack(codecFormatMsgpack, encoded)
+		assert.Equal(t, codecFormatMsgpack|compressedFormatZstd, packed[0])
+
+		res, err := decompressValue(packed)
+		require.NoError(t, err)
+		assert.JSONEq(t, testCompressibleValue, string(res))
+	})
+
+	t.Run("not compressed", func(t *testing.T) {
+		var nc *valueCompressor
+		packed := nc.pack(codecFormatMsgpack, encoded)
+		assert.Equal(t, codecFormatMsgpack|compressedFormatNone, packed[0])
+
+		res, err := decompressValue(packed)
+		require.NoError(t, err)
+		assert.JSONEq(t, testCompressibleValue, string(res))
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		raw := []byte{0x0a, 0x04, 'd', 'a', 'p', 'r'}
+		packed := c.pack(codecFormatPassthrough, raw)
+
+		res, err := decompressValue(packed)
+		require.NoError(t, err)
+		assert.Equal(t, raw, res)
+	})
+
+	t.Run("unsupported codec", func(t *testing.T) {
+		_, err := decompressValue([]byte{0x70, 1, 2})
+		require.ErrorContains(t, err, "unsupported format")
+	})
+}
+
+func TestSerializerInStore(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+	m.pgDba.supportsCompression = true
+	m.pgDba.codec, _ = statecodec.Parse(statecodec.Msgpack)
+
+	t.Run("set stores the encoded value", func(t *testing.T) {
+		m.pgDba.setQueryFn = func(*state.SetRequest, SetQueryOptions) string {
+			return "INSERT INTO state"
+		}
+
+		m.db.ExpectExec("INSERT INTO").
+			WithArgs("key", "null", false, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		err := m.pgDba.Set(context.Background(), &state.SetRequest{Key: "key", Value: map[string]any{"city": "Seattle"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("get decodes values", func(t *testing.T) {
+		value, isBinary, packed, err := m.pgDba.encodeValue(map[string]any{"city": "Seattle"})
+		require.NoError(t, err)
+		assert.Equal(t, "null", value)
+		assert.False(t, isBinary)
+
+		m.db.ExpectQuery("SELECT").
+			WithArgs("key").
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "isbinary", "etag", "compressedvalue"}).
+				AddRow("key", []byte(value), false, int64(1), packed))
+
+		res, err := m.pgDba.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"city":"Seattle"}`, string(res.Data))
+	})
+
+	t.Run("get reads values stored as JSON", func(t *testing.T) {
+		m.db.ExpectQuery("SELECT").
+			WithArgs("key").
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "isbinary", "etag", "compressedvalue"}).
+				AddRow("key", []byte(`{"city":"Seattle"}`), false, int64(1), nil))
+
+		res, err := m.pgDba.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `{"city":"Seattle"}`, string(res.Data))
+	})
+
+	require.NoError(t, m.db.ExpectationsWereMet())
+}
+
+func TestSerializerNotSupported(t *testing.T) {
+	dba := newPostgresDBAccess(logger.NewLogger("test"), Options{})
+	err := dba.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString": "host=localhost user=postgres",
+		"serializer":       "msgpack",
+	}}})
+	assert.ErrorContains(t, err, "not supported")
+}
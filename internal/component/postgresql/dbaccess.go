@@ -33,6 +33,15 @@ type dbAccess interface {
 	BulkDelete(ctx context.Context, req []state.DeleteRequest) error
 	ExecuteMulti(ctx context.Context, req *state.TransactionalStateRequest) error
 	Query(ctx context.Context, req *state.QueryRequest) (*state.QueryResponse, error)
+	QueryStream(ctx context.Context, req *state.QueryRequest, fetchSize int, fn state.QueryPageFn) error
+	Reserve(ctx context.Context, req *state.ReserveRequest) error
+	CommitReservation(ctx context.Context, id string) error
+	RollbackReservation(ctx context.Context, id string) error
+	ListKeys(ctx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error)
+	ValidationReport() *state.ValidationReport
+	SetAuditLogStore(store state.Store) error
+	SetAuditLogPublisher(publish state.AuditLogPublishFn) error
+	Ping(ctx context.Context) error
 	Close() error // io.Closer
 }
 
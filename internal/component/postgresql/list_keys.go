@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"unicode/utf8"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/state"
+)
+
+var errListKeysUnsupported = errors.New("listing keys is not supported by this component")
+
+// ListKeys returns a page of the keys with the prefix, in byte order; the continuation token is the last key of the page.
+// Keys are compared with the text_pattern_ops operators (such as ~<~), which use the index created by the migrations regardless of the collation of the database.
+// A prefix is matched as the range of keys between the prefix and its upper bound, rather than with LIKE, so the index is used with any query plan.
+func (p *PostgresDBAccess) ListKeys(parentCtx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error) {
+	if !p.supportsListKeys {
+		return nil, errListKeysUnsupported
+	}
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return nil, err
+	}
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT key FROM ` + p.metadata.TableName + `
+		WHERE (expiredate IS NULL OR expiredate >= CURRENT_TIMESTAMP)`
+	args := make([]any, 0, 4)
+	addArg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if req.Prefix != "" {
+		query += ` AND key ~>=~ ` + addArg(req.Prefix)
+		if upper, ok := prefixUpperBound(req.Prefix); ok {
+			query += ` AND key ~<~ ` + addArg(upper)
+		}
+	}
+	if req.ContinuationToken != "" {
+		query += ` AND key ~>~ ` + addArg(req.ContinuationToken)
+	}
+	// Read one more key to know if there's another page
+	query += ` ORDER BY key USING ~<~ LIMIT ` + addArg(req.Limit+1)
+
+	opCtx, opCancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer opCancel()
+	ctx, cancel := internalsql.WithQueryTimeout(opCtx, p.metadata.QueryTimeout)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(opCtx, ctx, err)
+	}
+	defer rows.Close()
+
+	res := &state.ListKeysResponse{
+		Keys: []string{},
+	}
+	for rows.Next() {
+		var key string
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, internalsql.WrapQueryTimeoutError(opCtx, ctx, err)
+		}
+		if len(res.Keys) == req.Limit {
+			res.ContinuationToken = res.Keys[len(res.Keys)-1]
+			break
+		}
+		res.Keys = append(res.Keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(opCtx, ctx, err)
+	}
+
+	return res, nil
+}
+
+// prefixUpperBound returns the smallest string that is greater, in byte order, than all strings that start with prefix.
+// In UTF-8, byte order is the same as the order of the code points, so the bound is the prefix with its last code point incremented.
+// It returns false if there's no such string, because the prefix is made only of the largest code point.
+func prefixUpperBound(prefix string) (string, bool) {
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		r := runes[i] + 1
+		if r >= 0xD800 && r <= 0xDFFF {
+			// Surrogates can't be encoded in UTF-8
+			r = 0xE000
+		}
+		if r <= utf8.MaxRune {
+			runes[i] = r
+			return string(runes[:i+1]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestListKeys(t *testing.T) {
+	t.Run("not supported", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+
+		_, err := m.pgDba.ListKeys(context.Background(), &state.ListKeysRequest{})
+		require.ErrorIs(t, err, errListKeysUnsupported)
+	})
+
+	t.Run("first page", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsListKeys = true
+
+		m.db.ExpectQuery(regexp.QuoteMeta(`AND key ~>=~ $1 AND key ~<~ $2 ORDER BY key USING ~<~ LIMIT $3`)).
+			WithArgs("app||user", "app||uses", 3).
+			WillReturnRows(pgxmock.NewRows([]string{"key"}).AddRow("app||user1").AddRow("app||user2").AddRow("app||user3"))
+
+		res, err := m.pgDba.ListKeys(context.Background(), &state.ListKeysRequest{Prefix: "app||user", Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app||user1", "app||user2"}, res.Keys)
+		assert.Equal(t, "app||user2", res.ContinuationToken)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsListKeys = true
+
+		m.db.ExpectQuery(regexp.QuoteMeta(`AND key ~>~ $1 ORDER BY key USING ~<~ LIMIT $2`)).
+			WithArgs("app||user2", state.DefaultListKeysLimit+1).
+			WillReturnRows(pgxmock.NewRows([]string{"key"}).AddRow("app||user3"))
+
+		res, err := m.pgDba.ListKeys(context.Background(), &state.ListKeysRequest{ContinuationToken: "app||user2"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app||user3"}, res.Keys)
+		assert.Empty(t, res.ContinuationToken)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsListKeys = true
+
+		_, err := m.pgDba.ListKeys(context.Background(), &state.ListKeysRequest{Limit: -1})
+		require.Error(t, err)
+	})
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	tests := []struct {
+		prefix string
+		upper  string
+		ok     bool
+	}{
+		{prefix: "", ok: false},
+		{prefix: "abc", upper: "abd", ok: true},
+		{prefix: "ab\u00FF", upper: "ab\u0100", ok: true},
+		{prefix: "a\uD7FF", upper: "a\uE000", ok: true},
+		{prefix: "a\U0010FFFF", upper: "b", ok: true},
+		{prefix: "\U0010FFFF\U0010FFFF", ok: false},
+	}
+	for _, tt := range tests {
+		upper, ok := prefixUpperBound(tt.prefix)
+		assert.Equal(t, tt.ok, ok, tt.prefix)
+		assert.Equal(t, tt.upper, upper, tt.prefix)
+	}
+}
@@ -14,17 +14,37 @@ limitations under the License.
 package postgresql
 
 import (
+	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dapr/components-contrib/internal/component/audit"
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
+	stateutils "github.com/dapr/components-contrib/state/utils"
 	"github.com/dapr/kit/ptr"
 )
 
 const (
 	cleanupIntervalKey = "cleanupIntervalInSeconds"
 	timeoutKey         = "timeoutInSeconds"
+	queryTimeoutKey    = "queryTimeout"
+
+	bulkLoadThresholdKey = "bulkLoadThreshold"
+	bulkLoadOverwriteKey = "bulkLoadOverwrite"
+
+	compressionMinSizeKey = "compressionMinSize"
+
+	queryExecModeKey          = "queryExecMode"
+	statementCacheCapacityKey = "statementCacheCapacity"
 
 	defaultTableName         = "state"
 	defaultMetadataTableName = "dapr_metadata"
@@ -33,22 +53,86 @@ const (
 )
 
 type postgresMetadataStruct struct {
-	ConnectionString      string
+	ConnectionString string
+	// Discrete connection properties, used to build the connection string if ConnectionString is empty
+	internalsql.ConnectionMetadata `mapstructure:",squash"`
+	// Validation of pooled connections before they're used
+	internalsql.HealthMetadata `mapstructure:",squash"`
+
 	ConnectionMaxIdleTime time.Duration
 	TableName             string // Could be in the format "schema.table" or just "table"
 	MetadataTableName     string // Could be in the format "schema.table" or just "table"
 
 	Timeout         time.Duration  `mapstructure:"timeoutInSeconds"`
 	CleanupInterval *time.Duration `mapstructure:"cleanupIntervalInSeconds"`
+	QueryTimeout    time.Duration  // Timeout for each statement executed by Get, Set, Delete, Multi and Query; if 0, statements are bound only by the operation's context
+
+	ValidateOnly bool
+
+	// Number of connections opened and validated during Init; by default, only one connection is used to ping the database and perform migrations
+	WarmupConnections int
+	// If true, connecting to the database, performing migrations, and warming up connections are deferred to the first operation, so Init doesn't wait for the database
+	LazyInit bool
+
+	// Minimum number of items in a BulkSet request to load them with COPY instead of individual upserts; 0 disables bulk loading
+	BulkLoadThreshold int
+	// If true, bulk loading overwrites existing keys; otherwise, a bulk load that includes existing keys fails
+	BulkLoadOverwrite bool
+
+	// Algorithm used to compress values: "zstd", or "none" (the default) to disable compression
+	Compression string
+	// Values smaller than this size, in bytes, are stored uncompressed
+	CompressionMinSize int
+	// Comma-separated list of fields (as JSON paths such as "person.org") that are stored uncompressed alongside compressed values, so they can be queried
+	CompressionQueryableFields string
+
+	// Codec used to serialize values: "json" (the default), "msgpack", or "protobuf-passthrough"
+	Serializer string
+	// Options of the JSON encoding of values
+	stateutils.JSONOptions `mapstructure:",squash"`
+
+	// Isolation level of transactions used by Multi, BulkSet, and BulkDelete; if empty, the database's default is used
+	TransactionIsolationLevel string
+	txIsoLevel                pgx.TxIsoLevel
+
+	// How statements are executed: "cache_statement" (the default), "cache_describe", "describe", "exec", or "simple_protocol"
+	QueryExecMode string
+	queryExecMode pgx.QueryExecMode
+	// Maximum number of prepared statements (or statement descriptions) cached per connection; if 0, pgx's default is used
+	StatementCacheCapacity int
+	// If true, the SQL statements built by the Query API are executed without being added to the statement cache, as each query can be different
+	QueryBypassStatementCache bool
+
+	// Audit log of the keys modified by Set, Delete and Multi
+	audit.Metadata `mapstructure:",squash"`
 }
 
 func (m *postgresMetadataStruct) InitWithMetadata(meta state.Metadata) error {
 	// Reset the object
 	m.ConnectionString = ""
+	m.ConnectionMetadata = internalsql.ConnectionMetadata{}
+	m.HealthMetadata = internalsql.HealthMetadata{}
 	m.TableName = defaultTableName
 	m.MetadataTableName = defaultMetadataTableName
 	m.CleanupInterval = ptr.Of(defaultCleanupInternal * time.Second)
 	m.Timeout = defaultTimeout * time.Second
+	m.ValidateOnly = false
+	m.WarmupConnections = 0
+	m.LazyInit = false
+	m.QueryTimeout = 0
+	m.BulkLoadThreshold = 0
+	m.BulkLoadOverwrite = false
+	m.Compression = ""
+	m.CompressionMinSize = defaultCompressionMinSize
+	m.CompressionQueryableFields = ""
+	m.Serializer = ""
+	m.TransactionIsolationLevel = ""
+	m.txIsoLevel = ""
+	m.QueryExecMode = ""
+	m.queryExecMode = 0
+	m.StatementCacheCapacity = 0
+	m.QueryBypassStatementCache = false
+	m.Metadata = audit.Metadata{}
 
 	// Decode the metadata
 	err := metadata.DecodeMetadata(meta.Properties, &m)
@@ -57,6 +141,10 @@ func (m *postgresMetadataStruct) InitWithMetadata(meta state.Metadata) error {
 	}
 
 	// Validate and sanitize input
+	m.ConnectionString, err = m.ConnectionMetadata.ConnectionString(m.ConnectionString, buildConnectionString)
+	if err != nil {
+		return err
+	}
 	if m.ConnectionString == "" {
 		return errMissingConnectionString
 	}
@@ -66,6 +154,50 @@ func (m *postgresMetadataStruct) InitWithMetadata(meta state.Metadata) error {
 		return fmt.Errorf("invalid value for '%s': must be greater than 0", timeoutKey)
 	}
 
+	// Query timeout
+	if m.QueryTimeout < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", queryTimeoutKey)
+	}
+
+	// Connection warmup
+	if m.WarmupConnections < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", utils.WarmupConnectionsKey)
+	}
+	if m.LazyInit && m.ValidateOnly {
+		return fmt.Errorf("metadata property '%s' can't be used with '%s'", utils.LazyInitKey, state.ValidateOnlyKey)
+	}
+
+	// Bulk load threshold
+	if m.BulkLoadThreshold < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", bulkLoadThresholdKey)
+	}
+
+	// Compression min size
+	if m.CompressionMinSize < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", compressionMinSizeKey)
+	}
+
+	// Transaction isolation level
+	m.txIsoLevel, err = parseTxIsoLevel(m.TransactionIsolationLevel)
+	if err != nil {
+		return err
+	}
+
+	// Statement caching
+	m.queryExecMode, err = parseQueryExecMode(m.QueryExecMode)
+	if err != nil {
+		return err
+	}
+	if m.StatementCacheCapacity < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", statementCacheCapacityKey)
+	}
+
+	// Audit log
+	err = m.Metadata.Validate()
+	if err != nil {
+		return err
+	}
+
 	// Cleanup interval
 	if m.CleanupInterval != nil {
 		// Non-positive value from meta means disable auto cleanup.
@@ -81,3 +213,85 @@ func (m *postgresMetadataStruct) InitWithMetadata(meta state.Metadata) error {
 
 	return nil
 }
+
+// parseTxIsoLevel returns the pgx isolation level for the value of the "transactionIsolationLevel" metadata property.
+func parseTxIsoLevel(val string) (pgx.TxIsoLevel, error) {
+	level, err := internalsql.ParseIsolationLevel(val)
+	if err != nil {
+		return "", err
+	}
+
+	switch level {
+	case sql.LevelDefault:
+		return "", nil
+	case sql.LevelReadUncommitted:
+		return pgx.ReadUncommitted, nil
+	case sql.LevelReadCommitted:
+		return pgx.ReadCommitted, nil
+	case sql.LevelRepeatableRead:
+		return pgx.RepeatableRead, nil
+	case sql.LevelSerializable:
+		return pgx.Serializable, nil
+	default:
+		return "", fmt.Errorf("invalid value for '%s': isolation level '%s' is not supported by PostgreSQL", internalsql.TransactionIsolationLevelKey, val)
+	}
+}
+
+// parseQueryExecMode returns the pgx query execution mode for the value of the "queryExecMode" metadata property.
+// It returns 0 if the value is empty, so pgx's default is used.
+func parseQueryExecMode(val string) (pgx.QueryExecMode, error) {
+	switch strings.ToLower(val) {
+	case "":
+		return 0, nil
+	case "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	default:
+		return 0, fmt.Errorf("invalid value for '%s': '%s' is not one of 'cache_statement', 'cache_describe', 'describe', 'exec', 'simple_protocol'", queryExecModeKey, val)
+	}
+}
+
+// buildConnectionString returns a connection string in the URL format from the discrete connection properties.
+// If the host is an absolute path, it's the directory containing the Unix domain socket.
+func buildConnectionString(m internalsql.ConnectionMetadata) string {
+	u := url.URL{
+		Scheme: "postgres",
+		Path:   "/" + m.Database,
+	}
+	if m.User != "" {
+		if m.Password != "" {
+			u.User = url.UserPassword(m.User, m.Password)
+		} else {
+			u.User = url.User(m.User)
+		}
+	}
+
+	var port string
+	if m.Port > 0 {
+		port = strconv.Itoa(m.Port)
+	}
+	switch {
+	case strings.HasPrefix(m.Host, "/"):
+		q := url.Values{"host": []string{m.Host}}
+		if port != "" {
+			q.Set("port", port)
+		}
+		u.RawQuery = q.Encode()
+	case port != "":
+		u.Host = net.JoinHostPort(m.Host, port)
+	case strings.Contains(m.Host, ":"):
+		// IPv6 address
+		u.Host = "[" + m.Host + "]"
+	default:
+		u.Host = m.Host
+	}
+
+	return u.String()
+}
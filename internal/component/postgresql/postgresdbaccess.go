@@ -27,7 +27,10 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/dapr/components-contrib/internal/component/audit"
 	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
 	stateutils "github.com/dapr/components-contrib/state/utils"
@@ -56,9 +59,25 @@ type PostgresDBAccess struct {
 
 	gc internalsql.GarbageCollector
 
-	migrateFn  func(context.Context, PGXPoolConn, MigrateOptions) error
-	setQueryFn func(*state.SetRequest, SetQueryOptions) string
-	etagColumn string
+	migrateFn        func(context.Context, PGXPoolConn, MigrateOptions) error
+	planMigrationsFn func(context.Context, PGXPoolConn, MigrateOptions, *state.ValidationReport) error
+	setQueryFn       func(*state.SetRequest, SetQueryOptions) string
+	etagColumn       string
+	supportsBulkLoad bool
+
+	supportsReservations bool
+	supportsListKeys     bool
+
+	supportsCompression bool
+	compressor          *valueCompressor
+	codec               statecodec.Codec
+
+	validationReport *state.ValidationReport
+
+	auditLog audit.Holder
+
+	// Set when the metadata property "lazyInit" is true, to complete the initialization on the first operation
+	lazyInit *utils.LazyInit
 }
 
 // newPostgresDBAccess creates a new instance of postgresAccess.
@@ -66,18 +85,42 @@ func newPostgresDBAccess(logger logger.Logger, opts Options) *PostgresDBAccess {
 	logger.Debug("Instantiating new Postgres state store")
 
 	return &PostgresDBAccess{
-		logger:     logger,
-		migrateFn:  opts.MigrateFn,
-		setQueryFn: opts.SetQueryFn,
-		etagColumn: opts.ETagColumn,
+		logger:           logger,
+		migrateFn:        opts.MigrateFn,
+		planMigrationsFn: opts.PlanMigrationsFn,
+		setQueryFn:       opts.SetQueryFn,
+		etagColumn:       opts.ETagColumn,
+		supportsBulkLoad: opts.SupportsBulkLoad,
+
+		supportsReservations: opts.SupportsReservations,
+		supportsListKeys:     opts.SupportsListKeys,
+
+		supportsCompression: opts.SupportsCompression,
 	}
 }
 
+// unixSocketPath returns the path of the Unix domain socket if the host is a directory (as in "host=/var/run/postgresql"), or an empty string otherwise.
+func unixSocketPath(host string, port uint16) string {
+	network, address := pgconn.NetworkAddress(host, port)
+	if network != "unix" {
+		return ""
+	}
+	return address
+}
+
 // Init sets up Postgres connection and ensures that the state table exists.
 func (p *PostgresDBAccess) Init(ctx context.Context, meta state.Metadata) error {
 	p.logger.Debug("Initializing Postgres state store")
 
-	err := p.metadata.InitWithMetadata(meta)
+	// Secret references are resolved before the connection string is built
+	props, err := meta.ResolvedProperties(ctx)
+	if err != nil {
+		p.logger.Errorf("Failed to resolve metadata: %v", err)
+		return err
+	}
+	meta.Properties = props
+
+	err = p.metadata.InitWithMetadata(meta)
 	if err != nil {
 		p.logger.Errorf("Failed to parse metadata: %v", err)
 		return err
@@ -85,37 +128,154 @@ func (p *PostgresDBAccess) Init(ctx context.Context, meta state.Metadata) error
 
 	config, err := pgxpool.ParseConfig(p.metadata.ConnectionString)
 	if err != nil {
-		err = fmt.Errorf("failed to parse connection string: %w", err)
+		err = fmt.Errorf("failed to parse connection string: %w", utils.RedactError(err, p.metadata.ConnectionString))
 		p.logger.Error(err)
 		return err
 	}
 	if p.metadata.ConnectionMaxIdleTime > 0 {
 		config.MaxConnIdleTime = p.metadata.ConnectionMaxIdleTime
 	}
+	if p.metadata.HealthMetadata.Enabled() {
+		// Connections that fail the validation are destroyed, and the pool acquires another one
+		config.BeforeAcquire = p.validateConn
+	}
+	if p.metadata.queryExecMode != 0 {
+		config.ConnConfig.DefaultQueryExecMode = p.metadata.queryExecMode
+	}
+	if p.metadata.StatementCacheCapacity > 0 {
+		// The limit applies to the cache used by the execution mode
+		config.ConnConfig.StatementCacheCapacity = p.metadata.StatementCacheCapacity
+		config.ConnConfig.DescriptionCacheCapacity = p.metadata.StatementCacheCapacity
+	}
+	// The pool must be able to hold all the connections opened during warmup
+	if int64(p.metadata.WarmupConnections) > int64(config.MaxConns) {
+		config.MaxConns = int32(p.metadata.WarmupConnections)
+	}
+
+	if p.metadata.BulkLoadThreshold > 0 && !p.supportsBulkLoad {
+		return fmt.Errorf("metadata property '%s' is not supported by this component", bulkLoadThresholdKey)
+	}
+
+	p.compressor, err = newValueCompressor(p.metadata.Compression, p.metadata.CompressionMinSize, p.metadata.CompressionQueryableFields)
+	if err != nil {
+		return err
+	}
+	if p.compressor != nil && !p.supportsCompression {
+		return fmt.Errorf("metadata property '%s' is not supported by this component", compressionKey)
+	}
+
+	// Values serialized with codecs other than JSON are stored in the "compressedvalue" column
+	p.codec, err = statecodec.Parse(p.metadata.Serializer)
+	if err != nil {
+		return err
+	}
+	if !statecodec.IsJSON(p.codec) && !p.supportsCompression {
+		return fmt.Errorf("metadata property '%s' is not supported by this component", statecodec.SerializerKey)
+	}
+
+	p.auditLog.Init(p.metadata.Metadata, "postgresql||"+p.metadata.TableName)
+
+	if p.metadata.ValidateOnly {
+		if p.planMigrationsFn == nil {
+			return fmt.Errorf("metadata property '%s' is not supported by this component", state.ValidateOnlyKey)
+		}
+		p.validationReport = &state.ValidationReport{}
+	}
+
+	// When connecting over a Unix domain socket, ensure it exists so we can return a clear error
+	socketPath := unixSocketPath(config.ConnConfig.Host, config.ConnConfig.Port)
+	if socketPath != "" {
+		err = internalsql.ValidateUnixSocket(socketPath)
+		if err != nil {
+			p.logger.Error(err)
+			return err
+		}
+	}
 
 	connCtx, connCancel := context.WithTimeout(ctx, p.metadata.Timeout)
-	p.db, err = pgxpool.NewWithConfig(connCtx, config)
+	pool, err := pgxpool.NewWithConfig(connCtx, config)
 	connCancel()
 	if err != nil {
 		err = fmt.Errorf("failed to connect to the database: %w", err)
 		p.logger.Error(err)
 		return err
 	}
+	p.db = pool
 
+	// The pool connects on demand, so with lazy init nothing else is done until the first operation
+	if p.metadata.LazyInit {
+		p.lazyInit = utils.NewLazyInit(func(ctx context.Context) error {
+			return p.finishInit(ctx, pool, socketPath)
+		})
+		return nil
+	}
+
+	return p.finishInit(ctx, pool, socketPath)
+}
+
+// finishInit validates the connection, warms up the pool, and performs migrations and the other operations that require connecting to the database.
+func (p *PostgresDBAccess) finishInit(ctx context.Context, pool *pgxpool.Pool, socketPath string) error {
 	pingCtx, pingCancel := context.WithTimeout(ctx, p.metadata.Timeout)
-	err = p.db.Ping(pingCtx)
+	err := p.db.Ping(pingCtx)
 	pingCancel()
 	if err != nil {
-		err = fmt.Errorf("failed to ping the database: %w", err)
+		err = fmt.Errorf("failed to ping the database: %w", internalsql.WrapUnixSocketError(socketPath, err))
 		p.logger.Error(err)
 		return err
 	}
 
-	err = p.migrateFn(ctx, p.db, MigrateOptions{
+	if p.metadata.WarmupConnections > 0 {
+		warmupCtx, warmupCancel := context.WithTimeout(ctx, p.metadata.Timeout)
+		err = utils.WarmupConnections(warmupCtx, p.metadata.WarmupConnections, func(ctx context.Context) (func(), error) {
+			conn, err := pool.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			err = conn.Ping(ctx)
+			if err != nil {
+				conn.Release()
+				return nil, err
+			}
+			return conn.Release, nil
+		})
+		warmupCancel()
+		if err != nil {
+			err = fmt.Errorf("failed to warm up connections: %w", err)
+			p.logger.Error(err)
+			return err
+		}
+	}
+
+	migrateOpts := MigrateOptions{
 		Logger:            p.logger,
 		StateTableName:    p.metadata.TableName,
 		MetadataTableName: p.metadata.MetadataTableName,
-	})
+	}
+	if p.supportsReservations {
+		migrateOpts.ReservationTableName = p.reservationTableName()
+	}
+
+	// In validate-only mode, stop after planning the migrations
+	if p.validationReport != nil {
+		p.validationReport.Connected = true
+		err = p.planMigrationsFn(ctx, p.db, migrateOpts, p.validationReport)
+		if err != nil {
+			return fmt.Errorf("failed to plan migrations: %w", err)
+		}
+		err = p.planAuditTable(ctx)
+		if err != nil {
+			return err
+		}
+		p.logger.Infof("Validation completed. Planned changes: %v. Missing permissions: %v", p.validationReport.PlannedChanges, p.validationReport.MissingPermissions)
+		return p.validationReport.Err()
+	}
+
+	err = p.migrateFn(ctx, p.db, migrateOpts)
+	if err != nil {
+		return err
+	}
+
+	err = p.ensureAuditTable(ctx)
 	if err != nil {
 		return err
 	}
@@ -131,12 +291,9 @@ func (p *PostgresDBAccess) Init(ctx context.Context, meta state.Metadata) error
 				WHERE (EXTRACT('epoch' FROM CURRENT_TIMESTAMP - %[1]s.value::timestamp with time zone) * 1000)::bigint > $1`,
 				p.metadata.MetadataTableName,
 			),
-			DeleteExpiredValuesQuery: fmt.Sprintf(
-				`DELETE FROM %s WHERE expiredate IS NOT NULL AND expiredate < CURRENT_TIMESTAMP`,
-				p.metadata.TableName,
-			),
-			CleanupInterval: *p.metadata.CleanupInterval,
-			DBPgx:           p.db,
+			DeleteExpiredValuesQuery: p.deleteExpiredValuesQuery(),
+			CleanupInterval:          *p.metadata.CleanupInterval,
+			DBPgx:                    p.db,
 		})
 		if err != nil {
 			return err
@@ -147,6 +304,28 @@ func (p *PostgresDBAccess) Init(ctx context.Context, meta state.Metadata) error
 	return nil
 }
 
+// deleteExpiredValuesQuery returns the query used by the garbage collector, which deletes expired rows and, if reservations are supported, expired reservations.
+func (p *PostgresDBAccess) deleteExpiredValuesQuery() string {
+	query := fmt.Sprintf(
+		`DELETE FROM %s WHERE expiredate IS NOT NULL AND expiredate < CURRENT_TIMESTAMP`,
+		p.metadata.TableName,
+	)
+	if !p.supportsReservations {
+		return query
+	}
+
+	// A single statement, so the number of deleted rows is the number of expired values
+	return fmt.Sprintf(
+		`WITH expired_reservations AS (DELETE FROM %s WHERE expiredate < CURRENT_TIMESTAMP) `,
+		p.reservationTableName(),
+	) + query
+}
+
+// ValidationReport returns the report created by Init in validate-only mode, or nil if the component wasn't initialized in that mode.
+func (p *PostgresDBAccess) ValidationReport() *state.ValidationReport {
+	return p.validationReport
+}
+
 func (p *PostgresDBAccess) GetDB() *pgxpool.Pool {
 	// We can safely cast to *pgxpool.Pool because this method is never used in unit tests where we mock the DB
 	return p.db.(*pgxpool.Pool)
@@ -154,7 +333,12 @@ func (p *PostgresDBAccess) GetDB() *pgxpool.Pool {
 
 // Set makes an insert or update to the database.
 func (p *PostgresDBAccess) Set(ctx context.Context, req *state.SetRequest) error {
-	return p.doSet(ctx, p.db, req)
+	if err := p.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+	return p.execAudited(ctx, "Set", *req, func(db dbquerier) error {
+		return p.doSet(ctx, db, req)
+	})
 }
 
 func (p *PostgresDBAccess) doSet(parentCtx context.Context, db dbquerier, req *state.SetRequest) error {
@@ -167,16 +351,11 @@ func (p *PostgresDBAccess) doSet(parentCtx context.Context, db dbquerier, req *s
 		return errors.New("missing key in set operation")
 	}
 
-	v := req.Value
-	byteArray, isBinary := req.Value.([]uint8)
-	if isBinary {
-		v = base64.StdEncoding.EncodeToString(byteArray)
+	value, isBinary, compressed, err := p.encodeValue(req.Value)
+	if err != nil {
+		return err
 	}
 
-	// Convert to json string
-	bt, _ := stateutils.Marshal(v, json.Marshal)
-	value := string(bt)
-
 	// TTL
 	var ttlSeconds int
 	ttl, ttlerr := stateutils.ParseTTL(req.Metadata)
@@ -209,17 +388,26 @@ func (p *PostgresDBAccess) doSet(parentCtx context.Context, db dbquerier, req *s
 		queryExpiredate = "NULL"
 	}
 
-	query := p.setQueryFn(req, SetQueryOptions{
+	setQueryOpts := SetQueryOptions{
 		TableName:       p.metadata.TableName,
 		ExpireDateValue: queryExpiredate,
-	})
+	}
+	if p.supportsCompression {
+		params = append(params, compressed)
+		setQueryOpts.CompressedValueParam = "$" + strconv.Itoa(len(params))
+	}
+
+	query := p.setQueryFn(req, setQueryOpts)
 
-	result, err := db.Exec(parentCtx, query, params...)
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, p.metadata.QueryTimeout)
+	defer cancel()
+	result, err := db.Exec(ctx, query, params...)
 	if err != nil {
-		return err
+		return internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
 	}
 	if result.RowsAffected() != 1 {
-		if req.ETag != nil && *req.ETag != "" {
+		// With first-write concurrency, no row is updated if the key exists
+		if req.ETag != nil && *req.ETag != "" || req.Options.Concurrency == state.FirstWrite {
 			return state.NewETagError(state.ETagMismatch, nil)
 		}
 		return errors.New("no item was updated")
@@ -228,13 +416,65 @@ func (p *PostgresDBAccess) doSet(parentCtx context.Context, db dbquerier, req *s
 	return nil
 }
 
+// encodeValue returns the values to store in the "value", "isbinary", and "compressedvalue" columns.
+func (p *PostgresDBAccess) encodeValue(v any) (value string, isBinary bool, compressed []byte, err error) {
+	if statecodec.IsJSON(p.codec) {
+		value, isBinary = marshalValue(v, p.metadata.JSONOptions)
+		value, compressed = p.compressor.compress(value)
+		return value, isBinary, compressed, nil
+	}
+
+	encoded, err := p.codec.Encode(v)
+	if err != nil {
+		return "", false, nil, err
+	}
+
+	// The "value" column contains only the queryable fields, if any
+	var doc string
+	if b, ok := v.([]byte); ok {
+		doc = string(b)
+	} else {
+		doc, _ = marshalValue(v, p.metadata.JSONOptions)
+	}
+	return p.compressor.queryableProjection(doc), false, p.compressor.pack(codecFormat(p.codec), encoded), nil
+}
+
+// marshalValue returns the JSON-encoded value to store, and whether the original value was binary.
+func marshalValue(v any, opts stateutils.JSONOptions) (string, bool) {
+	byteArray, isBinary := v.([]uint8)
+	if isBinary {
+		v = base64.StdEncoding.EncodeToString(byteArray)
+	}
+
+	// Convert to json string
+	bt, _ := stateutils.Marshal(v, opts.Marshal)
+	return string(bt), isBinary
+}
+
 func (p *PostgresDBAccess) BulkSet(parentCtx context.Context, req []state.SetRequest) error {
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return err
+	}
+	if p.useBulkLoad(req) {
+		return p.bulkLoad(parentCtx, req)
+	}
+
 	tx, err := p.beginTx(parentCtx)
 	if err != nil {
 		return err
 	}
 	defer p.rollbackTx(parentCtx, tx, "BulkSet")
 
+	ops := bulkOperations(req)
+	err = p.checkNotReserved(parentCtx, tx, ops)
+	if err != nil {
+		return err
+	}
+	err = p.writeAuditLog(parentCtx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
 	if len(req) > 0 {
 		for i := range req {
 			err = p.doSet(parentCtx, tx, &req[i])
@@ -256,26 +496,31 @@ func (p *PostgresDBAccess) BulkSet(parentCtx context.Context, req []state.SetReq
 
 // Get returns data from the database. If data does not exist for the key an empty state.GetResponse will be returned.
 func (p *PostgresDBAccess) Get(parentCtx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return nil, err
+	}
 	if req.Key == "" {
 		return nil, errors.New("missing key in get operation")
 	}
 
 	query := `SELECT
-			key, value, isbinary, ` + p.etagColumn + ` AS etag
+			key, value, isbinary, ` + p.etagColumn + ` AS etag` + p.selectCompressedValue() + `
 		FROM ` + p.metadata.TableName + `
 			WHERE
 				key = $1
 				AND (expiredate IS NULL OR expiredate >= CURRENT_TIMESTAMP)`
-	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	opCtx, opCancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer opCancel()
+	ctx, cancel := internalsql.WithQueryTimeout(opCtx, p.metadata.QueryTimeout)
 	defer cancel()
 	row := p.db.QueryRow(ctx, query, req.Key)
-	_, value, etag, err := readRow(row)
+	_, value, etag, err := readRow(row, p.supportsCompression)
 	if err != nil {
 		// If no rows exist, return an empty response, otherwise return the error.
 		if errors.Is(err, pgx.ErrNoRows) {
 			return &state.GetResponse{}, nil
 		}
-		return nil, err
+		return nil, internalsql.WrapQueryTimeoutError(opCtx, ctx, err)
 	}
 
 	return &state.GetResponse{
@@ -285,6 +530,9 @@ func (p *PostgresDBAccess) Get(parentCtx context.Context, req *state.GetRequest)
 }
 
 func (p *PostgresDBAccess) BulkGet(parentCtx context.Context, req []state.GetRequest) ([]state.BulkGetResponse, error) {
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return nil, err
+	}
 	if len(req) == 0 {
 		return []state.BulkGetResponse{}, nil
 	}
@@ -297,7 +545,7 @@ func (p *PostgresDBAccess) BulkGet(parentCtx context.Context, req []state.GetReq
 
 	// Execute the query
 	query := `SELECT
-			key, value, isbinary, ` + p.etagColumn + ` AS etag
+			key, value, isbinary, ` + p.etagColumn + ` AS etag` + p.selectCompressedValue() + `
 		FROM ` + p.metadata.TableName + `
 			WHERE
 				key = ANY($1)
@@ -321,7 +569,7 @@ func (p *PostgresDBAccess) BulkGet(parentCtx context.Context, req []state.GetReq
 	res := make([]state.BulkGetResponse, len(req))
 	for ; rows.Next(); n++ {
 		r := state.BulkGetResponse{}
-		r.Key, r.Data, etag, err = readRow(rows)
+		r.Key, r.Data, etag, err = readRow(rows, p.supportsCompression)
 		if err != nil {
 			r.Error = err.Error()
 		}
@@ -332,12 +580,26 @@ func (p *PostgresDBAccess) BulkGet(parentCtx context.Context, req []state.GetReq
 	return res[:n], nil
 }
 
-func readRow(row pgx.Row) (key string, value []byte, etagS string, err error) {
+// selectCompressedValue returns the part of a SELECT statement that adds the compressed value column, if supported.
+func (p *PostgresDBAccess) selectCompressedValue() string {
+	if !p.supportsCompression {
+		return ""
+	}
+	return ", " + compressedValueColumn
+}
+
+// readRow reads a row with the key, value, isbinary, and etag columns, followed by the compressed value column if withCompressed is true.
+func readRow(row pgx.Row, withCompressed bool) (key string, value []byte, etagS string, err error) {
 	var (
-		isBinary bool
-		etag     pgtype.Int8
+		isBinary   bool
+		etag       pgtype.Int8
+		compressed []byte
 	)
-	err = row.Scan(&key, &value, &isBinary, &etag)
+	dest := []any{&key, &value, &isBinary, &etag}
+	if withCompressed {
+		dest = append(dest, &compressed)
+	}
+	err = row.Scan(dest...)
 	if err != nil {
 		return key, nil, "", err
 	}
@@ -346,6 +608,13 @@ func readRow(row pgx.Row) (key string, value []byte, etagS string, err error) {
 		etagS = strconv.FormatInt(etag.Int64, 10)
 	}
 
+	if compressed != nil {
+		value, err = decompressValue(compressed)
+		if err != nil {
+			return key, nil, "", err
+		}
+	}
+
 	if isBinary {
 		var (
 			s    string
@@ -370,7 +639,12 @@ func readRow(row pgx.Row) (key string, value []byte, etagS string, err error) {
 
 // Delete removes an item from the state store.
 func (p *PostgresDBAccess) Delete(ctx context.Context, req *state.DeleteRequest) (err error) {
-	return p.doDelete(ctx, p.db, req)
+	if err := p.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+	return p.execAudited(ctx, "Delete", *req, func(db dbquerier) error {
+		return p.doDelete(ctx, db, req)
+	})
 }
 
 func (p *PostgresDBAccess) doDelete(parentCtx context.Context, db dbquerier, req *state.DeleteRequest) (err error) {
@@ -378,7 +652,9 @@ func (p *PostgresDBAccess) doDelete(parentCtx context.Context, db dbquerier, req
 		return errors.New("missing key in delete operation")
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	opCtx, opCancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer opCancel()
+	ctx, cancel := internalsql.WithQueryTimeout(opCtx, p.metadata.QueryTimeout)
 	defer cancel()
 	var result pgconn.CommandTag
 	if req.ETag == nil || *req.ETag == "" {
@@ -394,7 +670,7 @@ func (p *PostgresDBAccess) doDelete(parentCtx context.Context, db dbquerier, req
 		result, err = db.Exec(ctx, "DELETE FROM "+p.metadata.TableName+" WHERE key = $1 AND $2 = "+p.etagColumn, req.Key, uint32(etag64))
 	}
 	if err != nil {
-		return err
+		return internalsql.WrapQueryTimeoutError(opCtx, ctx, err)
 	}
 
 	rows := result.RowsAffected()
@@ -406,12 +682,25 @@ func (p *PostgresDBAccess) doDelete(parentCtx context.Context, db dbquerier, req
 }
 
 func (p *PostgresDBAccess) BulkDelete(parentCtx context.Context, req []state.DeleteRequest) error {
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return err
+	}
 	tx, err := p.beginTx(parentCtx)
 	if err != nil {
 		return err
 	}
 	defer p.rollbackTx(parentCtx, tx, "BulkDelete")
 
+	ops := bulkOperations(req)
+	err = p.checkNotReserved(parentCtx, tx, ops)
+	if err != nil {
+		return err
+	}
+	err = p.writeAuditLog(parentCtx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
 	if len(req) > 0 {
 		for i := range req {
 			err = p.doDelete(parentCtx, tx, &req[i])
@@ -432,12 +721,24 @@ func (p *PostgresDBAccess) BulkDelete(parentCtx context.Context, req []state.Del
 }
 
 func (p *PostgresDBAccess) ExecuteMulti(parentCtx context.Context, request *state.TransactionalStateRequest) error {
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return err
+	}
 	tx, err := p.beginTx(parentCtx)
 	if err != nil {
 		return err
 	}
 	defer p.rollbackTx(parentCtx, tx, "ExecMulti")
 
+	err = p.checkNotReserved(parentCtx, tx, request.Operations)
+	if err != nil {
+		return err
+	}
+	err = p.writeAuditLog(parentCtx, tx, request.Operations...)
+	if err != nil {
+		return err
+	}
+
 	for _, o := range request.Operations {
 		switch x := o.(type) {
 		case state.SetRequest:
@@ -469,19 +770,18 @@ func (p *PostgresDBAccess) ExecuteMulti(parentCtx context.Context, request *stat
 
 // Query executes a query against store.
 func (p *PostgresDBAccess) Query(parentCtx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
-	q := &Query{
-		query:      "",
-		params:     []any{},
-		tableName:  p.metadata.TableName,
-		etagColumn: p.etagColumn,
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return nil, err
 	}
-	qbuilder := query.NewQueryBuilder(q)
-	if err := qbuilder.BuildQuery(&req.Query); err != nil {
+	q, err := p.buildQuery(req)
+	if err != nil {
 		return &state.QueryResponse{}, err
 	}
-	data, token, err := q.execute(parentCtx, p.logger, p.db)
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, p.metadata.QueryTimeout)
+	defer cancel()
+	data, token, err := q.execute(ctx, p.logger, p.db)
 	if err != nil {
-		return &state.QueryResponse{}, err
+		return &state.QueryResponse{}, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
 	}
 
 	return &state.QueryResponse{
@@ -490,6 +790,62 @@ func (p *PostgresDBAccess) Query(parentCtx context.Context, req *state.QueryRequ
 	}, nil
 }
 
+// QueryStream executes a query against store, invoking fn with each page of results.
+// Rows are read from the database as pages are consumed, and all pages come from the same snapshot, as they're read from a single statement.
+// The query timeout doesn't apply, as the duration of the stream depends on the callback; the stream is bound by the context.
+func (p *PostgresDBAccess) QueryStream(ctx context.Context, req *state.QueryRequest, fetchSize int, fn state.QueryPageFn) error {
+	if err := p.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+	q, err := p.buildQuery(req)
+	if err != nil {
+		return err
+	}
+
+	pager := state.NewQueryPager(fetchSize, q.offset(), fn)
+	err = q.iterate(ctx, p.db, pager.Add)
+	if err != nil {
+		return err
+	}
+	return pager.Flush()
+}
+
+// validateConn returns true if the pooled connection passes the validation query, or the ping if no validation query is set.
+func (p *PostgresDBAccess) validateConn(ctx context.Context, conn *pgx.Conn) bool {
+	var err error
+	if p.metadata.ValidationQuery != "" {
+		_, err = conn.Exec(ctx, p.metadata.ValidationQuery)
+	} else {
+		err = conn.Ping(ctx)
+	}
+	if err != nil {
+		p.logger.Debugf("Evicting connection that failed validation: %v", err)
+		return false
+	}
+	return true
+}
+
+// buildQuery returns the SQL query for a request of the Query API.
+func (p *PostgresDBAccess) buildQuery(req *state.QueryRequest) (*Query, error) {
+	q := &Query{
+		query:      "",
+		params:     []any{},
+		tableName:  p.metadata.TableName,
+		etagColumn: p.etagColumn,
+
+		withCompressed: p.supportsCompression,
+	}
+	if p.metadata.QueryBypassStatementCache {
+		// Statements are described on each execution and never cached
+		q.execMode = pgx.QueryExecModeDescribeExec
+	}
+	qbuilder := query.NewQueryBuilder(q)
+	if err := qbuilder.BuildQuery(&req.Query); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
 func (p *PostgresDBAccess) CleanupExpired() error {
 	if p.gc != nil {
 		return p.gc.CleanupExpired()
@@ -497,6 +853,19 @@ func (p *PostgresDBAccess) CleanupExpired() error {
 	return nil
 }
 
+// Ping checks that the database is reachable.
+// It acquires a connection from the pool only for the duration of the round-trip.
+func (p *PostgresDBAccess) Ping(parentCtx context.Context) error {
+	if p.db == nil {
+		return errors.New("database connection not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	err := p.db.Ping(ctx)
+	cancel()
+	return err
+}
+
 // Close implements io.Close.
 func (p *PostgresDBAccess) Close() error {
 	if p.db != nil {
@@ -520,7 +889,7 @@ func (p *PostgresDBAccess) GetCleanupInterval() *time.Duration {
 // Internal function that begins a transaction.
 func (p *PostgresDBAccess) beginTx(parentCtx context.Context) (pgx.Tx, error) {
 	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
-	tx, err := p.db.Begin(ctx)
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: p.metadata.txIsoLevel})
 	cancel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
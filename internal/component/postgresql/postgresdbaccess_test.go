@@ -22,9 +22,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	pgxmock "github.com/pashagolub/pgxmock/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
@@ -38,6 +43,57 @@ type fakeItem struct {
 	Color string
 }
 
+func TestUnixSocketPath(t *testing.T) {
+	assert.Equal(t, "/var/run/postgresql/.s.PGSQL.5432", unixSocketPath("/var/run/postgresql", 5432))
+	assert.Equal(t, "", unixSocketPath("localhost", 5432))
+}
+
+func TestInitWithMissingUnixSocket(t *testing.T) {
+	dba := newPostgresDBAccess(logger.NewLogger("test"), Options{})
+	err := dba.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString": "host=" + t.TempDir() + " user=postgres",
+	}}})
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+func TestInitWithMissingSecret(t *testing.T) {
+	dba := newPostgresDBAccess(logger.NewLogger("test"), Options{})
+	err := dba.Init(context.Background(), state.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"host":     "localhost",
+			"user":     "postgres",
+			"password": "secretref://vault/db#password",
+		},
+		SecretResolver: secretstores.NewMetadataSecretResolver(nil),
+	}})
+	assert.ErrorContains(t, err, "failed to resolve secret 'db' in store 'vault' for metadata property 'password'")
+}
+
+func TestLazyInit(t *testing.T) {
+	var migrations int
+	dba := newPostgresDBAccess(logger.NewLogger("test"), Options{
+		MigrateFn: func(context.Context, PGXPoolConn, MigrateOptions) error {
+			migrations++
+			return nil
+		},
+	})
+	defer dba.Close()
+
+	// Nothing is listening on this port, but Init doesn't connect
+	err := dba.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString": "host=127.0.0.1 port=1 user=postgres connect_timeout=1",
+		"lazyInit":         "true",
+		"timeoutInSeconds": "1",
+	}}})
+	assert.NoError(t, err)
+	assert.NotNil(t, dba.lazyInit)
+
+	// The first operation tries to connect and fails, without running migrations
+	_, err = dba.Get(context.Background(), &state.GetRequest{Key: "key"})
+	assert.ErrorContains(t, err, "failed to ping the database")
+	assert.Equal(t, 0, migrations)
+}
+
 func TestMultiWithNoRequests(t *testing.T) {
 	// Arrange
 	m, _ := mockDatabase(t)
@@ -59,6 +115,25 @@ func TestMultiWithNoRequests(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestMultiWithIsolationLevel(t *testing.T) {
+	// Arrange
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+	m.pgDba.metadata.txIsoLevel = pgx.Serializable
+
+	m.db.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.Serializable})
+	m.db.ExpectCommit()
+	// There's also a rollback called after a commit, which is expected and will not have effect
+	m.db.ExpectRollback()
+
+	// Act
+	err := m.pgDba.ExecuteMulti(context.Background(), &state.TransactionalStateRequest{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, m.db.ExpectationsWereMet())
+}
+
 func TestValidSetRequest(t *testing.T) {
 	// Arrange
 	m, _ := mockDatabase(t)
@@ -292,6 +367,125 @@ func TestValidBulkDelete(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSetFirstWriteConflict(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+
+	// The upsert doesn't update the row if the key exists and isn't expired
+	m.db.ExpectExec("INSERT INTO").
+		WithArgs("key", `"v"`, false).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	err := m.pgDba.Set(context.Background(), &state.SetRequest{
+		Key:     "key",
+		Value:   "v",
+		Options: state.SetStateOption{Concurrency: state.FirstWrite},
+	})
+	var etagErr *state.ETagError
+	require.ErrorAs(t, err, &etagErr)
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+}
+
+func TestQueryTimeout(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+	m.pgDba.metadata.QueryTimeout = 10 * time.Millisecond
+
+	t.Run("get", func(t *testing.T) {
+		m.db.ExpectQuery("SELECT").
+			WithArgs("key").
+			WillDelayFor(time.Second).
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "isbinary", "etag"}))
+
+		_, err := m.pgDba.Get(context.Background(), &state.GetRequest{Key: "key"})
+		assert.ErrorIs(t, err, internalsql.ErrQueryTimeout)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		m.db.ExpectExec("DELETE FROM").
+			WithArgs("key").
+			WillDelayFor(time.Second).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		err := m.pgDba.Delete(context.Background(), &state.DeleteRequest{Key: "key"})
+		assert.ErrorIs(t, err, internalsql.ErrQueryTimeout)
+	})
+
+	t.Run("parent context canceled", func(t *testing.T) {
+		m.pgDba.metadata.QueryTimeout = time.Minute
+		m.db.ExpectExec("DELETE FROM").
+			WithArgs("key").
+			WillDelayFor(time.Second).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := m.pgDba.Delete(ctx, &state.DeleteRequest{Key: "key"})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, internalsql.ErrQueryTimeout)
+	})
+}
+
+func TestQueryBypassStatementCache(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+
+	req := &state.QueryRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"filter": {"EQ": {"city": "Seattle"}}}`), &req.Query))
+
+	t.Run("default mode", func(t *testing.T) {
+		m.db.ExpectQuery("SELECT key, value").
+			WithArgs("Seattle").
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "etag"}))
+
+		_, err := m.pgDba.Query(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("bypass", func(t *testing.T) {
+		m.pgDba.metadata.QueryBypassStatementCache = true
+		m.db.ExpectQuery("SELECT key, value").
+			WithArgs(pgx.QueryExecModeDescribeExec, "Seattle").
+			WillReturnRows(pgxmock.NewRows([]string{"key", "value", "etag"}))
+
+		_, err := m.pgDba.Query(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+}
+
+func TestQueryStream(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.db.Close()
+
+	req := &state.QueryRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"page": {"token": "10"}}`), &req.Query))
+
+	m.db.ExpectQuery("SELECT key, value").
+		WillReturnRows(pgxmock.NewRows([]string{"key", "value", "etag"}).
+			AddRow("k1", []byte(`1`), uint32(1)).
+			AddRow("k2", []byte(`2`), uint32(2)).
+			AddRow("k3", []byte(`3`), uint32(3)))
+
+	var pages []*state.QueryResponse
+	err := m.pgDba.QueryStream(context.Background(), req, 2, func(page *state.QueryResponse) error {
+		pages = append(pages, page)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.db.ExpectationsWereMet())
+
+	require.Len(t, pages, 2)
+	require.Len(t, pages[0].Results, 2)
+	assert.Equal(t, "k1", pages[0].Results[0].Key)
+	assert.Equal(t, "2", *pages[0].Results[1].ETag)
+	assert.Equal(t, "12", pages[0].Token)
+	require.Len(t, pages[1].Results, 1)
+	assert.Equal(t, "k3", pages[1].Results[0].Key)
+	assert.Equal(t, "13", pages[1].Token)
+}
+
 func createSetRequest() state.SetRequest {
 	return state.SetRequest{
 		Key:   randomKey(),
@@ -15,6 +15,7 @@ package postgresql
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 
 	"github.com/dapr/components-contrib/metadata"
@@ -28,23 +29,49 @@ type PostgreSQL struct {
 
 	logger   logger.Logger
 	dbaccess dbAccess
+
+	// Performs the read-modify-write updates of SetWithMerge, retrying them on ETag conflicts as configured by the "etagRetry" metadata properties
+	etagRetrier *state.ETagRetrier
 }
 
 type Options struct {
 	MigrateFn  func(context.Context, PGXPoolConn, MigrateOptions) error
 	SetQueryFn func(*state.SetRequest, SetQueryOptions) string
 	ETagColumn string
+
+	// SupportsBulkLoad enables loading data with COPY in BulkSet, when the "bulkLoadThreshold" metadata property is set.
+	// The state table must have no columns other than key, value, isbinary and expiredate without a default value.
+	SupportsBulkLoad bool
+
+	// SupportsCompression indicates that the state table has the "compressedvalue" column, which enables compressing values.
+	// When true, SetQueryFn must store the parameter named in SetQueryOptions.CompressedValueParam in that column.
+	SupportsCompression bool
+
+	// PlanMigrationsFn is invoked instead of MigrateFn when the component is initialized in validate-only mode.
+	// It records the changes that MigrateFn would apply, and the missing permissions, in the report.
+	// If nil, the validate-only mode is not supported.
+	PlanMigrationsFn func(context.Context, PGXPoolConn, MigrateOptions, *state.ValidationReport) error
+
+	// SupportsReservations indicates that MigrateFn creates the table named in MigrateOptions.ReservationTableName, which enables the Reserver methods.
+	SupportsReservations bool
+
+	// SupportsListKeys indicates that MigrateFn creates an index on the key column with the text_pattern_ops operator class, which enables ListKeys.
+	SupportsListKeys bool
 }
 
 type MigrateOptions struct {
 	Logger            logger.Logger
 	StateTableName    string
 	MetadataTableName string
+	// Name of the table with the staged operations of reservations, used only if Options.SupportsReservations is true
+	ReservationTableName string
 }
 
 type SetQueryOptions struct {
 	TableName       string
 	ExpireDateValue string
+	// Placeholder of the parameter with the value of the "compressedvalue" column, such as "$4"; empty if compression is not supported
+	CompressedValueParam string
 }
 
 // NewPostgreSQLStateStore creates a new instance of PostgreSQL state store.
@@ -66,9 +93,30 @@ func newPostgreSQLStateStore(logger logger.Logger, dba dbAccess) *PostgreSQL {
 
 // Init initializes the SQL server state store.
 func (p *PostgreSQL) Init(ctx context.Context, metadata state.Metadata) error {
+	etagRetryConfig, err := state.ParseETagRetryConfig(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("failed to parse ETag retry configuration: %w", err)
+	}
+	p.etagRetrier = state.NewETagRetrier(p, etagRetryConfig)
+
 	return p.dbaccess.Init(ctx, metadata)
 }
 
+// ValidationReport returns the report created by Init in validate-only mode.
+func (p *PostgreSQL) ValidationReport() *state.ValidationReport {
+	return p.dbaccess.ValidationReport()
+}
+
+// SetAuditLogStore sets the state store that the audit log is saved to. Implements state.AuditLogSinkSetter.
+func (p *PostgreSQL) SetAuditLogStore(store state.Store) error {
+	return p.dbaccess.SetAuditLogStore(store)
+}
+
+// SetAuditLogPublisher sets the function that publishes the audit log. Implements state.AuditLogSinkSetter.
+func (p *PostgreSQL) SetAuditLogPublisher(publish state.AuditLogPublishFn) error {
+	return p.dbaccess.SetAuditLogPublisher(publish)
+}
+
 // Features returns the features available in this state store.
 func (p *PostgreSQL) Features() []state.Feature {
 	return []state.Feature{state.FeatureETag, state.FeatureTransactional, state.FeatureQueryAPI}
@@ -100,6 +148,11 @@ func (p *PostgreSQL) Set(ctx context.Context, req *state.SetRequest) error {
 	return p.dbaccess.Set(ctx, req)
 }
 
+// SetWithMerge updates a key with the value returned by merge, retrying on ETag conflicts. Implements state.MergeSetter.
+func (p *PostgreSQL) SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge state.MergeFn) error {
+	return p.etagRetrier.SetWithMerge(ctx, key, metadata, merge)
+}
+
 // BulkSet adds/updates multiple entities on store.
 func (p *PostgreSQL) BulkSet(ctx context.Context, req []state.SetRequest) error {
 	return p.dbaccess.BulkSet(ctx, req)
@@ -115,6 +168,36 @@ func (p *PostgreSQL) Query(ctx context.Context, req *state.QueryRequest) (*state
 	return p.dbaccess.Query(ctx, req)
 }
 
+// QueryStream executes a query against store, invoking fn with each page of results. Implements StreamingQuerier.
+func (p *PostgreSQL) QueryStream(ctx context.Context, req *state.QueryRequest, fetchSize int, fn state.QueryPageFn) error {
+	return p.dbaccess.QueryStream(ctx, req, fetchSize, fn)
+}
+
+// Reserve stages operations on a set of keys, to be committed atomically later. Implements Reserver.
+func (p *PostgreSQL) Reserve(ctx context.Context, req *state.ReserveRequest) error {
+	return p.dbaccess.Reserve(ctx, req)
+}
+
+// CommitReservation applies the operations staged in a reservation. Implements Reserver.
+func (p *PostgreSQL) CommitReservation(ctx context.Context, id string, _ map[string]string) error {
+	return p.dbaccess.CommitReservation(ctx, id)
+}
+
+// RollbackReservation discards the operations staged in a reservation. Implements Reserver.
+func (p *PostgreSQL) RollbackReservation(ctx context.Context, id string, _ map[string]string) error {
+	return p.dbaccess.RollbackReservation(ctx, id)
+}
+
+// ListKeys returns a page of the keys with a prefix. Implements KeyLister.
+func (p *PostgreSQL) ListKeys(ctx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error) {
+	return p.dbaccess.ListKeys(ctx, req)
+}
+
+// Ping checks that the database is reachable.
+func (p *PostgreSQL) Ping(ctx context.Context) error {
+	return p.dbaccess.Ping(ctx)
+}
+
 // Close implements io.Closer.
 func (p *PostgreSQL) Close() error {
 	if p.dbaccess != nil {
@@ -20,6 +20,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
+
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
 	"github.com/dapr/kit/logger"
@@ -33,6 +35,11 @@ type Query struct {
 	skip       *int64
 	tableName  string
 	etagColumn string
+
+	// If true, the query also selects the compressed value column
+	withCompressed bool
+	// If not 0, the mode the query is executed with, overriding the default of the connection
+	execMode pgx.QueryExecMode
 }
 
 func (q *Query) VisitEQ(f *query.EQ) (string, error) {
@@ -103,7 +110,11 @@ func (q *Query) VisitOR(f *query.OR) (string, error) {
 }
 
 func (q *Query) Finalize(filters string, qq *query.Query) error {
-	q.query = fmt.Sprintf("SELECT key, value, %s as etag FROM "+q.tableName, q.etagColumn)
+	q.query = fmt.Sprintf("SELECT key, value, %s as etag", q.etagColumn)
+	if q.withCompressed {
+		q.query += ", " + compressedValueColumn
+	}
+	q.query += " FROM " + q.tableName
 
 	if filters != "" {
 		q.query += " WHERE " + filters
@@ -141,44 +152,75 @@ func (q *Query) Finalize(filters string, qq *query.Query) error {
 }
 
 func (q *Query) execute(ctx context.Context, logger logger.Logger, db dbquerier) ([]state.QueryItem, string, error) {
-	rows, err := db.Query(ctx, q.query, q.params...)
+	ret := []state.QueryItem{}
+	err := q.iterate(ctx, db, func(item state.QueryItem) error {
+		ret = append(ret, item)
+		return nil
+	})
 	if err != nil {
 		return nil, "", err
 	}
+
+	var token string
+	if q.limit != 0 {
+		token = strconv.FormatInt(q.offset()+int64(len(ret)), 10)
+	}
+
+	return ret, token, nil
+}
+
+// iterate executes the query and invokes fn for each result, as rows are read from the database.
+func (q *Query) iterate(ctx context.Context, db dbquerier, fn func(item state.QueryItem) error) error {
+	args := q.params
+	if q.execMode != 0 {
+		// pgx reads the execution mode from the first argument
+		args = append([]any{q.execMode}, q.params...)
+	}
+	rows, err := db.Query(ctx, q.query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	ret := []state.QueryItem{}
 	for rows.Next() {
 		var (
-			key  string
-			data []byte
-			etag uint32
+			key        string
+			data       []byte
+			etag       uint32
+			compressed []byte
 		)
-		if err = rows.Scan(&key, &data, &etag); err != nil {
-			return nil, "", err
+		dest := []any{&key, &data, &etag}
+		if q.withCompressed {
+			dest = append(dest, &compressed)
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return err
+		}
+		if compressed != nil {
+			data, err = decompressValue(compressed)
+			if err != nil {
+				return err
+			}
 		}
-		result := state.QueryItem{
+		err = fn(state.QueryItem{
 			Key:  key,
 			Data: data,
 			ETag: ptr.Of(strconv.FormatUint(uint64(etag), 10)),
+		})
+		if err != nil {
+			return err
 		}
-		ret = append(ret, result)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, "", err
-	}
+	return rows.Err()
+}
 
-	var token string
-	if q.limit != 0 {
-		var skip int64
-		if q.skip != nil {
-			skip = *q.skip
-		}
-		token = strconv.FormatInt(skip+int64(len(ret)), 10)
+// offset returns the number of results skipped, from the token of the query.
+func (q *Query) offset() int64 {
+	if q.skip == nil {
+		return 0
 	}
-
-	return ret, token, nil
+	return *q.skip
 }
 
 func (q *Query) addParamValueAndReturnPosition(value interface{}) int {
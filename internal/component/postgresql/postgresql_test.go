@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
@@ -36,6 +37,10 @@ type fakeDBaccess struct {
 	setExecuted    bool
 	getExecuted    bool
 	deleteExecuted bool
+	pingExecuted   bool
+	// Errors returned by the next invocations of Set
+	setErrs  []error
+	setCalls int
 }
 
 func (m *fakeDBaccess) Init(ctx context.Context, metadata state.Metadata) error {
@@ -46,7 +51,13 @@ func (m *fakeDBaccess) Init(ctx context.Context, metadata state.Metadata) error
 
 func (m *fakeDBaccess) Set(ctx context.Context, req *state.SetRequest) error {
 	m.setExecuted = true
+	m.setCalls++
 
+	if len(m.setErrs) > 0 {
+		err := m.setErrs[0]
+		m.setErrs = m.setErrs[1:]
+		return err
+	}
 	return nil
 }
 
@@ -82,6 +93,44 @@ func (m *fakeDBaccess) Query(ctx context.Context, req *state.QueryRequest) (*sta
 	return nil, nil
 }
 
+func (m *fakeDBaccess) QueryStream(ctx context.Context, req *state.QueryRequest, fetchSize int, fn state.QueryPageFn) error {
+	return nil
+}
+
+func (m *fakeDBaccess) Reserve(ctx context.Context, req *state.ReserveRequest) error {
+	return nil
+}
+
+func (m *fakeDBaccess) CommitReservation(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *fakeDBaccess) RollbackReservation(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *fakeDBaccess) ListKeys(ctx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error) {
+	return nil, nil
+}
+
+func (m *fakeDBaccess) ValidationReport() *state.ValidationReport {
+	return nil
+}
+
+func (m *fakeDBaccess) SetAuditLogStore(store state.Store) error {
+	return nil
+}
+
+func (m *fakeDBaccess) SetAuditLogPublisher(publish state.AuditLogPublishFn) error {
+	return nil
+}
+
+func (m *fakeDBaccess) Ping(ctx context.Context) error {
+	m.pingExecuted = true
+
+	return nil
+}
+
 func (m *fakeDBaccess) Close() error {
 	return nil
 }
@@ -93,6 +142,49 @@ func TestInitRunsDBAccessInit(t *testing.T) {
 	assert.True(t, fake.initExecuted)
 }
 
+// Proves that the Ping method runs the ping method.
+func TestPingRunsDBAccessPing(t *testing.T) {
+	t.Parallel()
+	pgs, fake := createPostgreSQLWithFake(t)
+	err := pgs.Ping(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, fake.pingExecuted)
+}
+
+func TestSetWithMerge(t *testing.T) {
+	t.Parallel()
+	pgs, fake := createPostgreSQLWithFake(t)
+	conflict := state.NewETagError(state.ETagMismatch, nil)
+
+	// Retries are disabled by default
+	fake.setErrs = []error{conflict}
+	err := pgs.SetWithMerge(context.Background(), "key", nil, func(*state.GetResponse) (any, error) {
+		return "v", nil
+	})
+	require.ErrorIs(t, err, conflict)
+	assert.Equal(t, 1, fake.setCalls)
+
+	err = pgs.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString":    fakeConnectionString,
+		"etagRetryMaxRetries": "3",
+		"etagRetryDuration":   "1ms",
+	}}})
+	require.NoError(t, err)
+	fake.setCalls = 0
+	fake.setErrs = []error{conflict, conflict}
+	err = pgs.SetWithMerge(context.Background(), "key", nil, func(*state.GetResponse) (any, error) {
+		return "v", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, fake.setCalls)
+
+	err = pgs.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"connectionString":    fakeConnectionString,
+		"etagRetryMaxRetries": "many",
+	}}})
+	require.Error(t, err)
+}
+
 func createPostgreSQLWithFake(t *testing.T) (*PostgreSQL, *fakeDBaccess) {
 	pgs := createPostgreSQL(t)
 	fake := pgs.dbaccess.(*fakeDBaccess)
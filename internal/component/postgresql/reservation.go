@@ -0,0 +1,286 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/state"
+)
+
+// reservationTableSuffix is appended to the name of the state table to get the name of the table with the reservations.
+const reservationTableSuffix = "_reservations"
+
+var errReservationsUnsupported = errors.New("reservations are not supported by this component")
+
+// reservationTableName returns the name of the table with the reservations, in the same schema as the state table.
+func (p *PostgresDBAccess) reservationTableName() string {
+	return p.metadata.TableName + reservationTableSuffix
+}
+
+// Reserve stages the operations in the reservation table, with one row per key.
+// Rows of expired reservations are replaced, so keys are never reserved for longer than the TTL.
+// Until the reservation ends, Set, Delete, Multi and the bulk operations on the reserved keys fail with an ETag mismatch.
+func (p *PostgresDBAccess) Reserve(parentCtx context.Context, req *state.ReserveRequest) error {
+	if !p.supportsReservations {
+		return errReservationsUnsupported
+	}
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return err
+	}
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.beginTx(parentCtx)
+	if err != nil {
+		return err
+	}
+	defer p.rollbackTx(parentCtx, tx, "Reserve")
+
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer cancel()
+
+	query := `INSERT INTO ` + p.reservationTableName() + ` AS r
+			(key, reservationid, status, operation, expiredate)
+		VALUES
+			($1, $2, '` + state.ReservationStatusReserved + `', $3, CURRENT_TIMESTAMP + interval '` + strconv.FormatInt(req.TTL.Milliseconds(), 10) + ` milliseconds')
+		ON CONFLICT (key)
+		DO UPDATE SET
+			reservationid = excluded.reservationid,
+			status = excluded.status,
+			operation = excluded.operation,
+			expiredate = excluded.expiredate
+		WHERE r.expiredate < CURRENT_TIMESTAMP
+			OR (r.reservationid = excluded.reservationid AND r.status = '` + state.ReservationStatusReserved + `')`
+	for _, o := range req.Operations {
+		op, err := state.MarshalStagedOperation(o)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(ctx, query, o.GetKey(), req.ID, string(op))
+		if err != nil {
+			return fmt.Errorf("failed to reserve key '%s': %w", o.GetKey(), err)
+		}
+		if res.RowsAffected() != 1 {
+			return fmt.Errorf("failed to reserve key '%s': %w", o.GetKey(), state.ErrKeyReserved)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CommitReservation applies the staged operations and marks the reservation as committed, in a single transaction.
+// Committed reservations are kept until they expire, so committing again is a no-op.
+func (p *PostgresDBAccess) CommitReservation(parentCtx context.Context, id string) error {
+	if !p.supportsReservations {
+		return errReservationsUnsupported
+	}
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return err
+	}
+
+	tx, err := p.beginTx(parentCtx)
+	if err != nil {
+		return err
+	}
+	defer p.rollbackTx(parentCtx, tx, "CommitReservation")
+
+	ops, committed, err := p.lockReservation(parentCtx, tx, id)
+	if err != nil {
+		return err
+	}
+	if committed {
+		return nil
+	}
+
+	err = p.writeAuditLog(parentCtx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range ops {
+		switch x := o.(type) {
+		case state.SetRequest:
+			x.Value, err = p.stagedValue(x.Value)
+			if err != nil {
+				return err
+			}
+			err = p.doSet(parentCtx, tx, &x)
+		case state.DeleteRequest:
+			err = p.doDelete(parentCtx, tx, &x)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply the operation on key '%s': %w", o.GetKey(), err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer cancel()
+	_, err = tx.Exec(ctx,
+		`UPDATE `+p.reservationTableName()+` SET status = '`+state.ReservationStatusCommitted+`' WHERE reservationid = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reservation: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// lockReservation locks the rows of a reservation that hasn't expired, and returns its staged operations, or true if it was already committed.
+func (p *PostgresDBAccess) lockReservation(parentCtx context.Context, db dbquerier, id string) ([]state.TransactionalStateOperation, bool, error) {
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer cancel()
+
+	rows, err := db.Query(ctx,
+		`SELECT status, operation FROM `+p.reservationTableName()+`
+		WHERE reservationid = $1 AND expiredate >= CURRENT_TIMESTAMP
+		ORDER BY key
+		FOR UPDATE`,
+		id,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read reservation: %w", err)
+	}
+	defer rows.Close()
+
+	var ops []state.TransactionalStateOperation
+	for rows.Next() {
+		var (
+			status string
+			op     []byte
+		)
+		err = rows.Scan(&status, &op)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read reservation: %w", err)
+		}
+		if status == state.ReservationStatusCommitted {
+			return nil, true, nil
+		}
+		o, err := state.UnmarshalStagedOperation(op)
+		if err != nil {
+			return nil, false, err
+		}
+		ops = append(ops, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read reservation: %w", err)
+	}
+	if len(ops) == 0 {
+		return nil, false, state.ErrReservationNotFound
+	}
+	return ops, false, nil
+}
+
+// stagedValue returns the value of a staged set operation in the form expected by the codec.
+// Staged values are stored as JSON, which is passed through as-is unless values are serialized with another codec.
+func (p *PostgresDBAccess) stagedValue(v any) (any, error) {
+	raw, ok := v.(json.RawMessage)
+	if !ok || statecodec.IsJSON(p.codec) {
+		return v, nil
+	}
+
+	var decoded any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	err := dec.Decode(&decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse staged value: %w", err)
+	}
+	return decoded, nil
+}
+
+// RollbackReservation deletes the rows of the reservation, releasing the keys.
+func (p *PostgresDBAccess) RollbackReservation(parentCtx context.Context, id string) error {
+	if !p.supportsReservations {
+		return errReservationsUnsupported
+	}
+	if err := p.lazyInit.Do(parentCtx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, p.metadata.Timeout)
+	defer cancel()
+
+	// Committed reservations are not deleted, so a rollback after a commit is reported
+	var committed bool
+	err := p.db.QueryRow(ctx,
+		`WITH deleted AS (
+			DELETE FROM `+p.reservationTableName()+` WHERE reservationid = $1 AND status = '`+state.ReservationStatusReserved+`'
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM `+p.reservationTableName()+`
+			WHERE reservationid = $1 AND status = '`+state.ReservationStatusCommitted+`' AND expiredate >= CURRENT_TIMESTAMP
+		)`,
+		id,
+	).Scan(&committed)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to roll back reservation: %w", err)
+	}
+	if committed {
+		return state.ErrReservationCommitted
+	}
+	return nil
+}
+
+// checkNotReserved returns an ETag mismatch wrapping state.ErrKeyReserved if any of the keys is reserved by a reservation that hasn't expired.
+// The reserved rows are read with FOR SHARE, so the check waits for the reservations that are being committed or rolled back.
+func (p *PostgresDBAccess) checkNotReserved(parentCtx context.Context, db dbquerier, ops []state.TransactionalStateOperation) error {
+	if !p.supportsReservations || len(ops) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ops))
+	for i, o := range ops {
+		keys[i] = o.GetKey()
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, p.metadata.QueryTimeout)
+	defer cancel()
+
+	var key string
+	err := db.QueryRow(ctx,
+		`SELECT key FROM `+p.reservationTableName()+`
+		WHERE key = ANY($1) AND status = '`+state.ReservationStatusReserved+`' AND expiredate >= CURRENT_TIMESTAMP
+		LIMIT 1
+		FOR SHARE`,
+		keys,
+	).Scan(&key)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check the reservations of the keys: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+	default:
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("key '%s': %w", key, state.ErrKeyReserved))
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/audit"
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestReserve(t *testing.T) {
+	req := &state.ReserveRequest{
+		ID:  "saga1",
+		TTL: time.Minute,
+		Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "k1", Value: map[string]int{"n": 1}},
+			state.DeleteRequest{Key: "k2"},
+		},
+	}
+
+	t.Run("not supported", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+
+		err := m.pgDba.Reserve(context.Background(), req)
+		require.ErrorIs(t, err, errReservationsUnsupported)
+	})
+
+	t.Run("keys are reserved", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO state_reservations").
+			WithArgs("k1", "saga1", `{"operation":"upsert","key":"k1","value":{"n":1}}`).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO state_reservations").
+			WithArgs("k2", "saga1", `{"operation":"delete","key":"k2"}`).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		require.NoError(t, m.pgDba.Reserve(context.Background(), req))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("key reserved by another reservation", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectExec("INSERT INTO state_reservations").
+			WithArgs("k1", "saga1", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO state_reservations").
+			WithArgs("k2", "saga1", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		m.db.ExpectRollback()
+
+		err := m.pgDba.Reserve(context.Background(), req)
+		require.ErrorIs(t, err, state.ErrKeyReserved)
+		assert.ErrorContains(t, err, "'k2'")
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		err := m.pgDba.Reserve(context.Background(), &state.ReserveRequest{ID: "saga1", TTL: time.Minute})
+		require.Error(t, err)
+	})
+}
+
+func TestCommitReservation(t *testing.T) {
+	t.Run("operations are applied", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT status, operation FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"status", "operation"}).
+				AddRow(state.ReservationStatusReserved, []byte(`{"operation":"upsert","key":"k1","value":{"n":1}}`)).
+				AddRow(state.ReservationStatusReserved, []byte(`{"operation":"delete","key":"k2"}`)))
+		m.db.ExpectExec("INSERT INTO state").
+			WithArgs("k1", `{"n":1}`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs("k2").
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		m.db.ExpectExec("UPDATE state_reservations SET status = 'committed'").
+			WithArgs("saga1").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		require.NoError(t, m.pgDba.CommitReservation(context.Background(), "saga1"))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("operations are recorded in the audit log", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+		m.pgDba.auditLog.Init(audit.Metadata{AuditLogSink: audit.SinkTable, AuditLogTable: "dapr_state_audit", AuditLogActorKey: "actor"}, "postgresql||state")
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT status, operation FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"status", "operation"}).
+				AddRow(state.ReservationStatusReserved, []byte(`{"operation":"upsert","key":"k1","value":{"n":1},"metadata":{"actor":"alice"}}`)).
+				AddRow(state.ReservationStatusReserved, []byte(`{"operation":"delete","key":"k2"}`)))
+		m.db.ExpectExec("INSERT INTO dapr_state_audit").
+			WithArgs("k1", "upsert", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO dapr_state_audit").
+			WithArgs("k2", "delete", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("INSERT INTO state").
+			WithArgs("k1", `{"n":1}`, false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs("k2").
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		m.db.ExpectExec("UPDATE state_reservations SET status = 'committed'").
+			WithArgs("saga1").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		require.NoError(t, m.pgDba.CommitReservation(context.Background(), "saga1"))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("already committed", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT status, operation FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"status", "operation"}).
+				AddRow(state.ReservationStatusCommitted, []byte(`{"operation":"delete","key":"k2"}`)))
+		m.db.ExpectRollback()
+
+		require.NoError(t, m.pgDba.CommitReservation(context.Background(), "saga1"))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("not found or expired", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT status, operation FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"status", "operation"}))
+		m.db.ExpectRollback()
+
+		err := m.pgDba.CommitReservation(context.Background(), "saga1")
+		require.ErrorIs(t, err, state.ErrReservationNotFound)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("ETag mismatch", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT status, operation FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"status", "operation"}).
+				AddRow(state.ReservationStatusReserved, []byte(`{"operation":"delete","key":"k2","etag":"5"}`)))
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs("k2", uint32(5)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		m.db.ExpectRollback()
+
+		err := m.pgDba.CommitReservation(context.Background(), "saga1")
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+}
+
+func TestRollbackReservation(t *testing.T) {
+	t.Run("reservation is deleted", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectQuery("DELETE FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+
+		require.NoError(t, m.pgDba.RollbackReservation(context.Background(), "saga1"))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("already committed", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectQuery("DELETE FROM state_reservations").
+			WithArgs("saga1").
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := m.pgDba.RollbackReservation(context.Background(), "saga1")
+		require.ErrorIs(t, err, state.ErrReservationCommitted)
+	})
+}
+
+func TestWritesOnReservedKeys(t *testing.T) {
+	requireReserved := func(t *testing.T, err error) {
+		t.Helper()
+		require.ErrorIs(t, err, state.ErrKeyReserved)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	}
+
+	t.Run("set", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT key FROM state_reservations").
+			WithArgs([]string{"k1"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key"}).AddRow("k1"))
+		m.db.ExpectRollback()
+
+		err := m.pgDba.Set(context.Background(), &state.SetRequest{Key: "k1", Value: "v"})
+		requireReserved(t, err)
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT key FROM state_reservations").
+			WithArgs([]string{"k0", "k1"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key"}).AddRow("k1"))
+		m.db.ExpectRollback()
+
+		err := m.pgDba.ExecuteMulti(context.Background(), &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "k0", Value: "v"},
+				state.DeleteRequest{Key: "k1"},
+			},
+		})
+		requireReserved(t, err)
+		assert.ErrorContains(t, err, "'k1'")
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("keys that aren't reserved", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+		m.pgDba.supportsReservations = true
+
+		m.db.ExpectBegin()
+		m.db.ExpectQuery("SELECT key FROM state_reservations").
+			WithArgs([]string{"k2"}).
+			WillReturnRows(pgxmock.NewRows([]string{"key"}))
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs("k2").
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		m.db.ExpectCommit()
+		m.db.ExpectRollback()
+
+		require.NoError(t, m.pgDba.Delete(context.Background(), &state.DeleteRequest{Key: "k2"}))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+
+	t.Run("stores without reservations", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+
+		m.db.ExpectExec("DELETE FROM state").
+			WithArgs("k1").
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		require.NoError(t, m.pgDba.Delete(context.Background(), &state.DeleteRequest{Key: "k1"}))
+		require.NoError(t, m.db.ExpectationsWereMet())
+	})
+}
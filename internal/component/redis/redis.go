@@ -15,6 +15,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -49,6 +50,13 @@ type RedisPipeliner interface {
 	Do(ctx context.Context, args ...interface{})
 }
 
+// RedisTx is a dedicated connection, on which keys can be watched so a transaction started with MULTI is aborted by EXEC if they're modified.
+type RedisTx interface {
+	// Pipeline sends the commands in a single round trip, and returns the reply of each command.
+	// Replies that are errors returned by Redis are returned as values of type error, and nil replies as nil; other errors, such as network errors, are returned as the error.
+	Pipeline(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error)
+}
+
 //nolint:interfacebloat
 type RedisClient interface {
 	GetNilValueError() RedisError
@@ -69,6 +77,8 @@ type RedisClient interface {
 	XPendingExtResult(ctx context.Context, stream string, group string, start string, end string, count int64) ([]RedisXPendingExt, error)
 	XClaimResult(ctx context.Context, stream string, group string, consumer string, minIdleTime time.Duration, messageIDs []string) ([]RedisXMessage, error)
 	TxPipeline() RedisPipeliner
+	// Watch invokes fn with a dedicated connection after watching the keys, which are unwatched when fn returns.
+	Watch(ctx context.Context, fn func(tx RedisTx) error, keys ...string) error
 	TTLResult(ctx context.Context, key string) (time.Duration, error)
 }
 
@@ -145,6 +155,21 @@ func GetServerVersion(c RedisClient) (string, error) {
 	return "", fmt.Errorf("could not find redis_version in redis info response")
 }
 
+// checkFailoverNodeRole validates the result of the ROLE command on a new connection when using Sentinel.
+// Connections to replicas are rejected so the client resolves the current master again; Sentinel nodes are allowed.
+func checkFailoverNodeRole(role []interface{}, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to verify the role of the Redis node: %w", err)
+	}
+	if len(role) == 0 {
+		return errors.New("failed to verify the role of the Redis node: empty response")
+	}
+	if r, ok := role[0].(string); ok && (r == "slave" || r == "replica") {
+		return errors.New("connected to a Redis replica instead of the master: the master may have been demoted after a failover")
+	}
+	return nil
+}
+
 type RedisError string
 
 func (e RedisError) Error() string { return string(e) }
@@ -14,11 +14,19 @@ limitations under the License.
 package redis
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -40,8 +48,15 @@ const (
 	idleCheckFrequency    = "idleCheckFrequency"
 	maxConnAge            = "maxConnAge"
 	enableTLS             = "enableTLS"
+	insecureSkipVerify    = "insecureSkipVerify"
+	caCert                = "caCert"
+	clientCert            = "clientCert"
+	clientKey             = "clientKey"
 	failover              = "failover"
 	sentinelMasterName    = "sentinelMasterName"
+	sentinelUsername      = "sentinelUsername"
+	sentinelPassword      = "sentinelPassword"
+	sentinelHealthCheck   = "sentinelHealthCheck"
 )
 
 func getFakeProperties() map[string]string {
@@ -136,3 +151,152 @@ func TestParseRedisMetadata(t *testing.T) {
 		assert.True(t, m.RedisMinRetryInterval == -1)
 	})
 }
+
+func TestFailoverSettings(t *testing.T) {
+	t.Run("sentinel credentials", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[sentinelUsername] = "sentinelUser"
+		fakeProperties[sentinelPassword] = "sentinelPass"
+		fakeProperties[sentinelHealthCheck] = "true"
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		require.NoError(t, err)
+		assert.Equal(t, "sentinelUser", m.SentinelUsername)
+		assert.Equal(t, "sentinelPass", m.SentinelPassword)
+		assert.True(t, m.SentinelHealthCheck)
+	})
+
+	t.Run("sentinel hosts are trimmed", func(t *testing.T) {
+		m := &Settings{Host: "a:26379, b:26379 ,, c:26379"}
+		assert.Equal(t, []string{"a:26379", "b:26379", "c:26379"}, m.Hosts())
+	})
+
+	t.Run("sentinelMasterName is required with failover", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[sentinelMasterName] = ""
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		assert.Error(t, err)
+	})
+
+	t.Run("sentinelMasterName is not required without failover", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[failover] = "false"
+		fakeProperties[sentinelMasterName] = ""
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCheckFailoverNodeRole(t *testing.T) {
+	assert.NoError(t, checkFailoverNodeRole([]interface{}{"master", int64(100), []interface{}{}}, nil))
+	assert.NoError(t, checkFailoverNodeRole([]interface{}{"sentinel", []interface{}{"mymaster"}}, nil))
+	assert.Error(t, checkFailoverNodeRole([]interface{}{"slave", "127.0.0.1", int64(6379), "connected", int64(100)}, nil))
+	assert.Error(t, checkFailoverNodeRole(nil, nil))
+	assert.Error(t, checkFailoverNodeRole(nil, errors.New("NOPERM")))
+}
+
+func generateTestCert(t *testing.T) (certPEM string, keyPEM string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	return certPEM, keyPEM
+}
+
+func TestTLSSettings(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+
+	t.Run("TLS disabled", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[enableTLS] = "false"
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		require.NoError(t, err)
+		assert.Nil(t, m.tlsConfig)
+	})
+
+	t.Run("TLS enabled verifies the server certificate by default", func(t *testing.T) {
+		m := &Settings{}
+		err := m.Decode(getFakeProperties())
+		require.NoError(t, err)
+		require.NotNil(t, m.tlsConfig)
+		assert.False(t, m.tlsConfig.InsecureSkipVerify)
+		assert.Nil(t, m.tlsConfig.RootCAs)
+		assert.Empty(t, m.tlsConfig.Certificates)
+	})
+
+	t.Run("insecureSkipVerify", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[insecureSkipVerify] = "true"
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		require.NoError(t, err)
+		require.NotNil(t, m.tlsConfig)
+		assert.True(t, m.tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("custom CA and client certificate", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[caCert] = certPEM
+		fakeProperties[clientCert] = certPEM
+		fakeProperties[clientKey] = keyPEM
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		require.NoError(t, err)
+		require.NotNil(t, m.tlsConfig)
+		assert.NotNil(t, m.tlsConfig.RootCAs)
+		assert.Len(t, m.tlsConfig.Certificates, 1)
+		assert.False(t, m.tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("invalid CA", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[caCert] = "not a cert"
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		assert.Error(t, err)
+	})
+
+	t.Run("client certificate without key", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[clientCert] = certPEM
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		assert.Error(t, err)
+	})
+
+	t.Run("TLS options without enableTLS", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[enableTLS] = "false"
+		fakeProperties[caCert] = certPEM
+
+		m := &Settings{}
+		err := m.Decode(fakeProperties)
+		assert.Error(t, err)
+	})
+}
@@ -14,8 +14,13 @@ limitations under the License.
 package redis
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dapr/kit/config"
@@ -73,10 +78,30 @@ type Settings struct {
 	// The master name
 	SentinelMasterName string `mapstructure:"sentinelMasterName"`
 	// Use Redis Sentinel for automatic failover.
+	// When enabled, the host contains the comma-separated list of Sentinel addresses.
 	Failover bool `mapstructure:"failover"`
+	// The username used to authenticate with the Sentinel nodes, if ACLs are enabled.
+	SentinelUsername string `mapstructure:"sentinelUsername"`
+	// The password used to authenticate with the Sentinel nodes, if different from the Redis password.
+	SentinelPassword string `mapstructure:"sentinelPassword"`
+	// If true, verifies the role of each new connection when using Sentinel, and rejects connections to nodes that have been demoted to replicas.
+	// This allows the client to reconnect to the new master even if the notification from Sentinel was missed.
+	SentinelHealthCheck bool `mapstructure:"sentinelHealthCheck"`
 
-	// A flag to enables TLS by setting InsecureSkipVerify to true
+	// A flag to enable TLS
 	EnableTLS bool `mapstructure:"enableTLS"`
+	// Skips the verification of the server's certificate when TLS is enabled. Not recommended for production.
+	InsecureSkipVerify bool `mapstructure:"insecureSkipVerify"`
+	// PEM-encoded CA certificate used to verify the server's certificate.
+	// If empty, the system's root CAs are used.
+	CACert string `mapstructure:"caCert"`
+	// PEM-encoded client certificate, for mutual TLS.
+	ClientCert string `mapstructure:"clientCert"`
+	// PEM-encoded client private key, for mutual TLS.
+	ClientKey string `mapstructure:"clientKey"`
+
+	// TLS configuration built from the settings above; nil if TLS is disabled.
+	tlsConfig *tls.Config
 }
 
 func (s *Settings) Decode(in interface{}) error {
@@ -84,9 +109,84 @@ func (s *Settings) Decode(in interface{}) error {
 		return fmt.Errorf("decode failed. %w", err)
 	}
 
+	if s.Failover && s.SentinelMasterName == "" {
+		return errors.New("'sentinelMasterName' is required when 'failover' is enabled")
+	}
+
+	tlsConfig, err := s.buildTLSConfig()
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	s.tlsConfig = tlsConfig
+
 	return nil
 }
 
+// Hosts returns the addresses in the comma-separated list of the host, such as the Sentinel nodes or the nodes of a cluster.
+// Whitespace around the addresses and empty entries are ignored.
+func (s *Settings) Hosts() []string {
+	hosts := make([]string, 0, strings.Count(s.Host, ",")+1)
+	for _, host := range strings.Split(s.Host, ",") {
+		host = strings.TrimSpace(host)
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// buildTLSConfig returns the TLS configuration for connecting to Redis, or nil if TLS is not enabled.
+func (s *Settings) buildTLSConfig() (*tls.Config, error) {
+	if !s.EnableTLS {
+		if s.CACert != "" || s.ClientCert != "" || s.ClientKey != "" || s.InsecureSkipVerify {
+			return nil, errors.New("TLS options are set but 'enableTLS' is false")
+		}
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//nolint:gosec
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+
+	if s.CACert != "" {
+		if !isValidPEM(s.CACert) {
+			return nil, errors.New("invalid caCert")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(s.CACert)) {
+			return nil, errors.New("unable to load CA certificate")
+		}
+	}
+
+	if (s.ClientCert == "") != (s.ClientKey == "") {
+		return nil, errors.New("'clientCert' and 'clientKey' must be set together")
+	}
+	if s.ClientCert != "" {
+		if !isValidPEM(s.ClientCert) {
+			return nil, errors.New("invalid clientCert")
+		}
+		if !isValidPEM(s.ClientKey) {
+			return nil, errors.New("invalid clientKey")
+		}
+		cert, err := tls.X509KeyPair([]byte(s.ClientCert), []byte(s.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate and key pair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// isValidPEM validates the provided input has PEM formatted block.
+func isValidPEM(val string) bool {
+	block, _ := pem.Decode([]byte(val))
+
+	return block != nil
+}
+
 type Duration time.Duration
 
 func (r *Duration) DecodeString(value string) error {
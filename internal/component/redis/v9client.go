@@ -15,8 +15,7 @@ package redis
 
 import (
 	"context"
-	"crypto/tls"
-	"strings"
+	"errors"
 	"time"
 
 	v9 "github.com/redis/go-redis/v9"
@@ -280,6 +279,53 @@ func (c v9Client) TxPipeline() RedisPipeliner {
 	}
 }
 
+// v9Tx is a RedisTx on the connection of a v9.Tx.
+type v9Tx struct {
+	tx           *v9.Tx
+	writeTimeout Duration
+}
+
+func (t v9Tx) Pipeline(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error) {
+	if t.writeTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(t.writeTimeout))
+		defer cancel()
+		ctx = timeoutCtx
+	}
+
+	results := make([]*v9.Cmd, len(cmds))
+	_, err := t.tx.Pipelined(ctx, func(pipe v9.Pipeliner) error {
+		for i, cmd := range cmds {
+			results[i] = pipe.Do(ctx, cmd...)
+		}
+		return nil
+	})
+	// Errors returned by Redis are returned as replies
+	var redisErr v9.Error
+	if err != nil && !errors.Is(err, v9.Nil) && !errors.As(err, &redisErr) {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(results))
+	for i, res := range results {
+		val, err := res.Result()
+		switch {
+		case errors.Is(err, v9.Nil):
+			replies[i] = nil
+		case err != nil:
+			replies[i] = err
+		default:
+			replies[i] = val
+		}
+	}
+	return replies, nil
+}
+
+func (c v9Client) Watch(ctx context.Context, fn func(tx RedisTx) error, keys ...string) error {
+	return c.client.Watch(ctx, func(tx *v9.Tx) error {
+		return fn(v9Tx{tx: tx, writeTimeout: c.writeTimeout})
+	}, keys...)
+}
+
 func (c v9Client) TTLResult(ctx context.Context, key string) (time.Duration, error) {
 	var writeCtx context.Context
 	if c.writeTimeout > 0 {
@@ -299,7 +345,9 @@ func newV9FailoverClient(s *Settings) RedisClient {
 	opts := &v9.FailoverOptions{
 		DB:                    s.DB,
 		MasterName:            s.SentinelMasterName,
-		SentinelAddrs:         []string{s.Host},
+		SentinelAddrs:         s.Hosts(),
+		SentinelUsername:      s.SentinelUsername,
+		SentinelPassword:      s.SentinelPassword,
 		Password:              s.Password,
 		Username:              s.Username,
 		MaxRetries:            s.RedisMaxRetries,
@@ -316,16 +364,17 @@ func newV9FailoverClient(s *Settings) RedisClient {
 		ContextTimeoutEnabled: true,
 	}
 
-	/* #nosec */
-	if s.EnableTLS {
-		opts.TLSConfig = &tls.Config{
-			InsecureSkipVerify: s.EnableTLS,
+	opts.TLSConfig = s.tlsConfig
+
+	if s.SentinelHealthCheck {
+		opts.OnConnect = func(ctx context.Context, cn *v9.Conn) error {
+			cmd := v9.NewSliceCmd(ctx, "ROLE")
+			_ = cn.Process(ctx, cmd)
+			return checkFailoverNodeRole(cmd.Result())
 		}
 	}
 
 	if s.RedisType == ClusterType {
-		opts.SentinelAddrs = strings.Split(s.Host, ",")
-
 		return v9Client{
 			client:       v9.NewFailoverClusterClient(opts),
 			readTimeout:  s.ReadTimeout,
@@ -348,7 +397,7 @@ func newV9Client(s *Settings) RedisClient {
 	}
 	if s.RedisType == ClusterType {
 		options := &v9.ClusterOptions{
-			Addrs:                 strings.Split(s.Host, ","),
+			Addrs:                 s.Hosts(),
 			Password:              s.Password,
 			Username:              s.Username,
 			MaxRetries:            s.RedisMaxRetries,
@@ -364,12 +413,7 @@ func newV9Client(s *Settings) RedisClient {
 			ConnMaxIdleTime:       time.Duration(s.IdleTimeout),
 			ContextTimeoutEnabled: true,
 		}
-		/* #nosec */
-		if s.EnableTLS {
-			options.TLSConfig = &tls.Config{
-				InsecureSkipVerify: s.EnableTLS,
-			}
-		}
+		options.TLSConfig = s.tlsConfig
 
 		return v9Client{
 			client:       v9.NewClusterClient(options),
@@ -398,12 +442,7 @@ func newV9Client(s *Settings) RedisClient {
 		ContextTimeoutEnabled: true,
 	}
 
-	/* #nosec */
-	if s.EnableTLS {
-		options.TLSConfig = &tls.Config{
-			InsecureSkipVerify: s.EnableTLS,
-		}
-	}
+	options.TLSConfig = s.tlsConfig
 
 	return v9Client{
 		client:       v9.NewClient(options),
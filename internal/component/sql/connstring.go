@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"errors"
+)
+
+// ConnectionMetadata contains the discrete properties used to build the connection string when the "connectionString" metadata property is not set.
+// This way, the password can be set with a reference to a secret instead of being embedded in the connection string.
+// It's meant to be embedded (with "squash") in the metadata struct of the component.
+type ConnectionMetadata struct {
+	// Host name or IP of the database server
+	Host string `mapstructure:"host"`
+	// Port of the database server; if 0, the default port of the database is used
+	Port int `mapstructure:"port"`
+	// User to authenticate as
+	User string `mapstructure:"user"`
+	// Password of the user, usually set with a reference to a secret
+	Password string `mapstructure:"password"`
+	// Database to connect to
+	Database string `mapstructure:"database"`
+}
+
+// ConnectionStringBuilder builds a connection string in the format of a database driver from the discrete connection properties.
+// Builders must escape the values, which can contain any character.
+type ConnectionStringBuilder func(m ConnectionMetadata) string
+
+// ConnectionString returns the connection string to use: connectionString if it's not empty, as it takes precedence, or otherwise the one built from the discrete properties with build.
+// It returns an empty string if neither the connection string nor the host is set, so the component can report that the connection string is missing.
+func (m ConnectionMetadata) ConnectionString(connectionString string, build ConnectionStringBuilder) (string, error) {
+	if connectionString != "" {
+		return connectionString, nil
+	}
+
+	if m.Host == "" {
+		if m.Port != 0 || m.User != "" || m.Password != "" || m.Database != "" {
+			return "", errors.New("metadata property 'host' is required when the connection properties are used instead of 'connectionString'")
+		}
+		return "", nil
+	}
+	if m.Port < 0 || m.Port > 65535 {
+		return "", errors.New("invalid value for 'port': must be between 1 and 65535")
+	}
+
+	return build(m), nil
+}
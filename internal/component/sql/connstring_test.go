@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionString(t *testing.T) {
+	build := func(m ConnectionMetadata) string {
+		return fmt.Sprintf("%s:%s@%s:%d/%s", m.User, m.Password, m.Host, m.Port, m.Database)
+	}
+
+	t.Run("connection string takes precedence", func(t *testing.T) {
+		m := ConnectionMetadata{Host: "localhost", User: "user"}
+		cs, err := m.ConnectionString("host=db", build)
+		require.NoError(t, err)
+		assert.Equal(t, "host=db", cs)
+	})
+
+	t.Run("built from the properties", func(t *testing.T) {
+		m := ConnectionMetadata{Host: "localhost", Port: 5432, User: "user", Password: "secret", Database: "dapr"}
+		cs, err := m.ConnectionString("", build)
+		require.NoError(t, err)
+		assert.Equal(t, "user:secret@localhost:5432/dapr", cs)
+	})
+
+	t.Run("nothing set", func(t *testing.T) {
+		cs, err := ConnectionMetadata{}.ConnectionString("", build)
+		require.NoError(t, err)
+		assert.Empty(t, cs)
+	})
+
+	t.Run("missing host", func(t *testing.T) {
+		m := ConnectionMetadata{User: "user", Password: "secret"}
+		_, err := m.ConnectionString("", build)
+		require.ErrorContains(t, err, "'host' is required")
+	})
+
+	t.Run("invalid port", func(t *testing.T) {
+		m := ConnectionMetadata{Host: "localhost", Port: 70000}
+		_, err := m.ConnectionString("", build)
+		require.ErrorContains(t, err, "invalid value for 'port'")
+	})
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/dapr/components-contrib/internal/utils"
+)
+
+// HealthMetadata contains the properties used to validate pooled connections before they're used, so connections broken by a failover are evicted instead of failing the next operation.
+// It's meant to be embedded (with "squash") in the metadata struct of the component.
+type HealthMetadata struct {
+	// If true, pooled connections are pinged before they're used
+	PingBeforeUse bool `mapstructure:"pingBeforeUse"`
+	// Statement executed on pooled connections before they're used, such as "SELECT 1"; it takes precedence over PingBeforeUse
+	ValidationQuery string `mapstructure:"validationQuery"`
+}
+
+// Enabled returns true if pooled connections are validated before they're used.
+func (m HealthMetadata) Enabled() bool {
+	return m.PingBeforeUse || m.ValidationQuery != ""
+}
+
+// OpenDB opens a database like sql.Open, validating pooled connections before they're used if health checks are enabled in m.
+// Connections that fail the validation are closed, and the operation is transparently performed on another connection, which is a fresh one if no other pooled connection is valid.
+func OpenDB(driverName string, dataSourceName string, m HealthMetadata) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil || !m.Enabled() {
+		// Errors of the driver may contain the connection string
+		return db, utils.RedactError(err, dataSourceName)
+	}
+
+	// sql.Open doesn't connect, so this only retrieves the driver
+	drv := db.Driver()
+	_ = db.Close()
+
+	var connector driver.Connector
+	if dc, ok := drv.(driver.DriverContext); ok {
+		connector, err = dc.OpenConnector(dataSourceName)
+		if err != nil {
+			return nil, utils.RedactError(err, dataSourceName)
+		}
+	} else {
+		connector = &dsnConnector{dsn: dataSourceName, driver: drv}
+	}
+
+	return sql.OpenDB(&validatingConnector{
+		Connector: connector,
+		metadata:  m,
+	}), nil
+}
+
+// dsnConnector is the connector for drivers that don't implement driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// validatingConnector returns connections that are validated when they're taken from the pool.
+type validatingConnector struct {
+	driver.Connector
+	metadata HealthMetadata
+}
+
+func (c *validatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &validatingConn{Conn: conn, metadata: c.metadata}, nil
+}
+
+// validatingConn wraps a connection of the driver to validate it in ResetSession, which database/sql invokes before a pooled connection is used again.
+// It forwards the optional interfaces of the driver's connection, returning driver.ErrSkip if they're not implemented, so database/sql uses its fallbacks.
+type validatingConn struct {
+	driver.Conn
+	metadata HealthMetadata
+}
+
+func (c *validatingConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		err := r.ResetSession(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch {
+	case c.metadata.ValidationQuery != "":
+		_, err = c.ExecContext(ctx, c.metadata.ValidationQuery, nil)
+		if errors.Is(err, driver.ErrSkip) {
+			err = c.execPrepared(ctx, c.metadata.ValidationQuery)
+		}
+	case c.metadata.PingBeforeUse:
+		err = c.Ping(ctx)
+	}
+	if err != nil {
+		// database/sql closes the connection and retries the operation on another one
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *validatingConn) execPrepared(ctx context.Context, query string) error {
+	stmt, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	//nolint:staticcheck
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+func (c *validatingConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *validatingConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *validatingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *validatingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		return nil, errors.New("the driver doesn't support transaction options")
+	}
+	//nolint:staticcheck
+	return c.Conn.Begin()
+}
+
+func (c *validatingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *validatingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *validatingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver is a driver whose connections can be broken, as after a failover.
+type fakeDriver struct {
+	lock  sync.Mutex
+	conns []*fakeConn
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	c := &fakeConn{}
+	d.conns = append(d.conns, c)
+	return c, nil
+}
+
+func (d *fakeDriver) breakAll() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, c := range d.conns {
+		c.broken = true
+	}
+}
+
+type fakeConn struct {
+	broken  bool
+	closed  bool
+	queries []string
+	pings   int
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.queries = append(c.queries, query)
+	if c.broken {
+		return nil, errors.New("connection reset by peer")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	c.pings++
+	if c.broken {
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func openFakeDB(t *testing.T, m HealthMetadata) (*sql.DB, *fakeDriver) {
+	t.Helper()
+
+	drv := &fakeDriver{}
+	name := "fake-" + t.Name()
+	sql.Register(name, drv)
+
+	db, err := OpenDB(name, "dsn", m)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	return db, drv
+}
+
+func TestOpenDB(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		db, drv := openFakeDB(t, HealthMetadata{})
+
+		_, err := db.ExecContext(context.Background(), "op")
+		require.NoError(t, err)
+
+		// Without validation, the broken connection fails the operation
+		drv.breakAll()
+		_, err = db.ExecContext(context.Background(), "op")
+		require.Error(t, err)
+		assert.Len(t, drv.conns, 1)
+	})
+
+	t.Run("validation query", func(t *testing.T) {
+		db, drv := openFakeDB(t, HealthMetadata{ValidationQuery: "SELECT 1"})
+
+		_, err := db.ExecContext(context.Background(), "op")
+		require.NoError(t, err)
+
+		drv.breakAll()
+		_, err = db.ExecContext(context.Background(), "op")
+		require.NoError(t, err)
+
+		// The broken connection is evicted and the operation is performed on a new one
+		require.Len(t, drv.conns, 2)
+		assert.True(t, drv.conns[0].closed)
+		assert.Equal(t, []string{"op", "SELECT 1"}, drv.conns[0].queries)
+		assert.Equal(t, []string{"op"}, drv.conns[1].queries)
+	})
+
+	t.Run("ping before use", func(t *testing.T) {
+		db, drv := openFakeDB(t, HealthMetadata{PingBeforeUse: true})
+
+		_, err := db.ExecContext(context.Background(), "op")
+		require.NoError(t, err)
+		_, err = db.ExecContext(context.Background(), "op")
+		require.NoError(t, err)
+		require.Len(t, drv.conns, 1)
+		assert.Equal(t, 1, drv.conns[0].pings)
+
+		drv.breakAll()
+		_, err = db.ExecContext(context.Background(), "op")
+		require.NoError(t, err)
+
+		require.Len(t, drv.conns, 2)
+		assert.True(t, drv.conns[0].closed)
+		assert.Equal(t, 2, drv.conns[0].pings)
+		assert.Equal(t, []string{"op"}, drv.conns[1].queries)
+	})
+}
+
+// failingDriver is a driver that fails to parse the connection string, echoing it in the error as some drivers do.
+type failingDriver struct {
+	fakeDriver
+}
+
+func (d *failingDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	return nil, errors.New("invalid connection string: " + dsn)
+}
+
+func TestOpenDBRedactsErrors(t *testing.T) {
+	name := "failing-" + t.Name()
+	sql.Register(name, &failingDriver{})
+
+	_, err := OpenDB(name, "server=localhost;password=s3cr3t;database=dapr", HealthMetadata{PingBeforeUse: true})
+	require.Error(t, err)
+	assert.Equal(t, "invalid connection string: server=localhost;password=*****;database=dapr", err.Error())
+}
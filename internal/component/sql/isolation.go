@@ -0,0 +1,46 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TransactionIsolationLevelKey is the name of the metadata property that configures the isolation level of transactions.
+const TransactionIsolationLevelKey = "transactionIsolationLevel"
+
+// ParseIsolationLevel parses the value of the "transactionIsolationLevel" metadata property.
+// Names are case-insensitive, and words can be separated by spaces, underscores, or dashes (e.g. "READ COMMITTED" or "readCommitted").
+// An empty value returns sql.LevelDefault, which uses the default isolation level of the database.
+func ParseIsolationLevel(val string) (sql.IsolationLevel, error) {
+	normalized := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(val))
+	switch normalized {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "readuncommitted":
+		return sql.LevelReadUncommitted, nil
+	case "readcommitted":
+		return sql.LevelReadCommitted, nil
+	case "repeatableread":
+		return sql.LevelRepeatableRead, nil
+	case "snapshot":
+		return sql.LevelSnapshot, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("invalid value for '%s': unsupported isolation level '%s'", TransactionIsolationLevelKey, val)
+	}
+}
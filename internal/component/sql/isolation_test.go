@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIsolationLevel(t *testing.T) {
+	tests := map[string]sql.IsolationLevel{
+		"":                 sql.LevelDefault,
+		"default":          sql.LevelDefault,
+		"READ UNCOMMITTED": sql.LevelReadUncommitted,
+		"readCommitted":    sql.LevelReadCommitted,
+		"repeatable_read":  sql.LevelRepeatableRead,
+		"Snapshot":         sql.LevelSnapshot,
+		"SERIALIZABLE":     sql.LevelSerializable,
+	}
+	for in, expect := range tests {
+		level, err := ParseIsolationLevel(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, expect, level, in)
+	}
+
+	for _, in := range []string{"linearizable", "write committed", "foo"} {
+		_, err := ParseIsolationLevel(in)
+		require.Error(t, err, in)
+	}
+}
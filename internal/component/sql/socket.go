@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ValidateUnixSocket checks that a Unix domain socket exists at the given path.
+func ValidateUnixSocket(path string) error {
+	fi, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("unix socket '%s' does not exist: check that the database server is running and listening on it", path)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("permission denied accessing unix socket '%s': check that the process can access the directory containing it", path)
+	case err != nil:
+		return fmt.Errorf("failed to access unix socket '%s': %w", path, err)
+	}
+
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("'%s' is not a unix socket", path)
+	}
+	return nil
+}
+
+// WrapUnixSocketError returns a more descriptive error if connecting to a Unix domain socket failed because of insufficient permissions.
+// Other errors, or errors when path is empty (not connecting over a socket), are returned as-is.
+func WrapUnixSocketError(path string, err error) error {
+	if path != "" && err != nil && errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("permission denied connecting to unix socket '%s': check that the process has write access to the socket: %w", path, err)
+	}
+	return err
+}
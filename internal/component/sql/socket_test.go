@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not supported on Windows")
+	}
+
+	dir := t.TempDir()
+
+	t.Run("socket exists", func(t *testing.T) {
+		path := filepath.Join(dir, "db.sock")
+		lis, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		defer lis.Close()
+
+		assert.NoError(t, ValidateUnixSocket(path))
+	})
+
+	t.Run("socket does not exist", func(t *testing.T) {
+		err := ValidateUnixSocket(filepath.Join(dir, "missing.sock"))
+		assert.ErrorContains(t, err, "does not exist")
+	})
+
+	t.Run("not a socket", func(t *testing.T) {
+		path := filepath.Join(dir, "file")
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0o600))
+
+		err := ValidateUnixSocket(path)
+		assert.ErrorContains(t, err, "is not a unix socket")
+	})
+}
+
+func TestWrapUnixSocketError(t *testing.T) {
+	permErr := fmt.Errorf("dial: %w", fs.ErrPermission)
+	otherErr := errors.New("other")
+
+	err := WrapUnixSocketError("/tmp/db.sock", permErr)
+	assert.ErrorContains(t, err, "permission denied connecting to unix socket")
+	assert.ErrorIs(t, err, fs.ErrPermission)
+
+	assert.Equal(t, otherErr, WrapUnixSocketError("/tmp/db.sock", otherErr))
+	assert.Equal(t, permErr, WrapUnixSocketError("", permErr))
+	assert.NoError(t, WrapUnixSocketError("/tmp/db.sock", nil))
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQueryTimeout is returned, wrapped, when a statement doesn't complete within the configured query timeout.
+var ErrQueryTimeout = errors.New("query timeout exceeded")
+
+// WithQueryTimeout returns a context to execute a single statement, which expires after the given timeout.
+// If the timeout is not positive, the parent context is returned as-is, so the statement is bound only by the parent context.
+func WithQueryTimeout(parentCtx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return parentCtx, func() {}
+	}
+	return context.WithTimeout(parentCtx, timeout)
+}
+
+// WrapQueryTimeoutError returns err wrapped with ErrQueryTimeout if the statement failed because ctx, created with WithQueryTimeout, expired.
+// Errors caused by the parent context being canceled or expiring are returned as-is.
+func WrapQueryTimeoutError(parentCtx context.Context, ctx context.Context, err error) error {
+	if err == nil || parentCtx.Err() != nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+}
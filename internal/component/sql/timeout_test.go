@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	t.Run("no timeout", func(t *testing.T) {
+		parentCtx := context.Background()
+		ctx, cancel := WithQueryTimeout(parentCtx, 0)
+		defer cancel()
+		assert.Equal(t, parentCtx, ctx)
+
+		err := errors.New("failed")
+		assert.Equal(t, err, WrapQueryTimeoutError(parentCtx, ctx, err))
+	})
+
+	t.Run("statement timed out", func(t *testing.T) {
+		parentCtx := context.Background()
+		ctx, cancel := WithQueryTimeout(parentCtx, time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		err := WrapQueryTimeoutError(parentCtx, ctx, ctx.Err())
+		assert.ErrorIs(t, err, ErrQueryTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("parent canceled", func(t *testing.T) {
+		parentCtx, parentCancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer parentCancel()
+		ctx, cancel := WithQueryTimeout(parentCtx, time.Minute)
+		defer cancel()
+		<-ctx.Done()
+
+		err := WrapQueryTimeoutError(parentCtx, ctx, ctx.Err())
+		assert.NotErrorIs(t, err, ErrQueryTimeout)
+	})
+
+	t.Run("nil error", func(t *testing.T) {
+		ctx, cancel := WithQueryTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		assert.NoError(t, WrapQueryTimeoutError(context.Background(), ctx, nil))
+	})
+}
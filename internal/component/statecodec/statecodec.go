@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package statecodec contains the codecs state stores can use to serialize values.
+package statecodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tinylib/msgp/msgp"
+
+	stateutils "github.com/dapr/components-contrib/state/utils"
+)
+
+const (
+	// SerializerKey is the metadata key for selecting the codec.
+	SerializerKey = "serializer"
+
+	// JSON stores values as JSON. This is the default.
+	JSON = "json"
+	// Msgpack stores values as MessagePack. Values that are JSON documents are returned as JSON.
+	Msgpack = "msgpack"
+	// ProtobufPassthrough stores binary values (such as serialized protobuf messages) as-is, without a JSON round-trip.
+	ProtobufPassthrough = "protobuf-passthrough"
+)
+
+// Metadata contains the metadata property for selecting the codec.
+// Components embed it with `mapstructure:",squash"`.
+type Metadata struct {
+	// Codec used to serialize values: "json" (the default), "msgpack", or "protobuf-passthrough".
+	Serializer string `mapstructure:"serializer"`
+}
+
+// Codec serializes values of state stores.
+// State stores persist the name of the codec alongside each value, so values remain readable after the codec is changed.
+type Codec interface {
+	// Name returns the name of the codec.
+	Name() string
+	// Encode serializes the value of a SetRequest.
+	Encode(value any) ([]byte, error)
+	// Decode returns the data returned to callers of Get from a serialized value.
+	Decode(data []byte) ([]byte, error)
+}
+
+// Parse returns the codec with the given name. An empty name selects the JSON codec.
+func Parse(name string) (Codec, error) {
+	switch strings.ToLower(name) {
+	case "", JSON:
+		return jsonCodec{}, nil
+	case Msgpack:
+		return msgpackCodec{}, nil
+	case ProtobufPassthrough:
+		return passthroughCodec{}, nil
+	default:
+		return nil, fmt.Errorf("invalid value for '%s': unsupported serializer '%s'", SerializerKey, name)
+	}
+}
+
+// Decode decodes a value that was serialized with the codec with the given name.
+func Decode(name string, data []byte) ([]byte, error) {
+	c, err := Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decode(data)
+}
+
+// IsJSON returns true if c is the JSON codec.
+func IsJSON(c Codec) bool {
+	return c == nil || c.Name() == JSON
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return JSON
+}
+
+func (jsonCodec) Encode(value any) ([]byte, error) {
+	return stateutils.Marshal(value, json.Marshal)
+}
+
+func (jsonCodec) Decode(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+type passthroughCodec struct{}
+
+func (passthroughCodec) Name() string {
+	return ProtobufPassthrough
+}
+
+// Encode stores binary values as-is; other values are stored as JSON.
+func (passthroughCodec) Encode(value any) ([]byte, error) {
+	return stateutils.Marshal(value, json.Marshal)
+}
+
+func (passthroughCodec) Decode(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string {
+	return Msgpack
+}
+
+// Encode stores JSON documents as the equivalent MessagePack object, and other binary values as MessagePack binary data.
+func (msgpackCodec) Encode(value any) ([]byte, error) {
+	var doc []byte
+	switch v := value.(type) {
+	case []byte:
+		if !json.Valid(v) {
+			return msgp.AppendBytes(nil, v), nil
+		}
+		doc = v
+	default:
+		var err error
+		doc, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize value: %w", err)
+		}
+	}
+
+	generic, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	res, err := msgp.AppendIntf(nil, generic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize value as msgpack: %w", err)
+	}
+	return res, nil
+}
+
+func (msgpackCodec) Decode(data []byte) ([]byte, error) {
+	v, _, err := msgp.ReadIntfBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize msgpack value: %w", err)
+	}
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	res, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert msgpack value to JSON: %w", err)
+	}
+	return res, nil
+}
+
+// decodeJSON decodes a JSON document, preserving integers that don't fit in a float64.
+func decodeJSON(doc []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON value: %w", err)
+	}
+	return convertNumbers(v), nil
+}
+
+// convertNumbers replaces json.Number values with int64 or float64, which can be serialized as msgpack.
+func convertNumbers(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]any:
+		for k, e := range val {
+			val[k] = convertNumbers(e)
+		}
+		return val
+	case []any:
+		for i, e := range val {
+			val[i] = convertNumbers(e)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statecodec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for name, expect := range map[string]string{
+		"":                     JSON,
+		"json":                 JSON,
+		"MsgPack":              Msgpack,
+		"protobuf-passthrough": ProtobufPassthrough,
+	} {
+		c, err := Parse(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expect, c.Name())
+	}
+
+	_, err := Parse("xml")
+	require.Error(t, err)
+
+	c, _ := Parse("")
+	assert.True(t, IsJSON(c))
+	c, _ = Parse(Msgpack)
+	assert.False(t, IsJSON(c))
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	c := msgpackCodec{}
+
+	t.Run("JSON document", func(t *testing.T) {
+		enc, err := c.Encode([]byte(`{"name":"dapr","count":9007199254740993,"ratio":0.5,"tags":["a",true,null]}`))
+		require.NoError(t, err)
+
+		dec, err := c.Decode(enc)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"dapr","count":9007199254740993,"ratio":0.5,"tags":["a",true,null]}`, string(dec))
+		// Large integers are preserved exactly
+		assert.Contains(t, string(dec), "9007199254740993")
+	})
+
+	t.Run("non-binary value", func(t *testing.T) {
+		enc, err := c.Encode(map[string]any{"a": 1})
+		require.NoError(t, err)
+
+		dec, err := c.Decode(enc)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":1}`, string(dec))
+	})
+
+	t.Run("binary value", func(t *testing.T) {
+		raw := []byte{0x0a, 0x04, 'd', 'a', 'p', 'r'}
+		enc, err := c.Encode(raw)
+		require.NoError(t, err)
+
+		dec, err := c.Decode(enc)
+		require.NoError(t, err)
+		assert.Equal(t, raw, dec)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := c.Decode([]byte{0xc1})
+		require.Error(t, err)
+	})
+}
+
+func TestPassthroughCodec(t *testing.T) {
+	c := passthroughCodec{}
+
+	raw := []byte{0x0a, 0x04, 'd', 'a', 'p', 'r'}
+	enc, err := c.Encode(raw)
+	require.NoError(t, err)
+	assert.Equal(t, raw, enc)
+	dec, err := c.Decode(enc)
+	require.NoError(t, err)
+	assert.Equal(t, raw, dec)
+
+	enc, err = c.Encode(map[string]any{"a": 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1}`, string(enc))
+}
+
+func TestDecode(t *testing.T) {
+	enc, err := msgpackCodec{}.Encode([]byte(`{"a":1}`))
+	require.NoError(t, err)
+
+	dec, err := Decode(Msgpack, enc)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1}`, string(dec))
+
+	// Values stored before the codec was recorded are JSON
+	dec, err = Decode("", []byte(`{"a":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(dec))
+
+	_, err = Decode("xml", enc)
+	require.Error(t, err)
+}
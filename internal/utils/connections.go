@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Metadata keys for the options that control how components connect during Init.
+const (
+	// WarmupConnectionsKey is the number of connections opened and validated during Init, so the first requests don't pay the cost of connecting.
+	WarmupConnectionsKey = "warmupConnections"
+	// LazyInitKey defers connecting until the first operation, so Init returns without waiting for the server.
+	LazyInitKey = "lazyInit"
+)
+
+// LazyInit runs an initialization function the first time Do is invoked.
+// If the function fails, it's invoked again by the next call to Do.
+// A nil *LazyInit does nothing, so components that are initialized eagerly can leave it unset.
+type LazyInit struct {
+	fn   func(ctx context.Context) error
+	lock sync.Mutex
+	done atomic.Bool
+}
+
+// NewLazyInit returns a LazyInit that runs fn.
+func NewLazyInit(fn func(ctx context.Context) error) *LazyInit {
+	return &LazyInit{fn: fn}
+}
+
+// Do runs the initialization function if it hasn't completed successfully yet.
+// Concurrent callers wait for the same invocation.
+func (l *LazyInit) Do(ctx context.Context) error {
+	if l == nil || l.done.Load() {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.done.Load() {
+		return nil
+	}
+
+	err := l.fn(ctx)
+	if err != nil {
+		return err
+	}
+	l.done.Store(true)
+	return nil
+}
+
+// WarmupConnections opens n connections concurrently using connect, which returns a function to release the connection.
+// All connections are held until every one is open, so each call opens a new connection instead of reusing an idle one; they are then released to the pool.
+func WarmupConnections(ctx context.Context, n int, connect func(ctx context.Context) (release func(), err error)) error {
+	if n <= 0 {
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	releases := make([]func(), 0, n)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			release, err := connect(ctx)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			if release != nil {
+				releases = append(releases, release)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, release := range releases {
+		release()
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyInit(t *testing.T) {
+	t.Run("nil does nothing", func(t *testing.T) {
+		var l *LazyInit
+		require.NoError(t, l.Do(context.Background()))
+	})
+
+	t.Run("runs once", func(t *testing.T) {
+		var calls atomic.Int32
+		l := NewLazyInit(func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, l.Do(context.Background()))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("retries after failure", func(t *testing.T) {
+		var calls int
+		l := NewLazyInit(func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				return errors.New("simulated")
+			}
+			return nil
+		})
+
+		require.Error(t, l.Do(context.Background()))
+		require.NoError(t, l.Do(context.Background()))
+		require.NoError(t, l.Do(context.Background()))
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestWarmupConnections(t *testing.T) {
+	t.Run("holds all connections before releasing", func(t *testing.T) {
+		var (
+			open     atomic.Int32
+			maxOpen  atomic.Int32
+			released atomic.Int32
+		)
+		err := WarmupConnections(context.Background(), 5, func(ctx context.Context) (func(), error) {
+			n := open.Add(1)
+			for {
+				m := maxOpen.Load()
+				if n <= m || maxOpen.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			return func() {
+				// All connections must be open when the first one is released
+				assert.Equal(t, int32(5), open.Load())
+				released.Add(1)
+			}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(5), maxOpen.Load())
+		assert.Equal(t, int32(5), released.Load())
+	})
+
+	t.Run("zero does nothing", func(t *testing.T) {
+		err := WarmupConnections(context.Background(), 0, func(ctx context.Context) (func(), error) {
+			t.Fatal("connect must not be invoked")
+			return nil, nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("errors are returned and other connections released", func(t *testing.T) {
+		var (
+			calls    atomic.Int32
+			released atomic.Int32
+		)
+		err := WarmupConnections(context.Background(), 3, func(ctx context.Context) (func(), error) {
+			if calls.Add(1) == 2 {
+				return nil, errors.New("simulated")
+			}
+			return func() { released.Add(1) }, nil
+		})
+		require.ErrorContains(t, err, "simulated")
+		assert.Equal(t, int32(2), released.Load())
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/lock"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
@@ -76,7 +77,7 @@ func (r *StandaloneRedisLock) InitLockStore(ctx context.Context, metadata lock.M
 	}
 	// 3. connect to redis
 	if _, err = r.client.PingResult(ctx); err != nil {
-		return fmt.Errorf("[standaloneRedisLock]: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+		return fmt.Errorf("[standaloneRedisLock]: error connecting to redis at %s: %s", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
 	}
 	// no replica
 	replicas, err := r.getConnectedSlaves(ctx)
@@ -13,6 +13,8 @@ limitations under the License.
 
 package metadata
 
+import "context"
+
 // Base is the common metadata across components.
 // All components-specific metadata should embed this.
 type Base struct {
@@ -20,4 +22,12 @@ type Base struct {
 	Name string
 	// Properties is the metadata properties.
 	Properties map[string]string `json:"properties,omitempty"`
+	// SecretResolver resolves the values of properties that reference a secret, as in "secretref://<store>/<name>#<key>".
+	// It's set by the runtime, and can be nil if no secret store is available.
+	SecretResolver SecretResolver `json:"-"`
+}
+
+// ResolvedProperties returns the properties with the values that reference a secret replaced with the value of the secret.
+func (b Base) ResolvedProperties(ctx context.Context) (map[string]string, error) {
+	return ResolveSecretReferences(ctx, b.Properties, b.SecretResolver)
 }
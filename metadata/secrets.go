@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SecretReferencePrefix is the prefix of metadata values that reference a secret, in the format "secretref://<store>/<name>#<key>".
+// The key is optional, and defaults to the name of the secret.
+const SecretReferencePrefix = "secretref://"
+
+// ErrSecretNotFound is returned by a SecretResolver when the referenced secret, or the key in it, doesn't exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretResolver retrieves the values of secrets referenced in metadata properties.
+type SecretResolver interface {
+	// ResolveSecret returns the value of key in the secret with the given name, from the secret store.
+	ResolveSecret(ctx context.Context, store string, name string, key string) (string, error)
+}
+
+// SecretReference is a reference to a value in a secret store.
+type SecretReference struct {
+	Store string
+	Name  string
+	Key   string
+}
+
+func (r SecretReference) String() string {
+	return SecretReferencePrefix + r.Store + "/" + r.Name + "#" + r.Key
+}
+
+// ParseSecretReference parses a metadata value that references a secret.
+// It returns false if the value isn't a secret reference.
+func ParseSecretReference(val string) (SecretReference, bool, error) {
+	if !strings.HasPrefix(val, SecretReferencePrefix) {
+		return SecretReference{}, false, nil
+	}
+
+	var ref SecretReference
+	ref.Store, ref.Name, _ = strings.Cut(strings.TrimPrefix(val, SecretReferencePrefix), "/")
+	ref.Name, ref.Key, _ = strings.Cut(ref.Name, "#")
+	if ref.Store == "" || ref.Name == "" {
+		return ref, true, fmt.Errorf("invalid secret reference '%s': must be in the format '%s<store>/<name>#<key>'", val, SecretReferencePrefix)
+	}
+	if ref.Key == "" {
+		ref.Key = ref.Name
+	}
+	return ref, true, nil
+}
+
+// ResolveSecretReferences returns a copy of the properties in which values that reference a secret are replaced with the value of the secret.
+// It returns an error if a referenced secret can't be retrieved, or if properties reference secrets and resolver is nil.
+func ResolveSecretReferences(ctx context.Context, props map[string]string, resolver SecretResolver) (map[string]string, error) {
+	res := make(map[string]string, len(props))
+	for k, v := range props {
+		ref, ok, err := ParseSecretReference(v)
+		if err != nil {
+			return nil, fmt.Errorf("metadata property '%s': %w", k, err)
+		}
+		if !ok {
+			res[k] = v
+			continue
+		}
+
+		if resolver == nil {
+			return nil, fmt.Errorf("metadata property '%s' references a secret, but no secret resolver is configured", k)
+		}
+		res[k], err = resolver.ResolveSecret(ctx, ref.Store, ref.Name, ref.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve secret '%s' in store '%s' for metadata property '%s': %w", ref.Name, ref.Store, k, err)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretResolver map[string]string
+
+func (f fakeSecretResolver) ResolveSecret(_ context.Context, store string, name string, key string) (string, error) {
+	val, ok := f[store+"/"+name+"#"+key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return val, nil
+}
+
+func TestParseSecretReference(t *testing.T) {
+	tests := map[string]struct {
+		val     string
+		ref     SecretReference
+		isRef   bool
+		wantErr bool
+	}{
+		"plain value":     {val: "hunter2"},
+		"empty":           {val: ""},
+		"with key":        {val: "secretref://vault/db#password", ref: SecretReference{Store: "vault", Name: "db", Key: "password"}, isRef: true},
+		"without key":     {val: "secretref://env/DB_PASSWORD", ref: SecretReference{Store: "env", Name: "DB_PASSWORD", Key: "DB_PASSWORD"}, isRef: true},
+		"name with slash": {val: "secretref://vault/apps/db#password", ref: SecretReference{Store: "vault", Name: "apps/db", Key: "password"}, isRef: true},
+		"missing name":    {val: "secretref://vault", isRef: true, wantErr: true},
+		"missing store":   {val: "secretref:///db", isRef: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ref, isRef, err := ParseSecretReference(tc.val)
+			assert.Equal(t, tc.isRef, isRef)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ref, ref)
+		})
+	}
+}
+
+func TestResolvedProperties(t *testing.T) {
+	resolver := fakeSecretResolver{"vault/db#password": "hunter2"}
+
+	t.Run("references are resolved", func(t *testing.T) {
+		b := Base{
+			Properties: map[string]string{
+				"host":     "localhost",
+				"password": "secretref://vault/db#password",
+			},
+			SecretResolver: resolver,
+		}
+		props, err := b.ResolvedProperties(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"host": "localhost", "password": "hunter2"}, props)
+
+		// The original properties are not modified
+		assert.Equal(t, "secretref://vault/db#password", b.Properties["password"])
+	})
+
+	t.Run("no references without a resolver", func(t *testing.T) {
+		b := Base{Properties: map[string]string{"password": "hunter2"}}
+		props, err := b.ResolvedProperties(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", props["password"])
+	})
+
+	t.Run("reference without a resolver", func(t *testing.T) {
+		b := Base{Properties: map[string]string{"password": "secretref://vault/db#password"}}
+		_, err := b.ResolvedProperties(context.Background())
+		require.ErrorContains(t, err, "'password'")
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		b := Base{
+			Properties:     map[string]string{"password": "secretref://vault/other#password"},
+			SecretResolver: resolver,
+		}
+		_, err := b.ResolvedProperties(context.Background())
+		require.ErrorIs(t, err, ErrSecretNotFound)
+		assert.ErrorContains(t, err, "secret 'other' in store 'vault' for metadata property 'password'")
+	})
+}
@@ -334,8 +334,8 @@ func GetMetadataInfoFromStructType(t reflect.Type, metadataMap *map[string]strin
 		}
 		mapStructureTags := strings.Split(mapStructureTag, ",")
 		numTags := len(mapStructureTags)
-		if numTags > 1 && mapStructureTags[numTags-1] == "squash" && currentField.Anonymous {
-			// traverse embedded struct
+		if numTags > 1 && mapStructureTags[numTags-1] == "squash" && currentField.Type.Kind() == reflect.Struct {
+			// traverse embedded struct, or named struct field that mapstructure decodes as if it were embedded
 			GetMetadataInfoFromStructType(currentField.Type, metadataMap, componentType)
 			continue
 		}
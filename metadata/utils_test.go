@@ -236,8 +236,13 @@ func TestMetadataStructToStringMap(t *testing.T) {
 			NestedString       string
 		}
 
+		type NamedNestedStruct struct {
+			NamedNestedString string `mapstructure:"named_nested_string"`
+		}
+
 		type testMetadata struct {
 			NestedStruct              `mapstructure:",squash"`
+			Named                     NamedNestedStruct `mapstructure:",squash"`
 			Mystring                  string
 			Myduration                Duration
 			Myinteger                 int
@@ -267,6 +272,8 @@ func TestMetadataStructToStringMap(t *testing.T) {
 		assert.NotContains(t, metadatainfo, "SomethingWithCustomName")
 		assert.Equal(t, "string", metadatainfo["nested_string_custom"])
 		assert.Equal(t, "string", metadatainfo["NestedString"])
+		assert.NotContains(t, metadatainfo, "Named")
+		assert.Equal(t, "string", metadatainfo["named_nested_string"])
 		assert.NotContains(t, metadatainfo, "pubsub_only_property")
 		assert.Equal(t, "string", metadatainfo["binding_only_property"])
 		assert.Equal(t, "string", metadatainfo["pubsub_and_binding_property"])
@@ -0,0 +1,459 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/Azure/go-amqp"
+	"github.com/cenkalti/backoff/v4"
+
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+// Timeout for settling messages and closing links.
+const operationTimeout = 10 * time.Second
+
+// amqpPubSub is a pub/sub component for brokers that implement AMQP 1.0.
+type amqpPubSub struct {
+	metadata *metadata
+	logger   logger.Logger
+	dial     func(addr string, opts *amqp.ConnOptions) (*amqp.Conn, error)
+
+	// The connection and session are re-created if the connection is lost.
+	// After a failed attempt, connecting again is delayed by dialBackOff.
+	connLock    sync.Mutex
+	conn        *amqp.Conn
+	session     *amqp.Session
+	dialBackOff backoff.BackOff
+	dialErr     error
+	nextDial    time.Time
+
+	sendersLock sync.Mutex
+	senders     map[string]*amqp.Sender
+
+	wg      sync.WaitGroup
+	closed  atomic.Bool
+	closeCh chan struct{}
+}
+
+// NewAMQPPubSub returns a new AMQP 1.0 pub/sub component.
+func NewAMQPPubSub(logger logger.Logger) pubsub.PubSub {
+	return &amqpPubSub{
+		logger:  logger,
+		dial:    amqp.Dial,
+		senders: map[string]*amqp.Sender{},
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Init parses the metadata and connects to the broker.
+func (a *amqpPubSub) Init(ctx context.Context, md pubsub.Metadata) error {
+	m, err := parseMetadata(md)
+	if err != nil {
+		return err
+	}
+	a.metadata = m
+
+	bo := backoff.NewExponentialBackOff()
+	bo.MaxInterval = m.ReconnectWait
+	bo.MaxElapsedTime = 0
+	a.dialBackOff = bo
+
+	a.connLock.Lock()
+	defer a.connLock.Unlock()
+	return a.connect(ctx)
+}
+
+// connect dials the broker and creates the session.
+// It must be called while holding connLock.
+func (a *amqpPubSub) connect(ctx context.Context) error {
+	opts, err := a.connOptions()
+	if err != nil {
+		return err
+	}
+
+	a.logger.Debugf("Connecting to AMQP broker at %s", a.metadata.URL)
+	conn, err := a.dial(a.metadata.URL, opts)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the AMQP broker: %w", err)
+	}
+
+	session, err := conn.NewSession(ctx, nil)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create AMQP session: %w", err)
+	}
+
+	a.conn = conn
+	a.session = session
+	return nil
+}
+
+// getSession returns the session, re-connecting to the broker if the connection was lost.
+func (a *amqpPubSub) getSession(ctx context.Context) (*amqp.Session, error) {
+	a.connLock.Lock()
+	defer a.connLock.Unlock()
+
+	if a.session != nil {
+		return a.session, nil
+	}
+	if a.closed.Load() {
+		return nil, errors.New("component is closed")
+	}
+	if wait := time.Until(a.nextDial); wait > 0 {
+		return nil, fmt.Errorf("not connected to the AMQP broker, retrying in %s: %w", wait.Round(time.Millisecond), a.dialErr)
+	}
+
+	err := a.connect(ctx)
+	if err != nil {
+		a.dialErr = err
+		a.nextDial = time.Now().Add(a.dialBackOff.NextBackOff())
+		return nil, err
+	}
+	a.logger.Info("Re-connected to the AMQP broker")
+	a.dialBackOff.Reset()
+	a.nextDial = time.Time{}
+	return a.session, nil
+}
+
+// resetConnection closes the connection if err shows that it, or the session, is no longer usable, so it's re-created by the next call to getSession.
+// session is the session the error was returned from: if it was already replaced, there's nothing to do.
+func (a *amqpPubSub) resetConnection(session *amqp.Session, err error) {
+	if session == nil || !isConnectionError(err) {
+		return
+	}
+
+	a.connLock.Lock()
+	defer a.connLock.Unlock()
+	if a.session != session {
+		return
+	}
+
+	a.logger.Warnf("Lost connection to the AMQP broker: %v", err)
+	_ = a.conn.Close()
+	a.conn = nil
+	a.session = nil
+
+	// Sender links belong to the closed session
+	a.sendersLock.Lock()
+	for topic := range a.senders {
+		delete(a.senders, topic)
+	}
+	a.sendersLock.Unlock()
+}
+
+// isConnectionError returns true if err was caused by the connection or the session being closed.
+func isConnectionError(err error) bool {
+	var (
+		connErr    *amqp.ConnError
+		sessionErr *amqp.SessionError
+	)
+	return errors.As(err, &connErr) || errors.As(err, &sessionErr)
+}
+
+func (a *amqpPubSub) connOptions() (*amqp.ConnOptions, error) {
+	opts := &amqp.ConnOptions{
+		HostName: a.metadata.HostName,
+		Timeout:  a.metadata.ConnectTimeout,
+	}
+
+	switch a.metadata.SASLType {
+	case saslTypePlain:
+		opts.SASLType = amqp.SASLTypePlain(a.metadata.Username, a.metadata.Password)
+	case saslTypeAnonymous:
+		opts.SASLType = amqp.SASLTypeAnonymous()
+	case saslTypeExternal:
+		opts.SASLType = amqp.SASLTypeExternal("")
+	}
+
+	u, err := url.Parse(a.metadata.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "amqps" {
+		tlsProps, err := a.metadata.tlsProperties()
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig, err = pubsub.ConvertTLSPropertiesToTLSConfig(tlsProps)
+		if err != nil {
+			return nil, err
+		}
+		//nolint:gosec
+		opts.TLSConfig.InsecureSkipVerify = a.metadata.InsecureSkipVerify
+	}
+
+	return opts, nil
+}
+
+// Features returns the features supported by the component.
+func (a *amqpPubSub) Features() []pubsub.Feature {
+	return []pubsub.Feature{pubsub.FeatureMessageTTL}
+}
+
+// Publish sends a message to the topic's address.
+func (a *amqpPubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+	if a.closed.Load() {
+		return errors.New("component is closed")
+	}
+	if req.Topic == "" {
+		return errors.New("topic name is empty")
+	}
+
+	msg, err := toAMQPMessage(req, a.metadata.CloudEventsPropertyPrefix)
+	if err != nil {
+		return err
+	}
+
+	session, err := a.getSession(ctx)
+	if err != nil {
+		return err
+	}
+	sender, err := a.getSender(ctx, session, req.Topic)
+	if err != nil {
+		a.resetConnection(session, err)
+		return err
+	}
+	err = sender.Send(ctx, msg)
+	if err != nil {
+		// The link may have been detached: drop it so it's re-created on the next publish
+		a.removeSender(req.Topic, sender)
+		a.resetConnection(session, err)
+		return fmt.Errorf("failed to publish message to %s: %w", req.Topic, err)
+	}
+
+	return nil
+}
+
+// getSender returns the cached sender link for the topic, creating it in the session if needed.
+func (a *amqpPubSub) getSender(ctx context.Context, session *amqp.Session, topic string) (*amqp.Sender, error) {
+	a.sendersLock.Lock()
+	defer a.sendersLock.Unlock()
+
+	sender, ok := a.senders[topic]
+	if ok {
+		return sender, nil
+	}
+
+	sender, err := session.NewSender(ctx, a.metadata.address(topic), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sender link for %s: %w", topic, err)
+	}
+	a.senders[topic] = sender
+	return sender, nil
+}
+
+func (a *amqpPubSub) removeSender(topic string, sender *amqp.Sender) {
+	a.sendersLock.Lock()
+	if a.senders[topic] == sender {
+		delete(a.senders, topic)
+	}
+	a.sendersLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+	_ = sender.Close(ctx)
+}
+
+// Subscribe attaches a receiver link to the topic's address and delivers messages to the handler.
+// Each message is settled once the handler returns: it's accepted if the handler succeeded, or settled with the configured failure disposition otherwise.
+func (a *amqpPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	if a.closed.Load() {
+		return errors.New("component is closed")
+	}
+
+	// Create the first receiver synchronously so errors are returned to the caller
+	session, err := a.getSession(ctx)
+	if err != nil {
+		return err
+	}
+	receiver, err := a.newReceiver(ctx, session, req.Topic)
+	if err != nil {
+		a.resetConnection(session, err)
+		return err
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	a.wg.Add(2)
+	go func() {
+		defer a.wg.Done()
+		defer cancel()
+		select {
+		case <-a.closeCh:
+		case <-subCtx.Done():
+		}
+	}()
+	go func() {
+		defer a.wg.Done()
+		a.subscribeLoop(subCtx, session, receiver, req.Topic, handler)
+	}()
+
+	return nil
+}
+
+func (a *amqpPubSub) newReceiver(ctx context.Context, session *amqp.Session, topic string) (*amqp.Receiver, error) {
+	// Require the broker to wait for our disposition, so settlement reflects the outcome of the handler
+	senderSettleMode := amqp.SenderSettleModeUnsettled
+	receiver, err := session.NewReceiver(ctx, a.metadata.address(topic), &amqp.ReceiverOptions{
+		Credit:                    a.metadata.Credit,
+		RequestedSenderSettleMode: &senderSettleMode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receiver link for %s: %w", topic, err)
+	}
+	a.logger.Infof("Subscribed to %s", topic)
+	return receiver, nil
+}
+
+// subscribeLoop receives messages until ctx is canceled, re-attaching the receiver link if it's detached and re-connecting if the connection is lost.
+func (a *amqpPubSub) subscribeLoop(ctx context.Context, session *amqp.Session, receiver *amqp.Receiver, topic string, handler pubsub.Handler) {
+	defer a.logger.Infof("Closing subscription to %s", topic)
+
+	for {
+		var err error
+		if receiver == nil {
+			session, err = a.getSession(ctx)
+			if err == nil {
+				receiver, err = a.newReceiver(ctx, session, topic)
+			}
+		}
+		if receiver != nil {
+			err = a.receiveMessages(ctx, receiver, topic, handler)
+			closeCtx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+			_ = receiver.Close(closeCtx)
+			cancel()
+			receiver = nil
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		a.resetConnection(session, err)
+
+		a.logger.Errorf("Error receiving messages from %s: %v. Re-attaching in %s...", topic, err, a.metadata.ReconnectWait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.metadata.ReconnectWait):
+		}
+	}
+}
+
+// receiveMessages processes messages concurrently.
+// The number of messages being processed at the same time is bounded by the link credit, as the broker doesn't deliver more unsettled messages.
+func (a *amqpPubSub) receiveMessages(ctx context.Context, receiver *amqp.Receiver, topic string, handler pubsub.Handler) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Guards against brokers that send pre-settled messages, which don't consume credit
+	sem := make(chan struct{}, a.metadata.Credit)
+	for {
+		msg, err := receiver.Receive(ctx)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			a.handleMessage(ctx, receiver, msg, topic, handler)
+		}()
+	}
+}
+
+func (a *amqpPubSub) handleMessage(ctx context.Context, receiver *amqp.Receiver, msg *amqp.Message, topic string, handler pubsub.Handler) {
+	handlerErr := handler(ctx, fromAMQPMessage(msg, topic))
+
+	settleCtx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	var err error
+	if handlerErr == nil {
+		err = receiver.AcceptMessage(settleCtx, msg)
+	} else {
+		a.logger.Warnf("Error processing message from %s: %v", topic, handlerErr)
+		switch a.metadata.FailureDisposition {
+		case failureDispositionRelease:
+			err = receiver.ReleaseMessage(settleCtx, msg)
+		case failureDispositionReject:
+			err = receiver.RejectMessage(settleCtx, msg, &amqp.Error{
+				Condition:   amqp.ErrCondInternalError,
+				Description: handlerErr.Error(),
+			})
+		default:
+			err = receiver.ModifyMessage(settleCtx, msg, &amqp.ModifyMessageOptions{
+				DeliveryFailed: true,
+			})
+		}
+	}
+	if err != nil {
+		a.logger.Errorf("Failed to settle message from %s: %v", topic, err)
+	}
+}
+
+// Close stops all subscriptions and closes the connection.
+func (a *amqpPubSub) Close() error {
+	if a.closed.CompareAndSwap(false, true) {
+		close(a.closeCh)
+	}
+	a.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	a.sendersLock.Lock()
+	for topic, sender := range a.senders {
+		_ = sender.Close(ctx)
+		delete(a.senders, topic)
+	}
+	a.sendersLock.Unlock()
+
+	a.connLock.Lock()
+	defer a.connLock.Unlock()
+	if a.session != nil {
+		_ = a.session.Close(ctx)
+		a.session = nil
+	}
+	if a.conn != nil {
+		err := a.conn.Close()
+		a.conn = nil
+		return err
+	}
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (a *amqpPubSub) GetComponentMetadata() map[string]string {
+	metadataStruct := metadata{}
+	metadataInfo := map[string]string{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.PubSubType)
+	return metadataInfo
+}
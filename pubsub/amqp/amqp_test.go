@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	amqp "github.com/Azure/go-amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mdata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+func getMetadata(props map[string]string) pubsub.Metadata {
+	return pubsub.Metadata{Base: mdata.Base{Properties: props}}
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(getMetadata(map[string]string{
+			"url": "amqp://localhost:5672",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, saslTypeAnonymous, m.SASLType)
+		assert.Equal(t, uint32(defaultCredit), m.Credit)
+		assert.Equal(t, failureDispositionModify, m.FailureDisposition)
+		assert.Equal(t, defaultReconnectWait, m.ReconnectWait)
+		assert.Equal(t, "cloudEvents:", m.CloudEventsPropertyPrefix)
+		assert.Equal(t, "orders", m.address("orders"))
+	})
+
+	t.Run("plain auth and address prefix", func(t *testing.T) {
+		m, err := parseMetadata(getMetadata(map[string]string{
+			"url":                "amqps://broker:5671",
+			"username":           "user",
+			"password":           "pass",
+			"addressPrefix":      "topic://",
+			"credit":             "10",
+			"failureDisposition": "Reject",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, saslTypePlain, m.SASLType)
+		assert.Equal(t, uint32(10), m.Credit)
+		assert.Equal(t, failureDispositionReject, m.FailureDisposition)
+		assert.Equal(t, "topic://orders", m.address("orders"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := []map[string]string{
+			{},
+			{"url": "http://broker"},
+			{"url": "amqp://broker", "saslType": "plain"},
+			{"url": "amqp://broker", "saslType": "external"},
+			{"url": "amqp://broker", "saslType": "kerberos"},
+			{"url": "amqp://broker", "credit": "0"},
+			{"url": "amqp://broker", "failureDisposition": "drop"},
+		}
+		for _, props := range invalid {
+			_, err := parseMetadata(getMetadata(props))
+			assert.Error(t, err, props)
+		}
+	})
+
+	t.Run("invalid TLS certificate", func(t *testing.T) {
+		m, err := parseMetadata(getMetadata(map[string]string{
+			"url":    "amqps://broker",
+			"caCert": "not a cert",
+		}))
+		require.NoError(t, err)
+		a := &amqpPubSub{metadata: m}
+		_, err = a.connOptions()
+		require.Error(t, err)
+	})
+}
+
+func TestToAMQPMessage(t *testing.T) {
+	t.Run("metadata and ttl", func(t *testing.T) {
+		ct := "application/json"
+		msg, err := toAMQPMessage(&pubsub.PublishRequest{
+			Data:        []byte(`{"a":1}`),
+			Topic:       "orders",
+			ContentType: &ct,
+			Metadata: map[string]string{
+				"ttlInSeconds": "30",
+				"region":       "west",
+			},
+		}, defaultCloudEventsAppPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`{"a":1}`), msg.GetData())
+		assert.Equal(t, 30*time.Second, msg.Header.TTL)
+		assert.True(t, msg.Header.Durable)
+		require.NotNil(t, msg.Properties.ContentType)
+		assert.Equal(t, "application/json", *msg.Properties.ContentType)
+		assert.Equal(t, map[string]any{"region": "west"}, msg.ApplicationProperties)
+	})
+
+	t.Run("cloud event attributes", func(t *testing.T) {
+		ct := "application/cloudevents+json"
+		msg, err := toAMQPMessage(&pubsub.PublishRequest{
+			Data:        []byte(`{"id":"1234","source":"myapp","type":"order.created","specversion":"1.0","subject":"order-1","traceparent":"00-abc","data":{"a":1}}`),
+			Topic:       "orders",
+			ContentType: &ct,
+		}, defaultCloudEventsAppPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"cloudEvents:id":          "1234",
+			"cloudEvents:source":      "myapp",
+			"cloudEvents:type":        "order.created",
+			"cloudEvents:specversion": "1.0",
+			"cloudEvents:subject":     "order-1",
+			"cloudEvents:traceparent": "00-abc",
+		}, msg.ApplicationProperties)
+		assert.Equal(t, "1234", msg.Properties.MessageID)
+		require.NotNil(t, msg.Properties.Subject)
+		assert.Equal(t, "order-1", *msg.Properties.Subject)
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		_, err := toAMQPMessage(&pubsub.PublishRequest{
+			Data:     []byte("hello"),
+			Topic:    "orders",
+			Metadata: map[string]string{"ttlInSeconds": "abc"},
+		}, defaultCloudEventsAppPrefix)
+		require.Error(t, err)
+	})
+}
+
+func TestFromAMQPMessage(t *testing.T) {
+	t.Run("data and properties", func(t *testing.T) {
+		ct := "application/json"
+		msg := amqp.NewMessage([]byte(`{"a":1}`))
+		msg.Properties = &amqp.MessageProperties{ContentType: &ct}
+		msg.ApplicationProperties = map[string]any{
+			"region": "west",
+			"count":  int64(3),
+		}
+
+		res := fromAMQPMessage(msg, "orders")
+		assert.Equal(t, "orders", res.Topic)
+		assert.Equal(t, []byte(`{"a":1}`), res.Data)
+		assert.Equal(t, map[string]string{"region": "west", "count": "3"}, res.Metadata)
+		require.NotNil(t, res.ContentType)
+		assert.Equal(t, "application/json", *res.ContentType)
+	})
+
+	t.Run("value body", func(t *testing.T) {
+		msg := &amqp.Message{Value: "hello"}
+		res := fromAMQPMessage(msg, "orders")
+		assert.Equal(t, []byte("hello"), res.Data)
+		assert.Nil(t, res.Metadata)
+		assert.Nil(t, res.ContentType)
+	})
+}
+
+func TestReconnect(t *testing.T) {
+	t.Run("connection errors", func(t *testing.T) {
+		assert.True(t, isConnectionError(fmt.Errorf("failed to publish: %w", &amqp.ConnError{})))
+		assert.True(t, isConnectionError(&amqp.SessionError{}))
+		assert.False(t, isConnectionError(&amqp.DetachError{}))
+		assert.False(t, isConnectionError(errors.New("timeout")))
+	})
+
+	t.Run("dial is retried with backoff", func(t *testing.T) {
+		dials := 0
+		a := NewAMQPPubSub(logger.NewLogger("test")).(*amqpPubSub)
+		a.dial = func(string, *amqp.ConnOptions) (*amqp.Conn, error) {
+			dials++
+			return nil, errors.New("connection refused")
+		}
+		err := a.Init(context.Background(), getMetadata(map[string]string{
+			"url":           "amqp://localhost:5672",
+			"reconnectWait": "1h",
+		}))
+		require.Error(t, err)
+		assert.Equal(t, 1, dials)
+
+		// Publishing re-dials, then waits for the backoff interval before dialing again
+		req := &pubsub.PublishRequest{Topic: "orders", Data: []byte("hello")}
+		require.ErrorContains(t, a.Publish(context.Background(), req), "connection refused")
+		assert.Equal(t, 2, dials)
+		require.ErrorContains(t, a.Publish(context.Background(), req), "retrying in")
+		assert.Equal(t, 2, dials)
+
+		a.nextDial = time.Now()
+		require.ErrorContains(t, a.Publish(context.Background(), req), "connection refused")
+		assert.Equal(t, 3, dials)
+	})
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	amqp "github.com/Azure/go-amqp"
+
+	"github.com/dapr/components-contrib/contenttype"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// toAMQPMessage creates the AMQP message for a publish request.
+// The request's metadata is sent as application properties; if the message is a CloudEvent, its attributes are sent as application properties too, with the configured prefix.
+func toAMQPMessage(req *pubsub.PublishRequest, cloudEventsPrefix string) (*amqp.Message, error) {
+	msg := amqp.NewMessage(req.Data)
+	msg.Header = &amqp.MessageHeader{
+		Durable: true,
+	}
+	msg.Properties = &amqp.MessageProperties{}
+
+	ttl, ok, err := contribMetadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		msg.Header.TTL = ttl
+	}
+
+	var ct string
+	if req.ContentType != nil && *req.ContentType != "" {
+		ct = *req.ContentType
+	} else if v, ok := contribMetadata.TryGetContentType(req.Metadata); ok {
+		ct = v
+	}
+	if ct != "" {
+		msg.Properties.ContentType = &ct
+	}
+
+	props := make(map[string]any, len(req.Metadata))
+	for k, v := range req.Metadata {
+		switch k {
+		case contribMetadata.TTLMetadataKey, contribMetadata.ContentType:
+			// These are set in the message's header and properties
+		default:
+			props[k] = v
+		}
+	}
+
+	if contenttype.IsCloudEventContentType(ct) {
+		addCloudEventProperties(msg, props, req.Data, cloudEventsPrefix)
+	}
+
+	if len(props) > 0 {
+		msg.ApplicationProperties = props
+	}
+
+	return msg, nil
+}
+
+// addCloudEventProperties copies the attributes of a CloudEvent in structured mode to the application properties.
+// The "id" and "subject" attributes are also set as the message ID and subject.
+// Invalid CloudEvents are sent as-is.
+func addCloudEventProperties(msg *amqp.Message, props map[string]any, data []byte, prefix string) {
+	var ce map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&ce) != nil {
+		return
+	}
+
+	for k, v := range ce {
+		if k == pubsub.DataField || k == pubsub.DataBase64Field {
+			continue
+		}
+		switch val := v.(type) {
+		case string:
+			props[prefix+k] = val
+		case bool:
+			props[prefix+k] = val
+		case json.Number:
+			props[prefix+k] = val.String()
+		}
+	}
+
+	if id, ok := ce[pubsub.IDField].(string); ok && id != "" {
+		msg.Properties.MessageID = id
+	}
+	if subject, ok := ce[pubsub.SubjectField].(string); ok && subject != "" {
+		msg.Properties.Subject = &subject
+	}
+}
+
+// fromAMQPMessage creates the message delivered to the app from an AMQP message.
+// Application properties are added to the message's metadata.
+func fromAMQPMessage(msg *amqp.Message, topic string) *pubsub.NewMessage {
+	data := msg.GetData()
+	if len(data) == 0 && msg.Value != nil {
+		switch v := msg.Value.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			data = []byte(fmt.Sprint(v))
+		}
+	}
+
+	res := &pubsub.NewMessage{
+		Data:  data,
+		Topic: topic,
+	}
+
+	if len(msg.ApplicationProperties) > 0 {
+		res.Metadata = make(map[string]string, len(msg.ApplicationProperties))
+		for k, v := range msg.ApplicationProperties {
+			switch val := v.(type) {
+			case string:
+				res.Metadata[k] = val
+			case nil:
+				res.Metadata[k] = ""
+			default:
+				res.Metadata[k] = fmt.Sprint(val)
+			}
+		}
+	}
+
+	if msg.Properties != nil && msg.Properties.ContentType != nil {
+		ct := *msg.Properties.ContentType
+		res.ContentType = &ct
+	}
+
+	return res
+}
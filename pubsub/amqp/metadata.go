@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	saslTypePlain     = "plain"
+	saslTypeAnonymous = "anonymous"
+	saslTypeExternal  = "external"
+
+	// Dispositions for messages whose handler failed.
+	failureDispositionModify  = "modify"
+	failureDispositionRelease = "release"
+	failureDispositionReject  = "reject"
+
+	defaultCredit               = 100
+	defaultReconnectWait        = 5 * time.Second
+	defaultConnectTimeout       = 30 * time.Second
+	defaultFailureDisposition   = failureDispositionModify
+	defaultCloudEventsAppPrefix = "cloudEvents:"
+)
+
+type metadata struct {
+	// URL of the broker, with the "amqp" or "amqps" scheme.
+	URL string `mapstructure:"url"`
+	// SASL mechanism: "plain", "anonymous", or "external". Defaults to "plain" if a username is set, or "anonymous" otherwise.
+	SASLType string `mapstructure:"saslType"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Virtual host name sent in the open frame. Defaults to the host in the URL.
+	HostName string `mapstructure:"hostName"`
+	// TLS settings, used with the "amqps" scheme.
+	CACert             string `mapstructure:"caCert"`
+	ClientCert         string `mapstructure:"clientCert"`
+	ClientKey          string `mapstructure:"clientKey"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+	// Prefix added to topic names to obtain the address of the node on the broker, for example "topic://".
+	AddressPrefix string `mapstructure:"addressPrefix"`
+	// Link credit for subscriptions, which is the maximum number of unsettled messages delivered to the app at the same time.
+	Credit uint32 `mapstructure:"credit"`
+	// Disposition of messages the app failed to process: "modify" (redeliver, incrementing the delivery count), "release" (redeliver), or "reject" (dead-letter).
+	FailureDisposition string `mapstructure:"failureDisposition"`
+	// Timeout for connecting to the broker.
+	ConnectTimeout time.Duration `mapstructure:"connectTimeout"`
+	// Wait time before re-attaching a subscription link that was detached.
+	// It's also the maximum wait between attempts to re-connect to the broker after the connection is lost.
+	ReconnectWait time.Duration `mapstructure:"reconnectWait"`
+	// Prefix for the application properties that carry CloudEvent attributes.
+	CloudEventsPropertyPrefix string `mapstructure:"cloudEventsPropertyPrefix"`
+}
+
+func parseMetadata(md pubsub.Metadata) (*metadata, error) {
+	m := metadata{
+		Credit:                    defaultCredit,
+		FailureDisposition:        defaultFailureDisposition,
+		ConnectTimeout:            defaultConnectTimeout,
+		ReconnectWait:             defaultReconnectWait,
+		CloudEventsPropertyPrefix: defaultCloudEventsAppPrefix,
+	}
+	err := contribMetadata.DecodeMetadata(md.Properties, &m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	if m.URL == "" {
+		return nil, errors.New("metadata property 'url' is required")
+	}
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return nil, fmt.Errorf("metadata property 'url' is invalid: %w", err)
+	}
+	if u.Scheme != "amqp" && u.Scheme != "amqps" {
+		return nil, errors.New("metadata property 'url' must have the 'amqp' or 'amqps' scheme")
+	}
+
+	m.SASLType = strings.ToLower(m.SASLType)
+	if m.SASLType == "" {
+		if m.Username != "" {
+			m.SASLType = saslTypePlain
+		} else {
+			m.SASLType = saslTypeAnonymous
+		}
+	}
+	switch m.SASLType {
+	case saslTypePlain:
+		if m.Username == "" || m.Password == "" {
+			return nil, errors.New("metadata properties 'username' and 'password' are required with the 'plain' SASL type")
+		}
+	case saslTypeAnonymous:
+		// Nop
+	case saslTypeExternal:
+		if m.ClientCert == "" || m.ClientKey == "" {
+			return nil, errors.New("metadata properties 'clientCert' and 'clientKey' are required with the 'external' SASL type")
+		}
+	default:
+		return nil, fmt.Errorf("metadata property 'saslType' has invalid value '%s'", m.SASLType)
+	}
+
+	m.FailureDisposition = strings.ToLower(m.FailureDisposition)
+	switch m.FailureDisposition {
+	case failureDispositionModify, failureDispositionRelease, failureDispositionReject:
+		// Nop
+	default:
+		return nil, fmt.Errorf("metadata property 'failureDisposition' has invalid value '%s'", m.FailureDisposition)
+	}
+
+	if m.Credit == 0 {
+		return nil, errors.New("metadata property 'credit' must be greater than 0")
+	}
+
+	return &m, nil
+}
+
+// tlsProperties returns the TLS properties from the metadata, validating the PEM blocks.
+func (m metadata) tlsProperties() (pubsub.TLSProperties, error) {
+	return pubsub.TLS(map[string]string{
+		pubsub.CACert:     m.CACert,
+		pubsub.ClientCert: m.ClientCert,
+		pubsub.ClientKey:  m.ClientKey,
+	})
+}
+
+// address returns the address of the node for a topic.
+func (m metadata) address(topic string) string {
+	return m.AddressPrefix + topic
+}
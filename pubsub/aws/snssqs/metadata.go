@@ -19,6 +19,16 @@ type snsSqsMetadata struct {
 	SecretKey string `mapstructure:"secretKey"`
 	// aws session token to use.
 	SessionToken string `mapstructure:"sessionToken"`
+	// role to assume with the credentials above, for example for cross-account access.
+	AssumeRoleArn string `mapstructure:"assumeRoleArn"`
+	// name of the session for the assumed role.
+	SessionName string `mapstructure:"sessionName"`
+	// external ID required by the trust policy of the role to assume.
+	ExternalID string `mapstructure:"externalID"`
+	// optional role assumed first, whose credentials are then used to assume the role above.
+	IntermediateRoleArn string `mapstructure:"intermediateRoleArn"`
+	// comma-separated list of regions the component is allowed to connect to.
+	AllowedRegions string `mapstructure:"allowedRegions"`
 	// aws region in which SNS/SQS should create resources.
 	Region string `mapstructure:"region"`
 	// aws partition in which SNS/SQS should create resources.
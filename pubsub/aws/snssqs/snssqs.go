@@ -167,7 +167,18 @@ func (s *snsSqs) Init(ctx context.Context, metadata pubsub.Metadata) error {
 	s.queues = sync.Map{}
 	s.subscriptions = sync.Map{}
 
-	sess, err := awsAuth.GetClient(md.AccessKey, md.SecretKey, md.SessionToken, md.Region, md.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              md.Region,
+		Endpoint:            md.Endpoint,
+		AccessKey:           md.AccessKey,
+		SecretKey:           md.SecretKey,
+		SessionToken:        md.SessionToken,
+		AssumeRoleArn:       md.AssumeRoleArn,
+		SessionName:         md.SessionName,
+		ExternalID:          md.ExternalID,
+		IntermediateRoleArn: md.IntermediateRoleArn,
+		AllowedRegions:      md.AllowedRegions,
+	})
 	if err != nil {
 		return fmt.Errorf("error creating an AWS client: %w", err)
 	}
@@ -29,6 +29,8 @@ import (
 	"github.com/dapr/kit/ptr"
 )
 
+var _ pubsub.Seeker = (*AzureEventHubs)(nil)
+
 // AzureEventHubs allows sending/receiving Azure Event Hubs events.
 type AzureEventHubs struct {
 	*impl.AzureEventHubs
@@ -22,10 +22,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/dapr/components-contrib/crypto"
 	impl "github.com/dapr/components-contrib/internal/component/azure/servicebus"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
 
@@ -43,6 +45,11 @@ type azureServiceBus struct {
 	wg       sync.WaitGroup
 }
 
+var (
+	_ pubsub.ClaimCheckStoreSetter = (*azureServiceBus)(nil)
+	_ pubsub.CryptoProviderSetter  = (*azureServiceBus)(nil)
+)
+
 // NewAzureServiceBusQueues returns a new implementation.
 func NewAzureServiceBusQueues(logger logger.Logger) pubsub.PubSub {
 	return &azureServiceBus{
@@ -65,6 +72,16 @@ func (a *azureServiceBus) Init(_ context.Context, metadata pubsub.Metadata) (err
 	return nil
 }
 
+// SetClaimCheckStore sets the state store used to offload payloads larger than the "claimCheckThreshold".
+func (a *azureServiceBus) SetClaimCheckStore(store state.Store) error {
+	return a.client.SetClaimCheckStore(store)
+}
+
+// SetCryptoProvider sets the crypto component used to encrypt payloads when publishing and decrypt them when receiving.
+func (a *azureServiceBus) SetCryptoProvider(provider crypto.SubtleCrypto) error {
+	return a.client.SetCryptoProvider(provider)
+}
+
 func (a *azureServiceBus) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
 	if a.closed.Load() {
 		return errors.New("component is closed")
@@ -86,6 +103,15 @@ func (a *azureServiceBus) Subscribe(ctx context.Context, req pubsub.SubscribeReq
 		return errors.New("component is closed")
 	}
 
+	claimCheck, err := a.client.ClaimCheckStore()
+	if err != nil {
+		return err
+	}
+	encrypter, err := a.client.Encrypter()
+	if err != nil {
+		return err
+	}
+
 	sub := impl.NewSubscription(
 		impl.SubscriptionOptions{
 			MaxActiveMessages:     a.metadata.MaxActiveMessages,
@@ -93,9 +119,12 @@ func (a *azureServiceBus) Subscribe(ctx context.Context, req pubsub.SubscribeReq
 			MaxBulkSubCount:       nil,
 			MaxRetriableEPS:       a.metadata.MaxRetriableErrorsPerSec,
 			MaxConcurrentHandlers: a.metadata.MaxConcurrentHandlers,
+			PrefetchCount:         a.metadata.PrefetchCount,
 			Entity:                "queue " + req.Topic,
 			LockRenewalInSec:      a.metadata.LockRenewalInSec,
 			RequireSessions:       false,
+			ClaimCheck:            claimCheck,
+			Encrypter:             encrypter,
 		},
 		a.logger,
 	)
@@ -108,6 +137,15 @@ func (a *azureServiceBus) BulkSubscribe(ctx context.Context, req pubsub.Subscrib
 		return errors.New("component is closed")
 	}
 
+	claimCheck, err := a.client.ClaimCheckStore()
+	if err != nil {
+		return err
+	}
+	encrypter, err := a.client.Encrypter()
+	if err != nil {
+		return err
+	}
+
 	maxBulkSubCount := utils.GetIntValOrDefault(req.BulkSubscribeConfig.MaxMessagesCount, defaultMaxBulkSubCount)
 	sub := impl.NewSubscription(
 		impl.SubscriptionOptions{
@@ -116,9 +154,12 @@ func (a *azureServiceBus) BulkSubscribe(ctx context.Context, req pubsub.Subscrib
 			MaxBulkSubCount:       &maxBulkSubCount,
 			MaxRetriableEPS:       a.metadata.MaxRetriableErrorsPerSec,
 			MaxConcurrentHandlers: a.metadata.MaxConcurrentHandlers,
+			PrefetchCount:         a.metadata.PrefetchCount,
 			Entity:                "queue " + req.Topic,
 			LockRenewalInSec:      a.metadata.LockRenewalInSec,
 			RequireSessions:       false,
+			ClaimCheck:            claimCheck,
+			Encrypter:             encrypter,
 		},
 		a.logger,
 	)
@@ -22,10 +22,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/dapr/components-contrib/crypto"
 	impl "github.com/dapr/components-contrib/internal/component/azure/servicebus"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
 
@@ -43,6 +45,11 @@ type azureServiceBus struct {
 	wg       sync.WaitGroup
 }
 
+var (
+	_ pubsub.ClaimCheckStoreSetter = (*azureServiceBus)(nil)
+	_ pubsub.CryptoProviderSetter  = (*azureServiceBus)(nil)
+)
+
 // NewAzureServiceBusTopics returns a new pub-sub implementation.
 func NewAzureServiceBusTopics(logger logger.Logger) pubsub.PubSub {
 	return &azureServiceBus{
@@ -65,6 +72,16 @@ func (a *azureServiceBus) Init(_ context.Context, metadata pubsub.Metadata) (err
 	return nil
 }
 
+// SetClaimCheckStore sets the state store used to offload payloads larger than the "claimCheckThreshold".
+func (a *azureServiceBus) SetClaimCheckStore(store state.Store) error {
+	return a.client.SetClaimCheckStore(store)
+}
+
+// SetCryptoProvider sets the crypto component used to encrypt payloads when publishing and decrypt them when receiving.
+func (a *azureServiceBus) SetCryptoProvider(provider crypto.SubtleCrypto) error {
+	return a.client.SetCryptoProvider(provider)
+}
+
 func (a *azureServiceBus) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
 	if a.closed.Load() {
 		return errors.New("component is closed")
@@ -84,6 +101,15 @@ func (a *azureServiceBus) Subscribe(subscribeCtx context.Context, req pubsub.Sub
 		return errors.New("component is closed")
 	}
 
+	claimCheck, err := a.client.ClaimCheckStore()
+	if err != nil {
+		return err
+	}
+	encrypter, err := a.client.Encrypter()
+	if err != nil {
+		return err
+	}
+
 	requireSessions := utils.IsTruthy(req.Metadata[impl.RequireSessionsMetadataKey])
 	sessionIdleTimeout := time.Duration(utils.GetElemOrDefaultFromMap(req.Metadata, impl.SessionIdleTimeoutMetadataKey, impl.DefaultSesssionIdleTimeoutInSec)) * time.Second
 	maxConcurrentSessions := utils.GetElemOrDefaultFromMap(req.Metadata, impl.MaxConcurrentSessionsMetadataKey, impl.DefaultMaxConcurrentSessions)
@@ -95,10 +121,13 @@ func (a *azureServiceBus) Subscribe(subscribeCtx context.Context, req pubsub.Sub
 			MaxBulkSubCount:       nil,
 			MaxRetriableEPS:       a.metadata.MaxRetriableErrorsPerSec,
 			MaxConcurrentHandlers: a.metadata.MaxConcurrentHandlers,
+			PrefetchCount:         a.metadata.PrefetchCount,
 			Entity:                "topic " + req.Topic,
 			LockRenewalInSec:      a.metadata.LockRenewalInSec,
 			RequireSessions:       requireSessions,
 			SessionIdleTimeout:    sessionIdleTimeout,
+			ClaimCheck:            claimCheck,
+			Encrypter:             encrypter,
 		},
 		a.logger,
 	)
@@ -115,6 +144,15 @@ func (a *azureServiceBus) BulkSubscribe(subscribeCtx context.Context, req pubsub
 		return errors.New("component is closed")
 	}
 
+	claimCheck, err := a.client.ClaimCheckStore()
+	if err != nil {
+		return err
+	}
+	encrypter, err := a.client.Encrypter()
+	if err != nil {
+		return err
+	}
+
 	requireSessions := utils.IsTruthy(req.Metadata[impl.RequireSessionsMetadataKey])
 	sessionIdleTimeout := time.Duration(utils.GetElemOrDefaultFromMap(req.Metadata, impl.SessionIdleTimeoutMetadataKey, impl.DefaultSesssionIdleTimeoutInSec)) * time.Second
 	maxConcurrentSessions := utils.GetElemOrDefaultFromMap(req.Metadata, impl.MaxConcurrentSessionsMetadataKey, impl.DefaultMaxConcurrentSessions)
@@ -127,10 +165,13 @@ func (a *azureServiceBus) BulkSubscribe(subscribeCtx context.Context, req pubsub
 			MaxBulkSubCount:       &maxBulkSubCount,
 			MaxRetriableEPS:       a.metadata.MaxRetriableErrorsPerSec,
 			MaxConcurrentHandlers: a.metadata.MaxConcurrentHandlers,
+			PrefetchCount:         a.metadata.PrefetchCount,
 			Entity:                "topic " + req.Topic,
 			LockRenewalInSec:      a.metadata.LockRenewalInSec,
 			RequireSessions:       requireSessions,
 			SessionIdleTimeout:    sessionIdleTimeout,
+			ClaimCheck:            claimCheck,
+			Encrypter:             encrypter,
 		},
 		a.logger,
 	)
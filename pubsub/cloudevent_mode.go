@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	contribContenttype "github.com/dapr/components-contrib/contenttype"
+)
+
+// CloudEventMode is how CloudEvents are represented on the wire.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md#message.
+type CloudEventMode string
+
+const (
+	// CloudEventModeStructured sends the whole CloudEvent, encoded as JSON, as the body of the message.
+	CloudEventModeStructured CloudEventMode = "structured"
+	// CloudEventModeBinary sends the data of the CloudEvent as the body of the message, and its attributes as headers.
+	CloudEventModeBinary CloudEventMode = "binary"
+)
+
+// ParseCloudEventMode parses the value of a "cloudEventMode" metadata property.
+// If val is empty, the mode is CloudEventModeStructured.
+func ParseCloudEventMode(val string) (CloudEventMode, error) {
+	switch mode := CloudEventMode(strings.ToLower(strings.TrimSpace(val))); mode {
+	case "", CloudEventModeStructured:
+		return CloudEventModeStructured, nil
+	case CloudEventModeBinary:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid cloudEventMode '%s': must be '%s' or '%s'", val, CloudEventModeStructured, CloudEventModeBinary)
+	}
+}
+
+// ToBinaryCloudEvent converts data, a CloudEvent in structured mode, to binary mode.
+// It returns the data of the CloudEvent, and the headers that carry its attributes: each attribute is in a header named prefix followed by the attribute name, except "datacontenttype", which is in the contentTypeHeader header.
+// Empty attributes are omitted.
+// If data is not a CloudEvent, ok is false and the message should be sent unchanged.
+func ToBinaryCloudEvent(data []byte, prefix string, contentTypeHeader string) (payload []byte, headers map[string]string, ok bool) {
+	var ce map[string]interface{}
+	if unmarshalPrecise(data, &ce) != nil {
+		return nil, nil, false
+	}
+	if s, _ := ce[SpecVersionField].(string); s == "" {
+		return nil, nil, false
+	}
+	if s, _ := ce[IDField].(string); s == "" {
+		return nil, nil, false
+	}
+
+	contentType, _ := ce[DataContentTypeField].(string)
+	if b64, exists := ce[DataBase64Field]; exists {
+		s, _ := b64.(string)
+		var err error
+		payload, err = base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, nil, false
+		}
+	} else if d, exists := ce[DataField]; exists && d != nil {
+		if s, isString := d.(string); isString && !contribContenttype.IsJSONContentType(contentType) {
+			payload = []byte(s)
+		} else {
+			var err error
+			payload, err = json.Marshal(d)
+			if err != nil {
+				return nil, nil, false
+			}
+		}
+	}
+
+	headers = make(map[string]string, len(ce))
+	if contentType != "" {
+		headers[contentTypeHeader] = contentType
+	}
+	for name, v := range ce {
+		if name == DataField || name == DataBase64Field || name == DataContentTypeField {
+			continue
+		}
+		var val string
+		switch x := v.(type) {
+		case nil:
+			continue
+		case string:
+			val = x
+		case json.Number:
+			val = x.String()
+		case bool:
+			val = strconv.FormatBool(x)
+		default:
+			b, err := json.Marshal(x)
+			if err != nil {
+				return nil, nil, false
+			}
+			val = string(b)
+		}
+		if val != "" {
+			headers[prefix+name] = val
+		}
+	}
+	return payload, headers, true
+}
+
+// FromBinaryCloudEvent converts a message that carries a CloudEvent in binary mode, as created by ToBinaryCloudEvent, to a CloudEvent in structured mode.
+// If the headers don't contain the "specversion" attribute, the message is not a CloudEvent in binary mode, and ok is false.
+// Header names are matched case-insensitively.
+func FromBinaryCloudEvent(data []byte, headers map[string]string, prefix string, contentTypeHeader string) (ce []byte, ok bool) {
+	prefix = strings.ToLower(prefix)
+	event := make(map[string]interface{}, len(headers)+1)
+	var contentType string
+	for name, val := range headers {
+		lower := strings.ToLower(name)
+		switch {
+		case lower == strings.ToLower(contentTypeHeader):
+			contentType = val
+		case strings.HasPrefix(lower, prefix) && len(lower) > len(prefix):
+			event[lower[len(prefix):]] = val
+		}
+	}
+	if s, _ := event[SpecVersionField].(string); s == "" {
+		return nil, false
+	}
+
+	if contentType != "" {
+		event[DataContentTypeField] = contentType
+	}
+	if len(data) > 0 {
+		switch {
+		case (contentType == "" || contribContenttype.IsJSONContentType(contentType)) && json.Valid(data):
+			event[DataField] = json.RawMessage(data)
+		case contribContenttype.IsStringContentType(contentType) || (contentType == "" && utf8.Valid(data)):
+			event[DataField] = string(data)
+		default:
+			event[DataBase64Field] = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+
+	ce, err := json.Marshal(event)
+	if err != nil {
+		return nil, false
+	}
+	return ce, true
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCloudEventMode(t *testing.T) {
+	mode, err := ParseCloudEventMode("")
+	require.NoError(t, err)
+	assert.Equal(t, CloudEventModeStructured, mode)
+
+	mode, err = ParseCloudEventMode("Binary")
+	require.NoError(t, err)
+	assert.Equal(t, CloudEventModeBinary, mode)
+
+	_, err = ParseCloudEventMode("batched")
+	require.Error(t, err)
+}
+
+func TestBinaryCloudEvent(t *testing.T) {
+	t.Run("JSON data", func(t *testing.T) {
+		ce := NewCloudEventsEnvelope("a", "source", "type", "", "topic", "pubsub", "application/json", []byte(`{"n":12345678901234567890}`), "", "")
+		data, err := json.Marshal(ce)
+		require.NoError(t, err)
+
+		payload, headers, ok := ToBinaryCloudEvent(data, "ce_", "content-type")
+		require.True(t, ok)
+		assert.JSONEq(t, `{"n":12345678901234567890}`, string(payload))
+		assert.Equal(t, "application/json", headers["content-type"])
+		assert.Equal(t, "a", headers["ce_id"])
+		assert.Equal(t, "1.0", headers["ce_specversion"])
+		assert.Equal(t, "topic", headers["ce_topic"])
+		assert.NotContains(t, headers, "ce_traceparent", "empty attributes are omitted")
+		assert.NotContains(t, headers, "ce_data")
+
+		structured, ok := FromBinaryCloudEvent(payload, headers, "ce_", "content-type")
+		require.True(t, ok)
+		var got map[string]interface{}
+		require.NoError(t, unmarshalPrecise(structured, &got))
+		assert.Equal(t, "a", got[IDField])
+		assert.Equal(t, "type", got[TypeField])
+		assert.Equal(t, "application/json", got[DataContentTypeField])
+		assert.Equal(t, map[string]interface{}{"n": json.Number("12345678901234567890")}, got[DataField])
+	})
+
+	t.Run("text data", func(t *testing.T) {
+		data := []byte(`{"specversion":"1.0","id":"a","source":"s","type":"t","datacontenttype":"text/plain","data":"hello"}`)
+		payload, headers, ok := ToBinaryCloudEvent(data, "ce_", "content-type")
+		require.True(t, ok)
+		assert.Equal(t, "hello", string(payload))
+
+		structured, ok := FromBinaryCloudEvent(payload, headers, "ce_", "content-type")
+		require.True(t, ok)
+		assert.JSONEq(t, string(data), string(structured))
+	})
+
+	t.Run("binary data", func(t *testing.T) {
+		data := []byte(`{"specversion":"1.0","id":"a","source":"s","type":"t","datacontenttype":"application/octet-stream","data_base64":"AAEC"}`)
+		payload, headers, ok := ToBinaryCloudEvent(data, "ce_", "content-type")
+		require.True(t, ok)
+		assert.Equal(t, []byte{0, 1, 2}, payload)
+
+		structured, ok := FromBinaryCloudEvent(payload, headers, "ce_", "content-type")
+		require.True(t, ok)
+		assert.JSONEq(t, string(data), string(structured))
+	})
+
+	t.Run("not a CloudEvent", func(t *testing.T) {
+		_, _, ok := ToBinaryCloudEvent([]byte(`{"id":"a"}`), "ce_", "content-type")
+		assert.False(t, ok)
+		_, _, ok = ToBinaryCloudEvent([]byte("hello"), "ce_", "content-type")
+		assert.False(t, ok)
+
+		_, ok = FromBinaryCloudEvent([]byte("hello"), map[string]string{"content-type": "text/plain", "ce_id": "a"}, "ce_", "content-type")
+		assert.False(t, ok)
+	})
+
+	t.Run("headers are matched case-insensitively", func(t *testing.T) {
+		structured, ok := FromBinaryCloudEvent([]byte("hello"), map[string]string{"Content-Type": "text/plain", "CE_SpecVersion": "1.0", "ce_id": "a"}, "ce_", "content-type")
+		require.True(t, ok)
+		assert.JSONEq(t, `{"specversion":"1.0","id":"a","datacontenttype":"text/plain","data":"hello"}`, string(structured))
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -181,6 +182,68 @@ func FromRawPayload(data []byte, topic, pubsub string) map[string]interface{} {
 	}
 }
 
+// FromRawMessage returns a CloudEvent for a message that was published without a CloudEvent envelope (for example, when "rawPayload" is set on the subscription).
+// Unlike FromRawPayload, it respects the content type of the message, if set by the component:
+// - Messages that are already CloudEvents are not wrapped again.
+// - JSON payloads are added as "data" rather than base64-encoded, and text payloads as strings.
+// - Other payloads are base64-encoded, with their content type preserved.
+// Trace context is taken from the "traceparent" and "tracestate" metadata of the message, when present.
+func FromRawMessage(msg *NewMessage, pubsub string) map[string]interface{} {
+	traceParent := metadataValueCaseInsensitive(msg.Metadata, TraceParentField)
+	traceState := metadataValueCaseInsensitive(msg.Metadata, TraceStateField)
+
+	var contentType string
+	if msg.ContentType != nil {
+		contentType = *msg.ContentType
+	}
+
+	if contribContenttype.IsCloudEventContentType(contentType) {
+		ce, err := FromCloudEvent(msg.Data, msg.Topic, pubsub, traceParent, traceState)
+		if err == nil {
+			return ce
+		}
+	}
+
+	ce := FromRawPayload(msg.Data, msg.Topic, pubsub)
+	switch {
+	case contentType == "" || contribContenttype.IsCloudEventContentType(contentType):
+		// Keep the payload base64-encoded, as its content type is unknown or it's not a valid CloudEvent
+	case contribContenttype.IsJSONContentType(contentType):
+		var data interface{}
+		if unmarshalPrecise(msg.Data, &data) == nil {
+			delete(ce, DataBase64Field)
+			ce[DataField] = data
+			ce[DataContentTypeField] = contentType
+		}
+	case contribContenttype.IsStringContentType(contentType):
+		delete(ce, DataBase64Field)
+		ce[DataField] = string(msg.Data)
+		ce[DataContentTypeField] = contentType
+	default:
+		ce[DataContentTypeField] = contentType
+	}
+
+	if traceParent != "" {
+		ce[TraceIDField] = traceParent
+		ce[TraceParentField] = traceParent
+		ce[TraceStateField] = traceState
+	}
+
+	return ce
+}
+
+func metadataValueCaseInsensitive(md map[string]string, key string) string {
+	if v, ok := md[key]; ok {
+		return v
+	}
+	for k, v := range md {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
 // HasExpired determines if the current cloud event has expired.
 func HasExpired(cloudEvent map[string]interface{}) bool {
 	e, ok := cloudEvent[ExpirationField]
@@ -375,3 +375,63 @@ func TestNewFromRawPayload(t *testing.T) {
 		assert.Equal(t, "aGVsbG8gd29ybGQ=", n[DataBase64Field])
 	})
 }
+
+func TestFromRawMessage(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	t.Run("no content type", func(t *testing.T) {
+		n := FromRawMessage(&NewMessage{Data: []byte("hello world"), Topic: "mytopic"}, "mypubsub")
+		assert.Equal(t, "application/octet-stream", n[DataContentTypeField])
+		assert.Equal(t, "aGVsbG8gd29ybGQ=", n[DataBase64Field])
+		assert.Nil(t, n[DataField])
+		assert.Nil(t, n[TraceParentField])
+	})
+
+	t.Run("JSON payload", func(t *testing.T) {
+		n := FromRawMessage(&NewMessage{
+			Data:        []byte(`{"id": 42}`),
+			Topic:       "mytopic",
+			ContentType: ptr("application/json; charset=utf-8"),
+			Metadata:    map[string]string{"TraceParent": "00-1-2-01", "tracestate": "k=v"},
+		}, "mypubsub")
+		assert.Equal(t, "application/json; charset=utf-8", n[DataContentTypeField])
+		assert.Equal(t, map[string]interface{}{"id": json.Number("42")}, n[DataField])
+		assert.Nil(t, n[DataBase64Field])
+		assert.Equal(t, "mytopic", n[TopicField])
+		assert.Equal(t, "mypubsub", n[PubsubField])
+		assert.Equal(t, "00-1-2-01", n[TraceParentField])
+		assert.Equal(t, "00-1-2-01", n[TraceIDField])
+		assert.Equal(t, "k=v", n[TraceStateField])
+	})
+
+	t.Run("invalid JSON payload", func(t *testing.T) {
+		n := FromRawMessage(&NewMessage{Data: []byte(`{"id"`), ContentType: ptr("application/json")}, "mypubsub")
+		assert.Equal(t, "application/octet-stream", n[DataContentTypeField])
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(`{"id"`)), n[DataBase64Field])
+	})
+
+	t.Run("text payload", func(t *testing.T) {
+		n := FromRawMessage(&NewMessage{Data: []byte("hello world"), ContentType: ptr("text/plain")}, "mypubsub")
+		assert.Equal(t, "text/plain", n[DataContentTypeField])
+		assert.Equal(t, "hello world", n[DataField])
+		assert.Nil(t, n[DataBase64Field])
+	})
+
+	t.Run("binary payload", func(t *testing.T) {
+		n := FromRawMessage(&NewMessage{Data: []byte{0x1, 0x2}, ContentType: ptr("image/png")}, "mypubsub")
+		assert.Equal(t, "image/png", n[DataContentTypeField])
+		assert.Equal(t, "AQI=", n[DataBase64Field])
+	})
+
+	t.Run("CloudEvent payload is not wrapped again", func(t *testing.T) {
+		n := FromRawMessage(&NewMessage{
+			Data:        []byte(`{"specversion": "1.0", "id": "a", "source": "s", "type": "t", "data": {"id": 42}}`),
+			Topic:       "mytopic",
+			ContentType: ptr("application/cloudevents+json"),
+		}, "mypubsub")
+		assert.Equal(t, "a", n[IDField])
+		assert.Equal(t, map[string]interface{}{"id": json.Number("42")}, n[DataField])
+		assert.Equal(t, "mytopic", n[TopicField])
+		assert.Nil(t, n[DataBase64Field])
+	})
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"errors"
+	"strings"
+)
+
+// PermanentError is returned by handlers for messages that can never be processed, for example because they fail validation.
+// Components that support it don't retry these messages, and send them to the dead-letter destination right away, if one is configured.
+type PermanentError struct {
+	Err error
+}
+
+// NewPermanentError returns an error that marks err as permanent.
+func NewPermanentError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Error implements the error interface.
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorClassifier decides whether the errors returned by handlers are permanent or can be retried.
+// Errors are permanent if they wrap a PermanentError, or if their message contains one of PermanentErrorSubstrings, for apps that can't return a PermanentError.
+type ErrorClassifier struct {
+	// Comma-separated list of substrings that mark the errors containing them as permanent.
+	PermanentErrorSubstrings []string `mapstructure:"permanentErrorSubstrings"`
+}
+
+// IsPermanent returns true if err is permanent.
+func (c ErrorClassifier) IsPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+	var permanentErr *PermanentError
+	if errors.As(err, &permanentErr) {
+		return true
+	}
+	msg := err.Error()
+	for _, s := range c.PermanentErrorSubstrings {
+		s = strings.TrimSpace(s)
+		if s != "" && strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
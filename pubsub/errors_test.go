@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermanentError(t *testing.T) {
+	cause := errors.New("invalid order")
+	err := NewPermanentError(cause)
+	require.EqualError(t, err, "invalid order")
+	require.ErrorIs(t, err, cause)
+	require.NoError(t, NewPermanentError(nil))
+}
+
+func TestErrorClassifier(t *testing.T) {
+	c := ErrorClassifier{
+		PermanentErrorSubstrings: []string{"validation failed", " ", " unknown customer"},
+	}
+
+	tests := map[string]struct {
+		err       error
+		permanent bool
+	}{
+		"nil":                          {err: nil, permanent: false},
+		"transient":                    {err: errors.New("connection reset"), permanent: false},
+		"permanent error":              {err: NewPermanentError(errors.New("bad payload")), permanent: true},
+		"wrapped permanent":            {err: fmt.Errorf("handler: %w", NewPermanentError(errors.New("bad payload"))), permanent: true},
+		"matching substring":           {err: errors.New("request validation failed: missing id"), permanent: true},
+		"trimmed substring":            {err: errors.New("unknown customer 42"), permanent: true},
+		"substring is matched exactly": {err: errors.New("Validation Failed"), permanent: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.permanent, c.IsPermanent(tc.err))
+		})
+	}
+
+	t.Run("no substrings", func(t *testing.T) {
+		assert.False(t, ErrorClassifier{}.IsPermanent(errors.New("validation failed")))
+		assert.True(t, ErrorClassifier{}.IsPermanent(NewPermanentError(errors.New("validation failed"))))
+	})
+}
@@ -28,7 +28,9 @@ type metadata struct {
 	AuthProviderCertURL     string `mapstructure:"authProviderX509CertUrl"`
 	ClientCertURL           string `mapstructure:"clientX509CertUrl"`
 	DisableEntityManagement bool   `mapstructure:"disableEntityManagement"`
-	EnableMessageOrdering   bool   `mapstructure:"enableMessageOrdering"`
-	MaxReconnectionAttempts int    `mapstructure:"maxReconnectionAttempts"`
-	ConnectionRecoveryInSec int    `mapstructure:"connectionRecoveryInSec"`
+	// If true, subscriptions are created with message ordering enabled and messages can be published with an "orderingKey".
+	// Existing subscriptions must have been created with message ordering enabled too.
+	EnableMessageOrdering   bool `mapstructure:"enableMessageOrdering"`
+	MaxReconnectionAttempts int  `mapstructure:"maxReconnectionAttempts"`
+	ConnectionRecoveryInSec int  `mapstructure:"connectionRecoveryInSec"`
 }
@@ -54,6 +54,10 @@ const (
 	metadataMaxReconnectionAttemptsKey = "maxReconnectionAttempts"
 	metadataConnectionRecoveryInSecKey = "connectionRecoveryInSec"
 
+	// Publish metadata key for the ordering key of a message.
+	// Messages with the same ordering key are delivered in the order they were published, and are processed one at a time.
+	metadataOrderingKey = "orderingKey"
+
 	// Defaults.
 	defaultMaxReconnectionAttempts = 30
 	defaultConnectionRecoveryInSec = 2
@@ -65,6 +69,10 @@ type GCPPubSub struct {
 	metadata *metadata
 	logger   logger.Logger
 
+	// Handles of the topics, which are reused so their publish settings and paused ordering keys are shared by all publishes
+	topicsLock sync.Mutex
+	topics     map[string]*gcppubsub.Topic
+
 	closed  atomic.Bool
 	closeCh chan struct{}
 	wg      sync.WaitGroup
@@ -89,7 +97,11 @@ type WhatNow struct {
 
 // NewGCPPubSub returns a new GCPPubSub instance.
 func NewGCPPubSub(logger logger.Logger) pubsub.PubSub {
-	return &GCPPubSub{logger: logger, closeCh: make(chan struct{})}
+	return &GCPPubSub{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+		topics:  make(map[string]*gcppubsub.Topic),
+	}
 }
 
 func createMetadata(pubSubMetadata pubsub.Metadata) (*metadata, error) {
@@ -182,9 +194,21 @@ func (g *GCPPubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) err
 
 	topic := g.getTopic(req.Topic)
 
-	_, err := topic.Publish(ctx, &gcppubsub.Message{
+	msg := &gcppubsub.Message{
 		Data: req.Data,
-	}).Get(ctx)
+	}
+	if orderingKey := req.Metadata[metadataOrderingKey]; orderingKey != "" {
+		if !g.metadata.EnableMessageOrdering {
+			return fmt.Errorf("%s publishing with an ordering key requires '%s' to be enabled in the component metadata", errorMessagePrefix, metadataEnableMessageOrderingKey)
+		}
+		msg.OrderingKey = orderingKey
+	}
+
+	_, err := topic.Publish(ctx, msg).Get(ctx)
+	if err != nil && msg.OrderingKey != "" {
+		// After a failure, the client stops publishing messages with the same ordering key until it's resumed
+		topic.ResumePublish(msg.OrderingKey)
+	}
 
 	return err
 }
@@ -210,6 +234,13 @@ func (g *GCPPubSub) Subscribe(parentCtx context.Context, req pubsub.SubscribeReq
 	topic := g.getTopic(req.Topic)
 	sub := g.getSubscription(g.metadata.ConsumerID + "-" + req.Topic)
 
+	if g.metadata.EnableMessageOrdering {
+		err := g.checkMessageOrdering(parentCtx, sub)
+		if err != nil {
+			return err
+		}
+	}
+
 	subscribeCtx, cancel := context.WithCancel(parentCtx)
 	g.wg.Add(2)
 	go func() {
@@ -272,6 +303,9 @@ func (g *GCPPubSub) handleSubscriptionMessages(parentCtx context.Context, topic
 				Data:  m.Data,
 				Topic: topic.ID(),
 			}
+			if m.OrderingKey != "" {
+				msg.Metadata = map[string]string{metadataOrderingKey: m.OrderingKey}
+			}
 
 			err := handler(ctx, msg)
 
@@ -329,8 +363,19 @@ func (g *GCPPubSub) ensureTopic(parentCtx context.Context, topic string) error {
 	return nil
 }
 
+// getTopic returns the handle of the topic, which is created the first time the topic is used.
+// Each handle has its own publisher, which is stopped when the component is closed.
 func (g *GCPPubSub) getTopic(topic string) *gcppubsub.Topic {
-	return g.client.Topic(topic)
+	g.topicsLock.Lock()
+	defer g.topicsLock.Unlock()
+
+	entity, ok := g.topics[topic]
+	if !ok {
+		entity = g.client.Topic(topic)
+		entity.EnableMessageOrdering = g.metadata.EnableMessageOrdering
+		g.topics[topic] = entity
+	}
+	return entity
 }
 
 func (g *GCPPubSub) ensureSubscription(parentCtx context.Context, subscription string, topic string) error {
@@ -352,6 +397,19 @@ func (g *GCPPubSub) ensureSubscription(parentCtx context.Context, subscription s
 	return subErr
 }
 
+// checkMessageOrdering returns an error if the subscription doesn't have message ordering enabled.
+// Message ordering can only be enabled when a subscription is created, so existing subscriptions need to be re-created.
+func (g *GCPPubSub) checkMessageOrdering(ctx context.Context, sub *gcppubsub.Subscription) error {
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		return fmt.Errorf("%s could not verify that message ordering is enabled on subscription %s: %w", errorMessagePrefix, sub.ID(), err)
+	}
+	if !cfg.EnableMessageOrdering {
+		return fmt.Errorf("%s '%s' is set, but message ordering is not enabled on subscription %s: message ordering can only be enabled when the subscription is created", errorMessagePrefix, metadataEnableMessageOrderingKey, sub.ID())
+	}
+	return nil
+}
+
 func (g *GCPPubSub) getSubscription(subscription string) *gcppubsub.Subscription {
 	return g.client.Subscription(subscription)
 }
@@ -361,6 +419,15 @@ func (g *GCPPubSub) Close() error {
 	if g.closed.CompareAndSwap(false, true) {
 		close(g.closeCh)
 	}
+
+	// Stopping the topics sends the messages that are still buffered
+	g.topicsLock.Lock()
+	for _, topic := range g.topics {
+		topic.Stop()
+	}
+	g.topics = make(map[string]*gcppubsub.Topic)
+	g.topicsLock.Unlock()
+
 	return g.client.Close()
 }
 
@@ -14,11 +14,24 @@ limitations under the License.
 package pubsub
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
 
+	gcppubsub "cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/pstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
 )
 
 const (
@@ -128,3 +141,117 @@ func TestInit(t *testing.T) {
 		assert.ErrorContains(t, err, "connectionRecoveryInSec")
 	})
 }
+
+func newTestPubSub(t *testing.T, md *metadata) (*GCPPubSub, *pstest.Server) {
+	t.Helper()
+
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+	conn, err := grpc.Dial(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	client, err := gcppubsub.NewClient(context.Background(), "project", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+
+	g := NewGCPPubSub(logger.NewLogger("test")).(*GCPPubSub)
+	g.client = client
+	g.metadata = md
+	t.Cleanup(func() { g.Close() })
+	return g, srv
+}
+
+func TestMessageOrdering(t *testing.T) {
+	t.Run("ordering key requires message ordering", func(t *testing.T) {
+		g, _ := newTestPubSub(t, &metadata{})
+		err := g.Publish(context.Background(), &pubsub.PublishRequest{
+			Topic:    "topic",
+			Data:     []byte("a"),
+			Metadata: map[string]string{"orderingKey": "key"},
+		})
+		require.ErrorContains(t, err, "enableMessageOrdering")
+	})
+
+	t.Run("subscription without message ordering", func(t *testing.T) {
+		g, _ := newTestPubSub(t, &metadata{ConsumerID: "consumer", DisableEntityManagement: true, EnableMessageOrdering: true})
+		topic, err := g.client.CreateTopic(context.Background(), "topic")
+		require.NoError(t, err)
+		_, err = g.client.CreateSubscription(context.Background(), "consumer-topic", gcppubsub.SubscriptionConfig{Topic: topic})
+		require.NoError(t, err)
+
+		err = g.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "topic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			return nil
+		})
+		require.ErrorContains(t, err, "message ordering is not enabled on subscription consumer-topic")
+	})
+
+	t.Run("messages with the same key are delivered in order", func(t *testing.T) {
+		g, _ := newTestPubSub(t, &metadata{ConsumerID: "consumer", EnableMessageOrdering: true, MaxReconnectionAttempts: 1})
+
+		var (
+			lock     sync.Mutex
+			received []string
+			keys     []string
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := g.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "topic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+			received = append(received, string(msg.Data))
+			keys = append(keys, msg.Metadata["orderingKey"])
+			return nil
+		})
+		require.NoError(t, err)
+
+		for _, data := range []string{"1", "2", "3"} {
+			err = g.Publish(context.Background(), &pubsub.PublishRequest{
+				Topic:    "topic",
+				Data:     []byte(data),
+				Metadata: map[string]string{"orderingKey": "key"},
+			})
+			require.NoError(t, err)
+		}
+
+		assert.Eventually(t, func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(received) == 3
+		}, 5*time.Second, 10*time.Millisecond)
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(t, []string{"1", "2", "3"}, received)
+		assert.Equal(t, []string{"key", "key", "key"}, keys)
+	})
+
+	t.Run("ordering keys are resumed after a failure", func(t *testing.T) {
+		g, srv := newTestPubSub(t, &metadata{DisableEntityManagement: true, EnableMessageOrdering: true})
+		_, err := g.client.CreateTopic(context.Background(), "topic")
+		require.NoError(t, err)
+
+		srv.SetAutoPublishResponse(false)
+		srv.AddPublishResponse(nil, status.Error(codes.InvalidArgument, "rejected"))
+		srv.AddPublishResponse(&pubsubpb.PublishResponse{MessageIds: []string{"1"}}, nil)
+
+		publish := func() error {
+			return g.Publish(context.Background(), &pubsub.PublishRequest{
+				Topic:    "topic",
+				Data:     []byte("a"),
+				Metadata: map[string]string{"orderingKey": "key"},
+			})
+		}
+		require.Error(t, publish())
+		// The key was resumed on the handle used by the next publish
+		require.NoError(t, publish())
+	})
+}
+
+func TestTopicHandles(t *testing.T) {
+	g, _ := newTestPubSub(t, &metadata{EnableMessageOrdering: true})
+
+	topic := g.getTopic("topic")
+	assert.True(t, topic.EnableMessageOrdering)
+	assert.Same(t, topic, g.getTopic("topic"))
+	assert.NotSame(t, topic, g.getTopic("other"))
+
+	require.NoError(t, g.Close())
+	assert.Empty(t, g.topics)
+}
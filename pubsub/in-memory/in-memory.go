@@ -16,21 +16,39 @@ package inmemory
 import (
 	"context"
 	"errors"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/dapr/components-contrib/internal/eventbus"
+	"github.com/dapr/components-contrib/internal/utils"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
 
+const (
+	defaultMaxBulkSubCount           = 100
+	defaultMaxBulkSubAwaitDurationMs = 1000
+)
+
 type bus struct {
-	bus     eventbus.Bus
-	log     logger.Logger
-	closed  atomic.Bool
-	closeCh chan struct{}
-	wg      sync.WaitGroup
+	bus      eventbus.Bus
+	metadata metadata
+	log      logger.Logger
+	closed   atomic.Bool
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+}
+
+// message is a message published on the bus.
+type message struct {
+	data        []byte
+	metadata    map[string]string
+	contentType string
 }
 
 func New(logger logger.Logger) pubsub.PubSub {
@@ -53,6 +71,11 @@ func (a *bus) Features() []pubsub.Feature {
 }
 
 func (a *bus) Init(_ context.Context, metadata pubsub.Metadata) error {
+	m, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	a.metadata = m
 	a.bus = eventbus.New(true)
 
 	return nil
@@ -63,33 +86,205 @@ func (a *bus) Publish(_ context.Context, req *pubsub.PublishRequest) error {
 		return errors.New("component is closed")
 	}
 
-	a.bus.Publish(req.Topic, req.Data)
+	msg := &message{
+		data:     req.Data,
+		metadata: req.Metadata,
+	}
+	if req.ContentType != nil {
+		msg.contentType = *req.ContentType
+	}
+	a.publish(req.Topic, msg)
 
 	return nil
 }
 
+// BulkPublish publishes each entry as a separate message.
+func (a *bus) BulkPublish(_ context.Context, req *pubsub.BulkPublishRequest) (pubsub.BulkPublishResponse, error) {
+	if a.closed.Load() {
+		err := errors.New("component is closed")
+		return pubsub.NewBulkPublishResponse(req.Entries, err), err
+	}
+
+	for _, entry := range req.Entries {
+		a.publish(req.Topic, &message{
+			data:        entry.Event,
+			metadata:    entry.Metadata,
+			contentType: entry.ContentType,
+		})
+	}
+
+	return pubsub.BulkPublishResponse{}, nil
+}
+
+// publish delivers the message to subscribers, after the configured delay.
+func (a *bus) publish(topic string, msg *message) {
+	if a.metadata.DeliveryDelay <= 0 {
+		a.bus.Publish(topic, msg)
+		return
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		select {
+		case <-time.After(a.metadata.DeliveryDelay):
+			a.bus.Publish(topic, msg)
+		case <-a.closeCh:
+		}
+	}()
+}
+
 func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
 	if a.closed.Load() {
 		return errors.New("component is closed")
 	}
 
 	// For this component we allow built-in retries because it is backed by memory
-	retryHandler := func(data []byte) {
-		for i := 0; i < 10; i++ {
-			handleErr := handler(ctx, &pubsub.NewMessage{Data: data, Topic: req.Topic, Metadata: req.Metadata})
-			if handleErr == nil {
-				break
-			}
-			a.log.Error(handleErr)
+	retryHandler := func(msg *message) {
+		newMsg := &pubsub.NewMessage{
+			Data:     msg.data,
+			Topic:    req.Topic,
+			Metadata: msg.metadata,
+		}
+		if msg.contentType != "" {
+			newMsg.ContentType = &msg.contentType
+		}
+		a.deliver(ctx, req.Topic, func() error {
+			return handler(ctx, newMsg)
+		})
+	}
+
+	return a.subscribe(ctx, req.Topic, retryHandler)
+}
+
+// BulkSubscribe delivers messages in batches of up to MaxMessagesCount messages, waiting up to MaxAwaitDurationMs for a batch to fill.
+// Entries whose handler failed are redelivered in a new batch.
+func (a *bus) BulkSubscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.BulkHandler) error {
+	if a.closed.Load() {
+		return errors.New("component is closed")
+	}
+
+	maxCount := utils.GetIntValOrDefault(req.BulkSubscribeConfig.MaxMessagesCount, defaultMaxBulkSubCount)
+	maxAwait := time.Duration(utils.GetIntValOrDefault(req.BulkSubscribeConfig.MaxAwaitDurationMs, defaultMaxBulkSubAwaitDurationMs)) * time.Millisecond
+
+	msgCh := make(chan *message, maxCount)
+	enqueueHandler := func(msg *message) {
+		select {
+		case msgCh <- msg:
+		case <-ctx.Done():
+		case <-a.closeCh:
+		}
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		batch := make([]*message, 0, maxCount)
+		flush := func() {
+			a.deliverBatch(ctx, req.Topic, batch, handler)
+			batch = make([]*message, 0, maxCount)
+		}
+
+		var awaitCh <-chan time.Time
+		for {
 			select {
-			case <-time.After(100 * time.Millisecond):
-				// Nop
+			case msg := <-msgCh:
+				batch = append(batch, msg)
+				if len(batch) == 1 {
+					awaitCh = time.After(maxAwait)
+				}
+				if len(batch) >= maxCount {
+					flush()
+					awaitCh = nil
+				}
+			case <-awaitCh:
+				flush()
+				awaitCh = nil
 			case <-ctx.Done():
 				return
+			case <-a.closeCh:
+				return
 			}
 		}
+	}()
+
+	return a.subscribe(ctx, req.Topic, enqueueHandler)
+}
+
+// deliverBatch delivers a batch of messages to a bulk handler.
+func (a *bus) deliverBatch(ctx context.Context, topic string, batch []*message, handler pubsub.BulkHandler) {
+	entries := make([]pubsub.BulkMessageEntry, len(batch))
+	for i, msg := range batch {
+		entries[i] = pubsub.BulkMessageEntry{
+			EntryId:     uuid.New().String(),
+			Event:       msg.data,
+			ContentType: msg.contentType,
+			Metadata:    msg.metadata,
+		}
 	}
-	err := a.bus.SubscribeAsync(req.Topic, retryHandler, true)
+
+	a.deliver(ctx, topic, func() error {
+		statuses, err := handler(ctx, &pubsub.BulkMessage{
+			Entries: entries,
+			Topic:   topic,
+		})
+		if err == nil {
+			return nil
+		}
+		if statuses == nil {
+			// All entries failed
+			return err
+		}
+
+		// Only redeliver the entries that failed
+		failed := make(map[string]struct{}, len(statuses))
+		for _, s := range statuses {
+			if s.Error != nil {
+				failed[s.EntryId] = struct{}{}
+			}
+		}
+		remaining := make([]pubsub.BulkMessageEntry, 0, len(failed))
+		for _, e := range entries {
+			if _, ok := failed[e.EntryId]; ok {
+				remaining = append(remaining, e)
+			}
+		}
+		entries = remaining
+		if len(entries) == 0 {
+			return nil
+		}
+		return err
+	})
+}
+
+// deliver invokes fn until it succeeds, up to the configured number of delivery attempts.
+func (a *bus) deliver(ctx context.Context, topic string, fn func() error) {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return
+		}
+		if attempt >= a.metadata.MaxDeliveryAttempts {
+			a.log.Errorf("Failed to deliver message on topic %s after %d attempts: %v", topic, attempt, err)
+			return
+		}
+		a.log.Warnf("Error delivering message on topic %s, redelivering in %v: %v", topic, a.metadata.RedeliveryInterval, err)
+
+		select {
+		case <-time.After(a.metadata.RedeliveryInterval):
+			// Nop
+		case <-ctx.Done():
+			return
+		case <-a.closeCh:
+			return
+		}
+	}
+}
+
+// subscribe adds the handler to the topic, and removes it when ctx is done or the component is closed.
+func (a *bus) subscribe(ctx context.Context, topic string, handler func(msg *message)) error {
+	err := a.bus.SubscribeAsync(topic, handler, true)
 	if err != nil {
 		return err
 	}
@@ -102,9 +297,9 @@ func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handle
 		case <-ctx.Done():
 		case <-a.closeCh:
 		}
-		err := a.bus.Unsubscribe(req.Topic, retryHandler)
+		err := a.bus.Unsubscribe(topic, handler)
 		if err != nil {
-			a.log.Errorf("error while unsubscribing from topic %s: %v", req.Topic, err)
+			a.log.Errorf("error while unsubscribing from topic %s: %v", topic, err)
 		}
 	}()
 
@@ -113,5 +308,14 @@ func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handle
 
 // GetComponentMetadata returns the metadata of the component.
 func (a *bus) GetComponentMetadata() map[string]string {
-	return map[string]string{}
+	metadataStruct := metadata{}
+	metadataInfo := map[string]string{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.PubSubType)
+	return metadataInfo
 }
+
+// Ensure the component supports bulk operations.
+var (
+	_ pubsub.BulkPublisher  = (*bus)(nil)
+	_ pubsub.BulkSubscriber = (*bus)(nil)
+)
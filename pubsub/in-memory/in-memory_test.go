@@ -16,10 +16,14 @@ package inmemory
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
@@ -102,6 +106,155 @@ func TestRetry(t *testing.T) {
 	assert.Equal(t, 5, i)
 }
 
+func TestMessageMetadata(t *testing.T) {
+	bus := New(logger.NewLogger("test"))
+	bus.Init(context.Background(), pubsub.Metadata{})
+
+	ch := make(chan *pubsub.NewMessage, 1)
+	bus.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		ch <- msg
+		return nil
+	})
+
+	ct := "text/plain"
+	bus.Publish(context.Background(), &pubsub.PublishRequest{
+		Data:        []byte("ABCD"),
+		Topic:       "demo",
+		Metadata:    map[string]string{"key": "value"},
+		ContentType: &ct,
+	})
+	msg := <-ch
+	assert.Equal(t, map[string]string{"key": "value"}, msg.Metadata)
+	require.NotNil(t, msg.ContentType)
+	assert.Equal(t, "text/plain", *msg.ContentType)
+}
+
+func TestDeliveryDelay(t *testing.T) {
+	bus := New(logger.NewLogger("test"))
+	err := bus.Init(context.Background(), pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+		"deliveryDelay": "200ms",
+	}}})
+	require.NoError(t, err)
+	defer bus.Close()
+
+	ch := make(chan []byte)
+	bus.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		return publish(ch, msg)
+	})
+
+	start := time.Now()
+	bus.Publish(context.Background(), &pubsub.PublishRequest{Data: []byte("ABCD"), Topic: "demo"})
+	assert.Equal(t, "ABCD", string(<-ch))
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestRedeliveryDisabled(t *testing.T) {
+	bus := New(logger.NewLogger("test"))
+	err := bus.Init(context.Background(), pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+		"maxDeliveryAttempts": "1",
+	}}})
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	ch := make(chan []byte)
+	bus.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		calls.Add(1)
+		if string(msg.Data) == "fail" {
+			return errors.New("failed")
+		}
+		return publish(ch, msg)
+	})
+
+	bus.Publish(context.Background(), &pubsub.PublishRequest{Data: []byte("fail"), Topic: "demo"})
+	bus.Publish(context.Background(), &pubsub.PublishRequest{Data: []byte("ok"), Topic: "demo"})
+	assert.Equal(t, "ok", string(<-ch))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestBulkPublishSubscribe(t *testing.T) {
+	bus := New(logger.NewLogger("test"))
+	err := bus.Init(context.Background(), pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+		"redeliveryInterval": "10ms",
+	}}})
+	require.NoError(t, err)
+	defer bus.Close()
+
+	ch := make(chan *pubsub.BulkMessage, 10)
+	var failedOnce atomic.Bool
+	err = bus.(pubsub.BulkSubscriber).BulkSubscribe(context.Background(), pubsub.SubscribeRequest{
+		Topic: "demo",
+		BulkSubscribeConfig: pubsub.BulkSubscribeConfig{
+			MaxMessagesCount:   3,
+			MaxAwaitDurationMs: 100,
+		},
+	}, func(ctx context.Context, msg *pubsub.BulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+		ch <- msg
+		// Fail the second entry of the first batch
+		if failedOnce.CompareAndSwap(false, true) {
+			statuses := make([]pubsub.BulkSubscribeResponseEntry, len(msg.Entries))
+			for i, e := range msg.Entries {
+				statuses[i].EntryId = e.EntryId
+			}
+			statuses[1].Error = errors.New("failed")
+			return statuses, errors.New("failed")
+		}
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	res, err := bus.(pubsub.BulkPublisher).BulkPublish(context.Background(), &pubsub.BulkPublishRequest{
+		Topic: "demo",
+		Entries: []pubsub.BulkMessageEntry{
+			{EntryId: "1", Event: []byte("a"), ContentType: "text/plain"},
+			{EntryId: "2", Event: []byte("b"), ContentType: "text/plain"},
+			{EntryId: "3", Event: []byte("c"), ContentType: "text/plain"},
+			{EntryId: "4", Event: []byte("d"), ContentType: "text/plain", Metadata: map[string]string{"key": "value"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, res.FailedEntries)
+
+	events := func(msg *pubsub.BulkMessage) []string {
+		res := make([]string, len(msg.Entries))
+		for i, e := range msg.Entries {
+			res[i] = string(e.Event)
+		}
+		return res
+	}
+
+	// The first batch is full
+	msg := <-ch
+	assert.Equal(t, "demo", msg.Topic)
+	assert.Equal(t, []string{"a", "b", "c"}, events(msg))
+	assert.Equal(t, "text/plain", msg.Entries[0].ContentType)
+
+	// Only the failed entry is redelivered
+	msg = <-ch
+	assert.Equal(t, []string{"b"}, events(msg))
+
+	// The last batch is delivered after the await duration
+	msg = <-ch
+	assert.Equal(t, []string{"d"}, events(msg))
+	assert.Equal(t, map[string]string{"key": "value"}, msg.Entries[0].Metadata)
+}
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata(pubsub.Metadata{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), m.DeliveryDelay)
+	assert.Equal(t, defaultMaxDeliveryAttempts, m.MaxDeliveryAttempts)
+	assert.Equal(t, defaultRedeliveryInterval, m.RedeliveryInterval)
+
+	for _, props := range []map[string]string{
+		{"deliveryDelay": "-1s"},
+		{"maxDeliveryAttempts": "0"},
+		{"redeliveryInterval": "-1s"},
+	} {
+		_, err = parseMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		assert.Error(t, err, props)
+	}
+}
+
 func publish(ch chan []byte, msg *pubsub.NewMessage) error {
 	go func() { ch <- msg.Data }()
 
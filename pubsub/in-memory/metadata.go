@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"errors"
+	"time"
+
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	defaultMaxDeliveryAttempts = 10
+	defaultRedeliveryInterval  = 100 * time.Millisecond
+)
+
+type metadata struct {
+	// Delay before published messages are delivered to subscribers.
+	DeliveryDelay time.Duration `mapstructure:"deliveryDelay"`
+	// Maximum number of times a message is delivered when the handler fails. Set to 1 to disable redelivery.
+	MaxDeliveryAttempts int `mapstructure:"maxDeliveryAttempts"`
+	// Interval between deliveries of a message that failed.
+	RedeliveryInterval time.Duration `mapstructure:"redeliveryInterval"`
+}
+
+func parseMetadata(md pubsub.Metadata) (metadata, error) {
+	m := metadata{
+		MaxDeliveryAttempts: defaultMaxDeliveryAttempts,
+		RedeliveryInterval:  defaultRedeliveryInterval,
+	}
+	err := contribMetadata.DecodeMetadata(md.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.DeliveryDelay < 0 {
+		return m, errors.New("metadata property 'deliveryDelay' must not be negative")
+	}
+	if m.MaxDeliveryAttempts < 1 {
+		return m, errors.New("metadata property 'maxDeliveryAttempts' must be at least 1")
+	}
+	if m.RedeliveryInterval < 0 {
+		return m, errors.New("metadata property 'redeliveryInterval' must not be negative")
+	}
+
+	return m, nil
+}
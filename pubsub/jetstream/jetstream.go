@@ -19,6 +19,7 @@ import (
 	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nkeys"
@@ -29,6 +30,9 @@ import (
 	"github.com/dapr/kit/retry"
 )
 
+// Maximum time to wait for the messages already received to be processed when a subscription is removed.
+const drainTimeout = 30 * time.Second
+
 type jetstreamPubSub struct {
 	nc   *nats.Conn
 	jsc  nats.JetStreamContext
@@ -192,6 +196,9 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 	consumerConfig.AckPolicy = js.meta.internalAckPolicy
 	consumerConfig.FilterSubject = req.Topic
 
+	// Messages already received when the subscription is removed are processed with this context, which outlives ctx
+	handlerCtx, handlerCancel := context.WithCancel(context.Background())
+
 	natsHandler := func(m *nats.Msg) {
 		jsm, err := m.Metadata()
 		if err != nil {
@@ -203,7 +210,7 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 		}
 
 		js.l.Debugf("Processing JetStream message %s/%d", m.Subject, jsm.Sequence)
-		err = handler(ctx, &pubsub.NewMessage{
+		err = handler(handlerCtx, &pubsub.NewMessage{
 			Topic: req.Topic,
 			Data:  m.Data,
 			Metadata: map[string]string{
@@ -236,6 +243,7 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 	if streamName == "" {
 		streamName, err = js.jsc.StreamNameBySubject(req.Topic)
 		if err != nil {
+			handlerCancel()
 			return err
 		}
 	}
@@ -243,6 +251,7 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 
 	consumerInfo, err := js.jsc.AddConsumer(streamName, &consumerConfig)
 	if err != nil {
+		handlerCancel()
 		return err
 	}
 
@@ -255,25 +264,65 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 		subscription, err = js.jsc.Subscribe(req.Topic, natsHandler, nats.Bind(streamName, consumerInfo.Name))
 	}
 	if err != nil {
+		handlerCancel()
 		return err
 	}
 
 	js.wg.Add(1)
 	go func() {
 		defer js.wg.Done()
+		defer handlerCancel()
 		select {
 		case <-ctx.Done():
+			// The subscription was removed, while other subscriptions keep running
+			js.drainSubscription(subscription, req.Topic)
 		case <-js.closeCh:
-		}
-		err := subscription.Unsubscribe()
-		if err != nil {
-			js.l.Warnf("nats: error while unsubscribing from topic %s: %v", req.Topic, err)
+			err := subscription.Unsubscribe()
+			if err != nil {
+				js.l.Warnf("nats: error while unsubscribing from topic %s: %v", req.Topic, err)
+			}
 		}
 	}()
 
 	return nil
 }
 
+// drainSubscription stops receiving messages for the subscription, and unsubscribes after the messages already received are processed and acknowledged.
+// It waits up to drainTimeout, or until the component is closed.
+func (js *jetstreamPubSub) drainSubscription(subscription *nats.Subscription, topic string) {
+	err := subscription.Drain()
+	if err != nil {
+		js.l.Warnf("nats: error while draining subscription to topic %s: %v", topic, err)
+		err = subscription.Unsubscribe()
+		if err != nil {
+			js.l.Warnf("nats: error while unsubscribing from topic %s: %v", topic, err)
+		}
+		return
+	}
+
+	timeout := time.NewTimer(drainTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	// The subscription becomes invalid once draining is complete
+	for subscription.IsValid() {
+		select {
+		case <-ticker.C:
+		case <-js.closeCh:
+			return
+		case <-timeout.C:
+			js.l.Warnf("nats: timed out draining subscription to topic %s, unsubscribing", topic)
+			err = subscription.Unsubscribe()
+			if err != nil {
+				js.l.Warnf("nats: error while unsubscribing from topic %s: %v", topic, err)
+			}
+			return
+		}
+	}
+	js.l.Debugf("nats: unsubscribed from topic %s after draining", topic)
+}
+
 func (js *jetstreamPubSub) Close() error {
 	defer js.wg.Wait()
 	if js.closed.CompareAndSwap(false, true) {
@@ -22,11 +22,24 @@ import (
 
 	"github.com/dapr/kit/logger"
 
+	"github.com/dapr/components-contrib/crypto"
 	"github.com/dapr/components-contrib/internal/component/kafka"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/tracing"
+)
+
+var (
+	_ pubsub.IdempotencyStoreSetter = (*PubSub)(nil)
+	_ pubsub.ClaimCheckStoreSetter  = (*PubSub)(nil)
+	_ pubsub.CryptoProviderSetter   = (*PubSub)(nil)
+	_ pubsub.DeadLetterStoreSetter  = (*PubSub)(nil)
+	_ pubsub.DeliveryMetricsSetter  = (*PubSub)(nil)
+	_ pubsub.Seeker                 = (*PubSub)(nil)
+	_ tracing.TracerSetter          = (*PubSub)(nil)
 )
 
 type PubSub struct {
@@ -36,6 +49,10 @@ type PubSub struct {
 	closed  atomic.Bool
 	closeCh chan struct{}
 	wg      sync.WaitGroup
+
+	// Context of the consumer group, which outlives the context of each subscription, as subscriptions are added and removed at runtime
+	subscribeCtx    context.Context
+	subscribeCancel context.CancelFunc
 }
 
 func (p *PubSub) Init(ctx context.Context, metadata pubsub.Metadata) error {
@@ -74,6 +91,29 @@ func (p *PubSub) BulkSubscribe(ctx context.Context, req pubsub.SubscribeRequest,
 }
 
 func (p *PubSub) subscribeUtil(ctx context.Context, req pubsub.SubscribeRequest, handlerConfig kafka.SubscriptionHandlerConfig) error {
+	err := p.kafka.CheckIdempotencyStore()
+	if err != nil {
+		return err
+	}
+	err = p.kafka.CheckClaimCheckStore()
+	if err != nil {
+		return err
+	}
+	err = p.kafka.CheckDeadLetterStore()
+	if err != nil {
+		return err
+	}
+	err = p.kafka.CheckCryptoProvider()
+	if err != nil {
+		return err
+	}
+
+	// Check the topic before adding the handler, so a missing topic doesn't leave a stale handler
+	err = p.kafka.EnsureTopics(req.Topic)
+	if err != nil {
+		return err
+	}
+
 	p.kafka.AddTopicHandler(req.Topic, handlerConfig)
 
 	p.wg.Add(1)
@@ -83,23 +123,19 @@ func (p *PubSub) subscribeUtil(ctx context.Context, req pubsub.SubscribeRequest,
 		select {
 		case <-ctx.Done():
 		case <-p.closeCh:
-		}
-
-		// Remove the topic handler before restarting the subscriber
-		p.kafka.RemoveTopicHandler(req.Topic)
-
-		// If the component's context has been canceled, do not re-subscribe
-		if ctx.Err() != nil {
+			// The consumer group is stopped by Close
 			return
 		}
 
-		err := p.kafka.Subscribe(ctx)
+		// Stop consuming the topic, draining its messages being processed, and keep consuming the other topics
+		err := p.kafka.Unsubscribe(p.subscribeCtx, req.Topic)
 		if err != nil {
-			p.logger.Errorf("kafka pubsub: error re-subscribing: %v", err)
+			p.logger.Errorf("kafka pubsub: error re-subscribing after removing topic %s: %v", req.Topic, err)
 		}
 	}()
 
-	return p.kafka.Subscribe(ctx)
+	// Restart the consumer group with the new topic; other topics keep their committed offsets
+	return p.kafka.Subscribe(p.subscribeCtx)
 }
 
 // NewKafka returns a new kafka pubsub instance.
@@ -107,13 +143,46 @@ func NewKafka(logger logger.Logger) pubsub.PubSub {
 	k := kafka.NewKafka(logger)
 	// in kafka pubsub component, enable consumer retry by default
 	k.DefaultConsumeRetryEnabled = true
+	subscribeCtx, subscribeCancel := context.WithCancel(context.Background())
 	return &PubSub{
-		kafka:   k,
-		logger:  logger,
-		closeCh: make(chan struct{}),
+		kafka:           k,
+		logger:          logger,
+		closeCh:         make(chan struct{}),
+		subscribeCtx:    subscribeCtx,
+		subscribeCancel: subscribeCancel,
 	}
 }
 
+// SetIdempotencyStore sets the state store used to record the IDs of processed messages, so duplicate deliveries are skipped.
+func (p *PubSub) SetIdempotencyStore(store state.Store) error {
+	return p.kafka.SetIdempotencyStore(store)
+}
+
+// SetClaimCheckStore sets the state store used to offload payloads larger than the "claimCheckThreshold".
+func (p *PubSub) SetClaimCheckStore(store state.Store) error {
+	return p.kafka.SetClaimCheckStore(store)
+}
+
+// SetDeadLetterStore sets the state store that messages are saved to when they can't be processed after all retries.
+func (p *PubSub) SetDeadLetterStore(store state.Store) error {
+	return p.kafka.SetDeadLetterStore(store)
+}
+
+// SetCryptoProvider sets the crypto component used to encrypt payloads when publishing and decrypt them when receiving.
+func (p *PubSub) SetCryptoProvider(provider crypto.SubtleCrypto) error {
+	return p.kafka.SetCryptoProvider(provider)
+}
+
+// SetDeliveryMetricsRecorder sets the recorder of metrics about the delivery of messages.
+func (p *PubSub) SetDeliveryMetricsRecorder(recorder pubsub.DeliveryMetricsRecorder) {
+	p.kafka.SetDeliveryMetricsRecorder(recorder)
+}
+
+// SetTracer sets the tracer used to create spans for published and consumed messages.
+func (p *PubSub) SetTracer(tracer tracing.Tracer) {
+	p.kafka.SetTracer(tracer)
+}
+
 // Publish message to Kafka cluster.
 func (p *PubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
 	if p.closed.Load() {
@@ -123,6 +192,17 @@ func (p *PubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) error
 	return p.kafka.Publish(ctx, req.Topic, req.Data, req.Metadata)
 }
 
+// Seek moves the subscription to a topic to an offset or a point in time, restarting the consumer group.
+// Only the partitions consumed by this instance are moved.
+func (p *PubSub) Seek(ctx context.Context, req *pubsub.SeekRequest) error {
+	if p.closed.Load() {
+		return errors.New("component is closed")
+	}
+
+	// The consumer group outlives the request
+	return p.kafka.Seek(p.subscribeCtx, req)
+}
+
 // BatchPublish messages to Kafka cluster.
 func (p *PubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest) (pubsub.BulkPublishResponse, error) {
 	if p.closed.Load() {
@@ -136,6 +216,7 @@ func (p *PubSub) Close() (err error) {
 	defer p.wg.Wait()
 	if p.closed.CompareAndSwap(false, true) {
 		close(p.closeCh)
+		p.subscribeCancel()
 	}
 	return p.kafka.Close()
 }
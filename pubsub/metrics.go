@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryOutcome is the outcome of the delivery of a message to a subscriber.
+type DeliveryOutcome string
+
+const (
+	// DeliveryAcked means that the message was processed successfully and acknowledged.
+	DeliveryAcked DeliveryOutcome = "acked"
+	// DeliveryRetried means that processing the message failed, and it will be delivered again.
+	DeliveryRetried DeliveryOutcome = "retried"
+	// DeliveryNacked means that processing the message failed, and it will not be delivered again.
+	DeliveryNacked DeliveryOutcome = "nacked"
+	// DeliveryDeadLettered means that processing the message failed, and it was moved to a dead-letter queue.
+	DeliveryDeadLettered DeliveryOutcome = "deadLettered"
+)
+
+// DeliveryMetricsRecorder records metrics about the delivery of messages to subscribers.
+// Its methods are invoked concurrently.
+type DeliveryMetricsRecorder interface {
+	// RecordDelivered is invoked before a message received on topic is passed to the handler.
+	RecordDelivered(topic string)
+	// RecordOutcome is invoked after the handler returned, with the time the handler took.
+	RecordOutcome(topic string, outcome DeliveryOutcome, latency time.Duration)
+}
+
+// DeliveryMetricsSetter is implemented by components that report metrics about the delivery of messages.
+// The recorder must be set before subscribing.
+type DeliveryMetricsSetter interface {
+	SetDeliveryMetricsRecorder(recorder DeliveryMetricsRecorder)
+}
+
+// StartDelivery records that a message received on topic is delivered to the handler, and returns a function that records the outcome once the handler returned.
+// If recorder is nil, nothing is recorded.
+func StartDelivery(recorder DeliveryMetricsRecorder, topic string) func(outcome DeliveryOutcome) {
+	if recorder == nil {
+		return func(DeliveryOutcome) {}
+	}
+	recorder.RecordDelivered(topic)
+	start := time.Now()
+	return func(outcome DeliveryOutcome) {
+		recorder.RecordOutcome(topic, outcome, time.Since(start))
+	}
+}
+
+// DefaultLatencyBuckets are the upper bounds of the buckets of the latency histograms of DeliveryMetrics, if none are specified.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// DeliveryMetrics is a DeliveryMetricsRecorder that keeps per-topic counters and latency histograms in memory.
+type DeliveryMetrics struct {
+	buckets []time.Duration
+	topics  sync.Map // topic -> *topicDeliveryMetrics
+}
+
+type topicDeliveryMetrics struct {
+	delivered    atomic.Int64
+	acked        atomic.Int64
+	retried      atomic.Int64
+	nacked       atomic.Int64
+	deadLettered atomic.Int64
+	// One more than the buckets, for latencies above the last bound
+	latencyCounts []atomic.Int64
+	latencySum    atomic.Int64
+}
+
+// TopicDeliveryMetrics is a snapshot of the delivery metrics of a topic.
+type TopicDeliveryMetrics struct {
+	Delivered    int64
+	Acked        int64
+	Retried      int64
+	Nacked       int64
+	DeadLettered int64
+	// Upper bounds of the latency buckets.
+	LatencyBuckets []time.Duration
+	// Number of latencies in each bucket; the last element counts latencies greater than the last bound.
+	LatencyCounts []int64
+	LatencySum    time.Duration
+}
+
+// NewDeliveryMetrics returns a new DeliveryMetrics with the given upper bounds of the latency buckets, or DefaultLatencyBuckets if none.
+func NewDeliveryMetrics(buckets ...time.Duration) *DeliveryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &DeliveryMetrics{buckets: buckets}
+}
+
+func (m *DeliveryMetrics) topic(topic string) *topicDeliveryMetrics {
+	if t, ok := m.topics.Load(topic); ok {
+		return t.(*topicDeliveryMetrics)
+	}
+	t, _ := m.topics.LoadOrStore(topic, &topicDeliveryMetrics{
+		latencyCounts: make([]atomic.Int64, len(m.buckets)+1),
+	})
+	return t.(*topicDeliveryMetrics)
+}
+
+// RecordDelivered implements DeliveryMetricsRecorder.
+func (m *DeliveryMetrics) RecordDelivered(topic string) {
+	m.topic(topic).delivered.Add(1)
+}
+
+// RecordOutcome implements DeliveryMetricsRecorder.
+func (m *DeliveryMetrics) RecordOutcome(topic string, outcome DeliveryOutcome, latency time.Duration) {
+	t := m.topic(topic)
+	switch outcome {
+	case DeliveryAcked:
+		t.acked.Add(1)
+	case DeliveryRetried:
+		t.retried.Add(1)
+	case DeliveryNacked:
+		t.nacked.Add(1)
+	case DeliveryDeadLettered:
+		t.deadLettered.Add(1)
+	}
+
+	i := sort.Search(len(m.buckets), func(i int) bool { return latency <= m.buckets[i] })
+	t.latencyCounts[i].Add(1)
+	t.latencySum.Add(int64(latency))
+}
+
+// Snapshot returns the metrics of each topic.
+func (m *DeliveryMetrics) Snapshot() map[string]TopicDeliveryMetrics {
+	res := map[string]TopicDeliveryMetrics{}
+	m.topics.Range(func(key, value any) bool {
+		t := value.(*topicDeliveryMetrics)
+		s := TopicDeliveryMetrics{
+			Delivered:      t.delivered.Load(),
+			Acked:          t.acked.Load(),
+			Retried:        t.retried.Load(),
+			Nacked:         t.nacked.Load(),
+			DeadLettered:   t.deadLettered.Load(),
+			LatencyBuckets: m.buckets,
+			LatencyCounts:  make([]int64, len(t.latencyCounts)),
+			LatencySum:     time.Duration(t.latencySum.Load()),
+		}
+		for i := range t.latencyCounts {
+			s.LatencyCounts[i] = t.latencyCounts[i].Load()
+		}
+		res[key.(string)] = s
+		return true
+	})
+	return res
+}
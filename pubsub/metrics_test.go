@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartDeliveryWithNilRecorder(t *testing.T) {
+	done := StartDelivery(nil, "topic")
+	require.NotNil(t, done)
+	assert.NotPanics(t, func() { done(DeliveryAcked) })
+}
+
+func TestDeliveryMetrics(t *testing.T) {
+	t.Run("counts outcomes and latencies per topic", func(t *testing.T) {
+		m := NewDeliveryMetrics(100*time.Millisecond, 10*time.Millisecond)
+		m.RecordDelivered("a")
+		m.RecordOutcome("a", DeliveryAcked, 5*time.Millisecond)
+		m.RecordDelivered("a")
+		m.RecordOutcome("a", DeliveryRetried, 50*time.Millisecond)
+		m.RecordDelivered("a")
+		m.RecordOutcome("a", DeliveryDeadLettered, time.Second)
+		m.RecordDelivered("b")
+		m.RecordOutcome("b", DeliveryNacked, 10*time.Millisecond)
+
+		s := m.Snapshot()
+		require.Len(t, s, 2)
+		assert.Equal(t, TopicDeliveryMetrics{
+			Delivered:      3,
+			Acked:          1,
+			Retried:        1,
+			DeadLettered:   1,
+			LatencyBuckets: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond},
+			LatencyCounts:  []int64{1, 1, 1},
+			LatencySum:     1055 * time.Millisecond,
+		}, s["a"])
+		assert.Equal(t, int64(1), s["b"].Nacked)
+		assert.Equal(t, []int64{1, 0, 0}, s["b"].LatencyCounts)
+	})
+
+	t.Run("concurrent deliveries", func(t *testing.T) {
+		m := NewDeliveryMetrics()
+		const workers, messages = 8, 500
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < messages; j++ {
+					done := StartDelivery(m, "topic")
+					if j%2 == 0 {
+						done(DeliveryAcked)
+					} else {
+						done(DeliveryRetried)
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		s := m.Snapshot()["topic"]
+		assert.Equal(t, int64(workers*messages), s.Delivered)
+		assert.Equal(t, int64(workers*messages/2), s.Acked)
+		assert.Equal(t, int64(workers*messages/2), s.Retried)
+		var total int64
+		for _, c := range s.LatencyCounts {
+			total += c
+		}
+		assert.Equal(t, int64(workers*messages), total)
+	})
+}
@@ -17,7 +17,9 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/dapr/components-contrib/crypto"
 	"github.com/dapr/components-contrib/health"
+	"github.com/dapr/components-contrib/state"
 )
 
 // PubSub is the interface for message buses.
@@ -47,6 +49,41 @@ type BulkSubscriber interface {
 	BulkSubscribe(ctx context.Context, req SubscribeRequest, bulkHandler BulkHandler) error
 }
 
+// IdempotencyStoreSetter is implemented by components that can skip the delivery of messages that were already processed.
+// When the "idempotencyStore" metadata property is set, the runtime passes the state store with that name to the component after Init and before subscribing.
+// The state store is used to record the IDs of processed messages, with a TTL set by the "idempotencyTTL" metadata property.
+type IdempotencyStoreSetter interface {
+	SetIdempotencyStore(store state.Store) error
+}
+
+// ClaimCheckStoreSetter is implemented by components that can offload large payloads using the claim-check pattern.
+// When the "claimCheckStore" metadata property is set, the runtime passes the state store with that name to the component after Init and before publishing or subscribing.
+// Payloads larger than the "claimCheckThreshold" metadata property are stored in the state store, and the messages carry a reference to them.
+type ClaimCheckStoreSetter interface {
+	SetClaimCheckStore(store state.Store) error
+}
+
+// DeadLetterStoreSetter is implemented by components that can save poison messages to a state store.
+// When the "deadLetterStore" metadata property is set, the runtime passes the state store with that name to the component after Init and before subscribing.
+// Messages that can't be processed after all retries are saved in the state store with their topic, ID, payload and error, and are then acknowledged.
+type DeadLetterStoreSetter interface {
+	SetDeadLetterStore(store state.Store) error
+}
+
+// CryptoProviderSetter is implemented by components that can encrypt payloads end-to-end with a crypto component.
+// When the "cryptoComponent" metadata property is set, the runtime passes the crypto component with that name to the component after Init and before publishing or subscribing.
+// Payloads are encrypted with the "cryptoKeyName" key when published, and decrypted before they're delivered; messages that can't be decrypted are never delivered.
+type CryptoProviderSetter interface {
+	SetCryptoProvider(provider crypto.SubtleCrypto) error
+}
+
+// Seeker is implemented by components whose subscriptions can be moved to another position in the stream at runtime, for example to recover from an incident.
+// After seeking, the messages after the position are delivered again, including those that were already processed, so handlers must be idempotent.
+// Messages being processed when the subscription is moved may also be delivered twice.
+type Seeker interface {
+	Seek(ctx context.Context, req *SeekRequest) error
+}
+
 // Handler is the handler used to invoke the app handler.
 type Handler func(ctx context.Context, msg *NewMessage) error
 
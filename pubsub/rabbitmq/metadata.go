@@ -22,6 +22,7 @@ import (
 
 	"github.com/dapr/kit/logger"
 
+	"github.com/dapr/components-contrib/internal/component/idempotency"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 )
@@ -50,6 +51,17 @@ type rabbitmqMetadata struct {
 	SaslExternal         bool                   `mapstructure:"saslExternal"`
 	Concurrency          pubsub.ConcurrencyMode `mapstructure:"concurrency"`
 	DefaultQueueTTL      *time.Duration         `mapstructure:"ttlInSeconds"`
+	MessageTTL           time.Duration          `mapstructure:"messageTtl"`
+	DeadLetterExchange   string                 `mapstructure:"deadLetterExchange"`
+	DeadLetterRoutingKey string                 `mapstructure:"deadLetterRoutingKey"`
+	// JSON path of the field of the message (such as "data.region" in a CloudEvent) whose value is the routing key
+	RoutingKeyPath string   `mapstructure:"routingKeyPath"`
+	routingKeyPath []string `mapstructure:"-"`
+	// Routing key of messages without the "routingKey" metadata, whose content has no field at RoutingKeyPath
+	DefaultRoutingKey string `mapstructure:"defaultRoutingKey"`
+
+	idempotency.Metadata   `mapstructure:",squash"`
+	pubsub.ErrorClassifier `mapstructure:",squash"`
 }
 
 const (
@@ -77,6 +89,10 @@ const (
 	metadataPublisherConfirmKey     = "publisherConfirm"
 	metadataSaslExternal            = "saslExternal"
 	metadataMaxPriority             = "maxPriority"
+	metadataMessageTTLKey           = "messageTtl"
+	metadataDeadLetterExchangeKey   = "deadLetterExchange"
+	metadataDeadLetterRoutingKey    = "deadLetterRoutingKey"
+	metadataRoutingKeyPathKey       = "routingKeyPath"
 
 	defaultReconnectWaitSeconds = 3
 
@@ -143,6 +159,19 @@ func createMetadata(pubSubMetadata pubsub.Metadata, log logger.Logger) (*rabbitm
 		result.DefaultQueueTTL = &ttl
 	}
 
+	if result.MessageTTL < 0 {
+		return &result, fmt.Errorf("%s invalid %s: must not be negative", errorMessagePrefix, metadataMessageTTLKey)
+	}
+
+	if result.DeadLetterRoutingKey != "" && result.DeadLetterExchange == "" {
+		return &result, fmt.Errorf("%s %s requires %s to be set", errorMessagePrefix, metadataDeadLetterRoutingKey, metadataDeadLetterExchangeKey)
+	}
+
+	result.routingKeyPath, err = parseRoutingKeyPath(result.RoutingKeyPath)
+	if err != nil {
+		return &result, fmt.Errorf("%s invalid %s: %w", errorMessagePrefix, metadataRoutingKeyPathKey, err)
+	}
+
 	result.TLSProperties, err = pubsub.TLS(pubSubMetadata.Properties)
 	if err != nil {
 		return &result, fmt.Errorf("%s invalid TLS configuration: %w", errorMessagePrefix, err)
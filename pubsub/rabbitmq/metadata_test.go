@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/assert"
@@ -413,6 +414,56 @@ func TestCreateMetadata(t *testing.T) {
 		// assert
 		assert.Error(t, err)
 	})
+
+	t.Run("message ttl and dead letter exchange", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataMessageTTLKey] = "90"
+		fakeMetaData.Properties[metadataDeadLetterExchangeKey] = "expired"
+		fakeMetaData.Properties[metadataDeadLetterRoutingKey] = "mykey"
+
+		// act
+		m, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 90*time.Second, m.MessageTTL)
+		assert.Equal(t, "expired", m.DeadLetterExchange)
+		assert.Equal(t, "mykey", m.DeadLetterRoutingKey)
+	})
+
+	t.Run("message ttl is invalid", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataMessageTTLKey] = "-1m"
+
+		// act
+		_, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.Error(t, err)
+	})
+
+	t.Run("dead letter routing key without exchange", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataDeadLetterRoutingKey] = "mykey"
+
+		// act
+		_, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.Error(t, err)
+	})
 }
 
 func TestConnectionURI(t *testing.T) {
@@ -26,17 +26,28 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 
+	"github.com/dapr/components-contrib/internal/component/idempotency"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/tracing"
 	"github.com/dapr/kit/logger"
 )
 
+var (
+	_ pubsub.IdempotencyStoreSetter = (*rabbitMQ)(nil)
+	_ pubsub.DeliveryMetricsSetter  = (*rabbitMQ)(nil)
+	_ tracing.TracerSetter          = (*rabbitMQ)(nil)
+)
+
 const (
 	fanoutExchangeKind              = "fanout"
 	logMessagePrefix                = "rabbitmq pub/sub:"
 	errorMessagePrefix              = "rabbitmq pub/sub error:"
+	tracingSystem                   = "rabbitmq"
 	errorChannelNotInitialized      = "channel not initialized"
 	errorChannelConnection          = "channel/connection is not open"
 	defaultDeadLetterExchangeFormat = "dlx-%s"
@@ -49,9 +60,13 @@ const (
 	argMaxLength          = "x-max-length"
 	argMaxLengthBytes     = "x-max-length-bytes"
 	argDeadLetterExchange = "x-dead-letter-exchange"
+	argDeadLetterRouting  = "x-dead-letter-routing-key"
+	argMessageTTL         = "x-message-ttl"
 	argMaxPriority        = "x-max-priority"
 	queueModeLazy         = "lazy"
 	reqMetadataRoutingKey = "routingKey"
+	// Request metadata key for the ID of a published message. If not set, a random ID is generated.
+	reqMetadataMessageIDKey = "messageId"
 )
 
 // RabbitMQ allows sending/receiving messages in pub/sub format.
@@ -68,6 +83,10 @@ type rabbitMQ struct {
 	closed         atomic.Bool
 	wg             sync.WaitGroup
 
+	idempotency idempotency.Holder
+	metrics     pubsub.DeliveryMetricsRecorder
+	tracer      tracing.Tracer
+
 	logger logger.Logger
 }
 
@@ -141,6 +160,7 @@ func (r *rabbitMQ) Init(_ context.Context, metadata pubsub.Metadata) error {
 	}
 
 	r.metadata = meta
+	r.idempotency.Init(meta.Metadata, "rabbitmq||"+meta.ConsumerID)
 
 	r.reconnect(0)
 	// We do not return error on reconnect because it can cause problems if init() happens
@@ -212,10 +232,7 @@ func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest)
 
 		return r.channel, r.connectionCount, err
 	}
-	routingKey := ""
-	if val, ok := req.Metadata[reqMetadataRoutingKey]; ok && val != "" {
-		routingKey = val
-	}
+	routingKey := r.routingKey(req.Data, req.Metadata)
 
 	ttl, ok, err := metadata.TryGetTTL(req.Metadata)
 	if err != nil {
@@ -229,7 +246,13 @@ func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest)
 		expiration = strconv.FormatInt(r.metadata.DefaultQueueTTL.Milliseconds(), 10)
 	}
 
+	messageID := req.Metadata[reqMetadataMessageIDKey]
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+
 	p := amqp.Publishing{
+		MessageId:    messageID,
 		ContentType:  "text/plain",
 		Body:         req.Data,
 		DeliveryMode: r.metadata.DeliveryMode,
@@ -245,6 +268,12 @@ func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest)
 		p.Priority = priority
 	}
 
+	// Only the trace context is sent as headers
+	if sc, ok := tracing.Extract(tracing.MapCarrier(req.Metadata)); ok {
+		p.Headers = amqp.Table{}
+		tracing.Inject(amqpHeaderCarrier(p.Headers), sc)
+	}
+
 	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(ctx, req.Topic, routingKey, false, false, p)
 	if err != nil {
 		r.logger.Errorf("%s publishing to %s failed in channel.Publish: %v", logMessagePrefix, req.Topic, err)
@@ -265,17 +294,29 @@ func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest)
 	return r.channel, r.connectionCount, nil
 }
 
-func (r *rabbitMQ) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+func (r *rabbitMQ) Publish(ctx context.Context, req *pubsub.PublishRequest) (err error) {
 	if r.closed.Load() {
 		return errors.New("component is closed")
 	}
 
 	r.logger.Debugf("%s publishing message to %s", logMessagePrefix, req.Topic)
 
+	span, md := tracing.StartProducerSpan(ctx, r.tracer, tracingSystem, req.Topic, req.Metadata)
+	defer func() {
+		span.End(err)
+	}()
+	traced := *req
+	traced.Metadata = md
+	req = &traced
+
 	attempt := 0
 	for {
 		attempt++
-		channel, connectionCount, err := r.publishSync(ctx, req)
+		var (
+			channel         rabbitMQChannelBroker
+			connectionCount int
+		)
+		channel, connectionCount, err = r.publishSync(ctx, req)
 		if err == nil {
 			return nil
 		}
@@ -312,17 +353,22 @@ func (r *rabbitMQ) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, h
 		return errors.New("consumerID is required for subscriptions")
 	}
 
+	if _, err := r.idempotency.Get(); err != nil {
+		return err
+	}
+
 	queueName := fmt.Sprintf("%s-%s", r.metadata.ConsumerID, req.Topic)
 	r.logger.Infof("%s subscribe to topic/queue '%s/%s'", logMessagePrefix, req.Topic, queueName)
 
 	// Do not set a timeout on the context, as we're just waiting for the first ack; we're using a semaphore instead
-	ackCh := make(chan struct{}, 1)
+	ackCh := make(chan error, 1)
 	defer close(ackCh)
 
 	subctx, cancel := context.WithCancel(ctx)
 	r.wg.Add(2)
 	go func() {
 		defer r.wg.Done()
+		defer cancel()
 		r.subscribeForever(subctx, req, queueName, handler, ackCh)
 	}()
 	go func() {
@@ -338,8 +384,8 @@ func (r *rabbitMQ) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, h
 	select {
 	case <-time.After(time.Minute):
 		return fmt.Errorf("failed to subscribe to %s", queueName)
-	case <-ackCh:
-		return nil
+	case err := <-ackCh:
+		return err
 	}
 }
 
@@ -354,7 +400,13 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 
 	r.logger.Infof("%s declaring queue '%s'", logMessagePrefix, queueName)
 	var args amqp.Table
-	if r.metadata.EnableDeadLetter {
+	if r.metadata.DeadLetterExchange != "" {
+		// Use the dead letter exchange configured by the user, which must already exist
+		args = amqp.Table{argDeadLetterExchange: r.metadata.DeadLetterExchange}
+		if r.metadata.DeadLetterRoutingKey != "" {
+			args[argDeadLetterRouting] = r.metadata.DeadLetterRoutingKey
+		}
+	} else if r.metadata.EnableDeadLetter {
 		// declare dead letter exchange
 		dlxName := fmt.Sprintf(defaultDeadLetterExchangeFormat, queueName)
 		dlqName := fmt.Sprintf(defaultDeadLetterQueueFormat, queueName)
@@ -370,6 +422,7 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 		dlqArgs[argQueueMode] = queueModeLazy
 		q, err = channel.QueueDeclare(dlqName, true, r.metadata.DeleteWhenUnused, false, false, dlqArgs)
 		if err != nil {
+			err = wrapQueueDeclareError(dlqName, err)
 			r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in channel.QueueDeclare: %v", logMessagePrefix, req.Topic, dlqName, err)
 
 			return nil, err
@@ -384,6 +437,10 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 		args = amqp.Table{argDeadLetterExchange: dlxName}
 	}
 	args = r.metadata.formatQueueDeclareArgs(args)
+	// The TTL is not applied to the dead letter queue, or expired messages would be dropped from it too
+	if r.metadata.MessageTTL > 0 {
+		args[argMessageTTL] = r.metadata.MessageTTL.Milliseconds()
+	}
 
 	// use priority queue if configured on subscription
 	if val, ok := req.Metadata[metadataMaxPriority]; ok && val != "" {
@@ -403,6 +460,7 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 
 	q, err := channel.QueueDeclare(queueName, r.metadata.Durable, r.metadata.DeleteWhenUnused, false, false, args)
 	if err != nil {
+		err = wrapQueueDeclareError(queueName, err)
 		r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in channel.QueueDeclare: %v", logMessagePrefix, req.Topic, queueName, err)
 
 		return nil, err
@@ -450,7 +508,7 @@ func (r *rabbitMQ) ensureSubscription(req pubsub.SubscribeRequest, queueName str
 	return r.channel, r.connectionCount, q, err
 }
 
-func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeRequest, queueName string, handler pubsub.Handler, ackCh chan struct{}) {
+func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeRequest, queueName string, handler pubsub.Handler, ackCh chan error) {
 	for {
 		var (
 			err             error
@@ -463,6 +521,11 @@ func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeReq
 		for {
 			channel, connectionCount, q, err = r.ensureSubscription(req, queueName)
 			if err != nil {
+				// If the queue exists with different arguments, retrying won't help: report it to the caller of Subscribe
+				if ackCh != nil && errors.Is(err, errQueueArgumentsMismatch) {
+					ackCh <- err
+					return
+				}
 				errFuncName = "ensureSubscription"
 				break
 			}
@@ -483,7 +546,7 @@ func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeReq
 
 			// one-time notification on successful subscribe
 			if ackCh != nil {
-				ackCh <- struct{}{}
+				ackCh <- nil
 				ackCh = nil
 			}
 
@@ -561,29 +624,118 @@ func (r *rabbitMQ) handleMessage(ctx context.Context, d amqp.Delivery, topic str
 		Topic: topic,
 	}
 
-	err := handler(ctx, pubsubMsg)
+	// Messages without an ID can't be checked for duplicates
+	store, err := r.idempotency.Get()
+	if err == nil && store != nil && d.MessageId != "" {
+		var processed bool
+		processed, err = store.IsProcessed(ctx, topic+"/"+d.MessageId)
+		if err == nil && processed {
+			r.logger.Debugf("%s skipping message '%s' from topic '%s' that was already processed", logMessagePrefix, d.MessageId, topic)
+			if !r.metadata.AutoAck {
+				if err = d.Ack(false); err != nil {
+					r.logger.Errorf("%s error acking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, err)
+				}
+			}
+			return err
+		}
+	}
+	if err != nil {
+		// The message could not be checked for duplicates, so it's requeued to be retried
+		r.logger.Errorf("%s handling message from topic '%s', %s", errorMessagePrefix, topic, err)
+		if !r.metadata.AutoAck {
+			if nackErr := d.Nack(false, true); nackErr != nil {
+				r.logger.Errorf("%s error nacking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, nackErr)
+			}
+		}
+		return err
+	}
+
+	if sc, ok := tracing.Extract(amqpHeaderCarrier(d.Headers)); ok {
+		pubsubMsg.Metadata = map[string]string{}
+		tracing.Inject(tracing.MapCarrier(pubsubMsg.Metadata), sc)
+	}
+	handlerCtx, span := tracing.StartConsumerSpan(ctx, r.tracer, tracingSystem, topic, pubsubMsg.Metadata)
+	done := pubsub.StartDelivery(r.metrics, topic)
+	err = handler(handlerCtx, pubsubMsg)
+	span.End(err)
 
 	if err != nil {
 		r.logger.Errorf("%s handling message from topic '%s', %s", errorMessagePrefix, topic, err)
+		done(r.failedDeliveryOutcome(err))
 
 		if !r.metadata.AutoAck {
 			// if message is not auto acked we need to ack/nack
-			r.logger.Debugf("%s nacking message '%s' from topic '%s', requeue=%t", logMessagePrefix, d.MessageId, topic, r.metadata.RequeueInFailure)
-			if err = d.Nack(false, r.metadata.RequeueInFailure); err != nil {
+			// Messages that failed permanently are not requeued, so they're dead-lettered right away if the queue has a dead-letter exchange
+			requeue := r.metadata.RequeueInFailure && !r.metadata.ErrorClassifier.IsPermanent(err)
+			r.logger.Debugf("%s nacking message '%s' from topic '%s', requeue=%t", logMessagePrefix, d.MessageId, topic, requeue)
+			if err = d.Nack(false, requeue); err != nil {
 				r.logger.Errorf("%s error nacking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, err)
 			}
 		}
-	} else if !r.metadata.AutoAck {
+		return err
+	}
+
+	// The message must be recorded as processed before it's acked, so if we crash in between, it's skipped when redelivered rather than lost
+	if store != nil && d.MessageId != "" {
+		if markErr := store.MarkProcessed(ctx, topic+"/"+d.MessageId); markErr != nil {
+			r.logger.Warnf("%s message '%s' from topic '%s' may be processed again if redelivered: %v", logMessagePrefix, d.MessageId, topic, markErr)
+		}
+	}
+
+	if !r.metadata.AutoAck {
 		// if message is not auto acked we need to ack/nack
 		r.logger.Debugf("%s acking message '%s' from topic '%s'", logMessagePrefix, d.MessageId, topic)
 		if err = d.Ack(false); err != nil {
 			r.logger.Errorf("%s error acking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, err)
 		}
 	}
+	done(pubsub.DeliveryAcked)
 
 	return err
 }
 
+// failedDeliveryOutcome returns what happens to a message that the handler failed to process with err.
+func (r *rabbitMQ) failedDeliveryOutcome(err error) pubsub.DeliveryOutcome {
+	switch {
+	case r.metadata.AutoAck:
+		// The message was already acked when it was delivered
+		return pubsub.DeliveryNacked
+	case r.metadata.RequeueInFailure && !r.metadata.ErrorClassifier.IsPermanent(err):
+		return pubsub.DeliveryRetried
+	case r.metadata.DeadLetterExchange != "" || r.metadata.EnableDeadLetter:
+		return pubsub.DeliveryDeadLettered
+	default:
+		return pubsub.DeliveryNacked
+	}
+}
+
+// SetDeliveryMetricsRecorder sets the recorder of metrics about the delivery of messages.
+func (r *rabbitMQ) SetDeliveryMetricsRecorder(recorder pubsub.DeliveryMetricsRecorder) {
+	r.metrics = recorder
+}
+
+// SetTracer sets the tracer used to create spans for published and consumed messages.
+func (r *rabbitMQ) SetTracer(tracer tracing.Tracer) {
+	r.tracer = tracer
+}
+
+// amqpHeaderCarrier is a tracing.Carrier backed by the headers of an AMQP message.
+type amqpHeaderCarrier amqp.Table
+
+func (c amqpHeaderCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c amqpHeaderCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+// SetIdempotencyStore sets the state store used to record the IDs of processed messages, so duplicate deliveries are skipped.
+func (r *rabbitMQ) SetIdempotencyStore(store state.Store) error {
+	return r.idempotency.SetIdempotencyStore(store)
+}
+
 // this function call should be wrapped by channelMutex.
 func (r *rabbitMQ) ensureExchangeDeclared(channel rabbitMQChannelBroker, exchange, exchangeKind string) error {
 	if !r.containsExchange(exchange) {
@@ -660,6 +812,19 @@ func (r *rabbitMQ) Features() []pubsub.Feature {
 	return []pubsub.Feature{pubsub.FeatureMessageTTL}
 }
 
+// errQueueArgumentsMismatch is returned when a queue already exists with different arguments than those configured.
+var errQueueArgumentsMismatch = errors.New("queue already exists with different arguments")
+
+// wrapQueueDeclareError returns errQueueArgumentsMismatch if the broker rejected the declaration of a queue because its arguments changed.
+// RabbitMQ doesn't allow changing arguments such as the TTL or the dead letter exchange of an existing queue.
+func wrapQueueDeclareError(queueName string, err error) error {
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+		return fmt.Errorf("%s %w: queue '%s' must be deleted, or the previous '%s', dead letter, and length limit settings restored: %w", errorMessagePrefix, errQueueArgumentsMismatch, queueName, metadataMessageTTLKey, err)
+	}
+	return err
+}
+
 func mustReconnect(channel rabbitMQChannelBroker, err error) bool {
 	if channel == nil {
 		return true
@@ -17,6 +17,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -27,6 +28,9 @@ import (
 
 	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/components-contrib/tracing"
 	"github.com/dapr/kit/logger"
 )
 
@@ -194,6 +198,60 @@ func TestPublishAndSubscribe(t *testing.T) {
 	assert.Equal(t, "foo bar", lastMessage)
 }
 
+func TestSubscribeIdempotency(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:   "anyhost",
+			metadataConsumerIDKey: "consumer",
+			"idempotencyStore":    "statestore",
+			pubsub.ConcurrencyKey: string(pubsub.Single),
+		},
+	}}
+	err := pubsubRabbitMQ.Init(context.Background(), metadata)
+	require.NoError(t, err)
+
+	topic := "mytopic"
+	received := make(chan string, 10)
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		received <- string(msg.Data)
+		return nil
+	}
+
+	// Subscribing fails until the idempotency store is set
+	err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler)
+	require.ErrorContains(t, err, "idempotency store 'statestore' is configured, but it was not set")
+
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	require.NoError(t, pubsubRabbitMQ.(pubsub.IdempotencyStoreSetter).SetIdempotencyStore(stateStore))
+
+	err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler)
+	require.NoError(t, err)
+
+	for _, data := range []string{"first", "duplicate", "second"} {
+		messageID := "1"
+		if data == "second" {
+			messageID = "2"
+		}
+		err = pubsubRabbitMQ.Publish(context.Background(), &pubsub.PublishRequest{
+			Topic:    topic,
+			Data:     []byte(data),
+			Metadata: map[string]string{"messageId": messageID},
+		})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "first", <-received)
+	assert.Equal(t, "second", <-received)
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message: %s", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestPublishReconnect(t *testing.T) {
 	broker := newBroker()
 	pubsubRabbitMQ := newRabbitMQTest(broker)
@@ -319,6 +377,87 @@ func TestSubscribeBindRoutingKeys(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestSubscribeMessageTTL(t *testing.T) {
+	t.Run("queue arguments", func(t *testing.T) {
+		broker := newBroker()
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		metadata := pubsub.Metadata{Base: mdata.Base{
+			Properties: map[string]string{
+				metadataHostnameKey:           "anyhost",
+				metadataConsumerIDKey:         "consumer",
+				metadataMessageTTLKey:         "1m",
+				metadataDeadLetterExchangeKey: "expired",
+				metadataDeadLetterRoutingKey:  "mykey",
+			},
+		}}
+		err := pubsubRabbitMQ.Init(context.Background(), metadata)
+		require.NoError(t, err)
+		defer pubsubRabbitMQ.Close()
+
+		err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "mytopic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, amqp.Table{
+			argMessageTTL:         int64(60000),
+			argDeadLetterExchange: "expired",
+			argDeadLetterRouting:  "mykey",
+		}, broker.queueArgs("consumer-mytopic"))
+	})
+
+	t.Run("ttl is not applied to the dead letter queue", func(t *testing.T) {
+		broker := newBroker()
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		metadata := pubsub.Metadata{Base: mdata.Base{
+			Properties: map[string]string{
+				metadataHostnameKey:         "anyhost",
+				metadataConsumerIDKey:       "consumer",
+				metadataMessageTTLKey:       "30",
+				metadataEnableDeadLetterKey: "true",
+			},
+		}}
+		err := pubsubRabbitMQ.Init(context.Background(), metadata)
+		require.NoError(t, err)
+		defer pubsubRabbitMQ.Close()
+
+		err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "mytopic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(30000), broker.queueArgs("consumer-mytopic")[argMessageTTL])
+		assert.Equal(t, "dlx-consumer-mytopic", broker.queueArgs("consumer-mytopic")[argDeadLetterExchange])
+		assert.NotContains(t, broker.queueArgs("dlq-consumer-mytopic"), argMessageTTL)
+	})
+
+	t.Run("changed queue arguments are reported", func(t *testing.T) {
+		broker := newBroker()
+		broker.queueDeclareErr = &amqp.Error{
+			Code:   amqp.PreconditionFailed,
+			Reason: "PRECONDITION_FAILED - inequivalent arg 'x-message-ttl' for queue 'consumer-mytopic'",
+		}
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		metadata := pubsub.Metadata{Base: mdata.Base{
+			Properties: map[string]string{
+				metadataHostnameKey:   "anyhost",
+				metadataConsumerIDKey: "consumer",
+				metadataMessageTTLKey: "1m",
+			},
+		}}
+		err := pubsubRabbitMQ.Init(context.Background(), metadata)
+		require.NoError(t, err)
+		defer pubsubRabbitMQ.Close()
+
+		err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "mytopic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			return nil
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errQueueArgumentsMismatch)
+		assert.Contains(t, err.Error(), "consumer-mytopic")
+	})
+}
+
 func TestSubscribeReconnect(t *testing.T) {
 	broker := newBroker()
 	pubsubRabbitMQ := newRabbitMQTest(broker)
@@ -389,6 +528,12 @@ type rabbitMQInMemoryBroker struct {
 
 	connectCount atomic.Int32
 	closeCount   atomic.Int32
+
+	// Arguments of the declared queues
+	queuesLock     sync.Mutex
+	declaredQueues map[string]amqp.Table
+	// If set, QueueDeclare returns this error
+	queueDeclareErr error
 }
 
 func (r *rabbitMQInMemoryBroker) Qos(prefetchCount, prefetchSize int, global bool) error {
@@ -406,15 +551,35 @@ func (r *rabbitMQInMemoryBroker) PublishWithDeferredConfirmWithContext(ctx conte
 		return nil, errors.New(errorChannelConnection)
 	}
 
-	r.buffer <- createAMQPMessage(msg.Body)
+	d := createAMQPMessage(msg.Body)
+	d.MessageId = msg.MessageId
+	d.Headers = msg.Headers
+	r.buffer <- d
 
 	return nil, nil
 }
 
 func (r *rabbitMQInMemoryBroker) QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	if r.queueDeclareErr != nil {
+		return amqp.Queue{}, r.queueDeclareErr
+	}
+
+	r.queuesLock.Lock()
+	defer r.queuesLock.Unlock()
+	if r.declaredQueues == nil {
+		r.declaredQueues = map[string]amqp.Table{}
+	}
+	r.declaredQueues[name] = args
+
 	return amqp.Queue{Name: name}, nil
 }
 
+func (r *rabbitMQInMemoryBroker) queueArgs(name string) amqp.Table {
+	r.queuesLock.Lock()
+	defer r.queuesLock.Unlock()
+	return r.declaredQueues[name]
+}
+
 func (r *rabbitMQInMemoryBroker) QueueBind(name string, key string, exchange string, noWait bool, args amqp.Table) error {
 	return nil
 }
@@ -448,3 +613,160 @@ func (r *rabbitMQInMemoryBroker) Close() error {
 func (r *rabbitMQInMemoryBroker) IsClosed() bool {
 	return r.connectCount.Load() <= r.closeCount.Load()
 }
+
+func TestSubscribeDeliveryMetrics(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:   "anyhost",
+			metadataConsumerIDKey: "consumer",
+			pubsub.ConcurrencyKey: string(pubsub.Single),
+		},
+	}}
+	err := pubsubRabbitMQ.Init(context.Background(), metadata)
+	require.NoError(t, err)
+
+	metrics := pubsub.NewDeliveryMetrics()
+	pubsubRabbitMQ.(pubsub.DeliveryMetricsSetter).SetDeliveryMetricsRecorder(metrics)
+
+	topic := "mytopic"
+	processed := make(chan struct{}, 10)
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		defer func() { processed <- struct{}{} }()
+		if string(msg.Data) == "fail" {
+			return errors.New("handler failed")
+		}
+		return nil
+	}
+	err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler)
+	require.NoError(t, err)
+
+	for _, data := range []string{"ok", "fail", "ok"} {
+		err = pubsubRabbitMQ.Publish(context.Background(), &pubsub.PublishRequest{Topic: topic, Data: []byte(data)})
+		require.NoError(t, err)
+		<-processed
+	}
+
+	assert.Eventually(t, func() bool {
+		s := metrics.Snapshot()[topic]
+		return s.Delivered == 3 && s.Acked == 2 && s.Nacked == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTracing(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:   "anyhost",
+			metadataConsumerIDKey: "consumer",
+			pubsub.ConcurrencyKey: string(pubsub.Single),
+		},
+	}}
+	err := pubsubRabbitMQ.Init(context.Background(), metadata)
+	require.NoError(t, err)
+
+	tracer := &tracing.RecordingTracer{}
+	pubsubRabbitMQ.(tracing.TracerSetter).SetTracer(tracer)
+
+	topic := "mytopic"
+	received := make(chan *pubsub.NewMessage, 1)
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		sc, ok := tracing.SpanContextFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, sc.Traceparent(), msg.Metadata[tracing.TraceparentKey])
+		received <- msg
+		return nil
+	}
+	err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler)
+	require.NoError(t, err)
+
+	err = pubsubRabbitMQ.Publish(context.Background(), &pubsub.PublishRequest{
+		Topic: topic,
+		Data:  []byte("hello"),
+		Metadata: map[string]string{
+			tracing.TraceparentKey: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	})
+	require.NoError(t, err)
+	msg := <-received
+
+	assert.Eventually(t, func() bool {
+		spans := tracer.Spans()
+		return len(spans) == 2 && spans[1].Ended
+	}, time.Second, 10*time.Millisecond)
+	spans := tracer.Spans()
+	producer, consumer := spans[0], spans[1]
+	assert.Equal(t, tracing.SpanKindProducer, producer.Kind)
+	assert.Equal(t, "rabbitmq", producer.Attributes[tracing.AttributeMessagingSystem])
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", producer.Parent.Traceparent())
+
+	// The consumer span is a child of the producer span, and its context is passed to the handler
+	assert.Equal(t, tracing.SpanKindConsumer, consumer.Kind)
+	assert.Equal(t, tracing.OperationProcess, consumer.Attributes[tracing.AttributeMessagingOperation])
+	assert.Equal(t, producer.Context, consumer.Parent)
+	assert.Equal(t, consumer.Context.Traceparent(), msg.Metadata[tracing.TraceparentKey])
+}
+
+// fakeAcknowledger records how messages are acked and nacked.
+type fakeAcknowledger struct {
+	acked   int
+	requeue []bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.requeue = append(a.requeue, requeue)
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestSubscribePermanentErrors(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:         "anyhost",
+			metadataConsumerIDKey:       "consumer",
+			metadataRequeueInFailureKey: "true",
+			metadataEnableDeadLetterKey: "true",
+			"permanentErrorSubstrings":  "validation failed",
+		},
+	}}
+	err := pubsubRabbitMQ.Init(context.Background(), metadata)
+	require.NoError(t, err)
+
+	metrics := pubsub.NewDeliveryMetrics()
+	pubsubRabbitMQ.(pubsub.DeliveryMetricsSetter).SetDeliveryMetricsRecorder(metrics)
+
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		switch string(msg.Data) {
+		case "invalid":
+			return pubsub.NewPermanentError(errors.New("invalid order"))
+		case "unknown":
+			return errors.New("validation failed: unknown customer")
+		}
+		return errors.New("downstream unavailable")
+	}
+
+	ack := &fakeAcknowledger{}
+	for _, data := range []string{"invalid", "unknown", "transient"} {
+		d := createAMQPMessage([]byte(data))
+		d.Acknowledger = ack
+		_ = pubsubRabbitMQ.(*rabbitMQ).handleMessage(context.Background(), d, "mytopic", handler)
+	}
+
+	// Only the transient failure is requeued, the others are dead-lettered right away
+	assert.Equal(t, []bool{false, false, true}, ack.requeue)
+	s := metrics.Snapshot()["mytopic"]
+	assert.Equal(t, int64(2), s.DeadLettered)
+	assert.Equal(t, int64(1), s.Retried)
+}
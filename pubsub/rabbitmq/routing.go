@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rabbitmq
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parseRoutingKeyPath parses a JSON path in the dot notation, such as "$.data.region" or "data.region", into the names of the fields.
+func parseRoutingKeyPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$.")
+	if path == "" {
+		return nil, nil
+	}
+	fields := strings.Split(path, ".")
+	for _, f := range fields {
+		if f == "" {
+			return nil, fmt.Errorf("invalid JSON path '%s': field names must not be empty", path)
+		}
+	}
+	return fields, nil
+}
+
+// routingKeyFromContent returns the value of the field at path in the message, which is usually a CloudEvent, to be used as routing key.
+// It returns false if the message isn't a JSON object, or the field is missing or isn't a string, number or boolean.
+func routingKeyFromContent(data []byte, path []string) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var cur any
+	if dec.Decode(&cur) != nil {
+		return "", false
+	}
+	for _, field := range path {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		cur, ok = obj[field]
+		if !ok {
+			return "", false
+		}
+	}
+
+	switch v := cur.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		if v {
+			return "true", true
+		}
+		return "false", true
+	default:
+		return "", false
+	}
+}
+
+// routingKey returns the routing key of a message: the "routingKey" metadata of the request if set, or else the value of the field at "routingKeyPath" in the message, or else "defaultRoutingKey".
+func (r *rabbitMQ) routingKey(data []byte, reqMetadata map[string]string) string {
+	if val := reqMetadata[reqMetadataRoutingKey]; val != "" {
+		return val
+	}
+	if len(r.metadata.routingKeyPath) > 0 {
+		if key, ok := routingKeyFromContent(data, r.metadata.routingKeyPath); ok {
+			return key
+		}
+		r.logger.Debugf("%s field '%s' not found in the message, using the default routing key", logMessagePrefix, r.metadata.RoutingKeyPath)
+	}
+	return r.metadata.DefaultRoutingKey
+}
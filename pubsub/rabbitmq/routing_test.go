@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rabbitmq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mdata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseRoutingKeyPath(t *testing.T) {
+	path, err := parseRoutingKeyPath("$.data.region")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data", "region"}, path)
+
+	path, err = parseRoutingKeyPath("type")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"type"}, path)
+
+	path, err = parseRoutingKeyPath("")
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	for _, p := range []string{"data..region", ".data", "$.data."} {
+		_, err = parseRoutingKeyPath(p)
+		assert.Error(t, err, p)
+	}
+}
+
+func TestRoutingKeyFromContent(t *testing.T) {
+	event := []byte(`{"type":"order.created","data":{"region":"eu","shard":3,"priority":true,"tags":["a"],"none":null}}`)
+
+	tests := []struct {
+		path     []string
+		expected string
+		ok       bool
+	}{
+		{[]string{"type"}, "order.created", true},
+		{[]string{"data", "region"}, "eu", true},
+		{[]string{"data", "shard"}, "3", true},
+		{[]string{"data", "priority"}, "true", true},
+		{[]string{"data", "missing"}, "", false},
+		{[]string{"data", "tags"}, "", false},
+		{[]string{"data", "none"}, "", false},
+		{[]string{"type", "nested"}, "", false},
+		{[]string{"data"}, "", false},
+	}
+	for _, tt := range tests {
+		key, ok := routingKeyFromContent(event, tt.path)
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.expected, key, tt.path)
+	}
+
+	_, ok := routingKeyFromContent([]byte("not json"), []string{"type"})
+	assert.False(t, ok)
+}
+
+func TestRoutingKey(t *testing.T) {
+	props := getFakeProperties()
+	props["routingKeyPath"] = "$.data.region"
+	props["defaultRoutingKey"] = "default"
+	m, err := createMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}}, logger.NewLogger("test"))
+	require.NoError(t, err)
+	r := &rabbitMQ{metadata: m, logger: logger.NewLogger("test")}
+
+	event := []byte(`{"data":{"region":"eu"}}`)
+	// The metadata of the request has precedence
+	assert.Equal(t, "explicit", r.routingKey(event, map[string]string{reqMetadataRoutingKey: "explicit"}))
+	assert.Equal(t, "eu", r.routingKey(event, nil))
+	assert.Equal(t, "default", r.routingKey([]byte(`{"data":{}}`), nil))
+	assert.Equal(t, "default", r.routingKey([]byte("plain text"), nil))
+
+	props["routingKeyPath"] = "data..region"
+	_, err = createMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}}, logger.NewLogger("test"))
+	require.ErrorContains(t, err, "invalid routingKeyPath")
+}
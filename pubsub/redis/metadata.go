@@ -15,6 +15,8 @@ package redis
 
 import (
 	"time"
+
+	"github.com/dapr/components-contrib/internal/component/deadletter"
 )
 
 type metadata struct {
@@ -24,6 +26,14 @@ type metadata struct {
 	redeliverInterval time.Duration
 	// The amount time a message must be pending before attempting to redeliver it (0 disables redelivery)
 	processingTimeout time.Duration
+	// The amount of time a message must be idle in the pending entries list before it's claimed by this consumer; defaults to processingTimeout
+	claimTimeout time.Duration
+	// The number of deliveries after which a message that was not acknowledged is moved to deadLetterStream and/or saved to the dead-letter store (0 for unlimited)
+	maxDeliveries int64
+	// The stream that receives messages that exceeded maxDeliveries
+	deadLetterStream string
+	// The state store that messages that exceeded maxDeliveries are saved to
+	deadLetter deadletter.Metadata
 	// The size of the message queue for processing
 	queueDepth uint
 	// The number of concurrent workers that are processing messages
@@ -31,4 +41,13 @@ type metadata struct {
 
 	// the max len of stream
 	maxLenApprox int64
+
+	// The number of entries the stream is trimmed to, never removing entries not yet acknowledged by a consumer group (0 disables it)
+	maxLen int64
+	// The age after which entries are trimmed, never removing entries not yet acknowledged by a consumer group (0 disables it)
+	maxAge time.Duration
+	// The interval between trimming streams according to maxLen and maxAge
+	trimInterval time.Duration
+	// Entries added within this duration before the oldest entry not yet acknowledged by a consumer group are never trimmed
+	trimSafetyMargin time.Duration
 }
@@ -23,9 +23,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/dapr/components-contrib/internal/component/deadletter"
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
 
@@ -37,6 +40,25 @@ const (
 	queueDepth        = "queueDepth"
 	concurrency       = "concurrency"
 	maxLenApprox      = "maxLenApprox"
+	claimTimeout      = "claimTimeout"
+	maxDeliveries     = "maxDeliveries"
+	deadLetterStream  = "deadLetterStream"
+	deadLetterStore   = "deadLetterStore"
+	deadLetterTTL     = "deadLetterTTL"
+	maxLen            = "maxLen"
+	maxAge            = "maxAge"
+	trimInterval      = "trimInterval"
+	trimSafetyMargin  = "trimSafetyMargin"
+
+	// Fields added to messages moved to the dead-letter stream.
+	deadLetterOriginalStreamField = "originalStream"
+	deadLetterOriginalIDField     = "originalID"
+	deadLetterDeliveriesField     = "deliveries"
+)
+
+var (
+	_ pubsub.DeliveryMetricsSetter = (*redisStreams)(nil)
+	_ pubsub.DeadLetterStoreSetter = (*redisStreams)(nil)
 )
 
 // redisStreams handles consuming from a Redis stream using
@@ -45,6 +67,7 @@ const (
 //
 // See https://redis.io/topics/streams-intro for more information
 // on the mechanics of Redis Streams.
+
 type redisStreams struct {
 	metadata       metadata
 	client         rediscomponent.RedisClient
@@ -55,6 +78,13 @@ type redisStreams struct {
 	closeCh        chan struct{}
 
 	queue chan redisMessageWrapper
+
+	metrics pubsub.DeliveryMetricsRecorder
+
+	deadLetter deadletter.Holder
+
+	// Streams trimmed according to maxLen and maxAge
+	streams sync.Map
 }
 
 // redisMessageWrapper encapsulates the message identifier,
@@ -81,6 +111,7 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		redeliverInterval: 15 * time.Second,
 		queueDepth:        100,
 		concurrency:       10,
+		trimInterval:      time.Minute,
 	}
 
 	if val, ok := meta.Properties[consumerID]; ok && val != "" {
@@ -133,6 +164,66 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		m.maxLenApprox = maxLenApprox
 	}
 
+	m.claimTimeout = m.processingTimeout
+	if val, ok := meta.Properties[claimTimeout]; ok && val != "" {
+		if claimTimeoutMs, err := strconv.ParseUint(val, 10, 64); err == nil {
+			m.claimTimeout = time.Duration(claimTimeoutMs) * time.Millisecond
+		} else if d, err := time.ParseDuration(val); err == nil {
+			m.claimTimeout = d
+		} else {
+			return m, fmt.Errorf("redis streams error: can't parse claimTimeout field: %s", err)
+		}
+	}
+
+	if val, ok := meta.Properties[maxDeliveries]; ok && val != "" {
+		maxDeliveries, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return m, fmt.Errorf("redis streams error: can't parse maxDeliveries field: %s", err)
+		}
+		m.maxDeliveries = int64(maxDeliveries)
+	}
+
+	if val, ok := meta.Properties[maxLen]; ok && val != "" {
+		maxLen, err := strconv.ParseUint(val, 10, 63)
+		if err != nil {
+			return m, fmt.Errorf("redis streams error: can't parse maxLen field: %s", err)
+		}
+		m.maxLen = int64(maxLen)
+	}
+
+	for _, d := range []struct {
+		name   string
+		target *time.Duration
+	}{
+		{maxAge, &m.maxAge},
+		{trimInterval, &m.trimInterval},
+		{trimSafetyMargin, &m.trimSafetyMargin},
+		{deadLetterTTL, &m.deadLetter.DeadLetterTTL},
+	} {
+		val := meta.Properties[d.name]
+		if val == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(val)
+		if err != nil || parsed < 0 {
+			return m, fmt.Errorf("redis streams error: can't parse %s field: invalid duration '%s'", d.name, val)
+		}
+		*d.target = parsed
+	}
+	if m.trimInterval <= 0 {
+		return m, errors.New("redis streams error: trimInterval must be greater than 0")
+	}
+
+	m.deadLetterStream = meta.Properties[deadLetterStream]
+	m.deadLetter.DeadLetterStore = meta.Properties[deadLetterStore]
+	deadLettered := m.deadLetterStream != "" || m.deadLetter.Enabled()
+	if m.maxDeliveries > 0 && !deadLettered {
+		return m, errors.New("redis streams error: deadLetterStream or deadLetterStore is required when maxDeliveries is set")
+	}
+	if deadLettered && m.maxDeliveries == 0 {
+		return m, errors.New("redis streams error: maxDeliveries is required when deadLetterStream or deadLetterStore is set")
+	}
+
 	return m, nil
 }
 
@@ -142,13 +233,14 @@ func (r *redisStreams) Init(ctx context.Context, metadata pubsub.Metadata) error
 		return err
 	}
 	r.metadata = m
+	r.deadLetter.Init(m.deadLetter, "redis||"+m.consumerID)
 	r.client, r.clientSettings, err = rediscomponent.ParseClientFromProperties(metadata.Properties, nil)
 	if err != nil {
 		return err
 	}
 
 	if _, err = r.client.PingResult(ctx); err != nil {
-		return fmt.Errorf("redis streams: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+		return fmt.Errorf("redis streams: error connecting to redis at %s: %s", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
 	}
 	r.queue = make(chan redisMessageWrapper, int(r.metadata.queueDepth))
 
@@ -160,6 +252,20 @@ func (r *redisStreams) Init(ctx context.Context, metadata pubsub.Metadata) error
 		}()
 	}
 
+	if r.metadata.maxLen > 0 || r.metadata.maxAge > 0 {
+		trimCtx, cancel := context.WithCancel(context.Background())
+		r.wg.Add(2)
+		go func() {
+			defer r.wg.Done()
+			defer cancel()
+			<-r.closeCh
+		}()
+		go func() {
+			defer r.wg.Done()
+			r.trimLoop(trimCtx)
+		}()
+	}
+
 	return nil
 }
 
@@ -172,6 +278,7 @@ func (r *redisStreams) Publish(ctx context.Context, req *pubsub.PublishRequest)
 	if err != nil {
 		return fmt.Errorf("redis streams: error from publish: %s", err)
 	}
+	r.streams.Store(req.Topic, struct{}{})
 
 	return nil
 }
@@ -180,6 +287,9 @@ func (r *redisStreams) Subscribe(ctx context.Context, req pubsub.SubscribeReques
 	if r.closed.Load() {
 		return errors.New("component is closed")
 	}
+	if _, err := r.deadLetter.Get(); err != nil {
+		return err
+	}
 
 	err := r.client.XGroupCreateMkStream(ctx, req.Topic, r.metadata.consumerID, "0")
 	// Ignore BUSYGROUP errors
@@ -188,6 +298,8 @@ func (r *redisStreams) Subscribe(ctx context.Context, req pubsub.SubscribeReques
 		return err
 	}
 
+	r.streams.Store(req.Topic, struct{}{})
+
 	loopCtx, cancel := context.WithCancel(ctx)
 	r.wg.Add(3)
 	go func() {
@@ -281,8 +393,11 @@ func (r *redisStreams) processMessage(msg redisMessageWrapper) error {
 		ctx, cancel = context.WithTimeout(ctx, r.metadata.processingTimeout)
 		defer cancel()
 	}
+	done := pubsub.StartDelivery(r.metrics, msg.message.Topic)
 	if err := msg.handler(ctx, &msg.message); err != nil {
 		r.logger.Errorf("Error processing Redis message %s: %v", msg.messageID, err)
+		// The message remains pending, and it's reclaimed later
+		done(pubsub.DeliveryRetried)
 
 		return err
 	}
@@ -290,13 +405,25 @@ func (r *redisStreams) processMessage(msg redisMessageWrapper) error {
 	// Use the background context in case subscriptionCtx is already closed.
 	if err := r.client.XAck(context.Background(), msg.message.Topic, r.metadata.consumerID, msg.messageID); err != nil {
 		r.logger.Errorf("Error acknowledging Redis message %s: %v", msg.messageID, err)
+		done(pubsub.DeliveryRetried)
 
 		return err
 	}
+	done(pubsub.DeliveryAcked)
 
 	return nil
 }
 
+// SetDeadLetterStore sets the state store that messages are saved to when they exceed maxDeliveries.
+func (r *redisStreams) SetDeadLetterStore(store state.Store) error {
+	return r.deadLetter.SetDeadLetterStore(store)
+}
+
+// SetDeliveryMetricsRecorder sets the recorder of metrics about the delivery of messages.
+func (r *redisStreams) SetDeliveryMetricsRecorder(recorder pubsub.DeliveryMetricsRecorder) {
+	r.metrics = recorder
+}
+
 // pollMessagesLoop calls `XReadGroup` for new messages and funnels them to the message channel
 // by calling `enqueueMessages`.
 func (r *redisStreams) pollNewMessagesLoop(ctx context.Context, stream string, handler pubsub.Handler) {
@@ -325,9 +452,9 @@ func (r *redisStreams) pollNewMessagesLoop(ctx context.Context, stream string, h
 // reclaimPendingMessagesLoop periodically reclaims pending messages
 // based on the `redeliverInterval` setting.
 func (r *redisStreams) reclaimPendingMessagesLoop(ctx context.Context, stream string, handler pubsub.Handler) {
-	// Having a `processingTimeout` or `redeliverInterval` means that
+	// Having a `claimTimeout` (which defaults to `processingTimeout`) or `redeliverInterval` of 0 means that
 	// redelivery is disabled so we just return out of the goroutine.
-	if r.metadata.processingTimeout == 0 || r.metadata.redeliverInterval == 0 {
+	if r.metadata.claimTimeout == 0 || r.metadata.redeliverInterval == 0 {
 		return
 	}
 
@@ -365,14 +492,24 @@ func (r *redisStreams) reclaimPendingMessages(ctx context.Context, stream string
 			break
 		}
 
-		// Filter out messages that have not timed out yet
+		// Filter out messages that have not timed out yet, and set aside those that were delivered too many times
 		msgIDs := make([]string, 0, len(pendingResult))
+		var deadLetterIDs []string
 		for _, msg := range pendingResult {
-			if msg.Idle >= r.metadata.processingTimeout {
+			if msg.Idle < r.metadata.claimTimeout {
+				continue
+			}
+			if r.metadata.maxDeliveries > 0 && msg.RetryCount >= r.metadata.maxDeliveries {
+				deadLetterIDs = append(deadLetterIDs, msg.ID)
+			} else {
 				msgIDs = append(msgIDs, msg.ID)
 			}
 		}
 
+		if len(deadLetterIDs) > 0 {
+			r.deadLetterMessages(ctx, stream, deadLetterIDs)
+		}
+
 		// Nothing to claim
 		if len(msgIDs) == 0 {
 			break
@@ -383,7 +520,7 @@ func (r *redisStreams) reclaimPendingMessages(ctx context.Context, stream string
 			stream,
 			r.metadata.consumerID,
 			r.metadata.consumerID,
-			r.metadata.processingTimeout,
+			r.metadata.claimTimeout,
 			msgIDs,
 		)
 		if err != nil && !errors.Is(err, r.client.GetNilValueError()) {
@@ -445,6 +582,87 @@ func (r *redisStreams) removeMessagesThatNoLongerExistFromPending(ctx context.Co
 	}
 }
 
+// deadLetterMessages moves pending messages that exceeded `maxDeliveries` to the dead-letter stream and/or saves them to the dead-letter store, and removes them from the pending list.
+// Messages are claimed first, so they're moved only once when multiple consumers reclaim messages at the same time.
+func (r *redisStreams) deadLetterMessages(ctx context.Context, stream string, messageIDs []string) {
+	store, err := r.deadLetter.Get()
+	if err != nil {
+		r.logger.Errorf("error moving Redis messages to the dead-letter store: %v", err)
+
+		return
+	}
+
+	claimResult, err := r.client.XClaimResult(ctx,
+		stream,
+		r.metadata.consumerID,
+		r.metadata.consumerID,
+		r.metadata.claimTimeout,
+		messageIDs,
+	)
+	if err != nil && !errors.Is(err, r.client.GetNilValueError()) {
+		r.logger.Errorf("error claiming Redis messages to dead-letter: %v", err)
+
+		return
+	}
+
+	for _, msg := range claimResult {
+		// The message is acknowledged only once it was moved to all the destinations
+		if store != nil && !r.saveDeadLetter(ctx, store, stream, msg) {
+			continue
+		}
+
+		if r.metadata.deadLetterStream != "" {
+			values := make(map[string]interface{}, len(msg.Values)+3)
+			for k, v := range msg.Values {
+				values[k] = v
+			}
+			values[deadLetterOriginalStreamField] = stream
+			values[deadLetterOriginalIDField] = msg.ID
+			values[deadLetterDeliveriesField] = r.metadata.maxDeliveries
+
+			if _, err = r.client.XAdd(ctx, r.metadata.deadLetterStream, r.metadata.maxLenApprox, values); err != nil {
+				r.logger.Errorf("error moving Redis message %s to dead-letter stream %s: %v", msg.ID, r.metadata.deadLetterStream, err)
+
+				continue
+			}
+			r.logger.Warnf("Moved Redis message %s from stream %s to dead-letter stream %s after %d deliveries", msg.ID, stream, r.metadata.deadLetterStream, r.metadata.maxDeliveries)
+		}
+
+		// Use the background context in case subscriptionCtx is already closed.
+		if err = r.client.XAck(context.Background(), stream, r.metadata.consumerID, msg.ID); err != nil {
+			r.logger.Errorf("error acknowledging Redis message %s moved to dead-letter stream: %v", msg.ID, err)
+		}
+	}
+}
+
+// saveDeadLetter saves a message that exceeded `maxDeliveries` to the dead-letter store, and returns true if it was saved.
+// The fields of the message other than the payload are saved as its metadata.
+func (r *redisStreams) saveDeadLetter(ctx context.Context, store *deadletter.Store, stream string, msg rediscomponent.RedisXMessage) bool {
+	wrapper := createRedisMessageWrapper(ctx, stream, nil, msg)
+	metadata := make(map[string]string, len(msg.Values))
+	for k, v := range msg.Values {
+		if k != "data" {
+			metadata[k] = fmt.Sprint(v)
+		}
+	}
+
+	key, err := store.Save(ctx, deadletter.Message{
+		Topic:    stream,
+		ID:       msg.ID,
+		Data:     wrapper.message.Data,
+		Metadata: metadata,
+		Error:    fmt.Sprintf("message was not acknowledged after %d deliveries", r.metadata.maxDeliveries),
+	})
+	if err != nil {
+		r.logger.Errorf("error moving Redis message %s to the dead-letter store: %v", msg.ID, err)
+
+		return false
+	}
+	r.logger.Warnf("Saved Redis message %s from stream %s to the dead-letter store with key %s after %d deliveries", msg.ID, stream, key, r.metadata.maxDeliveries)
+
+	return true
+}
+
 func (r *redisStreams) Close() error {
 	defer r.wg.Wait()
 	if r.closed.CompareAndSwap(false, true) {
@@ -463,7 +681,7 @@ func (r *redisStreams) Features() []pubsub.Feature {
 
 func (r *redisStreams) Ping(ctx context.Context) error {
 	if _, err := r.client.PingResult(ctx); err != nil {
-		return fmt.Errorf("redis pubsub: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+		return fmt.Errorf("redis pubsub: error connecting to redis at %s: %s", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
 	}
 
 	return nil
@@ -15,15 +15,22 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/dapr/components-contrib/internal/component/deadletter"
 	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
 	"github.com/dapr/kit/logger"
 
 	internalredis "github.com/dapr/components-contrib/internal/component/redis"
@@ -70,6 +77,165 @@ func TestParseRedisMetadata(t *testing.T) {
 	})
 }
 
+func TestParseDeadLetterMetadata(t *testing.T) {
+	t.Run("claimTimeout defaults to processingTimeout", func(t *testing.T) {
+		props := getFakeProperties()
+		props[processingTimeout] = "5s"
+		m, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Second, m.claimTimeout)
+
+		props[claimTimeout] = "30s"
+		m, err = parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Second, m.claimTimeout)
+	})
+
+	t.Run("maxDeliveries and deadLetterStream", func(t *testing.T) {
+		props := getFakeProperties()
+		props[maxDeliveries] = "3"
+		props[deadLetterStream] = "dlq"
+		m, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), m.maxDeliveries)
+		assert.Equal(t, "dlq", m.deadLetterStream)
+
+		delete(props, deadLetterStream)
+		_, err = parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.Error(t, err)
+
+		props[deadLetterStream] = "dlq"
+		delete(props, maxDeliveries)
+		_, err = parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.Error(t, err)
+	})
+
+	t.Run("maxDeliveries and deadLetterStore", func(t *testing.T) {
+		props := getFakeProperties()
+		props[maxDeliveries] = "3"
+		props[deadLetterStore] = "statestore"
+		props[deadLetterTTL] = "1h"
+		m, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Equal(t, "statestore", m.deadLetter.DeadLetterStore)
+		assert.Equal(t, time.Hour, m.deadLetter.DeadLetterTTL)
+		assert.Empty(t, m.deadLetterStream)
+
+		delete(props, maxDeliveries)
+		_, err = parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+		require.Error(t, err)
+	})
+}
+
+func TestReclaimAndDeadLetterStore(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	r := NewRedisStreams(logger.NewLogger("test"))
+	err := r.Init(context.Background(), pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+		"redisHost":       s.Addr(),
+		consumerID:        "group",
+		redeliverInterval: "20ms",
+		claimTimeout:      "10ms",
+		maxDeliveries:     "2",
+		deadLetterStore:   "statestore",
+	}}})
+	require.NoError(t, err)
+	defer r.Close()
+
+	// The store must be set before subscribing
+	err = r.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "topic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		return nil
+	})
+	require.ErrorContains(t, err, "dead-letter store 'statestore' is configured, but it was not set")
+
+	stateStore := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	defer stateStore.(interface{ Close() error }).Close()
+	require.NoError(t, r.(pubsub.DeadLetterStoreSetter).SetDeadLetterStore(stateStore))
+
+	err = r.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "topic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		return errors.New("handler failed")
+	})
+	require.NoError(t, err)
+
+	err = r.Publish(context.Background(), &pubsub.PublishRequest{Topic: "topic", Data: []byte("hello")})
+	require.NoError(t, err)
+
+	// The message is saved to the store once it was delivered 2 times, and removed from the pending list
+	require.Eventually(t, func() bool {
+		res, _ := r.(*redisStreams).client.XPendingExtResult(context.Background(), "topic", "group", "-", "+", 10)
+		return len(res) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	entries, err := s.Stream("topic")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	res, err := stateStore.Get(context.Background(), &state.GetRequest{Key: "deadletter||redis||group||topic||" + entries[0].ID})
+	require.NoError(t, err)
+	var record deadletter.Record
+	require.NoError(t, json.Unmarshal(res.Data, &record))
+	assert.Equal(t, "topic", record.Topic)
+	assert.Equal(t, entries[0].ID, record.ID)
+	assert.Equal(t, []byte("hello"), record.Data)
+	assert.Equal(t, "message was not acknowledged after 2 deliveries", record.Error)
+}
+
+func TestReclaimAndDeadLetter(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	r := NewRedisStreams(logger.NewLogger("test"))
+	err := r.Init(context.Background(), pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+		"redisHost":       s.Addr(),
+		consumerID:        "group",
+		redeliverInterval: "20ms",
+		claimTimeout:      "10ms",
+		maxDeliveries:     "3",
+		deadLetterStream:  "dlq",
+	}}})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var lock sync.Mutex
+	deliveries := 0
+	err = r.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "topic"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		lock.Lock()
+		deliveries++
+		lock.Unlock()
+		return errors.New("handler failed")
+	})
+	require.NoError(t, err)
+
+	err = r.Publish(context.Background(), &pubsub.PublishRequest{Topic: "topic", Data: []byte("hello")})
+	require.NoError(t, err)
+
+	// The message is reclaimed until it was delivered 3 times, then it's moved to the dead-letter stream
+	require.Eventually(t, func() bool {
+		entries, _ := s.Stream("dlq")
+		return len(entries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	entries, err := s.Stream("dlq")
+	require.NoError(t, err)
+	values := map[string]string{}
+	for i := 0; i+1 < len(entries[0].Values); i += 2 {
+		values[entries[0].Values[i]] = entries[0].Values[i+1]
+	}
+	assert.Equal(t, "hello", values["data"])
+	assert.Equal(t, "topic", values[deadLetterOriginalStreamField])
+	assert.Equal(t, "3", values[deadLetterDeliveriesField])
+
+	lock.Lock()
+	assert.Equal(t, 3, deliveries)
+	lock.Unlock()
+
+	// The message was removed from the pending list
+	assert.Eventually(t, func() bool {
+		// Redis returns a nil reply when there are no pending messages
+		res, _ := r.(*redisStreams).client.XPendingExtResult(context.Background(), "topic", "group", "-", "+", 10)
+		return len(res) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
 func TestProcessStreams(t *testing.T) {
 	fakeConsumerID := "fakeConsumer"
 	topicCount := 0
@@ -108,6 +274,24 @@ func TestProcessStreams(t *testing.T) {
 	assert.Equal(t, 3, messageCount)
 }
 
+func TestProcessStreamsDeliveryMetrics(t *testing.T) {
+	metrics := pubsub.NewDeliveryMetrics()
+	testRedisStream := &redisStreams{logger: logger.NewLogger("test")}
+	testRedisStream.SetDeliveryMetricsRecorder(metrics)
+	testRedisStream.queue = make(chan redisMessageWrapper, 10)
+	go testRedisStream.worker()
+
+	// The handler fails, so messages remain pending and are retried
+	testRedisStream.enqueueMessages(context.Background(), "stream", func(ctx context.Context, msg *pubsub.NewMessage) error {
+		return errors.New("fake error")
+	}, generateRedisStreamTestData(1, 3, "testData"))
+
+	assert.Eventually(t, func() bool {
+		s := metrics.Snapshot()["stream"]
+		return s.Delivered == 3 && s.Retried == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
 func generateRedisStreamTestData(topicCount, messageCount int, data string) []internalredis.RedisXMessage {
 	generateXMessage := func(id int) internalredis.RedisXMessage {
 		return internalredis.RedisXMessage{
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamID is the ID of an entry in a Redis stream, in the format "<milliseconds>-<sequence>".
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+func parseStreamID(id string) (streamID, error) {
+	msStr, seqStr, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msStr, 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("invalid stream ID '%s'", id)
+	}
+	var seq uint64
+	if seqStr != "" {
+		seq, err = strconv.ParseUint(seqStr, 10, 64)
+		if err != nil {
+			return streamID{}, fmt.Errorf("invalid stream ID '%s'", id)
+		}
+	}
+	return streamID{ms: ms, seq: seq}, nil
+}
+
+func (id streamID) less(other streamID) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+func (id streamID) String() string {
+	return strconv.FormatUint(id.ms, 10) + "-" + strconv.FormatUint(id.seq, 10)
+}
+
+// trimLoop periodically trims the streams the component published or subscribed to, according to `maxLen` and `maxAge`.
+func (r *redisStreams) trimLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.metadata.trimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.streams.Range(func(key, _ any) bool {
+				err := r.trimStream(ctx, key.(string))
+				if err != nil && ctx.Err() == nil {
+					r.logger.Errorf("redis streams: error trimming stream %s: %v", key, err)
+				}
+				return ctx.Err() == nil
+			})
+		}
+	}
+}
+
+// trimStream removes the entries of the stream that exceed `maxLen` or are older than `maxAge`.
+// Entries that are pending, or not yet delivered, for any consumer group are never removed, and neither are those added within `trimSafetyMargin` before them.
+func (r *redisStreams) trimStream(ctx context.Context, stream string) error {
+	var (
+		minID   streamID
+		hasTrim bool
+	)
+
+	if r.metadata.maxAge > 0 {
+		minID = streamID{ms: uint64(time.Now().Add(-r.metadata.maxAge).UnixMilli())}
+		hasTrim = true
+	}
+
+	if r.metadata.maxLen > 0 {
+		// The oldest of the last maxLen entries is the oldest entry to keep
+		res, err := r.client.DoRead(ctx, "XREVRANGE", stream, "+", "-", "COUNT", r.metadata.maxLen)
+		if err != nil {
+			return err
+		}
+		entries, _ := res.([]interface{})
+		if int64(len(entries)) == r.metadata.maxLen {
+			entry, _ := entries[len(entries)-1].([]interface{})
+			if len(entry) == 0 {
+				return fmt.Errorf("unexpected reply to XREVRANGE: %v", res)
+			}
+			id, err := parseStreamID(fmt.Sprint(entry[0]))
+			if err != nil {
+				return err
+			}
+			if !hasTrim || minID.less(id) {
+				minID = id
+			}
+			hasTrim = true
+		}
+	}
+
+	if !hasTrim {
+		return nil
+	}
+
+	safeID, hasGroups, err := r.oldestUnacknowledgedID(ctx, stream)
+	if err != nil {
+		return err
+	}
+	if hasGroups {
+		margin := uint64(r.metadata.trimSafetyMargin.Milliseconds())
+		if safeID.ms > margin {
+			safeID = streamID{ms: safeID.ms - margin}
+		} else {
+			safeID = streamID{}
+		}
+		if safeID.less(minID) {
+			minID = safeID
+		}
+	}
+
+	// Approximate trimming is more efficient, and it only ever keeps more entries
+	return r.client.DoWrite(ctx, "XTRIM", stream, "MINID", "~", minID.String())
+}
+
+// oldestUnacknowledgedID returns the ID of the oldest entry of the stream that any consumer group has not acknowledged yet: the oldest pending entry of the group, or the last entry delivered to the group if none is pending.
+// The second return value is false if the stream has no consumer groups.
+func (r *redisStreams) oldestUnacknowledgedID(ctx context.Context, stream string) (streamID, bool, error) {
+	res, err := r.client.DoRead(ctx, "XINFO", "GROUPS", stream)
+	if err != nil {
+		return streamID{}, false, err
+	}
+	groups, _ := res.([]interface{})
+
+	var (
+		oldest streamID
+		found  bool
+	)
+	for _, g := range groups {
+		info := replyToMap(g)
+		name := info["name"]
+		if name == "" {
+			return streamID{}, false, fmt.Errorf("unexpected reply to XINFO GROUPS: %v", res)
+		}
+
+		id, err := parseStreamID(info["last-delivered-id"])
+		if err != nil {
+			return streamID{}, false, err
+		}
+		if info["pending"] != "" && info["pending"] != "0" {
+			pending, err := r.client.XPendingExtResult(ctx, stream, name, "-", "+", 1)
+			if err != nil {
+				return streamID{}, false, err
+			}
+			if len(pending) > 0 {
+				id, err = parseStreamID(pending[0].ID)
+				if err != nil {
+					return streamID{}, false, err
+				}
+			}
+		}
+
+		if !found || id.less(oldest) {
+			oldest = id
+			found = true
+		}
+	}
+	return oldest, found, nil
+}
+
+// replyToMap converts a reply containing field-value pairs, either as a RESP2 array or as a RESP3 map, to a map of strings.
+func replyToMap(reply interface{}) map[string]string {
+	res := map[string]string{}
+	switch v := reply.(type) {
+	case []interface{}:
+		for i := 0; i+1 < len(v); i += 2 {
+			if v[i+1] != nil {
+				res[fmt.Sprint(v[i])] = fmt.Sprint(v[i+1])
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			if val != nil {
+				res[fmt.Sprint(k)] = fmt.Sprint(val)
+			}
+		}
+	}
+	return res
+}
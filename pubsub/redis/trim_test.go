@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalredis "github.com/dapr/components-contrib/internal/component/redis"
+	mdata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+func newTrimTestStreams(t *testing.T, m metadata) (*redisStreams, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	client, _, err := internalredis.ParseClientFromProperties(map[string]string{"redisHost": s.Addr()}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return &redisStreams{
+		logger:   logger.NewLogger("test"),
+		client:   client,
+		metadata: m,
+	}, s
+}
+
+func addEntries(t *testing.T, s *miniredis.Miniredis, stream string, ms ...int) {
+	for _, v := range ms {
+		_, err := s.XAdd(stream, fmt.Sprintf("%d-0", v), []string{"data", "x"})
+		require.NoError(t, err)
+	}
+}
+
+func streamIDs(t *testing.T, s *miniredis.Miniredis, stream string) []string {
+	entries, err := s.Stream(stream)
+	require.NoError(t, err)
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestParseStreamID(t *testing.T) {
+	id, err := parseStreamID("1526919030474-55")
+	require.NoError(t, err)
+	assert.Equal(t, streamID{ms: 1526919030474, seq: 55}, id)
+	assert.Equal(t, "1526919030474-55", id.String())
+	assert.True(t, streamID{ms: 1, seq: 9}.less(streamID{ms: 2}))
+	assert.True(t, streamID{ms: 2, seq: 1}.less(streamID{ms: 2, seq: 2}))
+
+	_, err = parseStreamID("abc")
+	require.Error(t, err)
+}
+
+func TestTrimStream(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("maxLen without consumer groups", func(t *testing.T) {
+		r, s := newTrimTestStreams(t, metadata{maxLen: 3})
+		addEntries(t, s, "stream", 1, 2, 3, 4, 5)
+
+		require.NoError(t, r.trimStream(ctx, "stream"))
+		assert.Equal(t, []string{"3-0", "4-0", "5-0"}, streamIDs(t, s, "stream"))
+	})
+
+	t.Run("maxAge", func(t *testing.T) {
+		r, s := newTrimTestStreams(t, metadata{maxAge: time.Hour})
+		now := time.Now()
+		addEntries(t, s, "stream", int(now.Add(-2*time.Hour).UnixMilli()), int(now.Add(-time.Minute).UnixMilli()))
+
+		require.NoError(t, r.trimStream(ctx, "stream"))
+		assert.Len(t, streamIDs(t, s, "stream"), 1)
+	})
+
+	t.Run("pending entries are not trimmed", func(t *testing.T) {
+		r, s := newTrimTestStreams(t, metadata{maxLen: 1})
+		addEntries(t, s, "stream", 1, 2, 3, 4, 5)
+		require.NoError(t, r.client.XGroupCreateMkStream(ctx, "stream", "group", "0"))
+		// Deliver 3 entries, and acknowledge the first one
+		_, err := r.client.XReadGroupResult(ctx, "group", "consumer", []string{"stream", ">"}, 3, 0)
+		require.NoError(t, err)
+		require.NoError(t, r.client.XAck(ctx, "stream", "group", "1-0"))
+
+		require.NoError(t, r.trimStream(ctx, "stream"))
+		assert.Equal(t, []string{"2-0", "3-0", "4-0", "5-0"}, streamIDs(t, s, "stream"))
+	})
+
+	t.Run("undelivered entries are not trimmed", func(t *testing.T) {
+		r, s := newTrimTestStreams(t, metadata{maxLen: 1})
+		addEntries(t, s, "stream", 1, 2, 3, 4, 5)
+		require.NoError(t, r.client.XGroupCreateMkStream(ctx, "stream", "group", "0"))
+		_, err := r.client.XReadGroupResult(ctx, "group", "consumer", []string{"stream", ">"}, 2, 0)
+		require.NoError(t, err)
+		require.NoError(t, r.client.XAck(ctx, "stream", "group", "1-0"))
+		require.NoError(t, r.client.XAck(ctx, "stream", "group", "2-0"))
+
+		require.NoError(t, r.trimStream(ctx, "stream"))
+		assert.Equal(t, []string{"2-0", "3-0", "4-0", "5-0"}, streamIDs(t, s, "stream"))
+	})
+
+	t.Run("safety margin", func(t *testing.T) {
+		r, s := newTrimTestStreams(t, metadata{maxLen: 1, trimSafetyMargin: 2 * time.Millisecond})
+		addEntries(t, s, "stream", 1, 2, 3, 4, 5, 6)
+		require.NoError(t, r.client.XGroupCreateMkStream(ctx, "stream", "group", "0"))
+		_, err := r.client.XReadGroupResult(ctx, "group", "consumer", []string{"stream", ">"}, 6, 0)
+		require.NoError(t, err)
+		for _, id := range []string{"1-0", "2-0", "3-0", "4-0", "6-0"} {
+			require.NoError(t, r.client.XAck(ctx, "stream", "group", id))
+		}
+
+		// 5-0 is pending, so entries within 2ms before it are kept
+		require.NoError(t, r.trimStream(ctx, "stream"))
+		assert.Equal(t, []string{"3-0", "4-0", "5-0", "6-0"}, streamIDs(t, s, "stream"))
+	})
+}
+
+func TestParseTrimMetadata(t *testing.T) {
+	props := getFakeProperties()
+	props[maxLen] = "1000"
+	props[maxAge] = "24h"
+	props[trimSafetyMargin] = "1m"
+	m, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), m.maxLen)
+	assert.Equal(t, 24*time.Hour, m.maxAge)
+	assert.Equal(t, time.Minute, m.trimInterval)
+	assert.Equal(t, time.Minute, m.trimSafetyMargin)
+
+	props[maxAge] = "-1h"
+	_, err = parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: props}})
+	require.Error(t, err)
+}
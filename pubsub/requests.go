@@ -15,8 +15,10 @@ package pubsub
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // PublishRequest is the request to publish a message.
@@ -43,6 +45,42 @@ type SubscribeRequest struct {
 	BulkSubscribeConfig BulkSubscribeConfig `json:"bulkSubscribe,omitempty"`
 }
 
+// SeekRequest is the request to move the subscription to a topic to a position in the stream, so messages after it are delivered again.
+// Exactly one of Offset and Timestamp must be set.
+type SeekRequest struct {
+	Topic string `json:"topic"`
+	// Partition to move; if empty, all partitions are moved.
+	Partition string `json:"partition,omitempty"`
+	// Position of the first message to deliver, such as an offset in Kafka or a sequence number in Event Hubs.
+	// This is usually a checkpoint recorded before an incident.
+	Offset *int64 `json:"offset,omitempty"`
+	// Messages enqueued at or after this time are delivered.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// Confirm must be true, to guard against replaying messages by accident.
+	Confirm  bool              `json:"confirm"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ErrSeekNotConfirmed is returned when a SeekRequest doesn't have Confirm set.
+var ErrSeekNotConfirmed = errors.New("seeking delivers messages again, including those that were already processed; set 'confirm' to true to proceed")
+
+// Validate returns an error if the request is invalid or not confirmed.
+func (r *SeekRequest) Validate() error {
+	if r.Topic == "" {
+		return errors.New("topic is required to seek")
+	}
+	if (r.Offset == nil) == (r.Timestamp == nil) {
+		return errors.New("exactly one of offset and timestamp is required to seek")
+	}
+	if r.Offset != nil && *r.Offset < 0 {
+		return errors.New("offset to seek to must not be negative")
+	}
+	if !r.Confirm {
+		return ErrSeekNotConfirmed
+	}
+	return nil
+}
+
 // NewMessage is an event arriving from a message bus instance.
 type NewMessage struct {
 	Data        []byte            `json:"data"`
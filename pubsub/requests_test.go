@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/ptr"
+)
+
+func TestSeekRequestValidate(t *testing.T) {
+	ts := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, (&SeekRequest{Topic: "orders", Offset: ptr.Of(int64(10)), Confirm: true}).Validate())
+	require.NoError(t, (&SeekRequest{Topic: "orders", Timestamp: &ts, Confirm: true}).Validate())
+
+	err := (&SeekRequest{Topic: "orders", Offset: ptr.Of(int64(10))}).Validate()
+	require.ErrorIs(t, err, ErrSeekNotConfirmed)
+
+	for _, req := range []*SeekRequest{
+		{Offset: ptr.Of(int64(10)), Confirm: true},
+		{Topic: "orders", Confirm: true},
+		{Topic: "orders", Offset: ptr.Of(int64(10)), Timestamp: &ts, Confirm: true},
+		{Topic: "orders", Offset: ptr.Of(int64(-1)), Confirm: true},
+	} {
+		err = req.Validate()
+		require.Error(t, err, req)
+		assert.NotErrorIs(t, err, ErrSeekNotConfirmed)
+	}
+}
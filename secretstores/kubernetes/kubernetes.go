@@ -17,10 +17,20 @@ package kubernetes
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
+	"golang.org/x/exp/slices"
+	core_v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	core_v1_ac "k8s.io/client-go/applyconfigurations/core/v1"
 	"k8s.io/client-go/kubernetes"
 
 	kubeclient "github.com/dapr/components-contrib/internal/authentication/kubernetes"
@@ -29,11 +39,34 @@ import (
 	"github.com/dapr/kit/logger"
 )
 
-var _ secretstores.SecretStore = (*kubernetesSecretStore)(nil)
+var (
+	_ secretstores.SecretStore        = (*kubernetesSecretStore)(nil)
+	_ secretstores.BulkSetSecretStore = (*kubernetesSecretStore)(nil)
+)
+
+// Field manager used for server-side apply when writing secrets.
+const fieldManager = "dapr-secretstore"
 
 type kubernetesSecretStore struct {
 	kubeClient kubernetes.Interface
 	logger     logger.Logger
+	metadata   kubernetesMetadata
+	selector   labels.Selector
+	namespaces []string
+	// Labels required by the selector, which are added to the secrets that are written
+	selectorLabels map[string]string
+}
+
+type kubernetesMetadata struct {
+	// If true, allows creating and updating secrets with BulkSetSecret.
+	// The service account must also be granted the "patch" verb on secrets by Kubernetes RBAC.
+	AllowWrites bool `mapstructure:"allowWrites"`
+	// Kubernetes label selector, such as "app=dapr,tier!=frontend": only secrets matching it can be read and written.
+	// Secrets written with BulkSetSecret are given the labels it requires.
+	LabelSelector string `mapstructure:"labelSelector"`
+	// Comma-separated list of namespaces, other than the default one, that secrets can be read from and written to.
+	// When set, requests can only access the default namespace and the namespaces in this list.
+	Namespaces string `mapstructure:"namespaces"`
 }
 
 // NewKubernetesSecretStore returns a new Kubernetes secret store.
@@ -42,7 +75,17 @@ func NewKubernetesSecretStore(logger logger.Logger) secretstores.SecretStore {
 }
 
 // Init creates a Kubernetes client.
-func (k *kubernetesSecretStore) Init(_ context.Context, metadata secretstores.Metadata) error {
+func (k *kubernetesSecretStore) Init(_ context.Context, meta secretstores.Metadata) error {
+	k.metadata = kubernetesMetadata{}
+	err := metadata.DecodeMetadata(meta.Properties, &k.metadata)
+	if err != nil {
+		return err
+	}
+	err = k.parseFilters()
+	if err != nil {
+		return err
+	}
+
 	client, err := kubeclient.GetKubeClient()
 	if err != nil {
 		return err
@@ -52,21 +95,76 @@ func (k *kubernetesSecretStore) Init(_ context.Context, metadata secretstores.Me
 	return nil
 }
 
+func (k *kubernetesSecretStore) parseFilters() error {
+	k.selector = labels.Everything()
+	if k.metadata.LabelSelector != "" {
+		selector, err := labels.Parse(k.metadata.LabelSelector)
+		if err != nil {
+			return fmt.Errorf("invalid value for metadata 'labelSelector': %w", err)
+		}
+		k.selector = selector
+	}
+
+	k.selectorLabels = map[string]string{}
+	requirements, _ := k.selector.Requirements()
+	for _, r := range requirements {
+		switch r.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			if r.Values().Len() == 1 {
+				k.selectorLabels[r.Key()] = r.Values().List()[0]
+			}
+		}
+	}
+	if k.metadata.AllowWrites && !k.selector.Matches(labels.Set(k.selectorLabels)) {
+		return errors.New("when 'allowWrites' is true, metadata 'labelSelector' can only require labels to have a single value, so written secrets can be labelled to match it")
+	}
+
+	k.namespaces = nil
+	for _, ns := range strings.Split(k.metadata.Namespaces, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" && !slices.Contains(k.namespaces, ns) {
+			k.namespaces = append(k.namespaces, ns)
+		}
+	}
+
+	return nil
+}
+
 // GetSecret retrieves a secret using a key and returns a map of decrypted string/string values.
+// The name can be in the format "namespace/name" to read a secret from one of the namespaces in the "namespaces" metadata property.
 func (k *kubernetesSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
 	resp := secretstores.GetSecretResponse{
 		Data: map[string]string{},
 	}
-	namespace, err := k.getNamespaceFromMetadata(req.Metadata)
-	if err != nil {
-		return resp, err
+
+	namespace, name, found := strings.Cut(req.Name, "/")
+	if found {
+		if !slices.Contains(k.namespaces, namespace) {
+			return resp, fmt.Errorf("reading secrets from namespace '%s' is not allowed", namespace)
+		}
+	} else {
+		var err error
+		namespace, err = k.getNamespaceFromMetadata(req.Metadata)
+		if err != nil {
+			return resp, err
+		}
+		err = k.checkNamespaceAllowed(namespace)
+		if err != nil {
+			return resp, err
+		}
+		name = req.Name
 	}
 
-	secret, err := k.kubeClient.CoreV1().Secrets(namespace).Get(ctx, req.Name, meta_v1.GetOptions{}) //nolint:nosnakecase
+	secret, err := k.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, meta_v1.GetOptions{}) //nolint:nosnakecase
 	if err != nil {
 		return resp, err
 	}
 
+	// Secrets that don't match the label selector are treated as if they didn't exist
+	if k.selector != nil && !k.selector.Matches(labels.Set(secret.Labels)) {
+		return resp, apierrors.NewNotFound(core_v1.Resource("secrets"), name)
+	}
+
 	for k, v := range secret.Data {
 		resp.Data[k] = string(v)
 	}
@@ -75,6 +173,8 @@ func (k *kubernetesSecretStore) GetSecret(ctx context.Context, req secretstores.
 }
 
 // BulkGetSecret retrieves all secrets in the store and returns a map of decrypted string/string values.
+// If the "namespaces" metadata property is set and the request doesn't specify a namespace, secrets are also read from each of those namespaces, and their keys are in the format "namespace/name".
+// Only secrets matching the "labelSelector" metadata property are returned.
 func (k *kubernetesSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
 	resp := secretstores.BulkGetSecretResponse{
 		Data: map[string]map[string]string{},
@@ -83,22 +183,164 @@ func (k *kubernetesSecretStore) BulkGetSecret(ctx context.Context, req secretsto
 	if err != nil {
 		return resp, err
 	}
+	err = k.checkNamespaceAllowed(namespace)
+	if err != nil {
+		return resp, err
+	}
 
-	secrets, err := k.kubeClient.CoreV1().Secrets(namespace).List(ctx, meta_v1.ListOptions{}) //nolint:nosnakecase
+	err = k.listSecrets(ctx, namespace, "", resp.Data)
 	if err != nil {
 		return resp, err
 	}
 
+	if req.Metadata["namespace"] == "" {
+		for _, ns := range k.namespaces {
+			if ns == namespace {
+				continue
+			}
+			err = k.listSecrets(ctx, ns, ns+"/", resp.Data)
+			if err != nil {
+				return resp, err
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// listSecrets adds all secrets in the namespace that match the label selector to data, with the given key prefix.
+func (k *kubernetesSecretStore) listSecrets(ctx context.Context, namespace string, prefix string, data map[string]map[string]string) error {
+	opts := meta_v1.ListOptions{} //nolint:nosnakecase
+	if k.selector != nil && !k.selector.Empty() {
+		opts.LabelSelector = k.selector.String()
+	}
+
+	// If the service account isn't allowed to list secrets in the namespace, the Kubernetes API returns a "forbidden" error
+	secrets, err := k.kubeClient.CoreV1().Secrets(namespace).List(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to list secrets in namespace '%s': %w", namespace, err)
+	}
+
 	for _, s := range secrets.Items {
-		resp.Data[s.Name] = map[string]string{}
+		values := make(map[string]string, len(s.Data))
 		for k, v := range s.Data {
-			resp.Data[s.Name][k] = string(v)
+			values[k] = string(v)
+		}
+		data[prefix+s.Name] = values
+	}
+
+	return nil
+}
+
+// checkNamespaceAllowed returns an error if the "namespaces" metadata property is set and the namespace is neither the default one nor in that list.
+func (k *kubernetesSecretStore) checkNamespaceAllowed(namespace string) error {
+	if len(k.namespaces) == 0 || namespace == os.Getenv("NAMESPACE") || slices.Contains(k.namespaces, namespace) {
+		return nil
+	}
+	return fmt.Errorf("accessing secrets in namespace '%s' is not allowed", namespace)
+}
+
+// BulkSetSecret creates or updates multiple secrets in the namespace using server-side apply, so fields managed by others (such as keys not included in the request, labels, and annotations) are preserved.
+// Like in GetSecret, names can be in the format "namespace/name" to write a secret to one of the namespaces in the "namespaces" metadata property.
+// Set "force" to "true" in the request metadata to take ownership of keys that are managed by others; otherwise, such conflicts are reported as failures.
+// Existing secrets that don't match the "labelSelector" metadata property are reported as failures too.
+func (k *kubernetesSecretStore) BulkSetSecret(ctx context.Context, req secretstores.BulkSetSecretRequest) (secretstores.BulkSetSecretResponse, error) {
+	resp := secretstores.BulkSetSecretResponse{
+		Succeeded: []string{},
+	}
+	if !k.metadata.AllowWrites {
+		return resp, errors.New("writing secrets is not allowed: set 'allowWrites' to true in the component metadata")
+	}
+
+	namespace, err := k.getNamespaceFromMetadata(req.Metadata)
+	if err != nil {
+		return resp, err
+	}
+	err = k.checkNamespaceAllowed(namespace)
+	if err != nil {
+		return resp, err
+	}
+
+	var force bool
+	if v := req.Metadata["force"]; v != "" {
+		force, err = strconv.ParseBool(v)
+		if err != nil {
+			return resp, fmt.Errorf("invalid value for metadata 'force': %w", err)
 		}
 	}
 
+	// Process the secrets in a deterministic order
+	names := make([]string, 0, len(req.Secrets))
+	for name := range req.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data := make(map[string][]byte, len(req.Secrets[name]))
+		for key, value := range req.Secrets[name] {
+			data[key] = []byte(value)
+		}
+
+		secretNamespace, secretName, found := strings.Cut(name, "/")
+		if !found {
+			secretNamespace, secretName = namespace, name
+		}
+
+		if found && !slices.Contains(k.namespaces, secretNamespace) {
+			err = fmt.Errorf("writing secrets to namespace '%s' is not allowed", secretNamespace)
+		} else {
+			err = k.applySecret(ctx, secretNamespace, secretName, data, force)
+		}
+		if err != nil {
+			k.logger.Warnf("Failed to set secret '%s' in namespace '%s': %v", secretName, secretNamespace, err)
+			if resp.Failed == nil {
+				resp.Failed = map[string]string{}
+			}
+			resp.Failed[name] = err.Error()
+			continue
+		}
+		resp.Succeeded = append(resp.Succeeded, name)
+	}
+
+	if len(resp.Failed) > 0 {
+		failed := make([]string, 0, len(resp.Failed))
+		for name := range resp.Failed {
+			failed = append(failed, name)
+		}
+		sort.Strings(failed)
+		return resp, fmt.Errorf("failed to set %d of %d secrets: %s", len(failed), len(names), strings.Join(failed, ", "))
+	}
+
 	return resp, nil
 }
 
+// applySecret creates or updates a secret using server-side apply.
+// If the label selector is set, existing secrets that don't match it are not modified, and the secret is given the labels the selector requires.
+func (k *kubernetesSecretStore) applySecret(ctx context.Context, namespace string, name string, data map[string][]byte, force bool) error {
+	secret := core_v1_ac.Secret(name, namespace).WithData(data)
+	if k.selector != nil && !k.selector.Empty() {
+		existing, err := k.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, meta_v1.GetOptions{}) //nolint:nosnakecase
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return err
+		case !k.selector.Matches(labels.Set(existing.Labels)):
+			return fmt.Errorf("secret doesn't match the label selector '%s'", k.selector)
+		default:
+			// The apply fails with a conflict if the labels were changed after they were checked
+			secret.WithResourceVersion(existing.ResourceVersion)
+		}
+		secret.WithLabels(k.selectorLabels)
+	}
+
+	_, err := k.kubeClient.CoreV1().Secrets(namespace).Apply(ctx, secret, meta_v1.ApplyOptions{ //nolint:nosnakecase
+		FieldManager: fieldManager,
+		Force:        force,
+	})
+	return err
+}
+
 func (k *kubernetesSecretStore) getNamespaceFromMetadata(metadata map[string]string) (string, error) {
 	if val, ok := metadata["namespace"]; ok && val != "" {
 		return val, nil
@@ -118,8 +360,7 @@ func (k *kubernetesSecretStore) Features() []secretstores.Feature {
 }
 
 func (k *kubernetesSecretStore) GetComponentMetadata() map[string]string {
-	type unusedMetadataStruct struct{}
-	metadataStruct := unusedMetadataStruct{}
+	metadataStruct := kubernetesMetadata{}
 	metadataInfo := map[string]string{}
 	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.SecretStoreType)
 	return metadataInfo
@@ -14,11 +14,23 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	core_v1_ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
+	"github.com/dapr/components-contrib/secretstores"
 	"github.com/dapr/kit/logger"
 )
 
@@ -59,3 +71,264 @@ func TestGetFeatures(t *testing.T) {
 		assert.Empty(t, f)
 	})
 }
+
+func TestBulkSetSecret(t *testing.T) {
+	newStore := func(allowWrites bool, namespaces ...string) (*kubernetesSecretStore, *[]string) {
+		client := fake.NewSimpleClientset()
+		applied := []string{}
+		// The fake clientset doesn't support server-side apply, so we intercept the patches
+		client.PrependReactor("patch", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			patch := action.(k8stesting.PatchAction)
+			assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+			if patch.GetName() == "bad" {
+				return true, nil, errors.New("forbidden")
+			}
+			applied = append(applied, patch.GetNamespace()+"/"+patch.GetName())
+			return true, &core_v1.Secret{}, nil
+		})
+		return &kubernetesSecretStore{
+			kubeClient: client,
+			logger:     logger.NewLogger("test"),
+			metadata:   kubernetesMetadata{AllowWrites: allowWrites},
+			namespaces: namespaces,
+		}, &applied
+	}
+
+	t.Run("writes not allowed", func(t *testing.T) {
+		store, applied := newStore(false)
+		_, err := store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+			Secrets:  map[string]map[string]string{"a": {"k": "v"}},
+			Metadata: map[string]string{"namespace": "ns"},
+		})
+		require.Error(t, err)
+		assert.Empty(t, *applied)
+	})
+
+	t.Run("all succeed", func(t *testing.T) {
+		store, applied := newStore(true)
+		resp, err := store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+			Secrets: map[string]map[string]string{
+				"b": {"k": "v"},
+				"a": {"k1": "v1", "k2": "v2"},
+			},
+			Metadata: map[string]string{"namespace": "ns"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, resp.Succeeded)
+		assert.Empty(t, resp.Failed)
+		assert.Equal(t, []string{"ns/a", "ns/b"}, *applied)
+	})
+
+	t.Run("continues after failures", func(t *testing.T) {
+		store, applied := newStore(true)
+		resp, err := store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+			Secrets: map[string]map[string]string{
+				"a":   {"k": "v"},
+				"bad": {"k": "v"},
+				"c":   {"k": "v"},
+			},
+			Metadata: map[string]string{"namespace": "ns", "force": "true"},
+		})
+		require.ErrorContains(t, err, "failed to set 1 of 3 secrets: bad")
+		assert.Equal(t, []string{"a", "c"}, resp.Succeeded)
+		assert.Equal(t, map[string]string{"bad": "forbidden"}, resp.Failed)
+		assert.Equal(t, []string{"ns/a", "ns/c"}, *applied)
+	})
+
+	t.Run("namespaces allowlist", func(t *testing.T) {
+		t.Setenv("NAMESPACE", "default")
+
+		store, applied := newStore(true, "ns")
+		resp, err := store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+			Secrets: map[string]map[string]string{
+				"a":         {"k": "v"},
+				"ns/b":      {"k": "v"},
+				"private/c": {"k": "v"},
+			},
+		})
+		require.ErrorContains(t, err, "failed to set 1 of 3 secrets: private/c")
+		assert.Equal(t, []string{"a", "ns/b"}, resp.Succeeded)
+		assert.Equal(t, map[string]string{"private/c": "writing secrets to namespace 'private' is not allowed"}, resp.Failed)
+		assert.Equal(t, []string{"default/a", "ns/b"}, *applied)
+
+		_, err = store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+			Secrets:  map[string]map[string]string{"a": {"k": "v"}},
+			Metadata: map[string]string{"namespace": "private"},
+		})
+		require.ErrorContains(t, err, "namespace 'private' is not allowed")
+		assert.Len(t, *applied, 2)
+	})
+
+	t.Run("invalid force", func(t *testing.T) {
+		store, _ := newStore(true)
+		_, err := store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+			Metadata: map[string]string{"namespace": "ns", "force": "maybe"},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestBulkSetSecretLabelSelector(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Namespace: "ns", Name: "tagged", Labels: map[string]string{"dapr": "true"}, ResourceVersion: "7"}},
+		&core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Namespace: "ns", Name: "untagged"}},
+	)
+	applied := map[string]*core_v1_ac.SecretApplyConfiguration{}
+	// The fake clientset doesn't support server-side apply, so we intercept the patches
+	client.PrependReactor("patch", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		secret := &core_v1_ac.SecretApplyConfiguration{}
+		require.NoError(t, json.Unmarshal(patch.GetPatch(), secret))
+		applied[patch.GetName()] = secret
+		return true, &core_v1.Secret{}, nil
+	})
+	store := &kubernetesSecretStore{
+		kubeClient: client,
+		logger:     logger.NewLogger("test"),
+		metadata:   kubernetesMetadata{AllowWrites: true, LabelSelector: "dapr=true,tier!=frontend"},
+	}
+	require.NoError(t, store.parseFilters())
+
+	resp, err := store.BulkSetSecret(context.Background(), secretstores.BulkSetSecretRequest{
+		Secrets: map[string]map[string]string{
+			"tagged":   {"k": "v"},
+			"untagged": {"k": "v"},
+			"new":      {"k": "v"},
+		},
+		Metadata: map[string]string{"namespace": "ns"},
+	})
+	require.ErrorContains(t, err, "failed to set 1 of 3 secrets: untagged")
+	assert.Equal(t, []string{"new", "tagged"}, resp.Succeeded)
+	assert.Contains(t, resp.Failed["untagged"], "doesn't match the label selector")
+
+	t.Run("secrets that don't match are not modified", func(t *testing.T) {
+		assert.NotContains(t, applied, "untagged")
+	})
+
+	t.Run("secrets are given the labels of the selector", func(t *testing.T) {
+		require.Contains(t, applied, "new")
+		assert.Equal(t, map[string]string{"dapr": "true"}, applied["new"].Labels)
+		require.Contains(t, applied, "tagged")
+		assert.Equal(t, map[string]string{"dapr": "true"}, applied["tagged"].Labels)
+		// Updates of existing secrets are conditioned on the version that was checked
+		assert.Equal(t, "7", *applied["tagged"].ResourceVersion)
+	})
+
+	t.Run("selectors that can't be satisfied by labels", func(t *testing.T) {
+		store := &kubernetesSecretStore{metadata: kubernetesMetadata{AllowWrites: true, LabelSelector: "dapr"}}
+		require.ErrorContains(t, store.parseFilters(), "labelSelector")
+		store = &kubernetesSecretStore{metadata: kubernetesMetadata{AllowWrites: true, LabelSelector: "tier in (a,b)"}}
+		require.ErrorContains(t, store.parseFilters(), "labelSelector")
+
+		// Without writes, any selector is accepted
+		store = &kubernetesSecretStore{metadata: kubernetesMetadata{LabelSelector: "dapr"}}
+		require.NoError(t, store.parseFilters())
+	})
+}
+
+func TestNamespaceAndLabelFiltering(t *testing.T) {
+	newSecret := func(namespace, name string, labels map[string]string) *core_v1.Secret {
+		return &core_v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+			Data:       map[string][]byte{"key": []byte(namespace + "-" + name)},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		newSecret("default", "tagged", map[string]string{"dapr": "true"}),
+		newSecret("default", "untagged", nil),
+		newSecret("other", "tagged", map[string]string{"dapr": "true"}),
+		newSecret("other", "untagged", map[string]string{"dapr": "false"}),
+		newSecret("private", "tagged", map[string]string{"dapr": "true"}),
+	)
+	newStore := func(t *testing.T, md kubernetesMetadata) *kubernetesSecretStore {
+		store := &kubernetesSecretStore{
+			kubeClient: client,
+			logger:     logger.NewLogger("test"),
+			metadata:   md,
+		}
+		require.NoError(t, store.parseFilters())
+		return store
+	}
+	t.Setenv("NAMESPACE", "default")
+
+	t.Run("invalid label selector", func(t *testing.T) {
+		store := &kubernetesSecretStore{metadata: kubernetesMetadata{LabelSelector: "dapr in (true"}}
+		require.Error(t, store.parseFilters())
+	})
+
+	t.Run("no filters", func(t *testing.T) {
+		store := newStore(t, kubernetesMetadata{})
+		resp, err := store.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"tagged":   {"key": "default-tagged"},
+			"untagged": {"key": "default-untagged"},
+		}, resp.Data)
+	})
+
+	t.Run("label selector", func(t *testing.T) {
+		store := newStore(t, kubernetesMetadata{LabelSelector: "dapr=true"})
+		resp, err := store.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"tagged": {"key": "default-tagged"},
+		}, resp.Data)
+
+		_, err = store.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "tagged"})
+		require.NoError(t, err)
+		_, err = store.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "untagged"})
+		require.Error(t, err)
+	})
+
+	t.Run("cross-namespace reads", func(t *testing.T) {
+		store := newStore(t, kubernetesMetadata{LabelSelector: "dapr=true", Namespaces: "other, default"})
+		resp, err := store.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"tagged":       {"key": "default-tagged"},
+			"other/tagged": {"key": "other-tagged"},
+		}, resp.Data)
+
+		// Requesting a namespace explicitly returns only its secrets, without prefixes
+		resp, err = store.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{
+			Metadata: map[string]string{"namespace": "other"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"tagged": {"key": "other-tagged"},
+		}, resp.Data)
+
+		get, err := store.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "other/tagged"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"key": "other-tagged"}, get.Data)
+	})
+
+	t.Run("namespace not allowed", func(t *testing.T) {
+		store := newStore(t, kubernetesMetadata{Namespaces: "other"})
+		_, err := store.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "private/tagged"})
+		require.ErrorContains(t, err, "namespace 'private' is not allowed")
+		_, err = store.GetSecret(context.Background(), secretstores.GetSecretRequest{
+			Name:     "tagged",
+			Metadata: map[string]string{"namespace": "private"},
+		})
+		require.ErrorContains(t, err, "namespace 'private' is not allowed")
+		_, err = store.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{
+			Metadata: map[string]string{"namespace": "private"},
+		})
+		require.ErrorContains(t, err, "namespace 'private' is not allowed")
+	})
+
+	t.Run("forbidden namespace", func(t *testing.T) {
+		forbiddenClient := fake.NewSimpleClientset()
+		forbiddenClient.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetNamespace() == "other" {
+				return true, nil, errors.New("forbidden")
+			}
+			return false, nil, nil
+		})
+		store := newStore(t, kubernetesMetadata{Namespaces: "other"})
+		store.kubeClient = forbiddenClient
+		_, err := store.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.ErrorContains(t, err, "failed to list secrets in namespace 'other': forbidden")
+	})
+}
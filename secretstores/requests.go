@@ -23,3 +23,10 @@ type GetSecretRequest struct {
 type BulkGetSecretRequest struct {
 	Metadata map[string]string `json:"metadata"`
 }
+
+// BulkSetSecretRequest describes a request to create or update multiple secrets in a secret store.
+type BulkSetSecretRequest struct {
+	// Secrets to create or update, keyed by the name of the secret.
+	Secrets  map[string]map[string]string `json:"secrets"`
+	Metadata map[string]string            `json:"metadata"`
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstores
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+// NewMetadataSecretResolver returns a metadata.SecretResolver that retrieves secrets from the given secret stores, keyed by the name of the store.
+// It's used to resolve secret references in the metadata of other components.
+func NewMetadataSecretResolver(stores map[string]SecretStore) metadata.SecretResolver {
+	return &secretResolver{stores: stores}
+}
+
+type secretResolver struct {
+	stores map[string]SecretStore
+}
+
+func (r *secretResolver) ResolveSecret(ctx context.Context, store string, name string, key string) (string, error) {
+	s, ok := r.stores[store]
+	if !ok || s == nil {
+		return "", fmt.Errorf("secret store '%s' not found", store)
+	}
+
+	res, err := s.GetSecret(ctx, GetSecretRequest{Name: name})
+	if err != nil {
+		return "", err
+	}
+	val, ok := res.Data[key]
+	if !ok {
+		return "", fmt.Errorf("%w: key '%s' not found in secret '%s'", metadata.ErrSecretNotFound, key, name)
+	}
+	return val, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstores
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+type fakeSecretStore struct {
+	secrets map[string]map[string]string
+}
+
+func (f *fakeSecretStore) Init(context.Context, Metadata) error {
+	return nil
+}
+
+func (f *fakeSecretStore) GetSecret(_ context.Context, req GetSecretRequest) (GetSecretResponse, error) {
+	data, ok := f.secrets[req.Name]
+	if !ok {
+		return GetSecretResponse{}, errors.New("secret does not exist")
+	}
+	return GetSecretResponse{Data: data}, nil
+}
+
+func (f *fakeSecretStore) BulkGetSecret(context.Context, BulkGetSecretRequest) (BulkGetSecretResponse, error) {
+	return BulkGetSecretResponse{Data: f.secrets}, nil
+}
+
+func (f *fakeSecretStore) Features() []Feature {
+	return nil
+}
+
+func (f *fakeSecretStore) GetComponentMetadata() map[string]string {
+	return nil
+}
+
+func TestMetadataSecretResolver(t *testing.T) {
+	resolver := NewMetadataSecretResolver(map[string]SecretStore{
+		"vault": &fakeSecretStore{secrets: map[string]map[string]string{
+			"db": {"password": "hunter2"},
+		}},
+	})
+
+	val, err := resolver.ResolveSecret(context.Background(), "vault", "db", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", val)
+
+	_, err = resolver.ResolveSecret(context.Background(), "vault", "db", "user")
+	require.ErrorIs(t, err, metadata.ErrSecretNotFound)
+
+	_, err = resolver.ResolveSecret(context.Background(), "vault", "cache", "password")
+	require.ErrorContains(t, err, "secret does not exist")
+
+	_, err = resolver.ResolveSecret(context.Background(), "kv", "db", "password")
+	require.ErrorContains(t, err, "secret store 'kv' not found")
+}
@@ -22,3 +22,11 @@ type GetSecretResponse struct {
 type BulkGetSecretResponse struct {
 	Data map[string]map[string]string `json:"data"`
 }
+
+// BulkSetSecretResponse describes the result of a bulk set secret request.
+type BulkSetSecretResponse struct {
+	// Names of the secrets that were created or updated.
+	Succeeded []string `json:"succeeded"`
+	// Errors for the secrets that could not be created or updated, keyed by the name of the secret.
+	Failed map[string]string `json:"failed,omitempty"`
+}
@@ -34,6 +34,22 @@ type SecretStore interface {
 	GetComponentMetadata() map[string]string
 }
 
+// BulkSetSecretStore is implemented by secret stores that can create or update secrets.
+type BulkSetSecretStore interface {
+	// BulkSetSecret creates or updates multiple secrets.
+	// A failure for one secret doesn't stop the others from being set: the response lists the secrets that failed, and a non-nil error is returned if any did.
+	BulkSetSecret(ctx context.Context, req BulkSetSecretRequest) (BulkSetSecretResponse, error)
+}
+
+func BulkSetSecret(ctx context.Context, secretStore SecretStore, req BulkSetSecretRequest) (BulkSetSecretResponse, error) {
+	// checks if this secretStore supports writing secrets then executes
+	if secretStoreWithBulkSet, ok := secretStore.(BulkSetSecretStore); ok {
+		return secretStoreWithBulkSet.BulkSetSecret(ctx, req)
+	} else {
+		return BulkSetSecretResponse{}, fmt.Errorf("bulk set is not implemented by this secret store")
+	}
+}
+
 func Ping(ctx context.Context, secretStore SecretStore) error {
 	// checks if this secretStore has the ping option then executes
 	if secretStoreWithPing, ok := secretStore.(health.Pinger); ok {
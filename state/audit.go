@@ -0,0 +1,29 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+)
+
+// AuditLogPublishFn publishes data to a topic of the pub/sub component that is the sink of an audit log.
+type AuditLogPublishFn func(ctx context.Context, topic string, data []byte) error
+
+// AuditLogSinkSetter is implemented by state stores that record the keys they modify in an audit log.
+// When the "auditLogSink" metadata property is "statestore" or "pubsub", the runtime passes the component named in "auditLogComponent" after Init and before any operation: the state store with SetAuditLogStore, or a function that publishes to the pub/sub component with SetAuditLogPublisher.
+// Records are written before the operations they describe, which fail if the records can't be written.
+type AuditLogSinkSetter interface {
+	SetAuditLogStore(store Store) error
+	SetAuditLogPublisher(publish AuditLogPublishFn) error
+}
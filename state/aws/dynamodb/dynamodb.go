@@ -43,17 +43,25 @@ type StateStore struct {
 	table            string
 	ttlAttributeName string
 	partitionKey     string
+	projection       *projection
 }
 
 type dynamoDBMetadata struct {
-	Region           string `json:"region"`
-	Endpoint         string `json:"endpoint"`
-	AccessKey        string `json:"accessKey"`
-	SecretKey        string `json:"secretKey"`
-	SessionToken     string `json:"sessionToken"`
-	Table            string `json:"table"`
-	TTLAttributeName string `json:"ttlAttributeName"`
-	PartitionKey     string `json:"partitionKey"`
+	Region              string `json:"region"`
+	Endpoint            string `json:"endpoint"`
+	AccessKey           string `json:"accessKey"`
+	SecretKey           string `json:"secretKey"`
+	SessionToken        string `json:"sessionToken"`
+	AssumeRoleArn       string `json:"assumeRoleArn"`
+	SessionName         string `json:"sessionName"`
+	ExternalID          string `json:"externalID"`
+	IntermediateRoleArn string `json:"intermediateRoleArn"`
+	AllowedRegions      string `json:"allowedRegions"`
+	Table               string `json:"table"`
+	TTLAttributeName    string `json:"ttlAttributeName"`
+	PartitionKey        string `json:"partitionKey"`
+	ProjectedAttributes string `json:"projectedAttributes"`
+	ProjectedIndexes    string `json:"projectedIndexes"`
 }
 
 const (
@@ -87,11 +95,26 @@ func (d *StateStore) Init(_ context.Context, metadata state.Metadata) error {
 	d.ttlAttributeName = meta.TTLAttributeName
 	d.partitionKey = meta.PartitionKey
 
+	d.projection, err = parseProjection(meta.ProjectedAttributes, meta.ProjectedIndexes)
+	if err != nil {
+		return err
+	}
+	if d.projection != nil {
+		for _, attr := range d.projection.attributes {
+			if attr == d.partitionKey || attr == d.ttlAttributeName {
+				return fmt.Errorf("dynamodb error: projected attribute '%s' conflicts with the partition key or the TTL attribute", attr)
+			}
+		}
+	}
+
 	return nil
 }
 
 // Features returns the features available in this state store.
 func (d *StateStore) Features() []state.Feature {
+	if d.projection != nil && len(d.projection.indexes) > 0 {
+		return []state.Feature{state.FeatureETag, state.FeatureQueryAPI}
+	}
 	return []state.Feature{state.FeatureETag}
 }
 
@@ -116,15 +139,22 @@ func (d *StateStore) Get(ctx context.Context, req *state.GetRequest) (*state.Get
 		return &state.GetResponse{}, nil
 	}
 
+	return d.getResponseFromItem(result.Item)
+}
+
+// getResponseFromItem returns the value and ETag of an item, or an empty response if the item has expired.
+// Projected attributes are ignored, so items written without them are returned as-is.
+func (d *StateStore) getResponseFromItem(item map[string]*dynamodb.AttributeValue) (*state.GetResponse, error) {
 	var output string
-	if err = dynamodbattribute.Unmarshal(result.Item["value"], &output); err != nil {
+	if err := dynamodbattribute.Unmarshal(item["value"], &output); err != nil {
 		return nil, err
 	}
 
 	var ttl int64
 	if d.ttlAttributeName != "" {
-		if val, ok := result.Item[d.ttlAttributeName]; ok {
-			if err = dynamodbattribute.Unmarshal(val, &ttl); err != nil {
+		if val, ok := item[d.ttlAttributeName]; ok {
+			err := dynamodbattribute.Unmarshal(val, &ttl)
+			if err != nil {
 				return nil, err
 			}
 			if ttl <= time.Now().Unix() {
@@ -139,8 +169,8 @@ func (d *StateStore) Get(ctx context.Context, req *state.GetRequest) (*state.Get
 	}
 
 	var etag string
-	if etagVal, ok := result.Item["etag"]; ok {
-		if err = dynamodbattribute.Unmarshal(etagVal, &etag); err != nil {
+	if etagVal, ok := item["etag"]; ok {
+		if err := dynamodbattribute.Unmarshal(etagVal, &etag); err != nil {
 			return nil, err
 		}
 		resp.ETag = &etag
@@ -309,7 +339,18 @@ func (d *StateStore) getDynamoDBMetadata(meta state.Metadata) (*dynamoDBMetadata
 }
 
 func (d *StateStore) getClient(metadata *dynamoDBMetadata) (*dynamodb.DynamoDB, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.GetClient(awsAuth.Options{
+		Region:              metadata.Region,
+		Endpoint:            metadata.Endpoint,
+		AccessKey:           metadata.AccessKey,
+		SecretKey:           metadata.SecretKey,
+		SessionToken:        metadata.SessionToken,
+		AssumeRoleArn:       metadata.AssumeRoleArn,
+		SessionName:         metadata.SessionName,
+		ExternalID:          metadata.ExternalID,
+		IntermediateRoleArn: metadata.IntermediateRoleArn,
+		AllowedRegions:      metadata.AllowedRegions,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -353,6 +394,10 @@ func (d *StateStore) getItemFromReq(req *state.SetRequest) (map[string]*dynamodb
 		}
 	}
 
+	if d.projection != nil {
+		d.projection.project(value, item)
+	}
+
 	return item, nil
 }
 
@@ -0,0 +1,391 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+)
+
+// projection maps top-level fields of JSON values to native DynamoDB attributes, which are written alongside the value so they can be queried.
+type projection struct {
+	// Name of the attribute for each projected field
+	attributes map[string]string
+	// Name of the global secondary index whose partition key is the attribute of the field, for each indexed field
+	indexes map[string]string
+}
+
+// parseProjection parses the "projectedAttributes" and "projectedIndexes" metadata properties.
+// Attributes are listed as "field" or "field=attribute" entries; indexes as "field=index" entries, and their fields must be projected.
+func parseProjection(attributes string, indexes string) (*projection, error) {
+	p := &projection{
+		attributes: map[string]string{},
+		indexes:    map[string]string{},
+	}
+	for _, entry := range strings.Split(attributes, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, attr, ok := strings.Cut(entry, "=")
+		field = strings.TrimSpace(field)
+		attr = strings.TrimSpace(attr)
+		if !ok {
+			attr = field
+		}
+		if field == "" || attr == "" || strings.Contains(field, ".") {
+			return nil, fmt.Errorf("dynamodb error: invalid projected attribute '%s': must be a top-level field of the value", entry)
+		}
+		switch attr {
+		case "value", "etag":
+			return nil, fmt.Errorf("dynamodb error: the name of projected attribute '%s' is reserved", attr)
+		}
+		p.attributes[field] = attr
+	}
+	for _, entry := range strings.Split(indexes, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, index, ok := strings.Cut(entry, "=")
+		field = strings.TrimSpace(field)
+		index = strings.TrimSpace(index)
+		if !ok || field == "" || index == "" {
+			return nil, fmt.Errorf("dynamodb error: invalid projected index '%s': must be in the format 'field=index'", entry)
+		}
+		if _, ok := p.attributes[field]; !ok {
+			return nil, fmt.Errorf("dynamodb error: the field '%s' of index '%s' is not a projected attribute", field, index)
+		}
+		p.indexes[field] = index
+	}
+	if len(p.attributes) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// project adds the attributes of the projected fields in the JSON value to the item.
+// Values that aren't JSON objects, and fields that are missing, null, or not scalars, aren't projected.
+func (p *projection) project(value string, item map[string]*dynamodb.AttributeValue) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal([]byte(value), &obj) != nil {
+		return
+	}
+	for field, attr := range p.attributes {
+		raw, ok := obj[field]
+		if !ok {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if dec.Decode(&v) != nil {
+			continue
+		}
+		if av := scalarAttributeValue(v); av != nil {
+			item[attr] = av
+		}
+	}
+}
+
+// scalarAttributeValue returns the attribute value of a JSON string, number, or boolean, or nil for other values.
+func scalarAttributeValue(v any) *dynamodb.AttributeValue {
+	switch x := v.(type) {
+	case string:
+		return &dynamodb.AttributeValue{S: aws.String(x)}
+	case json.Number:
+		return &dynamodb.AttributeValue{N: aws.String(x.String())}
+	case float64:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(x, 'f', -1, 64))}
+	case int:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(x))}
+	case int64:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(x, 10))}
+	case bool:
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(x)}
+	default:
+		return nil
+	}
+}
+
+// Query builds a DynamoDB query on a global secondary index from a state store query.
+// The filter must contain an equality condition on an indexed field, either alone or within a top-level AND; the other conditions are applied as a filter expression, and can only use projected fields.
+type Query struct {
+	projection *projection
+
+	index        string
+	keyCondition string
+	filter       string
+	names        map[string]*string
+	values       map[string]*dynamodb.AttributeValue
+	limit        int64
+	startKey     map[string]*dynamodb.AttributeValue
+}
+
+func NewQuery(p *projection) *Query {
+	return &Query{
+		projection: p,
+		names:      map[string]*string{},
+		values:     map[string]*dynamodb.AttributeValue{},
+	}
+}
+
+// build sets the key condition from the query and builds the filter expression from the remaining conditions.
+func (q *Query) build(qq *query.Query) error {
+	key, rest, err := q.splitKeyCondition(qq.Filter)
+	if err != nil {
+		return err
+	}
+	q.index = q.projection.indexes[key.Key]
+	q.keyCondition, err = q.VisitEQ(key)
+	if err != nil {
+		return err
+	}
+
+	remaining := *qq
+	remaining.Filter = rest
+	return query.NewQueryBuilder(q).BuildQuery(&remaining)
+}
+
+// splitKeyCondition returns the equality condition on an indexed field used as the key condition, and the other conditions.
+func (q *Query) splitKeyCondition(f query.Filter) (*query.EQ, query.Filter, error) {
+	switch x := f.(type) {
+	case *query.EQ:
+		if _, ok := q.projection.indexes[x.Key]; ok {
+			return x, nil, nil
+		}
+	case *query.AND:
+		for i, child := range x.Filters {
+			eq, ok := child.(*query.EQ)
+			if !ok {
+				continue
+			}
+			if _, ok = q.projection.indexes[eq.Key]; !ok {
+				continue
+			}
+			rest := make([]query.Filter, 0, len(x.Filters)-1)
+			rest = append(rest, x.Filters[:i]...)
+			rest = append(rest, x.Filters[i+1:]...)
+			if len(rest) == 1 {
+				return eq, rest[0], nil
+			}
+			return eq, &query.AND{Filters: rest}, nil
+		}
+	}
+	return nil, nil, errors.New("dynamodb error: the query must contain an equality condition on an indexed field")
+}
+
+func (q *Query) attributeName(field string) (string, error) {
+	attr, ok := q.projection.attributes[field]
+	if !ok {
+		return "", fmt.Errorf("dynamodb error: field '%s' is not a projected attribute", field)
+	}
+	placeholder := "#a" + strconv.Itoa(len(q.names))
+	q.names[placeholder] = aws.String(attr)
+	return placeholder, nil
+}
+
+func (q *Query) attributeValue(v any) (string, error) {
+	av := scalarAttributeValue(v)
+	if av == nil {
+		return "", fmt.Errorf("dynamodb error: unsupported value in query: %v", v)
+	}
+	placeholder := ":v" + strconv.Itoa(len(q.values))
+	q.values[placeholder] = av
+	return placeholder, nil
+}
+
+func (q *Query) VisitEQ(f *query.EQ) (string, error) {
+	name, err := q.attributeName(f.Key)
+	if err != nil {
+		return "", err
+	}
+	val, err := q.attributeValue(f.Val)
+	if err != nil {
+		return "", err
+	}
+	return name + " = " + val, nil
+}
+
+func (q *Query) VisitIN(f *query.IN) (string, error) {
+	if len(f.Vals) == 0 {
+		return "", fmt.Errorf("dynamodb error: empty IN operator for key %q", f.Key)
+	}
+	name, err := q.attributeName(f.Key)
+	if err != nil {
+		return "", err
+	}
+	vals := make([]string, len(f.Vals))
+	for i, v := range f.Vals {
+		vals[i], err = q.attributeValue(v)
+		if err != nil {
+			return "", err
+		}
+	}
+	return name + " IN (" + strings.Join(vals, ", ") + ")", nil
+}
+
+func (q *Query) visitFilters(op string, filters []query.Filter) (string, error) {
+	exprs := make([]string, len(filters))
+	for i, f := range filters {
+		var (
+			expr string
+			err  error
+		)
+		switch x := f.(type) {
+		case *query.EQ:
+			expr, err = q.VisitEQ(x)
+		case *query.IN:
+			expr, err = q.VisitIN(x)
+		case *query.AND:
+			expr, err = q.VisitAND(x)
+		case *query.OR:
+			expr, err = q.VisitOR(x)
+		default:
+			err = fmt.Errorf("dynamodb error: unsupported filter type %#v", f)
+		}
+		if err != nil {
+			return "", err
+		}
+		exprs[i] = "(" + expr + ")"
+	}
+	return strings.Join(exprs, " "+op+" "), nil
+}
+
+func (q *Query) VisitAND(f *query.AND) (string, error) {
+	return q.visitFilters("AND", f.Filters)
+}
+
+func (q *Query) VisitOR(f *query.OR) (string, error) {
+	return q.visitFilters("OR", f.Filters)
+}
+
+func (q *Query) Finalize(filters string, qq *query.Query) error {
+	q.filter = filters
+
+	if len(qq.Sort) > 0 {
+		return errors.New("dynamodb error: sorting is not supported")
+	}
+	if qq.Page.Limit > 0 {
+		q.limit = int64(qq.Page.Limit)
+	}
+	if qq.Page.Token != "" {
+		var err error
+		q.startKey, err = decodeQueryToken(qq.Page.Token)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// input returns the input of the DynamoDB query.
+func (q *Query) input(table string) *dynamodb.QueryInput {
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(table),
+		IndexName:                 aws.String(q.index),
+		KeyConditionExpression:    aws.String(q.keyCondition),
+		ExpressionAttributeNames:  q.names,
+		ExpressionAttributeValues: q.values,
+		ExclusiveStartKey:         q.startKey,
+	}
+	if q.filter != "" {
+		input.FilterExpression = aws.String(q.filter)
+	}
+	if q.limit > 0 {
+		input.Limit = aws.Int64(q.limit)
+	}
+	return input
+}
+
+// Query executes a query on a global secondary index of the projected attributes.
+// The limit of the page is the number of items read from the index before the filter expression is applied, so pages can contain fewer items.
+func (d *StateStore) Query(ctx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
+	if d.projection == nil || len(d.projection.indexes) == 0 {
+		return nil, errors.New("dynamodb error: querying requires projected attributes with indexes")
+	}
+
+	q := NewQuery(d.projection)
+	err := q.build(&req.Query)
+	if err != nil {
+		return &state.QueryResponse{}, err
+	}
+
+	out, err := d.client.QueryWithContext(ctx, q.input(d.table))
+	if err != nil {
+		return &state.QueryResponse{}, err
+	}
+
+	res := &state.QueryResponse{
+		Results: make([]state.QueryItem, 0, len(out.Items)),
+	}
+	for _, item := range out.Items {
+		var key string
+		err = dynamodbattribute.Unmarshal(item[d.partitionKey], &key)
+		if err != nil {
+			return &state.QueryResponse{}, err
+		}
+		resp, err := d.getResponseFromItem(item)
+		if err != nil {
+			res.Results = append(res.Results, state.QueryItem{Key: key, Error: err.Error()})
+			continue
+		}
+		// Expired items that DynamoDB didn't delete yet are skipped
+		if resp.Data == nil {
+			continue
+		}
+		res.Results = append(res.Results, state.QueryItem{Key: key, Data: resp.Data, ETag: resp.ETag})
+	}
+	if len(out.LastEvaluatedKey) > 0 {
+		res.Token, err = encodeQueryToken(out.LastEvaluatedKey)
+		if err != nil {
+			return &state.QueryResponse{}, err
+		}
+	}
+	return res, nil
+}
+
+func encodeQueryToken(key map[string]*dynamodb.AttributeValue) (string, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("dynamodb error: failed to encode the pagination token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeQueryToken(token string) (map[string]*dynamodb.AttributeValue, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("dynamodb error: invalid pagination token: %w", err)
+	}
+	var key map[string]*dynamodb.AttributeValue
+	err = json.Unmarshal(b, &key)
+	if err != nil {
+		return nil, fmt.Errorf("dynamodb error: invalid pagination token: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+)
+
+func TestParseProjection(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		p, err := parseProjection("", "")
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("attributes and indexes", func(t *testing.T) {
+		p, err := parseProjection("status, customerId=customer_id", "customerId=customer-index")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"status": "status", "customerId": "customer_id"}, p.attributes)
+		assert.Equal(t, map[string]string{"customerId": "customer-index"}, p.indexes)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseProjection("a.b", "")
+		require.ErrorContains(t, err, "top-level field")
+		_, err = parseProjection("data=value", "")
+		require.ErrorContains(t, err, "reserved")
+		_, err = parseProjection("status", "status")
+		require.ErrorContains(t, err, "field=index")
+		_, err = parseProjection("status", "other=other-index")
+		require.ErrorContains(t, err, "not a projected attribute")
+	})
+
+	t.Run("conflict with the partition key", func(t *testing.T) {
+		s := NewDynamoDBStateStore(nil)
+		err := s.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"table":               "a",
+			"region":              "eu-west-1",
+			"projectedAttributes": "id=key",
+		}}})
+		require.ErrorContains(t, err, "conflicts with the partition key")
+	})
+}
+
+func TestSetWithProjection(t *testing.T) {
+	p, err := parseProjection("status,count=cnt,active,nested,missing", "status=status-index")
+	require.NoError(t, err)
+
+	var item map[string]*dynamodb.AttributeValue
+	ss := &StateStore{
+		partitionKey: defaultPartitionKeyName,
+		projection:   p,
+		client: &mockedDynamoDB{
+			PutItemWithContextFn: func(ctx context.Context, input *dynamodb.PutItemInput, op ...request.Option) (*dynamodb.PutItemOutput, error) {
+				item = input.Item
+				return &dynamodb.PutItemOutput{}, nil
+			},
+		},
+	}
+
+	err = ss.Set(context.Background(), &state.SetRequest{
+		Key:   "key",
+		Value: map[string]any{"status": "open", "count": 12345678901234, "active": true, "nested": map[string]any{"a": 1}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "open", *item["status"].S)
+	assert.Equal(t, "12345678901234", *item["cnt"].N)
+	assert.True(t, *item["active"].BOOL)
+	assert.NotContains(t, item, "nested")
+	assert.NotContains(t, item, "missing")
+	assert.Len(t, item, 6)
+
+	// Values that aren't JSON objects aren't projected
+	err = ss.Set(context.Background(), &state.SetRequest{Key: "key", Value: []byte("not json")})
+	require.NoError(t, err)
+	assert.Len(t, item, 3)
+}
+
+func TestGetWithoutProjectedAttributes(t *testing.T) {
+	p, err := parseProjection("status", "status=status-index")
+	require.NoError(t, err)
+	ss := &StateStore{
+		partitionKey: defaultPartitionKeyName,
+		projection:   p,
+		client: &mockedDynamoDB{
+			GetItemWithContextFn: func(ctx context.Context, input *dynamodb.GetItemInput, op ...request.Option) (*dynamodb.GetItemOutput, error) {
+				return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+					"key":   {S: aws.String("key")},
+					"value": {S: aws.String(`{"status":"open"}`)},
+					"etag":  {S: aws.String("1bdead4badc0ffee")},
+				}}, nil
+			},
+		},
+	}
+
+	res, err := ss.Get(context.Background(), &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"open"}`, string(res.Data))
+	assert.Equal(t, "1bdead4badc0ffee", *res.ETag)
+}
+
+func TestQuery(t *testing.T) {
+	p, err := parseProjection("status,customerId=customer_id,priority", "customerId=customer-index")
+	require.NoError(t, err)
+
+	parseQuery := func(t *testing.T, q string) query.Query {
+		var qq query.Query
+		require.NoError(t, json.Unmarshal([]byte(q), &qq))
+		return qq
+	}
+
+	t.Run("equality on an indexed field", func(t *testing.T) {
+		var input *dynamodb.QueryInput
+		ss := &StateStore{
+			table:            tableName,
+			partitionKey:     defaultPartitionKeyName,
+			ttlAttributeName: "ttl",
+			projection:       p,
+			client: &mockedDynamoDB{
+				QueryWithContextFn: func(ctx context.Context, in *dynamodb.QueryInput, op ...request.Option) (*dynamodb.QueryOutput, error) {
+					input = in
+					return &dynamodb.QueryOutput{
+						Items: []map[string]*dynamodb.AttributeValue{
+							{"key": {S: aws.String("k1")}, "value": {S: aws.String(`{"customerId":"c1"}`)}, "etag": {S: aws.String("e1")}},
+							{"key": {S: aws.String("k2")}, "value": {S: aws.String(`{"customerId":"c1"}`)}, "etag": {S: aws.String("e2")}, "ttl": {N: aws.String("1")}},
+						},
+						LastEvaluatedKey: map[string]*dynamodb.AttributeValue{
+							"key":         {S: aws.String("k2")},
+							"customer_id": {S: aws.String("c1")},
+						},
+					}, nil
+				},
+			},
+		}
+
+		res, err := ss.Query(context.Background(), &state.QueryRequest{Query: parseQuery(t, `{"filter":{"EQ":{"customerId":"c1"}},"page":{"limit":2}}`)})
+		require.NoError(t, err)
+		assert.Equal(t, "customer-index", *input.IndexName)
+		assert.Equal(t, "#a0 = :v0", *input.KeyConditionExpression)
+		assert.Equal(t, "customer_id", *input.ExpressionAttributeNames["#a0"])
+		assert.Equal(t, "c1", *input.ExpressionAttributeValues[":v0"].S)
+		assert.Nil(t, input.FilterExpression)
+		assert.Equal(t, int64(2), *input.Limit)
+
+		// Expired items are skipped
+		require.Len(t, res.Results, 1)
+		assert.Equal(t, "k1", res.Results[0].Key)
+		assert.Equal(t, `{"customerId":"c1"}`, string(res.Results[0].Data))
+		assert.Equal(t, "e1", *res.Results[0].ETag)
+		require.NotEmpty(t, res.Token)
+
+		// The token is the key to start the next page from
+		_, err = ss.Query(context.Background(), &state.QueryRequest{Query: parseQuery(t, `{"filter":{"EQ":{"customerId":"c1"}},"page":{"limit":2,"token":"`+res.Token+`"}}`)})
+		require.NoError(t, err)
+		assert.Equal(t, "k2", *input.ExclusiveStartKey["key"].S)
+		assert.Equal(t, "c1", *input.ExclusiveStartKey["customer_id"].S)
+	})
+
+	t.Run("other conditions are a filter expression", func(t *testing.T) {
+		var input *dynamodb.QueryInput
+		ss := &StateStore{
+			table:        tableName,
+			partitionKey: defaultPartitionKeyName,
+			projection:   p,
+			client: &mockedDynamoDB{
+				QueryWithContextFn: func(ctx context.Context, in *dynamodb.QueryInput, op ...request.Option) (*dynamodb.QueryOutput, error) {
+					input = in
+					return &dynamodb.QueryOutput{}, nil
+				},
+			},
+		}
+
+		res, err := ss.Query(context.Background(), &state.QueryRequest{Query: parseQuery(t, `{"filter":{"AND":[
+			{"IN":{"status":["open","closed"]}},
+			{"EQ":{"customerId":"c1"}},
+			{"OR":[{"EQ":{"priority":1}},{"EQ":{"priority":2}}]}
+		]}}`)})
+		require.NoError(t, err)
+		assert.Empty(t, res.Results)
+		assert.Empty(t, res.Token)
+		assert.Equal(t, "customer-index", *input.IndexName)
+		assert.Equal(t, "#a0 = :v0", *input.KeyConditionExpression)
+		assert.Equal(t, "(#a1 IN (:v1, :v2)) AND ((#a2 = :v3) OR (#a3 = :v4))", *input.FilterExpression)
+		assert.Equal(t, "status", *input.ExpressionAttributeNames["#a1"])
+		assert.Equal(t, "priority", *input.ExpressionAttributeNames["#a2"])
+		assert.Equal(t, strconv.Itoa(2), *input.ExpressionAttributeValues[":v4"].N)
+		assert.Nil(t, input.Limit)
+	})
+
+	t.Run("unsupported queries", func(t *testing.T) {
+		ss := &StateStore{
+			table:        tableName,
+			partitionKey: defaultPartitionKeyName,
+			projection:   p,
+		}
+		tests := map[string]struct {
+			query string
+			err   string
+		}{
+			"no indexed field":       {query: `{"filter":{"EQ":{"status":"open"}}}`, err: "equality condition on an indexed field"},
+			"indexed field in OR":    {query: `{"filter":{"OR":[{"EQ":{"customerId":"c1"}},{"EQ":{"status":"open"}}]}}`, err: "equality condition on an indexed field"},
+			"field not projected":    {query: `{"filter":{"AND":[{"EQ":{"customerId":"c1"}},{"EQ":{"other":"x"}}]}}`, err: "'other' is not a projected attribute"},
+			"sorting":                {query: `{"filter":{"EQ":{"customerId":"c1"}},"sort":[{"key":"status"}]}`, err: "sorting is not supported"},
+			"invalid token":          {query: `{"filter":{"EQ":{"customerId":"c1"}},"page":{"token":"!"}}`, err: "invalid pagination token"},
+			"unsupported value type": {query: `{"filter":{"EQ":{"customerId":{"a":1}}}}`, err: "unsupported value"},
+		}
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := ss.Query(context.Background(), &state.QueryRequest{Query: parseQuery(t, tc.query)})
+				require.ErrorContains(t, err, tc.err)
+			})
+		}
+	})
+
+	t.Run("no indexes", func(t *testing.T) {
+		ss := &StateStore{partitionKey: defaultPartitionKeyName}
+		_, err := ss.Query(context.Background(), &state.QueryRequest{})
+		require.ErrorContains(t, err, "requires projected attributes")
+		assert.NotContains(t, ss.Features(), state.FeatureQueryAPI)
+	})
+
+}
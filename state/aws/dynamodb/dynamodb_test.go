@@ -37,6 +37,7 @@ type mockedDynamoDB struct {
 	PutItemWithContextFn        func(ctx context.Context, input *dynamodb.PutItemInput, op ...request.Option) (*dynamodb.PutItemOutput, error)
 	DeleteItemWithContextFn     func(ctx context.Context, input *dynamodb.DeleteItemInput, op ...request.Option) (*dynamodb.DeleteItemOutput, error)
 	BatchWriteItemWithContextFn func(ctx context.Context, input *dynamodb.BatchWriteItemInput, op ...request.Option) (*dynamodb.BatchWriteItemOutput, error)
+	QueryWithContextFn          func(ctx context.Context, input *dynamodb.QueryInput, op ...request.Option) (*dynamodb.QueryOutput, error)
 	dynamodbiface.DynamoDBAPI
 }
 
@@ -67,6 +68,10 @@ func (m *mockedDynamoDB) BatchWriteItemWithContext(ctx context.Context, input *d
 	return m.BatchWriteItemWithContextFn(ctx, input, op...)
 }
 
+func (m *mockedDynamoDB) QueryWithContext(ctx context.Context, input *dynamodb.QueryInput, op ...request.Option) (*dynamodb.QueryOutput, error) {
+	return m.QueryWithContextFn(ctx, input, op...)
+}
+
 func TestInit(t *testing.T) {
 	m := state.Metadata{}
 	s := &StateStore{
@@ -0,0 +1,368 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	conflictResolutionModeLastWriterWins = "lastwriterwins"
+	conflictResolutionModeCustom         = "custom"
+
+	// Default path used by Cosmos DB for "last writer wins", which is the timestamp of the last update.
+	defaultConflictResolutionPath = "/_ts"
+
+	// Version of the Cosmos DB REST API used to read the conflicts feed, which is not exposed by the SDK.
+	cosmosAPIVersion = "2018-12-31"
+)
+
+// Conflict is a write conflict recorded by Cosmos DB in the conflicts feed of the container.
+// Conflicts are recorded when multi-region writes are enabled and the conflict resolution policy is "custom", and they were not resolved by a stored procedure.
+type Conflict struct {
+	// ID of the conflict, which is used to delete it once reconciled.
+	ID string
+	// Operation that caused the conflict: "create", "replace" or "delete".
+	OperationType string
+	// Key and partition key of the item that was not applied.
+	Key          string
+	PartitionKey string
+	// Value and ETag of the item that was not applied.
+	// For deletes, Value is nil.
+	Value []byte
+	ETag  string
+}
+
+// parseConflictResolutionPolicy returns the conflict resolution policy configured in the metadata, or nil if none is configured.
+func parseConflictResolutionPolicy(m metadata) (*azcosmos.ConflictResolutionPolicy, error) {
+	switch strings.ToLower(m.ConflictResolutionMode) {
+	case "":
+		if m.ConflictResolutionPath != "" || m.ConflictResolutionProcedure != "" {
+			return nil, errors.New("conflictResolutionMode is required when conflictResolutionPath or conflictResolutionProcedure are set")
+		}
+		return nil, nil
+	case conflictResolutionModeLastWriterWins:
+		if m.ConflictResolutionProcedure != "" {
+			return nil, errors.New("conflictResolutionProcedure can only be used with the 'custom' conflict resolution mode")
+		}
+		path := m.ConflictResolutionPath
+		if path == "" {
+			path = defaultConflictResolutionPath
+		}
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid conflictResolutionPath '%s': must start with '/'", path)
+		}
+		return &azcosmos.ConflictResolutionPolicy{
+			Mode:           azcosmos.ConflictResolutionModeLastWriteWins,
+			ResolutionPath: path,
+		}, nil
+	case conflictResolutionModeCustom:
+		if m.ConflictResolutionPath != "" {
+			return nil, errors.New("conflictResolutionPath can only be used with the 'lastWriterWins' conflict resolution mode")
+		}
+		p := &azcosmos.ConflictResolutionPolicy{
+			Mode: azcosmos.ConflictResolutionModeCustom,
+		}
+		// Without a stored procedure, all conflicts are recorded in the conflicts feed
+		if m.ConflictResolutionProcedure != "" {
+			p.ResolutionProcedure = m.ConflictResolutionProcedure
+			if !strings.Contains(p.ResolutionProcedure, "/") {
+				p.ResolutionProcedure = fmt.Sprintf("dbs/%s/colls/%s/sprocs/%s", m.Database, m.Collection, m.ConflictResolutionProcedure)
+			}
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("invalid conflictResolutionMode '%s': supported values are 'lastWriterWins' and 'custom'", m.ConflictResolutionMode)
+	}
+}
+
+// conflictResolutionPolicyMatches returns true if the policy of an existing container is the one that is configured.
+func conflictResolutionPolicyMatches(existing *azcosmos.ConflictResolutionPolicy, configured *azcosmos.ConflictResolutionPolicy) bool {
+	// Containers without an explicit policy use "last writer wins" on the timestamp
+	if existing == nil {
+		existing = &azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeLastWriteWins}
+	}
+	if existing.Mode == "" {
+		existing.Mode = azcosmos.ConflictResolutionModeLastWriteWins
+	}
+
+	if existing.Mode != configured.Mode {
+		return false
+	}
+	if configured.Mode == azcosmos.ConflictResolutionModeLastWriteWins {
+		existingPath := existing.ResolutionPath
+		if existingPath == "" {
+			existingPath = defaultConflictResolutionPath
+		}
+		return existingPath == configured.ResolutionPath
+	}
+	return strings.EqualFold(strings.Trim(existing.ResolutionProcedure, "/"), strings.Trim(configured.ResolutionProcedure, "/"))
+}
+
+// ensureContainer creates the container with the configured conflict resolution policy if it doesn't exist, or validates the policy of the existing container.
+// The conflict resolution policy of a container can't be changed after it's created, so Init fails if the existing container has a different policy.
+func (c *StateStore) ensureContainer(ctx context.Context, dbClient *azcosmos.DatabaseClient, conflictPolicy *azcosmos.ConflictResolutionPolicy) error {
+	readCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	res, err := c.client.Read(readCtx, nil)
+	if err != nil {
+		if !isNotFoundError(err) {
+			return err
+		}
+
+		c.logger.Infof("Creating container '%s' with conflict resolution mode '%s'", c.metadata.Collection, conflictPolicy.Mode)
+		createCtx, createCancel := context.WithTimeout(ctx, defaultTimeout)
+		defer createCancel()
+		_, err = dbClient.CreateContainer(createCtx, azcosmos.ContainerProperties{
+			ID: c.metadata.Collection,
+			PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+				Paths: []string{"/" + metadataPartitionKey},
+			},
+			ConflictResolutionPolicy: conflictPolicy,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create container '%s': %w", c.metadata.Collection, err)
+		}
+		return nil
+	}
+
+	var existing *azcosmos.ConflictResolutionPolicy
+	if res.ContainerProperties != nil {
+		existing = res.ContainerProperties.ConflictResolutionPolicy
+	}
+	if !conflictResolutionPolicyMatches(existing, conflictPolicy) {
+		return fmt.Errorf("the conflict resolution policy of the existing container '%s' doesn't match the configured one, and it can't be changed on an existing container", c.metadata.Collection)
+	}
+	return nil
+}
+
+// GetConflicts returns the conflicts recorded in the conflicts feed of the container, which can be reconciled by the application and then removed with DeleteConflict.
+func (c *StateStore) GetConflicts(ctx context.Context) ([]Conflict, error) {
+	if c.conflicts == nil {
+		return nil, errors.New("state store is not initialized")
+	}
+	return c.conflicts.list(ctx)
+}
+
+// DeleteConflict removes a conflict from the conflicts feed of the container.
+func (c *StateStore) DeleteConflict(ctx context.Context, conflict Conflict) error {
+	if c.conflicts == nil {
+		return errors.New("state store is not initialized")
+	}
+	return c.conflicts.delete(ctx, conflict)
+}
+
+// conflictsFeed reads and deletes conflicts with the REST API of Cosmos DB, since the SDK doesn't support the conflicts feed.
+type conflictsFeed struct {
+	endpoint   string
+	database   string
+	collection string
+	client     *http.Client
+	logger     logger.Logger
+
+	// authorize returns the value of the Authorization header for a request.
+	authorize func(ctx context.Context, method string, resourceLink string, date string) (string, error)
+}
+
+type conflictResource struct {
+	ID            string `json:"id"`
+	OperationType string `json:"operationType"`
+	ResourceType  string `json:"resourceType"`
+	Content       string `json:"content"`
+}
+
+func newConflictsFeed(m metadata, token azcore.TokenCredential, log logger.Logger) (*conflictsFeed, error) {
+	f := &conflictsFeed{
+		endpoint:   strings.TrimSuffix(m.URL, "/"),
+		database:   m.Database,
+		collection: m.Collection,
+		client:     &http.Client{Timeout: defaultTimeout},
+		logger:     log,
+	}
+
+	if m.MasterKey != "" {
+		key, err := base64.StdEncoding.DecodeString(m.MasterKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode master key: %w", err)
+		}
+		f.authorize = masterKeyAuthorizer(key)
+	} else {
+		u, err := url.Parse(m.URL)
+		if err != nil {
+			return nil, err
+		}
+		scope := fmt.Sprintf("%s://%s/.default", u.Scheme, u.Hostname())
+		f.authorize = func(ctx context.Context, _ string, _ string, _ string) (string, error) {
+			tk, err := token.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+			if err != nil {
+				return "", err
+			}
+			return "type=aad&ver=1.0&sig=" + tk.Token, nil
+		}
+	}
+
+	return f, nil
+}
+
+// masterKeyAuthorizer signs requests with the master key.
+// See https://learn.microsoft.com/rest/api/cosmos-db/access-control-on-cosmosdb-resources
+func masterKeyAuthorizer(key []byte) func(ctx context.Context, method string, resourceLink string, date string) (string, error) {
+	return func(_ context.Context, method string, resourceLink string, date string) (string, error) {
+		stringToSign := strings.ToLower(method) + "\nconflicts\n" + resourceLink + "\n" + strings.ToLower(date) + "\n\n"
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(stringToSign))
+		sig := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		return url.QueryEscape("type=master&ver=1.0&sig=" + sig), nil
+	}
+}
+
+func (f *conflictsFeed) newRequest(ctx context.Context, method string, path string, resourceLink string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, f.endpoint+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	auth, err := f.authorize(ctx, method, resourceLink, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authorize request: %w", err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("x-ms-version", cosmosAPIVersion)
+	return req, nil
+}
+
+func (f *conflictsFeed) list(ctx context.Context) ([]Conflict, error) {
+	collLink := fmt.Sprintf("dbs/%s/colls/%s", f.database, f.collection)
+
+	var (
+		conflicts    []Conflict
+		continuation string
+	)
+	for {
+		req, err := f.newRequest(ctx, http.MethodGet, collLink+"/conflicts", collLink)
+		if err != nil {
+			return nil, err
+		}
+		if continuation != "" {
+			req.Header.Set("x-ms-continuation", continuation)
+		}
+
+		res, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to read conflicts feed: status %d: %s", res.StatusCode, string(body))
+		}
+
+		var page struct {
+			Conflicts []conflictResource `json:"Conflicts"`
+		}
+		err = json.Unmarshal(body, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse conflicts feed: %w", err)
+		}
+
+		for _, r := range page.Conflicts {
+			if r.ResourceType != "" && !strings.EqualFold(r.ResourceType, "document") {
+				continue
+			}
+			conflict, err := f.parseConflict(r)
+			if err != nil {
+				return nil, err
+			}
+			conflicts = append(conflicts, conflict)
+		}
+
+		continuation = res.Header.Get("x-ms-continuation")
+		if continuation == "" {
+			return conflicts, nil
+		}
+	}
+}
+
+func (f *conflictsFeed) parseConflict(r conflictResource) (Conflict, error) {
+	conflict := Conflict{
+		ID:            r.ID,
+		OperationType: strings.ToLower(r.OperationType),
+	}
+	if r.Content == "" {
+		return conflict, nil
+	}
+
+	item, err := NewCosmosItemFromResponse([]byte(r.Content), f.logger)
+	if err != nil {
+		return conflict, fmt.Errorf("failed to parse content of conflict '%s': %w", r.ID, err)
+	}
+	conflict.Key = item.ID
+	conflict.PartitionKey = item.PartitionKey
+	conflict.ETag = item.Etag
+	if conflict.OperationType != "delete" {
+		// We are sure this is a []byte if not nil
+		conflict.Value, _ = item.Value.([]byte)
+	}
+	return conflict, nil
+}
+
+func (f *conflictsFeed) delete(ctx context.Context, conflict Conflict) error {
+	if conflict.ID == "" {
+		return errors.New("conflict ID is required")
+	}
+
+	link := fmt.Sprintf("dbs/%s/colls/%s/conflicts/%s", f.database, f.collection, conflict.ID)
+	req, err := f.newRequest(ctx, http.MethodDelete, link, link)
+	if err != nil {
+		return err
+	}
+	pk, err := json.Marshal([]string{conflict.PartitionKey})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-documentdb-partitionkey", string(pk))
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("failed to delete conflict '%s': status %d: %s", conflict.ID, res.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseConflictResolutionPolicy(t *testing.T) {
+	base := metadata{Database: "db", Collection: "coll"}
+
+	t.Run("not configured", func(t *testing.T) {
+		p, err := parseConflictResolutionPolicy(base)
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("last writer wins with default path", func(t *testing.T) {
+		m := base
+		m.ConflictResolutionMode = "lastWriterWins"
+		p, err := parseConflictResolutionPolicy(m)
+		require.NoError(t, err)
+		assert.Equal(t, &azcosmos.ConflictResolutionPolicy{
+			Mode:           azcosmos.ConflictResolutionModeLastWriteWins,
+			ResolutionPath: "/_ts",
+		}, p)
+	})
+
+	t.Run("last writer wins with custom path", func(t *testing.T) {
+		m := base
+		m.ConflictResolutionMode = "LastWriterWins"
+		m.ConflictResolutionPath = "/value/version"
+		p, err := parseConflictResolutionPolicy(m)
+		require.NoError(t, err)
+		assert.Equal(t, "/value/version", p.ResolutionPath)
+	})
+
+	t.Run("custom with procedure name", func(t *testing.T) {
+		m := base
+		m.ConflictResolutionMode = "custom"
+		m.ConflictResolutionProcedure = "resolver"
+		p, err := parseConflictResolutionPolicy(m)
+		require.NoError(t, err)
+		assert.Equal(t, &azcosmos.ConflictResolutionPolicy{
+			Mode:                azcosmos.ConflictResolutionModeCustom,
+			ResolutionProcedure: "dbs/db/colls/coll/sprocs/resolver",
+		}, p)
+	})
+
+	t.Run("custom without procedure", func(t *testing.T) {
+		m := base
+		m.ConflictResolutionMode = "custom"
+		p, err := parseConflictResolutionPolicy(m)
+		require.NoError(t, err)
+		assert.Equal(t, azcosmos.ConflictResolutionModeCustom, p.Mode)
+		assert.Empty(t, p.ResolutionProcedure)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, m := range map[string]metadata{
+			"unknown mode":           {ConflictResolutionMode: "manual"},
+			"path without mode":      {ConflictResolutionPath: "/_ts"},
+			"procedure with lww":     {ConflictResolutionMode: "lastWriterWins", ConflictResolutionProcedure: "resolver"},
+			"path with custom":       {ConflictResolutionMode: "custom", ConflictResolutionPath: "/_ts"},
+			"path without leading /": {ConflictResolutionMode: "lastWriterWins", ConflictResolutionPath: "_ts"},
+		} {
+			_, err := parseConflictResolutionPolicy(m)
+			require.Error(t, err, name)
+		}
+	})
+}
+
+func TestConflictResolutionPolicyMatches(t *testing.T) {
+	lww := &azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeLastWriteWins, ResolutionPath: "/_ts"}
+	custom := &azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeCustom, ResolutionProcedure: "dbs/db/colls/coll/sprocs/resolver"}
+
+	assert.True(t, conflictResolutionPolicyMatches(nil, lww))
+	assert.True(t, conflictResolutionPolicyMatches(&azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeLastWriteWins}, lww))
+	assert.False(t, conflictResolutionPolicyMatches(&azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeLastWriteWins, ResolutionPath: "/version"}, lww))
+	assert.False(t, conflictResolutionPolicyMatches(nil, custom))
+	assert.True(t, conflictResolutionPolicyMatches(&azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeCustom, ResolutionProcedure: "/dbs/db/colls/coll/sprocs/resolver"}, custom))
+	assert.False(t, conflictResolutionPolicyMatches(&azcosmos.ConflictResolutionPolicy{Mode: azcosmos.ConflictResolutionModeCustom}, custom))
+}
+
+func TestConflictsFeed(t *testing.T) {
+	key := []byte("secret-key")
+
+	verifyAuth := func(t *testing.T, r *http.Request, resourceLink string) {
+		t.Helper()
+
+		stringToSign := strings.ToLower(r.Method) + "\nconflicts\n" + resourceLink + "\n" + strings.ToLower(r.Header.Get("x-ms-date")) + "\n\n"
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(stringToSign))
+		expected := "type=master&ver=1.0&sig=" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		auth, err := url.QueryUnescape(r.Header.Get("Authorization"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, auth)
+		assert.Equal(t, cosmosAPIVersion, r.Header.Get("x-ms-version"))
+	}
+
+	content := func(item CosmosItem) string {
+		b, _ := json.Marshal(item)
+		return string(b)
+	}
+
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/dbs/db/colls/coll/conflicts":
+			verifyAuth(t, r, "dbs/db/colls/coll")
+			var res map[string]any
+			if r.Header.Get("x-ms-continuation") == "" {
+				w.Header().Set("x-ms-continuation", "page2")
+				res = map[string]any{"Conflicts": []map[string]any{
+					{"id": "c1", "operationType": "replace", "resourceType": "document", "content": content(CosmosItem{ID: "k1", PartitionKey: "p1", Value: map[string]any{"n": 1}, Etag: "e1"})},
+					{"id": "sp", "operationType": "create", "resourceType": "storedProcedure"},
+				}}
+			} else {
+				assert.Equal(t, "page2", r.Header.Get("x-ms-continuation"))
+				res = map[string]any{"Conflicts": []map[string]any{
+					{"id": "c2", "operationType": "delete", "resourceType": "document", "content": content(CosmosItem{ID: "k2", PartitionKey: "k2", Etag: "e2"})},
+				}}
+			}
+			json.NewEncoder(w).Encode(res)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/dbs/db/colls/coll/conflicts/"):
+			verifyAuth(t, r, strings.TrimPrefix(r.URL.Path, "/"))
+			assert.Equal(t, `["p1"]`, r.Header.Get("x-ms-documentdb-partitionkey"))
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/dbs/db/colls/coll/conflicts/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	feed, err := newConflictsFeed(metadata{
+		URL:        srv.URL + "/",
+		MasterKey:  base64.StdEncoding.EncodeToString(key),
+		Database:   "db",
+		Collection: "coll",
+	}, nil, logger.NewLogger("test"))
+	require.NoError(t, err)
+	store := &StateStore{conflicts: feed}
+
+	conflicts, err := store.GetConflicts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, Conflict{ID: "c1", OperationType: "replace", Key: "k1", PartitionKey: "p1", Value: []byte(`{"n":1}`), ETag: "e1"}, conflicts[0])
+	assert.Equal(t, Conflict{ID: "c2", OperationType: "delete", Key: "k2", PartitionKey: "k2", ETag: "e2"}, conflicts[1])
+
+	err = store.DeleteConflict(context.Background(), conflicts[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c1"}, deleted)
+
+	err = store.DeleteConflict(context.Background(), Conflict{})
+	require.Error(t, err)
+}
@@ -43,6 +43,7 @@ import (
 // StateStore is a CosmosDB state store.
 type StateStore struct {
 	client      *azcosmos.ContainerClient
+	conflicts   *conflictsFeed
 	metadata    metadata
 	contentType string
 	logger      logger.Logger
@@ -54,6 +55,10 @@ type metadata struct {
 	Database    string `json:"database"`
 	Collection  string `json:"collection"`
 	ContentType string `json:"contentType"`
+
+	ConflictResolutionMode      string `json:"conflictResolutionMode"`
+	ConflictResolutionPath      string `json:"conflictResolutionPath"`
+	ConflictResolutionProcedure string `json:"conflictResolutionProcedure"`
 }
 
 type cosmosOperationType string
@@ -132,6 +137,10 @@ func (c *StateStore) Init(ctx context.Context, meta state.Metadata) error {
 	if m.ContentType == "" {
 		return errors.New("contentType is required")
 	}
+	conflictPolicy, err := parseConflictResolutionPolicy(m)
+	if err != nil {
+		return err
+	}
 
 	// Internal query policy was created due to lack of cross partition query capability in the current Go sdk
 	opts := azcosmos.ClientOptions{
@@ -146,7 +155,10 @@ func (c *StateStore) Init(ctx context.Context, meta state.Metadata) error {
 	}
 
 	// Create the client; first, try authenticating with a master key, if present
-	var client *azcosmos.Client
+	var (
+		client *azcosmos.Client
+		token  azcore.TokenCredential
+	)
 	if m.MasterKey != "" {
 		var cred azcosmos.KeyCredential
 		cred, err := azcosmos.NewKeyCredential(m.MasterKey)
@@ -164,9 +176,9 @@ func (c *StateStore) Init(ctx context.Context, meta state.Metadata) error {
 		if err != nil {
 			return err
 		}
-		token, tokenErr := env.GetTokenCredential()
-		if tokenErr != nil {
-			return tokenErr
+		token, err = env.GetTokenCredential()
+		if err != nil {
+			return err
 		}
 		client, err = azcosmos.NewClient(m.URL, token, &opts)
 		if err != nil {
@@ -188,6 +200,15 @@ func (c *StateStore) Init(ctx context.Context, meta state.Metadata) error {
 	c.metadata = m
 	c.contentType = m.ContentType
 
+	c.conflicts, err = newConflictsFeed(m, token, c.logger)
+	if err != nil {
+		return err
+	}
+
+	if conflictPolicy != nil {
+		return c.ensureContainer(ctx, dbClient, conflictPolicy)
+	}
+
 	readCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 	_, err = c.client.Read(readCtx, nil)
@@ -28,6 +28,9 @@ type BulkGetOpts struct {
 
 // BulkStore is an interface to perform bulk operations on store.
 type BulkStore interface {
+	// BulkGet returns a response for each request, in the same order.
+	// Keys that can't be read, for example because their value can't be decrypted or deserialized, have the Error field of their response set, while the other keys are still returned, so callers can retry only the failed keys.
+	// An error is returned only if the whole request fails.
 	BulkGet(ctx context.Context, req []GetRequest, opts BulkGetOpts) ([]BulkGetResponse, error)
 	BulkDelete(ctx context.Context, req []DeleteRequest) error
 	BulkSet(ctx context.Context, req []SetRequest) error
@@ -86,6 +89,16 @@ func (b *DefaultBulkStore) BulkGet(ctx context.Context, req []GetRequest, opts B
 	return res, nil
 }
 
+// NewBulkGetResponses returns a response for each request, in the same order, with only the key set.
+// It's used by stores that implement BulkGet natively, which fill in the responses of the keys they read.
+func NewBulkGetResponses(req []GetRequest) []BulkGetResponse {
+	res := make([]BulkGetResponse, len(req))
+	for i := range req {
+		res[i].Key = req[i].Key
+	}
+	return res
+}
+
 // BulkSet performs a bulk save operation.
 func (b *DefaultBulkStore) BulkSet(ctx context.Context, req []SetRequest) error {
 	// Check if the base implementation supports transactions
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// BulkSet saves the values of the requests concurrently, up to the configured limit.
+// Requests for the same partition are written together in an unlogged batch, while requests for different partitions are written individually, as batches spanning multiple partitions put a burden on the coordinator.
+// The error returned contains a state.BulkStoreError for each key that could not be saved, so callers can retry only those keys.
+func (c *Cassandra) BulkSet(ctx context.Context, req []state.SetRequest) error {
+	groups := groupByPartition(req, func(r state.SetRequest) string { return r.Key })
+	return bulkWrite(ctx, c.bulkConcurrency, groups, func(r state.SetRequest) string { return r.Key }, func(ctx context.Context, group []state.SetRequest) error {
+		if len(group) == 1 {
+			return c.Set(ctx, &group[0])
+		}
+		batch := c.newBatch(ctx, group[0].Options.Consistency)
+		// Statements in a batch share the same timestamp by default, so they're given increasing ones for the last request to win
+		ts := time.Now().UnixMicro()
+		for i := range group {
+			stmt, values, err := c.insertQuery(&group[i], ts+int64(i))
+			if err != nil {
+				return err
+			}
+			batch.Entries = append(batch.Entries, gocql.BatchEntry{Stmt: stmt, Args: values, Idempotent: true})
+		}
+		return c.session.ExecuteBatch(batch)
+	})
+}
+
+// BulkDelete deletes the keys of the requests concurrently, up to the configured limit, in the same way as BulkSet.
+func (c *Cassandra) BulkDelete(ctx context.Context, req []state.DeleteRequest) error {
+	groups := groupByPartition(req, func(r state.DeleteRequest) string { return r.Key })
+	return bulkWrite(ctx, c.bulkConcurrency, groups, func(r state.DeleteRequest) string { return r.Key }, func(ctx context.Context, group []state.DeleteRequest) error {
+		if len(group) == 1 {
+			return c.Delete(ctx, &group[0])
+		}
+		batch := c.newBatch(ctx, group[0].Options.Consistency)
+		ts := time.Now().UnixMicro()
+		for i := range group {
+			batch.Entries = append(batch.Entries, gocql.BatchEntry{Stmt: c.statements.deleteWithTimestamp, Args: []any{ts + int64(i), group[i].Key}, Idempotent: true})
+		}
+		return c.session.ExecuteBatch(batch)
+	})
+}
+
+// newBatch returns an unlogged batch with the consistency level used by Set for the consistency option of the requests.
+func (c *Cassandra) newBatch(ctx context.Context, consistency string) *gocql.Batch {
+	batch := c.session.NewBatch(gocql.UnloggedBatch).WithContext(ctx)
+	if c.speculativeExecution != nil {
+		// Batches have increasing timestamps set by the client, so they're idempotent too
+		batch.SpeculativeExecutionPolicy(c.speculativeExecution)
+	}
+	switch consistency {
+	case state.Strong:
+		batch.SetConsistency(gocql.Quorum)
+	case state.Eventual:
+		batch.SetConsistency(gocql.Any)
+	}
+	return batch
+}
+
+// partitionKey returns the partition key of the row of key.
+// The key column is the whole primary key of the table, so each key is in its own partition.
+func partitionKey(key string) string {
+	return key
+}
+
+// groupByPartition groups the requests by the partition of their key, keeping their order.
+func groupByPartition[T any](req []T, key func(T) string) [][]T {
+	groups := make([][]T, 0, len(req))
+	idx := make(map[string]int, len(req))
+	for _, r := range req {
+		p := partitionKey(key(r))
+		i, ok := idx[p]
+		if !ok {
+			i = len(groups)
+			idx[p] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups
+}
+
+// bulkWrite writes the groups of requests concurrently, with at most concurrency writes in progress.
+// It returns a state.BulkStoreError for each key of the groups that failed.
+func bulkWrite[T any](ctx context.Context, concurrency int, groups [][]T, key func(T) string, write func(ctx context.Context, group []T) error) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	limitCh := make(chan struct{}, concurrency)
+	for _, group := range groups {
+		// Limit concurrency
+		limitCh <- struct{}{}
+		wg.Add(1)
+		go func(group []T) {
+			defer func() {
+				<-limitCh
+				wg.Done()
+			}()
+
+			err := write(ctx, group)
+			if err == nil {
+				return
+			}
+			seen := make(map[string]struct{}, len(group))
+			lock.Lock()
+			defer lock.Unlock()
+			for _, r := range group {
+				k := key(r)
+				if _, ok := seen[k]; ok {
+					continue
+				}
+				seen[k] = struct{}{}
+				errs = append(errs, state.NewBulkStoreError(k, err))
+			}
+		}(group)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestGroupByPartition(t *testing.T) {
+	req := []state.SetRequest{
+		{Key: "a", Value: 1},
+		{Key: "b", Value: 2},
+		{Key: "a", Value: 3},
+		{Key: "c", Value: 4},
+	}
+	groups := groupByPartition(req, func(r state.SetRequest) string { return r.Key })
+	require.Len(t, groups, 3)
+	assert.Equal(t, []state.SetRequest{{Key: "a", Value: 1}, {Key: "a", Value: 3}}, groups[0])
+	assert.Equal(t, []state.SetRequest{{Key: "b", Value: 2}}, groups[1])
+	assert.Equal(t, []state.SetRequest{{Key: "c", Value: 4}}, groups[2])
+}
+
+func TestBulkWrite(t *testing.T) {
+	key := func(r state.DeleteRequest) string { return r.Key }
+
+	t.Run("limits concurrency", func(t *testing.T) {
+		var inProgress, maxInProgress, written atomic.Int32
+		groups := groupByPartition([]state.DeleteRequest{{Key: "a"}, {Key: "b"}, {Key: "c"}, {Key: "d"}, {Key: "e"}}, key)
+		err := bulkWrite(context.Background(), 2, groups, key, func(ctx context.Context, group []state.DeleteRequest) error {
+			n := inProgress.Add(1)
+			for {
+				m := maxInProgress.Load()
+				if n <= m || maxInProgress.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			inProgress.Add(-1)
+			written.Add(int32(len(group)))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(5), written.Load())
+		assert.LessOrEqual(t, maxInProgress.Load(), int32(2))
+	})
+
+	t.Run("reports failed keys", func(t *testing.T) {
+		groups := groupByPartition([]state.DeleteRequest{{Key: "a"}, {Key: "b"}, {Key: "a"}, {Key: "c"}}, key)
+		cause := errors.New("write timeout")
+		err := bulkWrite(context.Background(), 10, groups, key, func(ctx context.Context, group []state.DeleteRequest) error {
+			if group[0].Key == "b" {
+				return nil
+			}
+			return cause
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, cause)
+		assert.ElementsMatch(t, []string{"a", "c"}, state.BulkStoreErrorKeys(err))
+	})
+}
+
+func TestInsertQuery(t *testing.T) {
+	c := &Cassandra{statements: newStatements("dapr.items")}
+
+	stmt, values, err := c.insertQuery(&state.SetRequest{Key: "k", Value: []byte("v")}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO dapr.items (key, value) VALUES (?, ?)", stmt)
+	assert.Equal(t, []any{"k", []byte("v")}, values)
+
+	stmt, values, err = c.insertQuery(&state.SetRequest{Key: "k", Value: []byte("v"), Metadata: map[string]string{metadataTTLKey: "60"}}, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO dapr.items (key, value) VALUES (?, ?) USING TTL ? AND TIMESTAMP ?", stmt)
+	assert.Equal(t, []any{"k", []byte("v"), 60, int64(1000)}, values)
+
+	_, _, err = c.insertQuery(&state.SetRequest{Key: "k", Metadata: map[string]string{metadataTTLKey: "x"}}, 0)
+	require.Error(t, err)
+}
+
+func TestInsertQueryTTL(t *testing.T) {
+	c := &Cassandra{statements: newStatements("dapr.items")}
+
+	t.Run("row expires together", func(t *testing.T) {
+		stmt, values, err := c.insertQuery(&state.SetRequest{Key: "k", Value: []byte("v"), Metadata: map[string]string{metadataTTLKey: "30"}}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "INSERT INTO dapr.items (key, value) VALUES (?, ?) USING TTL ?", stmt)
+		assert.Equal(t, []any{"k", []byte("v"), 30}, values)
+	})
+
+	for _, ttl := range []string{"-1", "0", ""} {
+		t.Run("no TTL clears the previous one "+ttl, func(t *testing.T) {
+			stmt, values, err := c.insertQuery(&state.SetRequest{Key: "k", Value: []byte("v"), Metadata: map[string]string{metadataTTLKey: ttl}}, 0)
+			require.NoError(t, err)
+			assert.Equal(t, "INSERT INTO dapr.items (key, value) VALUES (?, ?)", stmt)
+			assert.Equal(t, []any{"k", []byte("v")}, values)
+		})
+	}
+
+	t.Run("too long", func(t *testing.T) {
+		_, _, err := c.insertQuery(&state.SetRequest{Key: "k", Metadata: map[string]string{metadataTTLKey: "630720001"}}, 0)
+		require.ErrorContains(t, err, "TTL must not be greater than 630720000 seconds")
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gocql/gocql"
 	jsoniter "github.com/json-iterator/go"
@@ -44,16 +46,26 @@ const (
 	defaultTable             = "items"
 	defaultKeyspace          = "dapr"
 	defaultPort              = 9042
+	defaultBulkConcurrency   = 10
 	metadataTTLKey           = "ttlInSeconds"
+	// Maximum TTL accepted by Cassandra, which is 20 years
+	maxTTL = 630720000
+
+	defaultSpeculativeExecutionDelay = 100 * time.Millisecond
 )
 
 // Cassandra is a state store implementation for Apache Cassandra.
 type Cassandra struct {
 	state.BulkStore
 
-	session *gocql.Session
-	cluster *gocql.ClusterConfig
-	table   string
+	session    *gocql.Session
+	table      string
+	statements statements
+	// If set, queries are sent to more hosts when the first one is slow to answer
+	speculativeExecution gocql.SpeculativeExecutionPolicy
+
+	// Maximum number of writes of bulk operations that are performed in parallel
+	bulkConcurrency int
 
 	logger logger.Logger
 }
@@ -68,6 +80,30 @@ type cassandraMetadata struct {
 	Consistency       string
 	Table             string
 	Keyspace          string
+	BulkConcurrency   int
+	// Datacenter whose hosts are preferred as coordinators
+	LocalDatacenter string
+	// Number of additional hosts a query is sent to when the previous one doesn't answer within SpeculativeExecutionDelay
+	SpeculativeExecutionAttempts int
+	SpeculativeExecutionDelay    time.Duration
+}
+
+// statements contains the CQL statements used by the store.
+// They are built once, so the driver prepares each of them only the first time it's executed on a host, and then reuses it.
+type statements struct {
+	get                 string
+	insert              string
+	delete              string
+	deleteWithTimestamp string
+}
+
+func newStatements(table string) statements {
+	return statements{
+		get:                 fmt.Sprintf("SELECT value FROM %s WHERE key = ?", table),
+		insert:              fmt.Sprintf("INSERT INTO %s (key, value) VALUES (?, ?)", table),
+		delete:              fmt.Sprintf("DELETE FROM %s WHERE key = ?", table),
+		deleteWithTimestamp: fmt.Sprintf("DELETE FROM %s USING TIMESTAMP ? WHERE key = ?", table),
+	}
 }
 
 // NewCassandraStateStore returns a new cassandra state store.
@@ -90,7 +126,6 @@ func (c *Cassandra) Init(_ context.Context, metadata state.Metadata) error {
 	if err != nil {
 		return fmt.Errorf("error creating cluster config: %w", err)
 	}
-	c.cluster = cluster
 
 	session, err := cluster.CreateSession()
 	if err != nil {
@@ -109,13 +144,21 @@ func (c *Cassandra) Init(_ context.Context, metadata state.Metadata) error {
 	}
 
 	c.table = meta.Keyspace + "." + meta.Table
+	c.statements = newStatements(c.table)
+	c.bulkConcurrency = meta.BulkConcurrency
+	if meta.SpeculativeExecutionAttempts > 0 {
+		c.speculativeExecution = &gocql.SimpleSpeculativeExecution{
+			NumAttempts:  meta.SpeculativeExecutionAttempts,
+			TimeoutDelay: meta.SpeculativeExecutionDelay,
+		}
+	}
 
 	return nil
 }
 
 // Features returns the features available in this state store.
 func (c *Cassandra) Features() []state.Feature {
-	return nil
+	return []state.Feature{state.FeatureTTL}
 }
 
 func (c *Cassandra) tryCreateKeyspace(keyspace string, replicationFactor int) error {
@@ -140,6 +183,13 @@ func (c *Cassandra) createClusterConfig(metadata *cassandraMetadata) (*gocql.Clu
 
 	clusterConfig.Consistency = cons
 
+	// Queries are routed to a replica of the key, preferring the hosts in the local datacenter if it's set
+	fallback := gocql.RoundRobinHostPolicy()
+	if metadata.LocalDatacenter != "" {
+		fallback = gocql.DCAwareRoundRobinPolicy(metadata.LocalDatacenter)
+	}
+	clusterConfig.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(fallback)
+
 	return clusterConfig, nil
 }
 
@@ -178,6 +228,9 @@ func getCassandraMetadata(meta state.Metadata) (*cassandraMetadata, error) {
 		ReplicationFactor: defaultReplicationFactor,
 		Consistency:       "All",
 		Port:              defaultPort,
+		BulkConcurrency:   defaultBulkConcurrency,
+
+		SpeculativeExecutionDelay: defaultSpeculativeExecutionDelay,
 	}
 	err := metadata.DecodeMetadata(meta.Properties, &m)
 	if err != nil {
@@ -212,35 +265,35 @@ func getCassandraMetadata(meta state.Metadata) (*cassandraMetadata, error) {
 		m.ReplicationFactor = int(r)
 	}
 
+	if m.BulkConcurrency <= 0 {
+		return nil, fmt.Errorf("bulkConcurrency must be greater than 0")
+	}
+
+	if m.SpeculativeExecutionAttempts < 0 {
+		return nil, fmt.Errorf("speculativeExecutionAttempts must not be negative")
+	}
+	if m.SpeculativeExecutionAttempts > 0 && m.SpeculativeExecutionDelay <= 0 {
+		return nil, fmt.Errorf("speculativeExecutionDelay must be greater than 0")
+	}
+
 	return &m, nil
 }
 
 // Delete performs a delete operation.
 func (c *Cassandra) Delete(ctx context.Context, req *state.DeleteRequest) error {
-	return c.session.Query(fmt.Sprintf("DELETE FROM %s WHERE key = ?", c.table), req.Key).WithContext(ctx).Exec()
+	return c.query(ctx, c.statements.delete, req.Key).Exec()
 }
 
 // Get retrieves state from cassandra with a key.
 func (c *Cassandra) Get(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
-	session := c.session
-
+	q := c.query(ctx, c.statements.get, req.Key)
 	if req.Options.Consistency == state.Strong {
-		sess, err := c.createSession(gocql.All)
-		if err != nil {
-			return nil, err
-		}
-		defer sess.Close()
-		session = sess
+		q.Consistency(gocql.All)
 	} else if req.Options.Consistency == state.Eventual {
-		sess, err := c.createSession(gocql.One)
-		if err != nil {
-			return nil, err
-		}
-		defer sess.Close()
-		session = sess
+		q.Consistency(gocql.One)
 	}
 
-	results, err := session.Query(fmt.Sprintf("SELECT value FROM %s WHERE key = ?", c.table), req.Key).WithContext(ctx).Iter().SliceMap()
+	results, err := q.Iter().SliceMap()
 	if err != nil {
 		return nil, err
 	}
@@ -256,6 +309,36 @@ func (c *Cassandra) Get(ctx context.Context, req *state.GetRequest) (*state.GetR
 
 // Set saves state into cassandra.
 func (c *Cassandra) Set(ctx context.Context, req *state.SetRequest) error {
+	stmt, values, err := c.insertQuery(req, 0)
+	if err != nil {
+		return err
+	}
+
+	q := c.query(ctx, stmt, values...)
+	if req.Options.Consistency == state.Strong {
+		q.Consistency(gocql.Quorum)
+	} else if req.Options.Consistency == state.Eventual {
+		q.Consistency(gocql.Any)
+	}
+
+	return q.Exec()
+}
+
+// query returns a query for the statement, executed with the speculative execution policy, if any.
+// All the queries of the store are idempotent, as the timestamps of writes are set by the client, so they can be sent to more hosts.
+func (c *Cassandra) query(ctx context.Context, stmt string, values ...any) *gocql.Query {
+	q := c.session.Query(stmt, values...).WithContext(ctx).Idempotent(true)
+	if c.speculativeExecution != nil {
+		q.SetSpeculativeExecutionPolicy(c.speculativeExecution)
+	}
+	return q
+}
+
+// insertQuery returns the statement and its values to save the value of req.
+// If timestamp is not 0, it's used as the timestamp of the write, in microseconds.
+// TTLs apply to the cells written by a statement, so all the columns of the row are written together, and expire together.
+// When there's no TTL, or it's 0 or -1, the row is rewritten without a TTL, which clears the TTL set by a previous write.
+func (c *Cassandra) insertQuery(req *state.SetRequest, timestamp int64) (string, []any, error) {
 	var bt []byte
 	b, ok := req.Value.([]byte)
 	if ok {
@@ -264,45 +347,29 @@ func (c *Cassandra) Set(ctx context.Context, req *state.SetRequest) error {
 		bt, _ = jsoniter.ConfigFastest.Marshal(req.Value)
 	}
 
-	session := c.session
-
-	if req.Options.Consistency == state.Strong {
-		sess, err := c.createSession(gocql.Quorum)
-		if err != nil {
-			return err
-		}
-		defer sess.Close()
-		session = sess
-	} else if req.Options.Consistency == state.Eventual {
-		sess, err := c.createSession(gocql.Any)
-		if err != nil {
-			return err
-		}
-		defer sess.Close()
-		session = sess
-	}
-
 	ttl, err := stateutils.ParseTTL(req.Metadata)
 	if err != nil {
-		return fmt.Errorf("error parsing TTL from Metadata: %s", err)
+		return "", nil, fmt.Errorf("error parsing TTL from Metadata: %s", err)
 	}
-
-	if ttl != nil {
-		return session.Query(fmt.Sprintf("INSERT INTO %s (key, value) VALUES (?, ?) USING TTL ?", c.table), req.Key, bt, *ttl).WithContext(ctx).Exec()
+	if ttl != nil && *ttl > maxTTL {
+		return "", nil, fmt.Errorf("TTL must not be greater than %d seconds", maxTTL)
 	}
 
-	return session.Query(fmt.Sprintf("INSERT INTO %s (key, value) VALUES (?, ?)", c.table), req.Key, bt).WithContext(ctx).Exec()
-}
-
-func (c *Cassandra) createSession(consistency gocql.Consistency) (*gocql.Session, error) {
-	session, err := c.cluster.CreateSession()
-	if err != nil {
-		return nil, fmt.Errorf("error creating session: %s", err)
+	stmt := c.statements.insert
+	values := []any{req.Key, bt}
+	var using []string
+	if ttl != nil && *ttl > 0 {
+		using = append(using, "TTL ?")
+		values = append(values, *ttl)
 	}
-
-	session.SetConsistency(consistency)
-
-	return session, nil
+	if timestamp != 0 {
+		using = append(using, "TIMESTAMP ?")
+		values = append(values, timestamp)
+	}
+	if len(using) > 0 {
+		stmt += " USING " + strings.Join(using, " AND ")
+	}
+	return stmt, values, nil
 }
 
 func (c *Cassandra) GetComponentMetadata() map[string]string {
@@ -14,8 +14,10 @@ limitations under the License.
 package cassandra
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -23,6 +25,21 @@ import (
 	"github.com/dapr/components-contrib/state"
 )
 
+func TestCreateClusterConfig(t *testing.T) {
+	c := &Cassandra{}
+	for _, localDC := range []string{"", "dc1"} {
+		cluster, err := c.createClusterConfig(&cassandraMetadata{Hosts: []string{"127.0.0.1"}, LocalDatacenter: localDC})
+		assert.NoError(t, err)
+		// Queries are routed to the replicas of their key
+		assert.Equal(t, "*gocql.tokenAwareHostPolicy", fmt.Sprintf("%T", cluster.PoolConfig.HostSelectionPolicy))
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	c := NewCassandraStateStore(nil)
+	assert.True(t, state.FeatureTTL.IsPresent(c.Features()))
+}
+
 func TestGetCassandraMetadata(t *testing.T) {
 	t.Run("With defaults", func(t *testing.T) {
 		properties := map[string]string{
@@ -41,6 +58,7 @@ func TestGetCassandraMetadata(t *testing.T) {
 		assert.Equal(t, defaultReplicationFactor, metadata.ReplicationFactor)
 		assert.Equal(t, defaultTable, metadata.Table)
 		assert.Equal(t, defaultPort, metadata.Port)
+		assert.Equal(t, defaultBulkConcurrency, metadata.BulkConcurrency)
 	})
 
 	t.Run("With custom values", func(t *testing.T) {
@@ -92,6 +110,43 @@ func TestGetCassandraMetadata(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("Bulk concurrency", func(t *testing.T) {
+		m := state.Metadata{
+			Base: metadata.Base{Properties: map[string]string{hosts: "127.0.0.1", "bulkConcurrency": "50"}},
+		}
+		metadata, err := getCassandraMetadata(m)
+		assert.NoError(t, err)
+		assert.Equal(t, 50, metadata.BulkConcurrency)
+
+		m.Properties["bulkConcurrency"] = "0"
+		_, err = getCassandraMetadata(m)
+		assert.Error(t, err)
+	})
+
+	t.Run("Routing and speculative execution", func(t *testing.T) {
+		m := state.Metadata{
+			Base: metadata.Base{Properties: map[string]string{
+				hosts:                          "127.0.0.1",
+				"localDatacenter":              "dc1",
+				"speculativeExecutionAttempts": "2",
+				"speculativeExecutionDelay":    "20ms",
+			}},
+		}
+		metadata, err := getCassandraMetadata(m)
+		assert.NoError(t, err)
+		assert.Equal(t, "dc1", metadata.LocalDatacenter)
+		assert.Equal(t, 2, metadata.SpeculativeExecutionAttempts)
+		assert.Equal(t, 20*time.Millisecond, metadata.SpeculativeExecutionDelay)
+
+		m.Properties["speculativeExecutionDelay"] = "0"
+		_, err = getCassandraMetadata(m)
+		assert.Error(t, err)
+
+		m.Properties["speculativeExecutionAttempts"] = "-1"
+		_, err = getCassandraMetadata(m)
+		assert.Error(t, err)
+	})
+
 	t.Run("Missing hosts", func(t *testing.T) {
 		properties := map[string]string{
 			consistency:       "Quorum",
@@ -0,0 +1,615 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+	stateutils "github.com/dapr/components-contrib/state/utils"
+	"github.com/dapr/kit/logger"
+)
+
+// The ETag of a state is the "_version" of its document.
+// Conditional writes read the document, compare its version with the ETag, and write it only if its "_seq_no" and "_primary_term" haven't changed since, which is how Elasticsearch implements optimistic concurrency.
+// Documents are never updated in place, so the version of a document changes on every write.
+
+const (
+	defaultIndexName        = "dapr-state"
+	defaultTimeoutInSeconds = 20
+
+	// Fields of the documents.
+	fieldValue     = "value"
+	fieldData      = "data"
+	fieldIsBinary  = "isBinary"
+	fieldExpiresAt = "expiresAt"
+)
+
+// Elasticsearch is a state store backed by an Elasticsearch or OpenSearch index.
+type Elasticsearch struct {
+	state.BulkStore
+
+	metadata elasticsearchMetadata
+	baseURL  string
+	timeout  time.Duration
+	client   *http.Client
+	logger   logger.Logger
+}
+
+type elasticsearchMetadata struct {
+	// URL of the cluster, such as "https://localhost:9200".
+	URL string `mapstructure:"url"`
+	// Credentials for basic authentication.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// API key, used instead of basic authentication.
+	APIKey string `mapstructure:"apiKey"`
+	// Name of the index where the states are stored.
+	IndexName string `mapstructure:"indexName"`
+	// JSON mapping of the properties of the values, such as {"properties": {"title": {"type": "text"}}}.
+	IndexMapping string `mapstructure:"indexMapping"`
+	// Name of the index lifecycle policy of the index.
+	ILMPolicy string `mapstructure:"ilmPolicy"`
+	// Timeout, in seconds, of each operation.
+	TimeoutInSeconds int `mapstructure:"timeoutInSeconds"`
+}
+
+// document is the source of the document that contains a state.
+type document struct {
+	// Set only if the state is a JSON object, so its properties can be queried
+	Value json.RawMessage `json:"value,omitempty"`
+	// Value of the state, encoded as JSON
+	Data     string `json:"data"`
+	IsBinary bool   `json:"isBinary,omitempty"`
+	// Expiration time in milliseconds since the epoch, if the state has a TTL
+	ExpiresAt *int64 `json:"expiresAt,omitempty"`
+}
+
+// hit is a document returned by Elasticsearch.
+type hit struct {
+	ID          string   `json:"_id"`
+	Found       bool     `json:"found"`
+	Version     int64    `json:"_version"`
+	SeqNo       int64    `json:"_seq_no"`
+	PrimaryTerm int64    `json:"_primary_term"`
+	Source      document `json:"_source"`
+}
+
+// NewElasticsearchStateStore creates a new instance of the Elasticsearch state store.
+func NewElasticsearchStateStore(logger logger.Logger) state.Store {
+	s := &Elasticsearch{
+		client: &http.Client{},
+		logger: logger,
+	}
+	s.BulkStore = state.NewDefaultBulkStore(s)
+	return s
+}
+
+// Init parses the metadata and creates the index if it doesn't exist.
+func (s *Elasticsearch) Init(ctx context.Context, md state.Metadata) error {
+	err := s.parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+	return s.ensureIndex(ctx)
+}
+
+func (s *Elasticsearch) parseMetadata(md map[string]string) error {
+	s.metadata = elasticsearchMetadata{
+		IndexName:        defaultIndexName,
+		TimeoutInSeconds: defaultTimeoutInSeconds,
+	}
+	err := metadata.DecodeMetadata(md, &s.metadata)
+	if err != nil {
+		return err
+	}
+
+	if s.metadata.URL == "" {
+		return errors.New("missing 'url' metadata property")
+	}
+	u, err := url.Parse(s.metadata.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid value for 'url': %s", s.metadata.URL)
+	}
+	s.baseURL = strings.TrimSuffix(s.metadata.URL, "/")
+
+	// Index names must be lowercase and can't contain characters that need escaping in URLs
+	name := s.metadata.IndexName
+	if name == "" || name != strings.ToLower(name) || strings.ContainsAny(name, `\/*?"<>| ,#:`) || strings.HasPrefix(name, "_") || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid value for 'indexName': %s", name)
+	}
+	if s.metadata.IndexMapping != "" && !json.Valid([]byte(s.metadata.IndexMapping)) {
+		return errors.New("invalid value for 'indexMapping': must be a JSON object")
+	}
+	if s.metadata.TimeoutInSeconds <= 0 {
+		return errors.New("invalid value for 'timeoutInSeconds': must be greater than 0")
+	}
+	s.timeout = time.Duration(s.metadata.TimeoutInSeconds) * time.Second
+
+	return nil
+}
+
+// ensureIndex creates the index, with the mapping of the documents and the index lifecycle policy, if it doesn't exist.
+func (s *Elasticsearch) ensureIndex(parentCtx context.Context) error {
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+
+	status, _, err := s.do(ctx, http.MethodHead, "/"+s.metadata.IndexName, nil)
+	if err != nil {
+		return fmt.Errorf("failed to check if index '%s' exists: %w", s.metadata.IndexName, err)
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	valueMapping := map[string]any{"type": "object"}
+	if s.metadata.IndexMapping != "" {
+		err = json.Unmarshal([]byte(s.metadata.IndexMapping), &valueMapping)
+		if err != nil {
+			return fmt.Errorf("invalid value for 'indexMapping': %w", err)
+		}
+	}
+	body := map[string]any{
+		"mappings": map[string]any{
+			// Strings are mapped as keywords, so filters match exact values, with a "text" sub-field for full-text search
+			"dynamic_templates": []any{
+				map[string]any{
+					"strings": map[string]any{
+						"path_match":         fieldValue + ".*",
+						"match_mapping_type": "string",
+						"mapping": map[string]any{
+							"type":   "keyword",
+							"fields": map[string]any{"text": map[string]any{"type": "text"}},
+						},
+					},
+				},
+			},
+			"properties": map[string]any{
+				fieldValue:     valueMapping,
+				fieldData:      map[string]any{"type": "text", "index": false},
+				fieldIsBinary:  map[string]any{"type": "boolean", "index": false},
+				fieldExpiresAt: map[string]any{"type": "date", "format": "epoch_millis"},
+			},
+		},
+	}
+	if s.metadata.ILMPolicy != "" {
+		body["settings"] = map[string]any{"index.lifecycle.name": s.metadata.ILMPolicy}
+	}
+
+	s.logger.Infof("Creating Elasticsearch index '%s'", s.metadata.IndexName)
+	status, res, err := s.do(ctx, http.MethodPut, "/"+s.metadata.IndexName, body)
+	if err != nil {
+		return fmt.Errorf("failed to create index '%s': %w", s.metadata.IndexName, err)
+	}
+	// Another instance may have created the index in the meanwhile
+	if status >= 300 && !strings.Contains(string(res), "resource_already_exists_exception") {
+		return fmt.Errorf("failed to create index '%s': %w", s.metadata.IndexName, responseError(status, res))
+	}
+	return nil
+}
+
+// Features returns the features available in this state store.
+func (s *Elasticsearch) Features() []state.Feature {
+	return []state.Feature{state.FeatureETag, state.FeatureQueryAPI}
+}
+
+// Get returns a state from the store.
+func (s *Elasticsearch) Get(parentCtx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	if req.Key == "" {
+		return nil, errors.New("missing key in get operation")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	h, err := s.getDocument(ctx, req.Key)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return &state.GetResponse{}, nil
+	}
+
+	data, err := h.Source.decode()
+	if err != nil {
+		return nil, err
+	}
+	etag := strconv.FormatInt(h.Version, 10)
+	return &state.GetResponse{
+		Data:     data,
+		ETag:     &etag,
+		Metadata: req.Metadata,
+	}, nil
+}
+
+// getDocument returns the document of a state, or nil if it doesn't exist or it's expired.
+func (s *Elasticsearch) getDocument(ctx context.Context, key string) (*hit, error) {
+	status, res, err := s.do(ctx, http.MethodGet, s.docPath("_doc", key), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status >= 300 {
+		return nil, responseError(status, res)
+	}
+
+	var h hit
+	err = json.Unmarshal(res, &h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if !h.Found || h.Source.expired() {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+// BulkGet returns multiple states with a single request.
+func (s *Elasticsearch) BulkGet(parentCtx context.Context, req []state.GetRequest, _ state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	if len(req) == 0 {
+		return []state.BulkGetResponse{}, nil
+	}
+
+	ids := make([]string, len(req))
+	for i, r := range req {
+		ids[i] = r.Key
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	status, res, err := s.do(ctx, http.MethodPost, "/"+s.metadata.IndexName+"/_mget", map[string]any{"ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, responseError(status, res)
+	}
+
+	var mget struct {
+		Docs []hit `json:"docs"`
+	}
+	err = json.Unmarshal(res, &mget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse documents: %w", err)
+	}
+
+	// Documents are returned in the order of the IDs
+	result := make([]state.BulkGetResponse, len(req))
+	for i, r := range req {
+		result[i] = state.BulkGetResponse{Key: r.Key}
+		if i >= len(mget.Docs) || !mget.Docs[i].Found || mget.Docs[i].Source.expired() {
+			continue
+		}
+		h := mget.Docs[i]
+		data, err := h.Source.decode()
+		if err != nil {
+			result[i].Error = err.Error()
+			continue
+		}
+		etag := strconv.FormatInt(h.Version, 10)
+		result[i].Data = data
+		result[i].ETag = &etag
+	}
+	return result, nil
+}
+
+// Set saves a state in the store.
+func (s *Elasticsearch) Set(parentCtx context.Context, req *state.SetRequest) error {
+	err := state.CheckRequestOptions(req.Options)
+	if err != nil {
+		return err
+	}
+	if req.Key == "" {
+		return errors.New("missing key in set operation")
+	}
+
+	doc, err := newDocument(req)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+
+	if req.ETag != nil && *req.ETag != "" {
+		current, err := s.documentForETag(ctx, req.Key, *req.ETag)
+		if err != nil {
+			return err
+		}
+		return s.write(ctx, http.MethodPut, s.docPath("_doc", req.Key), current, doc)
+	}
+
+	if req.Options.Concurrency != state.FirstWrite {
+		return s.write(ctx, http.MethodPut, s.docPath("_doc", req.Key), nil, doc)
+	}
+
+	// With first-write-wins, the document is created only if it doesn't exist
+	status, res, err := s.do(ctx, http.MethodPut, s.docPath("_create", req.Key), doc)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusConflict {
+		if status >= 300 {
+			return responseError(status, res)
+		}
+		return nil
+	}
+	// The document exists, but it can be overwritten if it's expired
+	current, err := s.currentDocument(ctx, req.Key)
+	if err != nil {
+		return err
+	}
+	if current == nil || !current.Source.expired() {
+		return state.NewETagError(state.ETagMismatch, nil)
+	}
+	return s.write(ctx, http.MethodPut, s.docPath("_doc", req.Key), current, doc)
+}
+
+// Delete removes a state from the store.
+func (s *Elasticsearch) Delete(parentCtx context.Context, req *state.DeleteRequest) error {
+	if req.Key == "" {
+		return errors.New("missing key in delete operation")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+
+	var current *hit
+	if req.ETag != nil && *req.ETag != "" {
+		var err error
+		current, err = s.documentForETag(ctx, req.Key, *req.ETag)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.write(ctx, http.MethodDelete, s.docPath("_doc", req.Key), current, nil)
+	var respErr *elasticsearchError
+	if errors.As(err, &respErr) && respErr.status == http.StatusNotFound && current == nil {
+		// Deleting a state that doesn't exist is not an error
+		return nil
+	}
+	return err
+}
+
+// documentForETag returns the document of a state if its version matches etag, or an ETag error otherwise.
+func (s *Elasticsearch) documentForETag(ctx context.Context, key string, etag string) (*hit, error) {
+	version, err := strconv.ParseInt(etag, 10, 64)
+	if err != nil {
+		return nil, state.NewETagError(state.ETagInvalid, err)
+	}
+	current, err := s.getDocument(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.Version != version {
+		return nil, state.NewETagError(state.ETagMismatch, nil)
+	}
+	return current, nil
+}
+
+// currentDocument returns the document of a state, including if it's expired, or nil if it doesn't exist.
+func (s *Elasticsearch) currentDocument(ctx context.Context, key string) (*hit, error) {
+	status, res, err := s.do(ctx, http.MethodGet, s.docPath("_doc", key), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status >= 300 {
+		return nil, responseError(status, res)
+	}
+	var h hit
+	err = json.Unmarshal(res, &h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if !h.Found {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+// write indexes or deletes a document.
+// If current is not nil, the request succeeds only if the document wasn't modified since current was read.
+func (s *Elasticsearch) write(ctx context.Context, method string, path string, current *hit, body any) error {
+	if current != nil {
+		path += "?if_seq_no=" + strconv.FormatInt(current.SeqNo, 10) + "&if_primary_term=" + strconv.FormatInt(current.PrimaryTerm, 10)
+	}
+	status, res, err := s.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict && current != nil {
+		return state.NewETagError(state.ETagMismatch, responseError(status, res))
+	}
+	if status >= 300 {
+		return responseError(status, res)
+	}
+	return nil
+}
+
+// Query executes a query against the values of the states, which must be JSON objects.
+func (s *Elasticsearch) Query(parentCtx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
+	q := &Query{}
+	qbuilder := query.NewQueryBuilder(q)
+	if err := qbuilder.BuildQuery(&req.Query); err != nil {
+		return &state.QueryResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	data, token, err := q.execute(ctx, s)
+	if err != nil {
+		return &state.QueryResponse{}, err
+	}
+
+	return &state.QueryResponse{
+		Results: data,
+		Token:   token,
+	}, nil
+}
+
+// Close implements io.Closer.
+func (s *Elasticsearch) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (s *Elasticsearch) GetComponentMetadata() map[string]string {
+	metadataStruct := elasticsearchMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.StateStoreType)
+	return metadataInfo
+}
+
+// docPath returns the path of the API endpoint for a document.
+func (s *Elasticsearch) docPath(endpoint string, key string) string {
+	return "/" + s.metadata.IndexName + "/" + endpoint + "/" + url.PathEscape(key)
+}
+
+// do sends a request to Elasticsearch, with body encoded as JSON if it's not nil, and returns the status code and the body of the response.
+func (s *Elasticsearch) do(ctx context.Context, method string, path string, body any) (int, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reqBody)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case s.metadata.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.metadata.APIKey)
+	case s.metadata.Username != "":
+		req.SetBasicAuth(s.metadata.Username, s.metadata.Password)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBody, nil
+}
+
+// elasticsearchError is returned when Elasticsearch responds with an error.
+type elasticsearchError struct {
+	status int
+	reason string
+}
+
+func (e *elasticsearchError) Error() string {
+	return fmt.Sprintf("elasticsearch error (status %d): %s", e.status, e.reason)
+}
+
+func responseError(status int, body []byte) error {
+	var res struct {
+		Error struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	reason := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &res) == nil && res.Error.Type != "" {
+		reason = res.Error.Type + ": " + res.Error.Reason
+	}
+	return &elasticsearchError{status: status, reason: reason}
+}
+
+// newDocument returns the document that stores the value of a request.
+func newDocument(req *state.SetRequest) (*document, error) {
+	doc := &document{}
+
+	var v any
+	switch x := req.Value.(type) {
+	case []byte:
+		if json.Valid(x) {
+			v = json.RawMessage(x)
+		} else {
+			doc.IsBinary = true
+			v = base64.StdEncoding.EncodeToString(x)
+		}
+	default:
+		v = x
+	}
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	doc.Data = string(enc)
+	if !doc.IsBinary && bytes.HasPrefix(bytes.TrimSpace(enc), []byte("{")) {
+		doc.Value = enc
+	}
+
+	ttl, err := stateutils.ParseTTL(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing TTL: %w", err)
+	}
+	if ttl != nil && *ttl > 0 {
+		expiresAt := time.Now().Add(time.Duration(*ttl) * time.Second).UnixMilli()
+		doc.ExpiresAt = &expiresAt
+	}
+	return doc, nil
+}
+
+// decode returns the value of the state.
+func (d document) decode() ([]byte, error) {
+	if !d.IsBinary {
+		return []byte(d.Data), nil
+	}
+	var s string
+	err := json.Unmarshal([]byte(d.Data), &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON binary data: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode binary data: %w", err)
+	}
+	return data, nil
+}
+
+func (d document) expired() bool {
+	return d.ExpiresAt != nil && *d.ExpiresAt <= time.Now().UnixMilli()
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+)
+
+// Query translates Dapr queries to the Elasticsearch query DSL.
+type Query struct {
+	// Body of the search request
+	body map[string]any
+	from int
+	size int
+}
+
+func (q *Query) VisitEQ(f *query.EQ) (string, error) {
+	// { "term": { <key>: <val> } }
+	return marshalClause(map[string]any{
+		"term": map[string]any{fieldValue + "." + f.Key: f.Val},
+	})
+}
+
+func (q *Query) VisitIN(f *query.IN) (string, error) {
+	// { "terms": { <key>: [ <val1>, <val2>, ... , <valN> ] } }
+	if len(f.Vals) == 0 {
+		return "", fmt.Errorf("empty IN operator for key %q", f.Key)
+	}
+	return marshalClause(map[string]any{
+		"terms": map[string]any{fieldValue + "." + f.Key: f.Vals},
+	})
+}
+
+func (q *Query) VisitAND(f *query.AND) (string, error) {
+	// { "bool": { "filter": [ <expression1>, ... , <expressionN> ] } }
+	clauses, err := q.visitFilters(f.Filters)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`{"bool":{"filter":[%s]}}`, strings.Join(clauses, ",")), nil
+}
+
+func (q *Query) VisitOR(f *query.OR) (string, error) {
+	// { "bool": { "should": [ <expression1>, ... , <expressionN> ], "minimum_should_match": 1 } }
+	clauses, err := q.visitFilters(f.Filters)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`{"bool":{"should":[%s],"minimum_should_match":1}}`, strings.Join(clauses, ",")), nil
+}
+
+func (q *Query) visitFilters(filters []query.Filter) ([]string, error) {
+	clauses := make([]string, 0, len(filters))
+	for _, fil := range filters {
+		var (
+			str string
+			err error
+		)
+		switch f := fil.(type) {
+		case *query.EQ:
+			str, err = q.VisitEQ(f)
+		case *query.IN:
+			str, err = q.VisitIN(f)
+		case *query.AND:
+			str, err = q.VisitAND(f)
+		case *query.OR:
+			str, err = q.VisitOR(f)
+		default:
+			return nil, fmt.Errorf("unsupported filter type %#v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, str)
+	}
+	return clauses, nil
+}
+
+func (q *Query) Finalize(filters string, qq *query.Query) error {
+	// Expired documents are excluded from the results
+	boolQuery := map[string]any{
+		"must_not": []any{
+			map[string]any{"range": map[string]any{fieldExpiresAt: map[string]any{"lte": "now"}}},
+		},
+	}
+	if filters != "" {
+		boolQuery["filter"] = []any{json.RawMessage(filters)}
+	}
+	q.body = map[string]any{
+		"query":   map[string]any{"bool": boolQuery},
+		"version": true,
+	}
+
+	// sorting
+	if len(qq.Sort) > 0 {
+		sort := make([]any, len(qq.Sort))
+		for i, s := range qq.Sort {
+			order := "asc"
+			if s.Order == query.DESC {
+				order = "desc"
+			}
+			sort[i] = map[string]any{fieldValue + "." + s.Key: map[string]any{"order": order}}
+		}
+		q.body["sort"] = sort
+	}
+
+	// pagination
+	if qq.Page.Limit > 0 {
+		q.size = qq.Page.Limit
+		q.body["size"] = q.size
+	}
+	if len(qq.Page.Token) != 0 {
+		from, err := strconv.Atoi(qq.Page.Token)
+		if err != nil || from < 0 {
+			return fmt.Errorf("invalid pagination token: %s", qq.Page.Token)
+		}
+		q.from = from
+		q.body["from"] = from
+	}
+
+	return nil
+}
+
+func (q *Query) execute(ctx context.Context, s *Elasticsearch) ([]state.QueryItem, string, error) {
+	status, res, err := s.do(ctx, http.MethodPost, "/"+s.metadata.IndexName+"/_search", q.body)
+	if err != nil {
+		return nil, "", err
+	}
+	if status >= 300 {
+		return nil, "", responseError(status, res)
+	}
+
+	var search struct {
+		Hits struct {
+			Hits []hit `json:"hits"`
+		} `json:"hits"`
+	}
+	err = json.Unmarshal(res, &search)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	ret := make([]state.QueryItem, len(search.Hits.Hits))
+	for i, h := range search.Hits.Hits {
+		etag := strconv.FormatInt(h.Version, 10)
+		ret[i] = state.QueryItem{
+			Key:  h.ID,
+			ETag: &etag,
+		}
+		ret[i].Data, err = h.Source.decode()
+		if err != nil {
+			ret[i].Error = err.Error()
+		}
+	}
+
+	// set next query token only if limit is specified
+	var token string
+	if q.size > 0 {
+		token = strconv.Itoa(q.from + len(ret))
+	}
+	return ret, token, nil
+}
+
+func marshalClause(clause map[string]any) (string, error) {
+	b, err := json.Marshal(clause)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
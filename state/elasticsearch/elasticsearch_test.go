@@ -0,0 +1,363 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+// fakeElasticsearch implements the subset of the Elasticsearch API used by the state store.
+type fakeElasticsearch struct {
+	lock         sync.Mutex
+	indexCreated map[string]any
+	docs         map[string]*hit
+	seqNo        int64
+	lastSearch   map[string]any
+	authHeader   string
+}
+
+func newFakeElasticsearch(t *testing.T) (*fakeElasticsearch, *httptest.Server) {
+	t.Helper()
+
+	f := &fakeElasticsearch{docs: map[string]*hit{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.authHeader = r.Header.Get("Authorization")
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	reply := func(status int, res any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(res)
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodHead:
+		if f.indexCreated == nil {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		_ = json.Unmarshal(body, &f.indexCreated)
+		reply(http.StatusOK, map[string]any{"acknowledged": true})
+	case len(parts) == 2 && parts[1] == "_mget":
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		_ = json.Unmarshal(body, &req)
+		docs := make([]any, len(req.IDs))
+		for i, id := range req.IDs {
+			if d, ok := f.docs[id]; ok {
+				docs[i] = d.response()
+			} else {
+				docs[i] = map[string]any{"_id": id, "found": false}
+			}
+		}
+		reply(http.StatusOK, map[string]any{"docs": docs})
+	case len(parts) == 2 && parts[1] == "_search":
+		f.lastSearch = nil
+		_ = json.Unmarshal(body, &f.lastSearch)
+		hits := []any{}
+		for _, d := range f.docs {
+			hits = append(hits, d.response())
+		}
+		reply(http.StatusOK, map[string]any{"hits": map[string]any{"hits": hits}})
+	case len(parts) == 3:
+		id, _ := url.PathUnescape(parts[2])
+		current := f.docs[id]
+		if r.URL.Query().Has("if_seq_no") {
+			seqNo, _ := strconv.ParseInt(r.URL.Query().Get("if_seq_no"), 10, 64)
+			if current == nil || current.SeqNo != seqNo {
+				reply(http.StatusConflict, map[string]any{"error": map[string]any{"type": "version_conflict_engine_exception", "reason": "conflict"}})
+				return
+			}
+		}
+		switch {
+		case r.Method == http.MethodGet:
+			if current == nil {
+				reply(http.StatusNotFound, map[string]any{"_id": id, "found": false})
+				return
+			}
+			reply(http.StatusOK, current.response())
+		case r.Method == http.MethodDelete:
+			if current == nil {
+				reply(http.StatusNotFound, map[string]any{"result": "not_found"})
+				return
+			}
+			delete(f.docs, id)
+			reply(http.StatusOK, map[string]any{"result": "deleted"})
+		case parts[1] == "_create" && current != nil:
+			reply(http.StatusConflict, map[string]any{"error": map[string]any{"type": "version_conflict_engine_exception", "reason": "exists"}})
+		default:
+			var doc document
+			_ = json.Unmarshal(body, &doc)
+			f.seqNo++
+			h := &hit{ID: id, Found: true, Version: 1, SeqNo: f.seqNo, PrimaryTerm: 1, Source: doc}
+			if current != nil {
+				h.Version = current.Version + 1
+			}
+			f.docs[id] = h
+			reply(http.StatusOK, map[string]any{"result": "updated"})
+		}
+	default:
+		reply(http.StatusBadRequest, map[string]any{"error": map[string]any{"type": "illegal_argument_exception", "reason": r.URL.Path}})
+	}
+}
+
+func (h *hit) response() map[string]any {
+	return map[string]any{
+		"_id":           h.ID,
+		"found":         true,
+		"_version":      h.Version,
+		"_seq_no":       h.SeqNo,
+		"_primary_term": h.PrimaryTerm,
+		"_source":       h.Source,
+	}
+}
+
+func initStore(t *testing.T, props map[string]string) (*Elasticsearch, *fakeElasticsearch) {
+	t.Helper()
+
+	f, srv := newFakeElasticsearch(t)
+	s := NewElasticsearchStateStore(logger.NewLogger("test")).(*Elasticsearch)
+	md := map[string]string{"url": srv.URL}
+	for k, v := range props {
+		md[k] = v
+	}
+	require.NoError(t, s.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: md}}))
+	return s, f
+}
+
+func TestParseMetadata(t *testing.T) {
+	s := NewElasticsearchStateStore(logger.NewLogger("test")).(*Elasticsearch)
+	require.NoError(t, s.parseMetadata(map[string]string{"url": "https://localhost:9200/"}))
+	assert.Equal(t, "https://localhost:9200", s.baseURL)
+	assert.Equal(t, defaultIndexName, s.metadata.IndexName)
+	assert.Equal(t, 20*time.Second, s.timeout)
+
+	invalid := map[string]map[string]string{
+		"missing url":      {},
+		"invalid url":      {"url": "localhost"},
+		"uppercase index":  {"url": "http://localhost:9200", "indexName": "State"},
+		"invalid index":    {"url": "http://localhost:9200", "indexName": "a/b"},
+		"invalid mapping":  {"url": "http://localhost:9200", "indexMapping": "{"},
+		"negative timeout": {"url": "http://localhost:9200", "timeoutInSeconds": "-1"},
+	}
+	for name, md := range invalid {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, s.parseMetadata(md))
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	t.Run("creates the index with the mapping and lifecycle policy", func(t *testing.T) {
+		_, f := initStore(t, map[string]string{
+			"indexMapping": `{"properties":{"title":{"type":"text"}}}`,
+			"ilmPolicy":    "dapr-state-policy",
+			"apiKey":       "key",
+		})
+		require.NotNil(t, f.indexCreated)
+		assert.Equal(t, "ApiKey key", f.authHeader)
+		assert.Equal(t, map[string]any{"index.lifecycle.name": "dapr-state-policy"}, f.indexCreated["settings"])
+		props := f.indexCreated["mappings"].(map[string]any)["properties"].(map[string]any)
+		assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{"title": map[string]any{"type": "text"}}}, props[fieldValue])
+		assert.Contains(t, props, fieldExpiresAt)
+	})
+
+	t.Run("existing index is not modified", func(t *testing.T) {
+		f, srv := newFakeElasticsearch(t)
+		f.indexCreated = map[string]any{"existing": true}
+		s := NewElasticsearchStateStore(logger.NewLogger("test"))
+		require.NoError(t, s.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{"url": srv.URL}}}))
+		assert.Equal(t, map[string]any{"existing": true}, f.indexCreated)
+	})
+}
+
+func TestCRUD(t *testing.T) {
+	s, _ := initStore(t, nil)
+	ctx := context.Background()
+
+	res, err := s.Get(ctx, &state.GetRequest{Key: "missing"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "a/b", Value: []byte(`{"name":"dapr"}`)}))
+	res, err = s.Get(ctx, &state.GetRequest{Key: "a/b"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"dapr"}`, string(res.Data))
+	assert.Equal(t, "1", *res.ETag)
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "bin", Value: []byte{0xff, 0x00}}))
+	res, err = s.Get(ctx, &state.GetRequest{Key: "bin"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, res.Data)
+
+	require.NoError(t, s.Delete(ctx, &state.DeleteRequest{Key: "a/b"}))
+	require.NoError(t, s.Delete(ctx, &state.DeleteRequest{Key: "a/b"}))
+	res, err = s.Get(ctx, &state.GetRequest{Key: "a/b"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+}
+
+func TestETag(t *testing.T) {
+	s, _ := initStore(t, nil)
+	ctx := context.Background()
+	assertETagError := func(t *testing.T, err error, kind state.ETagErrorKind) {
+		t.Helper()
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr), err)
+		assert.Equal(t, kind, etagErr.Kind())
+	}
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: "v1"}))
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: "v2", ETag: ptr.Of("1")}))
+	assertETagError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: "v3", ETag: ptr.Of("1")}), state.ETagMismatch)
+	assertETagError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: "v3", ETag: ptr.Of("x")}), state.ETagInvalid)
+	assertETagError(t, s.Set(ctx, &state.SetRequest{Key: "other", Value: "v", ETag: ptr.Of("1")}), state.ETagMismatch)
+
+	res, err := s.Get(ctx, &state.GetRequest{Key: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v2"`, string(res.Data))
+	assert.Equal(t, "2", *res.ETag)
+
+	assertETagError(t, s.Delete(ctx, &state.DeleteRequest{Key: "k", ETag: ptr.Of("1")}), state.ETagMismatch)
+	require.NoError(t, s.Delete(ctx, &state.DeleteRequest{Key: "k", ETag: ptr.Of("2")}))
+
+	t.Run("first write", func(t *testing.T) {
+		firstWrite := state.SetStateOption{Concurrency: state.FirstWrite}
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "fw", Value: "v1", Options: firstWrite}))
+		assertETagError(t, s.Set(ctx, &state.SetRequest{Key: "fw", Value: "v2", Options: firstWrite}), state.ETagMismatch)
+	})
+}
+
+func TestTTL(t *testing.T) {
+	s, f := initStore(t, nil)
+	ctx := context.Background()
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: "v", Metadata: map[string]string{"ttlInSeconds": "100"}}))
+	res, err := s.Get(ctx, &state.GetRequest{Key: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v"`, string(res.Data))
+
+	// Expire the document
+	f.lock.Lock()
+	f.docs["k"].Source.ExpiresAt = ptr.Of(time.Now().Add(-time.Second).UnixMilli())
+	f.lock.Unlock()
+
+	res, err = s.Get(ctx, &state.GetRequest{Key: "k"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+
+	bulk, err := s.BulkGet(ctx, []state.GetRequest{{Key: "k"}}, state.BulkGetOpts{})
+	require.NoError(t, err)
+	assert.Nil(t, bulk[0].Data)
+
+	// Expired documents can be overwritten with first-write-wins
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: "new", Options: state.SetStateOption{Concurrency: state.FirstWrite}}))
+	res, err = s.Get(ctx, &state.GetRequest{Key: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, `"new"`, string(res.Data))
+}
+
+func TestBulkGet(t *testing.T) {
+	s, _ := initStore(t, nil)
+	ctx := context.Background()
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "b", Value: "x"}))
+	res, err := s.BulkGet(ctx, []state.GetRequest{{Key: "a"}, {Key: "b"}}, state.BulkGetOpts{})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "a", res[0].Key)
+	assert.Nil(t, res[0].Data)
+	assert.Equal(t, "b", res[1].Key)
+	assert.Equal(t, `"x"`, string(res[1].Data))
+	assert.Equal(t, "1", *res[1].ETag)
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("query DSL", func(t *testing.T) {
+		var qq query.Query
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"filter": {"AND": [{"EQ": {"person.org": "A"}}, {"OR": [{"IN": {"state": ["CA", "WA"]}}, {"EQ": {"age": 30}}]}]},
+			"sort": [{"key": "person.id", "order": "DESC"}, {"key": "state"}],
+			"page": {"limit": 2, "token": "4"}
+		}`), &qq))
+
+		q := &Query{}
+		require.NoError(t, query.NewQueryBuilder(q).BuildQuery(&qq))
+		body, err := json.Marshal(q.body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"query": {"bool": {
+				"filter": [{"bool": {"filter": [
+					{"term": {"value.person.org": "A"}},
+					{"bool": {"should": [{"terms": {"value.state": ["CA", "WA"]}}, {"term": {"value.age": 30}}], "minimum_should_match": 1}}
+				]}}],
+				"must_not": [{"range": {"expiresAt": {"lte": "now"}}}]
+			}},
+			"sort": [{"value.person.id": {"order": "desc"}}, {"value.state": {"order": "asc"}}],
+			"size": 2,
+			"from": 4,
+			"version": true
+		}`, string(body))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		q := &Query{}
+		err := query.NewQueryBuilder(q).BuildQuery(&query.Query{QueryFields: query.QueryFields{Page: query.Pagination{Token: "x"}}})
+		require.Error(t, err)
+	})
+
+	t.Run("execute", func(t *testing.T) {
+		s, f := initStore(t, nil)
+		ctx := context.Background()
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "k", Value: []byte(`{"state":"CA"}`)}))
+
+		res, err := s.Query(ctx, &state.QueryRequest{Query: query.Query{QueryFields: query.QueryFields{Page: query.Pagination{Limit: 10}}}})
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+		assert.Equal(t, "k", res.Results[0].Key)
+		assert.Equal(t, `{"state":"CA"}`, string(res.Results[0].Data))
+		assert.Equal(t, "1", *res.Results[0].ETag)
+		assert.Equal(t, "1", res.Token)
+		assert.Contains(t, f.lastSearch, "query")
+	})
+}
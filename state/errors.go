@@ -55,6 +55,11 @@ func (e *ETagError) Error() string {
 	return errors.New(prefix).Error()
 }
 
+// Unwrap returns the error reported by the state store, if any.
+func (e *ETagError) Unwrap() error {
+	return e.err
+}
+
 // NewETagError returns an ETagError wrapping an existing context error.
 func NewETagError(kind ETagErrorKind, err error) *ETagError {
 	return &ETagError{
@@ -80,3 +85,49 @@ func NewBulkDeleteRowMismatchError(expected, affected uint64) *BulkDeleteRowMism
 		affected: affected,
 	}
 }
+
+// BulkStoreError is returned by bulk operations for each key that failed, so callers can retry only the keys that failed.
+// The errors of multiple keys are combined with errors.Join.
+type BulkStoreError struct {
+	key string
+	err error
+}
+
+// NewBulkStoreError returns a BulkStoreError for the key.
+func NewBulkStoreError(key string, err error) *BulkStoreError {
+	return &BulkStoreError{
+		key: key,
+		err: err,
+	}
+}
+
+// Key returns the key that failed.
+func (e *BulkStoreError) Key() string {
+	return e.key
+}
+
+func (e *BulkStoreError) Error() string {
+	return fmt.Sprintf("failed to process key '%s': %v", e.key, e.err)
+}
+
+func (e *BulkStoreError) Unwrap() error {
+	return e.err
+}
+
+// BulkStoreErrorKeys returns the keys of all the BulkStoreError in err, including those combined with errors.Join.
+func BulkStoreErrorKeys(err error) []string {
+	switch e := err.(type) { //nolint:errorlint
+	case *BulkStoreError:
+		return []string{e.key}
+	case interface{ Unwrap() []error }:
+		var keys []string
+		for _, inner := range e.Unwrap() {
+			keys = append(keys, BulkStoreErrorKeys(inner)...)
+		}
+		return keys
+	case interface{ Unwrap() error }:
+		return BulkStoreErrorKeys(e.Unwrap())
+	default:
+		return nil
+	}
+}
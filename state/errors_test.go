@@ -15,6 +15,7 @@ package state
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,4 +53,30 @@ func TestETagError(t *testing.T) {
 
 		assert.IsType(t, ETagMismatch, err.kind)
 	})
+
+	t.Run("unwrap", func(t *testing.T) {
+		cerr := errors.New("error1")
+		err := fmt.Errorf("wrapped: %w", NewETagError(ETagMismatch, cerr))
+
+		assert.ErrorIs(t, err, cerr)
+	})
+}
+
+func TestBulkStoreError(t *testing.T) {
+	cause := errors.New("timeout")
+	err := NewBulkStoreError("key1", cause)
+	assert.Equal(t, "key1", err.Key())
+	assert.Equal(t, "failed to process key 'key1': timeout", err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	t.Run("keys", func(t *testing.T) {
+		assert.Nil(t, BulkStoreErrorKeys(nil))
+		assert.Nil(t, BulkStoreErrorKeys(cause))
+		assert.Equal(t, []string{"key1"}, BulkStoreErrorKeys(err))
+		assert.Equal(t, []string{"key1"}, BulkStoreErrorKeys(fmt.Errorf("bulk set: %w", err)))
+
+		joined := errors.Join(err, NewBulkStoreError("key2", cause), cause)
+		assert.Equal(t, []string{"key1", "key2"}, BulkStoreErrorKeys(joined))
+		assert.Equal(t, []string{"key1", "key2"}, BulkStoreErrorKeys(fmt.Errorf("bulk set: %w", joined)))
+	})
 }
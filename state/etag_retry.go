@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/dapr/kit/retry"
+)
+
+const (
+	// ETagRetryMetadataPrefix is the prefix of the metadata properties that configure the retries of SetWithMerge on ETag conflicts, such as "etagRetryMaxRetries" and "etagRetryDuration".
+	ETagRetryMetadataPrefix = "etagRetry"
+
+	// DefaultETagRetryInterval is the default delay between retries of SetWithMerge.
+	DefaultETagRetryInterval = 100 * time.Millisecond
+)
+
+// MergeFn returns the value to store for a key, given its current state.
+// If the key doesn't exist, the response has no data and no ETag.
+type MergeFn func(current *GetResponse) (any, error)
+
+// MergeSetter is implemented by state stores that perform read-modify-write updates, retrying them on ETag conflicts.
+type MergeSetter interface {
+	// SetWithMerge reads the key, invokes merge with its current state, and stores the result only if the key wasn't modified in the meantime.
+	// If it was, the read and the merge are repeated, up to the number of retries configured in the metadata of the store.
+	// The metadata is passed to both the read and the write.
+	SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge MergeFn) error
+}
+
+// ParseETagRetryConfig returns the configuration of the retries of SetWithMerge from the metadata properties with ETagRetryMetadataPrefix.
+// Retries are opt-in: unless "etagRetryMaxRetries" is set, the update is attempted once.
+func ParseETagRetryConfig(props map[string]string) (retry.Config, error) {
+	config := retry.DefaultConfigWithNoRetry()
+	config.Duration = DefaultETagRetryInterval
+	config.InitialInterval = DefaultETagRetryInterval
+	err := retry.DecodeConfigWithPrefix(&config, props, ETagRetryMetadataPrefix)
+	return config, err
+}
+
+// ETagRetrier implements SetWithMerge on top of the Get and Set operations of a store.
+type ETagRetrier struct {
+	store  BaseStore
+	config retry.Config
+}
+
+// NewETagRetrier returns an ETagRetrier that performs updates on store, with the retries configured by config.
+func NewETagRetrier(store BaseStore, config retry.Config) *ETagRetrier {
+	return &ETagRetrier{
+		store:  store,
+		config: config,
+	}
+}
+
+// SetWithMerge reads the key, invokes merge with its current state, and stores the result conditioned on the ETag that was read, or only if the key still doesn't exist.
+// If the write fails because of an ETag mismatch, the whole operation is retried; once the retries are exhausted, the ETag error is returned.
+// Other errors, including those returned by merge, are not retried.
+func (r *ETagRetrier) SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge MergeFn) error {
+	return backoff.Retry(func() error {
+		err := r.setWithMerge(ctx, key, metadata, merge)
+		if err == nil || isETagMismatch(err) {
+			return err
+		}
+		return backoff.Permanent(err)
+	}, r.config.NewBackOffWithContext(ctx))
+}
+
+func (r *ETagRetrier) setWithMerge(ctx context.Context, key string, metadata map[string]string, merge MergeFn) error {
+	current, err := r.store.Get(ctx, &GetRequest{Key: key, Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		current = &GetResponse{}
+	}
+
+	value, err := merge(current)
+	if err != nil {
+		return err
+	}
+
+	req := &SetRequest{
+		Key:      key,
+		Value:    value,
+		Metadata: metadata,
+	}
+	if current.ETag != nil && *current.ETag != "" {
+		req.ETag = current.ETag
+	} else {
+		// The key didn't exist, so the write fails if it was created in the meantime
+		req.Options.Concurrency = FirstWrite
+	}
+	return r.store.Set(ctx, req)
+}
+
+func isETagMismatch(err error) bool {
+	var etagErr *ETagError
+	return errors.As(err, &etagErr) && etagErr.Kind() == ETagMismatch
+}
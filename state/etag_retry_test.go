@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/retry"
+)
+
+func TestParseETagRetryConfig(t *testing.T) {
+	t.Run("retries are disabled by default", func(t *testing.T) {
+		config, err := ParseETagRetryConfig(map[string]string{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), config.MaxRetries)
+	})
+
+	t.Run("constant policy", func(t *testing.T) {
+		config, err := ParseETagRetryConfig(map[string]string{
+			"etagRetryMaxRetries": "5",
+			"etagRetryDuration":   "10ms",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, retry.PolicyConstant, config.Policy)
+		assert.Equal(t, int64(5), config.MaxRetries)
+		assert.Equal(t, 10*time.Millisecond, config.Duration)
+	})
+
+	t.Run("exponential policy", func(t *testing.T) {
+		config, err := ParseETagRetryConfig(map[string]string{
+			"etagRetryPolicy":          "exponential",
+			"etagRetryMaxRetries":      "3",
+			"etagRetryInitialInterval": "5ms",
+			"etagRetryMaxInterval":     "1s",
+			"unrelatedMaxRetries":      "10",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, retry.PolicyExponential, config.Policy)
+		assert.Equal(t, int64(3), config.MaxRetries)
+		assert.Equal(t, 5*time.Millisecond, config.InitialInterval)
+		assert.Equal(t, time.Second, config.MaxInterval)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		_, err := ParseETagRetryConfig(map[string]string{
+			"etagRetryMaxRetries": "many",
+		})
+		require.Error(t, err)
+	})
+}
@@ -24,6 +24,8 @@ const (
 	FeatureTransactional Feature = "TRANSACTIONAL"
 	// FeatureQueryAPI is the feature that performs query operations.
 	FeatureQueryAPI Feature = "QUERY_API"
+	// FeatureTTL is the feature that expires keys after the time set with the "ttlInSeconds" metadata.
+	FeatureTTL Feature = "TTL"
 )
 
 // Feature names a feature that can be implemented by PubSub components.
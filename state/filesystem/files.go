@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	// Suffix of the sidecar files containing the version and expiration time of keys.
+	// The name predates versions, and is kept so sidecars written by previous versions are still read.
+	sidecarSuffix = ".ttl"
+	// Prefix of temporary files, which are renamed to their final name once fully written.
+	tempFilePrefix = ".tmp-"
+	// Name of the journal file, which records a transaction while it's being applied.
+	journalFileName = ".txn"
+)
+
+// item is the value of a key read from disk.
+type item struct {
+	data      []byte
+	etag      string
+	expiresAt *time.Time
+}
+
+// sidecar is the content of the sidecar file of a key.
+// It includes the hash of the value it applies to, so a sidecar left behind by an interrupted write isn't applied to a different value.
+type sidecar struct {
+	// Version of the value, which is the ETag; it's generated on every write, so writing back a previous value doesn't restore a previous ETag
+	Version string `json:"version,omitempty"`
+	// Expiration time in milliseconds, or nil if the value doesn't expire
+	ExpiresAt *int64 `json:"expiresAt,omitempty"`
+	// Hash of the value; previous versions used it as the ETag, hence the name
+	Hash string `json:"etag"`
+}
+
+// fileName returns the name of the file storing a key.
+// Keys are hashed, as they can contain characters that aren't allowed in file names or be longer than the maximum file name length.
+func fileName(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// computeHash returns the hash of a value, which binds a sidecar to it.
+// It's also the ETag of values without a version, written before versions were added.
+func computeHash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:16])
+}
+
+// readItem reads the value of a key, returning nil if the key doesn't exist or is expired.
+func readItem(path string, now time.Time) (*item, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	hash := computeHash(data)
+	sc, err := readSidecar(path, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &item{
+		data: data,
+		etag: hash,
+	}
+	if sc != nil {
+		if sc.Version != "" {
+			it.etag = sc.Version
+		}
+		if sc.ExpiresAt != nil {
+			it.expiresAt = ptr.Of(time.UnixMilli(*sc.ExpiresAt))
+		}
+	}
+	if it.expiresAt != nil && !it.expiresAt.After(now) {
+		return nil, nil
+	}
+
+	return it, nil
+}
+
+// readSidecar returns the sidecar of the value with the given hash, or nil if it doesn't have one.
+func readSidecar(path string, hash string) (*sidecar, error) {
+	raw, err := os.ReadFile(path + sidecarSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var sc sidecar
+	err = json.Unmarshal(raw, &sc)
+	if err != nil || sc.Hash != hash {
+		// The sidecar is corrupted or belongs to a different value
+		return nil, nil //nolint:nilerr
+	}
+	return &sc, nil
+}
+
+// writeItem writes the value of a key, and its sidecar with a new version and the expiration time if any.
+// The value is written before the sidecar, so if the process crashes in between, the value doesn't expire earlier than it should.
+func writeItem(path string, data []byte, expiresAt *time.Time) error {
+	err := writeFileAtomic(path, data)
+	if err != nil {
+		return err
+	}
+
+	sc := sidecar{
+		Version: uuid.NewString(),
+		Hash:    computeHash(data),
+	}
+	if expiresAt != nil {
+		sc.ExpiresAt = ptr.Of(expiresAt.UnixMilli())
+	}
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path+sidecarSuffix, raw)
+}
+
+// deleteItem deletes the value of a key and its expiration time.
+func deleteItem(path string) error {
+	err := removeIfExists(path)
+	if err != nil {
+		return err
+	}
+	return removeIfExists(path + sidecarSuffix)
+}
+
+// writeFileAtomic writes a file to a temporary location and then renames it, so readers and crashes never observe a partially-written file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		// Ensure the data is on disk before the file is renamed
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir persists the directory entries, such as renames, to disk.
+// Errors are ignored, as this is not supported on all platforms.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+func removeIfExists(path string) error {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
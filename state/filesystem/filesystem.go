@@ -0,0 +1,450 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filesystem implements a state store that persists each key in a file on the local filesystem.
+// It's meant for single-node scenarios, such as edge or offline deployments: the directory must not be shared by multiple instances.
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	stateutils "github.com/dapr/components-contrib/state/utils"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+// Filesystem is a state store that persists each key in a file.
+type Filesystem struct {
+	state.BulkStore
+
+	metadata filesystemMetadata
+	lock     sync.RWMutex
+	logger   logger.Logger
+
+	closeCh chan struct{}
+	closed  atomic.Bool
+	wg      sync.WaitGroup
+}
+
+// journalEntry is an operation of a transaction recorded in the journal.
+type journalEntry struct {
+	Key       string `json:"key"`
+	Delete    bool   `json:"delete,omitempty"`
+	Data      []byte `json:"data,omitempty"`
+	ExpiresAt *int64 `json:"expiresAt,omitempty"`
+}
+
+// NewFilesystemStateStore returns a new filesystem state store.
+func NewFilesystemStateStore(logger logger.Logger) state.Store {
+	s := &Filesystem{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+	}
+	s.BulkStore = state.NewDefaultBulkStore(s)
+	return s
+}
+
+// Init creates the directory, if needed, and completes any transaction that was interrupted.
+func (f *Filesystem) Init(_ context.Context, meta state.Metadata) error {
+	var err error
+	f.metadata, err = parseMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(f.metadata.RootPath, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", f.metadata.RootPath, err)
+	}
+
+	err = f.recover()
+	if err != nil {
+		return err
+	}
+
+	if f.metadata.CleanupInterval > 0 {
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			f.runCleanup()
+		}()
+	}
+
+	return nil
+}
+
+// Features returns the features available in this state store.
+func (f *Filesystem) Features() []state.Feature {
+	return []state.Feature{
+		state.FeatureETag,
+		state.FeatureTransactional,
+	}
+}
+
+// Get returns the value of a key.
+func (f *Filesystem) Get(_ context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	it, err := readItem(f.path(req.Key), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key '%s': %w", req.Key, err)
+	}
+	if it == nil {
+		return &state.GetResponse{}, nil
+	}
+
+	return &state.GetResponse{
+		Data: it.data,
+		ETag: ptr.Of(it.etag),
+	}, nil
+}
+
+// BulkGet returns the values of multiple keys, read as a consistent snapshot.
+func (f *Filesystem) BulkGet(_ context.Context, req []state.GetRequest, _ state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	now := time.Now()
+	res := make([]state.BulkGetResponse, len(req))
+	for i, r := range req {
+		res[i].Key = r.Key
+		it, err := readItem(f.path(r.Key), now)
+		if err != nil {
+			res[i].Error = fmt.Sprintf("failed to read key '%s': %v", r.Key, err)
+			continue
+		}
+		if it != nil {
+			res[i].Data = it.data
+			res[i].ETag = ptr.Of(it.etag)
+		}
+	}
+
+	return res, nil
+}
+
+// Set writes the value of a key.
+func (f *Filesystem) Set(_ context.Context, req *state.SetRequest) error {
+	entry, err := f.prepareSet(req)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err = f.validateETag(req.Key, req.ETag, req.Options.Concurrency)
+	if err != nil {
+		return err
+	}
+
+	return f.apply(entry)
+}
+
+// Delete removes a key.
+func (f *Filesystem) Delete(_ context.Context, req *state.DeleteRequest) error {
+	err := state.CheckRequestOptions(req.Options)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err = f.validateETag(req.Key, req.ETag, req.Options.Concurrency)
+	if err != nil {
+		return err
+	}
+
+	return f.apply(journalEntry{Key: req.Key, Delete: true})
+}
+
+// Multi applies multiple operations in a transaction.
+// The operations are recorded in a journal before being applied, so if the process crashes, the transaction is completed when the component is initialized again.
+func (f *Filesystem) Multi(_ context.Context, request *state.TransactionalStateRequest) error {
+	if len(request.Operations) == 0 {
+		return nil
+	}
+
+	entries := make([]journalEntry, len(request.Operations))
+	for i, o := range request.Operations {
+		switch req := o.(type) {
+		case state.SetRequest:
+			entry, err := f.prepareSet(&req)
+			if err != nil {
+				return err
+			}
+			entries[i] = entry
+		case state.DeleteRequest:
+			err := state.CheckRequestOptions(req.Options)
+			if err != nil {
+				return err
+			}
+			entries[i] = journalEntry{Key: req.Key, Delete: true}
+		default:
+			return fmt.Errorf("unsupported operation: %s", o.Operation())
+		}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Validate all ETags before making any change
+	for _, o := range request.Operations {
+		var err error
+		switch req := o.(type) {
+		case state.SetRequest:
+			err = f.validateETag(req.Key, req.ETag, req.Options.Concurrency)
+		case state.DeleteRequest:
+			err = f.validateETag(req.Key, req.ETag, req.Options.Concurrency)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	journal, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(f.journalPath(), journal)
+	if err != nil {
+		return fmt.Errorf("failed to write transaction journal: %w", err)
+	}
+
+	for _, entry := range entries {
+		err = f.apply(entry)
+		if err != nil {
+			// The journal is kept, so the transaction is completed when the component is initialized again
+			return fmt.Errorf("failed to apply transaction, which will be completed when the component is restarted: %w", err)
+		}
+	}
+
+	return removeIfExists(f.journalPath())
+}
+
+// Close stops the background cleanup.
+func (f *Filesystem) Close() error {
+	if f.closed.CompareAndSwap(false, true) {
+		close(f.closeCh)
+	}
+	f.wg.Wait()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (f *Filesystem) GetComponentMetadata() map[string]string {
+	metadataStruct := filesystemMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.StateStoreType)
+	return metadataInfo
+}
+
+func (f *Filesystem) path(key string) string {
+	return filepath.Join(f.metadata.RootPath, fileName(key))
+}
+
+func (f *Filesystem) journalPath() string {
+	return filepath.Join(f.metadata.RootPath, journalFileName)
+}
+
+// prepareSet validates a set request and returns the corresponding journal entry.
+func (f *Filesystem) prepareSet(req *state.SetRequest) (journalEntry, error) {
+	err := state.CheckRequestOptions(req.Options)
+	if err != nil {
+		return journalEntry{}, err
+	}
+
+	ttl, err := stateutils.ParseTTL(req.Metadata)
+	if err != nil {
+		return journalEntry{}, err
+	}
+
+	data, err := stateutils.Marshal(req.Value, json.Marshal)
+	if err != nil {
+		return journalEntry{}, err
+	}
+
+	entry := journalEntry{Key: req.Key, Data: data}
+	if ttl != nil && *ttl > 0 {
+		entry.ExpiresAt = ptr.Of(time.Now().Add(time.Duration(*ttl) * time.Second).UnixMilli())
+	}
+	return entry, nil
+}
+
+// validateETag checks the ETag of a request against the current value of the key.
+// It must be called while holding the write lock.
+func (f *Filesystem) validateETag(key string, etag *string, concurrency string) error {
+	hasETag := etag != nil && *etag != ""
+	if !hasETag && concurrency != state.FirstWrite {
+		return nil
+	}
+
+	it, err := readItem(f.path(key), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to read key '%s': %w", key, err)
+	}
+
+	switch {
+	case !hasETag && it != nil:
+		return state.NewETagError(state.ETagMismatch, errors.New("item already exists and no etag was passed"))
+	case hasETag && it == nil:
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("state does not exist or is expired for key=%s", key))
+	case hasETag && it.etag != *etag:
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("state etag does not match for key=%s", key))
+	}
+	return nil
+}
+
+// apply applies an operation to the filesystem.
+// Operations are idempotent, so they can be applied again when recovering a transaction.
+func (f *Filesystem) apply(entry journalEntry) error {
+	path := f.path(entry.Key)
+	if entry.Delete {
+		err := deleteItem(path)
+		if err != nil {
+			return fmt.Errorf("failed to delete key '%s': %w", entry.Key, err)
+		}
+		return nil
+	}
+
+	var expiresAt *time.Time
+	if entry.ExpiresAt != nil {
+		expiresAt = ptr.Of(time.UnixMilli(*entry.ExpiresAt))
+	}
+	err := writeItem(path, entry.Data, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to write key '%s': %w", entry.Key, err)
+	}
+	return nil
+}
+
+// recover completes a transaction that was interrupted, and removes temporary files left behind by interrupted writes.
+func (f *Filesystem) recover() error {
+	journal, err := os.ReadFile(f.journalPath())
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Nothing to recover
+	case err != nil:
+		return fmt.Errorf("failed to read transaction journal: %w", err)
+	default:
+		var entries []journalEntry
+		err = json.Unmarshal(journal, &entries)
+		if err != nil {
+			return fmt.Errorf("failed to parse transaction journal: %w", err)
+		}
+		f.logger.Infof("Completing a transaction with %d operations that was interrupted", len(entries))
+		for _, entry := range entries {
+			err = f.apply(entry)
+			if err != nil {
+				return err
+			}
+		}
+		err = removeIfExists(f.journalPath())
+		if err != nil {
+			return err
+		}
+	}
+
+	tempFiles, err := filepath.Glob(filepath.Join(f.metadata.RootPath, tempFilePrefix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, name := range tempFiles {
+		err = removeIfExists(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *Filesystem) runCleanup() {
+	t := time.NewTicker(f.metadata.CleanupInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-f.closeCh:
+			return
+		case <-t.C:
+			err := f.cleanupExpired()
+			if err != nil {
+				f.logger.Errorf("Error removing expired keys: %v", err)
+			}
+		}
+	}
+}
+
+// cleanupExpired removes the files of expired keys, as well as sidecar files whose key doesn't exist anymore.
+func (f *Filesystem) cleanupExpired() error {
+	sidecars, err := filepath.Glob(filepath.Join(f.metadata.RootPath, "*"+sidecarSuffix))
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := time.Now()
+	for _, name := range sidecars {
+		path := strings.TrimSuffix(name, sidecarSuffix)
+		_, err = os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = removeIfExists(name)
+			if err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		// Every key has a sidecar, so values are only read if their sidecar says they're expired
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		var sc sidecar
+		if json.Unmarshal(raw, &sc) != nil || sc.ExpiresAt == nil || time.UnixMilli(*sc.ExpiresAt).After(now) {
+			continue
+		}
+
+		// The sidecar is only applied if it belongs to the current value
+		it, err := readItem(path, now)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			err = deleteItem(path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func initStore(t *testing.T, dir string) *Filesystem {
+	t.Helper()
+
+	s := NewFilesystemStateStore(logger.NewLogger("test")).(*Filesystem)
+	err := s.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"rootPath": dir,
+	}}})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"rootPath": "/data",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, "/data", m.RootPath)
+		assert.Equal(t, defaultCleanupInterval, m.CleanupInterval)
+	})
+
+	t.Run("cleanup interval", func(t *testing.T) {
+		m, err := parseMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"rootPath":        "/data",
+			"cleanupInterval": "0",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), m.CleanupInterval)
+	})
+
+	t.Run("missing rootPath", func(t *testing.T) {
+		_, err := parseMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{}}})
+		require.Error(t, err)
+	})
+
+	t.Run("negative cleanup interval", func(t *testing.T) {
+		_, err := parseMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"rootPath":        "/data",
+			"cleanupInterval": "-1s",
+		}}})
+		require.Error(t, err)
+	})
+}
+
+func TestCRUD(t *testing.T) {
+	dir := t.TempDir()
+	s := initStore(t, dir)
+	ctx := context.Background()
+
+	t.Run("get missing key", func(t *testing.T) {
+		res, err := s.Get(ctx, &state.GetRequest{Key: "missing"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+		assert.Nil(t, res.ETag)
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "app||key1", Value: map[string]string{"a": "b"}})
+		require.NoError(t, err)
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "app||key1"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":"b"}`, string(res.Data))
+		require.NotNil(t, res.ETag)
+		assert.NotEmpty(t, *res.ETag)
+	})
+
+	t.Run("bytes are stored as-is", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "raw", Value: []byte("hello")})
+		require.NoError(t, err)
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "raw"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res.Data))
+	})
+
+	t.Run("bulk get", func(t *testing.T) {
+		res, err := s.BulkGet(ctx, []state.GetRequest{{Key: "app||key1"}, {Key: "missing"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, "app||key1", res[0].Key)
+		assert.JSONEq(t, `{"a":"b"}`, string(res[0].Data))
+		assert.NotNil(t, res[0].ETag)
+		assert.Equal(t, "missing", res[1].Key)
+		assert.Nil(t, res[1].Data)
+		assert.Empty(t, res[1].Error)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := s.Delete(ctx, &state.DeleteRequest{Key: "app||key1"})
+		require.NoError(t, err)
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "app||key1"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+
+		// Deleting a missing key is not an error
+		err = s.Delete(ctx, &state.DeleteRequest{Key: "app||key1"})
+		require.NoError(t, err)
+	})
+
+	t.Run("no temporary files are left behind", func(t *testing.T) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		for _, e := range entries {
+			assert.False(t, strings.HasPrefix(e.Name(), tempFilePrefix), e.Name())
+		}
+	})
+}
+
+func TestETag(t *testing.T) {
+	s := initStore(t, t.TempDir())
+	ctx := context.Background()
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "key", Value: "v1"}))
+	res, err := s.Get(ctx, &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	etag := res.ETag
+
+	t.Run("set with wrong etag", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "key", Value: "v2", ETag: ptr.Of("bad")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+
+	t.Run("set with etag of missing key", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "other", Value: "v2", ETag: etag})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+	})
+
+	t.Run("delete with wrong etag", func(t *testing.T) {
+		err := s.Delete(ctx, &state.DeleteRequest{Key: "key", ETag: ptr.Of("bad")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+	})
+
+	t.Run("set with correct etag", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "key", Value: "v2", ETag: etag})
+		require.NoError(t, err)
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v2"`, string(res.Data))
+		assert.NotEqual(t, *etag, *res.ETag)
+		etag = res.ETag
+	})
+
+	t.Run("first-write", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "key", Value: "v3", Options: state.SetStateOption{Concurrency: state.FirstWrite}})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+
+		err = s.Set(ctx, &state.SetRequest{Key: "new", Value: "v1", Options: state.SetStateOption{Concurrency: state.FirstWrite}})
+		require.NoError(t, err)
+	})
+
+	t.Run("delete with correct etag", func(t *testing.T) {
+		err := s.Delete(ctx, &state.DeleteRequest{Key: "key", ETag: etag})
+		require.NoError(t, err)
+	})
+
+	t.Run("writing back a previous value changes the etag", func(t *testing.T) {
+		// A reads the key, then B writes another value and the original one back
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "aba", Value: "v1"}))
+		res, err := s.Get(ctx, &state.GetRequest{Key: "aba"})
+		require.NoError(t, err)
+		read := res.ETag
+
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "aba", Value: "v2"}))
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "aba", Value: "v1"}))
+
+		err = s.Set(ctx, &state.SetRequest{Key: "aba", Value: "v3", ETag: read})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+
+		// Same after the key is deleted and created again
+		res, err = s.Get(ctx, &state.GetRequest{Key: "aba"})
+		require.NoError(t, err)
+		read = res.ETag
+		require.NoError(t, s.Delete(ctx, &state.DeleteRequest{Key: "aba"}))
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "aba", Value: "v1"}))
+		err = s.Delete(ctx, &state.DeleteRequest{Key: "aba", ETag: read})
+		require.ErrorAs(t, err, &etagErr)
+	})
+
+	t.Run("values without a version", func(t *testing.T) {
+		// Values written before versions were added use the hash of the value as ETag
+		path := s.path("legacy")
+		require.NoError(t, os.WriteFile(path, []byte(`"v"`), 0o600))
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "legacy"})
+		require.NoError(t, err)
+		assert.Equal(t, computeHash([]byte(`"v"`)), *res.ETag)
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "legacy", Value: "v", ETag: res.ETag}))
+	})
+}
+
+func TestTTL(t *testing.T) {
+	dir := t.TempDir()
+	s := initStore(t, dir)
+	ctx := context.Background()
+
+	t.Run("expired keys are not returned", func(t *testing.T) {
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "key", Value: "v", Metadata: map[string]string{"ttlInSeconds": "1"}}))
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v"`, string(res.Data))
+
+		time.Sleep(1100 * time.Millisecond)
+		res, err = s.Get(ctx, &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+
+	t.Run("expired keys are removed from disk", func(t *testing.T) {
+		path := s.path("key")
+		require.FileExists(t, path)
+		require.FileExists(t, path+sidecarSuffix)
+
+		require.NoError(t, s.cleanupExpired())
+		assert.NoFileExists(t, path)
+		assert.NoFileExists(t, path+sidecarSuffix)
+	})
+
+	t.Run("keys that don't expire are kept", func(t *testing.T) {
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "persistent", Value: "v"}))
+		require.NoError(t, s.cleanupExpired())
+		assert.FileExists(t, s.path("persistent"))
+		assert.FileExists(t, s.path("persistent")+sidecarSuffix)
+	})
+
+	t.Run("overwriting without ttl removes the expiration", func(t *testing.T) {
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "key2", Value: "v", Metadata: map[string]string{"ttlInSeconds": "1"}}))
+		require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "key2", Value: "v"}))
+
+		it, err := readItem(s.path("key2"), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, it)
+		assert.Nil(t, it.expiresAt)
+	})
+
+	t.Run("sidecar of a different value is ignored", func(t *testing.T) {
+		path := s.path("key3")
+		require.NoError(t, os.WriteFile(path, []byte(`"v"`), 0o600))
+		raw, err := json.Marshal(sidecar{ExpiresAt: ptr.Of(time.Now().Add(-time.Hour).UnixMilli()), Hash: "other"})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path+sidecarSuffix, raw, 0o600))
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "key3"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v"`, string(res.Data))
+	})
+
+	t.Run("sidecars written by previous versions", func(t *testing.T) {
+		// They contain the expiration time and the hash of the value, but no version
+		path := s.path("key4")
+		require.NoError(t, os.WriteFile(path, []byte(`"v"`), 0o600))
+		raw := fmt.Sprintf(`{"expiresAt":%d,"etag":"%s"}`, time.Now().Add(-time.Hour).UnixMilli(), computeHash([]byte(`"v"`)))
+		require.NoError(t, os.WriteFile(path+sidecarSuffix, []byte(raw), 0o600))
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "key4"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		err := s.Set(ctx, &state.SetRequest{Key: "key", Value: "v", Metadata: map[string]string{"ttlInSeconds": "foo"}})
+		require.Error(t, err)
+	})
+}
+
+func TestMulti(t *testing.T) {
+	dir := t.TempDir()
+	s := initStore(t, dir)
+	ctx := context.Background()
+
+	require.NoError(t, s.Set(ctx, &state.SetRequest{Key: "existing", Value: "v"}))
+
+	t.Run("applies all operations", func(t *testing.T) {
+		err := s.Multi(ctx, &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "key1", Value: "v1"},
+				state.SetRequest{Key: "key2", Value: "v2"},
+				state.DeleteRequest{Key: "existing"},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := s.BulkGet(ctx, []state.GetRequest{{Key: "key1"}, {Key: "key2"}, {Key: "existing"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, `"v1"`, string(res[0].Data))
+		assert.Equal(t, `"v2"`, string(res[1].Data))
+		assert.Nil(t, res[2].Data)
+		assert.NoFileExists(t, filepath.Join(dir, journalFileName))
+	})
+
+	t.Run("etag mismatch rolls back everything", func(t *testing.T) {
+		err := s.Multi(ctx, &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "key1", Value: "changed"},
+				state.DeleteRequest{Key: "key2", ETag: ptr.Of("bad")},
+			},
+		})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "key1"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v1"`, string(res.Data))
+	})
+}
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulate a crash while a transaction was being applied, and while a file was being written
+	s := initStore(t, dir)
+	require.NoError(t, s.Set(context.Background(), &state.SetRequest{Key: "deleted", Value: "v"}))
+	journal, err := json.Marshal([]journalEntry{
+		{Key: "key1", Data: []byte(`"v1"`)},
+		{Key: "deleted", Delete: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, journalFileName), journal, 0o600))
+	tmp := filepath.Join(dir, tempFilePrefix+"123")
+	require.NoError(t, os.WriteFile(tmp, []byte("partial"), 0o600))
+	require.NoError(t, s.Close())
+
+	s = initStore(t, dir)
+	res, err := s.BulkGet(context.Background(), []state.GetRequest{{Key: "key1"}, {Key: "deleted"}}, state.BulkGetOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, string(res[0].Data))
+	assert.Nil(t, res[1].Data)
+	assert.NoFileExists(t, filepath.Join(dir, journalFileName))
+	assert.NoFileExists(t, tmp)
+
+	t.Run("corrupted journal", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, journalFileName), []byte("{"), 0o600))
+		s := NewFilesystemStateStore(logger.NewLogger("test"))
+		err := s.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"rootPath": dir,
+		}}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesystem
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+)
+
+const defaultCleanupInterval = time.Hour
+
+type filesystemMetadata struct {
+	// Directory where the state is stored. It's created if it doesn't exist.
+	RootPath string `mapstructure:"rootPath"`
+	// Interval for removing expired keys from disk. Expired keys are never returned, even before they are removed.
+	// Set to 0 to disable.
+	CleanupInterval time.Duration `mapstructure:"cleanupInterval"`
+}
+
+func parseMetadata(meta state.Metadata) (filesystemMetadata, error) {
+	m := filesystemMetadata{
+		CleanupInterval: defaultCleanupInterval,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return m, err
+	}
+
+	if m.RootPath == "" {
+		return m, errors.New("missing required metadata property 'rootPath'")
+	}
+	if m.CleanupInterval < 0 {
+		return m, errors.New("invalid value for 'cleanupInterval': must be zero or greater")
+	}
+
+	return m, nil
+}
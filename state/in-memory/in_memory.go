@@ -29,6 +29,7 @@ import (
 	"github.com/dapr/components-contrib/state/utils"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
+	"github.com/dapr/kit/retry"
 )
 
 type inMemoryStore struct {
@@ -38,6 +39,7 @@ type inMemoryStore struct {
 	closeCh chan struct{}
 	closed  atomic.Bool
 	wg      sync.WaitGroup
+	retrier *state.ETagRetrier
 }
 
 func NewInMemoryStateStore(log logger.Logger) state.Store {
@@ -45,14 +47,22 @@ func NewInMemoryStateStore(log logger.Logger) state.Store {
 }
 
 func newStateStore(log logger.Logger) *inMemoryStore {
-	return &inMemoryStore{
+	store := &inMemoryStore{
 		items:   map[string]*inMemStateStoreItem{},
 		log:     log,
 		closeCh: make(chan struct{}),
 	}
+	store.retrier = state.NewETagRetrier(store, retry.DefaultConfigWithNoRetry())
+	return store
 }
 
 func (store *inMemoryStore) Init(ctx context.Context, metadata state.Metadata) error {
+	retryConfig, err := state.ParseETagRetryConfig(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("failed to parse ETag retry configuration: %w", err)
+	}
+	store.retrier = state.NewETagRetrier(store, retryConfig)
+
 	// start a background go routine to clean expired item
 	store.wg.Add(1)
 	go func() {
@@ -266,6 +276,11 @@ func (store *inMemoryStore) Set(ctx context.Context, req *state.SetRequest) erro
 	return nil
 }
 
+// SetWithMerge implements state.MergeSetter.
+func (store *inMemoryStore) SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge state.MergeFn) error {
+	return store.retrier.SetWithMerge(ctx, key, metadata, merge)
+}
+
 func (store *inMemoryStore) doSetValidateParameters(req *state.SetRequest) (int, error) {
 	err := state.CheckRequestOptions(req.Options)
 	if err != nil {
@@ -15,11 +15,14 @@ package inmemory
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
@@ -115,3 +118,105 @@ func TestReadAndWrite(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestSetWithMerge(t *testing.T) {
+	// increment is a merge function that increments a counter, simulating a concurrent write of the key on the first conflicts invocations
+	increment := func(store *inMemoryStore, key string, conflicts int) (state.MergeFn, *int) {
+		calls := 0
+		return func(current *state.GetResponse) (any, error) {
+			calls++
+			n := 0
+			if len(current.Data) > 0 {
+				n, _ = strconv.Atoi(string(current.Data))
+			}
+			if calls <= conflicts {
+				err := store.Set(context.Background(), &state.SetRequest{Key: key, Value: n + 100})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return n + 1, nil
+		}, &calls
+	}
+
+	get := func(t *testing.T, store *inMemoryStore, key string) string {
+		t.Helper()
+		res, err := store.Get(context.Background(), &state.GetRequest{Key: key})
+		require.NoError(t, err)
+		return string(res.Data)
+	}
+
+	t.Run("without retries, conflicts fail", func(t *testing.T) {
+		store := newStateStore(logger.NewLogger("test"))
+		require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+		defer store.Close()
+
+		merge, calls := increment(store, "counter", 1)
+		err := store.SetWithMerge(context.Background(), "counter", nil, merge)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, "100", get(t, store, "counter"))
+	})
+
+	t.Run("conflicts are retried", func(t *testing.T) {
+		store := newStateStore(logger.NewLogger("test"))
+		md := state.Metadata{}
+		md.Properties = map[string]string{
+			"etagRetryMaxRetries": "3",
+			"etagRetryDuration":   "1ms",
+		}
+		require.NoError(t, store.Init(context.Background(), md))
+		defer store.Close()
+
+		require.NoError(t, store.Set(context.Background(), &state.SetRequest{Key: "counter", Value: 1}))
+
+		merge, calls := increment(store, "counter", 2)
+		require.NoError(t, store.SetWithMerge(context.Background(), "counter", nil, merge))
+		assert.Equal(t, 3, *calls)
+		// The merge is re-applied on the value written concurrently: 1 -> 101 -> 201 -> 202
+		assert.Equal(t, "202", get(t, store, "counter"))
+
+		// The key is created if it doesn't exist
+		merge, calls = increment(store, "new", 0)
+		require.NoError(t, store.SetWithMerge(context.Background(), "new", nil, merge))
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, "1", get(t, store, "new"))
+	})
+
+	t.Run("retries are exhausted", func(t *testing.T) {
+		store := newStateStore(logger.NewLogger("test"))
+		md := state.Metadata{}
+		md.Properties = map[string]string{
+			"etagRetryMaxRetries": "2",
+			"etagRetryDuration":   "1ms",
+		}
+		require.NoError(t, store.Init(context.Background(), md))
+		defer store.Close()
+
+		merge, calls := increment(store, "counter", 10)
+		err := store.SetWithMerge(context.Background(), "counter", nil, merge)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("merge errors are not retried", func(t *testing.T) {
+		store := newStateStore(logger.NewLogger("test"))
+		md := state.Metadata{}
+		md.Properties = map[string]string{
+			"etagRetryMaxRetries": "3",
+		}
+		require.NoError(t, store.Init(context.Background(), md))
+		defer store.Close()
+
+		calls := 0
+		err := store.SetWithMerge(context.Background(), "counter", nil, func(*state.GetResponse) (any, error) {
+			calls++
+			return nil, errors.New("simulated")
+		})
+		require.ErrorContains(t, err, "simulated")
+		assert.Equal(t, 1, calls)
+	})
+}
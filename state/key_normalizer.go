@@ -0,0 +1,24 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+// KeyNormalizeFn returns the normalized form of a key.
+// It must be idempotent, so normalized keys are normalized to themselves.
+type KeyNormalizeFn func(key string) string
+
+// KeyNormalizerSetter is implemented by state stores that normalize the keys of requests, such as by lowercasing them, so keys produced by different apps with inconsistent casing or separators refer to the same values.
+// The function is applied to the keys of reads and writes alike, after the prefix of the key added by Dapr, and replaces the normalization configured with the "keyNormalization" metadata property.
+type KeyNormalizerSetter interface {
+	SetKeyNormalizer(fn KeyNormalizeFn) error
+}
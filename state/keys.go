@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultListKeysLimit is the number of keys returned by ListKeys when the request doesn't set a limit.
+const DefaultListKeysLimit = 100
+
+// KeyLister is implemented by state stores that can enumerate their keys.
+type KeyLister interface {
+	// ListKeys returns a page of the keys that start with the prefix of the request, without their values.
+	// Expired and deleted keys are not returned.
+	// If there may be more keys, the response contains a token to pass in the next request to read the next page; the token is opaque and specific to the store.
+	// Keys created or deleted while the pages are read may or may not be returned.
+	ListKeys(ctx context.Context, req *ListKeysRequest) (*ListKeysResponse, error)
+}
+
+// ListKeysRequest is the request to list the keys with a prefix.
+type ListKeysRequest struct {
+	// Prefix of the keys; if empty, all keys are listed
+	Prefix string
+	// Maximum number of keys in the page; if 0, DefaultListKeysLimit is used
+	// Stores that can't stop at an exact number of keys, such as Redis, treat the limit as a hint
+	Limit int
+	// Token returned in the previous page; empty to read the first page
+	ContinuationToken string
+	// Metadata of the request, such as the tenant
+	Metadata map[string]string
+}
+
+// Validate checks that the request is well-formed, and sets the default limit if it's not set.
+func (r *ListKeysRequest) Validate() error {
+	if r.Limit < 0 {
+		return errors.New("the limit must not be negative")
+	}
+	if r.Limit == 0 {
+		r.Limit = DefaultListKeysLimit
+	}
+	return nil
+}
+
+// ListKeysResponse is a page of keys.
+type ListKeysResponse struct {
+	Keys []string
+	// Token to read the next page; empty if there are no more keys
+	ContinuationToken string
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListKeysRequestValidate(t *testing.T) {
+	req := &ListKeysRequest{Prefix: "app||"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, DefaultListKeysLimit, req.Limit)
+
+	req = &ListKeysRequest{Limit: 10}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, 10, req.Limit)
+
+	req = &ListKeysRequest{Limit: -1}
+	require.Error(t, req.Validate())
+}
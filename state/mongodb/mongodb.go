@@ -16,7 +16,6 @@ package mongodb
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -81,6 +80,9 @@ type MongoDB struct {
 	features     []state.Feature
 	logger       logger.Logger
 	isReplicaSet bool
+
+	// Performs the read-modify-write updates of SetWithMerge, retrying them on ETag conflicts as configured by the "etagRetry" metadata properties
+	etagRetrier *state.ETagRetrier
 }
 
 type mongoDBMetadata struct {
@@ -95,6 +97,10 @@ type mongoDBMetadata struct {
 	Params           string
 	ConnectionString string
 	OperationTimeout time.Duration
+	// Comma-separated list of properties (within the value) on which a 2dsphere index is created, to allow geospatial queries.
+	GeoIndexedProperties string
+	// Options of the JSON encoding of the values returned by Get, BulkGet and Query
+	stateutils.JSONOptions `mapstructure:",squash"`
 }
 
 // Item is Mongodb document wrapper.
@@ -121,6 +127,12 @@ func (m *MongoDB) Init(ctx context.Context, metadata state.Metadata) (err error)
 
 	m.operationTimeout = m.metadata.OperationTimeout
 
+	etagRetryConfig, err := state.ParseETagRetryConfig(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("error in parsing ETag retry options: %s", err)
+	}
+	m.etagRetrier = state.NewETagRetrier(m, etagRetryConfig)
+
 	client, err := m.getMongoDBClient(ctx)
 	if err != nil {
 		return fmt.Errorf("error in creating mongodb client: %s", err)
@@ -159,6 +171,16 @@ func (m *MongoDB) Init(ctx context.Context, metadata state.Metadata) (err error)
 		return fmt.Errorf("error in creating ttl index: %s", err)
 	}
 
+	// Create the 2dsphere indexes used by geospatial queries
+	for _, prop := range m.metadata.geoIndexedProperties() {
+		_, err = m.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: value + "." + prop, Value: "2dsphere"}},
+		})
+		if err != nil {
+			return fmt.Errorf("error in creating 2dsphere index on property '%s': %s", prop, err)
+		}
+	}
+
 	if !m.isReplicaSet {
 		m.logger.Info("Connected to MongoDB without a replica set. Transactions are not available, and the component cannot be used as actor state store.")
 	}
@@ -181,7 +203,16 @@ func (m *MongoDB) Set(ctx context.Context, req *state.SetRequest) error {
 	return nil
 }
 
+// SetWithMerge updates a key with the value returned by merge, retrying on ETag conflicts. Implements state.MergeSetter.
+func (m *MongoDB) SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge state.MergeFn) error {
+	return m.etagRetrier.SetWithMerge(ctx, key, metadata, merge)
+}
+
 func (m *MongoDB) Ping(ctx context.Context) error {
+	if m.client == nil {
+		return errors.New("mongoDB client not initialized")
+	}
+
 	if err := m.client.Ping(ctx, nil); err != nil {
 		return fmt.Errorf("error connecting to mongoDB at %s: %s", m.metadata.Host, err)
 	}
@@ -190,6 +221,10 @@ func (m *MongoDB) Ping(ctx context.Context) error {
 }
 
 func (m *MongoDB) setInternal(ctx context.Context, req *state.SetRequest) error {
+	if patch := req.Metadata[patchMetadataKey]; patch != "" {
+		return m.patchInternal(ctx, req, patch)
+	}
+
 	var v interface{}
 	switch obj := req.Value.(type) {
 	case []byte:
@@ -254,12 +289,21 @@ func (m *MongoDB) setInternal(ctx context.Context, req *state.SetRequest) error
 
 	_, err = m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
 	if err != nil {
+		if isETagConflict(err, filter) {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
 		return fmt.Errorf("error in updating document: %s", err)
 	}
 
 	return nil
 }
 
+// isETagConflict returns true if an upsert filtered on the ETag failed because a document with the key exists with a different ETag.
+func isETagConflict(err error, filter bson.M) bool {
+	_, ok := filter[etag]
+	return ok && mongo.IsDuplicateKeyError(err)
+}
+
 // Get retrieves state from MongoDB with a key.
 func (m *MongoDB) Get(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
 	filter := bson.D{
@@ -268,10 +312,25 @@ func (m *MongoDB) Get(ctx context.Context, req *state.GetRequest) (*state.GetRes
 			getFilterTTL(),
 		}},
 	}
+	opts := options.FindOne()
+	if projection := req.Metadata[projectionMetadataKey]; projection != "" {
+		p, err := buildProjection(projection)
+		if err != nil {
+			return &state.GetResponse{}, err
+		}
+		opts.SetProjection(p)
+	}
 	var result Item
 	err := m.collection.
-		FindOne(ctx, filter).
+		FindOne(ctx, filter, opts).
 		Decode(&result)
+	if err == nil && result.Value == nil && opts.Projection != nil {
+		// Values that aren't documents, such as strings, have no fields to project, so they're returned whole
+		result = Item{}
+		err = m.collection.
+			FindOne(ctx, filter).
+			Decode(&result)
+	}
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// Key not found, not an error.
@@ -292,6 +351,8 @@ func (m *MongoDB) Get(ctx context.Context, req *state.GetRequest) (*state.GetRes
 	}, nil
 }
 
+// BulkGet reads all keys with a single query.
+// Responses are returned in the order of the requests; documents that can't be decoded are reported in the Error field of their response.
 func (m *MongoDB) BulkGet(ctx context.Context, req []state.GetRequest, _ state.BulkGetOpts) ([]state.BulkGetResponse, error) {
 	// If nothing is being requested, short-circuit
 	if len(req) == 0 {
@@ -299,9 +360,12 @@ func (m *MongoDB) BulkGet(ctx context.Context, req []state.GetRequest, _ state.B
 	}
 
 	// Get all the keys
+	res := state.NewBulkGetResponses(req)
 	keys := make(bson.A, len(req))
+	idx := make(map[string][]int, len(req))
 	for i, r := range req {
 		keys[i] = r.Key
+		idx[r.Key] = append(idx[r.Key], i)
 	}
 
 	// Perform the query
@@ -316,43 +380,57 @@ func (m *MongoDB) BulkGet(ctx context.Context, req []state.GetRequest, _ state.B
 	cur, err := m.collection.Find(ctx, filter)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			// No documents found, just return an empty list
-			err = nil
+			// No documents found, just return the empty responses
+			return res, nil
 		}
-		return []state.BulkGetResponse{}, err
+		return nil, err
 	}
 	defer cur.Close(ctx)
 
 	// Read all results
-	res := make([]state.BulkGetResponse, 0, len(keys))
+	read := make(map[string]struct{}, len(req))
 	for cur.Next(ctx) {
-		var (
-			doc  Item
-			data []byte
-		)
-		err = cur.Decode(&doc)
-		if err != nil {
-			return res, err
+		key, ok := cur.Current.Lookup(id).StringValueOK()
+		if !ok {
+			continue
 		}
+		read[key] = struct{}{}
 
 		bgr := state.BulkGetResponse{
-			Key: doc.Key,
-		}
-		if doc.Etag != "" {
-			bgr.ETag = ptr.Of(doc.Etag)
+			Key: key,
 		}
-
-		data, err = m.decodeData(doc.Value)
+		var doc Item
+		err = cur.Decode(&doc)
 		if err != nil {
-			bgr.Error = err.Error()
+			bgr.Error = fmt.Sprintf("failed to decode document: %v", err)
 		} else {
-			bgr.Data = data
+			if doc.Etag != "" {
+				bgr.ETag = ptr.Of(doc.Etag)
+			}
+			var data []byte
+			data, err = m.decodeData(doc.Value)
+			if err != nil {
+				bgr.Error = err.Error()
+			} else {
+				bgr.Data = data
+			}
+		}
+		for _, i := range idx[key] {
+			res[i] = bgr
 		}
-		res = append(res, bgr)
 	}
+
+	// If the cursor fails, the keys that weren't read may exist, so they are reported as failed
 	err = cur.Err()
 	if err != nil {
-		return res, err
+		for key, ii := range idx {
+			if _, ok := read[key]; ok {
+				continue
+			}
+			for _, i := range ii {
+				res[i].Error = err.Error()
+			}
+		}
 	}
 
 	return res, nil
@@ -370,7 +448,13 @@ func getFilterTTL() bson.D {
 	}}}
 }
 
-func (m *MongoDB) decodeData(resValue any) (data []byte, err error) {
+func (m *MongoDB) decodeData(resValue any) ([]byte, error) {
+	return decodeValue(resValue, m.metadata.JSONOptions)
+}
+
+// decodeValue returns the JSON representation of a value read from the collection.
+func decodeValue(resValue any, opts stateutils.JSONOptions) (data []byte, err error) {
+	escapeHTML := !opts.JSONDisableHTMLEscape
 	switch obj := resValue.(type) {
 	case string:
 		data = []byte(obj)
@@ -382,24 +466,25 @@ func (m *MongoDB) decodeData(resValue any) (data []byte, err error) {
 		// See https://mongodb.github.io/swift-bson/docs/current/SwiftBSON/json-interop.html
 		// A decimal value stored as BSON will be returned as {"d": 5.5} if canonical is set to false instead of
 		// {"d": {"$numberDouble": 5.5}} when canonical JSON is returned.
-		if data, err = bson.MarshalExtJSON(obj, false, true); err != nil {
+		if data, err = bson.MarshalExtJSON(obj, false, escapeHTML); err != nil {
 			return nil, err
 		}
 	case primitive.A:
 		newobj := bson.D{{Key: value, Value: obj}}
 
-		if data, err = bson.MarshalExtJSON(newobj, false, true); err != nil {
+		if data, err = bson.MarshalExtJSON(newobj, false, escapeHTML); err != nil {
 			return nil, err
 		}
-		var input interface{}
-		json.Unmarshal(data, &input)
-		value := input.(map[string]interface{})[value]
-		if data, err = json.Marshal(value); err != nil {
+		var input map[string]any
+		if err = opts.Unmarshal(data, &input); err != nil {
+			return nil, err
+		}
+		if data, err = opts.Marshal(input[value]); err != nil {
 			return nil, err
 		}
 
 	default:
-		data, err = json.Marshal(obj)
+		data, err = opts.Marshal(obj)
 		if err != nil {
 			return nil, err
 		}
@@ -521,7 +606,10 @@ func (m *MongoDB) doTransaction(sessCtx mongo.SessionContext, operations []state
 
 // Query executes a query against store.
 func (m *MongoDB) Query(ctx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
-	q := &Query{}
+	q := &Query{
+		geoIndexedProperties: m.metadata.geoIndexedProperties(),
+		jsonOptions:          m.metadata.JSONOptions,
+	}
 	qbuilder := query.NewQueryBuilder(q)
 	if err := qbuilder.BuildQuery(&req.Query); err != nil {
 		return &state.QueryResponse{}, err
@@ -537,6 +625,32 @@ func (m *MongoDB) Query(ctx context.Context, req *state.QueryRequest) (*state.Qu
 	}, nil
 }
 
+// QueryStream executes a query, invoking fn with each page of results. Implements StreamingQuerier.
+// Documents are read from a single cursor, in batches of the fetch size, as pages are consumed.
+// If the query is sorted, the key is added as the last sort field, so results with the same sort values are in a stable order.
+func (m *MongoDB) QueryStream(ctx context.Context, req *state.QueryRequest, fetchSize int, fn state.QueryPageFn) error {
+	q := &Query{
+		geoIndexedProperties: m.metadata.geoIndexedProperties(),
+		jsonOptions:          m.metadata.JSONOptions,
+	}
+	qbuilder := query.NewQueryBuilder(q)
+	if err := qbuilder.BuildQuery(&req.Query); err != nil {
+		return err
+	}
+
+	pager := state.NewQueryPager(fetchSize, q.offset(), fn)
+	q.opts.SetBatchSize(int32(pager.FetchSize()))
+	if sort, ok := q.opts.Sort.(bson.D); ok {
+		q.opts.SetSort(append(sort, bson.E{Key: id, Value: 1}))
+	}
+
+	err := q.iterate(ctx, m.collection, pager.Add)
+	if err != nil {
+		return err
+	}
+	return pager.Flush()
+}
+
 func (metadata *mongoDBMetadata) getMongoConnectionString() string {
 	if metadata.ConnectionString != "" {
 		return metadata.ConnectionString
@@ -557,6 +671,18 @@ func (metadata *mongoDBMetadata) getMongoConnectionString() string {
 	return fmt.Sprintf(connectionURIFormat, metadata.Host, metadata.DatabaseName, metadata.Params)
 }
 
+// geoIndexedProperties returns the list of properties that have a 2dsphere index.
+func (metadata *mongoDBMetadata) geoIndexedProperties() []string {
+	props := []string{}
+	for _, p := range strings.Split(metadata.GeoIndexedProperties, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
 func (m *MongoDB) getMongoDBClient(ctx context.Context) (*mongo.Client, error) {
 	uri := m.metadata.getMongoConnectionString()
 
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestBulkGet(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("partial failures", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: id, Value: "b"}, {Key: value, Value: `{"b":1}`}, {Key: etag, Value: "etag-b"}},
+			// The value can't be decoded into an Item
+			bson.D{{Key: id, Value: "c"}, {Key: value, Value: "c"}, {Key: etag, Value: int32(1)}},
+			bson.D{{Key: id, Value: "a"}, {Key: value, Value: bson.D{{Key: "a", Value: int32(1)}}}, {Key: etag, Value: "etag-a"}},
+		))
+
+		m := &MongoDB{collection: mt.Coll}
+		res, err := m.BulkGet(context.Background(), []state.GetRequest{{Key: "a"}, {Key: "b"}, {Key: "c"}, {Key: "missing"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, 4)
+
+		// Responses are in the order of the requests
+		assert.Equal(t, "a", res[0].Key)
+		assert.Equal(t, `{"a":1}`, string(res[0].Data))
+		assert.Equal(t, "etag-a", *res[0].ETag)
+		assert.Empty(t, res[0].Error)
+
+		assert.Equal(t, "b", res[1].Key)
+		assert.Equal(t, `{"b":1}`, string(res[1].Data))
+		assert.Empty(t, res[1].Error)
+
+		assert.Equal(t, "c", res[2].Key)
+		assert.Nil(t, res[2].Data)
+		assert.Contains(t, res[2].Error, "failed to decode document")
+
+		assert.Equal(t, "missing", res[3].Key)
+		assert.Nil(t, res[3].Data)
+		assert.Empty(t, res[3].Error)
+	})
+
+	mt.Run("query failure", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		m := &MongoDB{collection: mt.Coll}
+		_, err := m.BulkGet(context.Background(), []state.GetRequest{{Key: "a"}}, state.BulkGetOpts{})
+		require.ErrorContains(t, err, "boom")
+	})
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dapr/components-contrib/state"
+	stateutils "github.com/dapr/components-contrib/state/utils"
+)
+
+const (
+	// Metadata key on SetRequest containing a patch document.
+	// When set, the value of the request is ignored and the stored value is updated in place.
+	patchMetadataKey = "patch"
+
+	patchSetOperator   = "$set"
+	patchUnsetOperator = "$unset"
+)
+
+// buildPatchUpdate parses a patch document and returns the corresponding MongoDB update document.
+// The patch document is a JSON object with the "$set" and/or "$unset" operators, whose field paths are relative to the stored value, for example:
+//
+//	{"$set": {"address.city": "Seattle"}, "$unset": ["phone"]}
+//
+// "$unset" accepts either an array of paths or an object whose keys are the paths (as in MongoDB).
+func buildPatchUpdate(patch string, newEtag string) (bson.D, error) {
+	var doc map[string]json.RawMessage
+	err := json.Unmarshal([]byte(patch), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch document: %w", err)
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("invalid patch document: at least one of '$set' or '$unset' is required")
+	}
+
+	setFields := bson.D{}
+	unsetFields := bson.D{}
+	for op, raw := range doc {
+		switch op {
+		case patchSetOperator:
+			var fields map[string]json.RawMessage
+			err = json.Unmarshal(raw, &fields)
+			if err != nil {
+				return nil, fmt.Errorf("invalid patch document: '%s' must be an object: %w", op, err)
+			}
+			for _, path := range sortedKeys(fields) {
+				err = validatePatchPath(path)
+				if err != nil {
+					return nil, err
+				}
+				var v any
+				dec := json.NewDecoder(bytes.NewReader(fields[path]))
+				dec.UseNumber()
+				err = dec.Decode(&v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid patch document: invalid value for '%s': %w", path, err)
+				}
+				setFields = append(setFields, bson.E{Key: value + "." + path, Value: v})
+			}
+		case patchUnsetOperator:
+			paths, err := parseUnsetPaths(raw)
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range paths {
+				err = validatePatchPath(path)
+				if err != nil {
+					return nil, err
+				}
+				unsetFields = append(unsetFields, bson.E{Key: value + "." + path, Value: ""})
+			}
+		default:
+			return nil, fmt.Errorf("invalid patch document: unsupported operator '%s'", op)
+		}
+	}
+
+	// Every patch generates a new ETag
+	setFields = append(setFields, bson.E{Key: etag, Value: newEtag})
+	update := bson.D{{Key: patchSetOperator, Value: setFields}}
+	if len(unsetFields) > 0 {
+		update = append(update, bson.E{Key: patchUnsetOperator, Value: unsetFields})
+	}
+	// Documents created by a patch have no TTL, but the field must exist for the TTL filter to match them
+	update = append(update, bson.E{Key: "$setOnInsert", Value: bson.D{{Key: ttl, Value: nil}}})
+
+	return update, nil
+}
+
+func parseUnsetPaths(raw json.RawMessage) ([]string, error) {
+	var paths []string
+	if json.Unmarshal(raw, &paths) == nil {
+		return paths, nil
+	}
+
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(raw, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch document: '%s' must be an array or an object", patchUnsetOperator)
+	}
+	return sortedKeys(fields), nil
+}
+
+func validatePatchPath(path string) error {
+	if path == "" {
+		return errors.New("invalid patch document: field paths cannot be empty")
+	}
+	for _, part := range strings.Split(path, ".") {
+		if part == "" || strings.HasPrefix(part, "$") {
+			return fmt.Errorf("invalid patch document: invalid field path '%s'", path)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// patchInternal applies a partial update to the stored value.
+// If the request has an ETag, the update is performed only if the ETag matches; otherwise, the document is created if it doesn't exist.
+func (m *MongoDB) patchInternal(ctx context.Context, req *state.SetRequest, patch string) error {
+	if _, ok := req.Metadata[stateutils.MetadataTTLKey]; ok {
+		return errors.New("TTLs are not supported when patching a value")
+	}
+
+	etagV, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	update, err := buildPatchUpdate(patch, etagV.String())
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{id: req.Key}
+	upsert := true
+	if req.ETag != nil {
+		filter[etag] = *req.ETag
+		upsert = false
+	} else if req.Options.Concurrency == state.FirstWrite {
+		// With first-write concurrency, the document must not exist yet
+		filter[etag] = etagV.String()
+	}
+
+	res, err := m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(upsert))
+	if err != nil {
+		if isETagConflict(err, filter) {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
+		return fmt.Errorf("error in patching document: %s", err)
+	}
+	if req.ETag != nil && res.MatchedCount == 0 {
+		return state.NewETagError(state.ETagMismatch, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestBuildPatchUpdate(t *testing.T) {
+	t.Run("set and unset", func(t *testing.T) {
+		update, err := buildPatchUpdate(`{"$set": {"address.city": "Seattle", "age": 42}, "$unset": ["phone"]}`, "etag1")
+		require.NoError(t, err)
+
+		expected := bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "value.address.city", Value: "Seattle"},
+				{Key: "value.age", Value: json.Number("42")},
+				{Key: "_etag", Value: "etag1"},
+			}},
+			{Key: "$unset", Value: bson.D{
+				{Key: "value.phone", Value: ""},
+			}},
+			{Key: "$setOnInsert", Value: bson.D{
+				{Key: "_ttl", Value: nil},
+			}},
+		}
+		assert.Equal(t, expected, update)
+	})
+
+	t.Run("unset as object", func(t *testing.T) {
+		update, err := buildPatchUpdate(`{"$unset": {"b": "", "a": ""}}`, "etag1")
+		require.NoError(t, err)
+
+		expected := bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "_etag", Value: "etag1"},
+			}},
+			{Key: "$unset", Value: bson.D{
+				{Key: "value.a", Value: ""},
+				{Key: "value.b", Value: ""},
+			}},
+			{Key: "$setOnInsert", Value: bson.D{
+				{Key: "_ttl", Value: nil},
+			}},
+		}
+		assert.Equal(t, expected, update)
+	})
+
+	t.Run("nested object value", func(t *testing.T) {
+		update, err := buildPatchUpdate(`{"$set": {"a": {"b": [1, "x"]}}}`, "etag1")
+		require.NoError(t, err)
+
+		set := update[0].Value.(bson.D)
+		require.Len(t, set, 2)
+		assert.Equal(t, "value.a", set[0].Key)
+		assert.Equal(t, map[string]any{"b": []any{json.Number("1"), "x"}}, set[0].Value)
+	})
+
+	t.Run("invalid documents", func(t *testing.T) {
+		invalid := []string{
+			`not json`,
+			`{}`,
+			`{"$inc": {"a": 1}}`,
+			`{"$set": ["a"]}`,
+			`{"$set": {"": 1}}`,
+			`{"$set": {"a..b": 1}}`,
+			`{"$set": {"a.$b": 1}}`,
+			`{"$unset": "a"}`,
+		}
+		for _, patch := range invalid {
+			_, err := buildPatchUpdate(patch, "etag1")
+			assert.Error(t, err, patch)
+		}
+	})
+}
+
+func TestUpsertETagConflict(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("first-write patch of an existing key is an ETag mismatch", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "E11000 duplicate key error collection: daprStore.daprCollection index: _id_ dup key",
+		}))
+
+		m := &MongoDB{collection: mt.Coll}
+		err := m.Set(context.Background(), &state.SetRequest{
+			Key:      "key",
+			Metadata: map[string]string{patchMetadataKey: `{"$set": {"a": 1}}`},
+			Options:  state.SetStateOption{Concurrency: state.FirstWrite},
+		})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+
+	mt.Run("set with a stale ETag is an ETag mismatch", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "E11000 duplicate key error",
+		}))
+
+		m := &MongoDB{collection: mt.Coll}
+		err := m.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", ETag: ptr.Of("stale")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Metadata key on GetRequest containing a projection document, to return only some fields of the stored value.
+const projectionMetadataKey = "projection"
+
+// buildProjection parses a projection document and returns the corresponding MongoDB projection.
+// The projection document is a JSON object whose keys are field paths relative to the stored value, with 1 to include them or 0 to exclude them, for example:
+//
+//	{"customer.name": 1, "total": 1}
+//
+// As in MongoDB, inclusions and exclusions can't be mixed. The ETag is always returned, so the response can be used for concurrency checks.
+func buildProjection(projection string) (bson.D, error) {
+	var doc map[string]json.RawMessage
+	err := json.Unmarshal([]byte(projection), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid projection document: %w", err)
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("invalid projection document: at least one field is required")
+	}
+
+	res := make(bson.D, 0, len(doc)+1)
+	var include *bool
+	for _, path := range sortedKeys(doc) {
+		err = validateProjectionPath(path)
+		if err != nil {
+			return nil, err
+		}
+		var incl bool
+		switch strings.TrimSpace(string(doc[path])) {
+		case "1", "true":
+			incl = true
+		case "0", "false":
+			incl = false
+		default:
+			return nil, fmt.Errorf("invalid projection document: value for '%s' must be 1 or 0", path)
+		}
+		if include != nil && *include != incl {
+			return nil, errors.New("invalid projection document: inclusions and exclusions can't be mixed")
+		}
+		include = &incl
+
+		v := 0
+		if incl {
+			v = 1
+		}
+		res = append(res, bson.E{Key: value + "." + path, Value: v})
+	}
+	if *include {
+		res = append(res, bson.E{Key: etag, Value: 1})
+	}
+
+	return res, nil
+}
+
+func validateProjectionPath(path string) error {
+	for _, part := range strings.Split(path, ".") {
+		if part == "" || strings.HasPrefix(part, "$") {
+			return fmt.Errorf("invalid projection document: invalid field path '%s'", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestBuildProjection(t *testing.T) {
+	t.Run("inclusion keeps the ETag", func(t *testing.T) {
+		p, err := buildProjection(`{"total": 1, "customer.name": true}`)
+		require.NoError(t, err)
+		assert.Equal(t, bson.D{
+			{Key: "value.customer.name", Value: 1},
+			{Key: "value.total", Value: 1},
+			{Key: "_etag", Value: 1},
+		}, p)
+	})
+
+	t.Run("exclusion", func(t *testing.T) {
+		p, err := buildProjection(`{"history": 0}`)
+		require.NoError(t, err)
+		assert.Equal(t, bson.D{{Key: "value.history", Value: 0}}, p)
+	})
+
+	for name, projection := range map[string]string{
+		"not json":     `customer.name`,
+		"empty":        `{}`,
+		"mixed":        `{"a": 1, "b": 0}`,
+		"invalid path": `{"a..b": 1}`,
+		"operator":     `{"$where": 1}`,
+		"invalid flag": `{"a": "yes"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := buildProjection(projection)
+			require.ErrorContains(t, err, "invalid projection document")
+		})
+	}
+}
+
+func TestGetWithProjection(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	req := &state.GetRequest{Key: "order", Metadata: map[string]string{projectionMetadataKey: `{"total": 1}`}}
+
+	mt.Run("projected document", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.coll", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "order"},
+			{Key: "value", Value: bson.D{{Key: "total", Value: 42}}},
+			{Key: "_etag", Value: "etag1"},
+		}))
+
+		m := &MongoDB{collection: mt.Coll}
+		res, err := m.Get(context.Background(), req)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"total": 42}`, string(res.Data))
+		require.NotNil(t, res.ETag)
+		assert.Equal(t, "etag1", *res.ETag)
+
+		projection := mt.GetStartedEvent().Command.Lookup("projection").Document()
+		assert.Equal(t, int32(1), projection.Lookup("value.total").Int32())
+		assert.Equal(t, int32(1), projection.Lookup("_etag").Int32())
+	})
+
+	mt.Run("value that isn't a document", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.coll", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: "order"},
+				{Key: "_etag", Value: "etag1"},
+			}),
+			mtest.CreateCursorResponse(0, "db.coll", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: "order"},
+				{Key: "value", Value: `"plain"`},
+				{Key: "_etag", Value: "etag1"},
+			}),
+		)
+
+		m := &MongoDB{collection: mt.Coll}
+		res, err := m.Get(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, `"plain"`, string(res.Data))
+	})
+
+	mt.Run("invalid projection", func(mt *mtest.T) {
+		m := &MongoDB{collection: mt.Coll}
+		_, err := m.Get(context.Background(), &state.GetRequest{Key: "order", Metadata: map[string]string{projectionMetadataKey: `{"a": 1, "b": 0}`}})
+		require.Error(t, err)
+	})
+}
@@ -15,24 +15,35 @@ package mongodb
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slices"
 
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
+	stateutils "github.com/dapr/components-contrib/state/utils"
 )
 
+// Mean radius of the Earth in meters, used to convert distances to radians.
+const earthRadiusMeters = 6371008.8
+
 type Query struct {
 	query  string
 	filter interface{}
 	opts   *options.FindOptions
+
+	// Properties that have a 2dsphere index and can be used in geospatial filters.
+	geoIndexedProperties []string
+	// Number of OR filters that contain the filter being visited; MongoDB rejects $near in $or expressions
+	orDepth int
+
+	jsonOptions stateutils.JSONOptions
 }
 
 func (q *Query) VisitEQ(f *query.EQ) (string, error) {
@@ -68,6 +79,29 @@ func (q *Query) VisitIN(f *query.IN) (string, error) {
 	return str, nil
 }
 
+func (q *Query) VisitGEO(f *query.GEO) (string, error) {
+	if !slices.Contains(q.geoIndexedProperties, f.Key) {
+		return "", fmt.Errorf("geospatial filter on %q requires a 2dsphere index: add the property to the 'geoIndexedProperties' metadata", f.Key)
+	}
+
+	if f.SortByDistance {
+		if q.orDepth > 0 {
+			return "", fmt.Errorf("geospatial filter on %q can't sort by distance inside an OR filter", f.Key)
+		}
+		// { <key>: { $near: { $geometry: { type: "Point", coordinates: [ <lng>, <lat> ] }, $maxDistance: <meters>, $minDistance: <meters> } } }
+		str := fmt.Sprintf(`{ "value.%s": { "$near": { "$geometry": { "type": "Point", "coordinates": [ %v, %v ] }, "$maxDistance": %v`,
+			f.Key, f.Center[0], f.Center[1], f.Radius)
+		if f.MinDistance > 0 {
+			str += fmt.Sprintf(`, "$minDistance": %v`, f.MinDistance)
+		}
+		return str + " } } }", nil
+	}
+
+	// { <key>: { $geoWithin: { $centerSphere: [ [ <lng>, <lat> ], <radians> ] } } }
+	return fmt.Sprintf(`{ "value.%s": { "$geoWithin": { "$centerSphere": [ [ %v, %v ], %v ] } } }`,
+		f.Key, f.Center[0], f.Center[1], f.Radius/earthRadiusMeters), nil
+}
+
 func (q *Query) visitFilters(op string, filters []query.Filter) (string, error) {
 	var (
 		arr []string
@@ -96,6 +130,11 @@ func (q *Query) visitFilters(op string, filters []query.Filter) (string, error)
 				return "", err
 			}
 			arr = append(arr, str)
+		case *query.GEO:
+			if str, err = q.VisitGEO(f); err != nil {
+				return "", err
+			}
+			arr = append(arr, str)
 		default:
 			return "", fmt.Errorf("unsupported filter type %#v", f)
 		}
@@ -111,6 +150,8 @@ func (q *Query) VisitAND(f *query.AND) (string, error) {
 
 func (q *Query) VisitOR(f *query.OR) (string, error) {
 	// { $or: [ { <expression1> }, { <expression2> } , ... , { <expressionN> } ] }
+	q.orDepth++
+	defer func() { q.orDepth-- }()
 	return q.visitFilters("$or", f.Filters)
 }
 
@@ -151,55 +192,73 @@ func (q *Query) Finalize(filters string, qq *query.Query) error {
 }
 
 func (q *Query) execute(ctx context.Context, collection *mongo.Collection) ([]state.QueryItem, string, error) {
-	cur, err := collection.Find(ctx, q.filter, []*options.FindOptions{q.opts}...)
+	ret := []state.QueryItem{}
+	err := q.iterate(ctx, collection, func(item state.QueryItem) error {
+		ret = append(ret, item)
+		return nil
+	})
 	if err != nil {
 		return nil, "", err
 	}
+	// set next query token only if limit is specified
+	var token string
+	if q.opts.Limit != nil && *q.opts.Limit != 0 {
+		token = strconv.FormatInt(q.offset()+int64(len(ret)), 10)
+	}
+
+	return ret, token, nil
+}
+
+// iterate executes the query and invokes fn for each result, as documents are read from the cursor.
+func (q *Query) iterate(ctx context.Context, collection *mongo.Collection, fn func(item state.QueryItem) error) error {
+	cur, err := collection.Find(ctx, q.filter, []*options.FindOptions{q.opts}...)
+	if err != nil {
+		if isMissingIndexError(err) {
+			return fmt.Errorf("the 2dsphere index required by the geospatial filter is missing: %w", err)
+		}
+		return err
+	}
 	defer cur.Close(ctx)
-	ret := []state.QueryItem{}
 	for cur.Next(ctx) {
 		var item Item
 		if err = cur.Decode(&item); err != nil {
-			return nil, "", err
+			return err
 		}
 		result := state.QueryItem{
 			Key:  item.Key,
 			ETag: &item.Etag,
 		}
 
-		switch obj := item.Value.(type) {
-		case string:
-			result.Data = []byte(obj)
-		case primitive.D:
-			// Setting canonical to `false`.
-			// See https://docs.mongodb.com/manual/reference/mongodb-extended-json/#bson-data-types-and-associated-representations
-			// Having bson marshalled into Relaxed JSON instead of canonical JSON, this way type preservation is lost but
-			// interoperability is preserved
-			// See https://mongodb.github.io/swift-bson/docs/current/SwiftBSON/json-interop.html
-			// A decimal value stored as BSON will be returned as {"d": 5.5} if canonical is set to false instead of
-			// {"d": {"$numberDouble": 5.5}} when canonical JSON is returned.
-			if result.Data, err = bson.MarshalExtJSON(obj, false, true); err != nil {
-				result.Error = err.Error()
-			}
-		default:
-			if result.Data, err = json.Marshal(item.Value); err != nil {
-				result.Error = err.Error()
-			}
+		if result.Data, err = decodeValue(item.Value, q.jsonOptions); err != nil {
+			result.Error = err.Error()
 		}
-		ret = append(ret, result)
-	}
-	if err = cur.Err(); err != nil {
-		return nil, "", err
-	}
-	// set next query token only if limit is specified
-	var token string
-	if q.opts.Limit != nil && *q.opts.Limit != 0 {
-		var skip int64
-		if q.opts.Skip != nil {
-			skip = *q.opts.Skip
+		if err = fn(result); err != nil {
+			return err
 		}
-		token = strconv.FormatInt(skip+int64(len(ret)), 10)
 	}
+	return cur.Err()
+}
 
-	return ret, token, nil
+const (
+	// Code of the server error returned when the index required by the query doesn't exist.
+	indexNotFoundErrorCode = 27
+	// Code of the server error returned by $geoNear, $near and $text queries without the index they require.
+	noQueryExecutionPlansErrorCode = 291
+)
+
+// isMissingIndexError returns true if the server rejected the query because the index it requires is missing.
+func isMissingIndexError(err error) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	return cmdErr.HasErrorCode(indexNotFoundErrorCode) || cmdErr.HasErrorCode(noQueryExecutionPlansErrorCode)
+}
+
+// offset returns the number of results skipped, from the token of the query.
+func (q *Query) offset() int64 {
+	if q.opts.Skip == nil {
+		return 0
+	}
+	return *q.opts.Skip
 }
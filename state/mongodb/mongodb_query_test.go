@@ -14,12 +14,18 @@ limitations under the License.
 package mongodb
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
 )
 
@@ -44,6 +50,14 @@ func TestMongoQuery(t *testing.T) {
 			input: "../../tests/state/query/q4.json",
 			query: `{ "$or": [ { "value.person.org": "A" }, { "$and": [ { "value.person.org": "B" }, { "value.state": { "$in": [ "CA", "WA" ] } } ] } ] }`,
 		},
+		{
+			input: "../../tests/state/query/q7.json",
+			query: `{ "$and": [ { "value.type": "store" }, { "value.location": { "$geoWithin": { "$centerSphere": [ [ -122.33, 47.6 ], 0.0007848050688613081 ] } } } ] }`,
+		},
+		{
+			input: "../../tests/state/query/q8.json",
+			query: `{ "value.location": { "$near": { "$geometry": { "type": "Point", "coordinates": [ -122.33, 47.6 ] }, "$maxDistance": 5000, "$minDistance": 100 } } }`,
+		},
 		{
 			input: "../../tests/state/query/q6.json",
 			query: `{ "$or": [ { "value.person.id": 123 }, { "$and": [ { "value.person.org": "B" }, { "value.person.id": { "$in": [ 567, 890 ] } } ] } ] }`,
@@ -56,10 +70,94 @@ func TestMongoQuery(t *testing.T) {
 		err = json.Unmarshal(data, &qq)
 		assert.NoError(t, err)
 
-		q := &Query{}
+		q := &Query{
+			geoIndexedProperties: []string{"location"},
+		}
 		qbuilder := query.NewQueryBuilder(q)
 		err = qbuilder.BuildQuery(&qq)
 		assert.NoError(t, err)
 		assert.Equal(t, test.query, q.query)
 	}
 }
+
+func TestMongoQueryGeoWithoutIndex(t *testing.T) {
+	data, err := os.ReadFile("../../tests/state/query/q7.json")
+	assert.NoError(t, err)
+	var qq query.Query
+	err = json.Unmarshal(data, &qq)
+	assert.NoError(t, err)
+
+	q := &Query{}
+	qbuilder := query.NewQueryBuilder(q)
+	err = qbuilder.BuildQuery(&qq)
+	assert.ErrorContains(t, err, "requires a 2dsphere index")
+}
+
+func TestMongoQueryMissingIndex(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	iterate := func(mt *mtest.T, code int32, message string) error {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: code, Message: message}))
+		q := &Query{filter: bson.D{}, opts: options.Find()}
+		return q.iterate(context.Background(), mt.Coll, func(state.QueryItem) error { return nil })
+	}
+
+	mt.Run("index not found", func(mt *mtest.T) {
+		err := iterate(mt, indexNotFoundErrorCode, "index not found")
+		assert.ErrorContains(t, err, "the 2dsphere index required by the geospatial filter is missing")
+	})
+
+	mt.Run("no query execution plans", func(mt *mtest.T) {
+		// The message doesn't matter
+		err := iterate(mt, noQueryExecutionPlansErrorCode, "error processing query")
+		assert.ErrorContains(t, err, "the 2dsphere index required by the geospatial filter is missing")
+	})
+
+	mt.Run("other errors", func(mt *mtest.T) {
+		err := iterate(mt, 2, "unable to find index")
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "the 2dsphere index required")
+	})
+}
+
+func TestMongoQueryGeoSortInOr(t *testing.T) {
+	var qq query.Query
+	err := json.Unmarshal([]byte(`{
+		"filter": {
+			"OR": [
+				{"EQ": {"type": "store"}},
+				{"AND": [
+					{"EQ": {"type": "kiosk"}},
+					{"GEO": {"location": {"center": [-122.33, 47.6], "radius": 5000, "sortByDistance": true}}}
+				]}
+			]
+		}
+	}`), &qq)
+	require.NoError(t, err)
+
+	q := &Query{
+		geoIndexedProperties: []string{"location"},
+	}
+	err = query.NewQueryBuilder(q).BuildQuery(&qq)
+	assert.ErrorContains(t, err, "can't sort by distance inside an OR filter")
+
+	// Without sorting, the filter is a $geoWithin, which is allowed in $or expressions
+	qq = query.Query{}
+	err = json.Unmarshal([]byte(`{
+		"filter": {
+			"OR": [
+				{"EQ": {"type": "store"}},
+				{"GEO": {"location": {"center": [-122.33, 47.6], "radius": 5000}}}
+			]
+		}
+	}`), &qq)
+	require.NoError(t, err)
+	q = &Query{
+		geoIndexedProperties: []string{"location"},
+	}
+	err = query.NewQueryBuilder(q).BuildQuery(&qq)
+	require.NoError(t, err)
+	assert.Contains(t, q.query, `"$or"`)
+	assert.Contains(t, q.query, `"$geoWithin"`)
+}
@@ -14,12 +14,18 @@ limitations under the License.
 package mongodb
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
+	stateutils "github.com/dapr/components-contrib/state/utils"
 )
 
 func TestGetMongoDBMetadata(t *testing.T) {
@@ -208,3 +214,95 @@ func TestGetMongoDBMetadata(t *testing.T) {
 		assert.Equal(t, expected, uri)
 	})
 }
+
+func TestDecodeValue(t *testing.T) {
+	doc := primitive.D{{Key: "id", Value: int64(12345678901234567)}, {Key: "q", Value: "a&b<c>"}}
+	arr := primitive.A{int64(12345678901234567), "a&b<c>"}
+
+	t.Run("default options", func(t *testing.T) {
+		var opts stateutils.JSONOptions
+		res, err := decodeValue(doc, opts)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":12345678901234567,"q":"a\u0026b\u003cc\u003e"}`, string(res))
+
+		// Numbers in arrays are converted to float64
+		res, err = decodeValue(arr, opts)
+		require.NoError(t, err)
+		assert.Equal(t, `[12345678901234568,"a\u0026b\u003cc\u003e"]`, string(res))
+	})
+
+	t.Run("values are preserved", func(t *testing.T) {
+		opts := stateutils.JSONOptions{JSONDisableHTMLEscape: true, JSONUseNumber: true}
+		res, err := decodeValue(doc, opts)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":12345678901234567,"q":"a&b<c>"}`, string(res))
+
+		res, err = decodeValue(arr, opts)
+		require.NoError(t, err)
+		assert.Equal(t, `[12345678901234567,"a&b<c>"]`, string(res))
+
+		res, err = decodeValue(map[string]string{"q": "a&b"}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, `{"q":"a&b"}`, string(res))
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		m, err := getMongoDBMetaData(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			host:                    "127.0.0.1",
+			"jsonDisableHTMLEscape": "true",
+			"jsonUseNumber":         "true",
+		}}})
+		require.NoError(t, err)
+		assert.True(t, m.JSONDisableHTMLEscape)
+		assert.True(t, m.JSONUseNumber)
+	})
+}
+
+func TestSetWithMerge(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	dupKeyErr := mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}
+	merge := func(current *state.GetResponse) (any, error) {
+		return "merged", nil
+	}
+
+	mt.Run("retries on conflicts", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			// The key doesn't exist, but it's created before the first write
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateWriteErrorsResponse(dupKeyErr),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+				{Key: id, Value: "key"}, {Key: value, Value: `"v"`}, {Key: etag, Value: "e1"},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		retryConfig, err := state.ParseETagRetryConfig(map[string]string{
+			"etagRetryMaxRetries": "1",
+			"etagRetryDuration":   "1ms",
+		})
+		require.NoError(t, err)
+		m := &MongoDB{collection: mt.Coll}
+		m.etagRetrier = state.NewETagRetrier(m, retryConfig)
+		require.NoError(t, m.SetWithMerge(context.Background(), "key", nil, merge))
+	})
+
+	mt.Run("conflict without retries", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateWriteErrorsResponse(dupKeyErr),
+		)
+
+		retryConfig, err := state.ParseETagRetryConfig(nil)
+		require.NoError(t, err)
+		m := &MongoDB{collection: mt.Coll}
+		m.etagRetrier = state.NewETagRetrier(m, retryConfig)
+		err = m.SetWithMerge(context.Background(), "key", nil, merge)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// Touch resets the TTL of a key, without transferring its value or changing its ETag.
+// Only documents that have a TTL which hasn't expired are updated; if none is, the document is read without its value to tell why.
+func (m *MongoDB) Touch(ctx context.Context, req *state.TouchRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+
+	filter := bson.D{
+		{Key: id, Value: req.Key},
+		{Key: ttl, Value: bson.M{"$ne": nil}},
+		{Key: "$expr", Value: bson.D{{Key: "$gte", Value: bson.A{"$" + ttl, "$$NOW"}}}},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			// MongoDB stores time in milliseconds
+			{Key: ttl, Value: bson.D{{Key: "$add", Value: bson.A{"$$NOW", req.TTL.Milliseconds()}}}},
+		}}},
+	}
+	res, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error in updating document: %w", err)
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// The document doesn't exist, has expired, or doesn't have a TTL
+	err = m.collection.FindOne(ctx,
+		bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: id, Value: bson.M{"$eq": req.Key}}},
+			getFilterTTL(),
+		}}},
+		options.FindOne().SetProjection(bson.D{{Key: id, Value: 1}}),
+	).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: %s", state.ErrTouchKeyNotFound, req.Key)
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", state.ErrTouchNoTTL, req.Key)
+}
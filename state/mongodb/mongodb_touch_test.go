@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestTouch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	req := &state.TouchRequest{Key: "session", TTL: time.Minute}
+
+	mt.Run("ttl reset", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		m := &MongoDB{collection: mt.Coll}
+		require.NoError(t, m.Touch(context.Background(), req))
+	})
+
+	mt.Run("key without ttl", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: id, Value: "session"}}),
+		)
+
+		m := &MongoDB{collection: mt.Coll}
+		require.ErrorIs(t, m.Touch(context.Background(), req), state.ErrTouchNoTTL)
+	})
+
+	mt.Run("key not found", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		m := &MongoDB{collection: mt.Coll}
+		require.ErrorIs(t, m.Touch(context.Background(), req), state.ErrTouchKeyNotFound)
+	})
+
+	mt.Run("invalid request", func(mt *mtest.T) {
+		m := &MongoDB{collection: mt.Coll}
+		require.Error(t, m.Touch(context.Background(), &state.TouchRequest{Key: "session"}))
+	})
+}
@@ -22,12 +22,13 @@ import (
 
 	"github.com/go-sql-driver/mysql"
 
+	sqlCleanup "github.com/dapr/components-contrib/internal/component/sql"
 	"github.com/dapr/kit/logger"
 )
 
 // This interface is used to help improve testing.
 type iMySQLFactory interface {
-	Open(connectionString string) (*sql.DB, error)
+	Open(connectionString string, health sqlCleanup.HealthMetadata) (*sql.DB, error)
 	RegisterTLSConfig(pemPath string) error
 }
 
@@ -41,8 +42,8 @@ func newMySQLFactory(logger logger.Logger) *mySQLFactory {
 	}
 }
 
-func (m *mySQLFactory) Open(connectionString string) (*sql.DB, error) {
-	return sql.Open("mysql", connectionString)
+func (m *mySQLFactory) Open(connectionString string, health sqlCleanup.HealthMetadata) (*sql.DB, error) {
+	return sqlCleanup.OpenDB("mysql", connectionString, health)
 }
 
 func (m *mySQLFactory) RegisterTLSConfig(pemPath string) error {
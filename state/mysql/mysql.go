@@ -20,14 +20,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 
 	sqlCleanup "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/utils"
@@ -56,6 +60,10 @@ const (
 	// Used if the user does not configure a database name in the metadata.
 	defaultSchemaName = "dapr_state_store"
 
+	// The key name in the metadata for the timeout of each statement executed by Get, Set, Delete and Multi.
+	// This is parsed as a Go duration; if not set, statements are bound only by timeoutInSeconds.
+	queryTimeoutKey = "queryTimeout"
+
 	// Used if the user does not provide a timeoutInSeconds value in the metadata.
 	defaultTimeoutInSeconds = 20
 
@@ -81,7 +89,23 @@ type MySQL struct {
 	cleanupInterval   *time.Duration
 	schemaName        string
 	connectionString  string
+	socketPath        string
+	health            sqlCleanup.HealthMetadata
 	timeout           time.Duration
+	queryTimeout      time.Duration
+	validateOnly      bool
+	warmupConnections int
+	lazyConnect       bool
+	jsonOptions       utils.JSONOptions
+
+	// Codec used to serialize values; its name is stored with each value in the serializer column
+	codec statecodec.Codec
+
+	// Set when lazyConnect is true, to complete the initialization on the first operation
+	lazyInit *internalutils.LazyInit
+
+	// Report created by Init in validate-only mode
+	validationReport *state.ValidationReport
 
 	// Instance of the database to issue commands to
 	db *sql.DB
@@ -94,13 +118,28 @@ type MySQL struct {
 }
 
 type mySQLMetadata struct {
-	TableName         string
-	SchemaName        string
-	ConnectionString  string
+	TableName        string
+	SchemaName       string
+	ConnectionString string
+	// Discrete connection properties, used to build the connection string if ConnectionString is empty
+	sqlCleanup.ConnectionMetadata `mapstructure:",squash"`
+	// Validation of pooled connections before they're used
+	sqlCleanup.HealthMetadata `mapstructure:",squash"`
+
 	Timeout           int
 	PemPath           string
 	MetadataTableName string
 	CleanupInterval   *time.Duration
+	QueryTimeout      time.Duration
+	ValidateOnly      bool
+	WarmupConnections int
+	LazyInit          bool
+
+	// Options of the JSON encoding of values
+	utils.JSONOptions `mapstructure:",squash"`
+
+	// Codec used to serialize values: "json" (the default), "msgpack", or "protobuf-passthrough"
+	Serializer string
 }
 
 // NewMySQLStateStore creates a new instance of MySQL state store.
@@ -137,12 +176,31 @@ func (m *MySQL) Init(ctx context.Context, metadata state.Metadata) error {
 		return err
 	}
 
-	db, err := m.factory.Open(m.connectionString)
+	// When connecting over a Unix domain socket, ensure it exists so we can return a clear error
+	m.socketPath = unixSocketPath(m.connectionString)
+	if m.socketPath != "" {
+		err = sqlCleanup.ValidateUnixSocket(m.socketPath)
+		if err != nil {
+			m.logger.Error(err)
+			return err
+		}
+	}
+
+	db, err := m.factory.Open(m.connectionString, m.health)
 	if err != nil {
 		m.logger.Error(err)
 		return err
 	}
 
+	// Opening the database doesn't connect, so with lazy init nothing else is done until the first operation
+	if m.lazyConnect {
+		m.db = db
+		m.lazyInit = internalutils.NewLazyInit(func(ctx context.Context) error {
+			return m.finishInit(ctx, m.db)
+		})
+		return nil
+	}
+
 	// will be nil if everything is good or an err that needs to be returned
 	return m.finishInit(ctx, db)
 }
@@ -184,11 +242,39 @@ func (m *MySQL) parseMetadata(md map[string]string) error {
 	}
 	m.schemaName = meta.SchemaName
 
+	meta.ConnectionString, err = meta.ConnectionMetadata.ConnectionString(meta.ConnectionString, func(c sqlCleanup.ConnectionMetadata) string {
+		return buildConnectionString(c, meta.PemPath != "")
+	})
+	if err != nil {
+		return err
+	}
 	if meta.ConnectionString == "" {
 		m.logger.Error("Missing MySql connection string")
 		return fmt.Errorf(errMissingConnectionString)
 	}
 	m.connectionString = meta.ConnectionString
+	m.health = meta.HealthMetadata
+	m.validateOnly = meta.ValidateOnly
+
+	if meta.QueryTimeout < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", queryTimeoutKey)
+	}
+	m.queryTimeout = meta.QueryTimeout
+
+	if meta.WarmupConnections < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", internalutils.WarmupConnectionsKey)
+	}
+	if meta.LazyInit && meta.ValidateOnly {
+		return fmt.Errorf("metadata property '%s' can't be used with '%s'", internalutils.LazyInitKey, state.ValidateOnlyKey)
+	}
+	m.warmupConnections = meta.WarmupConnections
+	m.lazyConnect = meta.LazyInit
+	m.jsonOptions = meta.JSONOptions
+
+	m.codec, err = statecodec.Parse(meta.Serializer)
+	if err != nil {
+		return err
+	}
 
 	// Cleanup interval
 	if meta.CleanupInterval != nil {
@@ -232,6 +318,11 @@ func (m *MySQL) Features() []state.Feature {
 	return []state.Feature{state.FeatureETag, state.FeatureTransactional}
 }
 
+// ValidationReport returns the report created by Init in validate-only mode, or nil if the state store wasn't initialized in that mode.
+func (m *MySQL) ValidationReport() *state.ValidationReport {
+	return m.validationReport
+}
+
 // Ping the database.
 func (m *MySQL) Ping(ctx context.Context) error {
 	if m.db == nil {
@@ -252,6 +343,11 @@ func (m *MySQL) PingWithContext(parentCtx context.Context) error {
 func (m *MySQL) finishInit(ctx context.Context, db *sql.DB) error {
 	m.db = db
 
+	// In validate-only mode, stop after planning the changes to the schema
+	if m.validateOnly {
+		return m.validate(ctx)
+	}
+
 	err := m.ensureStateSchema(ctx)
 	if err != nil {
 		m.logger.Error(err)
@@ -259,6 +355,13 @@ func (m *MySQL) finishInit(ctx context.Context, db *sql.DB) error {
 	}
 
 	err = m.Ping(ctx)
+	if err != nil {
+		err = sqlCleanup.WrapUnixSocketError(m.socketPath, err)
+		m.logger.Error(err)
+		return err
+	}
+
+	err = m.warmup(ctx)
 	if err != nil {
 		m.logger.Error(err)
 		return err
@@ -296,6 +399,190 @@ func (m *MySQL) finishInit(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+// warmup opens and validates the number of connections set in "warmupConnections", which are then kept idle in the pool.
+func (m *MySQL) warmup(parentCtx context.Context) error {
+	if m.warmupConnections <= 0 {
+		return nil
+	}
+
+	// By default, database/sql keeps only 2 idle connections
+	if m.warmupConnections > 2 {
+		m.db.SetMaxIdleConns(m.warmupConnections)
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, m.timeout)
+	defer cancel()
+	err := internalutils.WarmupConnections(ctx, m.warmupConnections, func(ctx context.Context) (func(), error) {
+		conn, err := m.db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = conn.PingContext(ctx)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return func() { conn.Close() }, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to warm up connections: %w", err)
+	}
+	return nil
+}
+
+// validate checks the connection and records in the validation report the changes that finishInit would apply, and the missing privileges.
+// It does not modify the database.
+func (m *MySQL) validate(ctx context.Context) error {
+	m.validationReport = &state.ValidationReport{}
+
+	err := m.Ping(ctx)
+	if err != nil {
+		err = sqlCleanup.WrapUnixSocketError(m.socketPath, err)
+		m.logger.Error(err)
+		return err
+	}
+	m.validationReport.Connected = true
+
+	err = m.planMigrations(ctx, m.validationReport)
+	if err != nil {
+		return fmt.Errorf("failed to plan migrations: %w", err)
+	}
+
+	m.logger.Infof("Validation completed. Planned changes: %v. Missing permissions: %v", m.validationReport.PlannedChanges, m.validationReport.MissingPermissions)
+	return m.validationReport.Err()
+}
+
+func (m *MySQL) planMigrations(ctx context.Context, report *state.ValidationReport) error {
+	grantee, err := m.currentGrantee(ctx)
+	if err != nil {
+		return err
+	}
+
+	exists, err := schemaExists(ctx, m.db, m.schemaName, m.timeout)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		report.AddPlannedChange("create schema '%s'", m.schemaName)
+		report.AddPlannedChange("create state table '%s'", m.tableName)
+		report.AddPlannedChange("create procedure 'DaprSaveFirstWriteV2'")
+		report.AddPlannedChange("create metadata table '%s'", m.metadataTableName)
+		return m.checkPrivileges(ctx, report, grantee, "", "CREATE", "CREATE ROUTINE")
+	}
+
+	// Privileges required to create the objects that are missing
+	var createPrivileges []string
+
+	exists, err = tableExists(ctx, m.db, m.schemaName, m.tableName, m.timeout)
+	if err != nil {
+		return err
+	}
+	if exists {
+		exists, err = columnExists(ctx, m.db, m.schemaName, m.tableName, "expiredate", m.timeout)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			report.AddPlannedChange("add column 'expiredate' to state table '%s'", m.tableName)
+			err = m.checkPrivileges(ctx, report, grantee, m.tableName, "ALTER", "INDEX")
+			if err != nil {
+				return err
+			}
+		}
+		exists, err = columnExists(ctx, m.db, m.schemaName, m.tableName, "serializer", m.timeout)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			report.AddPlannedChange("add column 'serializer' to state table '%s'", m.tableName)
+			err = m.checkPrivileges(ctx, report, grantee, m.tableName, "ALTER")
+			if err != nil {
+				return err
+			}
+		}
+		err = m.checkPrivileges(ctx, report, grantee, m.tableName, "SELECT", "INSERT", "UPDATE", "DELETE")
+		if err != nil {
+			return err
+		}
+	} else {
+		report.AddPlannedChange("create state table '%s'", m.tableName)
+		createPrivileges = append(createPrivileges, "CREATE")
+	}
+
+	exists, err = routineExists(ctx, m.db, m.schemaName, "DaprSaveFirstWriteV2", m.timeout)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		report.AddPlannedChange("create procedure 'DaprSaveFirstWriteV2'")
+		createPrivileges = append(createPrivileges, "CREATE ROUTINE")
+	}
+
+	exists, err = tableExists(ctx, m.db, m.schemaName, m.metadataTableName, m.timeout)
+	if err != nil {
+		return err
+	}
+	if exists {
+		err = m.checkPrivileges(ctx, report, grantee, m.metadataTableName, "SELECT", "INSERT", "UPDATE")
+		if err != nil {
+			return err
+		}
+	} else {
+		report.AddPlannedChange("create metadata table '%s'", m.metadataTableName)
+		createPrivileges = append(createPrivileges, "CREATE")
+	}
+
+	return m.checkPrivileges(ctx, report, grantee, "", createPrivileges...)
+}
+
+// currentGrantee returns the account of the current user in the format used by the information_schema privilege tables, i.e. 'user'@'host'.
+func (m *MySQL) currentGrantee(ctx context.Context) (string, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var grantee string
+	err := m.db.QueryRowContext(queryCtx,
+		`SELECT CONCAT('''', SUBSTRING_INDEX(CURRENT_USER(), '@', 1), '''@''', SUBSTRING_INDEX(CURRENT_USER(), '@', -1), '''')`,
+	).Scan(&grantee)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the current user: %w", err)
+	}
+	return grantee, nil
+}
+
+// checkPrivileges records in the report the privileges that are not granted to the user, either globally, on the schema, or on the table (if not empty).
+// Note that privileges granted through roles are not visible in information_schema, and are reported as missing.
+func (m *MySQL) checkPrivileges(ctx context.Context, report *state.ValidationReport, grantee string, tableName string, privileges ...string) error {
+	for _, privilege := range privileges {
+		queryCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		var granted int
+		err := m.db.QueryRowContext(queryCtx, `SELECT EXISTS (
+			SELECT 1 FROM information_schema.user_privileges WHERE grantee = ? AND privilege_type = ?
+			UNION ALL
+			SELECT 1 FROM information_schema.schema_privileges WHERE grantee = ? AND privilege_type = ? AND ? LIKE table_schema
+			UNION ALL
+			SELECT 1 FROM information_schema.table_privileges WHERE grantee = ? AND privilege_type = ? AND table_schema = ? AND table_name = ?
+		) AS 'granted'`,
+			grantee, privilege,
+			grantee, privilege, m.schemaName,
+			grantee, privilege, m.schemaName, tableName,
+		).Scan(&granted)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to check privilege %s: %w", privilege, err)
+		}
+
+		if granted != 1 {
+			if tableName != "" {
+				report.AddMissingPermission("%s on table '%s'", privilege, tableName)
+			} else {
+				report.AddMissingPermission("%s on schema '%s'", privilege, m.schemaName)
+			}
+		}
+	}
+	return nil
+}
+
 func (m *MySQL) ensureStateSchema(ctx context.Context) error {
 	exists, err := schemaExists(ctx, m.db, m.schemaName, m.timeout)
 	if err != nil {
@@ -315,12 +602,10 @@ func (m *MySQL) ensureStateSchema(ctx context.Context) error {
 	}
 
 	// Build a connection string that contains the new schema name
-	// All MySQL connection strings must contain a / so split on it.
-	parts := strings.Split(m.connectionString, "/")
-
-	// Even if the connection string ends with a / parts will have two values
-	// with the second being an empty string.
-	m.connectionString = fmt.Sprintf("%s/%s%s", parts[0], m.schemaName, parts[1])
+	m.connectionString, err = connectionStringWithSchema(m.connectionString, m.schemaName)
+	if err != nil {
+		return err
+	}
 
 	// Close the connection we used to confirm and or create the schema
 	err = m.db.Close()
@@ -330,11 +615,56 @@ func (m *MySQL) ensureStateSchema(ctx context.Context) error {
 	}
 
 	// Open a connection to the new schema
-	m.db, err = m.factory.Open(m.connectionString)
+	m.db, err = m.factory.Open(m.connectionString, m.health)
 
 	return err
 }
 
+// connectionStringWithSchema returns the connection string with the database name replaced by schemaName.
+// The connection string is parsed and formatted again, as slashes can appear in the address of a Unix socket and in the values of parameters.
+func connectionStringWithSchema(connectionString string, schemaName string) (string, error) {
+	cfg, err := mysql.ParseDSN(connectionString)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection string: %w", err)
+	}
+	cfg.DBName = schemaName
+	return cfg.FormatDSN(), nil
+}
+
+// buildConnectionString returns a DSN from the discrete connection properties.
+// If the host is an absolute path, it's the path of a Unix domain socket. If customTLS is true, the DSN uses the TLS configuration registered for the "pemPath" property.
+func buildConnectionString(c sqlCleanup.ConnectionMetadata, customTLS bool) string {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.DBName = c.Database
+	if strings.HasPrefix(c.Host, "/") {
+		cfg.Net = "unix"
+		cfg.Addr = c.Host
+	} else {
+		port := 3306
+		if c.Port > 0 {
+			port = c.Port
+		}
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(port))
+	}
+	if customTLS {
+		cfg.TLSConfig = "custom"
+	}
+	return cfg.FormatDSN()
+}
+
+// unixSocketPath returns the path of the Unix domain socket if the connection string uses one, as in "user:password@unix(/var/run/mysqld/mysqld.sock)/", or an empty string otherwise.
+func unixSocketPath(connectionString string) string {
+	cfg, err := mysql.ParseDSN(connectionString)
+	if err != nil || cfg.Net != "unix" {
+		// Invalid connection strings are reported when the connection is opened
+		return ""
+	}
+	return cfg.Addr
+}
+
 func (m *MySQL) ensureStateTable(ctx context.Context, schemaName, stateTableName string) error {
 	tableExists, err := tableExists(ctx, m.db, schemaName, stateTableName, m.timeout)
 	if err != nil {
@@ -354,6 +684,7 @@ func (m *MySQL) ensureStateTable(ctx context.Context, schemaName, stateTableName
 			id VARCHAR(255) NOT NULL PRIMARY KEY,
 			value JSON NOT NULL,
 			isbinary BOOLEAN NOT NULL,
+			serializer VARCHAR(32) NULL,
 			insertDate TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updateDate TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			eTag VARCHAR(36) NOT NULL,
@@ -368,12 +699,12 @@ func (m *MySQL) ensureStateTable(ctx context.Context, schemaName, stateTableName
 	}
 
 	// Check if expiredate column exists - to cater cases when table was created before v1.11.
-	columnExists, err := columnExists(ctx, m.db, schemaName, stateTableName, "expiredate", m.timeout)
+	expiredateExists, err := columnExists(ctx, m.db, schemaName, stateTableName, "expiredate", m.timeout)
 	if err != nil {
 		return err
 	}
 
-	if !columnExists {
+	if !expiredateExists {
 		m.logger.Infof("Adding expiredate column to MySql state table '%s'", stateTableName)
 		_, err = m.db.ExecContext(ctx, fmt.Sprintf(
 			`ALTER TABLE %s ADD COLUMN IF NOT EXISTS expiredate TIMESTAMP NULL;`, stateTableName))
@@ -387,8 +718,24 @@ func (m *MySQL) ensureStateTable(ctx context.Context, schemaName, stateTableName
 		}
 	}
 
-	// Create the DaprSaveFirstWriteV1 stored procedure
-	_, err = m.db.ExecContext(ctx, `CREATE PROCEDURE IF NOT EXISTS DaprSaveFirstWriteV1(tableName VARCHAR(255), id VARCHAR(255), value JSON, etag VARCHAR(36), isbinary BOOLEAN, expiredateToken TEXT)
+	// Check if serializer column exists - to cater cases when table was created before values could be serialized with other codecs.
+	// Rows without a serializer contain JSON values.
+	serializerExists, err := columnExists(ctx, m.db, schemaName, stateTableName, "serializer", m.timeout)
+	if err != nil {
+		return err
+	}
+
+	if !serializerExists {
+		m.logger.Infof("Adding serializer column to MySql state table '%s'", stateTableName)
+		_, err = m.db.ExecContext(ctx, fmt.Sprintf(
+			`ALTER TABLE %s ADD COLUMN IF NOT EXISTS serializer VARCHAR(32) NULL;`, stateTableName))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the DaprSaveFirstWriteV2 stored procedure
+	_, err = m.db.ExecContext(ctx, `CREATE PROCEDURE IF NOT EXISTS DaprSaveFirstWriteV2(tableName VARCHAR(255), id VARCHAR(255), value JSON, etag VARCHAR(36), isbinary BOOLEAN, serializer VARCHAR(32), expiredateToken TEXT)
 LANGUAGE SQL
 MODIFIES SQL DATA
   BEGIN
@@ -396,6 +743,7 @@ MODIFIES SQL DATA
     SET @value = value;
     SET @etag = etag;
     SET @isbinary = isbinary;
+    SET @serializer = serializer;
 
     SET @selectQuery = concat('SELECT COUNT(id) INTO @count FROM ', tableName ,' WHERE id = ? AND (expiredate IS NULL OR expiredate > CURRENT_TIMESTAMP)');
     PREPARE select_stmt FROM @selectQuery;
@@ -403,9 +751,9 @@ MODIFIES SQL DATA
     DEALLOCATE PREPARE select_stmt;
 
     IF @count < 1 THEN
-      SET @upsertQuery = concat('INSERT INTO ', tableName, ' SET id=?, value=?, eTag=?, isbinary=?, expiredate=', expiredateToken, ' ON DUPLICATE KEY UPDATE value=?, eTag=?, isbinary=?, expiredate=', expiredateToken);
+      SET @upsertQuery = concat('INSERT INTO ', tableName, ' SET id=?, value=?, eTag=?, isbinary=?, serializer=?, expiredate=', expiredateToken, ' ON DUPLICATE KEY UPDATE value=?, eTag=?, isbinary=?, serializer=?, expiredate=', expiredateToken);
       PREPARE upsert_stmt FROM @upsertQuery;
-      EXECUTE upsert_stmt USING @id, @value, @etag, @isbinary, @value, @etag, @isbinary;
+      EXECUTE upsert_stmt USING @id, @value, @etag, @isbinary, @serializer, @value, @etag, @isbinary, @serializer;
       DEALLOCATE PREPARE upsert_stmt;
 	ELSE
 	  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Row already exists';
@@ -463,6 +811,20 @@ func tableExists(ctx context.Context, db *sql.DB, schemaName, tableName string,
 	return exists == 1, err
 }
 
+// routineExists returns true if the stored procedure or function exists in the schema
+func routineExists(ctx context.Context, db *sql.DB, schemaName, routineName string, timeout time.Duration) (bool, error) {
+	routineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Returns 1 or 0 if the routine exists or not
+	var exists int
+	query := `SELECT EXISTS (
+		SELECT ROUTINE_NAME FROM information_schema.routines WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ?
+	) AS 'exists'`
+	err := db.QueryRowContext(routineCtx, query, schemaName, routineName).Scan(&exists)
+	return exists == 1, err
+}
+
 // columnExists returns true if the column exists in the table
 func columnExists(ctx context.Context, db *sql.DB, schemaName, tableName, columnName string, timeout time.Duration) (bool, error) {
 	columnCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -481,6 +843,10 @@ func columnExists(ctx context.Context, db *sql.DB, schemaName, tableName, column
 // Delete removes an entity from the store
 // Store Interface.
 func (m *MySQL) Delete(ctx context.Context, req *state.DeleteRequest) error {
+	if err := m.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	return m.deleteValue(ctx, m.db, req)
 }
 
@@ -496,7 +862,9 @@ func (m *MySQL) deleteValue(parentCtx context.Context, querier querier, req *sta
 		result sql.Result
 	)
 
-	execCtx, cancel := context.WithTimeout(parentCtx, m.timeout)
+	opCtx, opCancel := context.WithTimeout(parentCtx, m.timeout)
+	defer opCancel()
+	execCtx, cancel := sqlCleanup.WithQueryTimeout(opCtx, m.queryTimeout)
 	defer cancel()
 
 	if req.ETag == nil || *req.ETag == "" {
@@ -510,7 +878,7 @@ func (m *MySQL) deleteValue(parentCtx context.Context, querier querier, req *sta
 	}
 
 	if err != nil {
-		return err
+		return sqlCleanup.WrapQueryTimeoutError(opCtx, execCtx, err)
 	}
 
 	rows, err := result.RowsAffected()
@@ -528,6 +896,10 @@ func (m *MySQL) deleteValue(parentCtx context.Context, querier querier, req *sta
 // BulkDelete removes multiple entries from the store
 // Store Interface.
 func (m *MySQL) BulkDelete(ctx context.Context, req []state.DeleteRequest) error {
+	if err := m.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	tx, err := m.db.Begin()
 	if err != nil {
 		return err
@@ -554,14 +926,20 @@ func (m *MySQL) BulkDelete(ctx context.Context, req []state.DeleteRequest) error
 // Get returns an entity from store
 // Store Interface.
 func (m *MySQL) Get(parentCtx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	if err := m.lazyInit.Do(parentCtx); err != nil {
+		return nil, err
+	}
+
 	if req.Key == "" {
 		return nil, errors.New("missing key in get operation")
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, m.timeout)
+	opCtx, opCancel := context.WithTimeout(parentCtx, m.timeout)
+	defer opCancel()
+	ctx, cancel := sqlCleanup.WithQueryTimeout(opCtx, m.queryTimeout)
 	defer cancel()
 	// Concatenation is required for table name because sql.DB does not substitute parameters for table names
-	query := `SELECT id, value, eTag, isbinary FROM ` + m.tableName + ` WHERE id = ?
+	query := `SELECT id, value, eTag, isbinary, serializer FROM ` + m.tableName + ` WHERE id = ?
 			AND (expiredate IS NULL OR expiredate > CURRENT_TIMESTAMP)`
 	row := m.db.QueryRowContext(ctx, query, req.Key)
 	_, value, etag, err := readRow(row)
@@ -570,7 +948,7 @@ func (m *MySQL) Get(parentCtx context.Context, req *state.GetRequest) (*state.Ge
 		if errors.Is(err, sql.ErrNoRows) {
 			return &state.GetResponse{}, nil
 		}
-		return nil, err
+		return nil, sqlCleanup.WrapQueryTimeoutError(opCtx, ctx, err)
 	}
 	return &state.GetResponse{
 		Data:     value,
@@ -582,6 +960,10 @@ func (m *MySQL) Get(parentCtx context.Context, req *state.GetRequest) (*state.Ge
 // Set adds/updates an entity on store
 // Store Interface.
 func (m *MySQL) Set(ctx context.Context, req *state.SetRequest) error {
+	if err := m.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	return m.setValue(ctx, m.db, req)
 }
 
@@ -615,17 +997,12 @@ func (m *MySQL) setValue(parentCtx context.Context, querier querier, req *state.
 		maxRows  int64 = 1
 	)
 
-	var v any
-	isBinary := false
-	switch x := req.Value.(type) {
-	case []uint8:
-		isBinary = true
-		v = base64.StdEncoding.EncodeToString(x)
-	default:
-		v = x
+	v, isBinary, serializer, err := m.encodeValue(req.Value)
+	if err != nil {
+		return err
 	}
 
-	encB, _ := json.Marshal(v)
+	encB, _ := m.jsonOptions.Marshal(v)
 	enc := string(encB)
 
 	eTagObj, err := uuid.NewRandom()
@@ -646,11 +1023,11 @@ func (m *MySQL) setValue(parentCtx context.Context, querier querier, req *state.
 	if hasEtag {
 		// When an eTag is provided do an update - not insert
 		query = `UPDATE ` + m.tableName + `
-			SET value = ?, eTag = ?, isbinary = ?, expiredate = ` + ttlQuery + `
+			SET value = ?, eTag = ?, isbinary = ?, serializer = ?, expiredate = ` + ttlQuery + `
 			WHERE id = ?
 				AND eTag = ?
 				AND (expiredate IS NULL OR expiredate > CURRENT_TIMESTAMP)`
-		params = []any{enc, eTag, isBinary, req.Key, *req.ETag}
+		params = []any{enc, eTag, isBinary, serializer, req.Key, *req.ETag}
 	} else if req.Options.Concurrency == state.FirstWrite {
 		// If we're not in a transaction already, start one as we need to ensure consistency
 		if querier == m.db {
@@ -666,24 +1043,27 @@ func (m *MySQL) setValue(parentCtx context.Context, querier querier, req *state.
 		// Things get a bit tricky when the row exists but it is expired, so it just hasn't been garbage-collected yet
 		// What we can do in that case is to first check if the row doesn't exist or has expired, and then perform an upsert
 		// To do that, we use a stored procedure
-		query = "CALL DaprSaveFirstWriteV1(?, ?, ?, ?, ?, ?)"
-		params = []any{m.tableName, req.Key, enc, eTag, isBinary, ttlQuery}
+		query = "CALL DaprSaveFirstWriteV2(?, ?, ?, ?, ?, ?, ?)"
+		params = []any{m.tableName, req.Key, enc, eTag, isBinary, serializer, ttlQuery}
 	} else {
 		// If this is a duplicate MySQL returns that two rows affected
 		maxRows = 2
-		query = `INSERT INTO ` + m.tableName + ` (id, value, eTag, isbinary, expiredate)
-			VALUES (?, ?, ?, ?, ` + ttlQuery + `) 
+		query = `INSERT INTO ` + m.tableName + ` (id, value, eTag, isbinary, serializer, expiredate)
+			VALUES (?, ?, ?, ?, ?, ` + ttlQuery + `) 
 			ON DUPLICATE KEY UPDATE
-				value=?, eTag=?, isbinary=?, expiredate=` + ttlQuery
-		params = []any{req.Key, enc, eTag, isBinary, enc, eTag, isBinary}
+				value=?, eTag=?, isbinary=?, serializer=?, expiredate=` + ttlQuery
+		params = []any{req.Key, enc, eTag, isBinary, serializer, enc, eTag, isBinary, serializer}
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, m.timeout)
+	opCtx, opCancel := context.WithTimeout(parentCtx, m.timeout)
+	defer opCancel()
+	ctx, cancel := sqlCleanup.WithQueryTimeout(opCtx, m.queryTimeout)
 	defer cancel()
 	result, err = querier.ExecContext(ctx, query, params...)
 
 	if err != nil {
-		if hasEtag {
+		err = sqlCleanup.WrapQueryTimeoutError(opCtx, ctx, err)
+		if hasEtag && !errors.Is(err, sqlCleanup.ErrQueryTimeout) {
 			return state.NewETagError(state.ETagMismatch, err)
 		}
 
@@ -725,6 +1105,10 @@ func (m *MySQL) setValue(parentCtx context.Context, querier querier, req *state.
 }
 
 func (m *MySQL) BulkGet(parentCtx context.Context, req []state.GetRequest, _ state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	if err := m.lazyInit.Do(parentCtx); err != nil {
+		return nil, err
+	}
+
 	if len(req) == 0 {
 		return []state.BulkGetResponse{}, nil
 	}
@@ -738,7 +1122,7 @@ func (m *MySQL) BulkGet(parentCtx context.Context, req []state.GetRequest, _ sta
 	}
 
 	// Concatenation is required for table name because sql.DB does not substitute parameters for table names
-	stmt := `SELECT id, value, eTag, isbinary FROM ` + m.tableName + `
+	stmt := `SELECT id, value, eTag, isbinary, serializer FROM ` + m.tableName + `
 		WHERE
 			id IN (` + inClause + `)
 			AND (expiredate IS NULL OR expiredate > CURRENT_TIMESTAMP)`
@@ -767,37 +1151,78 @@ func (m *MySQL) BulkGet(parentCtx context.Context, req []state.GetRequest, _ sta
 	return res[:n], nil
 }
 
+// encodeValue returns the value to store as JSON, whether it's binary data encoded as base64, and the name of the codec it was serialized with.
+// Values serialized with codecs other than JSON are stored as binary data.
+func (m *MySQL) encodeValue(value any) (any, bool, string, error) {
+	if !statecodec.IsJSON(m.codec) {
+		b, err := m.codec.Encode(value)
+		if err != nil {
+			return nil, false, "", err
+		}
+		return base64.StdEncoding.EncodeToString(b), true, m.codec.Name(), nil
+	}
+
+	if b, ok := value.([]uint8); ok {
+		return base64.StdEncoding.EncodeToString(b), true, statecodec.JSON, nil
+	}
+	return value, false, statecodec.JSON, nil
+}
+
 func readRow(row interface{ Scan(dest ...any) error }) (key string, value []byte, etag string, err error) {
-	var isBinary bool
-	err = row.Scan(&key, &value, &etag, &isBinary)
+	var (
+		isBinary   bool
+		serializer sql.NullString
+	)
+	err = row.Scan(&key, &value, &etag, &isBinary, &serializer)
 	if err != nil {
 		return key, nil, "", err
 	}
 
-	if isBinary {
-		var (
-			s    string
-			data []byte
-		)
-
-		err = json.Unmarshal(value, &s)
+	// Rows without a serializer were written before the column was added, and contain JSON values
+	if serializer.Valid && serializer.String != statecodec.JSON {
+		value, err = decodeBinary(value)
 		if err != nil {
-			return key, nil, "", fmt.Errorf("failed to unmarshal JSON binary data: %w", err)
+			return key, nil, "", err
 		}
+		value, err = statecodec.Decode(serializer.String, value)
+		if err != nil {
+			return key, nil, "", err
+		}
+		return key, value, etag, nil
+	}
 
-		data, err = base64.StdEncoding.DecodeString(s)
+	if isBinary {
+		value, err = decodeBinary(value)
 		if err != nil {
-			return key, nil, "", fmt.Errorf("failed to decode binary data: %w", err)
+			return key, nil, "", err
 		}
-		return key, data, etag, nil
 	}
 
 	return key, value, etag, nil
 }
 
+// decodeBinary returns the binary data stored as a base64 JSON string.
+func decodeBinary(value []byte) ([]byte, error) {
+	var s string
+	err := json.Unmarshal(value, &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON binary data: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode binary data: %w", err)
+	}
+	return data, nil
+}
+
 // BulkSet adds/updates multiple entities on store
 // Store Interface.
 func (m *MySQL) BulkSet(ctx context.Context, req []state.SetRequest) error {
+	if err := m.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	tx, err := m.db.Begin()
 	if err != nil {
 		return err
@@ -824,6 +1249,10 @@ func (m *MySQL) BulkSet(ctx context.Context, req []state.SetRequest) error {
 // Multi handles multiple transactions.
 // TransactionalStore Interface.
 func (m *MySQL) Multi(ctx context.Context, request *state.TransactionalStateRequest) error {
+	if err := m.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	tx, err := m.db.Begin()
 	if err != nil {
 		return err
@@ -22,16 +22,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	sqlCleanup "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/utils"
 	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
 )
 
 const (
@@ -56,7 +62,46 @@ func TestEnsureStateSchemaHandlesShortConnectionString(t *testing.T) {
 	m.mySQL.ensureStateSchema(context.Background())
 
 	// Assert
-	assert.Equal(t, "theUser:thePassword@/theSchema", m.mySQL.connectionString)
+	assert.Equal(t, "theUser:thePassword@tcp(127.0.0.1:3306)/theSchema", m.mySQL.connectionString)
+}
+
+func TestConnectionStringWithSchema(t *testing.T) {
+	tests := map[string]string{
+		"theUser:thePassword@/":                                                  "theUser:thePassword@tcp(127.0.0.1:3306)/theSchema",
+		"theUser:thePassword@tcp(localhost:3306)/?parseTime=true":                "theUser:thePassword@tcp(localhost:3306)/theSchema?parseTime=true",
+		"theUser:thePassword@tcp(localhost:3306)/otherDB?tls=skip-verify":        "theUser:thePassword@tcp(localhost:3306)/theSchema?tls=skip-verify",
+		"theUser:thePassword@tcp(localhost:3306)/otherDB?loc=America%2FNew_York": "theUser:thePassword@tcp(localhost:3306)/theSchema?loc=America%2FNew_York",
+		"theUser:thePassword@unix(/var/run/mysqld/mysqld.sock)/":                 "theUser:thePassword@unix(/var/run/mysqld/mysqld.sock)/theSchema",
+		"theUser:thePassword@unix(/var/run/mysqld/mysqld.sock)/?a=b":             "theUser:thePassword@unix(/var/run/mysqld/mysqld.sock)/theSchema?a=b",
+	}
+	for in, expect := range tests {
+		dsn, err := connectionStringWithSchema(in, "theSchema")
+		require.NoError(t, err, in)
+		assert.Equal(t, expect, dsn, in)
+	}
+
+	_, err := connectionStringWithSchema("not a dsn", "theSchema")
+	assert.Error(t, err)
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	assert.Equal(t, "/var/run/mysqld/mysqld.sock", unixSocketPath("theUser:thePassword@unix(/var/run/mysqld/mysqld.sock)/"))
+	assert.Equal(t, "", unixSocketPath("theUser:thePassword@tcp(localhost:3306)/"))
+	assert.Equal(t, "", unixSocketPath("theUser:thePassword@/"))
+}
+
+func TestInitWithMissingUnixSocket(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+
+	md := state.Metadata{
+		Base: metadata.Base{Properties: map[string]string{
+			keyConnectionString: "theUser:thePassword@unix(" + filepath.Join(t.TempDir(), "mysqld.sock") + ")/",
+		}},
+	}
+
+	err := m.mySQL.Init(context.Background(), md)
+	assert.ErrorContains(t, err, "does not exist")
 }
 
 func TestFinishInitHandlesSchemaExistsError(t *testing.T) {
@@ -146,6 +191,119 @@ func TestFinishInitHandlesTableExistsError(t *testing.T) {
 	assert.Equal(t, "tableExistsError", err.Error(), "tableExists did not return err")
 }
 
+func TestFinishInitValidateOnly(t *testing.T) {
+	exists := func(v int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"exists"}).AddRow(v)
+	}
+
+	t.Run("plans missing objects without creating them", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.mySQL.Close()
+		m.mySQL.validateOnly = true
+		m.mySQL.schemaName = "dapr_state_store"
+		m.mySQL.metadataTableName = "dapr_metadata"
+
+		m.mock1.ExpectPing()
+		m.mock1.ExpectQuery("SELECT CONCAT").WillReturnRows(sqlmock.NewRows([]string{"grantee"}).AddRow("'dapr'@'%'"))
+		// Schema and state table exist, with the expiredate and serializer columns
+		m.mock1.ExpectQuery("SELECT EXISTS").WithArgs("dapr_state_store").WillReturnRows(exists(1))
+		m.mock1.ExpectQuery("SELECT EXISTS").WithArgs("dapr_state_store", "state").WillReturnRows(exists(1))
+		m.mock1.ExpectQuery("SELECT count").WillReturnRows(exists(1))
+		m.mock1.ExpectQuery("SELECT count").WillReturnRows(exists(1))
+		for _, privilege := range []string{"SELECT", "INSERT", "UPDATE", "DELETE"} {
+			m.mock1.ExpectQuery("SELECT EXISTS").
+				WithArgs("'dapr'@'%'", privilege, "'dapr'@'%'", privilege, "dapr_state_store", "'dapr'@'%'", privilege, "dapr_state_store", "state").
+				WillReturnRows(exists(1))
+		}
+		// Procedure exists, metadata table doesn't
+		m.mock1.ExpectQuery("information_schema.routines").WillReturnRows(exists(1))
+		m.mock1.ExpectQuery("SELECT EXISTS").WithArgs("dapr_state_store", "dapr_metadata").WillReturnRows(exists(0))
+		// The CREATE privilege is granted
+		m.mock1.ExpectQuery("SELECT EXISTS").WithArgs("'dapr'@'%'", "CREATE", "'dapr'@'%'", "CREATE", "dapr_state_store", "'dapr'@'%'", "CREATE", "dapr_state_store", "").
+			WillReturnRows(exists(1))
+
+		err := m.mySQL.finishInit(context.Background(), m.mySQL.db)
+		assert.NoError(t, err)
+		assert.NoError(t, m.mock1.ExpectationsWereMet())
+
+		report, err := state.GetValidationReport(m.mySQL)
+		assert.NoError(t, err)
+		assert.True(t, report.Connected)
+		assert.Equal(t, []string{"create metadata table 'dapr_metadata'"}, report.PlannedChanges)
+		assert.Empty(t, report.MissingPermissions)
+		assert.Nil(t, m.mySQL.gc)
+	})
+
+	t.Run("fails when privileges are missing", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.mySQL.Close()
+		m.mySQL.validateOnly = true
+		m.mySQL.schemaName = "dapr_state_store"
+		m.mySQL.metadataTableName = "dapr_metadata"
+
+		m.mock1.ExpectPing()
+		m.mock1.ExpectQuery("SELECT CONCAT").WillReturnRows(sqlmock.NewRows([]string{"grantee"}).AddRow("'dapr'@'%'"))
+		m.mock1.ExpectQuery("SELECT EXISTS").WithArgs("dapr_state_store").WillReturnRows(exists(0))
+		m.mock1.ExpectQuery("SELECT EXISTS").WillReturnRows(exists(0))
+		m.mock1.ExpectQuery("SELECT EXISTS").WillReturnRows(exists(1))
+
+		err := m.mySQL.finishInit(context.Background(), m.mySQL.db)
+		assert.ErrorContains(t, err, "CREATE on schema 'dapr_state_store'")
+		assert.NoError(t, m.mock1.ExpectationsWereMet())
+
+		report := m.mySQL.ValidationReport()
+		assert.Len(t, report.PlannedChanges, 4)
+		assert.Equal(t, []string{"CREATE on schema 'dapr_state_store'"}, report.MissingPermissions)
+	})
+
+	t.Run("ping error", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.mySQL.Close()
+		m.mySQL.validateOnly = true
+
+		m.mock1.ExpectPing().WillReturnError(fmt.Errorf("pingError"))
+
+		err := m.mySQL.finishInit(context.Background(), m.mySQL.db)
+		assert.EqualError(t, err, "pingError")
+		assert.False(t, m.mySQL.ValidationReport().Connected)
+	})
+}
+
+func TestQueryTimeout(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+	m.mySQL.queryTimeout = 10 * time.Millisecond
+
+	t.Run("get", func(t *testing.T) {
+		m.mock1.ExpectQuery("SELECT id").
+			WillDelayFor(time.Second).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary", "serializer"}))
+
+		_, err := m.mySQL.Get(context.Background(), &state.GetRequest{Key: "key"})
+		assert.ErrorIs(t, err, sqlCleanup.ErrQueryTimeout)
+	})
+
+	t.Run("set with etag", func(t *testing.T) {
+		m.mock1.ExpectExec("UPDATE state").
+			WillDelayFor(time.Second).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := m.mySQL.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", ETag: ptr.Of("946af56e")})
+		assert.ErrorIs(t, err, sqlCleanup.ErrQueryTimeout)
+		var etagErr *state.ETagError
+		assert.False(t, errors.As(err, &etagErr))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		m.mock1.ExpectExec("DELETE FROM").
+			WillDelayFor(time.Second).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := m.mySQL.Delete(context.Background(), &state.DeleteRequest{Key: "key"})
+		assert.ErrorIs(t, err, sqlCleanup.ErrQueryTimeout)
+	})
+}
+
 func TestClosingDatabaseTwiceReturnsNil(t *testing.T) {
 	// Arrange
 	m, _ := mockDatabase(t)
@@ -490,8 +648,8 @@ func TestGetSucceeds(t *testing.T) {
 	defer m.mySQL.Close()
 
 	t.Run("has json type", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary"}).AddRow("UnitTest", "{}", "946af56e", false)
-		m.mock1.ExpectQuery("SELECT id, value, eTag, isbinary FROM state WHERE id = ?").WillReturnRows(rows)
+		rows := sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary", "serializer"}).AddRow("UnitTest", "{}", "946af56e", false, nil)
+		m.mock1.ExpectQuery("SELECT id, value, eTag, isbinary, serializer FROM state WHERE id = ?").WillReturnRows(rows)
 
 		request := &state.GetRequest{
 			Key: "UnitTest",
@@ -508,8 +666,8 @@ func TestGetSucceeds(t *testing.T) {
 
 	t.Run("has binary type", func(t *testing.T) {
 		value, _ := utils.Marshal(base64.StdEncoding.EncodeToString([]byte("abcdefg")), json.Marshal)
-		rows := sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary"}).AddRow("UnitTest", value, "946af56e", true)
-		m.mock1.ExpectQuery("SELECT id, value, eTag, isbinary FROM state WHERE id = ?").WillReturnRows(rows)
+		rows := sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary", "serializer"}).AddRow("UnitTest", value, "946af56e", true, nil)
+		m.mock1.ExpectQuery("SELECT id, value, eTag, isbinary, serializer FROM state WHERE id = ?").WillReturnRows(rows)
 
 		request := &state.GetRequest{
 			Key: "UnitTest",
@@ -577,6 +735,8 @@ func TestEnsureStateTableCreatesTable(t *testing.T) {
 	m.mock1.ExpectExec("CREATE TABLE").WillReturnResult(sqlmock.NewResult(1, 1))
 	rows = sqlmock.NewRows([]string{"exists"}).AddRow(1)
 	m.mock1.ExpectQuery("SELECT count(/*)").WillReturnRows(rows)
+	rows = sqlmock.NewRows([]string{"exists"}).AddRow(1)
+	m.mock1.ExpectQuery("SELECT count(/*)").WillReturnRows(rows)
 	m.mock1.ExpectExec("CREATE PROCEDURE").WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Act
@@ -586,6 +746,69 @@ func TestEnsureStateTableCreatesTable(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// Verifies that ensureStateTable adds the serializer column to tables
+// created before it existed.
+func TestEnsureStateTableAddsSerializerColumn(t *testing.T) {
+	// Arrange
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+
+	m.mock1.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+	m.mock1.ExpectQuery("SELECT count(/*)").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+	m.mock1.ExpectQuery("SELECT count(/*)").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(0))
+	m.mock1.ExpectExec("ALTER TABLE state ADD COLUMN IF NOT EXISTS serializer VARCHAR").WillReturnResult(sqlmock.NewResult(0, 0))
+	m.mock1.ExpectExec("CREATE PROCEDURE IF NOT EXISTS DaprSaveFirstWriteV2").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	err := m.mySQL.ensureStateTable(context.Background(), "dapr_state_store", "state")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, m.mock1.ExpectationsWereMet())
+}
+
+func TestSerializer(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+
+	var err error
+	m.mySQL.codec, err = statecodec.Parse(statecodec.Msgpack)
+	require.NoError(t, err)
+
+	encoded, err := m.mySQL.codec.Encode(map[string]string{"city": "Seattle"})
+	require.NoError(t, err)
+	value, _ := json.Marshal(base64.StdEncoding.EncodeToString(encoded))
+
+	t.Run("set stores the name of the codec", func(t *testing.T) {
+		m.mock1.ExpectExec("INSERT INTO state").
+			WithArgs("key", string(value), sqlmock.AnyArg(), true, "msgpack", string(value), sqlmock.AnyArg(), true, "msgpack").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := m.mySQL.Set(context.Background(), &state.SetRequest{Key: "key", Value: map[string]string{"city": "Seattle"}})
+		require.NoError(t, err)
+		assert.NoError(t, m.mock1.ExpectationsWereMet())
+	})
+
+	t.Run("get decodes values", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary", "serializer"}).AddRow("key", value, "946af56e", true, "msgpack")
+		m.mock1.ExpectQuery("SELECT id, value, eTag, isbinary, serializer FROM state WHERE id = ?").WillReturnRows(rows)
+
+		response, err := m.mySQL.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"city":"Seattle"}`, string(response.Data))
+	})
+
+	t.Run("get reads values stored as JSON", func(t *testing.T) {
+		// Rows written before the serializer column was added have no codec
+		rows := sqlmock.NewRows([]string{"id", "value", "eTag", "isbinary", "serializer"}).AddRow("key", `{"city":"Seattle"}`, "946af56e", false, nil)
+		m.mock1.ExpectQuery("SELECT id, value, eTag, isbinary, serializer FROM state WHERE id = ?").WillReturnRows(rows)
+
+		response, err := m.mySQL.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `{"city":"Seattle"}`, string(response.Data))
+	})
+}
+
 // Verify that the call to MySQL init get passed through
 // to the DbAccess instance.
 func TestInitReturnsErrorOnNoConnectionString(t *testing.T) {
@@ -928,7 +1151,7 @@ func newFakeMySQLFactory(db1 *sql.DB, db2 *sql.DB, registerErr, openErr error) *
 	}
 }
 
-func (f *fakeMySQLFactory) Open(connectionString string) (*sql.DB, error) {
+func (f *fakeMySQLFactory) Open(connectionString string, health sqlCleanup.HealthMetadata) (*sql.DB, error) {
 	f.openCount++
 
 	if f.openCount == 1 {
@@ -963,3 +1186,164 @@ func TestValidIdentifier(t *testing.T) {
 		})
 	}
 }
+
+func TestLazyInit(t *testing.T) {
+	t.Run("init doesn't connect", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.mySQL.Close()
+
+		err := m.mySQL.Init(context.Background(), state.Metadata{
+			Base: metadata.Base{Properties: map[string]string{
+				keyConnectionString: fakeConnectionString,
+				"lazyInit":          "true",
+			}},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, m.mySQL.lazyInit)
+		assert.NoError(t, m.mock1.ExpectationsWereMet())
+
+		// The first operation runs the initialization, which is retried after a failure
+		m.mock1.ExpectQuery("SELECT EXISTS").WillReturnError(fmt.Errorf("existsError"))
+		_, err = m.mySQL.Get(context.Background(), &state.GetRequest{Key: "key"})
+		assert.EqualError(t, err, "existsError")
+
+		m.mock1.ExpectQuery("SELECT EXISTS").WillReturnError(fmt.Errorf("existsError"))
+		err = m.mySQL.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v"})
+		assert.EqualError(t, err, "existsError")
+		assert.NoError(t, m.mock1.ExpectationsWereMet())
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		defer m.mySQL.Close()
+
+		err := m.mySQL.Init(context.Background(), state.Metadata{
+			Base: metadata.Base{Properties: map[string]string{
+				keyConnectionString: fakeConnectionString,
+				"lazyInit":          "true",
+				"validateOnly":      "true",
+			}},
+		})
+		assert.Error(t, err)
+
+		err = m.mySQL.Init(context.Background(), state.Metadata{
+			Base: metadata.Base{Properties: map[string]string{
+				keyConnectionString: fakeConnectionString,
+				"warmupConnections": "-1",
+			}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestWarmup(t *testing.T) {
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+	m.mySQL.timeout = time.Second
+	m.mySQL.warmupConnections = 3
+
+	for i := 0; i < 3; i++ {
+		m.mock1.ExpectPing()
+	}
+
+	err := m.mySQL.warmup(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, m.mock1.ExpectationsWereMet())
+	assert.Equal(t, 3, m.mySQL.db.Stats().Idle)
+
+	m.mock1.ExpectPing().WillReturnError(fmt.Errorf("pingError"))
+	m.mySQL.warmupConnections = 1
+	err = m.mySQL.warmup(context.Background())
+	assert.ErrorContains(t, err, "pingError")
+}
+
+func TestBuildConnectionString(t *testing.T) {
+	tests := map[string]struct {
+		meta      sqlCleanup.ConnectionMetadata
+		customTLS bool
+		expected  string
+	}{
+		"all properties": {
+			meta:     sqlCleanup.ConnectionMetadata{Host: "db.example.com", Port: 3307, User: "dapr", Password: "secret", Database: "state"},
+			expected: "dapr:secret@tcp(db.example.com:3307)/state",
+		},
+		"default port": {
+			meta:     sqlCleanup.ConnectionMetadata{Host: "localhost", User: "dapr", Password: "secret"},
+			expected: "dapr:secret@tcp(localhost:3306)/",
+		},
+		"special characters": {
+			meta:     sqlCleanup.ConnectionMetadata{Host: "localhost", User: "dapr", Password: "p@ss:w/rd?a=b&c)"},
+			expected: "dapr:p@ss:w/rd?a=b&c)@tcp(localhost:3306)/",
+		},
+		"ipv6": {
+			meta:     sqlCleanup.ConnectionMetadata{Host: "::1", User: "dapr"},
+			expected: "dapr@tcp([::1]:3306)/",
+		},
+		"unix socket": {
+			meta:     sqlCleanup.ConnectionMetadata{Host: "/var/run/mysqld/mysqld.sock", User: "dapr"},
+			expected: "dapr@unix(/var/run/mysqld/mysqld.sock)/",
+		},
+		"custom tls": {
+			meta:      sqlCleanup.ConnectionMetadata{Host: "localhost", User: "dapr"},
+			customTLS: true,
+			expected:  "dapr@tcp(localhost:3306)/?tls=custom",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dsn := buildConnectionString(tt.meta, tt.customTLS)
+			assert.Equal(t, tt.expected, dsn)
+
+			if tt.customTLS {
+				// The "custom" TLS configuration is registered only in Init
+				return
+			}
+
+			// The DSN must be parsed back to the same values, including after the schema is set
+			withSchema, err := connectionStringWithSchema(dsn, "theSchema")
+			require.NoError(t, err)
+			for _, s := range []string{dsn, withSchema} {
+				cfg, err := mysql.ParseDSN(s)
+				require.NoError(t, err)
+				assert.Equal(t, tt.meta.User, cfg.User)
+				assert.Equal(t, tt.meta.Password, cfg.Passwd)
+			}
+		})
+	}
+}
+
+func TestInitConnectionProperties(t *testing.T) {
+	t.Run("built from the properties", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		err := m.mySQL.parseMetadata(map[string]string{
+			"host":     "localhost",
+			"user":     "dapr",
+			"password": "secret",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "dapr:secret@tcp(localhost:3306)/", m.mySQL.connectionString)
+	})
+
+	t.Run("connection string takes precedence", func(t *testing.T) {
+		m, _ := mockDatabase(t)
+		err := m.mySQL.parseMetadata(map[string]string{
+			keyConnectionString: fakeConnectionString,
+			"host":              "localhost",
+			"password":          "secret",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, fakeConnectionString, m.mySQL.connectionString)
+	})
+}
+
+func TestInitHealthMetadata(t *testing.T) {
+	m, _ := mockDatabase(t)
+	err := m.mySQL.parseMetadata(map[string]string{
+		keyConnectionString: fakeConnectionString,
+		"validationQuery":   "SELECT 1",
+		"pingBeforeUse":     "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, sqlCleanup.HealthMetadata{ValidationQuery: "SELECT 1", PingBeforeUse: true}, m.mySQL.health)
+}
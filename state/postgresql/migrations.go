@@ -24,22 +24,25 @@ import (
 	"github.com/jackc/pgx/v5"
 
 	"github.com/dapr/components-contrib/internal/component/postgresql"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
 
 // Performs migrations for the database schema
 type migrations struct {
-	logger            logger.Logger
-	stateTableName    string
-	metadataTableName string
+	logger               logger.Logger
+	stateTableName       string
+	metadataTableName    string
+	reservationTableName string
 }
 
 // performMigration the required migrations
 func performMigration(ctx context.Context, db postgresql.PGXPoolConn, opts postgresql.MigrateOptions) error {
 	m := &migrations{
-		logger:            opts.Logger,
-		stateTableName:    opts.StateTableName,
-		metadataTableName: opts.MetadataTableName,
+		logger:               opts.Logger,
+		stateTableName:       opts.StateTableName,
+		metadataTableName:    opts.MetadataTableName,
+		reservationTableName: opts.ReservationTableName,
 	}
 
 	// Use an advisory lock (with an arbitrary number) to ensure that no one else is performing migrations at the same time
@@ -85,25 +88,9 @@ func performMigration(ctx context.Context, db postgresql.PGXPoolConn, opts postg
 	}
 
 	// Select the migration level
-	var (
-		migrationLevelStr string
-		migrationLevel    int
-	)
-	queryCtx, cancel = context.WithTimeout(ctx, 30*time.Second)
-	err = db.QueryRow(queryCtx,
-		fmt.Sprintf(`SELECT value FROM %s WHERE key = 'migrations'`, m.metadataTableName),
-	).Scan(&migrationLevelStr)
-	cancel()
-	if errors.Is(err, pgx.ErrNoRows) {
-		// If there's no row...
-		migrationLevel = 0
-	} else if err != nil {
-		return fmt.Errorf("failed to read migration level: %w", err)
-	} else {
-		migrationLevel, err = strconv.Atoi(migrationLevelStr)
-		if err != nil || migrationLevel < 0 {
-			return fmt.Errorf("invalid migration level found in metadata table: %s", migrationLevelStr)
-		}
+	migrationLevel, err := m.getMigrationLevel(ctx, db)
+	if err != nil {
+		return err
 	}
 
 	// Perform the migrations
@@ -128,6 +115,149 @@ func performMigration(ctx context.Context, db postgresql.PGXPoolConn, opts postg
 	return nil
 }
 
+// planMigration records in the report the changes that performMigration would apply, and the permissions that are missing to apply them or to use the tables.
+// It does not modify the database.
+func planMigration(ctx context.Context, db postgresql.PGXPoolConn, opts postgresql.MigrateOptions, report *state.ValidationReport) error {
+	m := &migrations{
+		logger:               opts.Logger,
+		stateTableName:       opts.StateTableName,
+		metadataTableName:    opts.MetadataTableName,
+		reservationTableName: opts.ReservationTableName,
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	metaExists, metaSchema, metaTable, err := m.tableExists(queryCtx, db, m.metadataTableName)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	migrationLevel := 0
+	if metaExists {
+		migrationLevel, err = m.getMigrationLevel(ctx, db)
+		if err != nil {
+			return err
+		}
+		err = m.checkTablePrivileges(ctx, db, report, metaSchema, metaTable, "SELECT", "INSERT", "UPDATE")
+		if err != nil {
+			return err
+		}
+	} else {
+		report.AddPlannedChange("create metadata table '%s'", m.metadataTableName)
+		err = m.checkCreatePrivilege(ctx, db, report, m.metadataTableName)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := migrationLevel; i < len(allMigrations); i++ {
+		report.AddPlannedChange("migration %d: %s", i, fmt.Sprintf(migrationDescriptions[i], m.stateTableName))
+	}
+
+	queryCtx, cancel = context.WithTimeout(ctx, 30*time.Second)
+	stateExists, stateSchema, stateTable, err := m.tableExists(queryCtx, db, m.stateTableName)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if !stateExists {
+		return m.checkCreatePrivilege(ctx, db, report, m.stateTableName)
+	}
+
+	err = m.checkTablePrivileges(ctx, db, report, stateSchema, stateTable, "SELECT", "INSERT", "UPDATE", "DELETE")
+	if err != nil {
+		return err
+	}
+
+	// Altering an existing table requires being its owner
+	if migrationLevel < len(allMigrations) {
+		var isOwner bool
+		queryCtx, cancel = context.WithTimeout(ctx, 30*time.Second)
+		err = db.QueryRow(queryCtx,
+			`SELECT pg_has_role(tableowner, 'MEMBER') FROM pg_tables WHERE schemaname = $1 AND tablename = $2`,
+			stateSchema, stateTable,
+		).Scan(&isOwner)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to check ownership of table '%s': %w", m.stateTableName, err)
+		}
+		if !isOwner {
+			report.AddMissingPermission("ownership of table '%s'", m.stateTableName)
+		}
+	}
+
+	return nil
+}
+
+// Checks if the current user can create tables in the schema of the given table (or in the current schema if the table name doesn't include one)
+func (m migrations) checkCreatePrivilege(ctx context.Context, db postgresql.PGXPoolConn, report *state.ValidationReport, tableName string) error {
+	_, schema, err := m.tableSchemaName(tableName)
+	if err != nil {
+		return err
+	}
+
+	var (
+		granted    bool
+		schemaName string
+	)
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	err = db.QueryRow(queryCtx,
+		`SELECT COALESCE(NULLIF($1, ''), current_schema()), has_schema_privilege(COALESCE(NULLIF($1, ''), current_schema()), 'CREATE')`,
+		schema,
+	).Scan(&schemaName, &granted)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to check privileges on schema: %w", err)
+	}
+	if !granted {
+		report.AddMissingPermission("CREATE on schema '%s'", schemaName)
+	}
+	return nil
+}
+
+// Checks if the current user has the given privileges on an existing table
+func (m migrations) checkTablePrivileges(ctx context.Context, db postgresql.PGXPoolConn, report *state.ValidationReport, schema string, table string, privileges ...string) error {
+	for _, privilege := range privileges {
+		var granted bool
+		queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := db.QueryRow(queryCtx,
+			`SELECT has_table_privilege(quote_ident($1) || '.' || quote_ident($2), $3)`,
+			schema, table, privilege,
+		).Scan(&granted)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to check privileges on table '%s.%s': %w", schema, table, err)
+		}
+		if !granted {
+			report.AddMissingPermission("%s on table '%s.%s'", privilege, schema, table)
+		}
+	}
+	return nil
+}
+
+// Returns the migration level stored in the metadata table, which must exist
+func (m migrations) getMigrationLevel(ctx context.Context, db postgresql.PGXPoolConn) (int, error) {
+	var migrationLevelStr string
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	err := db.QueryRow(queryCtx,
+		fmt.Sprintf(`SELECT value FROM %s WHERE key = 'migrations'`, m.metadataTableName),
+	).Scan(&migrationLevelStr)
+	cancel()
+	if errors.Is(err, pgx.ErrNoRows) {
+		// If there's no row...
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read migration level: %w", err)
+	}
+
+	migrationLevel, err := strconv.Atoi(migrationLevelStr)
+	if err != nil || migrationLevel < 0 {
+		return 0, fmt.Errorf("invalid migration level found in metadata table: %s", migrationLevelStr)
+	}
+	return migrationLevel, nil
+}
+
 func (m migrations) createMetadataTable(ctx context.Context, db postgresql.PGXPoolConn) error {
 	m.logger.Infof("Creating metadata table '%s'", m.metadataTableName)
 	// Add an "IF NOT EXISTS" in case another Dapr sidecar is creating the same table at the same time
@@ -193,7 +323,16 @@ func (m migrations) tableSchemaName(tableName string) (table string, schema stri
 	}
 }
 
-var allMigrations = [2]func(ctx context.Context, db postgresql.PGXPoolConn, m *migrations) error{
+// Descriptions of the migrations in allMigrations, used in validate-only mode; %s is the name of the state table
+var migrationDescriptions = [len(allMigrations)]string{
+	"create state table '%s'",
+	"add column 'expiredate' to state table '%s'",
+	"add column 'compressedvalue' to state table '%s'",
+	"create reservation table for state table '%s'",
+	"create index on the keys of state table '%s' for prefix queries",
+}
+
+var allMigrations = [5]func(ctx context.Context, db postgresql.PGXPoolConn, m *migrations) error{
 	// Migration 0: create the state table
 	func(ctx context.Context, db postgresql.PGXPoolConn, m *migrations) error {
 		// We need to add an "IF NOT EXISTS" because we may be migrating from when we did not use a metadata table
@@ -229,4 +368,59 @@ var allMigrations = [2]func(ctx context.Context, db postgresql.PGXPoolConn, m *m
 		}
 		return nil
 	},
+
+	// Migration 2: add the "compressedvalue" column
+	func(ctx context.Context, db postgresql.PGXPoolConn, m *migrations) error {
+		m.logger.Infof("Adding compressedvalue column to state table '%s'", m.stateTableName)
+		_, err := db.Exec(ctx, fmt.Sprintf(
+			`ALTER TABLE %s ADD compressedvalue bytea`,
+			m.stateTableName,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to update state table: %w", err)
+		}
+		return nil
+	},
+
+	// Migration 3: create the table with the operations staged in reservations
+	func(ctx context.Context, db postgresql.PGXPoolConn, m *migrations) error {
+		m.logger.Infof("Creating reservation table '%s'", m.reservationTableName)
+		_, err := db.Exec(ctx, fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s (
+				key text NOT NULL PRIMARY KEY,
+				reservationid text NOT NULL,
+				status text NOT NULL,
+				operation jsonb NOT NULL,
+				expiredate TIMESTAMP WITH TIME ZONE NOT NULL
+			)`,
+			m.reservationTableName,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to create reservation table: %w", err)
+		}
+
+		_, err = db.Exec(ctx, fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS %s_reservationid_idx ON %s (reservationid)`,
+			// The index is created in the schema of the table, so the name must not include the schema
+			m.reservationTableName[strings.LastIndexByte(m.reservationTableName, '.')+1:], m.reservationTableName,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to create index on reservation table: %w", err)
+		}
+		return nil
+	},
+
+	// Migration 4: create an index on the keys with the text_pattern_ops operator class, used by ListKeys to find keys with a prefix regardless of the collation
+	func(ctx context.Context, db postgresql.PGXPoolConn, m *migrations) error {
+		m.logger.Infof("Creating index on the keys of state table '%s'", m.stateTableName)
+		_, err := db.Exec(ctx, fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS %s_key_pattern_idx ON %s (key text_pattern_ops)`,
+			// The index is created in the schema of the table, so the name must not include the schema
+			m.stateTableName[strings.LastIndexByte(m.stateTableName, '.')+1:], m.stateTableName,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to create index on state table: %w", err)
+		}
+		return nil
+	},
 }
@@ -22,8 +22,13 @@ import (
 // NewPostgreSQLStateStore creates a new instance of PostgreSQL state store.
 func NewPostgreSQLStateStore(logger logger.Logger) state.Store {
 	return postgresql.NewPostgreSQLStateStore(logger, postgresql.Options{
-		ETagColumn: "xmin",
-		MigrateFn:  performMigration,
+		ETagColumn:           "xmin",
+		SupportsBulkLoad:     true,
+		SupportsCompression:  true,
+		SupportsReservations: true,
+		SupportsListKeys:     true,
+		MigrateFn:            performMigration,
+		PlanMigrationsFn:     planMigration,
 		SetQueryFn: func(req *state.SetRequest, opts postgresql.SetQueryOptions) string {
 			// Sprintf is required for table name because sql.DB does not
 			// substitute parameters for table names.
@@ -37,13 +42,14 @@ func NewPostgreSQLStateStore(logger logger.Logger) state.Store {
 				}
 
 				return `INSERT INTO ` + opts.TableName + ` AS t
-					(key, value, isbinary, expiredate)
+					(key, value, isbinary, compressedvalue, expiredate)
 				VALUES
-					($1, $2, $3, ` + opts.ExpireDateValue + `)
+					($1, $2, $3, ` + opts.CompressedValueParam + `, ` + opts.ExpireDateValue + `)
 				ON CONFLICT (key)
 				DO UPDATE SET
 					value = excluded.value,
 					isbinary = excluded.isBinary,
+					compressedvalue = excluded.compressedvalue,
 					updatedate = CURRENT_TIMESTAMP,
 					expiredate = ` + opts.ExpireDateValue +
 					whereClause
@@ -53,6 +59,7 @@ func NewPostgreSQLStateStore(logger logger.Logger) state.Store {
 			SET
 				value = $2,
 				isbinary = $3,
+				compressedvalue = ` + opts.CompressedValueParam + `,
 				updatedate = CURRENT_TIMESTAMP,
 				expiredate = ` + opts.ExpireDateValue + `
 			WHERE
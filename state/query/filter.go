@@ -51,6 +51,11 @@ func ParseFilter(obj interface{}) (Filter, error) {
 			f := &OR{}
 			err := f.Parse(v)
 
+			return f, err
+		case "GEO":
+			f := &GEO{}
+			err := f.Parse(v)
+
 			return f, err
 		default:
 			return nil, fmt.Errorf("unsupported filter %q", k)
@@ -104,6 +109,66 @@ func (f *IN) Parse(obj interface{}) error {
 	return nil
 }
 
+// GEO is a geospatial filter that matches documents whose location is within a radius from a point.
+// Coordinates are expressed as [longitude, latitude], and distances in meters.
+// If SortByDistance is true, results are also sorted by distance from the center, nearest first.
+type GEO struct {
+	Key            string
+	Center         [2]float64
+	Radius         float64
+	MinDistance    float64
+	SortByDistance bool
+}
+
+func (f *GEO) Parse(obj interface{}) error {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("GEO filter must be a map")
+	}
+	if len(m) != 1 {
+		return fmt.Errorf("GEO filter must contain a single key/value pair")
+	}
+	for k, v := range m {
+		f.Key = k
+		opts, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("GEO filter value must be a map")
+		}
+
+		center, ok := opts["center"].([]interface{})
+		if !ok || len(center) != 2 {
+			return fmt.Errorf("GEO filter 'center' must be an array of [longitude, latitude]")
+		}
+		for i, c := range center {
+			if f.Center[i], ok = c.(float64); !ok {
+				return fmt.Errorf("GEO filter 'center' must contain numbers")
+			}
+		}
+		if f.Center[0] < -180 || f.Center[0] > 180 || f.Center[1] < -90 || f.Center[1] > 90 {
+			return fmt.Errorf("GEO filter 'center' is out of range")
+		}
+
+		if f.Radius, ok = opts["radius"].(float64); !ok || f.Radius <= 0 {
+			return fmt.Errorf("GEO filter 'radius' must be a positive number")
+		}
+		if val, has := opts["minDistance"]; has {
+			if f.MinDistance, ok = val.(float64); !ok || f.MinDistance < 0 || f.MinDistance >= f.Radius {
+				return fmt.Errorf("GEO filter 'minDistance' must be a non-negative number smaller than 'radius'")
+			}
+		}
+		if val, has := opts["sortByDistance"]; has {
+			if f.SortByDistance, ok = val.(bool); !ok {
+				return fmt.Errorf("GEO filter 'sortByDistance' must be a boolean")
+			}
+		}
+		if f.MinDistance > 0 && !f.SortByDistance {
+			return fmt.Errorf("GEO filter 'minDistance' requires 'sortByDistance'")
+		}
+	}
+
+	return nil
+}
+
 type AND struct {
 	Filters []Filter
 }
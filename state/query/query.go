@@ -63,6 +63,12 @@ type Visitor interface {
 	Finalize(string, *Query) error
 }
 
+// GeoVisitor is implemented by visitors that support geospatial filters.
+type GeoVisitor interface {
+	// returns "geo" expression
+	VisitGEO(*GEO) (string, error)
+}
+
 type Builder struct {
 	visitor Visitor
 }
@@ -95,6 +101,12 @@ func (h *Builder) buildFilter(filter Filter) (string, error) {
 		return h.visitor.VisitOR(f)
 	case *AND:
 		return h.visitor.VisitAND(f)
+	case *GEO:
+		gv, ok := h.visitor.(GeoVisitor)
+		if !ok {
+			return "", fmt.Errorf("geospatial filters are not supported by this state store")
+		}
+		return gv.VisitGEO(f)
 	default:
 		return "", fmt.Errorf("unsupported filter type %#v", filter)
 	}
@@ -130,6 +130,58 @@ func TestQuery(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "../../tests/state/query/q7.json",
+			query: Query{
+				QueryFields: QueryFields{
+					Filters: map[string]any{
+						"AND": []any{
+							map[string]any{
+								"EQ": map[string]any{
+									"type": "store",
+								},
+							},
+							map[string]any{
+								"GEO": map[string]any{
+									"location": map[string]any{
+										"center": []any{-122.33, 47.6},
+										"radius": float64(5000),
+									},
+								},
+							},
+						},
+					},
+					Sort: nil,
+					Page: Pagination{Limit: 2, Token: ""},
+				},
+				Filter: &AND{
+					Filters: []Filter{
+						&EQ{Key: "type", Val: "store"},
+						&GEO{Key: "location", Center: [2]float64{-122.33, 47.6}, Radius: 5000},
+					},
+				},
+			},
+		},
+		{
+			input: "../../tests/state/query/q8.json",
+			query: Query{
+				QueryFields: QueryFields{
+					Filters: map[string]any{
+						"GEO": map[string]any{
+							"location": map[string]any{
+								"center":         []any{-122.33, 47.6},
+								"radius":         float64(5000),
+								"minDistance":    float64(100),
+								"sortByDistance": true,
+							},
+						},
+					},
+					Sort: nil,
+					Page: Pagination{Limit: 0, Token: ""},
+				},
+				Filter: &GEO{Key: "location", Center: [2]float64{-122.33, 47.6}, Radius: 5000, MinDistance: 100, SortByDistance: true},
+			},
+		},
 	}
 	for _, test := range tests {
 		data, err := os.ReadFile(test.input)
@@ -140,3 +192,24 @@ func TestQuery(t *testing.T) {
 		assert.Equal(t, test.query, q)
 	}
 }
+
+func TestGEOParse(t *testing.T) {
+	invalid := []string{
+		`{"location": {"radius": 10}}`,
+		`{"location": {"center": [1], "radius": 10}}`,
+		`{"location": {"center": ["a", 1], "radius": 10}}`,
+		`{"location": {"center": [200, 1], "radius": 10}}`,
+		`{"location": {"center": [1, 1]}}`,
+		`{"location": {"center": [1, 1], "radius": -1}}`,
+		`{"location": {"center": [1, 1], "radius": 10, "minDistance": 20, "sortByDistance": true}}`,
+		`{"location": {"center": [1, 1], "radius": 10, "minDistance": 5}}`,
+		`{"location": {"center": [1, 1], "radius": 10, "sortByDistance": "yes"}}`,
+		`{"a": {}, "b": {}}`,
+	}
+	for _, in := range invalid {
+		var obj any
+		assert.NoError(t, json.Unmarshal([]byte(in), &obj))
+		f := &GEO{}
+		assert.Error(t, f.Parse(obj), in)
+	}
+}
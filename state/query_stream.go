@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"strconv"
+)
+
+// DefaultQueryFetchSize is the number of results in each page of a streamed query when the fetch size is not set.
+const DefaultQueryFetchSize = 100
+
+// QueryPageFn is invoked with each page of the results of a streamed query.
+// Returning an error stops the stream, and the error is returned by QueryStream.
+type QueryPageFn func(page *QueryResponse) error
+
+// StreamingQuerier is an interface to execute queries whose results are delivered in pages, so large result sets don't need to be held in memory.
+type StreamingQuerier interface {
+	// QueryStream executes the query and invokes fn with each page of at most fetchSize results (DefaultQueryFetchSize if fetchSize is not positive), in order.
+	// All pages are read from a single execution of the query, so items are neither skipped nor repeated across pages because of concurrent writes.
+	// The limit of the query, if any, bounds the total number of results, and the token is the position to start from.
+	// The token of each page is the position of the first result after the page, which can be used to resume the stream in a new query.
+	QueryStream(ctx context.Context, req *QueryRequest, fetchSize int, fn QueryPageFn) error
+}
+
+// QueryPager groups the results of a streamed query into pages.
+type QueryPager struct {
+	fetchSize int
+	position  int64
+	fn        QueryPageFn
+	items     []QueryItem
+}
+
+// NewQueryPager returns a QueryPager that invokes fn with pages of fetchSize items.
+// The position is the offset of the first result, from the token of the query.
+func NewQueryPager(fetchSize int, position int64, fn QueryPageFn) *QueryPager {
+	if fetchSize <= 0 {
+		fetchSize = DefaultQueryFetchSize
+	}
+	return &QueryPager{
+		fetchSize: fetchSize,
+		position:  position,
+		fn:        fn,
+		items:     make([]QueryItem, 0, fetchSize),
+	}
+}
+
+// FetchSize returns the number of results in each page.
+func (p *QueryPager) FetchSize() int {
+	return p.fetchSize
+}
+
+// Add adds an item to the current page, invoking the callback if the page is full.
+func (p *QueryPager) Add(item QueryItem) error {
+	p.items = append(p.items, item)
+	if len(p.items) < p.fetchSize {
+		return nil
+	}
+	return p.Flush()
+}
+
+// Flush invokes the callback with the current page, if it's not empty.
+// It must be invoked after the last result is added.
+func (p *QueryPager) Flush() error {
+	if len(p.items) == 0 {
+		return nil
+	}
+
+	p.position += int64(len(p.items))
+	page := &QueryResponse{
+		Results: p.items,
+		Token:   strconv.FormatInt(p.position, 10),
+	}
+	// The callback owns the results of the page
+	p.items = make([]QueryItem, 0, p.fetchSize)
+	return p.fn(page)
+}
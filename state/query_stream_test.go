@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPager(t *testing.T) {
+	t.Run("pages", func(t *testing.T) {
+		var pages []*QueryResponse
+		p := NewQueryPager(2, 10, func(page *QueryResponse) error {
+			pages = append(pages, page)
+			return nil
+		})
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, p.Add(QueryItem{Key: strconv.Itoa(i)}))
+		}
+		require.NoError(t, p.Flush())
+		// Flushing an empty page does nothing
+		require.NoError(t, p.Flush())
+
+		require.Len(t, pages, 3)
+		assert.Equal(t, []QueryItem{{Key: "0"}, {Key: "1"}}, pages[0].Results)
+		assert.Equal(t, "12", pages[0].Token)
+		assert.Equal(t, []QueryItem{{Key: "2"}, {Key: "3"}}, pages[1].Results)
+		assert.Equal(t, "14", pages[1].Token)
+		assert.Equal(t, []QueryItem{{Key: "4"}}, pages[2].Results)
+		assert.Equal(t, "15", pages[2].Token)
+	})
+
+	t.Run("default fetch size", func(t *testing.T) {
+		p := NewQueryPager(0, 0, func(*QueryResponse) error { return nil })
+		assert.Equal(t, DefaultQueryFetchSize, p.FetchSize())
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		p := NewQueryPager(1, 0, func(*QueryResponse) error { return errors.New("stop") })
+		require.EqualError(t, p.Add(QueryItem{Key: "a"}), "stop")
+	})
+}
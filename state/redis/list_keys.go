@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/state"
+)
+
+// globEscaper escapes the characters that have a special meaning in the patterns of SCAN MATCH.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// ListKeys returns a page of the keys with the prefix.
+// Keys are read with SCAN, which doesn't block the server; the continuation token is the SCAN cursor.
+// SCAN is invoked until it returns at least as many keys as the limit, so pages may contain a few more keys than the limit, and, as with SCAN, keys modified while the pages are read may be returned in more than one page.
+func (r *StateStore) ListKeys(ctx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error) {
+	req = r.keyNormalizer.ListKeysRequest(req)
+	if r.shards != nil {
+		return r.shards.ListKeys(ctx, req)
+	}
+	if err := r.lazyInit.Do(ctx); err != nil {
+		return nil, err
+	}
+	if r.clientSettings != nil && r.clientSettings.RedisType == rediscomponent.ClusterType {
+		return nil, errors.New("redis store: listing keys is not supported with Redis Cluster")
+	}
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := "0"
+	if req.ContinuationToken != "" {
+		_, err = strconv.ParseUint(req.ContinuationToken, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis store: invalid continuation token '%s'", req.ContinuationToken)
+		}
+		cursor = req.ContinuationToken
+	}
+	pattern := globEscaper.Replace(req.Prefix) + "*"
+
+	res := &state.ListKeysResponse{
+		Keys: []string{},
+	}
+	seen := map[string]struct{}{}
+	for {
+		var keys []string
+		cursor, keys, err = r.scan(ctx, cursor, pattern, req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			// SCAN can return the same key more than once
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			res.Keys = append(res.Keys, k)
+		}
+
+		if cursor == "0" {
+			return res, nil
+		}
+		if len(res.Keys) >= req.Limit {
+			res.ContinuationToken = cursor
+			return res, nil
+		}
+	}
+}
+
+// scan invokes SCAN once, and returns the next cursor and the keys.
+func (r *StateStore) scan(ctx context.Context, cursor string, pattern string, count int) (string, []string, error) {
+	res, err := r.client.DoRead(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count)
+	if err != nil {
+		return "", nil, err
+	}
+	arr, ok := res.([]any)
+	if !ok || len(arr) != 2 {
+		return "", nil, fmt.Errorf("redis store: unexpected response to SCAN: %v", res)
+	}
+	next, ok := arr[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("redis store: unexpected cursor in the response to SCAN: %v", arr[0])
+	}
+	items, ok := arr[1].([]any)
+	if !ok {
+		return "", nil, fmt.Errorf("redis store: unexpected keys in the response to SCAN: %v", arr[1])
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		k, ok := item.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("redis store: unexpected key in the response to SCAN: %v", item)
+		}
+		keys = append(keys, k)
+	}
+	return next, keys, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+// listAllKeys reads all the pages of keys with the prefix.
+func listAllKeys(t *testing.T, store state.KeyLister, prefix string, limit int) []string {
+	t.Helper()
+
+	var (
+		keys  []string
+		token string
+	)
+	for i := 0; ; i++ {
+		require.Less(t, i, 1000, "too many pages")
+		res, err := store.ListKeys(context.Background(), &state.ListKeysRequest{Prefix: prefix, Limit: limit, ContinuationToken: token})
+		require.NoError(t, err)
+		keys = append(keys, res.Keys...)
+		if res.ContinuationToken == "" {
+			break
+		}
+		token = res.ContinuationToken
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestListKeys(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+
+	expect := make([]string, 25)
+	for i := range expect {
+		expect[i] = "app||user*" + strconv.Itoa(100+i)
+		require.NoError(t, ss.Set(context.Background(), &state.SetRequest{Key: expect[i], Value: i}))
+	}
+	// Keys that don't have the prefix
+	require.NoError(t, ss.Set(context.Background(), &state.SetRequest{Key: "app||user1", Value: 1}))
+	require.NoError(t, ss.Set(context.Background(), &state.SetRequest{Key: "other||user*1", Value: 1}))
+
+	t.Run("pages", func(t *testing.T) {
+		assert.Equal(t, expect, listAllKeys(t, ss, "app||user*", 10))
+		assert.Len(t, listAllKeys(t, ss, "", 0), 27)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := ss.ListKeys(context.Background(), &state.ListKeysRequest{ContinuationToken: "nope"})
+		require.ErrorContains(t, err, "invalid continuation token")
+	})
+
+	t.Run("not supported with Redis Cluster", func(t *testing.T) {
+		cluster := &StateStore{
+			client:         c,
+			clientSettings: &rediscomponent.Settings{RedisType: rediscomponent.ClusterType},
+		}
+		_, err := cluster.ListKeys(context.Background(), &state.ListKeysRequest{})
+		require.ErrorContains(t, err, "Redis Cluster")
+	})
+}
+
+func TestShardedStoreListKeys(t *testing.T) {
+	ctx := context.Background()
+	ss, _ := newTestShardedStore(t, 3)
+
+	expect := make([]string, 40)
+	for i := range expect {
+		expect[i] = "app||key" + strconv.Itoa(100+i)
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: expect[i], Value: i}))
+	}
+	require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "other||key1", Value: 1}))
+
+	assert.Equal(t, expect, listAllKeys(t, ss, "app||", 7))
+	assert.Equal(t, expect, listAllKeys(t, ss, "app||", 100))
+
+	_, err := ss.ListKeys(ctx, &state.ListKeysRequest{ContinuationToken: "3:0"})
+	require.ErrorContains(t, err, "invalid continuation token")
+}
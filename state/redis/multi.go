@@ -0,0 +1,358 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dapr/components-contrib/contenttype"
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	daprmetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/utils"
+)
+
+// multiMetadata contains the options of transactions.
+type multiMetadata struct {
+	// Maximum number of operations in each MULTI/EXEC transaction; larger requests are split in consecutive transactions. 0 (the default) for no limit
+	MultiMaxBatchSize int `mapstructure:"multiMaxBatchSize"`
+	// Maximum number of commands sent in each round trip while queuing a transaction. 0 (the default) to send all the commands at once
+	MultiPipelineDepth int `mapstructure:"multiPipelineDepth"`
+}
+
+// parseMultiMetadata returns the options of transactions in the metadata properties.
+func parseMultiMetadata(props map[string]string) (multiMetadata, error) {
+	m := multiMetadata{}
+	err := daprmetadata.DecodeMetadata(props, &m)
+	if err != nil {
+		return m, fmt.Errorf("redis store: error parsing transaction options: %w", err)
+	}
+	if m.MultiMaxBatchSize < 0 {
+		return m, errors.New("redis store: invalid value for 'multiMaxBatchSize': must not be negative")
+	}
+	if m.MultiPipelineDepth < 0 {
+		return m, errors.New("redis store: invalid value for 'multiPipelineDepth': must not be negative")
+	}
+	return m, nil
+}
+
+// versionCheck is the ETag of an operation, that is compared with the current version of the key before the transaction is queued.
+type versionCheck struct {
+	key   string
+	etag  string
+	json  bool
+	clock bool
+}
+
+// command returns the command that reads the current version of the key.
+func (c versionCheck) command() []any {
+	switch {
+	case c.clock:
+		return []any{"HGET", c.key, "vclock"}
+	case c.json:
+		return []any{"JSON.GET", c.key, ".version"}
+	default:
+		return []any{"HGET", c.key, "version"}
+	}
+}
+
+// verify returns an ETag error if the reply of the command doesn't match the ETag.
+// Keys that don't exist, or that aren't stored as the check expects, are left to the scripts that apply the operations.
+func (c versionCheck) verify(reply any) error {
+	current, _ := reply.(string)
+	if !c.clock {
+		if current == "" || current == c.etag {
+			return nil
+		}
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("failed to update key %s", c.key))
+	}
+
+	res, err := compareVectorClocks(current, c.etag)
+	if err != nil {
+		return err
+	}
+	switch res {
+	case "concurrent":
+		return state.NewETagError(state.ETagMismatch, &VectorClockConflictError{
+			Key:     c.key,
+			Current: current,
+		})
+	case "stale":
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("failed to update key %s", c.key))
+	default:
+		return nil
+	}
+}
+
+// multiBatch contains the commands that apply a batch of operations, and the ETags that they are conditioned on.
+type multiBatch struct {
+	commands [][]any
+	checks   []versionCheck
+}
+
+// execMulti applies the operations in one or more transactions, with at most multiMaxBatchSize operations each.
+func (r *StateStore) execMulti(ctx context.Context, operations []state.TransactionalStateOperation, isJSON bool) error {
+	size := r.multi.MultiMaxBatchSize
+	if size <= 0 || size > len(operations) {
+		size = len(operations)
+	}
+	for start := 0; start < len(operations); start += size {
+		end := start + size
+		if end > len(operations) {
+			end = len(operations)
+		}
+		batch, err := r.multiBatch(operations[start:end], isJSON)
+		if err != nil {
+			return err
+		}
+		err = r.execBatch(ctx, batch)
+		if err != nil {
+			if start > 0 {
+				return fmt.Errorf("failed to apply operations %d to %d, after the previous operations were applied: %w", start, end-1, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// multiBatch returns the commands that apply the operations, and the ETags of the first operation on each key.
+func (r *StateStore) multiBatch(operations []state.TransactionalStateOperation, isJSON bool) (multiBatch, error) {
+	batch := multiBatch{
+		commands: make([][]any, 0, len(operations)),
+	}
+	seen := make(map[string]struct{}, len(operations))
+	for _, o := range operations {
+		cmds, check, err := r.multiCommands(o, isJSON)
+		if err != nil {
+			return batch, err
+		}
+		batch.commands = append(batch.commands, cmds...)
+		// Later operations on the same key are conditioned on the versions written by the transaction
+		if _, ok := seen[o.GetKey()]; ok {
+			continue
+		}
+		seen[o.GetKey()] = struct{}{}
+		if check != nil {
+			batch.checks = append(batch.checks, *check)
+		}
+	}
+	return batch, nil
+}
+
+// multiCommands returns the commands that apply an operation, and the ETag it is conditioned on if any.
+func (r *StateStore) multiCommands(o state.TransactionalStateOperation, isJSON bool) ([][]any, *versionCheck, error) {
+	switch req := o.(type) {
+	case state.SetRequest:
+		isReqJSON := isJSON ||
+			(len(req.Metadata) > 0 && req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType)
+		var (
+			cmds  [][]any
+			check *versionCheck
+		)
+		if r.vectorClockWriter != "" {
+			args, err := r.vectorClockSetArgs(&req, 1)
+			if err != nil {
+				return nil, nil, err
+			}
+			cmds = append(cmds, args)
+			// The ETag was validated by vectorClockSetArgs
+			if etag, _ := vectorClockETag(req.ETag, req.Options.Concurrency); etag != "" {
+				check = &versionCheck{key: req.Key, etag: etag, clock: true}
+			}
+		} else {
+			ver, err := r.parseETag(&req)
+			if err != nil {
+				return nil, nil, err
+			}
+			if isReqJSON {
+				bt, _ := utils.Marshal(&jsonEntry{Data: req.Value}, r.json.Marshal)
+				cmds = append(cmds, []any{"EVAL", setJSONQuery, 1, req.Key, ver, bt})
+			} else {
+				bt, codecName, err := r.encodeValue(req.Value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to serialize value of key %s: %w", req.Key, err)
+				}
+				cmds = append(cmds, []any{"EVAL", setDefaultQuery, 1, req.Key, ver, bt, 1, codecName})
+			}
+			if ver != 0 {
+				check = &versionCheck{key: req.Key, etag: strconv.Itoa(ver), json: isReqJSON}
+			}
+		}
+
+		ttl, err := r.parseTTL(&req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse ttl from metadata: %w", err)
+		}
+		// apply global TTL
+		if ttl == nil {
+			ttl = r.metadata.TTLInSeconds
+		}
+		if ttl != nil && *ttl > 0 {
+			cmds = append(cmds, []any{"EXPIRE", req.Key, *ttl})
+		}
+		if ttl != nil && *ttl <= 0 {
+			cmds = append(cmds, []any{"PERSIST", req.Key})
+		}
+		return cmds, check, nil
+
+	case state.DeleteRequest:
+		if r.vectorClockWriter != "" {
+			etag, err := vectorClockETag(req.ETag, req.Options.Concurrency)
+			if err != nil {
+				return nil, nil, err
+			}
+			var check *versionCheck
+			if etag != "" {
+				check = &versionCheck{key: req.Key, etag: etag, clock: true}
+			}
+			return [][]any{{"EVAL", delVectorClockQuery, 1, req.Key, etag}}, check, nil
+		}
+		etag := "0"
+		if req.ETag != nil {
+			etag = *req.ETag
+		}
+		isReqJSON := isJSON ||
+			(len(req.Metadata) > 0 && req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType)
+		var check *versionCheck
+		if etag != "0" && etag != "" {
+			check = &versionCheck{key: req.Key, etag: etag, json: isReqJSON}
+		}
+		if isReqJSON {
+			return [][]any{{"EVAL", delJSONQuery, 1, req.Key, etag}}, check, nil
+		}
+		return [][]any{{"EVAL", delDefaultQuery, 1, req.Key, etag}}, check, nil
+	}
+	return nil, nil, nil
+}
+
+// execBatch applies a batch of operations in a MULTI/EXEC transaction.
+// The keys of the operations with ETags are watched and their versions are checked before the transaction is queued, so ETag mismatches, and changes made by other clients after the checks, abort the transaction before any operation is applied.
+func (r *StateStore) execBatch(ctx context.Context, batch multiBatch) error {
+	if len(batch.commands) == 0 {
+		return nil
+	}
+
+	// Keys in Redis Cluster can be in different slots, that can't be watched together: the commands are sent in transactions by slot
+	// The ETags are checked by the scripts that apply the operations, as in single-key requests
+	if r.clientSettings != nil && r.clientSettings.RedisType == rediscomponent.ClusterType {
+		pipe := r.client.TxPipeline()
+		for _, cmd := range batch.commands {
+			pipe.Do(ctx, cmd...)
+		}
+		return r.multiError(pipe.Exec(ctx))
+	}
+
+	keys := make([]string, len(batch.checks))
+	for i, check := range batch.checks {
+		keys[i] = check.key
+	}
+	return r.client.Watch(ctx, func(tx rediscomponent.RedisTx) error {
+		if len(batch.checks) > 0 {
+			cmds := make([][]any, len(batch.checks))
+			for i, check := range batch.checks {
+				cmds[i] = check.command()
+			}
+			replies, err := r.pipeline(ctx, tx, cmds, false)
+			if err != nil {
+				return err
+			}
+			for i, check := range batch.checks {
+				err = check.verify(replies[i])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		cmds := make([][]any, 0, len(batch.commands)+2)
+		cmds = append(cmds, []any{"MULTI"})
+		cmds = append(cmds, batch.commands...)
+		cmds = append(cmds, []any{"EXEC"})
+		replies, err := r.pipeline(ctx, tx, cmds, true)
+		if err != nil {
+			var queueErr *multiQueueError
+			if errors.As(err, &queueErr) {
+				// Nothing was applied; discard the queued commands, so the connection can be reused
+				_, _ = tx.Pipeline(ctx, []any{"DISCARD"})
+				return queueErr.err
+			}
+			return err
+		}
+
+		switch res := replies[len(replies)-1].(type) {
+		case nil:
+			return state.NewETagError(state.ETagMismatch, errors.New("a watched key was modified by another client"))
+		case error:
+			return res
+		case []any:
+			for _, reply := range res {
+				if err, ok := reply.(error); ok {
+					return r.multiError(err)
+				}
+			}
+		}
+		return nil
+	}, keys...)
+}
+
+// multiQueueError is returned by pipeline when a command queued in a transaction is rejected.
+type multiQueueError struct {
+	err error
+}
+
+func (e *multiQueueError) Error() string {
+	return e.err.Error()
+}
+
+// pipeline sends the commands in rounds of at most multiPipelineDepth commands, and returns their replies.
+// If queued is true, the commands are a transaction ending with EXEC, and the commands are not sent after one of them is rejected.
+func (r *StateStore) pipeline(ctx context.Context, tx rediscomponent.RedisTx, cmds [][]any, queued bool) ([]any, error) {
+	depth := r.multi.MultiPipelineDepth
+	if depth <= 0 || depth > len(cmds) {
+		depth = len(cmds)
+	}
+	replies := make([]any, 0, len(cmds))
+	for start := 0; start < len(cmds); start += depth {
+		end := start + depth
+		if end > len(cmds) {
+			end = len(cmds)
+		}
+		res, err := tx.Pipeline(ctx, cmds[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for i, reply := range res {
+			// The reply of EXEC is checked by the caller
+			if err, ok := reply.(error); ok && queued && start+i < len(cmds)-1 {
+				return nil, &multiQueueError{err: err}
+			}
+		}
+		replies = append(replies, res...)
+	}
+	return replies, nil
+}
+
+// multiError translates the error of a script that reported a concurrent update with vector clocks.
+func (r *StateStore) multiError(err error) error {
+	if err != nil && r.vectorClockWriter != "" {
+		if conflictErr := vectorClockError(err); conflictErr != nil {
+			return conflictErr
+		}
+	}
+	return err
+}
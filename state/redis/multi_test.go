@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestParseMultiMetadata(t *testing.T) {
+	m, err := parseMultiMetadata(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, multiMetadata{}, m)
+
+	m, err = parseMultiMetadata(map[string]string{"multiMaxBatchSize": "100", "multiPipelineDepth": "10"})
+	require.NoError(t, err)
+	assert.Equal(t, multiMetadata{MultiMaxBatchSize: 100, MultiPipelineDepth: 10}, m)
+
+	for _, props := range []map[string]string{
+		{"multiMaxBatchSize": "-1"},
+		{"multiPipelineDepth": "-1"},
+		{"multiMaxBatchSize": "many"},
+	} {
+		_, err = parseMultiMetadata(props)
+		require.Error(t, err, props)
+	}
+}
+
+// watchHookClient runs a function after the keys of each transaction are watched.
+type watchHookClient struct {
+	rediscomponent.RedisClient
+	afterWatch func()
+}
+
+func (c watchHookClient) Watch(ctx context.Context, fn func(tx rediscomponent.RedisTx) error, keys ...string) error {
+	return c.RedisClient.Watch(ctx, func(tx rediscomponent.RedisTx) error {
+		c.afterWatch()
+		return fn(tx)
+	}, keys...)
+}
+
+func TestMultiTransactions(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ctx := context.Background()
+	newStore := func(m multiMetadata) *StateStore {
+		return &StateStore{
+			client: c,
+			json:   jsoniter.ConfigFastest,
+			logger: logger.NewLogger("test"),
+			multi:  m,
+		}
+	}
+	version := func(t *testing.T, key string) string {
+		t.Helper()
+		res, err := c.DoRead(ctx, "HMGET", key, "version")
+		require.NoError(t, err)
+		v, _ := res.([]any)[0].(string)
+		return v
+	}
+	sets := func(prefix string, n int) []state.TransactionalStateOperation {
+		ops := make([]state.TransactionalStateOperation, n)
+		for i := range ops {
+			ops[i] = state.SetRequest{Key: prefix + strconv.Itoa(i), Value: i}
+		}
+		return ops
+	}
+
+	t.Run("operations are applied in batches with pipelined commands", func(t *testing.T) {
+		ss := newStore(multiMetadata{MultiMaxBatchSize: 2, MultiPipelineDepth: 3})
+		ops := sets("batch", 5)
+		ops = append(ops, state.SetRequest{Key: "batch0", Value: "again", ETag: ptr.Of("1")})
+		require.NoError(t, ss.Multi(ctx, &state.TransactionalStateRequest{Operations: ops}))
+
+		assert.Equal(t, "2", version(t, "batch0"))
+		for i := 1; i < 5; i++ {
+			assert.Equal(t, "1", version(t, "batch"+strconv.Itoa(i)))
+		}
+	})
+
+	t.Run("ETag mismatch aborts the transaction before any operation is applied", func(t *testing.T) {
+		ss := newStore(multiMetadata{})
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "mismatch", Value: "a"}))
+
+		ops := sets("mismatch-new", 2)
+		ops = append(ops,
+			state.SetRequest{Key: "mismatch", Value: "b", ETag: ptr.Of("5")},
+			state.DeleteRequest{Key: "mismatch-new0"},
+		)
+		err := ss.Multi(ctx, &state.TransactionalStateRequest{Operations: ops})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+
+		assert.Equal(t, "1", version(t, "mismatch"))
+		assert.Empty(t, version(t, "mismatch-new0"))
+		assert.Empty(t, version(t, "mismatch-new1"))
+	})
+
+	t.Run("later operations on a key are conditioned on the versions written by the transaction", func(t *testing.T) {
+		ss := newStore(multiMetadata{})
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "chained", Value: "a"}))
+
+		err := ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "chained", Value: "b", ETag: ptr.Of("1")},
+			state.DeleteRequest{Key: "chained", ETag: ptr.Of("2")},
+		}})
+		require.NoError(t, err)
+		assert.Empty(t, version(t, "chained"))
+	})
+
+	t.Run("failed batch reports that the previous batches were applied", func(t *testing.T) {
+		ss := newStore(multiMetadata{MultiMaxBatchSize: 1})
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "partial", Value: "a"}))
+
+		err := ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "partial-new", Value: "a"},
+			state.SetRequest{Key: "partial", Value: "b", ETag: ptr.Of("5")},
+		}})
+		require.ErrorContains(t, err, "previous operations were applied")
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, "1", version(t, "partial-new"))
+	})
+
+	t.Run("concurrent modification of a watched key aborts the transaction", func(t *testing.T) {
+		ss := newStore(multiMetadata{MultiPipelineDepth: 1})
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "watched", Value: "a"}))
+		// Another client changes the key without changing its version, so only WATCH detects the change
+		ss.client = watchHookClient{
+			RedisClient: c,
+			afterWatch: func() {
+				require.NoError(t, c.DoWrite(ctx, "HSET", "watched", "data", `"other"`))
+			},
+		}
+
+		err := ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "watched-new", Value: "a"},
+			state.SetRequest{Key: "watched", Value: "b", ETag: ptr.Of("1")},
+		}})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		assert.Equal(t, "1", version(t, "watched"))
+		assert.Empty(t, version(t, "watched-new"))
+	})
+
+	t.Run("ETags are checked by the scripts with Redis Cluster", func(t *testing.T) {
+		ss := newStore(multiMetadata{})
+		ss.clientSettings = &rediscomponent.Settings{RedisType: rediscomponent.ClusterType}
+		require.NoError(t, ss.Multi(ctx, &state.TransactionalStateRequest{Operations: sets("cluster", 1)}))
+
+		err := ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "cluster0", Value: "b", ETag: ptr.Of("2")},
+		}})
+		require.Error(t, err)
+		assert.Equal(t, "1", version(t, "cluster0"))
+
+		require.NoError(t, ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "cluster-new", Value: "a"},
+			state.SetRequest{Key: "cluster0", Value: "b", ETag: ptr.Of("1")},
+		}}))
+		assert.Equal(t, "2", version(t, "cluster0"))
+		assert.Equal(t, "1", version(t, "cluster-new"))
+
+		require.NoError(t, ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.DeleteRequest{Key: "cluster0", ETag: ptr.Of("2")},
+		}}))
+		assert.Empty(t, version(t, "cluster0"))
+	})
+
+	t.Run("rejected command discards the transaction", func(t *testing.T) {
+		ss := newStore(multiMetadata{MultiPipelineDepth: 1})
+		err := ss.execBatch(ctx, multiBatch{commands: [][]any{
+			{"SET", "rejected", "a"},
+			{"SET", "rejected"},
+			{"SET", "rejected-after", "a"},
+		}})
+		require.Error(t, err)
+		var queueErr *multiQueueError
+		assert.False(t, errors.As(err, &queueErr))
+
+		res, err := c.DoRead(ctx, "EXISTS", "rejected", "rejected-after")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), res)
+
+		// The connection can be used by the next transactions
+		require.NoError(t, ss.Multi(ctx, &state.TransactionalStateRequest{Operations: sets("rejected-next", 1)}))
+		assert.Equal(t, "1", version(t, "rejected-next0"))
+	})
+}
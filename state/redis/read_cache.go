@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	defaultLocalCacheMaxEntries = 10000
+
+	// Number of stripes of write generations; keys that share a stripe invalidate each other's in-flight reads, which is safe
+	readCacheGenerationStripes = 1024
+
+	readCacheVariantDefault = "default"
+	readCacheVariantJSON    = "json"
+)
+
+// readCacheVariants are the variants of the responses cached for each key.
+var readCacheVariants = []string{readCacheVariantDefault, readCacheVariantJSON}
+
+// readCacheMetadata contains the options of the read cache.
+type readCacheMetadata struct {
+	// If true, concurrent Get requests for the same key share a single round trip to Redis
+	GetCoalescing bool `mapstructure:"getCoalescing"`
+	// If positive, values read with Get are cached in memory for this duration
+	LocalCacheTTL time.Duration `mapstructure:"localCacheTTL"`
+	// Maximum number of values in the local cache
+	LocalCacheMaxEntries int `mapstructure:"localCacheMaxEntries"`
+}
+
+// readCacheFetchFn reads a key from Redis.
+type readCacheFetchFn func(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error)
+
+// readCache coalesces concurrent reads of the same key and optionally caches the values in memory for a short time.
+// Writes made through the state store invalidate the cached values; writes made by other clients are visible once the cached values expire.
+type readCache struct {
+	coalesce   bool
+	ttl        time.Duration
+	maxEntries int
+
+	group   singleflight.Group
+	lock    sync.Mutex
+	entries map[string]readCacheEntry
+
+	// Generations are incremented when keys are written, so reads that started before a write are neither shared with reads that started after it, nor cached
+	generations [readCacheGenerationStripes]atomic.Uint64
+}
+
+type readCacheEntry struct {
+	res     *state.GetResponse
+	expires time.Time
+}
+
+// newReadCache returns a readCache configured with the metadata properties, or nil if neither coalescing nor the local cache are enabled.
+func newReadCache(props map[string]string) (*readCache, error) {
+	m := readCacheMetadata{
+		LocalCacheMaxEntries: defaultLocalCacheMaxEntries,
+	}
+	err := metadata.DecodeMetadata(props, &m)
+	if err != nil {
+		return nil, fmt.Errorf("redis store: error parsing read cache options: %w", err)
+	}
+	if m.LocalCacheTTL < 0 {
+		return nil, errors.New("redis store: invalid value for 'localCacheTTL': must not be negative")
+	}
+	if m.LocalCacheMaxEntries <= 0 {
+		return nil, errors.New("redis store: invalid value for 'localCacheMaxEntries': must be positive")
+	}
+	if !m.GetCoalescing && m.LocalCacheTTL == 0 {
+		return nil, nil
+	}
+
+	return &readCache{
+		coalesce:   m.GetCoalescing,
+		ttl:        m.LocalCacheTTL,
+		maxEntries: m.LocalCacheMaxEntries,
+		entries:    make(map[string]readCacheEntry),
+	}, nil
+}
+
+// get returns the value of the key from the local cache, or reads it with fetch, sharing the round trip with concurrent reads of the same key.
+// The variant distinguishes requests for the same key that return different responses, such as JSON reads.
+// Each caller receives its own copy of the response.
+func (c *readCache) get(ctx context.Context, req *state.GetRequest, variant string, fetch readCacheFetchFn) (*state.GetResponse, error) {
+	cacheKey := variant + "||" + req.Key
+	// Reads with strong consistency always see the latest value in Redis
+	useLocal := c.ttl > 0 && req.Options.Consistency != state.Strong
+
+	if useLocal {
+		if res, ok := c.lookup(cacheKey); ok {
+			return res, nil
+		}
+	}
+
+	gen := c.generation(req.Key).Load()
+	var (
+		res *state.GetResponse
+		err error
+	)
+	if c.coalesce {
+		var v any
+		v, err, _ = c.group.Do(cacheKey+"||"+strconv.FormatUint(gen, 10), func() (any, error) {
+			return fetch(ctx, req)
+		})
+		if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			// The context of the caller that performed the shared read was canceled, but this caller's wasn't
+			v, err = fetch(ctx, req)
+		}
+		if err == nil {
+			res = v.(*state.GetResponse)
+		}
+	} else {
+		res, err = fetch(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ttl > 0 {
+		c.store(req.Key, cacheKey, gen, res)
+	}
+	return copyGetResponse(res), nil
+}
+
+func (c *readCache) lookup(cacheKey string) (*state.GetResponse, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[cacheKey]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, cacheKey)
+		return nil, false
+	}
+	return copyGetResponse(e.res), true
+}
+
+// store caches the response, unless the key was written since the read started.
+func (c *readCache) store(key string, cacheKey string, gen uint64, res *state.GetResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// The generation is checked while holding the lock, as invalidate increments it before removing the entries
+	if c.generation(key).Load() != gen {
+		return
+	}
+
+	if _, ok := c.entries[cacheKey]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[cacheKey] = readCacheEntry{
+		res:     copyGetResponse(res),
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// evict removes the expired entries or, if there are none, an arbitrary entry.
+// It must be invoked while holding the lock.
+func (c *readCache) evict() {
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	for k := range c.entries {
+		delete(c.entries, k)
+		return
+	}
+}
+
+// invalidate removes the cached values of the keys, and prevents reads in progress from being cached or shared with later reads.
+// It must be invoked after the keys are written.
+func (c *readCache) invalidate(keys ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, key := range keys {
+		c.generation(key).Add(1)
+		for _, variant := range readCacheVariants {
+			delete(c.entries, variant+"||"+key)
+		}
+	}
+}
+
+func (c *readCache) generation(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &c.generations[h.Sum32()%readCacheGenerationStripes]
+}
+
+// copyGetResponse returns a deep copy of the response, so callers can't modify each other's responses or the cached ones.
+func copyGetResponse(res *state.GetResponse) *state.GetResponse {
+	if res == nil {
+		return nil
+	}
+
+	cp := &state.GetResponse{}
+	if res.Data != nil {
+		cp.Data = bytes.Clone(res.Data)
+	}
+	if res.ETag != nil {
+		cp.ETag = ptr.Of(*res.ETag)
+	}
+	if res.ContentType != nil {
+		cp.ContentType = ptr.Of(*res.ContentType)
+	}
+	if res.Metadata != nil {
+		cp.Metadata = make(map[string]string, len(res.Metadata))
+		for k, v := range res.Metadata {
+			cp.Metadata[k] = v
+		}
+	}
+	return cp
+}
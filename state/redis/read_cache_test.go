@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestNewReadCache(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		c, err := newReadCache(map[string]string{})
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+
+	t.Run("coalescing only", func(t *testing.T) {
+		c, err := newReadCache(map[string]string{"getCoalescing": "true"})
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.True(t, c.coalesce)
+		assert.Equal(t, time.Duration(0), c.ttl)
+		assert.Equal(t, defaultLocalCacheMaxEntries, c.maxEntries)
+	})
+
+	t.Run("local cache", func(t *testing.T) {
+		c, err := newReadCache(map[string]string{"localCacheTTL": "2s", "localCacheMaxEntries": "10"})
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.False(t, c.coalesce)
+		assert.Equal(t, 2*time.Second, c.ttl)
+		assert.Equal(t, 10, c.maxEntries)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, err := newReadCache(map[string]string{"localCacheTTL": "-1s"})
+		require.ErrorContains(t, err, "localCacheTTL")
+		_, err = newReadCache(map[string]string{"localCacheTTL": "1s", "localCacheMaxEntries": "0"})
+		require.ErrorContains(t, err, "localCacheMaxEntries")
+	})
+}
+
+func TestReadCacheCoalescing(t *testing.T) {
+	c, err := newReadCache(map[string]string{"getCoalescing": "true"})
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+		calls.Add(1)
+		<-release
+		return &state.GetResponse{Data: []byte("value"), ETag: ptr.Of("1")}, nil
+	}
+
+	const n = 10
+	results := make([]*state.GetResponse, n)
+	started := sync.WaitGroup{}
+	done := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			res, err := c.get(context.Background(), &state.GetRequest{Key: "key"}, readCacheVariantDefault, fetch)
+			assert.NoError(t, err)
+			results[i] = res
+		}(i)
+	}
+	started.Wait()
+	// Give the goroutines time to join the shared read
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	assert.Less(t, calls.Load(), int32(n))
+	for _, res := range results {
+		require.NotNil(t, res)
+		assert.Equal(t, []byte("value"), res.Data)
+		assert.Equal(t, "1", *res.ETag)
+	}
+
+	// Each caller has its own copy of the response
+	results[0].Data[0] = 'X'
+	*results[0].ETag = "2"
+	assert.Equal(t, []byte("value"), results[1].Data)
+	assert.Equal(t, "1", *results[1].ETag)
+}
+
+func TestReadCacheLocalCache(t *testing.T) {
+	newCache := func(t *testing.T) *readCache {
+		c, err := newReadCache(map[string]string{"localCacheTTL": "1m", "localCacheMaxEntries": "2"})
+		require.NoError(t, err)
+		return c
+	}
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+		calls.Add(1)
+		return &state.GetResponse{Data: []byte(req.Key), ETag: ptr.Of("1")}, nil
+	}
+
+	t.Run("cache hit", func(t *testing.T) {
+		calls.Store(0)
+		c := newCache(t)
+		res, err := c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		res.Data[0] = 'X'
+
+		res, err = c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("a"), res.Data)
+		assert.Equal(t, int32(1), calls.Load())
+
+		// Variants are cached separately
+		_, err = c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantJSON, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("strong consistency bypasses the cache", func(t *testing.T) {
+		calls.Store(0)
+		c := newCache(t)
+		req := &state.GetRequest{Key: "a", Options: state.GetStateOption{Consistency: state.Strong}}
+		_, err := c.get(context.Background(), req, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		_, err = c.get(context.Background(), req, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		calls.Store(0)
+		c := newCache(t)
+		_, err := c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		c.invalidate("a")
+		_, err = c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("reads that started before a write aren't cached", func(t *testing.T) {
+		calls.Store(0)
+		c := newCache(t)
+		writing := func(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+			c.invalidate(req.Key)
+			return fetch(ctx, req)
+		}
+		_, err := c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantDefault, writing)
+		require.NoError(t, err)
+		_, err = c.get(context.Background(), &state.GetRequest{Key: "a"}, readCacheVariantDefault, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("max entries", func(t *testing.T) {
+		c := newCache(t)
+		for _, k := range []string{"a", "b", "c"} {
+			_, err := c.get(context.Background(), &state.GetRequest{Key: k}, readCacheVariantDefault, fetch)
+			require.NoError(t, err)
+		}
+		assert.Len(t, c.entries, 2)
+	})
+}
+
+func TestReadCacheInvalidatedByWrites(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	rc, err := newReadCache(map[string]string{"getCoalescing": "true", "localCacheTTL": "1m"})
+	require.NoError(t, err)
+	ss := &StateStore{
+		client:    c,
+		json:      jsoniter.ConfigFastest,
+		logger:    logger.NewLogger("test"),
+		readCache: rc,
+	}
+	ctx := context.Background()
+
+	require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "key", Value: "v1"}))
+	res, err := ss.Get(ctx, &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, string(res.Data))
+	assert.Equal(t, "1", *res.ETag)
+
+	// A write made by another client isn't visible until the cached value expires
+	err = c.DoWrite(ctx, "HSET", "key", "data", `"other"`, "version", "5")
+	require.NoError(t, err)
+	res, err = ss.Get(ctx, &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, string(res.Data))
+
+	// Writes through the store invalidate the cached value, so the ETag is always current
+	require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "key", Value: "v2", ETag: ptr.Of("5")}))
+	res, err = ss.Get(ctx, &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v2"`, string(res.Data))
+	assert.Equal(t, "6", *res.ETag)
+
+	require.NoError(t, ss.Multi(ctx, &state.TransactionalStateRequest{
+		Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "key", Value: "v3"},
+		},
+	}))
+	res, err = ss.Get(ctx, &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v3"`, string(res.Data))
+
+	require.NoError(t, ss.Delete(ctx, &state.DeleteRequest{Key: "key"}))
+	res, err = ss.Get(ctx, &state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+}
@@ -25,7 +25,10 @@ import (
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/internal/component/keynormalizer"
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	daprmetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
@@ -36,6 +39,9 @@ import (
 
 const (
 	setDefaultQuery = `
+	if ARGV[5] == "1" and redis.call("EXISTS", KEYS[1]) == 1 then
+	  return error("failed to set key " .. KEYS[1])
+	end;
 	local etag = redis.pcall("HGET", KEYS[1], "version");
 	if type(etag) == "table" then
 	  redis.call("DEL", KEYS[1]);
@@ -46,6 +52,11 @@ const (
 	  if ARGV[3] == "0" then
 	    redis.call("HSET", KEYS[1], "first-write", 0);
 	  end;
+	  if ARGV[4] and ARGV[4] ~= "" then
+	    redis.call("HSET", KEYS[1], "codec", ARGV[4]);
+	  else
+	    redis.call("HDEL", KEYS[1], "codec");
+	  end;
 	  return redis.call("HINCRBY", KEYS[1], "version", 1)
 	else
 	  return error("failed to set key " .. KEYS[1])
@@ -58,6 +69,9 @@ const (
 	  return error("failed to delete " .. KEYS[1])
 	end`
 	setJSONQuery = `
+	if ARGV[4] == "1" and redis.call("EXISTS", KEYS[1]) == 1 then
+	  return error("failed to set key " .. KEYS[1])
+	end;
 	local etag = redis.pcall("JSON.GET", KEYS[1], ".version");
 	if type(etag) == "table" then
 	  redis.call("JSON.DEL", KEYS[1]);
@@ -90,6 +104,11 @@ const (
 	defaultDB                = 0
 )
 
+// stateStoreMetadata contains the metadata properties of the state store that aren't settings of the Redis client.
+type stateStoreMetadata struct {
+	KeyNormalizer keynormalizer.Metadata `mapstructure:",squash"`
+}
+
 // StateStore is a Redis state store.
 type StateStore struct {
 	state.BulkStore
@@ -101,7 +120,30 @@ type StateStore struct {
 	metadata                       rediscomponent.Metadata
 	replicas                       int
 	querySchemas                   querySchemas
+	codec                          statecodec.Codec
 	suppressActorStateStoreWarning atomic.Bool
+	warmupConnections              int
+
+	// Set when "getCoalescing" or "localCacheTTL" are enabled, to coalesce and cache reads
+	readCache *readCache
+
+	// Set when the "lazyInit" option is enabled, to connect on the first operation
+	lazyInit *internalutils.LazyInit
+
+	// Set when the "redisShards" option is enabled, to distribute keys across multiple Redis servers
+	shards *shardedStore
+
+	// Set when the "concurrencyMode" option is "vectorClock", to the ID of this writer in the vector clocks of keys
+	vectorClockWriter string
+
+	// Options of the MULTI/EXEC transactions of Multi requests
+	multi multiMetadata
+
+	// Normalization of the keys of requests, disabled by default
+	keyNormalizer keynormalizer.Normalizer
+
+	// Performs the read-modify-write updates of SetWithMerge, retrying them on ETag conflicts as configured by the "etagRetry" metadata properties
+	etagRetrier *state.ETagRetrier
 
 	features []state.Feature
 	logger   logger.Logger
@@ -124,8 +166,15 @@ func newStateStore(log logger.Logger) *StateStore {
 }
 
 func (r *StateStore) Ping(ctx context.Context) error {
+	if r.shards != nil {
+		return r.shards.Ping(ctx)
+	}
+	if r.client == nil {
+		return errors.New("redis store: client not initialized")
+	}
+
 	if _, err := r.client.PingResult(ctx); err != nil {
-		return fmt.Errorf("redis store: error connecting to redis at %s: %w", r.clientSettings.Host, err)
+		return fmt.Errorf("redis store: error connecting to redis at %s: %w", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
 	}
 
 	return nil
@@ -133,12 +182,48 @@ func (r *StateStore) Ping(ctx context.Context) error {
 
 // Init does metadata and connection parsing.
 func (r *StateStore) Init(ctx context.Context, metadata state.Metadata) error {
+	// Secret references, such as for the password, are resolved before the client is created
+	props, err := metadata.ResolvedProperties(ctx)
+	if err != nil {
+		return err
+	}
+	metadata.Properties = props
+
+	// Keys are normalized before they're routed to shards
+	storeMetadata := stateStoreMetadata{}
+	err = daprmetadata.DecodeMetadata(metadata.Properties, &storeMetadata)
+	if err != nil {
+		return fmt.Errorf("redis store: error parsing metadata: %w", err)
+	}
+	r.keyNormalizer, err = keynormalizer.New(storeMetadata.KeyNormalizer)
+	if err != nil {
+		return fmt.Errorf("redis store: %w", err)
+	}
+
+	// The retrier updates keys with Get and Set, so it works in sharded mode too
+	etagRetryConfig, err := state.ParseETagRetryConfig(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("redis store: error parsing ETag retry options: %w", err)
+	}
+	r.etagRetrier = state.NewETagRetrier(mergeStore{r}, etagRetryConfig)
+
+	// In sharded mode, each shard is managed by a separate store
+	if metadata.Properties[shardsKey] != "" {
+		r.shards, err = newShardedStore(ctx, metadata, r.logger)
+		return err
+	}
+
 	m, err := rediscomponent.ParseRedisMetadata(metadata.Properties)
 	if err != nil {
 		return err
 	}
 	r.metadata = m
 
+	r.codec, err = statecodec.Parse(metadata.Properties[statecodec.SerializerKey])
+	if err != nil {
+		return err
+	}
+
 	defaultSettings := rediscomponent.Settings{RedisMaxRetries: m.MaxRetries, RedisMaxRetryInterval: rediscomponent.Duration(m.MaxRetryBackoff)}
 	r.client, r.clientSettings, err = rediscomponent.ParseClientFromProperties(metadata.Properties, &defaultSettings)
 	if err != nil {
@@ -150,8 +235,50 @@ func (r *StateStore) Init(ctx context.Context, metadata state.Metadata) error {
 		return fmt.Errorf("redis store: error parsing query index schema: %w", err)
 	}
 
+	r.readCache, err = newReadCache(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	r.vectorClockWriter, err = parseVectorClockWriter(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	r.multi, err = parseMultiMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	if val := metadata.Properties[internalutils.WarmupConnectionsKey]; val != "" {
+		r.warmupConnections, err = strconv.Atoi(val)
+		if err != nil || r.warmupConnections < 0 {
+			return fmt.Errorf("redis store: invalid value for '%s': must be a non-negative integer", internalutils.WarmupConnectionsKey)
+		}
+	}
+
+	// With lazy init, connecting to Redis is deferred until the first operation
+	if internalutils.IsTruthy(metadata.Properties[internalutils.LazyInitKey]) {
+		r.lazyInit = internalutils.NewLazyInit(r.connect)
+		return nil
+	}
+
+	return r.connect(ctx)
+}
+
+// connect checks the connection to Redis and loads the information about the server.
+func (r *StateStore) connect(ctx context.Context) (err error) {
 	if _, err = r.client.PingResult(ctx); err != nil {
-		return fmt.Errorf("redis store: error connecting to redis at %s: %w", r.clientSettings.Host, err)
+		return fmt.Errorf("redis store: error connecting to redis at %s: %w", internalutils.RedactConnectionString(r.clientSettings.Host), internalutils.RedactError(err, r.clientSettings.Host))
+	}
+
+	// Each concurrent ping uses a separate connection from the pool
+	err = internalutils.WarmupConnections(ctx, r.warmupConnections, func(ctx context.Context) (func(), error) {
+		_, err := r.client.PingResult(ctx)
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("redis store: error warming up connections: %w", err)
 	}
 
 	if r.replicas, err = r.getConnectedSlaves(ctx); err != nil {
@@ -172,6 +299,17 @@ func (r *StateStore) Features() []state.Feature {
 	return r.features
 }
 
+// SetKeyNormalizer sets the function that normalizes the keys of requests. Implements state.KeyNormalizerSetter.
+func (r *StateStore) SetKeyNormalizer(fn state.KeyNormalizeFn) error {
+	r.keyNormalizer = keynormalizer.FromFunc(fn)
+	if r.shards != nil {
+		for i := range r.shards.shards {
+			_ = r.shards.shards[i].store.SetKeyNormalizer(fn)
+		}
+	}
+	return nil
+}
+
 func (r *StateStore) getConnectedSlaves(ctx context.Context) (int, error) {
 	res, err := r.client.DoRead(ctx, "INFO", "replication")
 	if err != nil {
@@ -203,10 +341,23 @@ func (r *StateStore) parseConnectedSlaves(res string) int {
 
 // Delete performs a delete operation.
 func (r *StateStore) Delete(ctx context.Context, req *state.DeleteRequest) error {
+	req = r.keyNormalizer.DeleteRequest(req)
+	if r.shards != nil {
+		return r.shards.Delete(ctx, req)
+	}
+	if err := r.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	err := state.CheckRequestOptions(req.Options)
 	if err != nil {
 		return err
 	}
+	defer r.invalidateReadCache(req.Key)
+
+	if r.vectorClockWriter != "" {
+		return r.deleteWithVectorClock(ctx, req)
+	}
 
 	if req.ETag == nil {
 		etag := "0"
@@ -268,9 +419,21 @@ func (r *StateStore) getDefault(ctx context.Context, req *state.GetRequest) (*st
 	if err != nil {
 		return nil, err
 	}
+	if r.vectorClockWriter != "" {
+		// Keys written before vector clocks were enabled have no clock
+		version = nil
+		if clock, ok := hashField(vals, "vclock"); ok {
+			version = ptr.Of(clock)
+		}
+	}
+
+	value, err := decodeValue(vals, data)
+	if err != nil {
+		return nil, err
+	}
 
 	return &state.GetResponse{
-		Data: []byte(data),
+		Data: value,
 		ETag: version,
 	}, nil
 }
@@ -314,11 +477,31 @@ func (r *StateStore) getJSON(ctx context.Context, req *state.GetRequest) (*state
 
 // Get retrieves state from redis with a key.
 func (r *StateStore) Get(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
-	if req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType && r.clientHasJSON {
-		return r.getJSON(ctx, req)
+	req = r.keyNormalizer.GetRequest(req)
+	if r.shards != nil {
+		return r.shards.Get(ctx, req)
 	}
+	if err := r.lazyInit.Do(ctx); err != nil {
+		return nil, err
+	}
+
+	fetch, variant := r.getDefault, readCacheVariantDefault
+	// With vector clocks, values are always stored in hashes
+	if req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType && r.clientHasJSON && r.vectorClockWriter == "" {
+		fetch, variant = r.getJSON, readCacheVariantJSON
+	}
+
+	if r.readCache != nil {
+		return r.readCache.get(ctx, req, variant, fetch)
+	}
+	return fetch(ctx, req)
+}
 
-	return r.getDefault(ctx, req)
+// invalidateReadCache removes the keys from the read cache, if enabled.
+func (r *StateStore) invalidateReadCache(keys ...string) {
+	if r.readCache != nil {
+		r.readCache.invalidate(keys...)
+	}
 }
 
 type jsonEntry struct {
@@ -326,16 +509,52 @@ type jsonEntry struct {
 	Version *int        `json:"version,omitempty"`
 }
 
+// SetWithMerge updates a key with the value returned by merge, retrying on ETag conflicts. Implements state.MergeSetter.
+// Keys that don't exist are created only if they still don't exist, so a key created by a regular Set in the meantime is merged again.
+func (r *StateStore) SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge state.MergeFn) error {
+	return r.etagRetrier.SetWithMerge(ctx, key, metadata, merge)
+}
+
+// mergeStore is the store updated by the ETagRetrier of SetWithMerge.
+// First-write concurrency only fails for keys that were also created with first-write, so keys without an ETag are created with a write that fails if the key exists.
+type mergeStore struct {
+	*StateStore
+}
+
+func (m mergeStore) Set(ctx context.Context, req *state.SetRequest) error {
+	createOnly := req.ETag == nil && req.Options.Concurrency == state.FirstWrite
+	return m.set(ctx, req, createOnly)
+}
+
 // Set saves state into redis.
 func (r *StateStore) Set(ctx context.Context, req *state.SetRequest) error {
-	err := state.CheckRequestOptions(req.Options)
-	if err != nil {
+	return r.set(ctx, req, false)
+}
+
+// set saves state into redis; if createOnly is true, the write fails with an ETag mismatch if the key exists.
+func (r *StateStore) set(ctx context.Context, req *state.SetRequest, createOnly bool) error {
+	req = r.keyNormalizer.SetRequest(req)
+	if r.shards != nil {
+		return r.shards.storeFor(req.Key).set(ctx, req, createOnly)
+	}
+	if err := r.lazyInit.Do(ctx); err != nil {
 		return err
 	}
-	ver, err := r.parseETag(req)
+
+	err := state.CheckRequestOptions(req.Options)
 	if err != nil {
 		return err
 	}
+	// Invalidate even if the write fails, as it may have been applied
+	defer r.invalidateReadCache(req.Key)
+
+	var ver int
+	if r.vectorClockWriter == "" {
+		ver, err = r.parseETag(req)
+		if err != nil {
+			return err
+		}
+	}
 	ttl, err := r.parseTTL(req)
 	if err != nil {
 		return fmt.Errorf("failed to parse ttl from metadata: %w", err)
@@ -349,17 +568,30 @@ func (r *StateStore) Set(ctx context.Context, req *state.SetRequest) error {
 	if req.Options.Concurrency == state.FirstWrite {
 		firstWrite = 0
 	}
+	createOnlyArg := 0
+	if createOnly {
+		createOnlyArg = 1
+	}
 
-	if req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType && r.clientHasJSON {
+	if r.vectorClockWriter != "" {
+		err = r.setWithVectorClock(ctx, req, firstWrite)
+		if err != nil {
+			return err
+		}
+	} else if req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType && r.clientHasJSON {
 		bt, _ := utils.Marshal(&jsonEntry{Data: req.Value}, r.json.Marshal)
-		err = r.client.DoWrite(ctx, "EVAL", setJSONQuery, 1, req.Key, ver, bt, firstWrite)
+		err = r.client.DoWrite(ctx, "EVAL", setJSONQuery, 1, req.Key, ver, bt, firstWrite, createOnlyArg)
 	} else {
-		bt, _ := utils.Marshal(req.Value, r.json.Marshal)
-		err = r.client.DoWrite(ctx, "EVAL", setDefaultQuery, 1, req.Key, ver, bt, firstWrite)
+		bt, codecName, encErr := r.encodeValue(req.Value)
+		if encErr != nil {
+			return fmt.Errorf("failed to serialize value of key %s: %w", req.Key, encErr)
+		}
+		err = r.client.DoWrite(ctx, "EVAL", setDefaultQuery, 1, req.Key, ver, bt, firstWrite, codecName, createOnlyArg)
 	}
 
 	if err != nil {
-		if req.ETag != nil {
+		// With first-write concurrency, the scripts fail if the key exists
+		if req.ETag != nil || req.Options.Concurrency == state.FirstWrite {
 			return state.NewETagError(state.ETagMismatch, err)
 		}
 
@@ -392,6 +624,14 @@ func (r *StateStore) Set(ctx context.Context, req *state.SetRequest) error {
 
 // Multi performs a transactional operation. succeeds only if all operations succeed, and fails if one or more operations fail.
 func (r *StateStore) Multi(ctx context.Context, request *state.TransactionalStateRequest) error {
+	request = r.keyNormalizer.TransactionalStateRequest(request)
+	if r.shards != nil {
+		return r.shards.Multi(ctx, request)
+	}
+	if err := r.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+
 	if r.suppressActorStateStoreWarning.CompareAndSwap(false, true) {
 		r.logger.Warn("Redis does not support transaction rollbacks and should not be used in production as an actor state store.")
 	}
@@ -399,57 +639,15 @@ func (r *StateStore) Multi(ctx context.Context, request *state.TransactionalStat
 	// Check if the entire transaction is using JSON based on the transactional request's metadata
 	isJSON := request.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType && r.clientHasJSON
 
-	pipe := r.client.TxPipeline()
-	for _, o := range request.Operations {
-		switch req := o.(type) {
-		case state.SetRequest:
-			ver, err := r.parseETag(&req)
-			if err != nil {
-				return err
-			}
-			ttl, err := r.parseTTL(&req)
-			if err != nil {
-				return fmt.Errorf("failed to parse ttl from metadata: %w", err)
-			}
-			// apply global TTL
-			if ttl == nil {
-				ttl = r.metadata.TTLInSeconds
-			}
-			var bt []byte
-			isReqJSON := isJSON ||
-				(len(req.Metadata) > 0 && req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType)
-			if isReqJSON {
-				bt, _ = utils.Marshal(&jsonEntry{Data: req.Value}, r.json.Marshal)
-				pipe.Do(ctx, "EVAL", setJSONQuery, 1, req.Key, ver, bt)
-			} else {
-				bt, _ = utils.Marshal(req.Value, r.json.Marshal)
-				pipe.Do(ctx, "EVAL", setDefaultQuery, 1, req.Key, ver, bt)
-			}
-			if ttl != nil && *ttl > 0 {
-				pipe.Do(ctx, "EXPIRE", req.Key, *ttl)
-			}
-			if ttl != nil && *ttl <= 0 {
-				pipe.Do(ctx, "PERSIST", req.Key)
-			}
-
-		case state.DeleteRequest:
-			if req.ETag == nil {
-				etag := "0"
-				req.ETag = &etag
-			}
-			isReqJSON := isJSON ||
-				(len(req.Metadata) > 0 && req.Metadata[daprmetadata.ContentType] == contenttype.JSONContentType)
-			if isReqJSON {
-				pipe.Do(ctx, "EVAL", delJSONQuery, 1, req.Key, *req.ETag)
-			} else {
-				pipe.Do(ctx, "EVAL", delDefaultQuery, 1, req.Key, *req.ETag)
-			}
+	if r.readCache != nil {
+		keys := make([]string, len(request.Operations))
+		for i, o := range request.Operations {
+			keys[i] = o.GetKey()
 		}
+		defer r.readCache.invalidate(keys...)
 	}
 
-	err := pipe.Exec(ctx)
-
-	return err
+	return r.execMulti(ctx, request.Operations, isJSON)
 }
 
 func (r *StateStore) registerSchemas(ctx context.Context) error {
@@ -472,6 +670,39 @@ func (r *StateStore) registerSchemas(ctx context.Context) error {
 	return nil
 }
 
+// encodeValue serializes a value stored in a hash, and returns the name of the codec to store alongside it.
+// The name is empty for values stored as JSON, so they remain readable by older versions of the component.
+func (r *StateStore) encodeValue(v any) (data []byte, codecName string, err error) {
+	if statecodec.IsJSON(r.codec) {
+		data, _ = utils.Marshal(v, r.json.Marshal)
+		return data, "", nil
+	}
+
+	data, err = r.codec.Encode(v)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, r.codec.Name(), nil
+}
+
+// decodeValue decodes data with the codec recorded in the hash's "codec" field.
+// Values without the field were stored as JSON.
+func decodeValue(vals []any, data string) ([]byte, error) {
+	var codecName string
+	for i := 0; i+1 < len(vals); i += 2 {
+		field, _ := strconv.Unquote(fmt.Sprintf("%q", vals[i]))
+		if field == "codec" {
+			codecName, _ = strconv.Unquote(fmt.Sprintf("%q", vals[i+1]))
+			break
+		}
+	}
+	if codecName == "" {
+		return []byte(data), nil
+	}
+
+	return statecodec.Decode(codecName, []byte(data))
+}
+
 func (r *StateStore) getKeyVersion(vals []interface{}) (data string, version *string, err error) {
 	seenData := false
 	seenVersion := false
@@ -522,6 +753,13 @@ func (r *StateStore) parseTTL(req *state.SetRequest) (*int, error) {
 
 // Query executes a query against store.
 func (r *StateStore) Query(ctx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
+	if r.shards != nil {
+		return nil, errors.New("redis store: queries are not supported when keys are sharded")
+	}
+	if err := r.lazyInit.Do(ctx); err != nil {
+		return nil, err
+	}
+
 	if !r.clientHasJSON {
 		return nil, errors.New("redis-json server support is required for query capability")
 	}
@@ -550,13 +788,54 @@ func (r *StateStore) Query(ctx context.Context, req *state.QueryRequest) (*state
 	}, nil
 }
 
+// BulkGet performs a Get operation in bulk, fanning out to the shards in sharded mode.
+func (r *StateStore) BulkGet(ctx context.Context, req []state.GetRequest, opts state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	normalized := r.keyNormalizer.GetRequests(req)
+	var (
+		res []state.BulkGetResponse
+		err error
+	)
+	if r.shards != nil {
+		res, err = r.shards.BulkGet(ctx, normalized, opts)
+	} else {
+		res, err = r.BulkStore.BulkGet(ctx, normalized, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The responses have the keys of the requests, before normalization
+	r.keyNormalizer.RestoreKeys(req, res)
+	return res, nil
+}
+
+// BulkSet performs a bulk save operation, fanning out to the shards in sharded mode.
+func (r *StateStore) BulkSet(ctx context.Context, req []state.SetRequest) error {
+	req = r.keyNormalizer.SetRequests(req)
+	if r.shards != nil {
+		return r.shards.BulkSet(ctx, req)
+	}
+	return r.BulkStore.BulkSet(ctx, req)
+}
+
+// BulkDelete performs a bulk delete operation, fanning out to the shards in sharded mode.
+func (r *StateStore) BulkDelete(ctx context.Context, req []state.DeleteRequest) error {
+	req = r.keyNormalizer.DeleteRequests(req)
+	if r.shards != nil {
+		return r.shards.BulkDelete(ctx, req)
+	}
+	return r.BulkStore.BulkDelete(ctx, req)
+}
+
 func (r *StateStore) Close() error {
+	if r.shards != nil {
+		return r.shards.Close()
+	}
 	return r.client.Close()
 }
 
 func (r *StateStore) GetComponentMetadata() map[string]string {
-	metadataStruct := rediscomponent.Settings{}
 	metadataInfo := map[string]string{}
-	daprmetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, daprmetadata.StateStoreType)
+	daprmetadata.GetMetadataInfoFromStructType(reflect.TypeOf(rediscomponent.Settings{}), &metadataInfo, daprmetadata.StateStoreType)
+	daprmetadata.GetMetadataInfoFromStructType(reflect.TypeOf(stateStoreMetadata{}), &metadataInfo, daprmetadata.StateStoreType)
 	return metadataInfo
 }
@@ -23,8 +23,12 @@ import (
 	redis "github.com/go-redis/redis/v8"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/dapr/components-contrib/internal/component/keynormalizer"
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
@@ -251,6 +255,64 @@ func TestTransactionalUpsert(t *testing.T) {
 	assert.Equal(t, int64(-1), res)
 }
 
+func TestSerializer(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+	ss.codec, _ = statecodec.Parse(statecodec.Msgpack)
+
+	t.Run("values are stored with their codec", func(t *testing.T) {
+		err := ss.Set(context.Background(), &state.SetRequest{Key: "weapon", Value: map[string]any{"name": "deathstar"}})
+		require.NoError(t, err)
+
+		codec, err := c.DoRead(context.Background(), "HGET", "weapon", "codec")
+		require.NoError(t, err)
+		assert.Equal(t, statecodec.Msgpack, codec)
+
+		res, err := ss.Get(context.Background(), &state.GetRequest{Key: "weapon"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"deathstar"}`, string(res.Data))
+		assert.Equal(t, ptr.Of("1"), res.ETag)
+	})
+
+	t.Run("transactions store the codec", func(t *testing.T) {
+		err := ss.Multi(context.Background(), &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "weapon2", Value: []byte{0x0a, 0x02, 'x', 'y'}},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := ss.Get(context.Background(), &state.GetRequest{Key: "weapon2"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x0a, 0x02, 'x', 'y'}, res.Data)
+	})
+
+	t.Run("values remain readable after switching to JSON", func(t *testing.T) {
+		ss.codec, _ = statecodec.Parse(statecodec.JSON)
+
+		res, err := ss.Get(context.Background(), &state.GetRequest{Key: "weapon"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"deathstar"}`, string(res.Data))
+
+		err = ss.Set(context.Background(), &state.SetRequest{Key: "weapon", Value: "deathstar"})
+		require.NoError(t, err)
+
+		exists, err := c.DoRead(context.Background(), "HEXISTS", "weapon", "codec")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), exists)
+
+		res, err = ss.Get(context.Background(), &state.GetRequest{Key: "weapon"})
+		require.NoError(t, err)
+		assert.Equal(t, `"deathstar"`, string(res.Data))
+	})
+}
+
 func TestTransactionalDelete(t *testing.T) {
 	s, c := setupMiniredis()
 	defer s.Close()
@@ -302,6 +364,11 @@ func TestPing(t *testing.T) {
 
 	err = ss.Ping(context.Background())
 	assert.Error(t, err)
+
+	t.Run("not initialized", func(t *testing.T) {
+		ss := &StateStore{logger: logger.NewLogger("test")}
+		assert.Error(t, ss.Ping(context.Background()))
+	})
 }
 
 func TestRequestsWithGlobalTTL(t *testing.T) {
@@ -505,6 +572,101 @@ func TestGetMetadata(t *testing.T) {
 	assert.Contains(t, metadataInfo, "idleCheckFrequency")
 	assert.Equal(t, metadataInfo["redisHost"], "string")
 	assert.Equal(t, metadataInfo["idleCheckFrequency"], "redis.Duration")
+	assert.Equal(t, "string", metadataInfo["keyNormalization"])
+	assert.Equal(t, "string", metadataInfo["keyNormalizationSeparators"])
+	assert.NotContains(t, metadataInfo, "KeyNormalizer")
+}
+
+func TestSetWithMerge(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ctx := context.Background()
+	newStore := func(t *testing.T, props map[string]string) *StateStore {
+		ss := &StateStore{
+			client: c,
+			json:   jsoniter.ConfigFastest,
+			logger: logger.NewLogger("test"),
+		}
+		config, err := state.ParseETagRetryConfig(props)
+		require.NoError(t, err)
+		ss.etagRetrier = state.NewETagRetrier(mergeStore{ss}, config)
+		return ss
+	}
+	// increment is a merge function that increments a counter, after writing the key concurrently on the first conflicts invocations
+	increment := func(ss *StateStore, key string, conflicts int) (state.MergeFn, *int) {
+		calls := 0
+		return func(current *state.GetResponse) (any, error) {
+			calls++
+			n := 0
+			if len(current.Data) > 0 {
+				n, _ = strconv.Atoi(string(current.Data))
+			}
+			if calls <= conflicts {
+				err := ss.Set(ctx, &state.SetRequest{Key: key, Value: n + 100})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return n + 1, nil
+		}, &calls
+	}
+	get := func(t *testing.T, ss *StateStore, key string) string {
+		t.Helper()
+		res, err := ss.Get(ctx, &state.GetRequest{Key: key})
+		require.NoError(t, err)
+		return string(res.Data)
+	}
+
+	t.Run("without retries, conflicts fail", func(t *testing.T) {
+		ss := newStore(t, nil)
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "merge-once", Value: 1}))
+		merge, calls := increment(ss, "merge-once", 1)
+		err := ss.SetWithMerge(ctx, "merge-once", nil, merge)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, "101", get(t, ss, "merge-once"))
+	})
+
+	t.Run("conflicts are retried", func(t *testing.T) {
+		ss := newStore(t, map[string]string{
+			"etagRetryMaxRetries": "3",
+			"etagRetryDuration":   "1ms",
+		})
+		require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "merge-retried", Value: 1}))
+		merge, calls := increment(ss, "merge-retried", 2)
+		require.NoError(t, ss.SetWithMerge(ctx, "merge-retried", nil, merge))
+		assert.Equal(t, 3, *calls)
+		assert.Equal(t, "202", get(t, ss, "merge-retried"))
+
+		// The key is created if it doesn't exist
+		merge, calls = increment(ss, "merge-new", 0)
+		require.NoError(t, ss.SetWithMerge(ctx, "merge-new", nil, merge))
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, "1", get(t, ss, "merge-new"))
+	})
+
+	t.Run("keys created in the meantime are not overwritten", func(t *testing.T) {
+		// The key is created by a regular Set after it was read as missing
+		ss := newStore(t, nil)
+		merge, calls := increment(ss, "merge-created", 1)
+		err := ss.SetWithMerge(ctx, "merge-created", nil, merge)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, "100", get(t, ss, "merge-created"))
+
+		ss = newStore(t, map[string]string{
+			"etagRetryMaxRetries": "3",
+			"etagRetryDuration":   "1ms",
+		})
+		merge, calls = increment(ss, "merge-created-retried", 1)
+		require.NoError(t, ss.SetWithMerge(ctx, "merge-created-retried", nil, merge))
+		assert.Equal(t, 2, *calls)
+		assert.Equal(t, "101", get(t, ss, "merge-created-retried"))
+	})
 }
 
 func setupMiniredis() (*miniredis.Miniredis, rediscomponent.RedisClient) {
@@ -519,3 +681,102 @@ func setupMiniredis() (*miniredis.Miniredis, rediscomponent.RedisClient) {
 
 	return s, rediscomponent.ClientFromV8Client(redis.NewClient(opts))
 }
+
+func TestLazyInit(t *testing.T) {
+	// Reserve an address, then stop the server so Redis isn't reachable
+	s := miniredis.RunT(t)
+	addr := s.Addr()
+	s.Close()
+
+	ss := newStateStore(logger.NewLogger("test"))
+	err := ss.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"redisHost":         addr,
+		"redisMaxRetries":   "-1",
+		"lazyInit":          "true",
+		"warmupConnections": "2",
+	}}})
+	require.NoError(t, err)
+	defer ss.Close()
+	require.NotNil(t, ss.lazyInit)
+	assert.Equal(t, 2, ss.warmupConnections)
+
+	_, err = ss.Get(context.Background(), &state.GetRequest{Key: "key"})
+	require.ErrorContains(t, err, "error connecting to redis")
+
+	// Once the server is reachable, the initialization is attempted again (miniredis doesn't support INFO replication)
+	s = miniredis.NewMiniRedis()
+	require.NoError(t, s.StartAddr(addr))
+	defer s.Close()
+	err = ss.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "error connecting to redis")
+	assert.Greater(t, s.TotalConnectionCount(), 0)
+}
+
+func TestWarmupConnectionsMetadata(t *testing.T) {
+	ss := newStateStore(logger.NewLogger("test"))
+	err := ss.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"redisHost":         "localhost:6379",
+		"warmupConnections": "-1",
+	}}})
+	require.ErrorContains(t, err, "warmupConnections")
+}
+
+func TestInitWithMissingSecret(t *testing.T) {
+	ss := newStateStore(logger.NewLogger("test"))
+	err := ss.Init(context.Background(), state.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"redisHost":     "localhost:6379",
+			"redisPassword": "secretref://vault/redis#password",
+		},
+	}})
+	require.ErrorContains(t, err, "metadata property 'redisPassword' references a secret")
+}
+
+func TestKeyNormalization(t *testing.T) {
+	ss := newStateStore(logger.NewLogger("test"))
+	err := ss.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"redisHost":        "localhost:6379",
+		"keyNormalization": "uppercase",
+	}}})
+	require.ErrorContains(t, err, "keyNormalization")
+
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss = &StateStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+	ss.BulkStore = state.NewDefaultBulkStore(ss)
+	ss.keyNormalizer, err = keynormalizer.New(keynormalizer.Metadata{KeyNormalization: "lowercase,replaceSeparators"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: "myapp||Order_1", Value: "a"}))
+	assert.True(t, s.Exists("myapp||order-1"))
+
+	res, err := ss.Get(ctx, &state.GetRequest{Key: "myapp||ORDER.1"})
+	require.NoError(t, err)
+	assert.Equal(t, `"a"`, string(res.Data))
+
+	bulk, err := ss.BulkGet(ctx, []state.GetRequest{{Key: "myapp||order:1"}, {Key: "myapp||Order/1"}}, state.BulkGetOpts{})
+	require.NoError(t, err)
+	require.Len(t, bulk, 2)
+	keys := []string{bulk[0].Key, bulk[1].Key}
+	assert.ElementsMatch(t, []string{"myapp||order:1", "myapp||Order/1"}, keys)
+	assert.Equal(t, `"a"`, string(bulk[0].Data))
+	assert.Equal(t, `"a"`, string(bulk[1].Data))
+
+	err = ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+		state.SetRequest{Key: "myapp||ORDER_2", Value: "b"},
+		state.DeleteRequest{Key: "myapp||Order_1"},
+	}})
+	require.NoError(t, err)
+	assert.True(t, s.Exists("myapp||order-2"))
+	assert.False(t, s.Exists("myapp||order-1"))
+
+	require.NoError(t, ss.Delete(ctx, &state.DeleteRequest{Key: "myapp||order_2"}))
+	assert.False(t, s.Exists("myapp||order-2"))
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Metadata property with the list of Redis servers to shard keys across, as "host:port" or "name=host:port" entries separated by commas
+	shardsKey = "redisShards"
+	// Metadata property with the number of points of each shard on the hash ring
+	shardVirtualNodesKey = "redisShardVirtualNodes"
+
+	defaultShardVirtualNodes = 160
+)
+
+// shard is a Redis server that stores a portion of the keys.
+type shard struct {
+	// The name identifies the shard on the hash ring, so a shard can be moved to another address without moving its keys
+	name  string
+	host  string
+	store *StateStore
+}
+
+// hashRing maps keys to shards with consistent hashing.
+// Each shard owns many points on the ring, so adding or removing a shard only moves the keys of the points it gains or loses.
+type hashRing struct {
+	points []uint64
+	owners map[uint64]int
+}
+
+func newHashRing(names []string, virtualNodes int) *hashRing {
+	r := &hashRing{
+		points: make([]uint64, 0, len(names)*virtualNodes),
+		owners: make(map[uint64]int, len(names)*virtualNodes),
+	}
+	for i, name := range names {
+		for v := 0; v < virtualNodes; v++ {
+			h := xxhash.Sum64String(name + "#" + strconv.Itoa(v))
+			// In the unlikely case of a collision, the point belongs to the shard whose name sorts first, so the result doesn't depend on the order of the shards
+			if o, ok := r.owners[h]; ok {
+				if names[o] < name {
+					continue
+				}
+			} else {
+				r.points = append(r.points, h)
+			}
+			r.owners[h] = i
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// locate returns the index of the shard that owns the key.
+func (r *hashRing) locate(key string) int {
+	h := xxhash.Sum64String(shardHashKey(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// shardHashKey returns the part of the key that is hashed.
+// As with Redis Cluster, if the key contains a non-empty hash tag in braces, only the tag is hashed, so related keys can be stored in the same shard and used in the same transaction.
+func shardHashKey(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// parseShards parses the list of shards in the metadata property.
+func parseShards(val string) ([]shard, error) {
+	var shards []shard
+	names := map[string]struct{}{}
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		s := shard{name: entry, host: entry}
+		if name, host, ok := strings.Cut(entry, "="); ok {
+			s.name = strings.TrimSpace(name)
+			s.host = strings.TrimSpace(host)
+		}
+		if s.name == "" || s.host == "" {
+			return nil, fmt.Errorf("redis store: invalid shard '%s' in '%s'", entry, shardsKey)
+		}
+		if _, ok := names[s.name]; ok {
+			return nil, fmt.Errorf("redis store: duplicate shard '%s' in '%s'", s.name, shardsKey)
+		}
+		names[s.name] = struct{}{}
+		shards = append(shards, s)
+	}
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("redis store: no shards in '%s'", shardsKey)
+	}
+	return shards, nil
+}
+
+// shardedStore distributes keys across multiple Redis servers, each managed by its own StateStore.
+type shardedStore struct {
+	shards []shard
+	ring   *hashRing
+}
+
+// newShardedStore creates and initializes a StateStore for each shard in the metadata, with the same options except for the host.
+func newShardedStore(ctx context.Context, metadata state.Metadata, log logger.Logger) (*shardedStore, error) {
+	shards, err := parseShards(metadata.Properties[shardsKey])
+	if err != nil {
+		return nil, err
+	}
+	virtualNodes := defaultShardVirtualNodes
+	if val := metadata.Properties[shardVirtualNodesKey]; val != "" {
+		virtualNodes, err = strconv.Atoi(val)
+		if err != nil || virtualNodes <= 0 {
+			return nil, fmt.Errorf("redis store: invalid value for '%s': must be a positive integer", shardVirtualNodesKey)
+		}
+	}
+
+	s := &shardedStore{shards: shards}
+	names := make([]string, len(shards))
+	for i := range shards {
+		names[i] = shards[i].name
+
+		props := make(map[string]string, len(metadata.Properties))
+		for k, v := range metadata.Properties {
+			props[k] = v
+		}
+		delete(props, shardsKey)
+		delete(props, shardVirtualNodesKey)
+		props["redisHost"] = shards[i].host
+
+		md := metadata
+		md.Properties = props
+		store := newStateStore(log)
+		store.BulkStore = state.NewDefaultBulkStore(store)
+		err = store.Init(ctx, md)
+		if err != nil {
+			// Close the shards that were initialized
+			shards[i].store = store
+			s.shards = shards[:i+1]
+			s.Close()
+			return nil, fmt.Errorf("redis store: failed to initialize shard '%s': %w", shards[i].name, err)
+		}
+		shards[i].store = store
+	}
+	s.ring = newHashRing(names, virtualNodes)
+
+	return s, nil
+}
+
+// storeFor returns the store of the shard that owns the key.
+func (s *shardedStore) storeFor(key string) *StateStore {
+	return s.shards[s.ring.locate(key)].store
+}
+
+// groupByShard returns the indexes of the keys grouped by the shard that owns them, preserving their order.
+func (s *shardedStore) groupByShard(n int, key func(i int) string) map[int][]int {
+	groups := map[int][]int{}
+	for i := 0; i < n; i++ {
+		idx := s.ring.locate(key(i))
+		groups[idx] = append(groups[idx], i)
+	}
+	return groups
+}
+
+// forEachGroup invokes fn concurrently for each group of keys and returns the errors, joined.
+func (s *shardedStore) forEachGroup(groups map[int][]int, fn func(store *StateStore, idx []int) error) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	for shardIdx, idx := range groups {
+		wg.Add(1)
+		go func(sh shard, idx []int) {
+			defer wg.Done()
+			err := fn(sh.store, idx)
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("shard '%s': %w", sh.name, err))
+				lock.Unlock()
+			}
+		}(s.shards[shardIdx], idx)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *shardedStore) Ping(ctx context.Context) error {
+	errs := make([]error, 0, len(s.shards))
+	for _, sh := range s.shards {
+		errs = append(errs, sh.store.Ping(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *shardedStore) Get(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	return s.storeFor(req.Key).Get(ctx, req)
+}
+
+func (s *shardedStore) Set(ctx context.Context, req *state.SetRequest) error {
+	return s.storeFor(req.Key).Set(ctx, req)
+}
+
+func (s *shardedStore) Delete(ctx context.Context, req *state.DeleteRequest) error {
+	return s.storeFor(req.Key).Delete(ctx, req)
+}
+
+func (s *shardedStore) Touch(ctx context.Context, req *state.TouchRequest) error {
+	return s.storeFor(req.Key).Touch(ctx, req)
+}
+
+// Multi executes the transaction on the shard that owns its keys.
+// Transactions can't span multiple shards; use hash tags to store related keys in the same shard.
+func (s *shardedStore) Multi(ctx context.Context, req *state.TransactionalStateRequest) error {
+	if len(req.Operations) == 0 {
+		return nil
+	}
+	groups := s.groupByShard(len(req.Operations), func(i int) string { return req.Operations[i].GetKey() })
+	if len(groups) > 1 {
+		return errors.New("redis store: the keys of the transaction are stored in different shards")
+	}
+	return s.storeFor(req.Operations[0].GetKey()).Multi(ctx, req)
+}
+
+// BulkGet reads the keys from their shards concurrently, and returns the responses in the order of the requests.
+// If a shard fails, the error is reported in the responses of its keys.
+func (s *shardedStore) BulkGet(ctx context.Context, req []state.GetRequest, opts state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	res := state.NewBulkGetResponses(req)
+	groups := s.groupByShard(len(req), func(i int) string { return req[i].Key })
+	_ = s.forEachGroup(groups, func(store *StateStore, idx []int) error {
+		shardReq := make([]state.GetRequest, len(idx))
+		for j, i := range idx {
+			shardReq[j] = req[i]
+		}
+		shardRes, err := store.BulkGet(ctx, shardReq, opts)
+		if err != nil {
+			for _, i := range idx {
+				res[i].Error = err.Error()
+			}
+			return nil
+		}
+		for j, i := range idx {
+			res[i] = shardRes[j]
+		}
+		return nil
+	})
+	return res, nil
+}
+
+// BulkSet saves the keys in their shards concurrently; the operations are atomic within each shard only.
+func (s *shardedStore) BulkSet(ctx context.Context, req []state.SetRequest) error {
+	groups := s.groupByShard(len(req), func(i int) string { return req[i].Key })
+	return s.forEachGroup(groups, func(store *StateStore, idx []int) error {
+		shardReq := make([]state.SetRequest, len(idx))
+		for j, i := range idx {
+			shardReq[j] = req[i]
+		}
+		return store.BulkSet(ctx, shardReq)
+	})
+}
+
+// BulkDelete deletes the keys from their shards concurrently; the operations are atomic within each shard only.
+func (s *shardedStore) BulkDelete(ctx context.Context, req []state.DeleteRequest) error {
+	groups := s.groupByShard(len(req), func(i int) string { return req[i].Key })
+	return s.forEachGroup(groups, func(store *StateStore, idx []int) error {
+		shardReq := make([]state.DeleteRequest, len(idx))
+		for j, i := range idx {
+			shardReq[j] = req[i]
+		}
+		return store.BulkDelete(ctx, shardReq)
+	})
+}
+
+// ListKeys lists the keys of the shards one after the other.
+// The continuation token is the index of the shard followed by the token of the shard, as "index:token".
+func (s *shardedStore) ListKeys(ctx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		idx        int
+		shardToken string
+	)
+	if req.ContinuationToken != "" {
+		var (
+			idxStr string
+			ok     bool
+		)
+		idxStr, shardToken, ok = strings.Cut(req.ContinuationToken, ":")
+		idx, err = strconv.Atoi(idxStr)
+		if !ok || err != nil || idx < 0 || idx >= len(s.shards) {
+			return nil, fmt.Errorf("redis store: invalid continuation token '%s'", req.ContinuationToken)
+		}
+	}
+
+	res := &state.ListKeysResponse{
+		Keys: []string{},
+	}
+	for ; idx < len(s.shards); idx++ {
+		shardRes, err := s.shards[idx].store.ListKeys(ctx, &state.ListKeysRequest{
+			Prefix:            req.Prefix,
+			Limit:             req.Limit - len(res.Keys),
+			ContinuationToken: shardToken,
+			Metadata:          req.Metadata,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("shard '%s': %w", s.shards[idx].name, err)
+		}
+		res.Keys = append(res.Keys, shardRes.Keys...)
+		shardToken = shardRes.ContinuationToken
+
+		if len(res.Keys) >= req.Limit {
+			switch {
+			case shardToken != "":
+				res.ContinuationToken = strconv.Itoa(idx) + ":" + shardToken
+			case idx+1 < len(s.shards):
+				res.ContinuationToken = strconv.Itoa(idx+1) + ":"
+			}
+			return res, nil
+		}
+	}
+	return res, nil
+}
+
+func (s *shardedStore) Close() error {
+	errs := make([]error, 0, len(s.shards))
+	for _, sh := range s.shards {
+		if sh.store != nil && sh.store.client != nil {
+			errs = append(errs, sh.store.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseShards(t *testing.T) {
+	shards, err := parseShards("host1:6379, b=host2:6379 ,")
+	require.NoError(t, err)
+	require.Len(t, shards, 2)
+	assert.Equal(t, "host1:6379", shards[0].name)
+	assert.Equal(t, "host1:6379", shards[0].host)
+	assert.Equal(t, "b", shards[1].name)
+	assert.Equal(t, "host2:6379", shards[1].host)
+
+	_, err = parseShards(" , ")
+	require.ErrorContains(t, err, "no shards")
+	_, err = parseShards("a=host1:6379,a=host2:6379")
+	require.ErrorContains(t, err, "duplicate shard 'a'")
+	_, err = parseShards("=host1:6379")
+	require.ErrorContains(t, err, "invalid shard")
+}
+
+func TestShardHashKey(t *testing.T) {
+	assert.Equal(t, "app||key", shardHashKey("app||key"))
+	assert.Equal(t, "user1", shardHashKey("app||{user1}.profile"))
+	assert.Equal(t, "app||{}key", shardHashKey("app||{}key"))
+	assert.Equal(t, "app||{key", shardHashKey("app||{key"))
+}
+
+func TestHashRing(t *testing.T) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = "app||key" + strconv.Itoa(i)
+	}
+
+	ring := newHashRing([]string{"a", "b", "c"}, defaultShardVirtualNodes)
+	counts := make([]int, 3)
+	owners := make([]string, len(keys))
+	for i, k := range keys {
+		idx := ring.locate(k)
+		counts[idx]++
+		owners[i] = []string{"a", "b", "c"}[idx]
+	}
+	for _, c := range counts {
+		// Each shard owns roughly a third of the keys
+		assert.InDelta(t, len(keys)/3, c, float64(len(keys))/10)
+	}
+
+	t.Run("the order of the shards doesn't matter", func(t *testing.T) {
+		names := []string{"c", "a", "b"}
+		reordered := newHashRing(names, defaultShardVirtualNodes)
+		for i, k := range keys {
+			assert.Equal(t, owners[i], names[reordered.locate(k)])
+		}
+	})
+
+	t.Run("adding a shard only moves keys to the new shard", func(t *testing.T) {
+		names := []string{"a", "b", "c", "d"}
+		grown := newHashRing(names, defaultShardVirtualNodes)
+		moved := 0
+		for i, k := range keys {
+			owner := names[grown.locate(k)]
+			if owner != owners[i] {
+				assert.Equal(t, "d", owner)
+				moved++
+			}
+		}
+		assert.InDelta(t, len(keys)/4, moved, float64(len(keys))/10)
+	})
+
+	t.Run("removing a shard only moves its keys", func(t *testing.T) {
+		names := []string{"a", "c"}
+		shrunk := newHashRing(names, defaultShardVirtualNodes)
+		for i, k := range keys {
+			if owners[i] != "b" {
+				assert.Equal(t, owners[i], names[shrunk.locate(k)])
+			}
+		}
+	})
+}
+
+func newTestShardedStore(t *testing.T, n int) (*StateStore, []*StateStore) {
+	t.Helper()
+
+	shards := make([]shard, n)
+	names := make([]string, n)
+	stores := make([]*StateStore, n)
+	for i := range shards {
+		s, c := setupMiniredis()
+		t.Cleanup(s.Close)
+		stores[i] = &StateStore{
+			client: c,
+			json:   jsoniter.ConfigFastest,
+			logger: logger.NewLogger("test"),
+		}
+		stores[i].BulkStore = state.NewDefaultBulkStore(stores[i])
+		names[i] = "shard" + strconv.Itoa(i)
+		shards[i] = shard{name: names[i], host: s.Addr(), store: stores[i]}
+	}
+
+	ss := newStateStore(logger.NewLogger("test"))
+	ss.shards = &shardedStore{
+		shards: shards,
+		ring:   newHashRing(names, defaultShardVirtualNodes),
+	}
+	return ss, stores
+}
+
+func TestShardedStore(t *testing.T) {
+	ctx := context.Background()
+	ss, stores := newTestShardedStore(t, 3)
+
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = "app||key" + strconv.Itoa(i)
+	}
+
+	t.Run("keys are stored in their shard", func(t *testing.T) {
+		for _, k := range keys {
+			require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: k, Value: k}))
+		}
+		used := map[int]struct{}{}
+		for _, k := range keys {
+			idx := ss.shards.ring.locate(k)
+			used[idx] = struct{}{}
+			for i, store := range stores {
+				res, err := store.Get(ctx, &state.GetRequest{Key: k})
+				require.NoError(t, err)
+				if i == idx {
+					assert.Equal(t, `"`+k+`"`, string(res.Data))
+				} else {
+					assert.Nil(t, res.Data)
+				}
+			}
+		}
+		assert.Len(t, used, 3)
+	})
+
+	t.Run("bulk get preserves the order of the requests", func(t *testing.T) {
+		req := make([]state.GetRequest, len(keys)+1)
+		for i, k := range keys {
+			req[i] = state.GetRequest{Key: k}
+		}
+		req[len(keys)] = state.GetRequest{Key: "app||missing"}
+
+		res, err := ss.BulkGet(ctx, req, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, len(req))
+		for i, k := range keys {
+			assert.Equal(t, k, res[i].Key)
+			assert.Equal(t, `"`+k+`"`, string(res[i].Data))
+			assert.Equal(t, "1", *res[i].ETag)
+		}
+		assert.Equal(t, "app||missing", res[len(keys)].Key)
+		assert.Nil(t, res[len(keys)].Data)
+	})
+
+	t.Run("bulk set and bulk delete", func(t *testing.T) {
+		setReq := make([]state.SetRequest, len(keys))
+		for i, k := range keys {
+			setReq[i] = state.SetRequest{Key: k, Value: "updated"}
+		}
+		require.NoError(t, ss.BulkSet(ctx, setReq))
+		for _, k := range keys {
+			res, err := ss.Get(ctx, &state.GetRequest{Key: k})
+			require.NoError(t, err)
+			assert.Equal(t, `"updated"`, string(res.Data))
+		}
+
+		delReq := make([]state.DeleteRequest, len(keys))
+		for i, k := range keys {
+			delReq[i] = state.DeleteRequest{Key: k}
+		}
+		require.NoError(t, ss.BulkDelete(ctx, delReq))
+		for _, k := range keys {
+			res, err := ss.Get(ctx, &state.GetRequest{Key: k})
+			require.NoError(t, err)
+			assert.Nil(t, res.Data)
+		}
+	})
+
+	t.Run("transactions must not span shards", func(t *testing.T) {
+		ops := make([]state.TransactionalStateOperation, len(keys))
+		for i, k := range keys {
+			ops[i] = state.SetRequest{Key: k, Value: "v"}
+		}
+		err := ss.Multi(ctx, &state.TransactionalStateRequest{Operations: ops})
+		require.ErrorContains(t, err, "different shards")
+
+		// Keys with the same hash tag are stored in the same shard
+		err = ss.Multi(ctx, &state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "app||{user1}.a", Value: "a"},
+			state.SetRequest{Key: "app||{user1}.b", Value: "b"},
+			state.DeleteRequest{Key: "app||{user1}.c"},
+		}})
+		require.NoError(t, err)
+		res, err := ss.Get(ctx, &state.GetRequest{Key: "app||{user1}.b"})
+		require.NoError(t, err)
+		assert.Equal(t, `"b"`, string(res.Data))
+	})
+
+	t.Run("queries are not supported", func(t *testing.T) {
+		_, err := ss.Query(ctx, &state.QueryRequest{})
+		require.ErrorContains(t, err, "sharded")
+	})
+}
+
+func TestShardedStoreInitErrors(t *testing.T) {
+	ss := newStateStore(logger.NewLogger("test"))
+	err := ss.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"redisShards":            "host1:6379,host2:6379",
+		"redisShardVirtualNodes": "0",
+	}}})
+	require.ErrorContains(t, err, "redisShardVirtualNodes")
+
+	// Shards are initialized with the same options, and the errors are reported with the name of the shard
+	err = ss.Init(context.Background(), state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"redisShards":       "a=host1:6379",
+		"warmupConnections": "-1",
+	}}})
+	require.ErrorContains(t, err, "shard 'a'")
+	require.ErrorContains(t, err, "warmupConnections")
+}
+
+func TestShardedStoreBulkGetPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	ss, stores := newTestShardedStore(t, 2)
+
+	// Find a key for each shard
+	keys := make([]string, 2)
+	for i := 0; keys[0] == "" || keys[1] == ""; i++ {
+		k := "app||key" + strconv.Itoa(i)
+		keys[ss.shards.ring.locate(k)] = k
+	}
+	require.NoError(t, ss.Set(ctx, &state.SetRequest{Key: keys[0], Value: "v0"}))
+	require.NoError(t, stores[1].client.Close())
+
+	res, err := ss.BulkGet(ctx, []state.GetRequest{{Key: keys[1]}, {Key: keys[0]}}, state.BulkGetOpts{})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, keys[1], res[0].Key)
+	assert.NotEmpty(t, res[0].Error)
+	assert.Equal(t, keys[0], res[1].Key)
+	assert.Equal(t, `"v0"`, string(res[1].Data))
+	assert.Empty(t, res[1].Error)
+}
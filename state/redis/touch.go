@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// Errors returned by touchQuery when the key doesn't exist or doesn't have a TTL.
+const (
+	touchNotFoundReply = "TOUCH_NOT_FOUND"
+	touchNoTTLReply    = "TOUCH_NO_TTL"
+)
+
+// touchQuery resets the TTL of a key in milliseconds, only if it has one.
+// It returns an error without changing the key if PTTL reports that the key doesn't exist (-2) or has no TTL (-1).
+const touchQuery = `local ttl = redis.call("PTTL", KEYS[1]);
+	if ttl == -2 then return redis.error_reply("` + touchNotFoundReply + `") end;
+	if ttl == -1 then return redis.error_reply("` + touchNoTTLReply + `") end;
+	redis.call("PEXPIRE", KEYS[1], ARGV[1]); return 1`
+
+// Touch resets the TTL of a key with PEXPIRE, without transferring its value.
+// The TTL is checked and reset in a script, so a key that doesn't have a TTL is never given one.
+func (r *StateStore) Touch(ctx context.Context, req *state.TouchRequest) error {
+	req = r.keyNormalizer.TouchRequest(req)
+	if r.shards != nil {
+		return r.shards.Touch(ctx, req)
+	}
+	if err := r.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+	// Cached responses must not outlive a shortened TTL
+	defer r.invalidateReadCache(req.Key)
+
+	err = r.client.DoWrite(ctx, "EVAL", touchQuery, 1, req.Key, req.TTL.Milliseconds())
+	switch {
+	case err == nil:
+		return nil
+	case strings.Contains(err.Error(), touchNotFoundReply):
+		return fmt.Errorf("%w: %s", state.ErrTouchKeyNotFound, req.Key)
+	case strings.Contains(err.Error(), touchNoTTLReply):
+		return fmt.Errorf("%w: %s", state.ErrTouchNoTTL, req.Key)
+	default:
+		return fmt.Errorf("failed to reset the TTL of key %s: %w", req.Key, err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestTouch(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+	var _ state.Toucher = ss
+
+	require.NoError(t, ss.Set(context.Background(), &state.SetRequest{Key: "session", Value: "v", Metadata: map[string]string{"ttlInSeconds": "10"}}))
+	require.NoError(t, ss.Set(context.Background(), &state.SetRequest{Key: "persistent", Value: "v"}))
+
+	t.Run("ttl reset", func(t *testing.T) {
+		s.FastForward(5 * time.Second)
+		require.NoError(t, ss.Touch(context.Background(), &state.TouchRequest{Key: "session", TTL: time.Minute}))
+		assert.Equal(t, time.Minute, s.TTL("session"))
+
+		res, err := ss.Get(context.Background(), &state.GetRequest{Key: "session"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v"`, string(res.Data))
+	})
+
+	t.Run("key without ttl", func(t *testing.T) {
+		err := ss.Touch(context.Background(), &state.TouchRequest{Key: "persistent", TTL: time.Minute})
+		require.ErrorIs(t, err, state.ErrTouchNoTTL)
+		// The key isn't given a TTL
+		assert.Zero(t, s.TTL("persistent"))
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		err := ss.Touch(context.Background(), &state.TouchRequest{Key: "missing", TTL: time.Minute})
+		require.ErrorIs(t, err, state.ErrTouchKeyNotFound)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		require.Error(t, ss.Touch(context.Background(), &state.TouchRequest{Key: "session"}))
+	})
+}
+
+func TestTouchInvalidatesReadCache(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	cache, err := newReadCache(map[string]string{"localCacheTTL": "1m"})
+	require.NoError(t, err)
+	ss := &StateStore{
+		client:    c,
+		json:      jsoniter.ConfigFastest,
+		logger:    logger.NewLogger("test"),
+		readCache: cache,
+	}
+
+	require.NoError(t, ss.Set(context.Background(), &state.SetRequest{Key: "session", Value: "v", Metadata: map[string]string{"ttlInSeconds": "60"}}))
+	res, err := ss.Get(context.Background(), &state.GetRequest{Key: "session"})
+	require.NoError(t, err)
+	assert.Equal(t, `"v"`, string(res.Data))
+
+	// After the shortened TTL, the key isn't served from the cache
+	require.NoError(t, ss.Touch(context.Background(), &state.TouchRequest{Key: "session", TTL: time.Second}))
+	s.FastForward(2 * time.Second)
+	res, err = ss.Get(context.Background(), &state.GetRequest{Key: "session"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+)
+
+const (
+	concurrencyModeETag        = "etag"
+	concurrencyModeVectorClock = "vectorClock"
+
+	// Prefix of the errors returned by the scripts when a write is concurrent with the current version of the key
+	vectorClockConflictPrefix = "CONFLICT "
+
+	// Functions shared by the scripts that use vector clocks.
+	// Clocks are stored in the "vclock" field of the hash as "writer:counter" entries, sorted by writer and separated by commas.
+	vectorClockFunctions = `
+	local function parseClock(s)
+	  local c = {};
+	  if s then
+	    for w, n in string.gmatch(s, "([^,:]+):(%d+)") do
+	      c[w] = tonumber(n);
+	    end;
+	  end;
+	  return c;
+	end;
+	local function compareClocks(current, expected)
+	  local descends = true;
+	  for w, n in pairs(current) do
+	    if n > (expected[w] or 0) then descends = false end;
+	  end;
+	  if descends then return "ok" end;
+	  for w, n in pairs(expected) do
+	    if n > (current[w] or 0) then return "concurrent" end;
+	  end;
+	  return "stale";
+	end;
+	local clock = redis.pcall("HGET", KEYS[1], "vclock");
+	if type(clock) == "table" then
+	  clock = false;
+	end;
+	local current = parseClock(clock);
+	local expected = parseClock(ARGV[1]);
+	if ARGV[1] ~= "" then
+	  local res = compareClocks(current, expected);
+	  if res == "concurrent" then
+	    return redis.error_reply("` + vectorClockConflictPrefix + `" .. clock .. " " .. KEYS[1]);
+	  elseif res == "stale" then
+	    return redis.error_reply("failed to update key " .. KEYS[1]);
+	  end;
+	end;`
+	setVectorClockQuery = vectorClockFunctions + `
+	if ARGV[1] == "" and ARGV[3] == "0" and redis.call("EXISTS", KEYS[1]) == 1 then
+	  return redis.error_reply("failed to set key " .. KEYS[1]);
+	end;
+	if type(redis.pcall("HGET", KEYS[1], "version")) == "table" then
+	  redis.call("DEL", KEYS[1]);
+	end;
+	for w, n in pairs(expected) do
+	  if n > (current[w] or 0) then current[w] = n end;
+	end;
+	current[ARGV[5]] = (current[ARGV[5]] or 0) + 1;
+	local writers = {};
+	for w in pairs(current) do
+	  table.insert(writers, w);
+	end;
+	table.sort(writers);
+	local entries = {};
+	for i, w in ipairs(writers) do
+	  entries[i] = w .. ":" .. current[w];
+	end;
+	clock = table.concat(entries, ",");
+	redis.call("HSET", KEYS[1], "data", ARGV[2], "vclock", clock);
+	if ARGV[4] and ARGV[4] ~= "" then
+	  redis.call("HSET", KEYS[1], "codec", ARGV[4]);
+	else
+	  redis.call("HDEL", KEYS[1], "codec");
+	end;
+	redis.call("HINCRBY", KEYS[1], "version", 1);
+	return clock`
+	delVectorClockQuery = vectorClockFunctions + `
+	return redis.call("DEL", KEYS[1])`
+)
+
+// writerIDRegex matches the IDs of writers, which can't contain the separators of the entries of clocks.
+var writerIDRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// vectorClockMetadata contains the options of the concurrency control with vector clocks.
+type vectorClockMetadata struct {
+	// "etag" (the default) for single-version ETags, or "vectorClock" to track the versions of each writer
+	ConcurrencyMode string `mapstructure:"concurrencyMode"`
+	// ID of the writer (such as the region) in vector clocks, which must be unique among the writers of the same keys
+	WriterID string `mapstructure:"writerID"`
+}
+
+// parseVectorClockWriter returns the ID of the writer if vector clocks are enabled in the metadata properties, or an empty string otherwise.
+func parseVectorClockWriter(props map[string]string) (string, error) {
+	m := vectorClockMetadata{}
+	err := metadata.DecodeMetadata(props, &m)
+	if err != nil {
+		return "", fmt.Errorf("redis store: error parsing concurrency options: %w", err)
+	}
+
+	switch m.ConcurrencyMode {
+	case "", concurrencyModeETag:
+		return "", nil
+	case concurrencyModeVectorClock:
+		if !writerIDRegex.MatchString(m.WriterID) {
+			return "", errors.New("redis store: 'writerID' is required with vector clocks, and can contain only letters, digits, '_', '.' and '-'")
+		}
+		return m.WriterID, nil
+	default:
+		return "", fmt.Errorf("redis store: invalid value for 'concurrencyMode': '%s' is not one of 'etag', 'vectorClock'", m.ConcurrencyMode)
+	}
+}
+
+// VectorClockConflictError is returned, wrapped in a state.ETagError, when a write is concurrent with the current version of the key: neither the version the write is based on, nor the current version, include all the updates of the other.
+// Callers can resolve the conflict by merging the values, and writing the result with the current version as the ETag.
+type VectorClockConflictError struct {
+	Key string
+	// Vector clock of the current version of the key
+	Current string
+}
+
+func (e *VectorClockConflictError) Error() string {
+	return fmt.Sprintf("concurrent update of key '%s': the current version is '%s'", e.Key, e.Current)
+}
+
+// vectorClockError returns a state.ETagError with a VectorClockConflictError if err is the error of a script reporting a concurrent update, or nil otherwise.
+func vectorClockError(err error) error {
+	_, msg, ok := strings.Cut(err.Error(), vectorClockConflictPrefix)
+	if !ok {
+		return nil
+	}
+	clock, key, _ := strings.Cut(msg, " ")
+	return state.NewETagError(state.ETagMismatch, &VectorClockConflictError{
+		Key:     key,
+		Current: clock,
+	})
+}
+
+// vectorClockETag returns the clock that a write is conditioned on, or an empty string for unconditional writes.
+func vectorClockETag(etag *string, concurrency string) (string, error) {
+	if concurrency == state.LastWrite || etag == nil {
+		return "", nil
+	}
+	// Validate the clock, so invalid ETags aren't reported as mismatches
+	_, err := MergeVectorClocks(*etag, "")
+	if err != nil {
+		return "", err
+	}
+	return *etag, nil
+}
+
+// vectorClockSetArgs returns the arguments of the command that stores the value of a set request, incrementing the counter of this writer in the clock of the key.
+// If the request has an ETag, the write fails unless the clock in the ETag includes all the updates of the current clock.
+func (r *StateStore) vectorClockSetArgs(req *state.SetRequest, firstWrite int) ([]any, error) {
+	etag, err := vectorClockETag(req.ETag, req.Options.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	bt, codecName, err := r.encodeValue(req.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize value of key %s: %w", req.Key, err)
+	}
+	return []any{"EVAL", setVectorClockQuery, 1, req.Key, etag, bt, firstWrite, codecName, r.vectorClockWriter}, nil
+}
+
+// setWithVectorClock stores the value of a set request, when vector clocks are enabled.
+func (r *StateStore) setWithVectorClock(ctx context.Context, req *state.SetRequest, firstWrite int) error {
+	args, err := r.vectorClockSetArgs(req, firstWrite)
+	if err != nil {
+		return err
+	}
+	err = r.client.DoWrite(ctx, args...)
+	if err == nil {
+		return nil
+	}
+	if conflictErr := vectorClockError(err); conflictErr != nil {
+		return conflictErr
+	}
+	if req.ETag != nil {
+		return state.NewETagError(state.ETagMismatch, err)
+	}
+	return fmt.Errorf("failed to set key %s: %w", req.Key, err)
+}
+
+// deleteWithVectorClock deletes the key of a delete request, when vector clocks are enabled.
+func (r *StateStore) deleteWithVectorClock(ctx context.Context, req *state.DeleteRequest) error {
+	etag, err := vectorClockETag(req.ETag, req.Options.Concurrency)
+	if err != nil {
+		return err
+	}
+	err = r.client.DoWrite(ctx, "EVAL", delVectorClockQuery, 1, req.Key, etag)
+	if err == nil {
+		return nil
+	}
+	if conflictErr := vectorClockError(err); conflictErr != nil {
+		return conflictErr
+	}
+	return state.NewETagError(state.ETagMismatch, err)
+}
+
+// hashField returns the value of a field in the result of HGETALL.
+func hashField(vals []any, name string) (string, bool) {
+	for i := 0; i+1 < len(vals); i += 2 {
+		field, _ := strconv.Unquote(fmt.Sprintf("%q", vals[i]))
+		if field == name {
+			val, _ := strconv.Unquote(fmt.Sprintf("%q", vals[i+1]))
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// MergeVectorClocks returns the clock that includes the updates of both clocks, with the highest counter of each writer.
+func MergeVectorClocks(a string, b string) (string, error) {
+	merged := map[string]uint64{}
+	for _, clock := range []string{a, b} {
+		counters, err := parseVectorClock(clock)
+		if err != nil {
+			return "", err
+		}
+		for writer, n := range counters {
+			if n > merged[writer] {
+				merged[writer] = n
+			}
+		}
+	}
+
+	writers := make([]string, 0, len(merged))
+	for w := range merged {
+		writers = append(writers, w)
+	}
+	sort.Strings(writers)
+	entries := make([]string, len(writers))
+	for i, w := range writers {
+		entries[i] = w + ":" + strconv.FormatUint(merged[w], 10)
+	}
+	return strings.Join(entries, ","), nil
+}
+
+// parseVectorClock returns the counters of the writers in a clock.
+func parseVectorClock(clock string) (map[string]uint64, error) {
+	counters := map[string]uint64{}
+	if clock == "" {
+		return counters, nil
+	}
+	for _, entry := range strings.Split(clock, ",") {
+		writer, counter, ok := strings.Cut(entry, ":")
+		n, err := strconv.ParseUint(counter, 10, 64)
+		if !ok || err != nil || !writerIDRegex.MatchString(writer) {
+			return nil, state.NewETagError(state.ETagInvalid, fmt.Errorf("invalid vector clock entry '%s'", entry))
+		}
+		if n > counters[writer] {
+			counters[writer] = n
+		}
+	}
+	return counters, nil
+}
+
+// compareVectorClocks returns "ok" if the expected clock includes all the updates of the current clock, "concurrent" if neither clock includes all the updates of the other, or "stale" otherwise, like the scripts.
+func compareVectorClocks(current string, expected string) (string, error) {
+	cur, err := parseVectorClock(current)
+	if err != nil {
+		return "", err
+	}
+	exp, err := parseVectorClock(expected)
+	if err != nil {
+		return "", err
+	}
+	descends := true
+	for w, n := range cur {
+		if n > exp[w] {
+			descends = false
+		}
+	}
+	if descends {
+		return "ok", nil
+	}
+	for w, n := range exp {
+		if n > cur[w] {
+			return "concurrent", nil
+		}
+	}
+	return "stale", nil
+}
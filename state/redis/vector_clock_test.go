@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestParseVectorClockWriter(t *testing.T) {
+	writer, err := parseVectorClockWriter(map[string]string{})
+	require.NoError(t, err)
+	assert.Empty(t, writer)
+
+	writer, err = parseVectorClockWriter(map[string]string{"concurrencyMode": "etag", "writerID": "us-east"})
+	require.NoError(t, err)
+	assert.Empty(t, writer)
+
+	writer, err = parseVectorClockWriter(map[string]string{"concurrencyMode": "vectorClock", "writerID": "us-east"})
+	require.NoError(t, err)
+	assert.Equal(t, "us-east", writer)
+
+	for _, props := range []map[string]string{
+		{"concurrencyMode": "vectorClock"},
+		{"concurrencyMode": "vectorClock", "writerID": "us:east"},
+		{"concurrencyMode": "vectorClock", "writerID": "us,east"},
+		{"concurrencyMode": "lamport", "writerID": "us-east"},
+	} {
+		_, err = parseVectorClockWriter(props)
+		require.Error(t, err, props)
+	}
+}
+
+func TestMergeVectorClocks(t *testing.T) {
+	merged, err := MergeVectorClocks("eu:1,us:3", "ap:2,eu:4")
+	require.NoError(t, err)
+	assert.Equal(t, "ap:2,eu:4,us:3", merged)
+
+	merged, err = MergeVectorClocks("", "us:1")
+	require.NoError(t, err)
+	assert.Equal(t, "us:1", merged)
+
+	for _, clock := range []string{"3", "us:", "us:-1", ":1", "us:1,"} {
+		_, err = MergeVectorClocks(clock, "")
+		require.Error(t, err, clock)
+	}
+}
+
+func TestCompareVectorClocks(t *testing.T) {
+	for _, tc := range []struct {
+		current  string
+		expected string
+		res      string
+	}{
+		{"eu:1,us:2", "eu:1,us:2", "ok"},
+		{"us:1", "eu:1,us:1", "ok"},
+		{"", "us:1", "ok"},
+		{"eu:1,us:2", "us:2", "stale"},
+		{"eu:1,us:2", "us:3", "concurrent"},
+	} {
+		res, err := compareVectorClocks(tc.current, tc.expected)
+		require.NoError(t, err)
+		assert.Equal(t, tc.res, res, tc)
+	}
+
+	_, err := compareVectorClocks("us:1", "us")
+	require.Error(t, err)
+}
+
+func TestVectorClocks(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	newStore := func(writer string) *StateStore {
+		return &StateStore{
+			client:            c,
+			json:              jsoniter.ConfigFastest,
+			logger:            logger.NewLogger("test"),
+			vectorClockWriter: writer,
+		}
+	}
+	us := newStore("us")
+	eu := newStore("eu")
+	ctx := context.Background()
+
+	get := func(t *testing.T, key string) *state.GetResponse {
+		t.Helper()
+		res, err := us.Get(ctx, &state.GetRequest{Key: key})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("writes without ETag increment the counter of the writer", func(t *testing.T) {
+		require.NoError(t, us.Set(ctx, &state.SetRequest{Key: "k1", Value: "a"}))
+		require.NoError(t, us.Set(ctx, &state.SetRequest{Key: "k1", Value: "b"}))
+		require.NoError(t, eu.Set(ctx, &state.SetRequest{Key: "k1", Value: "c"}))
+
+		res := get(t, "k1")
+		assert.Equal(t, `"c"`, string(res.Data))
+		assert.Equal(t, ptr.Of("eu:1,us:2"), res.ETag)
+	})
+
+	t.Run("write based on the current version", func(t *testing.T) {
+		require.NoError(t, us.Set(ctx, &state.SetRequest{Key: "k2", Value: "a"}))
+		require.NoError(t, eu.Set(ctx, &state.SetRequest{Key: "k2", Value: "b", ETag: ptr.Of("us:1")}))
+		assert.Equal(t, ptr.Of("eu:1,us:1"), get(t, "k2").ETag)
+	})
+
+	t.Run("stale write is an ETag mismatch", func(t *testing.T) {
+		err := us.Set(ctx, &state.SetRequest{Key: "k2", Value: "c", ETag: ptr.Of("us:1")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		var conflictErr *VectorClockConflictError
+		assert.False(t, errors.As(err, &conflictErr))
+	})
+
+	t.Run("concurrent write is a conflict that can be resolved", func(t *testing.T) {
+		// The version of "us" includes an update that isn't in the current version
+		err := us.Set(ctx, &state.SetRequest{Key: "k2", Value: "c", ETag: ptr.Of("us:2")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		var conflictErr *VectorClockConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "k2", conflictErr.Key)
+		assert.Equal(t, "eu:1,us:1", conflictErr.Current)
+		assert.Equal(t, `"b"`, string(get(t, "k2").Data))
+
+		merged, err := MergeVectorClocks("us:2", conflictErr.Current)
+		require.NoError(t, err)
+		require.NoError(t, us.Set(ctx, &state.SetRequest{Key: "k2", Value: "bc", ETag: &merged}))
+		res := get(t, "k2")
+		assert.Equal(t, `"bc"`, string(res.Data))
+		assert.Equal(t, ptr.Of("eu:1,us:3"), res.ETag)
+	})
+
+	t.Run("invalid ETag", func(t *testing.T) {
+		err := us.Set(ctx, &state.SetRequest{Key: "k2", Value: "d", ETag: ptr.Of("3")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagInvalid, etagErr.Kind())
+	})
+
+	t.Run("first write", func(t *testing.T) {
+		req := &state.SetRequest{Key: "k3", Value: "a", Options: state.SetStateOption{Concurrency: state.FirstWrite}}
+		require.NoError(t, us.Set(ctx, req))
+		require.Error(t, eu.Set(ctx, req))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := eu.Delete(ctx, &state.DeleteRequest{Key: "k2", ETag: ptr.Of("eu:1,us:1")})
+		require.Error(t, err)
+		err = eu.Delete(ctx, &state.DeleteRequest{Key: "k2", ETag: ptr.Of("eu:2,us:1")})
+		var conflictErr *VectorClockConflictError
+		require.ErrorAs(t, err, &conflictErr)
+
+		require.NoError(t, eu.Delete(ctx, &state.DeleteRequest{Key: "k2", ETag: ptr.Of("eu:1,us:3")}))
+		assert.Nil(t, get(t, "k2").Data)
+		require.NoError(t, eu.Delete(ctx, &state.DeleteRequest{Key: "k1"}))
+		assert.Nil(t, get(t, "k1").Data)
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		require.NoError(t, us.Set(ctx, &state.SetRequest{Key: "k4", Value: "a"}))
+		err := eu.Multi(ctx, &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "k4", Value: "b", ETag: ptr.Of("us:1")},
+				state.DeleteRequest{Key: "k3"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ptr.Of("eu:1,us:1"), get(t, "k4").ETag)
+		assert.Nil(t, get(t, "k3").Data)
+
+		err = us.Multi(ctx, &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "k4", Value: "c", ETag: ptr.Of("us:2")},
+			},
+		})
+		var conflictErr *VectorClockConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "eu:1,us:1", conflictErr.Current)
+	})
+
+	t.Run("keys written without vector clocks", func(t *testing.T) {
+		require.NoError(t, newStore("").Set(ctx, &state.SetRequest{Key: "k5", Value: "a"}))
+		res := get(t, "k5")
+		assert.Equal(t, `"a"`, string(res.Data))
+		assert.Nil(t, res.ETag)
+
+		require.NoError(t, us.Set(ctx, &state.SetRequest{Key: "k5", Value: "b"}))
+		assert.Equal(t, ptr.Of("us:1"), get(t, "k5").ETag)
+	})
+}
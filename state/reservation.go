@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrKeyReserved is returned by Reserve when a key is reserved by another reservation that hasn't expired.
+	// Other write operations on a reserved key fail with an ETag mismatch that wraps it.
+	ErrKeyReserved = errors.New("key is reserved by another reservation")
+	// ErrReservationNotFound is returned by CommitReservation when the reservation doesn't exist or has expired.
+	ErrReservationNotFound = errors.New("reservation not found or expired")
+	// ErrReservationCommitted is returned by RollbackReservation when the reservation was already committed.
+	ErrReservationCommitted = errors.New("reservation was already committed")
+)
+
+const (
+	// ReservationStatusReserved is the status of a reservation whose operations are staged.
+	ReservationStatusReserved = "reserved"
+	// ReservationStatusCommitted is the status of a reservation whose operations were applied.
+	ReservationStatusCommitted = "committed"
+)
+
+// Reserver is implemented by state stores that can stage changes to a set of keys and apply them atomically later, so an orchestrator can coordinate sagas and two-phase commits across resources.
+type Reserver interface {
+	// Reserve stages the operations of the request, and reserves their keys until the reservation is committed, rolled back, or expires.
+	// It fails with ErrKeyReserved if any key is reserved by another reservation; reserving again the same keys with the same ID is a no-op.
+	// Until the reservation ends, Set, Delete, Multi and the bulk operations on a reserved key fail with an ETag mismatch wrapping ErrKeyReserved.
+	// To detect changes made before the keys were reserved, stage the operations with the ETags read before reserving, so the commit fails with an ETag mismatch.
+	Reserve(ctx context.Context, req *ReserveRequest) error
+	// CommitReservation applies the staged operations in a single transaction, including their ETag checks.
+	// If an operation fails, no change is applied and the reservation is kept, so it can be rolled back.
+	// Committing a reservation that was already committed is a no-op, as long as it hasn't expired.
+	// The metadata must match the metadata of the ReserveRequest for stores that use it to locate the reservation, such as the tenant.
+	CommitReservation(ctx context.Context, id string, metadata map[string]string) error
+	// RollbackReservation discards the staged operations and releases the keys.
+	// Rolling back a reservation that doesn't exist or has expired is a no-op.
+	RollbackReservation(ctx context.Context, id string, metadata map[string]string) error
+}
+
+// ReserveRequest is the request to stage operations in a reservation.
+type ReserveRequest struct {
+	// ID of the reservation, chosen by the caller
+	ID string
+	// The reservation expires after TTL if it's not committed or rolled back, releasing the keys
+	TTL time.Duration
+	// Operations to stage, either SetRequest or DeleteRequest; each key can appear only once
+	Operations []TransactionalStateOperation
+	// Metadata of the reservation, such as the tenant
+	Metadata map[string]string
+}
+
+// Validate checks that the request is well-formed.
+func (r *ReserveRequest) Validate() error {
+	if r.ID == "" {
+		return errors.New("missing reservation ID")
+	}
+	if r.TTL <= 0 {
+		return errors.New("the TTL of the reservation must be positive")
+	}
+	if len(r.Operations) == 0 {
+		return errors.New("the reservation must contain at least one operation")
+	}
+
+	keys := make(map[string]struct{}, len(r.Operations))
+	for _, o := range r.Operations {
+		switch o.(type) {
+		case SetRequest, DeleteRequest:
+		default:
+			return fmt.Errorf("unsupported operation: %s", o.Operation())
+		}
+		key := o.GetKey()
+		if key == "" {
+			return errors.New("missing key in reserved operation")
+		}
+		if _, ok := keys[key]; ok {
+			return fmt.Errorf("key '%s' appears more than once in the reservation", key)
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+// stagedOperation is the serialized form of an operation in a reservation.
+type stagedOperation struct {
+	Operation   OperationType     `json:"operation"`
+	Key         string            `json:"key"`
+	Value       json.RawMessage   `json:"value,omitempty"`
+	BinaryValue []byte            `json:"binaryValue,omitempty"`
+	ETag        *string           `json:"etag,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Concurrency string            `json:"concurrency,omitempty"`
+}
+
+// MarshalStagedOperation serializes an operation of a reservation, so it can be stored until the reservation is committed.
+// Values of SetRequest operations are stored as JSON, unless they're byte slices.
+func MarshalStagedOperation(o TransactionalStateOperation) ([]byte, error) {
+	var so stagedOperation
+	switch req := o.(type) {
+	case SetRequest:
+		so = stagedOperation{
+			Operation:   OperationUpsert,
+			Key:         req.Key,
+			ETag:        req.ETag,
+			Metadata:    req.Metadata,
+			Concurrency: req.Options.Concurrency,
+		}
+		if b, ok := req.Value.([]byte); ok {
+			so.BinaryValue = b
+		} else {
+			v, err := json.Marshal(req.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to serialize the value of key '%s': %w", req.Key, err)
+			}
+			so.Value = v
+		}
+	case DeleteRequest:
+		so = stagedOperation{
+			Operation:   OperationDelete,
+			Key:         req.Key,
+			ETag:        req.ETag,
+			Metadata:    req.Metadata,
+			Concurrency: req.Options.Concurrency,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", o.Operation())
+	}
+	return json.Marshal(so)
+}
+
+// UnmarshalStagedOperation returns the operation serialized by MarshalStagedOperation.
+// The value of a SetRequest is a json.RawMessage, or a byte slice for binary values.
+func UnmarshalStagedOperation(data []byte) (TransactionalStateOperation, error) {
+	var so stagedOperation
+	err := json.Unmarshal(data, &so)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse staged operation: %w", err)
+	}
+
+	switch so.Operation {
+	case OperationUpsert:
+		req := SetRequest{
+			Key:      so.Key,
+			ETag:     so.ETag,
+			Metadata: so.Metadata,
+			Options:  SetStateOption{Concurrency: so.Concurrency},
+		}
+		if so.BinaryValue != nil {
+			req.Value = so.BinaryValue
+		} else {
+			req.Value = so.Value
+		}
+		return req, nil
+	case OperationDelete:
+		return DeleteRequest{
+			Key:      so.Key,
+			ETag:     so.ETag,
+			Metadata: so.Metadata,
+			Options:  DeleteStateOption{Concurrency: so.Concurrency},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported staged operation: %s", so.Operation)
+	}
+}
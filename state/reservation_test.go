@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/ptr"
+)
+
+func TestReserveRequestValidate(t *testing.T) {
+	valid := func() *ReserveRequest {
+		return &ReserveRequest{
+			ID:  "saga1",
+			TTL: time.Minute,
+			Operations: []TransactionalStateOperation{
+				SetRequest{Key: "k1", Value: "v"},
+				DeleteRequest{Key: "k2"},
+			},
+		}
+	}
+
+	require.NoError(t, valid().Validate())
+
+	tests := map[string]func(r *ReserveRequest){
+		"missing ID":    func(r *ReserveRequest) { r.ID = "" },
+		"missing TTL":   func(r *ReserveRequest) { r.TTL = 0 },
+		"no operations": func(r *ReserveRequest) { r.Operations = nil },
+		"missing key":   func(r *ReserveRequest) { r.Operations[1] = DeleteRequest{} },
+		"duplicate key": func(r *ReserveRequest) { r.Operations[1] = DeleteRequest{Key: "k1"} },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := valid()
+			mutate(r)
+			require.Error(t, r.Validate())
+		})
+	}
+}
+
+func TestStagedOperation(t *testing.T) {
+	t.Run("set with JSON value", func(t *testing.T) {
+		data, err := MarshalStagedOperation(SetRequest{
+			Key:      "k1",
+			Value:    map[string]any{"n": 1},
+			ETag:     ptr.Of("3"),
+			Metadata: map[string]string{"ttlInSeconds": "10"},
+			Options:  SetStateOption{Concurrency: FirstWrite},
+		})
+		require.NoError(t, err)
+
+		o, err := UnmarshalStagedOperation(data)
+		require.NoError(t, err)
+		req, ok := o.(SetRequest)
+		require.True(t, ok)
+		assert.Equal(t, "k1", req.Key)
+		assert.Equal(t, json.RawMessage(`{"n":1}`), req.Value)
+		assert.Equal(t, "3", *req.ETag)
+		assert.Equal(t, "10", req.Metadata["ttlInSeconds"])
+		assert.Equal(t, FirstWrite, req.Options.Concurrency)
+	})
+
+	t.Run("set with binary value", func(t *testing.T) {
+		data, err := MarshalStagedOperation(SetRequest{Key: "k1", Value: []byte{0, 1, 2}})
+		require.NoError(t, err)
+
+		o, err := UnmarshalStagedOperation(data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 1, 2}, o.(SetRequest).Value)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		data, err := MarshalStagedOperation(DeleteRequest{Key: "k2", ETag: ptr.Of("4")})
+		require.NoError(t, err)
+
+		o, err := UnmarshalStagedOperation(data)
+		require.NoError(t, err)
+		req, ok := o.(DeleteRequest)
+		require.True(t, ok)
+		assert.Equal(t, "k2", req.Key)
+		assert.Equal(t, "4", *req.ETag)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := UnmarshalStagedOperation([]byte(`{"operation":"merge","key":"k1"}`))
+		require.Error(t, err)
+	})
+}
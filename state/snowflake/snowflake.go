@@ -0,0 +1,524 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	// Registers the "snowflake" database/sql driver
+	_ "github.com/snowflakedb/gosnowflake"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+// Optimistic concurrency is implemented with a version column, which is incremented on every write and used as ETag.
+// Values are stored in a VARIANT column, so JSON values can be queried with Snowflake's semi-structured data functions.
+
+const (
+	// Name of the database/sql driver, registered by the Snowflake Go driver (github.com/snowflakedb/gosnowflake).
+	driverName = "snowflake"
+
+	// Used if the user does not configure a table name in the metadata.
+	defaultTableName = "dapr_state"
+
+	// Used if the user does not provide a timeoutInSeconds value in the metadata.
+	// Snowflake has a high latency compared to OLTP databases, so the default is higher than in other SQL stores.
+	defaultTimeoutInSeconds = 60
+
+	// Parameters of the connection string that keep the session alive.
+	keepAliveParam          = "client_session_keep_alive"
+	keepAliveHeartbeatParam = "client_session_keep_alive_heartbeat_frequency"
+
+	errMissingConnectionString = "missing connection string"
+)
+
+// Snowflake state store.
+type Snowflake struct {
+	tableName        string
+	connectionString string
+	timeout          time.Duration
+
+	db     *sql.DB
+	logger logger.Logger
+
+	// Allows replacing the database in tests
+	open func(connectionString string) (*sql.DB, error)
+}
+
+type snowflakeMetadata struct {
+	// Connection string of the Snowflake Go driver, such as "user:password@account/database/schema?warehouse=wh".
+	ConnectionString string `mapstructure:"connectionString"`
+	// Name of the table where the data is stored. It can be qualified with the database and schema names.
+	TableName string `mapstructure:"tableName"`
+	// Timeout, in seconds, of each operation.
+	TimeoutInSeconds int `mapstructure:"timeoutInSeconds"`
+	// Keeps the Snowflake session alive, so idle connections in the pool don't expire.
+	KeepAlive bool `mapstructure:"keepAlive"`
+	// Interval between heartbeats that keep the session alive.
+	KeepAliveInterval time.Duration `mapstructure:"keepAliveInterval"`
+	// Maximum number of idle connections kept in the pool.
+	MaxIdleConnections int `mapstructure:"maxIdleConnections"`
+}
+
+// NewSnowflakeStateStore creates a new instance of the Snowflake state store.
+func NewSnowflakeStateStore(logger logger.Logger) state.Store {
+	return newSnowflakeStateStore(logger)
+}
+
+func newSnowflakeStateStore(logger logger.Logger) *Snowflake {
+	return &Snowflake{
+		logger: logger,
+		open: func(connectionString string) (*sql.DB, error) {
+			return sql.Open(driverName, connectionString)
+		},
+	}
+}
+
+// Init parses the metadata, connects to Snowflake, and creates the state table if it doesn't exist.
+func (s *Snowflake) Init(ctx context.Context, md state.Metadata) error {
+	meta, err := s.parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	db, err := s.open(s.connectionString)
+	if err != nil {
+		return fmt.Errorf("failed to open the Snowflake database: %w", err)
+	}
+	s.db = db
+	if meta.MaxIdleConnections > 0 {
+		s.db.SetMaxIdleConns(meta.MaxIdleConnections)
+	}
+
+	err = s.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Snowflake: %w", err)
+	}
+
+	return s.ensureStateTable(ctx)
+}
+
+func (s *Snowflake) parseMetadata(md map[string]string) (snowflakeMetadata, error) {
+	meta := snowflakeMetadata{
+		TableName:        defaultTableName,
+		TimeoutInSeconds: defaultTimeoutInSeconds,
+		KeepAlive:        true,
+	}
+	err := metadata.DecodeMetadata(md, &meta)
+	if err != nil {
+		return meta, err
+	}
+
+	if meta.ConnectionString == "" {
+		return meta, errors.New(errMissingConnectionString)
+	}
+	if !validIdentifier(meta.TableName) {
+		return meta, fmt.Errorf("table name '%s' is not valid", meta.TableName)
+	}
+	if meta.TimeoutInSeconds <= 0 {
+		return meta, errors.New("invalid value for 'timeoutInSeconds': must be greater than 0")
+	}
+	if meta.KeepAliveInterval < 0 {
+		return meta, errors.New("invalid value for 'keepAliveInterval': must not be negative")
+	}
+	if meta.MaxIdleConnections < 0 {
+		return meta, errors.New("invalid value for 'maxIdleConnections': must not be negative")
+	}
+
+	s.tableName = meta.TableName
+	s.timeout = time.Duration(meta.TimeoutInSeconds) * time.Second
+	s.connectionString = meta.ConnectionString
+	if meta.KeepAlive {
+		s.connectionString = withParam(s.connectionString, keepAliveParam, "true")
+		if meta.KeepAliveInterval > 0 {
+			s.connectionString = withParam(s.connectionString, keepAliveHeartbeatParam, strconv.FormatInt(int64(meta.KeepAliveInterval/time.Second), 10))
+		}
+	}
+
+	return meta, nil
+}
+
+// withParam adds a parameter to the connection string, unless it's already set.
+func withParam(connectionString string, name string, value string) string {
+	idx := strings.IndexByte(connectionString, '?')
+	if idx < 0 {
+		return connectionString + "?" + name + "=" + value
+	}
+	for _, p := range strings.Split(connectionString[idx+1:], "&") {
+		if strings.EqualFold(strings.SplitN(p, "=", 2)[0], name) {
+			return connectionString
+		}
+	}
+	return connectionString + "&" + name + "=" + value
+}
+
+func (s *Snowflake) ensureStateTable(parentCtx context.Context) error {
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+
+	// Note that tableName is sanitized
+	//nolint:gosec
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.tableName+` (
+		id VARCHAR NOT NULL PRIMARY KEY,
+		value VARIANT,
+		isbinary BOOLEAN NOT NULL,
+		version NUMBER(38, 0) NOT NULL,
+		updatedate TIMESTAMP_LTZ NOT NULL DEFAULT CURRENT_TIMESTAMP()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create state table '%s': %w", s.tableName, err)
+	}
+	return nil
+}
+
+// Features returns the features available in this state store.
+func (s *Snowflake) Features() []state.Feature {
+	return []state.Feature{state.FeatureETag, state.FeatureTransactional}
+}
+
+// Ping the database.
+func (s *Snowflake) Ping(parentCtx context.Context) error {
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	return s.db.PingContext(ctx)
+}
+
+// Get returns an entity from store.
+func (s *Snowflake) Get(parentCtx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	if req.Key == "" {
+		return nil, errors.New("missing key in get operation")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	row := s.db.QueryRowContext(ctx, `SELECT id, TO_JSON(value), version, isbinary FROM `+s.tableName+` WHERE id = ?`, req.Key)
+	_, value, etag, err := readRow(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &state.GetResponse{}, nil
+		}
+		return nil, err
+	}
+	return &state.GetResponse{
+		Data:     value,
+		ETag:     &etag,
+		Metadata: req.Metadata,
+	}, nil
+}
+
+// BulkGet returns multiple entities from the store with a single query.
+// Keys that don't exist are returned without data.
+func (s *Snowflake) BulkGet(parentCtx context.Context, req []state.GetRequest, _ state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	if len(req) == 0 {
+		return []state.BulkGetResponse{}, nil
+	}
+
+	inClause := strings.Repeat("?,", len(req))
+	inClause = inClause[:len(inClause)-1]
+	params := make([]any, len(req))
+	for i, r := range req {
+		params[i] = r.Key
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT id, TO_JSON(value), version, isbinary FROM `+s.tableName+` WHERE id IN (`+inClause+`)`, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]state.BulkGetResponse, len(req))
+	for rows.Next() {
+		key, value, etag, err := readRow(rows)
+		r := state.BulkGetResponse{Key: key}
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.Data = value
+			r.ETag = &etag
+		}
+		found[key] = r
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]state.BulkGetResponse, len(req))
+	for i, r := range req {
+		if f, ok := found[r.Key]; ok {
+			res[i] = f
+		} else {
+			res[i] = state.BulkGetResponse{Key: r.Key}
+		}
+		res[i].Metadata = r.Metadata
+	}
+	return res, nil
+}
+
+func readRow(row interface{ Scan(dest ...any) error }) (key string, value []byte, etag string, err error) {
+	var (
+		version  int64
+		isBinary bool
+		data     sql.NullString
+	)
+	err = row.Scan(&key, &data, &version, &isBinary)
+	if err != nil {
+		return key, nil, "", err
+	}
+	etag = strconv.FormatInt(version, 10)
+	if !data.Valid {
+		return key, nil, etag, nil
+	}
+
+	if isBinary {
+		var s string
+		err = json.Unmarshal([]byte(data.String), &s)
+		if err != nil {
+			return key, nil, "", fmt.Errorf("failed to unmarshal JSON binary data: %w", err)
+		}
+		value, err = base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return key, nil, "", fmt.Errorf("failed to decode binary data: %w", err)
+		}
+		return key, value, etag, nil
+	}
+
+	return key, []byte(data.String), etag, nil
+}
+
+// Set adds or updates an entity in the store.
+func (s *Snowflake) Set(ctx context.Context, req *state.SetRequest) error {
+	return s.setValue(ctx, s.db, req)
+}
+
+func (s *Snowflake) setValue(parentCtx context.Context, q querier, req *state.SetRequest) error {
+	err := state.CheckRequestOptions(req.Options)
+	if err != nil {
+		return err
+	}
+	if req.Key == "" {
+		return errors.New("missing key in set operation")
+	}
+
+	var v any
+	isBinary := false
+	switch x := req.Value.(type) {
+	case []byte:
+		isBinary = true
+		v = base64.StdEncoding.EncodeToString(x)
+	default:
+		v = x
+	}
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	// Values are inserted with SELECT, as Snowflake doesn't allow functions such as PARSE_JSON in a VALUES clause
+	var (
+		query  string
+		params []any
+	)
+	hasETag := req.ETag != nil && *req.ETag != ""
+	switch {
+	case hasETag:
+		version, err := strconv.ParseInt(*req.ETag, 10, 64)
+		if err != nil {
+			return state.NewETagError(state.ETagInvalid, err)
+		}
+		query = `UPDATE ` + s.tableName + `
+			SET value = PARSE_JSON(?), isbinary = ?, version = version + 1, updatedate = CURRENT_TIMESTAMP()
+			WHERE id = ? AND version = ?`
+		params = []any{string(enc), isBinary, req.Key, version}
+	case req.Options.Concurrency == state.FirstWrite:
+		query = `INSERT INTO ` + s.tableName + ` (id, value, isbinary, version)
+			SELECT ?, PARSE_JSON(?), ?, 1
+			WHERE NOT EXISTS (SELECT 1 FROM ` + s.tableName + ` WHERE id = ?)`
+		params = []any{req.Key, string(enc), isBinary, req.Key}
+	default:
+		query = `MERGE INTO ` + s.tableName + ` t
+			USING (SELECT ? AS id, PARSE_JSON(?) AS value, ? AS isbinary) s
+			ON t.id = s.id
+			WHEN MATCHED THEN UPDATE SET value = s.value, isbinary = s.isbinary, version = t.version + 1, updatedate = CURRENT_TIMESTAMP()
+			WHEN NOT MATCHED THEN INSERT (id, value, isbinary, version) VALUES (s.id, s.value, s.isbinary, 1)`
+		params = []any{req.Key, string(enc), isBinary}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+	result, err := q.ExecContext(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+
+	if hasETag || req.Options.Concurrency == state.FirstWrite {
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return state.NewETagError(state.ETagMismatch, nil)
+		}
+	}
+	return nil
+}
+
+// Delete removes an entity from the store.
+func (s *Snowflake) Delete(ctx context.Context, req *state.DeleteRequest) error {
+	return s.deleteValue(ctx, s.db, req)
+}
+
+func (s *Snowflake) deleteValue(parentCtx context.Context, q querier, req *state.DeleteRequest) error {
+	if req.Key == "" {
+		return errors.New("missing key in delete operation")
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.timeout)
+	defer cancel()
+
+	if req.ETag == nil || *req.ETag == "" {
+		_, err := q.ExecContext(ctx, `DELETE FROM `+s.tableName+` WHERE id = ?`, req.Key)
+		return err
+	}
+
+	version, err := strconv.ParseInt(*req.ETag, 10, 64)
+	if err != nil {
+		return state.NewETagError(state.ETagInvalid, err)
+	}
+	result, err := q.ExecContext(ctx, `DELETE FROM `+s.tableName+` WHERE id = ? AND version = ?`, req.Key, version)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return state.NewETagError(state.ETagMismatch, nil)
+	}
+	return nil
+}
+
+// BulkSet adds or updates multiple entities in the store, in a transaction.
+func (s *Snowflake) BulkSet(ctx context.Context, req []state.SetRequest) error {
+	ops := make([]state.TransactionalStateOperation, len(req))
+	for i, r := range req {
+		ops[i] = r
+	}
+	return s.Multi(ctx, &state.TransactionalStateRequest{Operations: ops})
+}
+
+// BulkDelete removes multiple entities from the store, in a transaction.
+func (s *Snowflake) BulkDelete(ctx context.Context, req []state.DeleteRequest) error {
+	ops := make([]state.TransactionalStateOperation, len(req))
+	for i, r := range req {
+		ops[i] = r
+	}
+	return s.Multi(ctx, &state.TransactionalStateRequest{Operations: ops})
+}
+
+// Multi handles multiple operations in a transaction.
+func (s *Snowflake) Multi(parentCtx context.Context, request *state.TransactionalStateRequest) error {
+	tx, err := s.db.BeginTx(parentCtx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			s.logger.Errorf("Error rolling back transaction: %v", rollbackErr)
+		}
+	}()
+
+	for _, o := range request.Operations {
+		switch req := o.(type) {
+		case state.SetRequest:
+			err = s.setValue(parentCtx, tx, &req)
+		case state.DeleteRequest:
+			err = s.deleteValue(parentCtx, tx, &req)
+		default:
+			err = fmt.Errorf("unsupported operation: %s", req.Operation())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Close implements io.Closer.
+func (s *Snowflake) Close() error {
+	if s.db == nil {
+		return nil
+	}
+
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (s *Snowflake) GetComponentMetadata() map[string]string {
+	metadataStruct := snowflakeMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.StateStoreType)
+	return metadataInfo
+}
+
+// validIdentifier validates a table name, optionally qualified with the database and schema names.
+// This is based on the rules for unquoted identifiers (https://docs.snowflake.com/en/sql-reference/identifiers-syntax), which start with a letter or an underscore.
+func validIdentifier(v string) bool {
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || (p[0] >= '0' && p[0] <= '9') || p[0] == '$' {
+			return false
+		}
+		for i := 0; i < len(p); i++ {
+			if (p[i] >= '0' && p[i] <= '9') ||
+				(p[i] >= 'a' && p[i] <= 'z') ||
+				(p[i] >= 'A' && p[i] <= 'Z') ||
+				p[i] == '_' || p[i] == '$' {
+				continue
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// Interface for both sql.DB and sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
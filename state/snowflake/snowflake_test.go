@@ -0,0 +1,288 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+const testConnectionString = "user:password@account/db/schema?warehouse=wh"
+
+func mockStore(t *testing.T) (*Snowflake, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	s := newSnowflakeStateStore(logger.NewLogger("test"))
+	s.open = func(string) (*sql.DB, error) {
+		return db, nil
+	}
+	return s, mock
+}
+
+func initStore(t *testing.T) (*Snowflake, sqlmock.Sqlmock) {
+	t.Helper()
+
+	s, mock := mockStore(t)
+	mock.ExpectPing()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS dapr_state").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, s.Init(context.Background(), state.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"connectionString": testConnectionString},
+	}}))
+	return s, mock
+}
+
+func TestDefaultOpen(t *testing.T) {
+	// sql.Open doesn't connect, but fails if the driver isn't registered or the DSN is invalid
+	s := newSnowflakeStateStore(logger.NewLogger("test"))
+	db, err := s.open(testConnectionString)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s := newSnowflakeStateStore(logger.NewLogger("test"))
+		_, err := s.parseMetadata(map[string]string{"connectionString": testConnectionString})
+		require.NoError(t, err)
+		assert.Equal(t, defaultTableName, s.tableName)
+		assert.Equal(t, 60*time.Second, s.timeout)
+		assert.Equal(t, testConnectionString+"&client_session_keep_alive=true", s.connectionString)
+	})
+
+	t.Run("custom values", func(t *testing.T) {
+		s := newSnowflakeStateStore(logger.NewLogger("test"))
+		meta, err := s.parseMetadata(map[string]string{
+			"connectionString":   "user:password@account/db/schema",
+			"tableName":          "db.schema.state",
+			"timeoutInSeconds":   "5",
+			"keepAliveInterval":  "30m",
+			"maxIdleConnections": "4",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "db.schema.state", s.tableName)
+		assert.Equal(t, 5*time.Second, s.timeout)
+		assert.Equal(t, 4, meta.MaxIdleConnections)
+		assert.Equal(t, "user:password@account/db/schema?client_session_keep_alive=true&client_session_keep_alive_heartbeat_frequency=1800", s.connectionString)
+	})
+
+	t.Run("keep-alive disabled or already set", func(t *testing.T) {
+		s := newSnowflakeStateStore(logger.NewLogger("test"))
+		_, err := s.parseMetadata(map[string]string{"connectionString": testConnectionString, "keepAlive": "false"})
+		require.NoError(t, err)
+		assert.Equal(t, testConnectionString, s.connectionString)
+
+		_, err = s.parseMetadata(map[string]string{"connectionString": testConnectionString + "&client_session_keep_alive=false"})
+		require.NoError(t, err)
+		assert.Equal(t, testConnectionString+"&client_session_keep_alive=false", s.connectionString)
+	})
+
+	invalid := map[string]map[string]string{
+		"missing connection string": {},
+		"invalid table name":        {"connectionString": testConnectionString, "tableName": "state; DROP TABLE x"},
+		"too many qualifiers":       {"connectionString": testConnectionString, "tableName": "a.b.c.d"},
+		"invalid timeout":           {"connectionString": testConnectionString, "timeoutInSeconds": "0"},
+	}
+	for name, md := range invalid {
+		t.Run(name, func(t *testing.T) {
+			s := newSnowflakeStateStore(logger.NewLogger("test"))
+			_, err := s.parseMetadata(md)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	s, mock := initStore(t)
+
+	t.Run("JSON value", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, TO_JSON\\(value\\), version, isbinary FROM dapr_state WHERE id = \\?").
+			WithArgs("key").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "value", "version", "isbinary"}).AddRow("key", `{"a":1}`, 3, false))
+		res, err := s.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(res.Data))
+		assert.Equal(t, "3", *res.ETag)
+	})
+
+	t.Run("binary value", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id").
+			WithArgs("key").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "value", "version", "isbinary"}).AddRow("key", `"AAEC"`, 1, true))
+		res, err := s.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 1, 2}, res.Data)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id").
+			WithArgs("missing").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "value", "version", "isbinary"}))
+		res, err := s.Get(context.Background(), &state.GetRequest{Key: "missing"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+		assert.Nil(t, res.ETag)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBulkGet(t *testing.T) {
+	s, mock := initStore(t)
+
+	mock.ExpectQuery("SELECT id, TO_JSON\\(value\\), version, isbinary FROM dapr_state WHERE id IN \\(\\?,\\?\\)").
+		WithArgs("a", "b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "version", "isbinary"}).AddRow("b", `"x"`, 2, false))
+	res, err := s.BulkGet(context.Background(), []state.GetRequest{{Key: "a"}, {Key: "b"}}, state.BulkGetOpts{})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "a", res[0].Key)
+	assert.Nil(t, res[0].Data)
+	assert.Equal(t, "b", res[1].Key)
+	assert.Equal(t, `"x"`, string(res[1].Data))
+	assert.Equal(t, "2", *res[1].ETag)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSet(t *testing.T) {
+	s, mock := initStore(t)
+
+	t.Run("upsert", func(t *testing.T) {
+		mock.ExpectExec("MERGE INTO dapr_state").
+			WithArgs("key", `{"a":1}`, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, s.Set(context.Background(), &state.SetRequest{Key: "key", Value: map[string]int{"a": 1}}))
+	})
+
+	t.Run("binary value", func(t *testing.T) {
+		mock.ExpectExec("MERGE INTO dapr_state").
+			WithArgs("key", `"AAEC"`, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, s.Set(context.Background(), &state.SetRequest{Key: "key", Value: []byte{0, 1, 2}}))
+	})
+
+	t.Run("ETag match", func(t *testing.T) {
+		mock.ExpectExec("UPDATE dapr_state").
+			WithArgs(`"v"`, false, "key", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, s.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", ETag: ptr.Of("3")}))
+	})
+
+	t.Run("ETag mismatch", func(t *testing.T) {
+		mock.ExpectExec("UPDATE dapr_state").
+			WithArgs(`"v"`, false, "key", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		err := s.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", ETag: ptr.Of("3")})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+
+	t.Run("invalid ETag", func(t *testing.T) {
+		err := s.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", ETag: ptr.Of("abc")})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagInvalid, etagErr.Kind())
+	})
+
+	t.Run("first write", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO dapr_state").
+			WithArgs("key", `"v"`, false, "key").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		err := s.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", Options: state.SetStateOption{Concurrency: state.FirstWrite}})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete(t *testing.T) {
+	s, mock := initStore(t)
+
+	mock.ExpectExec("DELETE FROM dapr_state WHERE id = \\?$").
+		WithArgs("key").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, s.Delete(context.Background(), &state.DeleteRequest{Key: "key"}))
+
+	mock.ExpectExec("DELETE FROM dapr_state WHERE id = \\? AND version = \\?").
+		WithArgs("key", int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err := s.Delete(context.Background(), &state.DeleteRequest{Key: "key", ETag: ptr.Of("2")})
+	var etagErr *state.ETagError
+	require.True(t, errors.As(err, &etagErr))
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMulti(t *testing.T) {
+	s, mock := initStore(t)
+
+	t.Run("commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("MERGE INTO dapr_state").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM dapr_state").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		require.NoError(t, s.Multi(context.Background(), &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "a", Value: "x"},
+				state.DeleteRequest{Key: "b"},
+			},
+		}))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE dapr_state").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		err := s.Multi(context.Background(), &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "a", Value: "x", ETag: ptr.Of("1")},
+				state.DeleteRequest{Key: "b"},
+			},
+		})
+		require.Error(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTimeout(t *testing.T) {
+	s, mock := initStore(t)
+	s.timeout = 10 * time.Millisecond
+
+	mock.ExpectQuery("SELECT id").WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"id", "value", "version", "isbinary"}))
+	start := time.Now()
+	_, err := s.Get(context.Background(), &state.GetRequest{Key: "key"})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
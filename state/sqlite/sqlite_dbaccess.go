@@ -17,7 +17,6 @@ import (
 	"context"
 	"database/sql"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -366,7 +365,7 @@ func (a *sqliteDBAccess) doSet(parentCtx context.Context, db querier, req *state
 		requestValue = base64.StdEncoding.EncodeToString(byteArray)
 	} else {
 		var bt []byte
-		bt, err = json.Marshal(req.Value)
+		bt, err = a.metadata.Marshal(req.Value)
 		if err != nil {
 			return err
 		}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
+	stateutils "github.com/dapr/components-contrib/state/utils"
 )
 
 const (
@@ -43,6 +44,9 @@ type sqliteMetadataStruct struct {
 	BusyTimeout       time.Duration `json:"busyTimeout" mapstructure:"busyTimeout"`
 	DisableWAL        bool          `json:"disableWAL" mapstructure:"disableWAL"` // Disable WAL journaling. You should not use WAL if the database is stored on a network filesystem (or data corruption may happen). This is ignored if the database is in-memory.
 
+	// Options of the JSON encoding of values
+	stateutils.JSONOptions `mapstructure:",squash"`
+
 	// Deprecated properties, maintained for backwards-compatibility
 	CleanupIntervalInSeconds string `json:"cleanupIntervalInSeconds" mapstructure:"cleanupIntervalInSeconds"`
 
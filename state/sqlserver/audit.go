@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/state"
+)
+
+// SetAuditLogStore sets the state store that the audit log is saved to. Implements state.AuditLogSinkSetter.
+func (s *SQLServer) SetAuditLogStore(store state.Store) error {
+	if s.auditLog == nil {
+		return errors.New("the state store is not initialized")
+	}
+	return s.auditLog.SetAuditLogStore(store)
+}
+
+// SetAuditLogPublisher sets the function that publishes the audit log. Implements state.AuditLogSinkSetter.
+func (s *SQLServer) SetAuditLogPublisher(publish state.AuditLogPublishFn) error {
+	if s.auditLog == nil {
+		return errors.New("the state store is not initialized")
+	}
+	return s.auditLog.SetAuditLogPublisher(publish)
+}
+
+// writeAuditLog records the operations in the audit log, if it's enabled.
+// If the audit log is stored in a table, the records are inserted in the schema of the store with db, so they're part of the transaction of the operations; otherwise, they're written to the sink, and must be written before the operations are executed.
+func (s *SQLServer) writeAuditLog(parentCtx context.Context, db dbExecutor, ops ...state.TransactionalStateOperation) error {
+	if s.auditLog == nil {
+		return nil
+	}
+	log, err := s.auditLog.Get()
+	if err != nil || log == nil {
+		return err
+	}
+
+	records := log.Records(ops...)
+	if !log.InTable() {
+		return log.Write(parentCtx, records)
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+	//nolint:gosec
+	query := fmt.Sprintf(`INSERT INTO [%s].[%s] ([Key], [Operation], [Actor], [RecordedAt]) VALUES (@Key, @Operation, @Actor, @RecordedAt)`, s.schema, log.Table())
+	for _, r := range records {
+		actor := sql.NullString{String: r.Actor, Valid: r.Actor != ""}
+		_, err = db.ExecContext(ctx, query,
+			sql.Named(keyColumnName, r.Key), sql.Named("Operation", string(r.Operation)),
+			sql.Named("Actor", actor), sql.Named("RecordedAt", r.Time),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write the audit log: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+		}
+	}
+	return nil
+}
+
+// execAudited executes a single operation together with its record in the audit log, in a transaction.
+// The transaction also holds the lock taken on the reservation of the key, if any, until the operation is executed.
+func (s *SQLServer) execAudited(ctx context.Context, op state.TransactionalStateOperation, fn func(db dbExecutor) error) error {
+	tx, err := s.db.BeginTx(ctx, s.txOptions)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.checkNotReserved(ctx, tx, []state.TransactionalStateOperation{op})
+	if err != nil {
+		return err
+	}
+	err = s.writeAuditLog(ctx, tx, op)
+	if err != nil {
+		return err
+	}
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// bulkOperations returns the requests of BulkSet or BulkDelete as operations, for the audit log and the check of the reservations.
+func bulkOperations[T state.TransactionalStateOperation](req []T) []state.TransactionalStateOperation {
+	ops := make([]state.TransactionalStateOperation, len(req))
+	for i := range req {
+		ops[i] = req[i]
+	}
+	return ops
+}
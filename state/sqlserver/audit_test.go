@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestAuditLogMetadata(t *testing.T) {
+	t.Run("table", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"auditLogSink":      "table",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "dapr_state_audit", sqlStore.auditLogTable)
+		assert.True(t, sqlStore.auditLog.InTable())
+	})
+
+	t.Run("invalid table name", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"auditLogSink":      "table",
+			"auditLogTable":     "audit; DROP TABLE state",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("state store must be set", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"auditLogSink":      "statestore",
+			"auditLogComponent": "auditstore",
+		})
+		require.NoError(t, err)
+		assert.Empty(t, sqlStore.auditLogTable)
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		sqlStore.db = db
+		expectNotReserved(mock)
+		mock.ExpectRollback()
+
+		err = sqlStore.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v"})
+		require.ErrorContains(t, err, "'auditstore' is configured, but it was not set")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing properties", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"auditLogSink":      "pubsub",
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestAuditLogInTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+	err = sqlStore.parseMetadata(map[string]string{
+		connectionStringKey: sampleConnectionString,
+		"auditLogSink":      "table",
+		"auditLogActorKey":  "user",
+	})
+	require.NoError(t, err)
+	sqlStore.db = db
+	sqlStore.upsertCommand = "[dbo].sp_Upsert_v5_state"
+	sqlStore.deleteWithoutETagCommand = "DELETE FROM [dbo].[state] WHERE [Key] = @Key"
+
+	t.Run("set is executed in a transaction with its record", func(t *testing.T) {
+		expectNotReserved(mock)
+		mock.ExpectExec(`INSERT INTO \[dbo\]\.\[dapr_state_audit\]`).
+			WithArgs("key", "upsert", "alice", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`sp_Upsert`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := sqlStore.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v", Metadata: map[string]string{"user": "alice"}})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("record is rolled back with a failed delete", func(t *testing.T) {
+		expectNotReserved(mock)
+		mock.ExpectExec(`INSERT INTO \[dbo\]\.\[dapr_state_audit\]`).
+			WithArgs("key", "delete", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM`).
+			WillReturnError(errors.New("delete failed"))
+		mock.ExpectRollback()
+
+		err := sqlStore.Delete(context.Background(), &state.DeleteRequest{Key: "key"})
+		require.Error(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAuditLogWriteAhead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+	err = sqlStore.parseMetadata(map[string]string{
+		connectionStringKey: sampleConnectionString,
+		"auditLogSink":      "file",
+		"auditLogFile":      path,
+	})
+	require.NoError(t, err)
+	sqlStore.db = db
+	sqlStore.upsertCommand = "[dbo].sp_Upsert_v5_state"
+
+	// The records are written to the file before the operation
+	expectNotReserved(mock)
+	mock.ExpectExec(`sp_Upsert`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = sqlStore.Set(context.Background(), &state.SetRequest{Key: "key", Value: "v"})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.FileExists(t, path)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+// Maximum number of keys read with a single query; SQL Server supports up to 2100 parameters per query.
+const bulkGetBatchSize = 500
+
+// BulkGet reads the keys in batches, with one query per batch.
+// If the query of a batch fails, for example because a key can't be converted to the key type or a value can't be decrypted, the keys of the batch are read one at a time, so the failure is only reported for the keys that failed.
+func (s *SQLServer) BulkGet(ctx context.Context, req []state.GetRequest, _ state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	// The responses have the keys of the requests, before normalization
+	original := req
+	req = s.keyNormalizer.GetRequests(req)
+	s, err := storeForRequests(ctx, s, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := state.NewBulkGetResponses(req)
+
+	var (
+		single []int
+		batch  = make([]int, 0, bulkGetBatchSize)
+	)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := s.bulkGetBatch(ctx, req, res, batch)
+		if err != nil {
+			s.logger.Debugf("Failed to read %d keys with a single query, reading them one at a time: %v", len(batch), err)
+			single = append(single, batch...)
+		}
+		batch = batch[:0]
+	}
+	for i := range req {
+		// Requests for a JSON path are read one at a time
+		if path := req[i].Metadata[jsonPathMetadataKey]; path != "" && path != "$" {
+			single = append(single, i)
+			continue
+		}
+		batch = append(batch, i)
+		if len(batch) == bulkGetBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	for _, i := range single {
+		r, err := s.Get(ctx, &req[i])
+		if err != nil {
+			res[i] = state.BulkGetResponse{Key: req[i].Key, Error: err.Error()}
+			continue
+		}
+		res[i] = state.BulkGetResponse{Key: req[i].Key, Data: r.Data, ETag: r.ETag}
+	}
+
+	// If the context was canceled, no key could be read
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	s.keyNormalizer.RestoreKeys(original, res)
+	return res, nil
+}
+
+// bulkGetBatch reads the keys of the requests at the given indexes, and sets their responses.
+// The responses are set only if the query succeeds.
+func (s *SQLServer) bulkGetBatch(parentCtx context.Context, req []state.GetRequest, res []state.BulkGetResponse, batch []int) error {
+	values := make([]string, len(batch))
+	args := make([]any, len(batch))
+	for j, i := range batch {
+		values[j] = "(" + strconv.Itoa(i) + ", @K" + strconv.Itoa(j) + ")"
+		args[j] = sql.Named("K"+strconv.Itoa(j), req[i].Key)
+	}
+	//nolint:gosec
+	query := fmt.Sprintf(`SELECT v.[Idx], t.[Data], t.[RowVersion], t.[Serializer]
+FROM (VALUES %s) AS v([Idx], [Key])
+JOIN [%s].[%s] AS t ON t.[Key] = v.[Key]
+WHERE t.[Deleted] = 0 AND (t.[ExpireDate] IS NULL OR t.[ExpireDate] > GETDATE())`,
+		strings.Join(values, ", "), s.schema, s.tableName,
+	)
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+	defer rows.Close()
+
+	found := make([]state.BulkGetResponse, 0, len(batch))
+	idx := make([]int, 0, len(batch))
+	for rows.Next() {
+		var (
+			i          int
+			data       string
+			rowVersion []byte
+			serializer sql.NullString
+		)
+		err = rows.Scan(&i, &data, &rowVersion, &serializer)
+		if err != nil {
+			return internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+		}
+		if i < 0 || i >= len(req) {
+			return fmt.Errorf("unexpected index %d in the results", i)
+		}
+		value, err := decodeValue(data, serializer)
+		if err != nil {
+			return err
+		}
+		idx = append(idx, i)
+		found = append(found, state.BulkGetResponse{
+			Key:  req[i].Key,
+			Data: value,
+			ETag: ptr.Of(hex.EncodeToString(rowVersion)),
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	for j, i := range idx {
+		res[i] = found[j]
+	}
+	return nil
+}
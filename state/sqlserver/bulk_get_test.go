@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestBulkGet(t *testing.T) {
+	newStore := func(t *testing.T) (*SQLServer, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return &SQLServer{
+			logger:     logger.NewLogger("test"),
+			db:         db,
+			schema:     "dbo",
+			tableName:  "state",
+			getCommand: "SELECT [Data], [RowVersion], [Serializer] FROM [dbo].[state] WHERE [Key] = @Key",
+		}, mock
+	}
+
+	t.Run("single query", func(t *testing.T) {
+		s, mock := newStore(t)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM (VALUES (0, @K0), (1, @K1), (2, @K2)) AS v([Idx], [Key])\nJOIN [dbo].[state] AS t")).
+			WithArgs("a", "b", "c").
+			WillReturnRows(sqlmock.NewRows([]string{"Idx", "Data", "RowVersion", "Serializer"}).
+				AddRow(2, `"c"`, []byte{0, 2}, nil).
+				AddRow(0, `"a"`, []byte{0, 1}, nil))
+
+		res, err := s.BulkGet(context.Background(), []state.GetRequest{{Key: "a"}, {Key: "b"}, {Key: "c"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, 3)
+		assert.Equal(t, "a", res[0].Key)
+		assert.Equal(t, `"a"`, string(res[0].Data))
+		assert.Equal(t, "0001", *res[0].ETag)
+		assert.Equal(t, "b", res[1].Key)
+		assert.Nil(t, res[1].Data)
+		assert.Empty(t, res[1].Error)
+		assert.Equal(t, "c", res[2].Key)
+		assert.Equal(t, "0002", *res[2].ETag)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failures are reported per key", func(t *testing.T) {
+		s, mock := newStore(t)
+		// A value that can't be decrypted fails the query, so the keys are read one at a time
+		mock.ExpectQuery("VALUES").WillReturnError(errors.New("failed to decrypt a column encryption key"))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE [Key] = @Key")).
+			WithArgs("a").
+			WillReturnRows(sqlmock.NewRows([]string{"Data", "RowVersion", "Serializer"}).AddRow(`"a"`, []byte{0, 1}, nil))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE [Key] = @Key")).
+			WithArgs("b").
+			WillReturnError(errors.New("failed to decrypt a column encryption key"))
+
+		res, err := s.BulkGet(context.Background(), []state.GetRequest{{Key: "a"}, {Key: "b"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, `"a"`, string(res[0].Data))
+		assert.Empty(t, res[0].Error)
+		assert.Equal(t, "b", res[1].Key)
+		assert.Nil(t, res[1].Data)
+		assert.Contains(t, res[1].Error, "failed to decrypt")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		s, mock := newStore(t)
+		req := make([]state.GetRequest, bulkGetBatchSize+1)
+		for i := range req {
+			req[i].Key = "k"
+		}
+		mock.ExpectQuery("VALUES").WillReturnRows(sqlmock.NewRows([]string{"Idx", "Data", "RowVersion", "Serializer"}))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM (VALUES (500, @K0)) AS v")).
+			WillReturnRows(sqlmock.NewRows([]string{"Idx", "Data", "RowVersion", "Serializer"}).AddRow(500, `"k"`, []byte{1}, nil))
+
+		res, err := s.BulkGet(context.Background(), req, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, len(req))
+		assert.Nil(t, res[0].Data)
+		assert.Equal(t, `"k"`, string(res[bulkGetBatchSize].Data))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("normalized keys", func(t *testing.T) {
+		s, mock := newStore(t)
+		require.NoError(t, s.SetKeyNormalizer(strings.ToLower))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM (VALUES (0, @K0), (1, @K1)) AS v([Idx], [Key])")).
+			WithArgs("a", "a").
+			WillReturnRows(sqlmock.NewRows([]string{"Idx", "Data", "RowVersion", "Serializer"}).
+				AddRow(0, `"a"`, []byte{0, 1}, nil).
+				AddRow(1, `"a"`, []byte{0, 1}, nil))
+
+		res, err := s.BulkGet(context.Background(), []state.GetRequest{{Key: "A"}, {Key: "a"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, "A", res[0].Key)
+		assert.Equal(t, "a", res[1].Key)
+		assert.Equal(t, `"a"`, string(res[0].Data))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
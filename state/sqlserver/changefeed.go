@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/kit/ptr"
+)
+
+// ChangeOperation is the kind of change reported by the change feed.
+type ChangeOperation string
+
+const (
+	// ChangeOperationUpsert is reported for keys that were inserted or updated.
+	ChangeOperationUpsert ChangeOperation = "upsert"
+	// ChangeOperationDelete is reported for keys that were deleted, including soft deletes and expired items removed by the garbage collector.
+	ChangeOperationDelete ChangeOperation = "delete"
+)
+
+var (
+	// ErrChangeTrackingDisabled is returned when reading changes from a state store that was not configured with "changeTracking".
+	ErrChangeTrackingDisabled = errors.New("change tracking is not enabled for the state store")
+
+	// ErrChangeTokenExpired is returned when the changes since a token were already removed from the change tracking data, because the token is older than the retention period.
+	// The feed must be restarted from an empty token.
+	ErrChangeTokenExpired = errors.New("change token has expired")
+)
+
+// Change is a change to a key in the state table.
+type Change struct {
+	Key       string
+	Operation ChangeOperation
+	// ETag of the item after the change; nil for deletes.
+	ETag *string
+}
+
+// GetChanges returns the keys that were changed since the version identified by sinceToken, and the token to use to resume reading changes after them.
+// When sinceToken is empty, no change is returned and the token identifies the current version of the table.
+// Only the last change of each key is returned, in the order in which they were made.
+// The metadata selects the tenant whose changes are read; tokens of different tenants can't be mixed.
+func (s *SQLServer) GetChanges(parentCtx context.Context, sinceToken string, metadata map[string]string) ([]Change, string, error) {
+	if !s.changeTracking {
+		return nil, "", ErrChangeTrackingDisabled
+	}
+	s, err := s.storeFor(parentCtx, metadata)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	if sinceToken == "" {
+		var current sql.NullInt64
+		err := s.db.QueryRowContext(ctx, "SELECT CHANGE_TRACKING_CURRENT_VERSION()").Scan(&current)
+		if err != nil {
+			return nil, "", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+		}
+		if !current.Valid {
+			return nil, "", ErrChangeTrackingDisabled
+		}
+		return nil, strconv.FormatInt(current.Int64, 10), nil
+	}
+
+	since, err := strconv.ParseInt(sinceToken, 10, 64)
+	if err != nil || since < 0 {
+		return nil, "", fmt.Errorf("invalid change token '%s'", sinceToken)
+	}
+
+	tableName := fmt.Sprintf("[%s].[%s]", s.schema, s.tableName)
+
+	var minValid sql.NullInt64
+	err = s.db.QueryRowContext(ctx, "SELECT CHANGE_TRACKING_MIN_VALID_VERSION(OBJECT_ID(@Table))", sql.Named("Table", tableName)).
+		Scan(&minValid)
+	if err != nil {
+		return nil, "", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+	if !minValid.Valid {
+		return nil, "", ErrChangeTrackingDisabled
+	}
+	if since < minValid.Int64 {
+		return nil, "", ErrChangeTokenExpired
+	}
+
+	// The join returns the current row version, and allows reporting soft deletes as deletes
+	//nolint:gosec
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT CONVERT(NVARCHAR(MAX), ct.[Key]), ct.[SYS_CHANGE_OPERATION], ct.[SYS_CHANGE_VERSION], s.[RowVersion], s.[Deleted]
+FROM CHANGETABLE(CHANGES %[1]s, @Since) AS ct
+LEFT JOIN %[1]s s ON s.[Key] = ct.[Key]
+ORDER BY ct.[SYS_CHANGE_VERSION]`, tableName),
+		sql.Named("Since", since),
+	)
+	if err != nil {
+		return nil, "", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+	defer rows.Close()
+
+	var (
+		changes []Change
+		last    = since
+	)
+	for rows.Next() {
+		var (
+			key        string
+			op         string
+			version    int64
+			rowVersion []byte
+			deleted    sql.NullBool
+		)
+		err = rows.Scan(&key, &op, &version, &rowVersion, &deleted)
+		if err != nil {
+			return nil, "", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+		}
+
+		c := Change{Key: key}
+		if op == "D" || rowVersion == nil || deleted.Bool {
+			c.Operation = ChangeOperationDelete
+		} else {
+			c.Operation = ChangeOperationUpsert
+			c.ETag = ptr.Of(hex.EncodeToString(rowVersion))
+		}
+		changes = append(changes, c)
+
+		if version > last {
+			last = version
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	return changes, strconv.FormatInt(last, 10), nil
+}
+
+// SubscribeChanges invokes handler with the changes made since the version identified by sinceToken, and then with new changes as they are made, until the context is canceled.
+// The table is checked for changes every "changeFeedPollInterval". If handler returns an error, the same changes are delivered again at the next check.
+// The token passed to handler can be stored to resume the feed later.
+// It returns nil when the context is canceled, and an error if the changes can't be read anymore, such as ErrChangeTokenExpired.
+func (s *SQLServer) SubscribeChanges(ctx context.Context, sinceToken string, metadata map[string]string, handler func(ctx context.Context, changes []Change, token string) error) error {
+	if !s.changeTracking {
+		return ErrChangeTrackingDisabled
+	}
+
+	ticker := time.NewTicker(s.changeFeedPollInterval)
+	defer ticker.Stop()
+
+	token := sinceToken
+	for {
+		changes, next, err := s.GetChanges(ctx, token, metadata)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, ErrChangeTokenExpired), errors.Is(err, ErrChangeTrackingDisabled):
+			return err
+		case err != nil:
+			s.logger.Warnf("Failed to read changes from the state table: %v", err)
+		case len(changes) == 0:
+			token = next
+		default:
+			err = handler(ctx, changes, next)
+			if err != nil {
+				s.logger.Warnf("Failed to process changes from the state table, they will be delivered again: %v", err)
+			} else {
+				token = next
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
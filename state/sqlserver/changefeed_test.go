@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func newChangeFeedTestStore(t *testing.T) (*SQLServer, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return &SQLServer{
+		logger:                 logger.NewLogger("test"),
+		db:                     db,
+		schema:                 "dbo",
+		tableName:              "state",
+		changeTracking:         true,
+		changeFeedPollInterval: 10 * time.Millisecond,
+	}, mock
+}
+
+func expectChanges(mock sqlmock.Sqlmock, since int64, minValid int64, rows *sqlmock.Rows) {
+	mock.ExpectQuery(`SELECT CHANGE_TRACKING_MIN_VALID_VERSION\(OBJECT_ID\(@Table\)\)`).
+		WithArgs("[dbo].[state]").
+		WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(minValid))
+	if rows != nil {
+		mock.ExpectQuery(`FROM CHANGETABLE\(CHANGES \[dbo\]\.\[state\], @Since\) AS ct`).
+			WithArgs(since).
+			WillReturnRows(rows)
+	}
+}
+
+func newChangeRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"Key", "SYS_CHANGE_OPERATION", "SYS_CHANGE_VERSION", "RowVersion", "Deleted"})
+}
+
+func TestGetChanges(t *testing.T) {
+	t.Run("change tracking disabled", func(t *testing.T) {
+		sqlStore := &SQLServer{}
+		_, _, err := sqlStore.GetChanges(context.Background(), "", nil)
+		require.ErrorIs(t, err, ErrChangeTrackingDisabled)
+	})
+
+	t.Run("empty token starts from the current version", func(t *testing.T) {
+		sqlStore, mock := newChangeFeedTestStore(t)
+		mock.ExpectQuery(`SELECT CHANGE_TRACKING_CURRENT_VERSION\(\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(int64(42)))
+
+		changes, token, err := sqlStore.GetChanges(context.Background(), "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Equal(t, "42", token)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("upserts and deletes", func(t *testing.T) {
+		sqlStore, mock := newChangeFeedTestStore(t)
+		expectChanges(mock, 10, 1, newChangeRows().
+			AddRow("k1", "I", int64(11), []byte{0, 0, 0, 0, 0, 0, 0, 1}, false).
+			AddRow("k2", "D", int64(12), nil, nil).
+			AddRow("k3", "U", int64(14), []byte{0, 0, 0, 0, 0, 0, 0, 2}, true).
+			AddRow("k4", "U", int64(13), []byte{0, 0, 0, 0, 0, 0, 0, 3}, false),
+		)
+
+		changes, token, err := sqlStore.GetChanges(context.Background(), "10", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Key: "k1", Operation: ChangeOperationUpsert, ETag: ptr.Of("0000000000000001")},
+			{Key: "k2", Operation: ChangeOperationDelete},
+			{Key: "k3", Operation: ChangeOperationDelete},
+			{Key: "k4", Operation: ChangeOperationUpsert, ETag: ptr.Of("0000000000000003")},
+		}, changes)
+		assert.Equal(t, "14", token)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no changes keeps the token", func(t *testing.T) {
+		sqlStore, mock := newChangeFeedTestStore(t)
+		expectChanges(mock, 10, 1, newChangeRows())
+
+		changes, token, err := sqlStore.GetChanges(context.Background(), "10", nil)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Equal(t, "10", token)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		sqlStore, mock := newChangeFeedTestStore(t)
+		expectChanges(mock, 10, 20, nil)
+
+		_, _, err := sqlStore.GetChanges(context.Background(), "10", nil)
+		require.ErrorIs(t, err, ErrChangeTokenExpired)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		sqlStore, _ := newChangeFeedTestStore(t)
+
+		_, _, err := sqlStore.GetChanges(context.Background(), "foo", nil)
+		require.Error(t, err)
+	})
+}
+
+func TestSubscribeChanges(t *testing.T) {
+	sqlStore, mock := newChangeFeedTestStore(t)
+
+	// First batch fails in the handler and is delivered again, then the feed resumes from the new token
+	expectChanges(mock, 5, 1, newChangeRows().AddRow("k1", "I", int64(6), []byte{0, 0, 0, 0, 0, 0, 0, 1}, false))
+	expectChanges(mock, 5, 1, newChangeRows().AddRow("k1", "I", int64(6), []byte{0, 0, 0, 0, 0, 0, 0, 1}, false))
+	expectChanges(mock, 6, 1, newChangeRows())
+	expectChanges(mock, 6, 7, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		calls  int
+		tokens []string
+	)
+	err := sqlStore.SubscribeChanges(ctx, "5", nil, func(_ context.Context, changes []Change, token string) error {
+		calls++
+		tokens = append(tokens, token)
+		assert.Equal(t, []Change{{Key: "k1", Operation: ChangeOperationUpsert, ETag: ptr.Of("0000000000000001")}}, changes)
+		if calls == 1 {
+			return errors.New("simulated")
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, ErrChangeTokenExpired)
+	assert.Equal(t, []string{"6", "6"}, tokens)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChangeTrackingMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+		})
+		require.NoError(t, err)
+		assert.False(t, sqlStore.changeTracking)
+		assert.Equal(t, defaultChangeTrackingRetention, sqlStore.changeTrackingRetention)
+		assert.Equal(t, defaultChangeFeedPollInterval, sqlStore.changeFeedPollInterval)
+	})
+
+	t.Run("custom values", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey:        sampleConnectionString,
+			"changeTracking":           "true",
+			changeTrackingRetentionKey: "6h",
+			changeFeedPollIntervalKey:  "5s",
+		})
+		require.NoError(t, err)
+		assert.True(t, sqlStore.changeTracking)
+		assert.Equal(t, 6*time.Hour, sqlStore.changeTrackingRetention)
+		assert.Equal(t, 5*time.Second, sqlStore.changeFeedPollInterval)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		sqlStore := &SQLServer{logger: logger.NewLogger("test")}
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey:        sampleConnectionString,
+			changeTrackingRetentionKey: "30s",
+		})
+		require.Error(t, err)
+
+		err = sqlStore.parseMetadata(map[string]string{
+			connectionStringKey:       sampleConnectionString,
+			changeFeedPollIntervalKey: "0",
+		})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/state/utils"
+)
+
+// serializerColumnName is the column of the state table with the name of the codec each value was serialized with.
+// It's NULL for rows written before the column was added, whose values are JSON.
+const serializerColumnName = "Serializer"
+
+// errSerializerUnsupported is returned by operations that need the server to read the values as JSON, when values are serialized with another codec.
+var errSerializerUnsupported = fmt.Errorf("operation not supported when metadata property '%s' is set to a codec other than '%s'", statecodec.SerializerKey, statecodec.JSON)
+
+// parseSerializer returns the codec selected in the metadata.
+func parseSerializer(m sqlServerMetadata) (statecodec.Codec, error) {
+	codec, err := statecodec.Parse(m.Serializer)
+	if err != nil {
+		return nil, err
+	}
+	if !statecodec.IsJSON(codec) && m.IndexedProperties != "" {
+		return nil, fmt.Errorf("metadata property '%s' can't be used with '%s', as indexed properties are computed from JSON values on the server", indexedPropertiesKey, statecodec.SerializerKey)
+	}
+	return codec, nil
+}
+
+// encodeValue returns the content of the Data column for a value, and the name of the codec it was serialized with.
+// Values serialized with codecs other than JSON are stored encoded as base64.
+func (s *SQLServer) encodeValue(v any) (string, string, error) {
+	if statecodec.IsJSON(s.codec) {
+		b, err := utils.Marshal(v, s.jsonOptions.Marshal)
+		if err != nil {
+			return "", "", err
+		}
+		return string(b), statecodec.JSON, nil
+	}
+
+	b, err := s.codec.Encode(v)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), s.codec.Name(), nil
+}
+
+// decodeValue returns the data of a value read from the Data column, using the codec in the Serializer column.
+func decodeValue(data string, serializer sql.NullString) ([]byte, error) {
+	if !serializer.Valid || serializer.String == statecodec.JSON {
+		return []byte(data), nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return statecodec.Decode(serializer.String, b)
+}
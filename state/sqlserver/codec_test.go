@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"encoding/base64"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestSerializerMetadata(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		sqlStore := New(logger.NewLogger("test")).(*SQLServer)
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, statecodec.JSON, sqlStore.codec.Name())
+		assert.Contains(t, sqlStore.Features(), state.FeatureQueryAPI)
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		sqlStore := New(logger.NewLogger("test")).(*SQLServer)
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"serializer":        "msgpack",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, statecodec.Msgpack, sqlStore.codec.Name())
+		assert.NotContains(t, sqlStore.Features(), state.FeatureQueryAPI)
+
+		_, err = sqlStore.Query(context.Background(), &state.QueryRequest{})
+		require.ErrorIs(t, err, errSerializerUnsupported)
+		_, err = sqlStore.getJSONPath(context.Background(), &state.GetRequest{Key: "k"}, "a.b")
+		require.ErrorIs(t, err, errSerializerUnsupported)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sqlStore := New(logger.NewLogger("test")).(*SQLServer)
+		err := sqlStore.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"serializer":        "xml",
+		})
+		require.Error(t, err)
+
+		err = sqlStore.parseMetadata(map[string]string{
+			connectionStringKey:  sampleConnectionString,
+			"serializer":         "msgpack",
+			indexedPropertiesKey: `[{"column": "Age","property": "age", "type": "int"}]`,
+		})
+		require.ErrorContains(t, err, "can't be used with 'serializer'")
+	})
+}
+
+func TestSerializer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlStore := New(logger.NewLogger("test")).(*SQLServer)
+	err = sqlStore.parseMetadata(map[string]string{
+		connectionStringKey: sampleConnectionString,
+		"serializer":        "msgpack",
+	})
+	require.NoError(t, err)
+	sqlStore.db = db
+	sqlStore.upsertCommand = "[dbo].sp_Upsert_v5_state"
+	sqlStore.getCommand = "SELECT [Data], [RowVersion], [Serializer] FROM [dbo].[state] WHERE [Key] = @Key"
+
+	encoded, err := sqlStore.codec.Encode(map[string]string{"city": "Seattle"})
+	require.NoError(t, err)
+	data := base64.StdEncoding.EncodeToString(encoded)
+
+	t.Run("set stores the name of the codec", func(t *testing.T) {
+		expectNotReserved(mock)
+		mock.ExpectExec(`sp_Upsert`).
+			WithArgs("key", data, nil, 0, nil, "msgpack").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := sqlStore.Set(context.Background(), &state.SetRequest{Key: "key", Value: map[string]string{"city": "Seattle"}})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("get decodes values", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \[Data\]`).
+			WithArgs("key").
+			WillReturnRows(sqlmock.NewRows([]string{"Data", "RowVersion", "Serializer"}).AddRow(data, []byte{0, 1}, "msgpack"))
+
+		res, err := sqlStore.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"city":"Seattle"}`, string(res.Data))
+	})
+
+	t.Run("get reads values stored as JSON", func(t *testing.T) {
+		// Rows written before the Serializer column was added have no codec
+		mock.ExpectQuery(`SELECT \[Data\]`).
+			WithArgs("key").
+			WillReturnRows(sqlmock.NewRows([]string{"Data", "RowVersion", "Serializer"}).AddRow(`{"city":"Seattle"}`, []byte{0, 1}, nil))
+
+		res, err := sqlStore.Get(context.Background(), &state.GetRequest{Key: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, `{"city":"Seattle"}`, string(res.Data))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSerializerColumnMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := &migration{store: &SQLServer{schema: "dbo", tableName: "state", metaTableName: "dapr_metadata", keyType: StringKeyType, keyLength: defaultKeyLength}}
+
+	mock.ExpectExec(regexp.QuoteMeta("[Serializer] 	NVARCHAR(32) NULL,")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// The column is added to existing tables, after the columns added by previous versions
+	for _, name := range []string{"InsertDate", "UpdateDate", "ExpireDate", "Deleted", "DeletedDate"} {
+		mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE [dbo].[state] ADD [" + name + "]")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE [dbo].[state] ADD [Serializer] NVARCHAR(32) NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE \[dbo\]\.\[dapr_metadata\]`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE \[dbo\]\.\[state_Reservations\]`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = m.ensureTableExists(context.Background(), db, m.newMigrationResult())
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	columnEncryptionKey         = "columnEncryption"
+	columnEncryptionKeyKey      = "columnEncryptionKey"
+	columnEncryptionTypeKey     = "columnEncryptionType"
+	columnMasterKeyKey          = "columnMasterKey"
+	encryptionTypeRandomized    = "Randomized"
+	encryptionTypeDeterministic = "Deterministic"
+)
+
+// driverSupportsColumnEncryption is true if the SQL Server driver implements Always Encrypted, decrypting and encrypting the values of encrypted columns on the client.
+// github.com/denisenkom/go-mssqldb doesn't, so enabling column encryption fails in Init instead of storing values the component can't read back.
+var driverSupportsColumnEncryption = false
+
+// errColumnEncryptionUnsupported is returned by operations that need the server to read the values, which are encrypted.
+var errColumnEncryptionUnsupported = errors.New("operation not supported when the Data column is encrypted with Always Encrypted")
+
+// columnEncryption contains the configuration of Always Encrypted for the Data column.
+type columnEncryption struct {
+	// Name of the column encryption key (CEK) used to encrypt the column
+	keyName string
+	// "Randomized" or "Deterministic"
+	encryptionType string
+	// If set, name of the column master key (CMK) the CEK must be encrypted with
+	masterKeyName string
+}
+
+// parseColumnEncryption validates the Always Encrypted metadata properties.
+// It returns nil if column encryption is disabled.
+func parseColumnEncryption(m sqlServerMetadata) (*columnEncryption, error) {
+	if !m.ColumnEncryption {
+		return nil, nil
+	}
+
+	if !driverSupportsColumnEncryption {
+		return nil, fmt.Errorf("metadata property '%s' requires a SQL Server driver with support for Always Encrypted, which this build of the component doesn't include", columnEncryptionKey)
+	}
+	if m.ColumnEncryptionKey == "" {
+		return nil, fmt.Errorf("metadata property '%s' is required when '%s' is enabled", columnEncryptionKeyKey, columnEncryptionKey)
+	}
+	if !isValidSQLName(m.ColumnEncryptionKey) {
+		return nil, fmt.Errorf("invalid value for '%s': '%s' is not a valid key name", columnEncryptionKeyKey, m.ColumnEncryptionKey)
+	}
+	if m.ColumnMasterKey != "" && !isValidSQLName(m.ColumnMasterKey) {
+		return nil, fmt.Errorf("invalid value for '%s': '%s' is not a valid key name", columnMasterKeyKey, m.ColumnMasterKey)
+	}
+	if m.IndexedProperties != "" {
+		return nil, fmt.Errorf("metadata property '%s' can't be used with '%s', as indexed properties are computed from the Data column on the server", indexedPropertiesKey, columnEncryptionKey)
+	}
+
+	ce := &columnEncryption{
+		keyName:       m.ColumnEncryptionKey,
+		masterKeyName: m.ColumnMasterKey,
+	}
+	switch strings.ToLower(m.ColumnEncryptionType) {
+	case "", strings.ToLower(encryptionTypeRandomized):
+		ce.encryptionType = encryptionTypeRandomized
+	case strings.ToLower(encryptionTypeDeterministic):
+		ce.encryptionType = encryptionTypeDeterministic
+	default:
+		return nil, fmt.Errorf("invalid value for '%s': must be '%s' or '%s'", columnEncryptionTypeKey, encryptionTypeRandomized, encryptionTypeDeterministic)
+	}
+	return ce, nil
+}
+
+// dataColumnDefinition returns the definition of the encrypted Data column.
+// Deterministic encryption requires a BIN2 collation for character columns.
+func (ce *columnEncryption) dataColumnDefinition() string {
+	var collation string
+	if ce.encryptionType == encryptionTypeDeterministic {
+		collation = "COLLATE Latin1_General_BIN2 "
+	}
+	return fmt.Sprintf(
+		"NVARCHAR(MAX) %sENCRYPTED WITH (COLUMN_ENCRYPTION_KEY = [%s], ENCRYPTION_TYPE = %s, ALGORITHM = 'AEAD_AES_256_CBC_HMAC_SHA_256') NOT NULL",
+		collation, ce.keyName, ce.encryptionType,
+	)
+}
+
+// ensureKeyExists checks that the column encryption key exists and, if a column master key is configured, that it's encrypted with it.
+// Keys are provisioned with tools that have access to the column master key, such as SSMS or the SqlServer PowerShell module, so the migrator doesn't create them.
+func (ce *columnEncryption) ensureKeyExists(ctx context.Context, db *sql.DB) error {
+	var masterKeys sql.NullString
+	err := db.QueryRowContext(ctx,
+		`SELECT STRING_AGG(cmk.[name], ',') FROM sys.column_encryption_keys cek
+		LEFT JOIN sys.column_encryption_key_values v ON v.column_encryption_key_id = cek.column_encryption_key_id
+		LEFT JOIN sys.column_master_keys cmk ON cmk.column_master_key_id = v.column_master_key_id
+		WHERE cek.[name] = @Name
+		GROUP BY cek.[name]`,
+		sql.Named("Name", ce.keyName),
+	).Scan(&masterKeys)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("column encryption key '%s' does not exist in the database", ce.keyName)
+	} else if err != nil {
+		return fmt.Errorf("failed to check column encryption key '%s': %w", ce.keyName, err)
+	}
+
+	if ce.masterKeyName != "" {
+		for _, k := range strings.Split(masterKeys.String, ",") {
+			if strings.EqualFold(k, ce.masterKeyName) {
+				return nil
+			}
+		}
+		return fmt.Errorf("column encryption key '%s' is not encrypted with column master key '%s'", ce.keyName, ce.masterKeyName)
+	}
+	return nil
+}
+
+// ensureDataColumnEncrypted checks that the Data column of an existing table is encrypted.
+// Encrypting existing data requires the keys on the client, so it can't be done by the migrator.
+func (ce *columnEncryption) ensureDataColumnEncrypted(ctx context.Context, db *sql.DB, schema string, table string) error {
+	var encryptionType sql.NullString
+	err := db.QueryRowContext(ctx,
+		`SELECT c.encryption_type_desc FROM sys.columns c
+		WHERE c.object_id = OBJECT_ID(@Table) AND c.[name] = 'Data'`,
+		sql.Named("Table", "["+schema+"].["+table+"]"),
+	).Scan(&encryptionType)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The table doesn't exist yet
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check encryption of the Data column: %w", err)
+	}
+
+	if !encryptionType.Valid {
+		return fmt.Errorf("the Data column of the existing table [%s].[%s] is not encrypted: encrypt it with a tool that has access to the column master key, such as SSMS or the SqlServer PowerShell module", schema, table)
+	}
+	return nil
+}
+
+// connStringWithColumnEncryption returns the connection string with Always Encrypted enabled in the driver, in the format of the connection string.
+func connStringWithColumnEncryption(connStr string) string {
+	if isURLConnString(connStr) {
+		u, err := url.Parse(connStr)
+		if err == nil {
+			q := u.Query()
+			q.Set("columnencryption", "true")
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return connStr + ";columnencryption=true;"
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/utils"
+	"github.com/dapr/kit/ptr"
+)
+
+// Metadata key on GetRequest with a JSON path (such as "$.address.city" or "$.items[0]"): when set, only the portion of the value at that path is returned.
+const jsonPathMetadataKey = "jsonPath"
+
+// Types of the values returned by OPENJSON.
+const (
+	openJSONTypeNull   = 0
+	openJSONTypeString = 1
+	openJSONTypeNumber = 2
+	openJSONTypeBool   = 3
+	openJSONTypeArray  = 4
+	openJSONTypeObject = 5
+)
+
+var jsonPathRegex = regexp.MustCompile(`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\[[0-9]+\])+$`)
+
+// splitJSONPath validates a JSON path and splits it into the path of the parent element and the name (or index) of the last member.
+// For example, "$.a.b[2]" is split into "$.a.b" and "2".
+func splitJSONPath(path string) (parent string, member string, err error) {
+	if !jsonPathRegex.MatchString(path) {
+		return "", "", fmt.Errorf("invalid JSON path '%s': must be in the format '$.property' or '$.array[index]'", path)
+	}
+
+	if strings.HasSuffix(path, "]") {
+		idx := strings.LastIndexByte(path, '[')
+		return path[:idx], path[idx+1 : len(path)-1], nil
+	}
+	idx := strings.LastIndexByte(path, '.')
+	return path[:idx], path[idx+1:], nil
+}
+
+// openJSONValueToJSON converts a value returned by OPENJSON to its JSON representation.
+func openJSONValueToJSON(value sql.NullString, typ int, opts utils.JSONOptions) ([]byte, error) {
+	switch typ {
+	case openJSONTypeNull:
+		return []byte("null"), nil
+	case openJSONTypeString:
+		return opts.Marshal(value.String)
+	case openJSONTypeNumber, openJSONTypeBool, openJSONTypeArray, openJSONTypeObject:
+		return []byte(value.String), nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value type %d", typ)
+	}
+}
+
+// getJSONPath returns the portion of the stored value at the given JSON path.
+// If the key or the path don't exist, an empty response is returned.
+// Passing the path as a parameter to OPENJSON requires SQL Server 2017 or newer.
+func (s *SQLServer) getJSONPath(parentCtx context.Context, req *state.GetRequest, path string) (*state.GetResponse, error) {
+	if s.columnEncryption != nil {
+		return nil, errColumnEncryptionUnsupported
+	}
+	if !statecodec.IsJSON(s.codec) {
+		return nil, errSerializerUnsupported
+	}
+
+	parent, member, err := splitJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	var (
+		value      sql.NullString
+		typ        int
+		rowVersion []byte
+	)
+	err = s.db.QueryRowContext(ctx, s.getJSONPathCommand,
+		sql.Named(keyColumnName, req.Key),
+		sql.Named("ParentPath", parent),
+		sql.Named("Member", member),
+	).Scan(&value, &typ, &rowVersion)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &state.GetResponse{}, nil
+	} else if err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	data, err := openJSONValueToJSON(value, typ, s.jsonOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state.GetResponse{
+		Data: data,
+		ETag: ptr.Of(hex.EncodeToString(rowVersion)),
+		Metadata: map[string]string{
+			jsonPathMetadataKey: path,
+		},
+	}, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/utils"
+	"github.com/dapr/kit/logger"
+)
+
+func TestSplitJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		parent  string
+		member  string
+		wantErr bool
+	}{
+		{path: "$.a", parent: "$", member: "a"},
+		{path: "$.a.b_c", parent: "$.a", member: "b_c"},
+		{path: "$.items[12]", parent: "$.items", member: "12"},
+		{path: "$[0].name", parent: "$[0]", member: "name"},
+		{path: "$", wantErr: true},
+		{path: "a.b", wantErr: true},
+		{path: "$.a'); DROP TABLE state; --", wantErr: true},
+		{path: "$.a[x]", wantErr: true},
+		{path: "$..a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			parent, member, err := splitJSONPath(tt.path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.parent, parent)
+			assert.Equal(t, tt.member, member)
+		})
+	}
+}
+
+func TestOpenJSONValueToJSON(t *testing.T) {
+	tests := []struct {
+		value    sql.NullString
+		typ      int
+		expected string
+	}{
+		{value: sql.NullString{}, typ: openJSONTypeNull, expected: `null`},
+		{value: sql.NullString{String: `Seattle "WA"`, Valid: true}, typ: openJSONTypeString, expected: `"Seattle \"WA\""`},
+		{value: sql.NullString{String: `42.5`, Valid: true}, typ: openJSONTypeNumber, expected: `42.5`},
+		{value: sql.NullString{String: `true`, Valid: true}, typ: openJSONTypeBool, expected: `true`},
+		{value: sql.NullString{String: `[1,2]`, Valid: true}, typ: openJSONTypeArray, expected: `[1,2]`},
+		{value: sql.NullString{String: `{"a":1}`, Valid: true}, typ: openJSONTypeObject, expected: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		res, err := openJSONValueToJSON(tt.value, tt.typ, utils.JSONOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, string(res))
+	}
+
+	_, err := openJSONValueToJSON(sql.NullString{}, 9, utils.JSONOptions{})
+	require.Error(t, err)
+
+	amp := sql.NullString{String: `Fish & <Chips>`, Valid: true}
+	res, err := openJSONValueToJSON(amp, openJSONTypeString, utils.JSONOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `"Fish \u0026 \u003cChips\u003e"`, string(res))
+	res, err = openJSONValueToJSON(amp, openJSONTypeString, utils.JSONOptions{JSONDisableHTMLEscape: true})
+	require.NoError(t, err)
+	assert.Equal(t, `"Fish & <Chips>"`, string(res))
+}
+
+func TestGetJSONPath(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlStore := &SQLServer{
+		logger:             logger.NewLogger("test"),
+		db:                 db,
+		getJSONPathCommand: "SELECT j.[value], j.[type], s.[RowVersion] FROM [dbo].[state] s CROSS APPLY OPENJSON(s.[Data], @ParentPath) j",
+	}
+
+	t.Run("path exists", func(t *testing.T) {
+		mock.ExpectQuery("OPENJSON").
+			WithArgs(sql.Named("Key", "key"), sql.Named("ParentPath", "$.address"), sql.Named("Member", "city")).
+			WillReturnRows(sqlmock.NewRows([]string{"value", "type", "RowVersion"}).AddRow("Seattle", openJSONTypeString, []byte{0, 1}))
+
+		res, err := sqlStore.Get(context.Background(), &state.GetRequest{
+			Key:      "key",
+			Metadata: map[string]string{jsonPathMetadataKey: "$.address.city"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `"Seattle"`, string(res.Data))
+		require.NotNil(t, res.ETag)
+		assert.Equal(t, "0001", *res.ETag)
+	})
+
+	t.Run("path does not exist", func(t *testing.T) {
+		mock.ExpectQuery("OPENJSON").
+			WillReturnRows(sqlmock.NewRows([]string{"value", "type", "RowVersion"}))
+
+		res, err := sqlStore.Get(context.Background(), &state.GetRequest{
+			Key:      "key",
+			Metadata: map[string]string{jsonPathMetadataKey: "$.nope"},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.Nil(t, res.ETag)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		_, err := sqlStore.Get(context.Background(), &state.GetRequest{
+			Key:      "key",
+			Metadata: map[string]string{jsonPathMetadataKey: "address.city"},
+		})
+		require.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/state"
+)
+
+// likeEscaper escapes the characters that have a special meaning in LIKE patterns, using '\' as escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `[`, `\[`)
+
+// ListKeys returns a page of the keys with the prefix, in the order of the key column.
+// The prefix is matched with LIKE, which seeks the primary key index, so keys are compared according to the collation of the key column; the continuation token is the last key of the page.
+func (s *SQLServer) ListKeys(parentCtx context.Context, req *state.ListKeysRequest) (*state.ListKeysResponse, error) {
+	if s.keyType != StringKeyType {
+		return nil, fmt.Errorf("listing keys requires keys of type '%s'", StringKeyType)
+	}
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+	s, err = s.storeFor(parentCtx, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	req = s.keyNormalizer.ListKeysRequest(req)
+
+	where := `[Key] LIKE @Pattern ESCAPE '\' AND [Deleted] = 0 AND ([ExpireDate] IS NULL OR [ExpireDate] > GETDATE())`
+	args := []any{
+		// Read one more key to know if there's another page
+		sql.Named("Limit", req.Limit+1),
+		sql.Named("Pattern", likeEscaper.Replace(req.Prefix)+"%"),
+	}
+	if req.ContinuationToken != "" {
+		where += " AND [Key] > @After"
+		args = append(args, sql.Named("After", req.ContinuationToken))
+	}
+	query := fmt.Sprintf("SELECT TOP (@Limit) [Key] FROM [%s].[%s] WHERE %s ORDER BY [Key]", s.schema, s.tableName, where)
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+	defer rows.Close()
+
+	res := &state.ListKeysResponse{
+		Keys: []string{},
+	}
+	for rows.Next() {
+		var key string
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+		}
+		if len(res.Keys) == req.Limit {
+			res.ContinuationToken = res.Keys[len(res.Keys)-1]
+			break
+		}
+		res.Keys = append(res.Keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	return res, nil
+}
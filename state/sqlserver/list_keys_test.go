@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestListKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlStore := &SQLServer{
+		logger:    logger.NewLogger("test"),
+		db:        db,
+		schema:    "dbo",
+		tableName: "state",
+		keyType:   StringKeyType,
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT TOP \(@Limit\) \[Key\] FROM \[dbo\]\.\[state\] WHERE \[Key\] LIKE @Pattern ESCAPE '\\' AND \[Deleted\] = 0 AND \(\[ExpireDate\] IS NULL OR \[ExpireDate\] > GETDATE\(\)\) ORDER BY \[Key\]`).
+			WithArgs(3, `app||user\_%`).
+			WillReturnRows(sqlmock.NewRows([]string{"Key"}).AddRow("app||user_1").AddRow("app||user_2").AddRow("app||user_3"))
+
+		res, err := sqlStore.ListKeys(context.Background(), &state.ListKeysRequest{Prefix: "app||user_", Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app||user_1", "app||user_2"}, res.Keys)
+		assert.Equal(t, "app||user_2", res.ContinuationToken)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		mock.ExpectQuery(`AND \[Key\] > @After ORDER BY \[Key\]`).
+			WithArgs(3, `app||user\_%`, "app||user_2").
+			WillReturnRows(sqlmock.NewRows([]string{"Key"}).AddRow("app||user_3"))
+
+		res, err := sqlStore.ListKeys(context.Background(), &state.ListKeysRequest{Prefix: "app||user_", Limit: 2, ContinuationToken: "app||user_2"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app||user_3"}, res.Keys)
+		assert.Empty(t, res.ContinuationToken)
+	})
+
+	t.Run("special characters are escaped", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT TOP`).
+			WithArgs(state.DefaultListKeysLimit+1, `a\%b\[c\\d%`).
+			WillReturnRows(sqlmock.NewRows([]string{"Key"}))
+
+		res, err := sqlStore.ListKeys(context.Background(), &state.ListKeysRequest{Prefix: `a%b[c\d`})
+		require.NoError(t, err)
+		assert.Empty(t, res.Keys)
+		assert.Empty(t, res.ContinuationToken)
+	})
+
+	t.Run("keys must be strings", func(t *testing.T) {
+		s := &SQLServer{keyType: IntegerKeyType}
+		_, err := s.ListKeys(context.Background(), &state.ListKeysRequest{})
+		require.Error(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
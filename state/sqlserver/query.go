@@ -0,0 +1,320 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+	"github.com/dapr/kit/ptr"
+)
+
+// Fields that can be used in filters and sorting of queries to refer to the dates when items were inserted and last updated, rather than to properties of the values.
+// The update date is null for items that were never updated.
+const (
+	queryFieldInsertDate = "_insertDate"
+	queryFieldUpdateDate = "_updateDate"
+)
+
+// Query builds T-SQL queries for the Query API.
+type Query struct {
+	query             string
+	params            []any
+	limit             int
+	skip              *int64
+	schema            string
+	tableName         string
+	indexedProperties []IndexedProperty
+}
+
+func (q *Query) VisitEQ(f *query.EQ) (string, error) {
+	return q.whereFieldEqual(f.Key, f.Val)
+}
+
+func (q *Query) VisitIN(f *query.IN) (string, error) {
+	if len(f.Vals) == 0 {
+		return "", fmt.Errorf("empty IN operator for key %q", f.Key)
+	}
+
+	arr := make([]string, len(f.Vals))
+	for i, v := range f.Vals {
+		str, err := q.whereFieldEqual(f.Key, v)
+		if err != nil {
+			return "", err
+		}
+		arr[i] = str
+	}
+	return "(" + strings.Join(arr, " OR ") + ")", nil
+}
+
+func (q *Query) visitFilters(op string, filters []query.Filter) (string, error) {
+	var (
+		arr []string
+		str string
+		err error
+	)
+
+	for _, fil := range filters {
+		switch f := fil.(type) {
+		case *query.EQ:
+			str, err = q.VisitEQ(f)
+		case *query.IN:
+			str, err = q.VisitIN(f)
+		case *query.OR:
+			str, err = q.VisitOR(f)
+		case *query.AND:
+			str, err = q.VisitAND(f)
+		default:
+			return "", fmt.Errorf("unsupported filter type %#v", f)
+		}
+		if err != nil {
+			return "", err
+		}
+		arr = append(arr, str)
+	}
+
+	return "(" + strings.Join(arr, " "+op+" ") + ")", nil
+}
+
+func (q *Query) VisitAND(f *query.AND) (string, error) {
+	return q.visitFilters("AND", f.Filters)
+}
+
+func (q *Query) VisitOR(f *query.OR) (string, error) {
+	return q.visitFilters("OR", f.Filters)
+}
+
+func (q *Query) Finalize(filters string, qq *query.Query) error {
+	q.query = fmt.Sprintf("SELECT CONVERT(NVARCHAR(MAX), [Key]), [Data], [RowVersion] FROM [%s].[%s] WHERE [Deleted] = 0 AND ([ExpireDate] IS NULL OR [ExpireDate] > GETDATE())", q.schema, q.tableName)
+	if filters != "" {
+		q.query += " AND " + filters
+	}
+
+	// OFFSET requires an ORDER BY clause, so results are sorted by key if no other sorting is requested
+	orderBy := make([]string, len(qq.Sort))
+	for i, sortItem := range qq.Sort {
+		field, err := q.translateField(sortItem.Key)
+		if err != nil {
+			return err
+		}
+		switch strings.ToUpper(sortItem.Order) {
+		case "", query.ASC:
+			orderBy[i] = field + " ASC"
+		case query.DESC:
+			orderBy[i] = field + " DESC"
+		default:
+			return fmt.Errorf("invalid sorting order %q for key %q", sortItem.Order, sortItem.Key)
+		}
+	}
+	if len(orderBy) == 0 && (qq.Page.Limit > 0 || qq.Page.Token != "") {
+		orderBy = append(orderBy, "[Key] ASC")
+	}
+	if len(orderBy) > 0 {
+		q.query += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+
+	var skip int64
+	if qq.Page.Token != "" {
+		var err error
+		skip, err = strconv.ParseInt(qq.Page.Token, 10, 64)
+		if err != nil || skip < 0 {
+			return fmt.Errorf("invalid page token %q", qq.Page.Token)
+		}
+		q.skip = &skip
+	}
+	if qq.Page.Limit > 0 || q.skip != nil {
+		q.query += " OFFSET " + strconv.FormatInt(skip, 10) + " ROWS"
+	}
+	if qq.Page.Limit > 0 {
+		q.query += " FETCH NEXT " + strconv.Itoa(qq.Page.Limit) + " ROWS ONLY"
+		q.limit = qq.Page.Limit
+	}
+
+	return nil
+}
+
+func (q *Query) execute(ctx context.Context, db *sql.DB) ([]state.QueryItem, string, error) {
+	ret := []state.QueryItem{}
+	err := q.iterate(ctx, db, func(item state.QueryItem) error {
+		ret = append(ret, item)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var token string
+	if q.limit != 0 {
+		token = strconv.FormatInt(q.offset()+int64(len(ret)), 10)
+	}
+
+	return ret, token, nil
+}
+
+// iterate executes the query and invokes fn for each result, as rows are read from the database.
+func (q *Query) iterate(ctx context.Context, db *sql.DB, fn func(item state.QueryItem) error) error {
+	rows, err := db.QueryContext(ctx, q.query, q.params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key        string
+			data       string
+			rowVersion []byte
+		)
+		if err = rows.Scan(&key, &data, &rowVersion); err != nil {
+			return err
+		}
+		err = fn(state.QueryItem{
+			Key:  key,
+			Data: []byte(data),
+			ETag: ptr.Of(hex.EncodeToString(rowVersion)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// offset returns the number of results skipped, from the token of the query.
+func (q *Query) offset() int64 {
+	if q.skip == nil {
+		return 0
+	}
+	return *q.skip
+}
+
+// translateField returns the expression for a field used in filters and sorting.
+// Properties that are indexed use their column, so the index can be used.
+func (q *Query) translateField(key string) (string, error) {
+	switch key {
+	case queryFieldInsertDate:
+		return "[" + insertDateColumnName + "]", nil
+	case queryFieldUpdateDate:
+		return "[" + updateDateColumnName + "]", nil
+	}
+
+	for _, ix := range q.indexedProperties {
+		if ix.Property == key {
+			return "[" + ix.ColumnName + "]", nil
+		}
+	}
+
+	// The path is validated as it's included in the query
+	path := "$." + key
+	if !jsonPathRegex.MatchString(path) {
+		return "", fmt.Errorf("invalid query key %q", key)
+	}
+	// JSON_VALUE fails on values that aren't valid JSON, so they are treated as null
+	return "JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '" + path + "')", nil
+}
+
+func (q *Query) whereFieldEqual(key string, value any) (string, error) {
+	field, err := q.translateField(key)
+	if err != nil {
+		return "", err
+	}
+
+	name := "p" + strconv.Itoa(len(q.params)+1)
+	q.params = append(q.params, sql.Named(name, fmt.Sprintf("%v", value)))
+	return field + " = @" + name, nil
+}
+
+// Query executes a query against the store.
+func (s *SQLServer) Query(parentCtx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
+	if s.columnEncryption != nil {
+		return nil, errColumnEncryptionUnsupported
+	}
+	if !statecodec.IsJSON(s.codec) {
+		return nil, errSerializerUnsupported
+	}
+
+	s, err := s.storeFor(parentCtx, req.Metadata)
+	if err != nil {
+		return &state.QueryResponse{}, err
+	}
+
+	q, err := s.buildQuery(req)
+	if err != nil {
+		return &state.QueryResponse{}, err
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+	data, token, err := q.execute(ctx, s.db)
+	if err != nil {
+		return &state.QueryResponse{}, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	return &state.QueryResponse{
+		Results: data,
+		Token:   token,
+	}, nil
+}
+
+// QueryStream executes a query against the store, invoking fn with each page of results. Implements StreamingQuerier.
+// Rows are read from the database as pages are consumed, from a single execution of the query.
+// The query timeout doesn't apply, as the duration of the stream depends on the callback; the stream is bound by the context.
+func (s *SQLServer) QueryStream(ctx context.Context, req *state.QueryRequest, fetchSize int, fn state.QueryPageFn) error {
+	if s.columnEncryption != nil {
+		return errColumnEncryptionUnsupported
+	}
+	if !statecodec.IsJSON(s.codec) {
+		return errSerializerUnsupported
+	}
+
+	s, err := s.storeFor(ctx, req.Metadata)
+	if err != nil {
+		return err
+	}
+
+	q, err := s.buildQuery(req)
+	if err != nil {
+		return err
+	}
+
+	pager := state.NewQueryPager(fetchSize, q.offset(), fn)
+	err = q.iterate(ctx, s.db, pager.Add)
+	if err != nil {
+		return err
+	}
+	return pager.Flush()
+}
+
+// buildQuery returns the SQL query for a request of the Query API.
+func (s *SQLServer) buildQuery(req *state.QueryRequest) (*Query, error) {
+	q := &Query{
+		params:            []any{},
+		schema:            s.schema,
+		tableName:         s.tableName,
+		indexedProperties: s.indexedProperties,
+	}
+	qbuilder := query.NewQueryBuilder(q)
+	if err := qbuilder.BuildQuery(&req.Query); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
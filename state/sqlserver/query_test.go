@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+const testQuerySelect = "SELECT CONVERT(NVARCHAR(MAX), [Key]), [Data], [RowVersion] FROM [dbo].[state] WHERE [Deleted] = 0 AND ([ExpireDate] IS NULL OR [ExpireDate] > GETDATE())"
+
+func TestQueryBuildQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		query string
+	}{
+		{
+			input: "../../tests/state/query/q1.json",
+			query: testQuerySelect + " ORDER BY [Key] ASC OFFSET 0 ROWS FETCH NEXT 2 ROWS ONLY",
+		},
+		{
+			input: "../../tests/state/query/q2.json",
+			query: testQuerySelect + " AND JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '$.state') = @p1 ORDER BY [Key] ASC OFFSET 0 ROWS FETCH NEXT 2 ROWS ONLY",
+		},
+		{
+			input: "../../tests/state/query/q2-token.json",
+			query: testQuerySelect + " AND JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '$.state') = @p1 ORDER BY [Key] ASC OFFSET 2 ROWS FETCH NEXT 2 ROWS ONLY",
+		},
+		{
+			input: "../../tests/state/query/q3.json",
+			query: testQuerySelect + " AND (JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '$.person.org') = @p1 AND (JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '$.state') = @p2 OR JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '$.state') = @p3)) ORDER BY JSON_VALUE(CASE WHEN ISJSON([Data]) = 1 THEN [Data] END, '$.state') DESC, [PersonName] ASC",
+		},
+	}
+	for _, test := range tests {
+		data, err := os.ReadFile(test.input)
+		require.NoError(t, err)
+		var qq query.Query
+		err = json.Unmarshal(data, &qq)
+		require.NoError(t, err)
+
+		q := &Query{
+			schema:    defaultSchema,
+			tableName: defaultTable,
+			indexedProperties: []IndexedProperty{
+				{ColumnName: "PersonName", Property: "person.name"},
+			},
+		}
+		qbuilder := query.NewQueryBuilder(q)
+		err = qbuilder.BuildQuery(&qq)
+		require.NoError(t, err, test.input)
+		assert.Equal(t, test.query, q.query, test.input)
+	}
+}
+
+func parseTestQuery(t *testing.T, data string) *query.Query {
+	t.Helper()
+	var qq query.Query
+	err := json.Unmarshal([]byte(data), &qq)
+	require.NoError(t, err)
+	return &qq
+}
+
+func TestQueryDateFields(t *testing.T) {
+	q := &Query{
+		schema:    defaultSchema,
+		tableName: defaultTable,
+	}
+	qbuilder := query.NewQueryBuilder(q)
+	err := qbuilder.BuildQuery(parseTestQuery(t, `{
+		"filter": {"EQ": {"_insertDate": "2023-04-01T10:00:00"}},
+		"sort": [{"key": "_updateDate", "order": "DESC"}],
+		"page": {"limit": 10}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, testQuerySelect+" AND [InsertDate] = @p1 ORDER BY [UpdateDate] DESC OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY", q.query)
+	assert.Equal(t, []any{sql.Named("p1", "2023-04-01T10:00:00")}, q.params)
+}
+
+func TestQueryInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"invalid key":      `{"filter": {"EQ": {"a'; DROP TABLE state; --": "x"}}}`,
+		"invalid sort key": `{"sort": [{"key": "a b"}]}`,
+		"invalid order":    `{"sort": [{"key": "a", "order": "SIDEWAYS"}]}`,
+		"invalid token":    `{"page": {"limit": 2, "token": "abc"}}`,
+	} {
+		q := &Query{
+			schema:    defaultSchema,
+			tableName: defaultTable,
+		}
+		err := query.NewQueryBuilder(q).BuildQuery(parseTestQuery(t, data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestQueryExecute(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlStore := &SQLServer{
+		logger:    logger.NewLogger("test"),
+		db:        db,
+		schema:    defaultSchema,
+		tableName: defaultTable,
+	}
+
+	mock.ExpectQuery(`ORDER BY \[UpdateDate\] DESC OFFSET 0 ROWS FETCH NEXT 2 ROWS ONLY`).
+		WillReturnRows(sqlmock.NewRows([]string{"Key", "Data", "RowVersion"}).
+			AddRow("k2", `{"a":2}`, []byte{0, 0, 0, 0, 0, 0, 0, 2}).
+			AddRow("k1", `{"a":1}`, []byte{0, 0, 0, 0, 0, 0, 0, 1}))
+
+	res, err := sqlStore.Query(context.Background(), &state.QueryRequest{
+		Query: *parseTestQuery(t, `{"sort": [{"key": "_updateDate", "order": "DESC"}], "page": {"limit": 2}}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []state.QueryItem{
+		{Key: "k2", Data: []byte(`{"a":2}`), ETag: ptr.Of("0000000000000002")},
+		{Key: "k1", Data: []byte(`{"a":1}`), ETag: ptr.Of("0000000000000001")},
+	}, res.Results)
+	assert.Equal(t, "2", res.Token)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryStream(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlStore := &SQLServer{
+		logger:    logger.NewLogger("test"),
+		db:        db,
+		schema:    defaultSchema,
+		tableName: defaultTable,
+	}
+
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"Key", "Data", "RowVersion"}).
+			AddRow("k1", `{"a":1}`, []byte{1}).
+			AddRow("k2", `{"a":2}`, []byte{2}).
+			AddRow("k3", `{"a":3}`, []byte{3})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		mock.ExpectQuery(`ORDER BY \[InsertDate\] ASC`).WillReturnRows(newRows())
+
+		var pages []*state.QueryResponse
+		err := sqlStore.QueryStream(context.Background(), &state.QueryRequest{
+			Query: *parseTestQuery(t, `{"sort": [{"key": "_insertDate"}]}`),
+		}, 2, func(page *state.QueryResponse) error {
+			pages = append(pages, page)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, pages, 2)
+		assert.Equal(t, []string{"k1", "k2"}, []string{pages[0].Results[0].Key, pages[0].Results[1].Key})
+		assert.Equal(t, "2", pages[0].Token)
+		require.Len(t, pages[1].Results, 1)
+		assert.Equal(t, "k3", pages[1].Results[0].Key)
+		assert.Equal(t, "3", pages[1].Token)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("callback error stops the stream", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT`).WillReturnRows(newRows())
+
+		calls := 0
+		err := sqlStore.QueryStream(context.Background(), &state.QueryRequest{
+			Query: *parseTestQuery(t, `{}`),
+		}, 1, func(page *state.QueryResponse) error {
+			calls++
+			return errors.New("stop")
+		})
+		require.EqualError(t, err, "stop")
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/state"
+)
+
+// reservationTableName returns the name of the table with the operations staged in reservations, in the schema of the state table.
+func (s *SQLServer) reservationTableName() string {
+	return s.tableName + "_Reservations"
+}
+
+// Reserve stages the operations in the reservation table, with one row per key.
+// Rows of expired reservations are replaced, so keys are never reserved for longer than the TTL, which is rounded up to the second.
+// Until the reservation ends, Set, Delete, Multi and the bulk operations on the reserved keys fail with an ETag mismatch.
+func (s *SQLServer) Reserve(parentCtx context.Context, req *state.ReserveRequest) error {
+	// Normalize the keys first, so keys that are the same after normalization are rejected
+	req = s.keyNormalizer.ReserveRequest(req)
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+	s, err = s.storeFor(parentCtx, req.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(parentCtx, s.txOptions)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//nolint:gosec
+	query := fmt.Sprintf(`MERGE [%[1]s].[%[2]s] WITH (HOLDLOCK) AS r
+USING (SELECT @Key AS [Key]) AS src ON r.[Key] = src.[Key]
+WHEN MATCHED AND (r.[ExpireDate] < GETDATE() OR (r.[ReservationID] = @ReservationID AND r.[Status] = '%[3]s')) THEN
+  UPDATE SET [ReservationID] = @ReservationID, [Status] = '%[3]s', [Operation] = @Operation, [ExpireDate] = DATEADD(SECOND, @TTL, GETDATE())
+WHEN NOT MATCHED THEN
+  INSERT ([Key], [ReservationID], [Status], [Operation], [ExpireDate]) VALUES (@Key, @ReservationID, '%[3]s', @Operation, DATEADD(SECOND, @TTL, GETDATE()));`,
+		s.schema, s.reservationTableName(), state.ReservationStatusReserved,
+	)
+	ttl := int64(math.Ceil(req.TTL.Seconds()))
+	for _, o := range req.Operations {
+		op, err := state.MarshalStagedOperation(o)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+		res, err := tx.ExecContext(ctx, query,
+			sql.Named(keyColumnName, o.GetKey()), sql.Named("ReservationID", req.ID),
+			sql.Named("Operation", string(op)), sql.Named("TTL", ttl),
+		)
+		if err != nil {
+			err = internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+			cancel()
+			return fmt.Errorf("failed to reserve key '%s': %w", o.GetKey(), err)
+		}
+		cancel()
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows != 1 {
+			return fmt.Errorf("failed to reserve key '%s': %w", o.GetKey(), state.ErrKeyReserved)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CommitReservation applies the staged operations and marks the reservation as committed, in a single transaction.
+// Committed reservations are kept until they expire, so committing again is a no-op.
+func (s *SQLServer) CommitReservation(ctx context.Context, id string, metadata map[string]string) error {
+	s, err := s.storeFor(ctx, metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, s.txOptions)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ops, committed, err := s.lockReservation(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if committed {
+		return nil
+	}
+
+	err = s.writeAuditLog(ctx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range ops {
+		switch req := o.(type) {
+		case state.SetRequest:
+			err = s.executeSet(ctx, tx, &req)
+		case state.DeleteRequest:
+			err = s.executeDelete(ctx, tx, &req)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply the operation on key '%s': %w", o.GetKey(), err)
+		}
+	}
+
+	//nolint:gosec
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE [%s].[%s] SET [Status] = '%s' WHERE [ReservationID] = @ReservationID`, s.schema, s.reservationTableName(), state.ReservationStatusCommitted),
+		sql.Named("ReservationID", id),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reservation: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// lockReservation locks the rows of a reservation that hasn't expired, and returns its staged operations, or true if it was already committed.
+func (s *SQLServer) lockReservation(parentCtx context.Context, tx *sql.Tx, id string) ([]state.TransactionalStateOperation, bool, error) {
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	//nolint:gosec
+	rows, err := tx.QueryContext(ctx,
+		fmt.Sprintf(`SELECT [Status], [Operation] FROM [%s].[%s] WITH (UPDLOCK, ROWLOCK) WHERE [ReservationID] = @ReservationID AND [ExpireDate] >= GETDATE() ORDER BY [Key]`, s.schema, s.reservationTableName()),
+		sql.Named("ReservationID", id),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read reservation: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+	}
+	defer rows.Close()
+
+	var ops []state.TransactionalStateOperation
+	for rows.Next() {
+		var status, op string
+		err = rows.Scan(&status, &op)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read reservation: %w", err)
+		}
+		if status == state.ReservationStatusCommitted {
+			return nil, true, nil
+		}
+		o, err := state.UnmarshalStagedOperation([]byte(op))
+		if err != nil {
+			return nil, false, err
+		}
+		ops = append(ops, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read reservation: %w", err)
+	}
+	if len(ops) == 0 {
+		return nil, false, state.ErrReservationNotFound
+	}
+	return ops, false, nil
+}
+
+// RollbackReservation deletes the rows of the reservation, releasing the keys.
+func (s *SQLServer) RollbackReservation(parentCtx context.Context, id string, metadata map[string]string) error {
+	s, err := s.storeFor(parentCtx, metadata)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	//nolint:gosec
+	_, err = s.db.ExecContext(ctx,
+		fmt.Sprintf(`DELETE FROM [%s].[%s] WHERE [ReservationID] = @ReservationID AND [Status] = '%s'`, s.schema, s.reservationTableName(), state.ReservationStatusReserved),
+		sql.Named("ReservationID", id),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to roll back reservation: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+	}
+
+	// Committed reservations are not deleted, so a rollback after a commit is reported
+	var committed bool
+	//nolint:gosec
+	err = s.db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT CAST(CASE WHEN EXISTS (SELECT * FROM [%s].[%s] WHERE [ReservationID] = @ReservationID AND [Status] = '%s' AND [ExpireDate] >= GETDATE()) THEN 1 ELSE 0 END AS BIT)`, s.schema, s.reservationTableName(), state.ReservationStatusCommitted),
+		sql.Named("ReservationID", id),
+	).Scan(&committed)
+	if err != nil {
+		return fmt.Errorf("failed to roll back reservation: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+	}
+	if committed {
+		return state.ErrReservationCommitted
+	}
+	return nil
+}
+
+// checkNotReserved returns an ETag mismatch wrapping state.ErrKeyReserved if any of the keys is reserved by a reservation that hasn't expired.
+// The rows of the keys in the reservation table are locked until tx ends, so the keys can't be reserved, and their reservations can't be committed, before the write in tx.
+func (s *SQLServer) checkNotReserved(parentCtx context.Context, tx *sql.Tx, ops []state.TransactionalStateOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ops))
+	for i, o := range ops {
+		keys[i] = o.GetKey()
+	}
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	var key string
+	//nolint:gosec
+	err = tx.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT TOP 1 [Key] FROM [%s].[%s] WITH (UPDLOCK, HOLDLOCK) WHERE [Key] IN (SELECT [value] FROM OPENJSON(@Keys)) AND [Status] = '%s' AND [ExpireDate] >= GETDATE()`, s.schema, s.reservationTableName(), state.ReservationStatusReserved),
+		sql.Named("Keys", string(keysJSON)),
+	).Scan(&key)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check the reservations of the keys: %w", internalsql.WrapQueryTimeoutError(parentCtx, ctx, err))
+	default:
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("key '%s': %w", key, state.ErrKeyReserved))
+	}
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func newReservationTestStore(t *testing.T) (*SQLServer, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return &SQLServer{
+		logger:                   logger.NewLogger("test"),
+		db:                       db,
+		schema:                   "dbo",
+		tableName:                "state",
+		upsertCommand:            "[dbo].sp_Upsert_v5_state",
+		deleteWithoutETagCommand: "DELETE [dbo].[state] WHERE [Key]=@Key",
+	}, mock
+}
+
+// expectNotReserved expects the transaction of a write to begin with the check that its keys aren't reserved, and no key to be reserved.
+func expectNotReserved(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM \[\w+\]\.\[state_Reservations\] WITH \(UPDLOCK, HOLDLOCK\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"Key"}))
+}
+
+func TestReserve(t *testing.T) {
+	req := &state.ReserveRequest{
+		ID:  "saga1",
+		TTL: 1500 * time.Millisecond,
+		Operations: []state.TransactionalStateOperation{
+			state.SetRequest{Key: "k1", Value: map[string]int{"n": 1}},
+			state.DeleteRequest{Key: "k2"},
+		},
+	}
+
+	t.Run("keys are reserved", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`MERGE \[dbo\]\.\[state_Reservations\]`).
+			WithArgs("k1", "saga1", `{"operation":"upsert","key":"k1","value":{"n":1}}`, int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`MERGE \[dbo\]\.\[state_Reservations\]`).
+			WithArgs("k2", "saga1", `{"operation":"delete","key":"k2"}`, int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.Reserve(context.Background(), req))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key reserved by another reservation", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`MERGE \[dbo\]\.\[state_Reservations\]`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := s.Reserve(context.Background(), req)
+		require.ErrorIs(t, err, state.ErrKeyReserved)
+		assert.ErrorContains(t, err, "'k1'")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCommitReservation(t *testing.T) {
+	t.Run("operations are applied", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \[Status\], \[Operation\] FROM \[dbo\]\.\[state_Reservations\] WITH \(UPDLOCK, ROWLOCK\)`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}).
+				AddRow(state.ReservationStatusReserved, `{"operation":"upsert","key":"k1","value":{"n":1}}`).
+				AddRow(state.ReservationStatusReserved, `{"operation":"delete","key":"k2"}`))
+		mock.ExpectExec(`\[dbo\]\.sp_Upsert_v5_state`).
+			WithArgs("k1", `{"n":1}`, nil, 0, nil, "json").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE \[dbo\]\.\[state\]`).
+			WithArgs("k2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE \[dbo\]\.\[state_Reservations\] SET \[Status\] = 'committed'`).
+			WithArgs("saga1").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.CommitReservation(context.Background(), "saga1", nil))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("operations are recorded in the audit log", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+		err := s.parseMetadata(map[string]string{
+			connectionStringKey: sampleConnectionString,
+			"auditLogSink":      "table",
+			"auditLogActorKey":  "user",
+		})
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \[Status\], \[Operation\]`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}).
+				AddRow(state.ReservationStatusReserved, `{"operation":"upsert","key":"k1","value":{"n":1},"metadata":{"user":"alice"}}`).
+				AddRow(state.ReservationStatusReserved, `{"operation":"delete","key":"k2"}`))
+		mock.ExpectExec(`INSERT INTO \[dbo\]\.\[dapr_state_audit\]`).
+			WithArgs("k1", "upsert", "alice", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO \[dbo\]\.\[dapr_state_audit\]`).
+			WithArgs("k2", "delete", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`\[dbo\]\.sp_Upsert_v5_state`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE \[dbo\]\.\[state\]`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE \[dbo\]\.\[state_Reservations\]`).
+			WithArgs("saga1").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.CommitReservation(context.Background(), "saga1", nil))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already committed", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \[Status\], \[Operation\]`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}).
+				AddRow(state.ReservationStatusCommitted, `{"operation":"delete","key":"k2"}`))
+		mock.ExpectRollback()
+
+		require.NoError(t, s.CommitReservation(context.Background(), "saga1", nil))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found or expired", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \[Status\], \[Operation\]`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}))
+		mock.ExpectRollback()
+
+		err := s.CommitReservation(context.Background(), "saga1", nil)
+		require.ErrorIs(t, err, state.ErrReservationNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ETag mismatch", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \[Status\], \[Operation\]`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}).
+				AddRow(state.ReservationStatusReserved, `{"operation":"upsert","key":"k1","value":{"n":1},"etag":"0005"}`))
+		mock.ExpectExec(`\[dbo\]\.sp_Upsert_v5_state`).
+			WithArgs("k1", `{"n":1}`, []byte{0, 5}, 0, nil, "json").
+			WillReturnError(errors.New("etag mismatch"))
+		mock.ExpectRollback()
+
+		err := s.CommitReservation(context.Background(), "saga1", nil)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRollbackReservation(t *testing.T) {
+	t.Run("reservation is deleted", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectExec(`DELETE FROM \[dbo\]\.\[state_Reservations\] WHERE \[ReservationID\] = @ReservationID AND \[Status\] = 'reserved'`).
+			WithArgs("saga1").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`SELECT CAST`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(false))
+
+		require.NoError(t, s.RollbackReservation(context.Background(), "saga1", nil))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already committed", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+
+		mock.ExpectExec(`DELETE FROM \[dbo\]\.\[state_Reservations\]`).
+			WithArgs("saga1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT CAST`).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(true))
+
+		err := s.RollbackReservation(context.Background(), "saga1", nil)
+		require.ErrorIs(t, err, state.ErrReservationCommitted)
+	})
+}
+
+func TestWritesOnReservedKeys(t *testing.T) {
+	expectReserved := func(mock sqlmock.Sqlmock, keys string, reserved string) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT TOP 1 \[Key\] FROM \[dbo\]\.\[state_Reservations\] WITH \(UPDLOCK, HOLDLOCK\)`).
+			WithArgs(keys).
+			WillReturnRows(sqlmock.NewRows([]string{"Key"}).AddRow(reserved))
+		mock.ExpectRollback()
+	}
+	requireReserved := func(t *testing.T, err error) {
+		t.Helper()
+		require.ErrorIs(t, err, state.ErrKeyReserved)
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	}
+
+	t.Run("set", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+		expectReserved(mock, `["k1"]`, "k1")
+
+		err := s.Set(context.Background(), &state.SetRequest{Key: "k1", Value: "v"})
+		requireReserved(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+		expectReserved(mock, `["k1"]`, "k1")
+
+		err := s.Delete(context.Background(), &state.DeleteRequest{Key: "k1"})
+		requireReserved(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+		expectReserved(mock, `["k0","k1"]`, "k1")
+
+		err := s.Multi(context.Background(), &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "k0", Value: "v"},
+				state.DeleteRequest{Key: "k1"},
+			},
+		})
+		requireReserved(t, err)
+		assert.ErrorContains(t, err, "'k1'")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keys that aren't reserved", func(t *testing.T) {
+		s, mock := newReservationTestStore(t)
+		expectNotReserved(mock)
+		mock.ExpectExec(`\[dbo\]\.sp_Upsert_v5_state`).
+			WithArgs("k2", `"v"`, nil, 0, nil, "json").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.Set(context.Background(), &state.SetRequest{Key: "k2", Value: "v"}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -20,13 +20,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 
 	mssql "github.com/denisenkom/go-mssqldb"
 
+	"github.com/dapr/components-contrib/internal/component/audit"
+	"github.com/dapr/components-contrib/internal/component/keynormalizer"
 	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/components-contrib/internal/component/statecodec"
+	internalutils "github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/utils"
@@ -66,17 +72,25 @@ const (
 )
 
 const (
-	connectionStringKey  = "connectionString"
-	tableNameKey         = "tableName"
-	metadataTableNameKey = "metadataTableName"
-	schemaKey            = "schema"
-	keyTypeKey           = "keyType"
-	keyLengthKey         = "keyLength"
-	indexedPropertiesKey = "indexedProperties"
-	keyColumnName        = "Key"
-	rowVersionColumnName = "RowVersion"
-	databaseNameKey      = "databaseName"
-	cleanupIntervalKey   = "cleanupIntervalInSeconds"
+	connectionStringKey        = "connectionString"
+	tableNameKey               = "tableName"
+	metadataTableNameKey       = "metadataTableName"
+	schemaKey                  = "schema"
+	keyTypeKey                 = "keyType"
+	keyLengthKey               = "keyLength"
+	indexedPropertiesKey       = "indexedProperties"
+	keyColumnName              = "Key"
+	rowVersionColumnName       = "RowVersion"
+	insertDateColumnName       = "InsertDate"
+	updateDateColumnName       = "UpdateDate"
+	deletedColumnName          = "Deleted"
+	deletedDateColumnName      = "DeletedDate"
+	databaseNameKey            = "databaseName"
+	cleanupIntervalKey         = "cleanupIntervalInSeconds"
+	queryTimeoutKey            = "queryTimeout"
+	tombstoneRetentionKey      = "tombstoneRetention"
+	changeTrackingRetentionKey = "changeTrackingRetention"
+	changeFeedPollIntervalKey  = "changeFeedPollInterval"
 
 	defaultKeyLength       = 200
 	defaultSchema          = "dbo"
@@ -84,12 +98,15 @@ const (
 	defaultTable           = "state"
 	defaultMetaTable       = "dapr_metadata"
 	defaultCleanupInterval = time.Hour
+
+	defaultChangeTrackingRetention = 48 * time.Hour
+	defaultChangeFeedPollInterval  = time.Second
 )
 
 // New creates a new instance of a SQL Server transaction store.
 func New(logger logger.Logger) state.Store {
 	s := &SQLServer{
-		features:        []state.Feature{state.FeatureETag, state.FeatureTransactional},
+		features:        []state.Feature{state.FeatureETag, state.FeatureTransactional, state.FeatureQueryAPI},
 		logger:          logger,
 		migratorFactory: newMigration,
 	}
@@ -109,6 +126,7 @@ type SQLServer struct {
 	state.BulkStore
 
 	connectionString  string
+	health            internalsql.HealthMetadata
 	databaseName      string
 	tableName         string
 	metaTableName     string
@@ -119,11 +137,55 @@ type SQLServer struct {
 	migratorFactory   func(*SQLServer) migrator
 
 	cleanupInterval *time.Duration
+	queryTimeout    time.Duration
+
+	// When softDelete is enabled, deleted rows are kept as tombstones, which are purged after tombstoneRetention if it's positive.
+	softDelete         bool
+	tombstoneRetention time.Duration
+
+	// When changeTracking is enabled, Init enables SQL Server Change Tracking on the state table, and changes can be read with GetChanges and SubscribeChanges.
+	changeTracking          bool
+	changeTrackingRetention time.Duration
+	changeFeedPollInterval  time.Duration
+	txOptions               *sql.TxOptions // nil to use the default isolation level
+
+	// When columnEncryption is set, the Data column is encrypted with Always Encrypted, so values are only readable by the driver.
+	columnEncryption *columnEncryption
+
+	jsonOptions utils.JSONOptions
+
+	// Codec used to serialize values; its name is stored with each value in the Serializer column
+	codec statecodec.Codec
+
+	// When tenants are configured, requests with the "tenant" metadata use the tables in the schema of the tenant
+	tenants map[string]*tenant
+	// Name of the tenant, in the store of a tenant
+	tenantName string
+
+	validateOnly     bool
+	validationReport *state.ValidationReport
+
+	warmupConnections int
+	lazyConnect       bool
+	// Set when lazyConnect is true, to perform the migrations and open the connections on the first operation
+	lazyInit *internalutils.LazyInit
+
+	// Audit log of the keys modified by Set, Delete and Multi, shared with the stores of the tenants
+	auditLog *audit.Holder
+	// Name of the table of the audit log, if the records are stored in the schema of the store
+	auditLogTable string
+
+	// Normalization of the keys of requests, disabled by default
+	keyNormalizer keynormalizer.Normalizer
+
+	// Performs the read-modify-write updates of SetWithMerge, retrying them on ETag conflicts as configured by the "etagRetry" metadata properties
+	etagRetrier *state.ETagRetrier
 
 	bulkDeleteCommand        string
 	itemRefTableTypeName     string
 	upsertCommand            string
 	getCommand               string
+	getJSONPathCommand       string
 	deleteWithETagCommand    string
 	deleteWithoutETagCommand string
 
@@ -134,7 +196,12 @@ type SQLServer struct {
 }
 
 type sqlServerMetadata struct {
-	ConnectionString  string
+	ConnectionString string
+	// Discrete connection properties, used to build the connection string if ConnectionString is empty
+	internalsql.ConnectionMetadata `mapstructure:",squash"`
+	// Validation of pooled connections before they're used
+	internalsql.HealthMetadata `mapstructure:",squash"`
+
 	DatabaseName      string
 	TableName         string
 	MetadataTableName string
@@ -142,6 +209,40 @@ type sqlServerMetadata struct {
 	KeyType           string
 	KeyLength         int
 	IndexedProperties string
+	QueryTimeout      time.Duration
+	ValidateOnly      bool
+	WarmupConnections int
+	LazyInit          bool
+
+	SoftDelete         bool
+	TombstoneRetention time.Duration
+
+	ChangeTracking          bool
+	ChangeTrackingRetention time.Duration
+	ChangeFeedPollInterval  time.Duration
+
+	TransactionIsolationLevel string
+
+	// Always Encrypted on the Data column
+	ColumnEncryption     bool
+	ColumnEncryptionKey  string
+	ColumnEncryptionType string
+	ColumnMasterKey      string
+
+	// Options of the JSON encoding of values
+	utils.JSONOptions `mapstructure:",squash"`
+
+	// Codec used to serialize values: "json" (the default), "msgpack", or "protobuf-passthrough"
+	Serializer string
+
+	// Schema of each tenant, as "tenant=schema" entries separated by commas
+	TenantSchemas string
+
+	// Audit log of the keys modified by Set, Delete and Multi
+	audit.Metadata `mapstructure:",squash"`
+
+	// Normalization of the keys of requests
+	KeyNormalizer keynormalizer.Metadata `mapstructure:",squash"`
 }
 
 func isLetterOrNumber(c rune) bool {
@@ -180,33 +281,119 @@ func isValidIndexedPropertyType(s string) bool {
 
 // Init initializes the SQL server state store.
 func (s *SQLServer) Init(ctx context.Context, metadata state.Metadata) error {
-	err := s.parseMetadata(metadata.Properties)
+	// Secret references are resolved before the connection string is built
+	props, err := metadata.ResolvedProperties(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.parseMetadata(props)
 	if err != nil {
 		return err
 	}
 
 	migration := s.migratorFactory(s)
-	mr, err := migration.executeMigrations(ctx)
+
+	// In validate-only mode, stop after planning the migrations
+	if s.validateOnly {
+		s.validationReport = &state.ValidationReport{}
+		err = migration.planMigrations(ctx, s.validationReport)
+		if err != nil {
+			return fmt.Errorf("failed to plan migrations: %w", err)
+		}
+		s.logger.Infof("Validation completed. Planned changes: %v. Missing permissions: %v", s.validationReport.PlannedChanges, s.validationReport.MissingPermissions)
+		return s.validationReport.Err()
+	}
+
+	// The migrations connect to the database, so with lazy init nothing else is done until the first operation
+	if s.lazyConnect {
+		s.lazyInit = internalutils.NewLazyInit(s.finishInit)
+		return nil
+	}
+
+	return s.finishInit(ctx)
+}
+
+// finishInit performs the migrations, opens the connection pool and starts the garbage collector.
+func (s *SQLServer) finishInit(ctx context.Context) error {
+	mr, err := s.migratorFactory(s).executeMigrations(ctx)
 	if err != nil {
 		return err
 	}
 
-	s.itemRefTableTypeName = mr.itemRefTableTypeName
-	s.bulkDeleteCommand = fmt.Sprintf("exec %s @itemsToDelete;", mr.bulkDeleteProcFullName)
-	s.upsertCommand = mr.upsertProcFullName
-	s.getCommand = mr.getCommand
-	s.deleteWithETagCommand = mr.deleteWithETagCommand
-	s.deleteWithoutETagCommand = mr.deleteWithoutETagCommand
+	s.applyMigrationResult(mr)
+
+	s.db, err = internalsql.OpenDB("sqlserver", s.connectionString, s.health)
+	if err != nil {
+		return err
+	}
 
-	s.db, err = sql.Open("sqlserver", s.connectionString)
+	err = s.warmup(ctx)
 	if err != nil {
 		return err
 	}
 
 	if s.cleanupInterval != nil {
-		gc, err := internalsql.ScheduleGarbageCollector(internalsql.GCOptions{
-			Logger: s.logger,
-			UpdateLastCleanupQuery: fmt.Sprintf(`BEGIN TRANSACTION;
+		s.gc, err = s.scheduleGarbageCollector()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// warmup opens and validates the number of connections set in "warmupConnections", which are then kept idle in the pool.
+func (s *SQLServer) warmup(ctx context.Context) error {
+	if s.warmupConnections <= 0 {
+		return nil
+	}
+
+	// By default, database/sql keeps only 2 idle connections
+	if s.warmupConnections > 2 {
+		s.db.SetMaxIdleConns(s.warmupConnections)
+	}
+
+	err := internalutils.WarmupConnections(ctx, s.warmupConnections, func(ctx context.Context) (func(), error) {
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = conn.PingContext(ctx)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return func() { conn.Close() }, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to warm up connections: %w", err)
+	}
+	return nil
+}
+
+// applyMigrationResult sets the statements that use the objects created by the migrations.
+func (s *SQLServer) applyMigrationResult(mr migrationResult) {
+	s.itemRefTableTypeName = mr.itemRefTableTypeName
+	s.upsertCommand = mr.upsertProcFullName
+	s.getCommand = mr.getCommand
+	s.getJSONPathCommand = mr.getJSONPathCommand
+	if s.softDelete {
+		s.bulkDeleteCommand = fmt.Sprintf("exec %s @itemsToDelete;", mr.bulkSoftDeleteProcFullName)
+		s.deleteWithETagCommand = mr.softDeleteWithETagCommand
+		s.deleteWithoutETagCommand = mr.softDeleteWithoutETagCommand
+	} else {
+		s.bulkDeleteCommand = fmt.Sprintf("exec %s @itemsToDelete;", mr.bulkDeleteProcFullName)
+		s.deleteWithETagCommand = mr.deleteWithETagCommand
+		s.deleteWithoutETagCommand = mr.deleteWithoutETagCommand
+	}
+}
+
+// scheduleGarbageCollector starts the garbage collector of the tables in the schema of the store.
+func (s *SQLServer) scheduleGarbageCollector() (internalsql.GarbageCollector, error) {
+	return internalsql.ScheduleGarbageCollector(internalsql.GCOptions{
+		Logger: s.logger,
+		UpdateLastCleanupQuery: fmt.Sprintf(`BEGIN TRANSACTION;
 BEGIN TRY
   INSERT INTO [%[1]s].[%[2]s] ([Key], [Value]) VALUES ('last-cleanup', CONVERT(nvarchar(MAX), GETDATE(), 21));
 END TRY
@@ -214,21 +401,31 @@ BEGIN CATCH
   UPDATE [%[1]s].[%[2]s] SET [Value] = CONVERT(nvarchar(MAX), GETDATE(), 21) WHERE [Key] = 'last-cleanup' AND Datediff_big(MS, [Value], GETUTCDATE()) > @Interval
 END CATCH
 COMMIT TRANSACTION;`, s.schema, s.metaTableName),
-			UpdateLastCleanupQueryParameterName: "Interval",
-			DeleteExpiredValuesQuery: fmt.Sprintf(
-				`DELETE FROM [%s].[%s] WHERE [ExpireDate] IS NOT NULL AND [ExpireDate] < GETDATE()`,
-				s.schema, s.tableName,
-			),
-			CleanupInterval: *s.cleanupInterval,
-			DBSql:           s.db,
-		})
-		if err != nil {
-			return err
-		}
-		s.gc = gc
+		UpdateLastCleanupQueryParameterName: "Interval",
+		DeleteExpiredValuesQuery:            s.deleteExpiredValuesQuery(),
+		CleanupInterval:                     *s.cleanupInterval,
+		DBSql:                               s.db,
+	})
+}
+
+// deleteExpiredValuesQuery returns the query used by the garbage collector, which deletes expired reservations and expired rows and, if a tombstone retention is configured, the tombstones older than that.
+func (s *SQLServer) deleteExpiredValuesQuery() string {
+	deleteReservations := fmt.Sprintf(
+		`DELETE FROM [%s].[%s] WHERE [ExpireDate] < GETDATE(); `,
+		s.schema, s.reservationTableName(),
+	)
+
+	if s.tombstoneRetention <= 0 {
+		return deleteReservations + fmt.Sprintf(
+			`DELETE FROM [%s].[%s] WHERE [ExpireDate] IS NOT NULL AND [ExpireDate] < GETDATE()`,
+			s.schema, s.tableName,
+		)
 	}
 
-	return nil
+	return deleteReservations + fmt.Sprintf(
+		`DELETE FROM [%s].[%s] WHERE ([ExpireDate] IS NOT NULL AND [ExpireDate] < GETDATE()) OR ([Deleted] = 1 AND [DeletedDate] < DATEADD(SECOND, -%d, GETDATE()))`,
+		s.schema, s.tableName, int64(s.tombstoneRetention.Seconds()),
+	)
 }
 
 func (s *SQLServer) parseMetadata(meta map[string]string) error {
@@ -238,15 +435,23 @@ func (s *SQLServer) parseMetadata(meta map[string]string) error {
 		DatabaseName:      defaultDatabase,
 		KeyLength:         defaultKeyLength,
 		MetadataTableName: defaultMetaTable,
+
+		ChangeTrackingRetention: defaultChangeTrackingRetention,
+		ChangeFeedPollInterval:  defaultChangeFeedPollInterval,
 	}
 	err := metadata.DecodeMetadata(meta, &m)
 	if err != nil {
 		return err
 	}
+	m.ConnectionString, err = m.ConnectionMetadata.ConnectionString(m.ConnectionString, buildConnectionString)
+	if err != nil {
+		return err
+	}
 	if m.ConnectionString == "" {
 		return fmt.Errorf("missing connection string")
 	}
 	s.connectionString = m.ConnectionString
+	s.health = m.HealthMetadata
 
 	if err := s.setTable(m.TableName); err != nil {
 		return err
@@ -272,6 +477,99 @@ func (s *SQLServer) parseMetadata(meta map[string]string) error {
 		return err
 	}
 
+	s.tenants, err = parseTenantSchemas(m.TenantSchemas)
+	if err != nil {
+		return err
+	}
+
+	s.columnEncryption, err = parseColumnEncryption(m)
+	if err != nil {
+		return err
+	}
+	if s.columnEncryption != nil {
+		s.connectionString = connStringWithColumnEncryption(s.connectionString)
+		// The Query API filters on the values on the server
+		s.features = []state.Feature{state.FeatureETag, state.FeatureTransactional}
+	}
+
+	s.codec, err = parseSerializer(m)
+	if err != nil {
+		return err
+	}
+	if !statecodec.IsJSON(s.codec) {
+		// The Query API reads the values as JSON on the server
+		s.features = []state.Feature{state.FeatureETag, state.FeatureTransactional}
+	}
+
+	s.validateOnly = m.ValidateOnly
+	s.jsonOptions = m.JSONOptions
+
+	if m.WarmupConnections < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", internalutils.WarmupConnectionsKey)
+	}
+	if m.LazyInit && m.ValidateOnly {
+		return fmt.Errorf("metadata property '%s' can't be used with '%s'", internalutils.LazyInitKey, state.ValidateOnlyKey)
+	}
+	s.warmupConnections = m.WarmupConnections
+	s.lazyConnect = m.LazyInit
+
+	err = m.Metadata.Validate()
+	if err != nil {
+		return err
+	}
+	s.auditLogTable = ""
+	if m.InTable() {
+		if !isValidSQLName(m.AuditLogTable) {
+			return fmt.Errorf("invalid audit log table name, accepted characters are (A-Z, a-z, 0-9, _)")
+		}
+		s.auditLogTable = m.AuditLogTable
+	}
+	if s.auditLog == nil {
+		s.auditLog = &audit.Holder{}
+	}
+	s.auditLog.Init(m.Metadata, "sqlserver||"+s.schema+"||"+s.tableName)
+
+	s.keyNormalizer, err = keynormalizer.New(m.KeyNormalizer)
+	if err != nil {
+		return err
+	}
+
+	etagRetryConfig, err := state.ParseETagRetryConfig(meta)
+	if err != nil {
+		return err
+	}
+	s.etagRetrier = state.NewETagRetrier(s, etagRetryConfig)
+
+	if m.QueryTimeout < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", queryTimeoutKey)
+	}
+	s.queryTimeout = m.QueryTimeout
+
+	if m.TombstoneRetention < 0 {
+		return fmt.Errorf("invalid value for '%s': must not be negative", tombstoneRetentionKey)
+	}
+	s.softDelete = m.SoftDelete
+	s.tombstoneRetention = m.TombstoneRetention
+
+	if m.ChangeTrackingRetention < time.Minute {
+		return fmt.Errorf("invalid value for '%s': must be at least 1 minute", changeTrackingRetentionKey)
+	}
+	if m.ChangeFeedPollInterval <= 0 {
+		return fmt.Errorf("invalid value for '%s': must be positive", changeFeedPollIntervalKey)
+	}
+	s.changeTracking = m.ChangeTracking
+	s.changeTrackingRetention = m.ChangeTrackingRetention
+	s.changeFeedPollInterval = m.ChangeFeedPollInterval
+
+	isoLevel, err := internalsql.ParseIsolationLevel(m.TransactionIsolationLevel)
+	if err != nil {
+		return err
+	}
+	s.txOptions = nil
+	if isoLevel != sql.LevelDefault {
+		s.txOptions = &sql.TxOptions{Isolation: isoLevel}
+	}
+
 	// Cleanup interval
 	if v := meta[cleanupIntervalKey]; v != "" {
 		cleanupIntervalInSec, err := strconv.ParseInt(v, 10, 0)
@@ -380,6 +678,33 @@ func (s *SQLServer) setSchema(schemaName string) error {
 	return nil
 }
 
+// buildConnectionString returns a connection string in the URL format from the discrete connection properties, as the ADO format doesn't allow escaping values.
+// The host can include the name of an instance, as in "host\instance".
+func buildConnectionString(m internalsql.ConnectionMetadata) string {
+	u := url.URL{
+		Scheme: "sqlserver",
+		Host:   m.Host,
+	}
+	if host, instance, ok := strings.Cut(m.Host, `\`); ok {
+		u.Host = host
+		u.Path = "/" + instance
+	}
+	if strings.Contains(u.Host, ":") {
+		// IPv6 address
+		u.Host = "[" + u.Host + "]"
+	}
+	if m.Port > 0 {
+		u.Host += ":" + strconv.Itoa(m.Port)
+	}
+	if m.User != "" {
+		u.User = url.UserPassword(m.User, m.Password)
+	}
+	if m.Database != "" {
+		u.RawQuery = url.Values{"database": []string{m.Database}}.Encode()
+	}
+	return u.String()
+}
+
 // Returns the database name if set or the default value otherwise.
 func (s *SQLServer) setDatabase(databaseName string) error {
 	if !isValidSQLName(databaseName) {
@@ -417,14 +742,53 @@ func (s *SQLServer) Features() []state.Feature {
 	return s.features
 }
 
+// SetKeyNormalizer sets the function that normalizes the keys of requests. Implements state.KeyNormalizerSetter.
+func (s *SQLServer) SetKeyNormalizer(fn state.KeyNormalizeFn) error {
+	s.keyNormalizer = keynormalizer.FromFunc(fn)
+	return nil
+}
+
+// Ping checks that the database is reachable.
+// The connection used for the round-trip is returned to the pool as soon as it completes, and the context's deadline is honored.
+func (s *SQLServer) Ping(ctx context.Context) error {
+	if err := s.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+	if s.db == nil {
+		return errors.New("sqlserver: not initialized")
+	}
+
+	return s.db.PingContext(ctx)
+}
+
+// ValidationReport returns the report created by Init in validate-only mode, or nil if the state store wasn't initialized in that mode.
+func (s *SQLServer) ValidationReport() *state.ValidationReport {
+	return s.validationReport
+}
+
 // Multi performs multiple updates on a Sql server store.
 func (s *SQLServer) Multi(ctx context.Context, request *state.TransactionalStateRequest) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	request = s.keyNormalizer.TransactionalStateRequest(request)
+	s, err := s.storeFor(ctx, request.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, s.txOptions)
 	defer tx.Rollback()
 	if err != nil {
 		return err
 	}
 
+	err = s.checkNotReserved(ctx, tx, request.Operations)
+	if err != nil {
+		return err
+	}
+	err = s.writeAuditLog(ctx, tx, request.Operations...)
+	if err != nil {
+		return err
+	}
+
 	for _, o := range request.Operations {
 		switch req := o.(type) {
 		case state.SetRequest:
@@ -449,10 +813,20 @@ func (s *SQLServer) Multi(ctx context.Context, request *state.TransactionalState
 
 // Delete removes an entity from the store.
 func (s *SQLServer) Delete(ctx context.Context, req *state.DeleteRequest) error {
-	return s.executeDelete(ctx, s.db, req)
+	req = s.keyNormalizer.DeleteRequest(req)
+	s, err := s.storeFor(ctx, req.Metadata)
+	if err != nil {
+		return err
+	}
+	return s.execAudited(ctx, *req, func(db dbExecutor) error {
+		return s.executeDelete(ctx, db, req)
+	})
 }
 
-func (s *SQLServer) executeDelete(ctx context.Context, db dbExecutor, req *state.DeleteRequest) error {
+func (s *SQLServer) executeDelete(parentCtx context.Context, db dbExecutor, req *state.DeleteRequest) error {
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
 	var err error
 	var res sql.Result
 	if req.ETag != nil {
@@ -469,7 +843,7 @@ func (s *SQLServer) executeDelete(ctx context.Context, db dbExecutor, req *state
 
 	// err represents errors thrown by the stored procedure or the database itself
 	if err != nil {
-		return err
+		return internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
 	}
 
 	// if the row with matching key (and ETag if specified) is not found, then the stored procedure returns 0 rows affected
@@ -487,6 +861,33 @@ func (s *SQLServer) executeDelete(ctx context.Context, db dbExecutor, req *state
 	return nil
 }
 
+// PurgeTombstones permanently removes the rows that were soft-deleted more than olderThan ago, and returns the number of rows removed.
+// Tombstones are only created when the state store is configured with "softDelete".
+// The metadata selects the tenant whose tombstones are purged.
+func (s *SQLServer) PurgeTombstones(parentCtx context.Context, olderThan time.Duration, metadata map[string]string) (int64, error) {
+	if olderThan < 0 {
+		return 0, errors.New("the age of the tombstones to purge must not be negative")
+	}
+	s, err := s.storeFor(parentCtx, metadata)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	//nolint:gosec
+	res, err := s.db.ExecContext(ctx,
+		fmt.Sprintf(`DELETE FROM [%s].[%s] WHERE [Deleted] = 1 AND [DeletedDate] < DATEADD(SECOND, -@Age, GETDATE())`, s.schema, s.tableName),
+		sql.Named("Age", int64(olderThan.Seconds())),
+	)
+	if err != nil {
+		return 0, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	return res.RowsAffected()
+}
+
 // TvpDeleteTableStringKey defines a table type with string key.
 type TvpDeleteTableStringKey struct {
 	ID         string
@@ -495,12 +896,28 @@ type TvpDeleteTableStringKey struct {
 
 // BulkDelete removes multiple entries from the store.
 func (s *SQLServer) BulkDelete(ctx context.Context, req []state.DeleteRequest) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	req = s.keyNormalizer.DeleteRequests(req)
+	s, err := storeForRequests(ctx, s, req)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, s.txOptions)
 	defer tx.Rollback()
 	if err != nil {
 		return err
 	}
 
+	ops := bulkOperations(req)
+	err = s.checkNotReserved(ctx, tx, ops)
+	if err != nil {
+		return err
+	}
+	err = s.writeAuditLog(ctx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
 	err = s.executeBulkDelete(ctx, tx, req)
 	if err != nil {
 		return err
@@ -509,7 +926,7 @@ func (s *SQLServer) BulkDelete(ctx context.Context, req []state.DeleteRequest) e
 	return tx.Commit()
 }
 
-func (s *SQLServer) executeBulkDelete(ctx context.Context, db dbExecutor, req []state.DeleteRequest) error {
+func (s *SQLServer) executeBulkDelete(parentCtx context.Context, db dbExecutor, req []state.DeleteRequest) error {
 	values := make([]TvpDeleteTableStringKey, len(req))
 	for i, d := range req {
 		var etag []byte
@@ -528,9 +945,12 @@ func (s *SQLServer) executeBulkDelete(ctx context.Context, db dbExecutor, req []
 		Value:    values,
 	}
 
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
 	res, err := db.ExecContext(ctx, s.bulkDeleteCommand, sql.Named("itemsToDelete", itemsToDelete))
 	if err != nil {
-		return err
+		return internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
 	}
 
 	rows, err := res.RowsAffected()
@@ -546,25 +966,47 @@ func (s *SQLServer) executeBulkDelete(ctx context.Context, db dbExecutor, req []
 }
 
 // Get returns an entity from store.
-func (s *SQLServer) Get(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
-	rows, err := s.db.QueryContext(ctx, s.getCommand, sql.Named(keyColumnName, req.Key))
+func (s *SQLServer) Get(parentCtx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	req = s.keyNormalizer.GetRequest(req)
+	s, err := s.storeFor(parentCtx, req.Metadata)
 	if err != nil {
 		return nil, err
 	}
 
+	if path := req.Metadata[jsonPathMetadataKey]; path != "" && path != "$" {
+		return s.getJSONPath(parentCtx, req, path)
+	}
+
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.getCommand, sql.Named(keyColumnName, req.Key))
+	if err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
 	if rows.Err() != nil {
-		return nil, rows.Err()
+		return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, rows.Err())
 	}
 
 	defer rows.Close()
 
 	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, rows.Err())
+		}
 		return &state.GetResponse{}, nil
 	}
 
 	var data string
 	var rowVersion []byte
-	err = rows.Scan(&data, &rowVersion)
+	var serializer sql.NullString
+	err = rows.Scan(&data, &rowVersion, &serializer)
+	if err != nil {
+		return nil, internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+	}
+
+	value, err := decodeValue(data, serializer)
 	if err != nil {
 		return nil, err
 	}
@@ -572,14 +1014,27 @@ func (s *SQLServer) Get(ctx context.Context, req *state.GetRequest) (*state.GetR
 	etag := hex.EncodeToString(rowVersion)
 
 	return &state.GetResponse{
-		Data: []byte(data),
+		Data: value,
 		ETag: ptr.Of(etag),
 	}, nil
 }
 
 // Set adds/updates an entity on store.
 func (s *SQLServer) Set(ctx context.Context, req *state.SetRequest) error {
-	return s.executeSet(ctx, s.db, req)
+	req = s.keyNormalizer.SetRequest(req)
+	s, err := s.storeFor(ctx, req.Metadata)
+	if err != nil {
+		return err
+	}
+	return s.execAudited(ctx, *req, func(db dbExecutor) error {
+		return s.executeSet(ctx, db, req)
+	})
+}
+
+// SetWithMerge updates a key with the value returned by merge, retrying on ETag conflicts. Implements state.MergeSetter.
+// The tenant in the metadata is resolved by Get and Set, which receive the metadata.
+func (s *SQLServer) SetWithMerge(ctx context.Context, key string, metadata map[string]string, merge state.MergeFn) error {
+	return s.etagRetrier.SetWithMerge(ctx, key, metadata, merge)
 }
 
 // dbExecutor implements a common functionality implemented by db or tx.
@@ -587,10 +1042,8 @@ type dbExecutor interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
-func (s *SQLServer) executeSet(ctx context.Context, db dbExecutor, req *state.SetRequest) error {
-	var err error
-	var bytes []byte
-	bytes, err = utils.Marshal(req.Value, json.Marshal)
+func (s *SQLServer) executeSet(parentCtx context.Context, db dbExecutor, req *state.SetRequest) error {
+	data, serializer, err := s.encodeValue(req.Value)
 	if err != nil {
 		return err
 	}
@@ -609,19 +1062,26 @@ func (s *SQLServer) executeSet(ctx context.Context, db dbExecutor, req *state.Se
 		return fmt.Errorf("error parsing TTL: %w", ttlerr)
 	}
 
+	ctx, cancel := internalsql.WithQueryTimeout(parentCtx, s.queryTimeout)
+	defer cancel()
+
 	var res sql.Result
 	if req.Options.Concurrency == state.FirstWrite {
 		res, err = db.ExecContext(ctx, s.upsertCommand, sql.Named(keyColumnName, req.Key),
-			sql.Named("Data", string(bytes)), etag,
-			sql.Named("FirstWrite", 1), sql.Named("TTL", ttl))
+			sql.Named("Data", data), etag,
+			sql.Named("FirstWrite", 1), sql.Named("TTL", ttl), sql.Named(serializerColumnName, serializer))
 	} else {
 		res, err = db.ExecContext(ctx, s.upsertCommand, sql.Named(keyColumnName, req.Key),
-			sql.Named("Data", string(bytes)), etag,
-			sql.Named("FirstWrite", 0), sql.Named("TTL", ttl))
+			sql.Named("Data", data), etag,
+			sql.Named("FirstWrite", 0), sql.Named("TTL", ttl), sql.Named(serializerColumnName, serializer))
 	}
 
 	if err != nil {
-		if req.ETag != nil && *req.ETag != "" {
+		err = internalsql.WrapQueryTimeoutError(parentCtx, ctx, err)
+		if req.ETag != nil && *req.ETag != "" && !errors.Is(err, internalsql.ErrQueryTimeout) {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
+		if req.Options.Concurrency == state.FirstWrite && isFirstWriteConflict(err) {
 			return state.NewETagError(state.ETagMismatch, err)
 		}
 
@@ -640,14 +1100,36 @@ func (s *SQLServer) executeSet(ctx context.Context, db dbExecutor, req *state.Se
 	return nil
 }
 
+// isFirstWriteConflict returns true if the upsert procedure rejected a first write because the key exists.
+func isFirstWriteConflict(err error) bool {
+	var sqlErr mssql.Error
+	return errors.As(err, &sqlErr) && sqlErr.Number == 2601
+}
+
 // BulkSet adds/updates multiple entities on store.
 func (s *SQLServer) BulkSet(ctx context.Context, req []state.SetRequest) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	req = s.keyNormalizer.SetRequests(req)
+	s, err := storeForRequests(ctx, s, req)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, s.txOptions)
 	defer tx.Rollback()
 	if err != nil {
 		return err
 	}
 
+	ops := bulkOperations(req)
+	err = s.checkNotReserved(ctx, tx, ops)
+	if err != nil {
+		return err
+	}
+	err = s.writeAuditLog(ctx, tx, ops...)
+	if err != nil {
+		return err
+	}
+
 	for i := range req {
 		err = s.executeSet(ctx, tx, &req[i])
 		if err != nil {
@@ -664,6 +1146,11 @@ func (s *SQLServer) GetComponentMetadata() map[string]string {
 
 // Close implements io.Closer.
 func (s *SQLServer) Close() error {
+	err := s.closeTenants()
+	if err != nil {
+		s.logger.Warnf("Failed to stop the garbage collectors of the tenants: %v", err)
+	}
+
 	if s.db != nil {
 		s.db.Close()
 		s.db = nil
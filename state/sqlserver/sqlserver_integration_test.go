@@ -81,6 +81,7 @@ func TestIntegrationCases(t *testing.T) {
 	t.Run("Bulk sets", testBulkSet)
 	t.Run("Bulk delete", testBulkDelete)
 	t.Run("Insert and Update Set Record Dates", testInsertAndUpdateSetRecordDates)
+	t.Run("Soft delete", testSoftDelete)
 	t.Run("Multiple initializations", testMultipleInitializations)
 
 	// Run concurrent set tests 10 times
@@ -747,6 +748,49 @@ func testInsertAndUpdateSetRecordDates(t *testing.T) {
 	})
 }
 
+func testSoftDelete(t *testing.T) {
+	schema := getUniqueDBSchema()
+	metadata := createMetadata(schema, StringKeyType, "")
+	metadata.Properties["softDelete"] = "true"
+	store := &SQLServer{
+		logger: logger.NewLogger("test"),
+	}
+	err := store.Init(context.Background(), metadata)
+	require.NoError(t, err)
+
+	u := user{"1", "John", "Coffee"}
+	err = store.Set(context.Background(), &state.SetRequest{Key: u.ID, Value: u})
+	require.NoError(t, err)
+	_, etag := assertUserExists(t, store, u.ID)
+
+	// Deleting keeps the row as a tombstone, which is not returned anymore
+	err = store.Delete(context.Background(), &state.DeleteRequest{Key: u.ID, ETag: &etag})
+	require.NoError(t, err)
+	res, err := store.Get(context.Background(), &state.GetRequest{Key: u.ID})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+	assertUserCountIsEqualTo(t, store, 1)
+
+	// The ETag of the deleted row can't be used anymore
+	err = store.Delete(context.Background(), &state.DeleteRequest{Key: u.ID, ETag: &etag})
+	require.Error(t, err)
+	err = store.Set(context.Background(), &state.SetRequest{Key: u.ID, Value: u, ETag: &etag})
+	require.Error(t, err)
+
+	// Setting the key again restores it
+	err = store.Set(context.Background(), &state.SetRequest{Key: u.ID, Value: u})
+	require.NoError(t, err)
+	assertLoadedUserIsEqual(t, store, u.ID, u)
+
+	// Tombstones are removed when purged
+	err = store.Delete(context.Background(), &state.DeleteRequest{Key: u.ID})
+	require.NoError(t, err)
+	n, err := store.PurgeTombstones(context.Background(), 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assertUserCountIsEqualTo(t, store, 0)
+}
+
 func testConcurrentSets(t *testing.T) {
 	const parallelism = 10
 
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+const (
+	// Metadata property with the schema of each tenant, as "tenant=schema" entries separated by commas
+	tenantSchemasKey = "tenantSchemas"
+	// Metadata key on requests with the tenant whose tables are used for the operation
+	tenantMetadataKey = "tenant"
+)
+
+// tenant is a tenant whose data is stored in the tables of a separate schema.
+type tenant struct {
+	schema string
+
+	// The store is created, and the tables of the tenant provisioned, on the first operation of the tenant
+	lock  sync.Mutex
+	store *SQLServer
+}
+
+// parseTenantSchemas parses the mapping of tenants to schemas in the metadata property.
+// Schema names are validated like the schema of the component, as they're used in the SQL statements.
+func parseTenantSchemas(val string) (map[string]*tenant, error) {
+	if strings.TrimSpace(val) == "" {
+		return nil, nil
+	}
+	tenants := map[string]*tenant{}
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, schema, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		schema = strings.TrimSpace(schema)
+		if !ok || name == "" || schema == "" {
+			return nil, fmt.Errorf("invalid entry '%s' in '%s': must be in the format 'tenant=schema'", entry, tenantSchemasKey)
+		}
+		if !isValidSQLName(schema) {
+			return nil, fmt.Errorf("invalid schema name for tenant '%s', accepted characters are (A-Z, a-z, 0-9, _)", name)
+		}
+		if _, ok := tenants[name]; ok {
+			return nil, fmt.Errorf("duplicate tenant '%s' in '%s'", name, tenantSchemasKey)
+		}
+		tenants[name] = &tenant{schema: schema}
+	}
+	return tenants, nil
+}
+
+// storeFor returns the store for the tenant in the metadata of a request: the store itself if the request has no tenant, or the store of the tenant otherwise.
+// With lazy init, the component's store is initialized first.
+func (s *SQLServer) storeFor(ctx context.Context, md map[string]string) (*SQLServer, error) {
+	if err := s.lazyInit.Do(ctx); err != nil {
+		return nil, err
+	}
+	name := md[tenantMetadataKey]
+	if name == "" || name == s.tenantName {
+		return s, nil
+	}
+	t, ok := s.tenants[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tenant '%s'", name)
+	}
+	return s.tenantStore(ctx, name, t)
+}
+
+// storeForRequests returns the store for the tenant of a group of requests, which must all have the same tenant.
+func storeForRequests[T state.StateRequest](ctx context.Context, s *SQLServer, req []T) (*SQLServer, error) {
+	if len(req) == 0 {
+		return s, s.lazyInit.Do(ctx)
+	}
+	name := req[0].GetMetadata()[tenantMetadataKey]
+	for i := range req {
+		if req[i].GetMetadata()[tenantMetadataKey] != name {
+			return nil, errors.New("all requests must have the same tenant")
+		}
+	}
+	return s.storeFor(ctx, req[0].GetMetadata())
+}
+
+// ProvisionTenant creates the schema and the tables of the tenant, if they don't exist.
+// Tenants are otherwise provisioned on their first operation.
+func (s *SQLServer) ProvisionTenant(ctx context.Context, name string) error {
+	t, ok := s.tenants[name]
+	if !ok {
+		return fmt.Errorf("unknown tenant '%s'", name)
+	}
+	if err := s.lazyInit.Do(ctx); err != nil {
+		return err
+	}
+	_, err := s.tenantStore(ctx, name, t)
+	return err
+}
+
+// tenantStore returns the store of the tenant, provisioning its tables if this is the first operation of the tenant.
+// The store of a tenant is a copy of the component's store that uses the tenant's schema, and shares its connection pool.
+func (s *SQLServer) tenantStore(ctx context.Context, name string, t *tenant) (*SQLServer, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.store != nil {
+		return t.store, nil
+	}
+
+	ts := &SQLServer{}
+	*ts = *s
+	ts.schema = t.schema
+	ts.tenants = nil
+	ts.tenantName = name
+	ts.gc = nil
+	ts.BulkStore = state.NewDefaultBulkStore(ts)
+
+	mr, err := s.migratorFactory(ts).executeMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision the tables of the tenant in schema '%s': %w", t.schema, err)
+	}
+	ts.applyMigrationResult(mr)
+
+	if ts.cleanupInterval != nil {
+		ts.gc, err = ts.scheduleGarbageCollector()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t.store = ts
+	return ts, nil
+}
+
+// closeTenants stops the garbage collectors of the tenants; their connections are closed with the component's pool.
+func (s *SQLServer) closeTenants() error {
+	errs := make([]error, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		t.lock.Lock()
+		if t.store != nil && t.store.gc != nil {
+			errs = append(errs, t.store.gc.Close())
+		}
+		t.store = nil
+		t.lock.Unlock()
+	}
+	return errors.Join(errs...)
+}
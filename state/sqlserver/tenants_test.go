@@ -0,0 +1,265 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlserver
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/retry"
+)
+
+func TestParseTenantSchemas(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		tenants, err := parseTenantSchemas(" ")
+		require.NoError(t, err)
+		assert.Empty(t, tenants)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		tenants, err := parseTenantSchemas("a=tenant_a, b = tenant_b,")
+		require.NoError(t, err)
+		require.Len(t, tenants, 2)
+		assert.Equal(t, "tenant_a", tenants["a"].schema)
+		assert.Equal(t, "tenant_b", tenants["b"].schema)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, val := range []string{
+			"a",
+			"=tenant_a",
+			"a=",
+			"a=x];DROP TABLE [dbo].[state",
+			"a=tenant_a,a=tenant_b",
+		} {
+			_, err := parseTenantSchemas(val)
+			assert.Error(t, err, val)
+		}
+	})
+}
+
+type tenantMigrator struct {
+	store    *SQLServer
+	executed *[]string
+}
+
+func (m *tenantMigrator) executeMigrations(context.Context) (migrationResult, error) {
+	*m.executed = append(*m.executed, m.store.schema)
+	return (&migration{store: m.store}).newMigrationResult(), nil
+}
+
+func (m *tenantMigrator) planMigrations(context.Context, *state.ValidationReport) error {
+	return nil
+}
+
+func TestTenantRouting(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	tenants, err := parseTenantSchemas("a=tenant_a")
+	require.NoError(t, err)
+	var executed []string
+	s := &SQLServer{
+		logger:     logger.NewLogger("test"),
+		db:         db,
+		schema:     "dbo",
+		tableName:  "state",
+		getCommand: "SELECT [Data], [RowVersion], [Serializer] FROM [dbo].[state] WHERE [Key] = @Key",
+		tenants:    tenants,
+		migratorFactory: func(s *SQLServer) migrator {
+			return &tenantMigrator{store: s, executed: &executed}
+		},
+	}
+	s.BulkStore = state.NewDefaultBulkStore(s)
+
+	t.Run("request without tenant", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [dbo].[state]")).
+			WillReturnRows(sqlmock.NewRows([]string{"Data", "RowVersion", "Serializer"}).AddRow(`"v"`, []byte{1}, nil))
+
+		res, err := s.Get(context.Background(), &state.GetRequest{Key: "k"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v"`, string(res.Data))
+		assert.Empty(t, executed)
+	})
+
+	t.Run("tenant is provisioned once", func(t *testing.T) {
+		md := map[string]string{tenantMetadataKey: "a"}
+		for i := 0; i < 2; i++ {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_a].[state]")).
+				WillReturnRows(sqlmock.NewRows([]string{"Data", "RowVersion", "Serializer"}).AddRow(`"a"`, []byte{1}, nil))
+
+			res, err := s.Get(context.Background(), &state.GetRequest{Key: "k", Metadata: md})
+			require.NoError(t, err)
+			assert.Equal(t, `"a"`, string(res.Data))
+		}
+		assert.Equal(t, []string{"tenant_a"}, executed)
+		require.NoError(t, s.ProvisionTenant(context.Background(), "a"))
+		assert.Len(t, executed, 1)
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		_, err := s.Get(context.Background(), &state.GetRequest{Key: "k", Metadata: map[string]string{tenantMetadataKey: "b"}})
+		require.ErrorContains(t, err, "unknown tenant 'b'")
+		require.Error(t, s.ProvisionTenant(context.Background(), "b"))
+	})
+
+	t.Run("bulk requests with different tenants", func(t *testing.T) {
+		_, err := s.BulkGet(context.Background(), []state.GetRequest{
+			{Key: "k1", Metadata: map[string]string{tenantMetadataKey: "a"}},
+			{Key: "k2"},
+		}, state.BulkGetOpts{})
+		require.Error(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, s.closeTenants())
+}
+
+func TestTenantIsolation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	tenants, err := parseTenantSchemas("a=tenant_a,b=tenant_b")
+	require.NoError(t, err)
+	var executed []string
+	s := &SQLServer{
+		logger:                 logger.NewLogger("test"),
+		db:                     db,
+		schema:                 "dbo",
+		tableName:              "state",
+		keyType:                StringKeyType,
+		changeTracking:         true,
+		changeFeedPollInterval: time.Hour,
+		tenants:                tenants,
+		migratorFactory: func(s *SQLServer) migrator {
+			return &tenantMigrator{store: s, executed: &executed}
+		},
+	}
+	s.etagRetrier = state.NewETagRetrier(s, retry.DefaultConfigWithNoRetry())
+	s.BulkStore = state.NewDefaultBulkStore(s)
+	mdA := map[string]string{tenantMetadataKey: "a"}
+	mdB := map[string]string{tenantMetadataKey: "b"}
+
+	t.Run("reservations", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("MERGE [tenant_a].[state_Reservations]")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		err := s.Reserve(context.Background(), &state.ReserveRequest{
+			ID:         "saga1",
+			TTL:        time.Minute,
+			Operations: []state.TransactionalStateOperation{state.SetRequest{Key: "k", Value: "a"}},
+			Metadata:   mdA,
+		})
+		require.NoError(t, err)
+
+		// The reservation isn't visible to the other tenant
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_b].[state_Reservations]")).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}))
+		mock.ExpectRollback()
+		err = s.CommitReservation(context.Background(), "saga1", mdB)
+		require.ErrorIs(t, err, state.ErrReservationNotFound)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_a].[state_Reservations]")).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Status", "Operation"}).
+				AddRow(state.ReservationStatusReserved, `{"operation":"upsert","key":"k","value":"a"}`))
+		mock.ExpectExec(regexp.QuoteMeta("[tenant_a].sp_Upsert_v5_state")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE [tenant_a].[state_Reservations]")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		require.NoError(t, s.CommitReservation(context.Background(), "saga1", mdA))
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM [tenant_b].[state_Reservations]")).
+			WithArgs("saga1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_b].[state_Reservations]")).
+			WithArgs("saga1").
+			WillReturnRows(sqlmock.NewRows([]string{"Committed"}).AddRow(false))
+		require.NoError(t, s.RollbackReservation(context.Background(), "saga1", mdB))
+	})
+
+	t.Run("list keys", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_b].[state]")).
+			WillReturnRows(sqlmock.NewRows([]string{"Key"}).AddRow("k"))
+		res, err := s.ListKeys(context.Background(), &state.ListKeysRequest{Metadata: mdB})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"k"}, res.Keys)
+	})
+
+	t.Run("set with merge", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_b].[state]")).
+			WithArgs("k").
+			WillReturnRows(sqlmock.NewRows([]string{"Data", "RowVersion", "Serializer"}).AddRow(`"b"`, []byte{1}, nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("FROM [tenant_b].[state_Reservations]")).
+			WillReturnRows(sqlmock.NewRows([]string{"Key"}))
+		mock.ExpectExec(regexp.QuoteMeta("[tenant_b].sp_Upsert_v5_state")).
+			WithArgs("k", `"bb"`, []byte{1}, 0, nil, "json").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		err := s.SetWithMerge(context.Background(), "k", mdB, func(current *state.GetResponse) (any, error) {
+			assert.Equal(t, `"b"`, string(current.Data))
+			return "bb", nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("CHANGE_TRACKING_MIN_VALID_VERSION")).
+			WithArgs("[tenant_a].[state]").
+			WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("CHANGETABLE(CHANGES [tenant_a].[state], @Since)")).
+			WillReturnRows(sqlmock.NewRows([]string{"Key", "Op", "Version", "RowVersion", "Deleted"}).AddRow("k", "U", 11, []byte{1}, false))
+		changes, _, err := s.GetChanges(context.Background(), "10", mdA)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "k", changes[0].Key)
+	})
+
+	t.Run("purge tombstones", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM [tenant_a].[state] WHERE [Deleted] = 1")).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		n, err := s.PurgeTombstones(context.Background(), time.Hour, mdA)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		md := map[string]string{tenantMetadataKey: "c"}
+		_, err := s.ListKeys(context.Background(), &state.ListKeysRequest{Metadata: md})
+		require.ErrorContains(t, err, "unknown tenant 'c'")
+		_, err = s.PurgeTombstones(context.Background(), time.Hour, md)
+		require.ErrorContains(t, err, "unknown tenant 'c'")
+		require.ErrorContains(t, s.RollbackReservation(context.Background(), "saga1", md), "unknown tenant 'c'")
+	})
+
+	assert.ElementsMatch(t, []string{"tenant_a", "tenant_b"}, executed)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, s.closeTenants())
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTouchKeyNotFound is returned by Touch when the key doesn't exist or has expired.
+	ErrTouchKeyNotFound = errors.New("key not found")
+	// ErrTouchNoTTL is returned by Touch when the key doesn't have a TTL, so it's not given one.
+	ErrTouchNoTTL = errors.New("key doesn't have a TTL")
+)
+
+// Toucher is implemented by state stores that can extend the TTL of a key without reading or writing its value, such as to keep a session alive on access.
+type Toucher interface {
+	// Touch resets the TTL of the key to the TTL of the request, starting from now.
+	// It fails with ErrTouchKeyNotFound if the key doesn't exist or has expired, and with ErrTouchNoTTL if the key doesn't have a TTL, leaving it unchanged.
+	Touch(ctx context.Context, req *TouchRequest) error
+}
+
+// TouchRequest is the request to reset the TTL of a key.
+type TouchRequest struct {
+	Key string
+	// New TTL of the key
+	TTL      time.Duration
+	Metadata map[string]string
+}
+
+// Validate checks that the request is well-formed.
+func (r *TouchRequest) Validate() error {
+	if r.Key == "" {
+		return errors.New("missing key")
+	}
+	if r.TTL <= 0 {
+		return errors.New("the TTL must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTouchRequestValidate(t *testing.T) {
+	require.NoError(t, (&TouchRequest{Key: "a", TTL: time.Second}).Validate())
+	require.Error(t, (&TouchRequest{TTL: time.Second}).Validate())
+	require.Error(t, (&TouchRequest{Key: "a"}).Validate())
+	require.Error(t, (&TouchRequest{Key: "a", TTL: -time.Second}).Validate())
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// JSONOptions contains the metadata properties that control how state stores serialize values as JSON.
+// Components embed it with `mapstructure:",squash"`.
+// The zero value matches the behavior of encoding/json.
+type JSONOptions struct {
+	// If true, the characters '&', '<' and '>' in strings are not escaped as \u0026, \u003c and \u003e.
+	JSONDisableHTMLEscape bool `mapstructure:"jsonDisableHTMLEscape"`
+	// If true, numbers in values that are decoded and re-encoded keep their original representation, instead of being converted to float64, which loses the precision of large integers.
+	JSONUseNumber bool `mapstructure:"jsonUseNumber"`
+}
+
+// Marshal returns the JSON encoding of v.
+func (o JSONOptions) Marshal(v any) ([]byte, error) {
+	if !o.JSONDisableHTMLEscape {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	// Encode terminates the value with a newline, which Marshal doesn't
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// Unmarshal parses the JSON document in data and stores the result in v.
+func (o JSONOptions) Unmarshal(data []byte, v any) error {
+	if !o.JSONUseNumber {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+	// Like Unmarshal, reject trailing data after the document
+	if _, err = dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
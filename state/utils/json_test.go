@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+func TestJSONOptions(t *testing.T) {
+	const doc = `{"id":12345678901234567890,"q":"a&b<c>"}`
+
+	t.Run("defaults match encoding/json", func(t *testing.T) {
+		var o JSONOptions
+		var v any
+		require.NoError(t, o.Unmarshal([]byte(doc), &v))
+		assert.IsType(t, float64(0), v.(map[string]any)["id"])
+
+		res, err := o.Marshal(v)
+		require.NoError(t, err)
+		expect, _ := json.Marshal(v)
+		assert.Equal(t, string(expect), string(res))
+		assert.Equal(t, `{"id":12345678901234567000,"q":"a\u0026b\u003cc\u003e"}`, string(res))
+	})
+
+	t.Run("values are preserved", func(t *testing.T) {
+		o := JSONOptions{JSONDisableHTMLEscape: true, JSONUseNumber: true}
+		var v any
+		require.NoError(t, o.Unmarshal([]byte(doc), &v))
+		res, err := o.Marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, doc, string(res))
+	})
+
+	t.Run("trailing data is rejected", func(t *testing.T) {
+		var v any
+		require.Error(t, JSONOptions{JSONUseNumber: true}.Unmarshal([]byte(`{} ]`), &v))
+		require.Error(t, JSONOptions{}.Unmarshal([]byte(`{} ]`), &v))
+	})
+
+	t.Run("decode metadata", func(t *testing.T) {
+		var m struct {
+			JSONOptions `mapstructure:",squash"`
+		}
+		err := metadata.DecodeMetadata(map[string]string{
+			"jsonDisableHTMLEscape": "true",
+			"jsonUseNumber":         "true",
+		}, &m)
+		require.NoError(t, err)
+		assert.True(t, m.JSONDisableHTMLEscape)
+		assert.True(t, m.JSONUseNumber)
+	})
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidateOnlyKey is the metadata key that enables the validate-only mode in the state stores that support it.
+// In this mode, Init connects to the database and checks permissions, but instead of applying schema migrations it records the changes it would perform in a ValidationReport.
+// A state store initialized in validate-only mode must not be used for anything else than retrieving the report.
+const ValidateOnlyKey = "validateOnly"
+
+// ValidationReport contains the result of initializing a state store in validate-only mode.
+type ValidationReport struct {
+	// Connected is true if the connection to the database succeeded.
+	Connected bool `json:"connected"`
+	// PlannedChanges contains the schema changes that Init would apply, such as creating tables or adding columns.
+	PlannedChanges []string `json:"plannedChanges"`
+	// MissingPermissions contains the permissions that are required by the state store but that were not granted.
+	MissingPermissions []string `json:"missingPermissions,omitempty"`
+}
+
+// AddPlannedChange records a change that would be applied to the database.
+func (r *ValidationReport) AddPlannedChange(format string, args ...any) {
+	r.PlannedChanges = append(r.PlannedChanges, fmt.Sprintf(format, args...))
+}
+
+// AddMissingPermission records a permission that was not granted, if not already recorded.
+func (r *ValidationReport) AddMissingPermission(format string, args ...any) {
+	permission := fmt.Sprintf(format, args...)
+	for _, p := range r.MissingPermissions {
+		if p == permission {
+			return
+		}
+	}
+	r.MissingPermissions = append(r.MissingPermissions, permission)
+}
+
+// Err returns an error if the validation failed, or nil otherwise.
+func (r *ValidationReport) Err() error {
+	if !r.Connected {
+		return errors.New("validation failed: could not connect to the database")
+	}
+	if len(r.MissingPermissions) > 0 {
+		return errors.New("validation failed: missing permissions: " + strings.Join(r.MissingPermissions, "; "))
+	}
+	return nil
+}
+
+// Validator is implemented by state stores that support the validate-only mode.
+type Validator interface {
+	// ValidationReport returns the report created by Init in validate-only mode, or nil if the state store wasn't initialized in that mode.
+	ValidationReport() *ValidationReport
+}
+
+// GetValidationReport returns the report created by a state store initialized in validate-only mode.
+func GetValidationReport(store Store) (*ValidationReport, error) {
+	validator, ok := store.(Validator)
+	if !ok {
+		return nil, errors.New("validate-only mode is not supported by this state store")
+	}
+	report := validator.ValidationReport()
+	if report == nil {
+		return nil, errors.New("state store was not initialized in validate-only mode")
+	}
+	return report, nil
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationReport(t *testing.T) {
+	t.Run("not connected", func(t *testing.T) {
+		r := &ValidationReport{}
+		assert.ErrorContains(t, r.Err(), "could not connect")
+	})
+
+	t.Run("planned changes only", func(t *testing.T) {
+		r := &ValidationReport{Connected: true}
+		r.AddPlannedChange("create table '%s'", "state")
+		assert.NoError(t, r.Err())
+		assert.Equal(t, []string{"create table 'state'"}, r.PlannedChanges)
+	})
+
+	t.Run("missing permissions", func(t *testing.T) {
+		r := &ValidationReport{Connected: true}
+		r.AddMissingPermission("CREATE on schema '%s'", "public")
+		r.AddMissingPermission("INSERT on table '%s'", "state")
+		r.AddMissingPermission("CREATE on schema '%s'", "public")
+		assert.EqualError(t, r.Err(), "validation failed: missing permissions: CREATE on schema 'public'; INSERT on table 'state'")
+	})
+}
@@ -90,6 +90,7 @@ import (
 	s_cloudflareworkerskv "github.com/dapr/components-contrib/state/cloudflare/workerskv"
 	s_cockroachdb "github.com/dapr/components-contrib/state/cockroachdb"
 	s_etcd "github.com/dapr/components-contrib/state/etcd"
+	s_filesystem "github.com/dapr/components-contrib/state/filesystem"
 	s_inmemory "github.com/dapr/components-contrib/state/in-memory"
 	s_memcached "github.com/dapr/components-contrib/state/memcached"
 	s_mongodb "github.com/dapr/components-contrib/state/mongodb"
@@ -605,6 +606,8 @@ func loadStateStore(tc TestComponent) state.Store {
 		store = s_awsdynamodb.NewDynamoDBStateStore(testLogger)
 	case "etcd":
 		store = s_etcd.NewEtcdStateStore(testLogger)
+	case "filesystem":
+		store = s_filesystem.NewFilesystemStateStore(testLogger)
 	default:
 		return nil
 	}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+)
+
+// RecordedSpan is a span created by a RecordingTracer.
+type RecordedSpan struct {
+	Name       string
+	Kind       SpanKind
+	Attributes map[string]string
+	Context    SpanContext
+	// Parent is the span context of the parent, which is not valid for root spans.
+	Parent SpanContext
+	Ended  bool
+	Err    error
+}
+
+// RecordingTracer is a Tracer that keeps the spans it creates in memory.
+// It's meant for tests and debugging.
+type RecordingTracer struct {
+	lock  sync.Mutex
+	spans []*RecordedSpan
+}
+
+// Start starts a span that is a child of the span context in ctx, if any, or the root of a new trace.
+// Spans are always sampled.
+func (t *RecordingTracer) Start(ctx context.Context, name string, kind SpanKind, attributes map[string]string) (context.Context, Span) {
+	parent, _ := SpanContextFromContext(ctx)
+	rs := &RecordedSpan{
+		Name:       name,
+		Kind:       kind,
+		Attributes: attributes,
+		Parent:     parent,
+		Context: SpanContext{
+			TraceID:    parent.TraceID,
+			Sampled:    true,
+			TraceState: parent.TraceState,
+		},
+	}
+	if !parent.IsValid() {
+		_, _ = rand.Read(rs.Context.TraceID[:])
+	}
+	_, _ = rand.Read(rs.Context.SpanID[:])
+
+	t.lock.Lock()
+	t.spans = append(t.spans, rs)
+	t.lock.Unlock()
+
+	return ContextWithSpanContext(ctx, rs.Context), &recordingSpan{tracer: t, span: rs}
+}
+
+// Spans returns a copy of the spans created so far.
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	res := make([]RecordedSpan, len(t.spans))
+	for i, s := range t.spans {
+		res[i] = *s
+	}
+	return res
+}
+
+type recordingSpan struct {
+	tracer *RecordingTracer
+	span   *RecordedSpan
+}
+
+func (s *recordingSpan) SpanContext() SpanContext {
+	return s.span.Context
+}
+
+func (s *recordingSpan) End(err error) {
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+	s.span.Ended = true
+	s.span.Err = err
+}
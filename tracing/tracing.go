@@ -0,0 +1,283 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing propagates W3C trace context (https://www.w3.org/TR/trace-context/) through bindings and pub/sub components, and lets them create spans.
+//
+// Components don't depend on a tracing SDK: the runtime sets a Tracer (for example, backed by OpenTelemetry) on components that implement TracerSetter.
+// If no Tracer is set, components still propagate the trace context they receive, so traces are linked end to end.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceparentKey is the name of the header or metadata property that contains the W3C trace parent.
+	TraceparentKey = "traceparent"
+	// TracestateKey is the name of the header or metadata property that contains the W3C trace state.
+	TracestateKey = "tracestate"
+)
+
+// Attributes of messaging spans, from the OpenTelemetry semantic conventions.
+const (
+	AttributeMessagingSystem      = "messaging.system"
+	AttributeMessagingDestination = "messaging.destination.name"
+	AttributeMessagingOperation   = "messaging.operation"
+	AttributeMessagingMessageID   = "messaging.message.id"
+)
+
+// Values of the AttributeMessagingOperation attribute.
+const (
+	OperationPublish = "publish"
+	OperationProcess = "process"
+)
+
+// SpanKind is the kind of a span.
+type SpanKind string
+
+const (
+	// SpanKindProducer is the kind of spans that publish messages.
+	SpanKindProducer SpanKind = "producer"
+	// SpanKindConsumer is the kind of spans that process messages.
+	SpanKindConsumer SpanKind = "consumer"
+	// SpanKindClient is the kind of spans that invoke a remote service.
+	SpanKindClient SpanKind = "client"
+)
+
+// SpanContext identifies a span, and is propagated across process boundaries.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Sampled    bool
+	TraceState string
+}
+
+// ParseSpanContext parses the values of the "traceparent" and "tracestate" headers.
+func ParseSpanContext(traceparent string, tracestate string) (SpanContext, error) {
+	// Format is "version-traceid-spanid-flags"; future versions may append fields
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, errors.New("invalid traceparent format")
+	}
+
+	var (
+		sc    SpanContext
+		flags [1]byte
+	)
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, errors.New("invalid traceparent format")
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, errors.New("traceparent contains an all-zero trace ID or span ID")
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	sc.TraceState = strings.TrimSpace(tracestate)
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex-encoded s into dst, which must be exactly as long as the decoded value.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// IsValid returns true if the trace ID and span ID are not all zeros.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent returns the value of the "traceparent" header for the span context.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// Carrier is where trace context is read from and written to, such as message headers.
+type Carrier interface {
+	Get(key string) string
+	Set(key string, value string)
+}
+
+// MapCarrier is a Carrier backed by metadata. Keys are matched case-insensitively when reading.
+type MapCarrier map[string]string
+
+// Get returns the value of key.
+func (c MapCarrier) Get(key string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	for k, v := range c {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Set sets the value of key.
+func (c MapCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+// HeaderCarrier is a Carrier backed by HTTP headers.
+type HeaderCarrier http.Header
+
+// Get returns the value of key.
+func (c HeaderCarrier) Get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+// Set sets the value of key.
+func (c HeaderCarrier) Set(key string, value string) {
+	http.Header(c).Set(key, value)
+}
+
+// Extract returns the span context in carrier, if it contains a valid one.
+func Extract(carrier Carrier) (SpanContext, bool) {
+	traceparent := carrier.Get(TraceparentKey)
+	if traceparent == "" {
+		return SpanContext{}, false
+	}
+	sc, err := ParseSpanContext(traceparent, carrier.Get(TracestateKey))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Inject writes sc into carrier, if it's valid.
+func Inject(carrier Carrier, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(TraceparentKey, sc.Traceparent())
+	if sc.TraceState != "" {
+		carrier.Set(TracestateKey, sc.TraceState)
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx that contains sc, which is the parent of spans started with ctx.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context in ctx, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Span is an operation that is being traced.
+type Span interface {
+	// SpanContext returns the context of the span, which is propagated to downstream services.
+	SpanContext() SpanContext
+	// End completes the span. If err is not nil, the span is marked as failed.
+	End(err error)
+}
+
+// Tracer creates spans.
+// Its methods are invoked concurrently.
+type Tracer interface {
+	// Start starts a span that is a child of the span context in ctx (see SpanContextFromContext), if any.
+	// The returned context contains the context of the new span.
+	Start(ctx context.Context, name string, kind SpanKind, attributes map[string]string) (context.Context, Span)
+}
+
+// TracerSetter is implemented by components that create spans.
+// The tracer must be set before the component is used.
+type TracerSetter interface {
+	SetTracer(tracer Tracer)
+}
+
+// StartSpan starts a span with tracer, as a child of parent if it's valid, or of the span context in ctx otherwise.
+// If tracer is nil, no span is created, and the returned span propagates the parent span context unchanged.
+func StartSpan(ctx context.Context, tracer Tracer, name string, kind SpanKind, parent SpanContext, attributes map[string]string) (context.Context, Span) {
+	if parent.IsValid() {
+		ctx = ContextWithSpanContext(ctx, parent)
+	} else {
+		parent, _ = SpanContextFromContext(ctx)
+	}
+	if tracer == nil {
+		return ctx, noopSpan{sc: parent}
+	}
+	return tracer.Start(ctx, name, kind, attributes)
+}
+
+// StartProducerSpan starts a span for a message published to destination on a messaging system, and returns a copy of metadata in which the context of the span is injected.
+// The parent of the span is the trace context in metadata, if any, or the one in ctx.
+func StartProducerSpan(ctx context.Context, tracer Tracer, system string, destination string, metadata map[string]string) (Span, map[string]string) {
+	parent, _ := Extract(MapCarrier(metadata))
+	_, span := StartSpan(ctx, tracer, destination+" "+OperationPublish, SpanKindProducer, parent, map[string]string{
+		AttributeMessagingSystem:      system,
+		AttributeMessagingDestination: destination,
+		AttributeMessagingOperation:   OperationPublish,
+	})
+
+	md := make(map[string]string, len(metadata)+2)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	replaceSpanContext(md, span.SpanContext())
+	return span, md
+}
+
+// StartConsumerSpan starts a span for a message received from destination on a messaging system, as a child of the trace context in the metadata of the message.
+// The context of the span is injected in metadata (if it's not nil) and in the returned context, so it's propagated to the handler.
+func StartConsumerSpan(ctx context.Context, tracer Tracer, system string, destination string, metadata map[string]string) (context.Context, Span) {
+	parent, _ := Extract(MapCarrier(metadata))
+	ctx, span := StartSpan(ctx, tracer, destination+" "+OperationProcess, SpanKindConsumer, parent, map[string]string{
+		AttributeMessagingSystem:      system,
+		AttributeMessagingDestination: destination,
+		AttributeMessagingOperation:   OperationProcess,
+	})
+	if metadata != nil && span.SpanContext() != parent {
+		replaceSpanContext(metadata, span.SpanContext())
+	}
+	return ctx, span
+}
+
+// replaceSpanContext injects sc in metadata, if it's valid, removing the trace context that was there (whose keys may differ in case).
+func replaceSpanContext(metadata map[string]string, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	for k := range metadata {
+		if strings.EqualFold(k, TraceparentKey) || strings.EqualFold(k, TracestateKey) {
+			delete(metadata, k)
+		}
+	}
+	Inject(MapCarrier(metadata), sc)
+}
+
+// noopSpan is returned when no Tracer is set, and only propagates the context of its parent.
+type noopSpan struct {
+	sc SpanContext
+}
+
+func (s noopSpan) SpanContext() SpanContext {
+	return s.sc
+}
+
+func (s noopSpan) End(error) {}
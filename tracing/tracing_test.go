@@ -0,0 +1,185 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseSpanContext(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		sc, err := ParseSpanContext(testTraceparent, "congo=t61rcWkgMzE")
+		require.NoError(t, err)
+		assert.True(t, sc.IsValid())
+		assert.True(t, sc.Sampled)
+		assert.Equal(t, "congo=t61rcWkgMzE", sc.TraceState)
+		assert.Equal(t, testTraceparent, sc.Traceparent())
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		sc, err := ParseSpanContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "")
+		require.NoError(t, err)
+		assert.False(t, sc.Sampled)
+	})
+
+	t.Run("future version with extra fields", func(t *testing.T) {
+		_, err := ParseSpanContext("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", "")
+		require.NoError(t, err)
+	})
+
+	invalid := map[string]string{
+		"empty":          "",
+		"too few fields": "00-4bf92f3577b34da6a3ce929d0e0e4736-01",
+		"extra fields":   testTraceparent + "-extra",
+		"invalid ver":    "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"uppercase":      "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"short trace ID": "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"zero trace ID":  "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"zero span ID":   "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"not hex":        "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	}
+	for name, tp := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseSpanContext(tp, "")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	t.Run("metadata is matched case-insensitively", func(t *testing.T) {
+		sc, ok := Extract(MapCarrier{"Traceparent": testTraceparent, "TraceState": "a=b"})
+		require.True(t, ok)
+		assert.Equal(t, "a=b", sc.TraceState)
+
+		_, ok = Extract(MapCarrier{"traceparent": "invalid"})
+		assert.False(t, ok)
+		_, ok = Extract(MapCarrier(nil))
+		assert.False(t, ok)
+	})
+
+	t.Run("HTTP headers", func(t *testing.T) {
+		sc, err := ParseSpanContext(testTraceparent, "a=b")
+		require.NoError(t, err)
+		h := http.Header{}
+		Inject(HeaderCarrier(h), sc)
+		assert.Equal(t, testTraceparent, h.Get("Traceparent"))
+		assert.Equal(t, "a=b", h.Get("Tracestate"))
+
+		extracted, ok := Extract(HeaderCarrier(h))
+		require.True(t, ok)
+		assert.Equal(t, sc, extracted)
+	})
+
+	t.Run("invalid span contexts are not injected", func(t *testing.T) {
+		md := MapCarrier{}
+		Inject(md, SpanContext{})
+		assert.Empty(t, md)
+	})
+}
+
+func TestStartProducerSpan(t *testing.T) {
+	t.Run("without tracer the trace context is propagated", func(t *testing.T) {
+		md := map[string]string{"Traceparent": testTraceparent, "other": "x"}
+		span, res := StartProducerSpan(context.Background(), nil, "kafka", "topic", md)
+		span.End(nil)
+		assert.Equal(t, map[string]string{"traceparent": testTraceparent, "other": "x"}, res)
+		assert.Equal(t, testTraceparent, md["Traceparent"], "metadata of the request must not be modified")
+	})
+
+	t.Run("without tracer and trace context", func(t *testing.T) {
+		_, res := StartProducerSpan(context.Background(), nil, "kafka", "topic", nil)
+		assert.Empty(t, res)
+	})
+
+	t.Run("with tracer", func(t *testing.T) {
+		tracer := &RecordingTracer{}
+		span, res := StartProducerSpan(context.Background(), tracer, "kafka", "topic", map[string]string{"traceparent": testTraceparent})
+		span.End(errors.New("failed"))
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "topic publish", spans[0].Name)
+		assert.Equal(t, SpanKindProducer, spans[0].Kind)
+		assert.Equal(t, map[string]string{
+			AttributeMessagingSystem:      "kafka",
+			AttributeMessagingDestination: "topic",
+			AttributeMessagingOperation:   OperationPublish,
+		}, spans[0].Attributes)
+		assert.Equal(t, testTraceparent, spans[0].Parent.Traceparent())
+		assert.True(t, spans[0].Ended)
+		assert.Error(t, spans[0].Err)
+
+		// The message carries the context of the producer span, in the same trace
+		assert.Equal(t, spans[0].Context.Traceparent(), res["traceparent"])
+		assert.Equal(t, spans[0].Parent.TraceID, spans[0].Context.TraceID)
+	})
+
+	t.Run("parent from context", func(t *testing.T) {
+		tracer := &RecordingTracer{}
+		parent, err := ParseSpanContext(testTraceparent, "")
+		require.NoError(t, err)
+		_, res := StartProducerSpan(ContextWithSpanContext(context.Background(), parent), tracer, "kafka", "topic", nil)
+		spans := tracer.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, parent, spans[0].Parent)
+		assert.Equal(t, spans[0].Context.Traceparent(), res["traceparent"])
+	})
+}
+
+func TestStartConsumerSpan(t *testing.T) {
+	t.Run("without tracer the trace context is propagated", func(t *testing.T) {
+		md := map[string]string{"traceparent": testTraceparent}
+		ctx, span := StartConsumerSpan(context.Background(), nil, "rabbitmq", "queue", md)
+		span.End(nil)
+		assert.Equal(t, testTraceparent, md["traceparent"])
+		sc, ok := SpanContextFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, testTraceparent, sc.Traceparent())
+	})
+
+	t.Run("with tracer", func(t *testing.T) {
+		tracer := &RecordingTracer{}
+		md := map[string]string{"Traceparent": testTraceparent}
+		ctx, span := StartConsumerSpan(context.Background(), tracer, "rabbitmq", "queue", md)
+		span.End(nil)
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "queue process", spans[0].Name)
+		assert.Equal(t, SpanKindConsumer, spans[0].Kind)
+		assert.Equal(t, testTraceparent, spans[0].Parent.Traceparent())
+
+		// The handler receives the context of the consumer span
+		assert.Equal(t, map[string]string{"traceparent": spans[0].Context.Traceparent()}, md)
+		sc, ok := SpanContextFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, spans[0].Context, sc)
+	})
+
+	t.Run("root span", func(t *testing.T) {
+		tracer := &RecordingTracer{}
+		_, span := StartConsumerSpan(context.Background(), tracer, "rabbitmq", "queue", nil)
+		assert.True(t, span.SpanContext().IsValid())
+		assert.False(t, tracer.Spans()[0].Parent.IsValid())
+	})
+}